@@ -0,0 +1,1809 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/base/node/dependency_updater/pkg/attestation"
+	"github.com/base/node/dependency_updater/pkg/buildmatrix"
+	"github.com/base/node/dependency_updater/pkg/doctor"
+	"github.com/base/node/dependency_updater/pkg/drift"
+	"github.com/base/node/dependency_updater/pkg/flagdiff"
+	"github.com/base/node/dependency_updater/pkg/history"
+	"github.com/base/node/dependency_updater/pkg/importer"
+	"github.com/base/node/dependency_updater/pkg/multirepo"
+	"github.com/base/node/dependency_updater/pkg/mutation"
+	"github.com/base/node/dependency_updater/pkg/ociartifact"
+	"github.com/base/node/dependency_updater/pkg/operator"
+	"github.com/base/node/dependency_updater/pkg/policy"
+	"github.com/base/node/dependency_updater/pkg/rebuild"
+	"github.com/base/node/dependency_updater/pkg/review"
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/selfupdate"
+	"github.com/base/node/dependency_updater/pkg/simulate"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/targets"
+	"github.com/base/node/dependency_updater/pkg/version"
+	"github.com/google/go-github/v72/github"
+	"github.com/urfave/cli/v3"
+)
+
+// checkOutput is the check command's JSON output.
+type checkOutput struct {
+	Updates   []version.UpdateInfo `json:"updates"`
+	Checks    []runner.CheckResult `json:"checks"`
+	Rationale []policy.Rationale   `json:"rationale"`
+	Drift     []drift.Drift        `json:"drift"`
+}
+
+func checkCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "check",
+		Usage: "Reports the available updates without changing any files",
+		Flags: slices.Concat([]cli.Flag{
+			&cli.BoolFlag{Name: "json", Usage: "Prints the updates, check results and the rationale of each selection as JSON, same as --format=json"},
+			&cli.StringFlag{Name: "fail-on-drift", Usage: "Exits with an error if a dependency that isn't pinned is behind by this level or more: patch, minor or major"},
+		}, formatFlags, matrixFlags),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			level := cmd.String("fail-on-drift")
+			if level != "" && !slices.Contains(drift.Levels, level) {
+				return fmt.Errorf("invalid --fail-on-drift %s, expected one of %s", level, strings.Join(drift.Levels, ", "))
+			}
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			run.DryRun = true
+			result, err := runner.Run(ctx, run)
+			if err != nil {
+				return fmt.Errorf("failed to check for updates: %s", err)
+			}
+			printCISummary(cmd, run, result)
+			if err := printCheck(cmd, run, result); err != nil || level == "" {
+				return err
+			}
+			beyond, _ := drift.Beyond(result.Drift, level)
+			if len(beyond) > 0 {
+				return fmt.Errorf("%d dependencies are a %s or more behind: %s", len(beyond), level, drift.Summary(beyond))
+			}
+			return nil
+		},
+	}
+}
+
+// printCheck prints the result of the check command as its flags ask.
+func printCheck(cmd *cli.Command, run runner.Options, result *runner.Result) error {
+	if cmd.Bool("matrix") {
+		return writeMatrix(cmd, run, result)
+	}
+	output := checkOutput{
+		Updates:   append([]version.UpdateInfo{}, result.Updates...),
+		Checks:    append([]runner.CheckResult{}, result.Checks...),
+		Rationale: append([]policy.Rationale{}, result.Rationale...),
+		Drift:     append([]drift.Drift{}, result.Drift...),
+	}
+	if printed, err := printFormatted(cmd, output); printed || err != nil {
+		return err
+	}
+	if len(result.Updates) == 0 {
+		fmt.Println("All dependencies are up to date")
+	}
+	now := time.Now()
+	for _, update := range result.Updates {
+		released := ""
+		if r := policy.Released(update.Published, now); r != "" {
+			released = " (" + r + ")"
+		}
+		switch update.Kind {
+		case version.KindPromotion:
+			fmt.Printf("%s %s -> %s promote to stable %s%s\n", update.Repo, update.From, update.To, update.DiffUrl, released)
+			continue
+		case version.KindRetraction:
+			fmt.Printf("%s %s -> %s replaces retracted %s %s%s\n", update.Repo, update.From, update.To, update.From, update.DiffUrl, released)
+			continue
+		}
+		fmt.Printf("%s %s -> %s %s%s\n", update.Repo, update.From, update.To, update.DiffUrl, released)
+	}
+	for _, check := range result.Checks {
+		fmt.Printf("%s %s: %s %s, %s\n", check.Dependency, check.Version, check.Check, check.Status(), check.Detail)
+	}
+	return nil
+}
+
+func updateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "update",
+		Usage: "Updates the version files to the selected versions",
+		Flags: slices.Concat([]cli.Flag{
+			&cli.BoolFlag{Name: "interactive", Usage: "Lists the updates with their release notes and risk score to select the ones to apply"},
+		}, matrixFlags, rebuildFlags, registryFlags),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			if err := update(ctx, cmd, true); err != nil {
+				return fmt.Errorf("failed to run updater: %s", err)
+			}
+			return nil
+		},
+	}
+}
+
+// update updates every repository of --repos, or the one of --repo. Only interactive updates
+// ask before writing, the daemon's run unattended.
+func update(ctx context.Context, cmd *cli.Command, interactive bool) error {
+	return forEachRepo(ctx, cmd, func(ctx context.Context, repo multirepo.Repo) error {
+		return updateRepo(ctx, cmd, repo, interactive)
+	})
+}
+
+// updateRepo updates repo. If it has a pull request, the run starts from its base branch and
+// proposes the updates in the pull request instead of finishing as the flags ask.
+func updateRepo(ctx context.Context, cmd *cli.Command, repo multirepo.Repo, interactive bool) error {
+	pr := repo.PullRequest
+	if cmd.Bool("dry-run") {
+		pr = nil
+	}
+	if pr != nil {
+		if err := pr.Prepare(ctx, repo.Path); err != nil {
+			return err
+		}
+	}
+	run, err := newRepoRun(ctx, cmd, repo, interactive)
+	if err != nil {
+		return err
+	}
+	if cmd.Bool("interactive") {
+		run.Select = func(ctx context.Context, dependencies version.Dependencies, planned []version.PlannedUpdate, checks []runner.CheckResult) ([]version.PlannedUpdate, error) {
+			items, err := review.Items(ctx, run.Sources, dependencies, planned, checks)
+			if err != nil {
+				return nil, err
+			}
+			return review.Prompt(os.Stdin, os.Stdout, items)
+		}
+	}
+	result, err := runner.Run(ctx, run)
+	if err != nil {
+		return err
+	}
+	if cmd.Bool("matrix") {
+		if err := writeMatrix(cmd, run, result); err != nil {
+			return err
+		}
+	}
+	if cmd.String("rebuild") != "" && !run.DryRun && len(result.Planned) > 0 {
+		if err := rebuildImages(ctx, cmd, run, result); err != nil {
+			return err
+		}
+	}
+	printCISummary(cmd, run, result)
+	if pr != nil {
+		return proposeUpdates(ctx, cmd, repo, result)
+	}
+	return finish(ctx, cmd, run, result)
+}
+
+// proposeUpdates opens the pull request of repo with the run's updates, or updates the open one.
+func proposeUpdates(ctx context.Context, cmd *cli.Command, repo multirepo.Repo, result *runner.Result) error {
+	if result.Updates == nil {
+		return nil
+	}
+	client, err := githubClient(cmd)
+	if err != nil {
+		return err
+	}
+	title, body, _ := commitMessage(result)
+	url, err := repo.PullRequest.Publish(ctx, client, repo.Path, title, body)
+	if err != nil {
+		return err
+	}
+	log.Printf("Proposed the updates of %s in %s", repo.Name, url)
+	return nil
+}
+
+var rebuildFlags = []cli.Flag{
+	&cli.StringFlag{Name: "rebuild", Usage: "Rebuilds the images whose dependencies changed with buildx or workflow, recording their digests in images.lock.json"},
+	&cli.StringSliceFlag{Name: "rebuild-platform", Usage: "Platforms buildx builds for, the builder's if unset"},
+	&cli.StringFlag{Name: "rebuild-repo", Usage: "Repository whose workflow is dispatched, <owner>/<repo>", Value: "base/node"},
+	&cli.StringFlag{Name: "rebuild-workflow", Usage: "Workflow file dispatched with the image, dockerfile and tag inputs", Value: "docker.yml"},
+	&cli.StringFlag{Name: "rebuild-ref", Usage: "Branch the workflow runs on, it has to have the updated versions"},
+}
+
+var registryFlags = []cli.Flag{
+	&cli.StringFlag{Name: "registry-username", Usage: "Registry username", Sources: cli.EnvVars("REGISTRY_USERNAME")},
+	&cli.StringFlag{Name: "registry-password", Usage: "Registry password or token", Sources: cli.EnvVars("REGISTRY_PASSWORD")},
+	&cli.BoolFlag{Name: "plain-http", Usage: "Talks to the registry without TLS"},
+}
+
+// rebuildImages rebuilds the images of the run's build matrix and records their digests.
+func rebuildImages(ctx context.Context, cmd *cli.Command, run runner.Options, result *runner.Result) error {
+	matrix, err := buildmatrix.Build(run.RepoPath, result.Planned, cmd.String("image-prefix"))
+	if err != nil {
+		return fmt.Errorf("error building the matrix: %s", err)
+	}
+	var builder rebuild.Builder
+	switch cmd.String("rebuild") {
+	case "buildx":
+		builder = rebuild.Buildx{RepoPath: run.RepoPath, Platforms: cmd.StringSlice("rebuild-platform")}
+	case "workflow":
+		owner, repo, ok := strings.Cut(cmd.String("rebuild-repo"), "/")
+		if !ok || cmd.String("rebuild-ref") == "" {
+			return fmt.Errorf("--rebuild workflow needs --rebuild-repo <owner>/<repo> and --rebuild-ref")
+		}
+		registry := &ociartifact.Pusher{
+			HTTP:      http.DefaultClient,
+			Username:  cmd.String("registry-username"),
+			Password:  cmd.String("registry-password"),
+			PlainHTTP: cmd.Bool("plain-http"),
+		}
+		client, err := githubClient(cmd)
+		if err != nil {
+			return err
+		}
+		builder = rebuild.Workflow{
+			Client:   client,
+			Owner:    owner,
+			Repo:     repo,
+			Workflow: cmd.String("rebuild-workflow"),
+			Ref:      cmd.String("rebuild-ref"),
+			Resolve: func(ctx context.Context, image string) (string, error) {
+				ref, err := ociartifact.ParseReference(image)
+				if err != nil {
+					return "", err
+				}
+				return registry.Resolve(ctx, ref)
+			},
+		}
+	default:
+		return fmt.Errorf("unknown --rebuild %q, expected buildx or workflow", cmd.String("rebuild"))
+	}
+
+	lock, err := rebuild.ReadLockfile(run.RepoPath)
+	if err != nil {
+		return err
+	}
+	err = rebuild.Rebuild(ctx, builder, matrix, lock, time.Now)
+	// Builds that succeeded before a failure are recorded either way.
+	if writeErr := lock.Write(run.RepoPath); writeErr != nil {
+		return writeErr
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range matrix.Include {
+		fmt.Printf("Rebuilt %s:%s %s\n", entry.Image, lock[entry.Name].Tag, lock[entry.Name].Digest)
+	}
+	return nil
+}
+
+var matrixFlags = []cli.Flag{
+	&cli.BoolFlag{Name: "matrix", Usage: "Outputs a GitHub Actions build matrix of the images whose dependencies changed, as MATRIX with --github-action"},
+	&cli.StringFlag{Name: "image-prefix", Usage: "Prefix of the matrix image names, followed by the Dockerfile's directory", Value: "ghcr.io/base/node-"},
+}
+
+// writeMatrix prints the build matrix of a run's updates, or writes it to GITHUB_OUTPUT.
+func writeMatrix(cmd *cli.Command, run runner.Options, result *runner.Result) error {
+	matrix, err := buildmatrix.Build(run.RepoPath, result.Planned, cmd.String("image-prefix"))
+	if err != nil {
+		return fmt.Errorf("error building the matrix: %s", err)
+	}
+	if !cmd.Bool("github-action") {
+		return printJSON(matrix)
+	}
+	encoded, err := json.Marshal(matrix)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(os.Getenv("GITHUB_OUTPUT"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open GITHUB_OUTPUT file: %s", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "MATRIX=%s\n", encoded); err != nil {
+		return fmt.Errorf("failed to write to GITHUB_OUTPUT file: %s", err)
+	}
+	return nil
+}
+
+func compareCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "compare",
+		Usage:     "Shows the changes between two versions of a dependency, the pinned and the selected one by default",
+		ArgsUsage: "<dependency> [from] [to]",
+		Description: "Lists the commits between the versions, the release notes of the releases in between and,\n" +
+			"for dependencies with an \"image\" in versions.json, the change in image size.",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{Name: "from", Usage: "Compares from this tag or commit instead of the pinned one"},
+			&cli.StringFlag{Name: "to", Usage: "Compares to this tag or commit instead of the selected one"},
+			&cli.IntFlag{Name: "commits", Usage: "Number of commits listed", Value: 20},
+			&cli.StringFlag{Name: "platform", Usage: "Platform whose image size is compared", Value: "linux/amd64"},
+		}, registryFlags...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			name, info, source, err := dependencyArg(cmd, run)
+			if err != nil {
+				return err
+			}
+			from, to := version.SanitizeTag(cmp.Or(cmd.Args().Get(1), cmd.String("from"))), version.SanitizeTag(cmp.Or(cmd.Args().Get(2), cmd.String("to")))
+			if from == "" {
+				from = pinnedRef(info)
+			}
+			if to == "" {
+				planned, err := policy.Resolve(ctx, source, name, info)
+				if err != nil {
+					return fmt.Errorf("error resolving %s: %s", name, err)
+				}
+				to = pinnedRef(info)
+				if planned != nil {
+					to = planned.Info.To
+				}
+			}
+			if from == to {
+				fmt.Printf("%s is at %s\n", name, from)
+				return nil
+			}
+
+			order := ""
+			if c, err := version.CompareVersions(from, to, info.TagPrefix); err == nil && c > 0 {
+				order = fmt.Sprintf(", %s is older than %s", to, from)
+			}
+			fmt.Printf("%s %s -> %s%s %s\n", name, from, to, order, source.CompareURL(info.Owner, info.Repo, from, to))
+
+			if comparison, err := sources.CompareCommits(ctx, source, info.Owner, info.Repo, from, to); err == nil {
+				fmt.Printf("\n%d commits\n", comparison.TotalCommits)
+				// GitHub lists the oldest commits first, the newest are the interesting ones.
+				commits := comparison.Commits
+				if limit := int(cmd.Int("commits")); len(commits) > limit {
+					commits = commits[len(commits)-limit:]
+				}
+				for i := len(commits) - 1; i >= 0; i-- {
+					fmt.Printf("  %.10s %s\n", commits[i].SHA, commits[i].Message)
+				}
+			} else if !errors.Is(err, errors.ErrUnsupported) {
+				log.Printf("Error comparing commits: %s", err)
+			}
+
+			if info.Tracking != "branch" {
+				tags, err := source.ListTags(ctx, info.Owner, info.Repo)
+				if err != nil {
+					return err
+				}
+				if releases, err := policy.Between(tags, info.TagPrefix, from, to); err == nil && len(releases) > 0 {
+					fmt.Printf("\nRelease notes of %d releases\n", len(releases))
+					for _, tag := range releases {
+						release, err := source.GetRelease(ctx, info.Owner, info.Repo, tag)
+						if err != nil {
+							// Tags without a release have no notes.
+							continue
+						}
+						fmt.Printf("\n## %s\n%s\n", cmp.Or(release.Name, tag), strings.TrimSpace(release.Body))
+					}
+				}
+			}
+
+			if info.Image != "" && info.Tracking != "branch" {
+				registry := &ociartifact.Pusher{
+					HTTP:      http.DefaultClient,
+					Username:  cmd.String("registry-username"),
+					Password:  cmd.String("registry-password"),
+					PlainHTTP: cmd.Bool("plain-http"),
+				}
+				var sizes []int64
+				for _, tag := range []string{from, to} {
+					image := info.Image + ":" + info.ImageTagOf(tag)
+					ref, err := ociartifact.ParseReference(image)
+					if err != nil {
+						log.Printf("Error getting the image size of %s: %s", image, err)
+						break
+					}
+					size, err := registry.ImageSize(ctx, ref, cmd.String("platform"))
+					if err != nil {
+						log.Printf("Error getting the image size of %s: %s", image, err)
+						break
+					}
+					sizes = append(sizes, size)
+				}
+				if len(sizes) == 2 {
+					fmt.Printf("\nImage size %s -> %s (%+.1f MB)\n", formatSize(sizes[0]), formatSize(sizes[1]), float64(sizes[1]-sizes[0])/1e6)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func formatSize(bytes int64) string {
+	return fmt.Sprintf("%.1f MB", float64(bytes)/1e6)
+}
+
+func pinCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "pin",
+		Usage:     "Sets a dependency to a version and holds it there until unpinned",
+		ArgsUsage: "<dependency> [tag]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "sha", Usage: "Commit of the pinned tag, looked up from the source if unset"},
+			&cli.BoolFlag{Name: "unpin", Usage: "Releases the hold so runs update the dependency again"},
+			&cli.BoolFlag{Name: "all-current", Usage: "Pins every dependency that isn't pinned at its current version, such as ahead of an incident or audit"},
+			&cli.DurationFlag{Name: "for", Usage: "Records when the pin expires, for unpin --expired to release it"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			var until time.Time
+			if d := cmd.Duration("for"); d > 0 {
+				until = time.Now().Add(d).UTC().Truncate(time.Second)
+			}
+			if cmd.Bool("all-current") {
+				return pinAllCurrent(ctx, cmd, run, until)
+			}
+			name, info, source, err := dependencyArg(cmd, run)
+			if err != nil {
+				return err
+			}
+
+			planned := version.PlannedUpdate{Dependency: name, Version: info.Tag, Commit: info.Commit, Pin: !cmd.Bool("unpin")}
+			if planned.Pin {
+				planned.PinUntil = until
+			}
+			if tag := version.SanitizeTag(cmd.Args().Get(1)); tag != "" && !cmd.Bool("unpin") {
+				commit := cmd.String("sha")
+				if commit == "" {
+					if commit, err = tagCommit(ctx, source, info, tag); err != nil {
+						return err
+					}
+				}
+				planned.Version, planned.Commit = tag, commit
+				if tag != info.Tag {
+					planned.Info = version.UpdateInfo{Repo: info.Repo, From: info.Tag, To: tag, DiffUrl: source.CompareURL(info.Owner, info.Repo, info.Tag, tag)}
+				}
+			} else if !cmd.Bool("unpin") {
+				return fmt.Errorf("pin needs a tag, or --unpin")
+			}
+
+			result, err := runner.Apply(ctx, run, []version.PlannedUpdate{planned})
+			if err != nil {
+				return fmt.Errorf("failed to pin %s: %s", name, err)
+			}
+			return finish(ctx, cmd, run, result)
+		},
+	}
+}
+
+// pinAllCurrent pins every unpinned dependency at its current version. Existing pins keep
+// their own expiry.
+func pinAllCurrent(ctx context.Context, cmd *cli.Command, run runner.Options, until time.Time) error {
+	dependencies, err := version.ReadDependencies(run.RepoPath)
+	if err != nil {
+		return err
+	}
+	var planned []version.PlannedUpdate
+	for _, name := range version.Names(dependencies) {
+		if info := dependencies[name]; !info.Pinned {
+			planned = append(planned, version.PlannedUpdate{Dependency: name, Version: info.Tag, Commit: info.Commit, Pin: true, PinUntil: until})
+			fmt.Printf("Pinning %s at %s\n", name, pinnedRef(info))
+		}
+	}
+	if len(planned) == 0 {
+		fmt.Println("Every dependency is pinned already")
+		return nil
+	}
+	result, err := runner.Apply(ctx, run, planned)
+	if err != nil {
+		return fmt.Errorf("failed to pin the current versions: %s", err)
+	}
+	return finish(ctx, cmd, run, result)
+}
+
+func unpinCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "unpin",
+		Usage:     "Releases the pin of a dependency, or every expired pin, so runs update them again",
+		ArgsUsage: "[dependency]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "expired", Usage: "Releases every pin whose expiry, set with pin --for, has passed"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			dependencies, err := version.ReadDependencies(run.RepoPath)
+			if err != nil {
+				return err
+			}
+			var names []string
+			if cmd.Bool("expired") {
+				now := time.Now()
+				for _, name := range version.Names(dependencies) {
+					if info := dependencies[name]; info.Pinned && !info.PinnedUntil.IsZero() && now.After(info.PinnedUntil) {
+						names = append(names, name)
+						fmt.Printf("Unpinning %s, its pin expired %s\n", name, formatTime(info.PinnedUntil))
+					}
+				}
+				if len(names) == 0 {
+					fmt.Println("No expired pins")
+					return nil
+				}
+			} else {
+				name, info, _, err := dependencyArg(cmd, run)
+				if err != nil {
+					return err
+				}
+				if !info.Pinned {
+					return fmt.Errorf("%s is not pinned", name)
+				}
+				names = append(names, name)
+			}
+
+			var planned []version.PlannedUpdate
+			for _, name := range names {
+				planned = append(planned, version.PlannedUpdate{Dependency: name, Version: dependencies[name].Tag, Commit: dependencies[name].Commit})
+			}
+			result, err := runner.Apply(ctx, run, planned)
+			if err != nil {
+				return fmt.Errorf("failed to unpin %s: %s", strings.Join(names, ", "), err)
+			}
+			return finish(ctx, cmd, run, result)
+		},
+	}
+}
+
+func diffCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "Prints the file changes moving a dependency to a given version would make, without writing them",
+		ArgsUsage: "<dependency>@<tag|commit>",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			name, ref, ok := strings.Cut(cmd.Args().First(), "@")
+			if !ok || name == "" || ref == "" {
+				return fmt.Errorf("diff needs <dependency>@<tag|commit>")
+			}
+			dependencies, err := version.ReadDependencies(run.RepoPath)
+			if err != nil {
+				return err
+			}
+			info, ok := dependencies[name]
+			if !ok {
+				return fmt.Errorf("unknown dependency %s", name)
+			}
+			source, err := run.Sources.For(info.Source)
+			if err != nil {
+				return err
+			}
+
+			planned := version.PlannedUpdate{Dependency: name, Version: ref, Pin: info.Pinned, PinUntil: info.PinnedUntil}
+			if info.Tracking == "branch" {
+				// Branch tracking pins commits, the tag stays as it is.
+				planned.Version = info.Tag
+				if planned.Commit, err = source.ResolveRef(ctx, info.Owner, info.Repo, ref); err != nil {
+					return fmt.Errorf("error resolving %s: %s", ref, err)
+				}
+			} else if planned.Commit, err = tagCommit(ctx, source, info, ref); err != nil {
+				return err
+			}
+			if from := pinnedRef(info); from != ref {
+				planned.Info = version.UpdateInfo{Repo: info.Repo, From: info.Tag, To: ref, DiffUrl: source.CompareURL(info.Owner, info.Repo, from, ref)}
+			}
+
+			run.DryRun = true
+			result, err := runner.Apply(ctx, run, []version.PlannedUpdate{planned})
+			if err != nil {
+				return fmt.Errorf("failed to plan %s: %s", cmd.Args().First(), err)
+			}
+			if len(result.Edits) == 0 {
+				fmt.Printf("%s is at %s, nothing would change\n", name, ref)
+				return nil
+			}
+			return finish(ctx, cmd, run, result)
+		},
+	}
+}
+
+func waitCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "wait",
+		Usage:     "Blocks until a new release of a dependency is published upstream and prints its version, giving up after --timeout",
+		ArgsUsage: "<dependency>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "constraint", Usage: `Version constraint the release has to satisfy, such as ">= 1.10, < 2"`},
+			&cli.BoolFlag{Name: "compatible", Usage: "Only waits for releases that aren't a breaking bump from the current version, which for dependencies with preOneBreaking includes 0.x minor bumps"},
+			&cli.DurationFlag{Name: "interval", Usage: "Time between checks of the source", Value: 5 * time.Minute},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			// Every check has to see the source's current tags, not the cached ones.
+			if err := cmd.Set("refresh", "true"); err != nil {
+				return err
+			}
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			name, info, source, err := dependencyArg(cmd, run)
+			if err != nil {
+				return err
+			}
+			var filters []func([]sources.Tag) []sources.Tag
+			if (cmd.String("constraint") != "" || cmd.Bool("compatible")) && (info.Tracking == "branch" || info.Tracking == "nightly") {
+				return fmt.Errorf("%s tracks %s builds, which have no version to constrain", name, info.Tracking)
+			}
+			if c := cmd.String("constraint"); c != "" {
+				constraints, err := semver.NewConstraint(c)
+				if err != nil {
+					return fmt.Errorf("invalid constraint %q: %s", c, err)
+				}
+				filters = append(filters, func(tags []sources.Tag) []sources.Tag { return policy.Matching(tags, info.TagPrefix, constraints) })
+			}
+			if cmd.Bool("compatible") {
+				filters = append(filters, func(tags []sources.Tag) []sources.Tag { return policy.Compatible(tags, info) })
+			}
+			if err := policy.Validate(name, info); err != nil {
+				return err
+			}
+
+			// What is left to fail is reading the source, which is retried at the next check.
+			for {
+				found, err := newRelease(ctx, source, name, info, filters)
+				switch {
+				case err != nil && ctx.Err() == nil:
+					log.Printf("Warning: checking %s for a new release failed, checking again in %s: %s", name, cmd.Duration("interval"), err)
+				case found != "":
+					fmt.Println(found)
+					return nil
+				case err == nil:
+					log.Printf("No new release of %s after %s yet, checking again in %s", name, pinnedRef(info), cmd.Duration("interval"))
+				}
+				select {
+				case <-ctx.Done():
+					return fmt.Errorf("no new release of %s within %s", name, cmd.Duration("timeout"))
+				case <-time.After(cmd.Duration("interval")):
+				}
+			}
+		},
+	}
+}
+
+// newRelease returns the version a dependency would update to, among the tags the filters
+// keep, or "" if there is none.
+func newRelease(ctx context.Context, source sources.VersionSource, name string, info *version.Info, filters []func([]sources.Tag) []sources.Tag) (string, error) {
+	if filters == nil {
+		planned, err := policy.Resolve(ctx, source, name, info)
+		if err != nil || planned == nil {
+			return "", err
+		}
+		return planned.Info.To, nil
+	}
+	tags, err := source.ListTags(ctx, info.Owner, info.Repo)
+	if err != nil {
+		return "", err
+	}
+	for _, filter := range filters {
+		tags = filter(tags)
+	}
+	if selected := policy.SelectTag(tags, info); selected != nil && selected.Name != info.Tag {
+		return selected.Name, nil
+	}
+	return "", nil
+}
+
+func rollbackCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "rollback",
+		Usage:     "Restores the version a dependency had before the updater last changed it",
+		ArgsUsage: "<dependency>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "pin", Usage: "Holds the dependency at the restored version so runs don't update it again", Value: true},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			name, info, source, err := dependencyArg(cmd, run)
+			if err != nil {
+				return err
+			}
+			component, ok := run.History.Components[name]
+			if !ok || component.Previous == nil {
+				return fmt.Errorf("no earlier version of %s recorded in the history", name)
+			}
+
+			previous := *component.Previous
+			to := previous.Tag
+			if to == "" {
+				to = previous.Commit
+			}
+			planned := version.PlannedUpdate{
+				Dependency: name,
+				Version:    previous.Tag,
+				Commit:     previous.Commit,
+				Pin:        cmd.Bool("pin"),
+				Info:       version.UpdateInfo{Repo: info.Repo, From: pinnedRef(info), To: to, DiffUrl: source.CompareURL(info.Owner, info.Repo, pinnedRef(info), to)},
+			}
+			result, err := runner.Apply(ctx, run, []version.PlannedUpdate{planned})
+			if err != nil {
+				return fmt.Errorf("failed to roll back %s: %s", name, err)
+			}
+			return finish(ctx, cmd, run, result)
+		},
+	}
+}
+
+// reportEntry is one dependency in the report command's output.
+type reportEntry struct {
+	Dependency string             `json:"dependency"`
+	Tag        string             `json:"tag,omitempty"`
+	Commit     string             `json:"commit"`
+	Pinned     bool               `json:"pinned,omitempty"`
+	PinUntil   time.Time          `json:"pinnedUntil,omitzero"`
+	History    *history.Component `json:"history,omitempty"`
+	// Drift is set with report --drift.
+	Drift *drift.Drift `json:"drift,omitempty"`
+}
+
+func reportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "report",
+		Usage: "Prints the pinned versions and the recorded history of every dependency",
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{Name: "json", Usage: "Prints the report as JSON, same as --format=json"},
+			&cli.BoolFlag{Name: "drift", Usage: "Checks every dependency upstream and starts the report with how far each one is behind"},
+		}, formatFlags...),
+		Commands: []*cli.Command{reportHistoryCommand()},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			dependencies, err := version.ReadDependencies(run.RepoPath)
+			if err != nil {
+				return err
+			}
+			drifts := map[string]*drift.Drift{}
+			if cmd.Bool("drift") {
+				run.DryRun, run.Force = true, true
+				result, err := runner.Run(ctx, run)
+				if err != nil {
+					return fmt.Errorf("failed to check for drift: %s", err)
+				}
+				for i := range result.Drift {
+					drifts[result.Drift[i].Dependency] = &result.Drift[i]
+				}
+			}
+			entries := []reportEntry{}
+			for _, name := range version.Names(dependencies) {
+				if !run.Selects(name) {
+					continue
+				}
+				info := dependencies[name]
+				entries = append(entries, reportEntry{Dependency: name, Tag: info.Tag, Commit: info.Commit, Pinned: info.Pinned, PinUntil: info.PinnedUntil, History: run.History.Components[name], Drift: drifts[name]})
+			}
+			if printed, err := printFormatted(cmd, entries); printed || err != nil {
+				return err
+			}
+			if cmd.Bool("drift") {
+				var header []drift.Drift
+				for _, entry := range entries {
+					if entry.Drift != nil {
+						header = append(header, *entry.Drift)
+					}
+				}
+				fmt.Printf("%s\n\n", drift.Summary(header))
+			}
+			for _, entry := range entries {
+				line := fmt.Sprintf("%s %s", entry.Dependency, pinnedRef(dependencies[entry.Dependency]))
+				if entry.Pinned && !entry.PinUntil.IsZero() {
+					line += " (pinned until " + formatTime(entry.PinUntil) + ")"
+				} else if entry.Pinned {
+					line += " (pinned)"
+				}
+				if entry.History != nil {
+					line += ", checked " + formatTime(entry.History.LastChecked)
+					if entry.History.Previous != nil {
+						line += fmt.Sprintf(", updated %s from %s", formatTime(entry.History.LastApplied), entry.History.Previous.Tag)
+					}
+				}
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+}
+
+func reportHistoryCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "history",
+		Usage: "Summarizes what changed since a run: the versions applied, the checks that newly hold candidates and the new failures",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{Name: "since", Usage: "Run ID, date such as 2026-01-31, or RFC 3339 time to compare the latest run with", Required: true},
+		}, formatFlags...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			comparison, err := run.History.Compare(cmd.String("since"))
+			if err != nil {
+				return err
+			}
+			if printed, err := printFormatted(cmd, comparison); printed || err != nil {
+				return err
+			}
+			printComparison(os.Stdout, comparison)
+			return nil
+		},
+	}
+}
+
+// printComparison prints the text format of report history.
+func printComparison(out io.Writer, c *history.Comparison) {
+	from := "the start of the history"
+	if c.From != nil {
+		from = fmt.Sprintf("run %s (%s)", c.From.ID, formatTime(c.From.Time))
+	}
+	if c.To == nil {
+		fmt.Fprintf(out, "No runs since %s\n", from)
+	} else {
+		runs := "runs"
+		if c.Runs == 1 {
+			runs = "run"
+		}
+		fmt.Fprintf(out, "%d %s since %s, %d failed, latest %s (%s)\n", c.Runs, runs, from, c.FailedRuns, c.To.ID, formatTime(c.To.Time))
+	}
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintf(out, "\n%s:\n", title)
+		for _, line := range lines {
+			fmt.Fprintf(out, "  %s\n", line)
+		}
+	}
+	var lines []string
+	for _, change := range c.Applied {
+		lines = append(lines, fmt.Sprintf("%s %s -> %s", change.Dependency, change.From, change.To))
+	}
+	section("Applied", lines)
+	holds := func(holds []history.Hold) []string {
+		var lines []string
+		for _, hold := range holds {
+			lines = append(lines, fmt.Sprintf("%s %s held by %s: %s", hold.Dependency, hold.Version, hold.Check, hold.Detail))
+		}
+		return lines
+	}
+	section("Newly held", holds(c.NewlyHeld))
+	section("No longer held", holds(c.Released))
+	failures := func(failures []history.Failure) []string {
+		var lines []string
+		for _, failure := range failures {
+			if failure.Check != "" {
+				lines = append(lines, fmt.Sprintf("%s failed %s: %s", failure.Dependency, failure.Check, failure.Detail))
+			} else {
+				lines = append(lines, fmt.Sprintf("%s: %s", failure.Dependency, failure.Detail))
+			}
+		}
+		return lines
+	}
+	section("New failures", failures(c.NewFailures))
+	section("Recovered", failures(c.Recovered))
+	lines = nil
+	for _, event := range c.Pins {
+		line := fmt.Sprintf("%s %s %s", formatTime(event.Time), event.Action, event.Dependency)
+		if event.Action == "pin" {
+			line += " at " + history.Pin{Tag: event.Tag, Commit: event.Commit}.String()
+		}
+		lines = append(lines, line)
+	}
+	section("Pins", lines)
+}
+
+func explainCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "explain",
+		Usage:     "Explains which version is selected for a dependency and why",
+		ArgsUsage: "<dependency>",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			name, info, source, err := dependencyArg(cmd, run)
+			if err != nil {
+				return err
+			}
+			explanation, err := policy.Explain(ctx, source, name, info)
+			if err != nil {
+				return fmt.Errorf("error resolving %s: %s", name, err)
+			}
+
+			fmt.Printf("%s tracks %s of %s/%s", name, info.Tracking, info.Owner, info.Repo)
+			if info.Tracking == "branch" {
+				fmt.Printf(" branch %s", info.Branch)
+			}
+			if info.TagPrefix != "" {
+				fmt.Printf(" with tag prefix %s", info.TagPrefix)
+			}
+			fmt.Printf(", currently at %s\n", explanation.Current)
+			if info.Tracking != "branch" {
+				fmt.Printf("%d tags listed, %d match the prefix and tracking mode, %d are newer than the current one\n", explanation.Tags, explanation.Matched, explanation.Newer)
+			}
+			switch {
+			case info.Pinned:
+				fmt.Printf("Pinned, runs skip it until unpinned\n")
+			case explanation.Update != nil:
+				if released := policy.Released(explanation.Update.Info.Published, time.Now()); released != "" {
+					fmt.Printf("Selected %s, %s, %s\n", explanation.Selected, released, explanation.Update.Info.DiffUrl)
+				} else {
+					fmt.Printf("Selected %s, %s\n", explanation.Selected, explanation.Update.Info.DiffUrl)
+				}
+				switch explanation.Update.Info.Kind {
+				case version.KindPromotion:
+					fmt.Printf("Promotes the running release candidate %s to stable\n", explanation.Current)
+				case version.KindRetraction:
+					fmt.Printf("Replaces %s, which is retracted upstream\n", explanation.Current)
+				}
+			case explanation.Selected == "" && info.IsRetracted(explanation.Current):
+				fmt.Printf("No valid version found, keeping the current version although it is retracted upstream\n")
+			case explanation.Selected == "":
+				fmt.Printf("No valid upgrade found, keeping the current version\n")
+			default:
+				fmt.Printf("Up to date, %s is the highest valid version\n", explanation.Selected)
+			}
+			if component, ok := run.History.Components[name]; ok && !info.Pinned {
+				fmt.Printf("Last checked %s\n", formatTime(component.LastChecked))
+			}
+			return nil
+		},
+	}
+}
+
+func daemonCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "daemon",
+		Usage: "Runs update repeatedly until interrupted",
+		Flags: append([]cli.Flag{
+			&cli.DurationFlag{Name: "interval", Usage: "Time between runs", Value: time.Hour},
+			&cli.BoolFlag{Name: "digests", Usage: "Also compares the recorded image digests with the registry's every run, warning of re-pushed tags"},
+		}, registryFlags...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ticker := time.NewTicker(cmd.Duration("interval"))
+			defer ticker.Stop()
+			for {
+				runCtx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+				if err := update(runCtx, cmd, false); err != nil {
+					log.Printf("Error running updater: %s", err)
+				}
+				if cmd.Bool("digests") {
+					warnMutated(runCtx, cmd)
+				}
+				cancel()
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+}
+
+// warnMutated logs a warning for every image tag re-pushed since its digest was recorded.
+func warnMutated(ctx context.Context, cmd *cli.Command) {
+	err := forEachRepo(ctx, cmd, func(ctx context.Context, repo multirepo.Repo) error {
+		run, err := newRepoRun(ctx, cmd, repo, false)
+		if err != nil {
+			return err
+		}
+		_, findings, err := checkDigests(ctx, cmd, run)
+		if err != nil {
+			return err
+		}
+		for _, finding := range mutation.Mutated(findings) {
+			log.Printf("Warning: %s, review it and record the new digest with digests --repin", finding)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error checking image digests: %s", err)
+	}
+}
+
+// dependencyArg looks up the dependency named by the first argument and its source.
+func dependencyArg(cmd *cli.Command, run runner.Options) (string, *version.Info, sources.VersionSource, error) {
+	name := cmd.Args().First()
+	if name == "" {
+		return "", nil, nil, fmt.Errorf("%s needs a dependency, one of the keys of versions.json", cmd.Name)
+	}
+	dependencies, err := version.ReadDependencies(run.RepoPath)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	info, ok := dependencies[name]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("unknown dependency %s", name)
+	}
+	source, err := run.Sources.For(info.Source)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return name, info, source, nil
+}
+
+// tagCommit returns the commit tag points to.
+func tagCommit(ctx context.Context, source sources.VersionSource, info *version.Info, tag string) (string, error) {
+	tags, err := source.ListTags(ctx, info.Owner, info.Repo)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tags {
+		if t.Name == tag {
+			return t.Commit, nil
+		}
+	}
+	return "", fmt.Errorf("tag %s not found in %s/%s", tag, info.Owner, info.Repo)
+}
+
+// pinnedRef is the tag a dependency is at, or its commit for branch tracking.
+func pinnedRef(info *version.Info) string {
+	if info.Tag == "" {
+		return info.Commit
+	}
+	return info.Tag
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+var formatFlags = []cli.Flag{
+	&cli.StringFlag{Name: "format", Usage: "Output format: text, json, or template to render --template", Value: "text"},
+	&cli.StringFlag{Name: "template", Usage: `Go template rendered over the output with --format=template, such as '{{range .Updates}}{{.Repo}} {{.To}}{{"\n"}}{{end}}', or @<file> to read it from a file. The json, join and csv functions format values`},
+}
+
+// printFormatted prints v as the --format flag asks, and reports whether it did. The text
+// format is left to the command.
+func printFormatted(cmd *cli.Command, v any) (bool, error) {
+	format := cmd.String("format")
+	if cmd.Bool("json") {
+		format = "json"
+	}
+	switch format {
+	case "text":
+		return false, nil
+	case "json":
+		return true, printJSON(v)
+	case "template":
+		return true, printTemplate(cmd.String("template"), v)
+	}
+	return true, fmt.Errorf("unknown format %q, use text, json or template", format)
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+	"join": func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	// csv quotes its arguments as one CSV record, without the line break.
+	"csv": func(fields ...any) (string, error) {
+		var record []string
+		for _, field := range fields {
+			record = append(record, fmt.Sprint(field))
+		}
+		var b strings.Builder
+		w := csv.NewWriter(&b)
+		w.Write(record)
+		w.Flush()
+		return strings.TrimSuffix(b.String(), "\n"), w.Error()
+	},
+}
+
+func printTemplate(text string, v any) error {
+	if text == "" {
+		return fmt.Errorf("--format=template needs a --template")
+	}
+	if path, ok := strings.CutPrefix(text, "@"); ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading template %s: %s", path, err)
+		}
+		text = string(content)
+	}
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %s", err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, v); err != nil {
+		return fmt.Errorf("error rendering template: %s", err)
+	}
+	if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+		out.WriteString("\n")
+	}
+	_, err = fmt.Print(out.String())
+	return err
+}
+
+func selfUpdateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "self-update",
+		Usage: "Replaces this binary with the newest updater release",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "public-key",
+				Usage:   "Hex encoded ed25519 key the release checksums must be signed with, defaults to the key the binary was released with",
+				Sources: cli.EnvVars("UPDATER_RELEASE_PUBLIC_KEY"),
+				Value:   releasePublicKey,
+			},
+			&cli.BoolFlag{Name: "checksum-only", Usage: "Skips the signature check and trusts the release checksums alone"},
+			&cli.BoolFlag{Name: "check", Usage: "Only reports whether a newer release exists"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			var publicKey ed25519.PublicKey
+			if !cmd.Bool("checksum-only") {
+				key := cmd.String("public-key")
+				if key == "" {
+					return fmt.Errorf("no release public key in this build, set --public-key or pass --checksum-only")
+				}
+				decoded, err := hex.DecodeString(key)
+				if err != nil || len(decoded) != ed25519.PublicKeySize {
+					return fmt.Errorf("invalid --public-key, expected %d hex encoded bytes", ed25519.PublicKeySize)
+				}
+				publicKey = decoded
+			}
+			source, err := sources.NewSet(sources.Options{GithubToken: cmd.String("token")}).For(sources.Default)
+			if err != nil {
+				return err
+			}
+
+			current := ""
+			if buildVersion != "" {
+				current = selfupdate.TagPrefix + "/" + buildVersion
+			}
+			release, err := selfupdate.Latest(ctx, source, current)
+			if err != nil {
+				return fmt.Errorf("error checking for updater releases: %s", err)
+			}
+			if release == nil {
+				fmt.Printf("Already at the newest release %s\n", cmd.Root().Version)
+				return nil
+			}
+			if cmd.Bool("check") {
+				fmt.Printf("%s is available, running %s\n", release.Tag, cmd.Root().Version)
+				return nil
+			}
+			if publicKey == nil {
+				log.Printf("WARN --checksum-only set, %s is not checked for a signature", release.Tag)
+			}
+			binary, err := selfupdate.Download(ctx, release, publicKey)
+			if err != nil {
+				return err
+			}
+			executable, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("error locating the running binary: %s", err)
+			}
+			if err := selfupdate.Replace(executable, binary); err != nil {
+				return err
+			}
+			fmt.Printf("Updated %s to %s\n", executable, release.Tag)
+			return nil
+		},
+	}
+}
+
+func configCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Sets up the updater's configuration",
+		Commands: []*cli.Command{
+			{
+				Name:  "init",
+				Usage: "Scans the repository's env files, Dockerfiles and compose files for the components it builds and writes a starter versions.json",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "force", Usage: "Replaces an existing versions.json"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+					defer cancel()
+					run, err := newRun(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					if _, err := os.Stat(filepath.Join(run.RepoPath, "versions.json")); err == nil && !cmd.Bool("force") {
+						return fmt.Errorf("%s already has a versions.json, use import to add to it or --force to replace it", run.RepoPath)
+					}
+					scanned, err := importer.Scan(run.RepoPath)
+					if err != nil {
+						return err
+					}
+					for _, skipped := range scanned.Skipped {
+						log.Printf("Skipped %s", skipped)
+					}
+					if len(scanned.Entries) == 0 {
+						return fmt.Errorf("found no components in %s, add them to versions.json by hand", run.RepoPath)
+					}
+					entries := scanned.Entries
+					if !cmd.Bool("yes") {
+						if entries, err = importer.Prompt(os.Stdin, os.Stdout, entries); err != nil {
+							return err
+						}
+					}
+
+					dependencies := version.Dependencies{}
+					for _, entry := range entries {
+						if entry.Info.Commit == "" {
+							source, err := run.Sources.For(entry.Info.Source)
+							if err != nil {
+								return err
+							}
+							ref := cmp.Or(entry.Info.Tag, entry.Info.Branch)
+							if entry.Info.Commit, err = source.ResolveRef(ctx, entry.Info.Owner, entry.Info.Repo, ref); err != nil {
+								return fmt.Errorf("error resolving %s %s: %s", entry.Name, ref, err)
+							}
+						}
+						dependencies[entry.Name] = entry.Info
+					}
+					if len(dependencies) == 0 {
+						fmt.Println("No components selected, nothing written")
+						return nil
+					}
+					edits, err := targets.Apply(ctx, run.RepoPath, run.Targets, dependencies, run.DryRun)
+					if err != nil {
+						return fmt.Errorf("failed to write versions.json: %s", err)
+					}
+					return finish(ctx, cmd, run, &runner.Result{Edits: edits})
+				},
+			},
+		},
+	}
+}
+
+func doctorCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "doctor",
+		Usage: "Diagnoses the token, rate limit, registries, file permissions, git state and versions.json, printing a fix for each problem",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "json", Usage: "Prints the findings as JSON"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := githubClient(cmd)
+			if err != nil {
+				return err
+			}
+			findings := doctor.GitHub(ctx, client, cmd.String("token"))
+			dependencies, err := version.ReadDependencies(run.RepoPath)
+			if err != nil {
+				findings = append(findings, doctor.Finding{Check: "config", Status: "fail", Detail: err.Error(), Fix: "run config init to write a versions.json"})
+			} else {
+				findings = append(findings, doctor.Config(dependencies, func(source string) bool {
+					_, err := run.Sources.For(source)
+					return err == nil
+				})...)
+				findings = append(findings, doctor.Registries(ctx, http.DefaultClient, dependencies)...)
+			}
+			writes := []string{filepath.Join(run.RepoPath, "versions.json"), filepath.Join(run.RepoPath, "versions.env")}
+			for _, flag := range []string{"flux-dir", "argocd-dir"} {
+				if dir := cmd.String(flag); dir != "" {
+					writes = append(writes, dir)
+				}
+			}
+			historyFile := cmd.String("history-file")
+			if historyFile == "" {
+				historyFile = filepath.Join(run.RepoPath, ".dependency_updater", "history.json")
+			}
+			findings = append(findings, doctor.Writable(append(writes, historyFile))...)
+			findings = append(findings, doctor.Git(ctx, run.RepoPath, nil))
+
+			if cmd.Bool("json") {
+				if err := printJSON(findings); err != nil {
+					return err
+				}
+			} else {
+				for _, finding := range findings {
+					fmt.Println(finding)
+				}
+			}
+			if doctor.Failed(findings) {
+				return fmt.Errorf("doctor found problems, see the fixes above")
+			}
+			return nil
+		},
+	}
+}
+
+func importCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "Adds the dependencies tracked by a renovate.json or dependabot.yml to versions.json",
+		ArgsUsage: "<renovate.json|dependabot.yml>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Usage: "Configuration format (renovate, dependabot), detected from the file name if unset"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			path := cmd.Args().First()
+			if path == "" {
+				return fmt.Errorf("import needs the renovate.json or dependabot.yml to read")
+			}
+			config, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %s", path, err)
+			}
+
+			format := cmd.String("format")
+			if format == "" && strings.Contains(filepath.Base(path), "renovate") {
+				format = "renovate"
+			} else if format == "" && strings.Contains(filepath.Base(path), "dependabot") {
+				format = "dependabot"
+			}
+			var imported *importer.Result
+			switch format {
+			case "renovate":
+				imported, err = importer.Renovate(run.RepoPath, config)
+			case "dependabot":
+				imported, err = importer.Dependabot(run.RepoPath, config)
+			default:
+				return fmt.Errorf("unknown configuration format %q, set --format to renovate or dependabot", format)
+			}
+			if err != nil {
+				return err
+			}
+			for _, skipped := range imported.Skipped {
+				log.Printf("Skipped %s", skipped)
+			}
+
+			dependencies, err := version.ReadDependencies(run.RepoPath)
+			if err != nil {
+				return err
+			}
+			added := 0
+			for _, entry := range imported.Entries {
+				if _, ok := dependencies[entry.Name]; ok {
+					log.Printf("Skipped %s from %s, versions.json already has it", entry.Name, entry.Origin)
+					continue
+				}
+				source, err := run.Sources.For(entry.Info.Source)
+				if err != nil {
+					return err
+				}
+				if entry.Info.Commit, err = source.ResolveRef(ctx, entry.Info.Owner, entry.Info.Repo, entry.Info.Tag); err != nil {
+					return fmt.Errorf("error resolving %s %s: %s", entry.Name, entry.Info.Tag, err)
+				}
+				dependencies[entry.Name] = entry.Info
+				added++
+				fmt.Printf("%s %s/%s %s from %s\n", entry.Name, entry.Info.Owner, entry.Info.Repo, entry.Info.Tag, entry.Origin)
+			}
+			if added == 0 {
+				fmt.Println("Nothing to import")
+				return nil
+			}
+
+			edits, err := targets.Apply(ctx, run.RepoPath, run.Targets, dependencies, run.DryRun)
+			if err != nil {
+				return fmt.Errorf("failed to import: %s", err)
+			}
+			return finish(ctx, cmd, run, &runner.Result{Edits: edits})
+		},
+	}
+}
+
+func operatorCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "operator",
+		Usage: "Checks NodeComponent resources in a Kubernetes cluster and records available updates in their status",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "namespace", Usage: "Namespace to watch, all namespaces if unset"},
+			&cli.DurationFlag{Name: "interval", Usage: "Time between checks of every NodeComponent", Value: time.Hour},
+			&cli.StringFlag{Name: "api-server", Usage: "Kubernetes API server URL, the in-cluster service account is used if unset"},
+			&cli.StringFlag{Name: "kube-token", Usage: "Bearer token for --api-server", Sources: cli.EnvVars("KUBE_TOKEN")},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			set, err := newSources(cmd)
+			if err != nil {
+				return err
+			}
+			client := &operator.Client{Server: cmd.String("api-server"), Token: cmd.String("kube-token"), HTTP: http.DefaultClient}
+			if client.Server == "" {
+				if client, err = operator.InCluster(); err != nil {
+					return err
+				}
+			}
+			o := &operator.Operator{
+				Client:    client,
+				Sources:   set,
+				Namespace: cmd.String("namespace"),
+				Interval:  cmd.Duration("interval"),
+				Now:       time.Now,
+			}
+			return o.Run(ctx)
+		},
+	}
+}
+
+func publishCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "publish",
+		Usage:     "Pushes versions.json and versions.env to an OCI registry as an artifact",
+		ArgsUsage: "<registry/repository[:tag]>",
+		Description: "Without a tag the artifact is tagged versions-<digest of versions.json>, so each version set\n" +
+			"gets its own tag and republishing an unchanged set is a no-op.",
+		Flags: append([]cli.Flag{
+			&cli.StringSliceFlag{Name: "also-tag", Usage: "Additional tags to push the artifact under, such as latest"},
+		}, registryFlags...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			repo := cmd.String("repo")
+			if repo == "" {
+				return fmt.Errorf("publish needs --repo")
+			}
+			arg := cmd.Args().First()
+			ref, err := ociartifact.ParseReference(arg)
+			if err != nil {
+				return err
+			}
+			versionsJSON, err := os.ReadFile(filepath.Join(repo, "versions.json"))
+			if err != nil {
+				return fmt.Errorf("error reading versions JSON: %s", err)
+			}
+			versionsEnv, err := os.ReadFile(filepath.Join(repo, "versions.env"))
+			if err != nil {
+				return fmt.Errorf("error reading versions env: %s", err)
+			}
+			files := []ociartifact.File{
+				{Name: "versions.json", MediaType: ociartifact.VersionsJSONType, Content: versionsJSON},
+				{Name: "versions.env", MediaType: ociartifact.VersionsEnvType, Content: versionsEnv},
+			}
+			if !strings.Contains(arg[strings.LastIndex(arg, "/"):], ":") {
+				sum := sha256.Sum256(versionsJSON)
+				ref.Tag = "versions-" + hex.EncodeToString(sum[:])[:12]
+			}
+
+			pusher := &ociartifact.Pusher{
+				HTTP:      http.DefaultClient,
+				Username:  cmd.String("registry-username"),
+				Password:  cmd.String("registry-password"),
+				PlainHTTP: cmd.Bool("plain-http"),
+			}
+			now := time.Now()
+			for _, ref.Tag = range append([]string{ref.Tag}, cmd.StringSlice("also-tag")...) {
+				digest, err := pusher.Push(ctx, ref, files, now)
+				if err != nil {
+					return fmt.Errorf("error publishing %s: %s", ref, err)
+				}
+				fmt.Printf("%s@%s\n", ref, digest)
+			}
+			return nil
+		},
+	}
+}
+
+func verifyAttestationCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "verify-attestation",
+		Usage:     "Verifies the signature of an attestation written by --attestation and that the repository's files match it",
+		ArgsUsage: "<attestation>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "public-key", Usage: "Hex encoded ed25519 public key the attestation must be signed with", Required: true},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			key, err := hex.DecodeString(cmd.String("public-key"))
+			if err != nil || len(key) != ed25519.PublicKeySize {
+				return fmt.Errorf("--public-key must be a hex encoded ed25519 public key")
+			}
+			content, err := os.ReadFile(cmd.Args().First())
+			if err != nil {
+				return fmt.Errorf("error reading attestation: %s", err)
+			}
+			var envelope attestation.Envelope
+			if err := json.Unmarshal(content, &envelope); err != nil {
+				return fmt.Errorf("error decoding attestation: %s", err)
+			}
+			statement, err := attestation.Verify(&envelope, key)
+			if err != nil {
+				return err
+			}
+			if repo := cmd.String("repo"); repo != "" {
+				if err := attestation.VerifySubjects(statement, repo); err != nil {
+					return err
+				}
+			}
+			for _, input := range statement.Predicate.Inputs {
+				fmt.Printf("%s %s -> %s %s\n", input.Dependency, input.From, input.To, input.Digest["gitCommit"])
+			}
+			return nil
+		},
+	}
+}
+
+func flagDiffCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "flag-diff",
+		Usage:     "Lists the flags removed, added or with a changed default between the pinned and the selected version of a client",
+		ArgsUsage: "<dependency>",
+		Description: "Runs the --help of both versions' images, the dependency's \"image\" in versions.json tagged\n" +
+			"with each version, so it needs docker or podman and the images to be pullable.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "to", Usage: "Compares to this tag instead of the selected one"},
+			&cli.StringFlag{Name: "image", Usage: "Image to run instead of the dependency's image"},
+			&cli.StringFlag{Name: "container-command", Usage: "Command running the images", Value: "docker"},
+			&cli.StringSliceFlag{Name: "help-args", Usage: "Arguments printing the help, such as node,--help for reth", Value: []string{"--help"}},
+			&cli.BoolFlag{Name: "fail-on-removed", Usage: "Exits with an error if the selected version removed flags"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			name, info, source, err := dependencyArg(cmd, run)
+			if err != nil {
+				return err
+			}
+			image := cmp.Or(cmd.String("image"), info.Image)
+			if image == "" {
+				return fmt.Errorf("%s has no image in versions.json, set --image", name)
+			}
+			if info.Tag == "" {
+				return fmt.Errorf("%s tracks a branch, its images have no version tags", name)
+			}
+			to := version.SanitizeTag(cmd.String("to"))
+			if to == "" {
+				planned, err := policy.Resolve(ctx, source, name, info)
+				if err != nil {
+					return fmt.Errorf("error resolving %s: %s", name, err)
+				}
+				if planned == nil {
+					fmt.Printf("%s is up to date at %s\n", name, info.Tag)
+					return nil
+				}
+				to = planned.Version
+			}
+
+			reader := flagdiff.Reader{Command: cmd.String("container-command"), Args: cmd.StringSlice("help-args")}
+			current, err := reader.Read(ctx, image+":"+info.ImageTagOf(info.Tag))
+			if err != nil {
+				return fmt.Errorf("error reading the flags of %s: %s", info.Tag, err)
+			}
+			candidate, err := reader.Read(ctx, image+":"+info.ImageTagOf(to))
+			if err != nil {
+				return fmt.Errorf("error reading the flags of %s: %s", to, err)
+			}
+			changes := flagdiff.Diff(current, candidate)
+			if len(changes) == 0 {
+				fmt.Printf("%s %s -> %s: no flag changes\n", name, info.Tag, to)
+				return nil
+			}
+			removed := 0
+			fmt.Printf("%s %s -> %s:\n", name, info.Tag, to)
+			for _, change := range changes {
+				if change.Kind == "removed" {
+					removed++
+				}
+				fmt.Printf("  %s\n", change)
+			}
+			if removed > 0 && cmd.Bool("fail-on-removed") {
+				return fmt.Errorf("%s removes %d flags", to, removed)
+			}
+			return nil
+		},
+	}
+}
+
+func digestsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "digests",
+		Usage: "Compares the image digest recorded for each dependency's tag with the registry's, to catch tags re-pushed since",
+		Description: "Record the digests once with --record, then run it on a schedule or with daemon --digests.\n" +
+			"A re-pushed tag fails the command until its new digest is reviewed and recorded with --repin.",
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{Name: "record", Usage: "Records the current digest of the dependencies without one"},
+			&cli.BoolFlag{Name: "repin", Usage: "Records the new digest of re-pushed tags, once they were reviewed"},
+		}, append(registryFlags, formatFlags...)...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			dependencies, findings, err := checkDigests(ctx, cmd, run)
+			if err != nil {
+				return err
+			}
+			if printed, err := printFormatted(cmd, findings); err != nil {
+				return err
+			} else if !printed {
+				for _, finding := range findings {
+					fmt.Println(finding)
+				}
+			}
+
+			var planned []version.PlannedUpdate
+			for _, finding := range findings {
+				if (cmd.Bool("record") && finding.Recorded == "" && finding.Current != "") || (cmd.Bool("repin") && finding.Mutated()) {
+					info := dependencies[finding.Dependency]
+					planned = append(planned, version.PlannedUpdate{Dependency: finding.Dependency, Version: info.Tag, Commit: info.Commit, Pin: info.Pinned, PinUntil: info.PinnedUntil, ImageDigest: finding.Current})
+				}
+			}
+			if len(planned) > 0 {
+				result, err := runner.Apply(ctx, run, planned)
+				if err != nil {
+					return fmt.Errorf("failed to record the digests: %s", err)
+				}
+				if err := finish(ctx, cmd, run, result); err != nil {
+					return err
+				}
+			}
+			if mutated := mutation.Mutated(findings); len(mutated) > 0 && !cmd.Bool("repin") {
+				return fmt.Errorf("%d image tags were re-pushed since their digests were recorded, review them and record the new digests with --repin", len(mutated))
+			}
+			return nil
+		},
+	}
+}
+
+// checkDigests compares the recorded image digests of the run's dependencies with the
+// registry's.
+func checkDigests(ctx context.Context, cmd *cli.Command, run runner.Options) (version.Dependencies, []mutation.Finding, error) {
+	dependencies, err := version.ReadDependencies(run.RepoPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	selected := version.Dependencies{}
+	for name, info := range dependencies {
+		if run.Selects(name) {
+			selected[name] = info
+		}
+	}
+	registry := &ociartifact.Pusher{
+		HTTP:      http.DefaultClient,
+		Username:  cmd.String("registry-username"),
+		Password:  cmd.String("registry-password"),
+		PlainHTTP: cmd.Bool("plain-http"),
+	}
+	return dependencies, mutation.Check(ctx, registry, selected), nil
+}
+
+func simulateCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "simulate",
+		Usage:     "Runs the selection policies and checks against hypothetical upstream releases from a scenario file, without changing any files",
+		ArgsUsage: "<scenario.yaml>",
+		Description: "The scenario lists releases that don't exist yet, which the dependencies' sources list before the upstream tags:\n\n" +
+			"  offline: false\n" +
+			"  releases:\n" +
+			"    - dependency: op_node\n" +
+			"      tag: op-node/v1.17.0-rc.1\n" +
+			"      published: 2026-11-01\n" +
+			"      prerelease: true\n" +
+			"      notes: \"Breaking: removes the --l2.enginekind flag\"\n\n" +
+			"Commit defaults to a made-up one. Offline scenarios leave out everything upstream, so dependencies only have their current tag and the scenario's releases.",
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{Name: "offline", Usage: "Leaves out the upstream tags and releases, same as offline: true in the scenario"},
+		}, formatFlags...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			path := cmd.Args().First()
+			if path == "" {
+				return fmt.Errorf("simulate needs a scenario file")
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("error reading scenario: %s", err)
+			}
+			scenario, err := simulate.ParseScenario(content)
+			if err != nil {
+				return fmt.Errorf("error parsing %s: %s", path, err)
+			}
+			scenario.Offline = scenario.Offline || cmd.Bool("offline")
+
+			run, err := newRun(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			dependencies, err := version.ReadDependencies(run.RepoPath)
+			if err != nil {
+				return err
+			}
+			if err := scenario.Inject(run.Sources, dependencies); err != nil {
+				return err
+			}
+			// A simulation checks every dependency and records nothing.
+			run.DryRun, run.Force = true, true
+			run.History, run.Checkpoint = nil, nil
+			result, err := runner.Run(ctx, run)
+			if err != nil {
+				return fmt.Errorf("simulation failed: %s", err)
+			}
+			if cmd.String("format") == "text" {
+				fmt.Printf("Simulated %d releases\n", len(scenario.Releases))
+				for _, rationale := range result.Rationale {
+					fmt.Printf("%s: %s\n", rationale.Dependency, rationale)
+				}
+			}
+			return printCheck(cmd, run, result)
+		},
+	}
+}
+
+// githubClient is a client of the GitHub API at --github-api-url, authenticated with --token.
+func githubClient(cmd *cli.Command) (*github.Client, error) {
+	client := github.NewClient(nil).WithAuthToken(cmd.String("token"))
+	baseURL, err := url.Parse(strings.TrimSuffix(cmd.String("github-api-url"), "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid --github-api-url: %s", err)
+	}
+	client.BaseURL = baseURL
+	return client, nil
+}
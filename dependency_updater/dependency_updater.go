@@ -1,56 +1,77 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
 	"context"
+	"crypto/ed25519"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"slices"
+	"io"
 	"time"
 
-	"github.com/ethereum-optimism/optimism/op-service/retry"
-	"github.com/google/go-github/v72/github"
+	"github.com/base/node/dependency_updater/pkg/attestation"
+	"github.com/base/node/dependency_updater/pkg/breaking"
+	"github.com/base/node/dependency_updater/pkg/chaos"
+	"github.com/base/node/dependency_updater/pkg/cilog"
+	"github.com/base/node/dependency_updater/pkg/consensus"
+	"github.com/base/node/dependency_updater/pkg/history"
+	"github.com/base/node/dependency_updater/pkg/license"
+	"github.com/base/node/dependency_updater/pkg/multirepo"
+	"github.com/base/node/dependency_updater/pkg/ociartifact"
+	"github.com/base/node/dependency_updater/pkg/plugins"
+	"github.com/base/node/dependency_updater/pkg/provenance"
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/targets"
+	"github.com/base/node/dependency_updater/pkg/version"
+	"github.com/base/node/dependency_updater/pkg/vulnscan"
 	"github.com/urfave/cli/v3"
 
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
 )
 
-type Info struct {
-	Tag       string `json:"tag,omitempty"`
-	Commit    string `json:"commit"`
-	TagPrefix string `json:"tagPrefix,omitempty"`
-	Owner     string `json:"owner"`
-	Repo      string `json:"repo"`
-	Branch    string `json:"branch,omitempty"`
-	Tracking  string `json:"tracking"`
-}
+// buildVersion is the dependency_updater/vX.Y.Z tag the binary was built from, set by the
+// release workflow with -ldflags "-X main.buildVersion=...". Development builds leave it empty.
+var buildVersion = ""
 
-type VersionUpdateInfo struct {
-	Repo    string
-	From    string
-	To      string
-	DiffUrl string
-}
-
-type Dependencies = map[string]*Info
+// releasePublicKey is the hex encoded ed25519 key release checksums are signed with, set by the
+// release workflow like buildVersion. self-update requires it, or --public-key, to verify releases.
+var releasePublicKey = ""
 
 func main() {
 	cmd := &cli.Command{
-		Name:  "updater",
-		Usage: "Updates the dependencies in the geth, nethermind and reth Dockerfiles",
+		Name:    "updater",
+		Usage:   "Updates the dependencies in the geth, nethermind and reth Dockerfiles",
+		Version: cmp.Or(buildVersion, "dev"),
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "token",
-				Usage:    "Auth token used to make requests to the Github API must be set using export",
-				Sources:  cli.EnvVars("GITHUB_TOKEN"),
-				Required: true,
+				Name:    "token",
+				Usage:   "Auth token used to make requests to the Github API must be set using export",
+				Sources: cli.EnvVars("GITHUB_TOKEN"),
+			},
+			&cli.StringFlag{
+				Name:  "github-api-url",
+				Usage: "Base URL of the GitHub API, for GitHub Enterprise Server or a mock server",
+				Value: "https://api.github.com/",
 			},
 			&cli.StringFlag{
-				Name:     "repo",
-				Usage:    "Specifies repo location to run the version updater on",
-				Required: true,
+				Name:  "repo",
+				Usage: "Specifies repo location to run the version updater on, required by all commands but self-update, operator and verify-attestation",
+			},
+			&cli.StringFlag{
+				Name:  "repos",
+				Usage: "JSON file listing the repositories update and daemon manage, each with its own targets, filters and pull request, instead of the one of --repo",
 			},
 			&cli.BoolFlag{
 				Name:     "commit",
@@ -62,327 +83,607 @@ func main() {
 				Usage:    "Specifies whether tool is being used through github action workflow",
 				Required: false,
 			},
+			&cli.StringFlag{
+				Name:  "ci",
+				Usage: "Folds the log of each dependency with the CI provider's group syntax and ends with a summary: github or gitlab, github with --github-action",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Prints the planned edits to the version files without writing them",
+			},
+			&cli.StringFlag{
+				Name:  "same-version",
+				Usage: "What runs do about dependencies resolving to their current version: noop, warn or error, which fails the run before applying anything",
+				Value: "noop",
+			},
+			&cli.BoolFlag{
+				Name:  "yes",
+				Usage: "Writes without asking: skips the diff and confirmation runs in a terminal show before writing the version files, and the questions of config init",
+			},
+			&cli.StringFlag{
+				Name:    "plugins-dir",
+				Usage:   "Directory searched for updater-* plugin executables, defaults to <repo>/dependency_updater/plugins",
+				Sources: cli.EnvVars("UPDATER_PLUGINS_DIR"),
+			},
+			&cli.BoolFlag{
+				Name:     "preflight",
+				Usage:    "Runs preflight checks against the live node before applying updates",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint used by the preflight checks",
+				Value: "http://localhost:7545",
+			},
+			&cli.StringFlag{
+				Name:  "data-dir",
+				Usage: "Chain data directory checked for disk headroom by the preflight checks",
+			},
+			&cli.Uint64Flag{
+				Name:  "min-free-disk",
+				Usage: "Minimum free space in GiB on the data directory volume required by the preflight checks",
+				Value: 100,
+			},
+			&cli.Uint64Flag{
+				Name:  "max-behind",
+				Usage: "Maximum number of blocks the node may be behind the head for the preflight checks to pass",
+				Value: 300,
+			},
+			&cli.DurationFlag{
+				Name:  "hardfork-window",
+				Usage: "Preflight checks fail if a hardfork activates within this duration",
+				Value: 48 * time.Hour,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Aborts a run, rolling back any applied edits, after this duration",
+				Value: 15 * time.Minute,
+			},
+			&cli.StringSliceFlag{
+				Name:  "source-timeout",
+				Usage: "Timeout for each version source call, either a duration for all sources or <source>=<duration>",
+				Value: []string{"1m"},
+			},
+			&cli.StringSliceFlag{
+				Name:    "rate-limit",
+				Usage:   "Request budget of a version source shared by all its calls, <source>=<count>/<s|m|h>",
+				Value:   []string{"github=5000/h"},
+				Sources: cli.EnvVars("UPDATER_RATE_LIMITS"),
+			},
+			&cli.StringFlag{
+				Name:    "cache-dir",
+				Usage:   "Directory caching fetched tags and releases between runs, defaults to the user cache directory",
+				Sources: cli.EnvVars("UPDATER_CACHE_DIR"),
+			},
+			&cli.StringSliceFlag{
+				Name:  "cache-ttl",
+				Usage: "How long cached tags and releases are used, either a duration for all sources or <source>=<duration>, 0 disables the cache",
+				Value: []string{"1h"},
+			},
+			&cli.StringFlag{
+				Name:  "history-file",
+				Usage: "File recording when each dependency was last checked, defaults to <repo>/.dependency_updater/history.json",
+			},
+			&cli.DurationFlag{
+				Name:  "check-interval",
+				Usage: "Minimum time between checks of a dependency without a checkInterval in versions.json, 0 checks every run",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Checks every dependency regardless of when it was last checked",
+			},
+			&cli.StringSliceFlag{
+				Name:  "breaking-marker",
+				Usage: "Phrases in release notes that put an update in the high risk tier, which needs approval",
+				Value: breaking.DefaultMarkers,
+			},
+			&cli.StringFlag{
+				Name:  "vuln-scanner",
+				Usage: "Scans the images of candidates of dependencies with an \"image\" using trivy or grype",
+			},
+			&cli.StringFlag{
+				Name:  "vuln-threshold",
+				Usage: "Holds candidates adding vulnerabilities of this severity or above, such as CRITICAL, only reports them if unset",
+			},
+			&cli.StringFlag{
+				Name:  "slsa-verifier",
+				Usage: "Verifies the provenance signature of candidates with this slsa-verifier command, provenance is only reported unverified if unset",
+			},
+			&cli.StringFlag{
+				Name:  "attestation",
+				Usage: "Writes a signed in-toto attestation of the applied updates to this file",
+			},
+			&cli.StringFlag{
+				Name:    "attestation-key",
+				Usage:   "Hex encoded ed25519 seed --attestation is signed with",
+				Sources: cli.EnvVars("UPDATER_ATTESTATION_KEY"),
+			},
+			&cli.StringFlag{
+				Name:  "checkpoint-file",
+				Usage: "File recording the progress of a run so an interrupted one resumes, defaults to <repo>/.dependency_updater/checkpoint.json",
+			},
+			&cli.BoolFlag{
+				Name:  "fresh",
+				Usage: "Discards the checkpoint of an interrupted run instead of resuming it",
+			},
+			&cli.StringFlag{
+				Name:  "flux-dir",
+				Usage: "Directory of Kubernetes manifests whose Flux $imagepolicy markers are updated along with versions.json",
+			},
+			&cli.StringFlag{
+				Name:  "argocd-dir",
+				Usage: "Directory of ArgoCD Applications whose annotated targetRevision and helm parameters are updated along with versions.json",
+			},
+			&cli.BoolFlag{
+				Name:  "refresh",
+				Usage: "Fetches tags and releases again instead of using cached ones",
+			},
+			&cli.StringFlag{
+				Name:   "chaos",
+				Usage:  "Injects faults into source responses for testing, comma separated <fault>[=<delay>][@<path>][:<probability>|:<times>x] rules of latency, 429, truncate and malformed",
+				Hidden: true,
+			},
+			&cli.Uint64Flag{
+				Name:   "chaos-seed",
+				Usage:  "Seed of the probabilistic --chaos faults, to reproduce a run",
+				Hidden: true,
+			},
+			&cli.StringSliceFlag{
+				Name:  "only",
+				Usage: "Glob of the dependencies check, update and report cover, such as 'op-*', hyphens matching underscores. Can be repeated",
+			},
+			&cli.StringSliceFlag{
+				Name:  "skip",
+				Usage: "Glob of dependencies check, update and report leave out. Can be repeated",
+			},
 		},
+		Commands: []*cli.Command{
+			checkCommand(),
+			updateCommand(),
+			compareCommand(),
+			pinCommand(),
+			unpinCommand(),
+			diffCommand(),
+			waitCommand(),
+			rollbackCommand(),
+			reportCommand(),
+			explainCommand(),
+			daemonCommand(),
+			selfUpdateCommand(),
+			importCommand(),
+			configCommand(),
+			doctorCommand(),
+			operatorCommand(),
+			publishCommand(),
+			verifyAttestationCommand(),
+			flagDiffCommand(),
+			digestsCommand(),
+			simulateCommand(),
+		},
+		// Without a command the updater updates, as it did before it had commands, so
+		// scheduled callers of the old form keep updating.
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			err := updater(cmd.String("token"), cmd.String("repo"), cmd.Bool("commit"), cmd.Bool("github-action"))
-			if err != nil {
+			if cmd.Args().Present() {
+				return fmt.Errorf("unknown command %s, run updater --help for the commands", cmd.Args().First())
+			}
+			log.Printf("Running the updater without a command is deprecated, run updater update instead")
+			ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+			defer cancel()
+			if err := update(ctx, cmd, true); err != nil {
 				return fmt.Errorf("failed to run updater: %s", err)
 			}
 			return nil
 		},
 	}
 
-	if err := cmd.Run(context.Background(), os.Args); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := cmd.Run(ctx, os.Args); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func updater(token string, repoPath string, commit bool, githubAction bool) error {
-	var err error
-	var dependencies Dependencies
-	var updatedDependencies []VersionUpdateInfo
-
-	f, err := os.ReadFile(repoPath + "/versions.json")
-	if err != nil {
-		return fmt.Errorf("error reading versions JSON: %s", err)
+// newRun builds the options shared by all commands from the root flags: the sources with
+// their timeouts and cache, the built-in and plugin targets, notifiers and the history.
+func newRun(ctx context.Context, cmd *cli.Command) (runner.Options, error) {
+	if cmd.String("repos") != "" {
+		return runner.Options{}, fmt.Errorf("%s works on one repository, use --repo instead of --repos", cmd.Name)
+	}
+	if cmd.String("repo") == "" {
+		return runner.Options{}, fmt.Errorf("%s needs --repo", cmd.Name)
 	}
+	return newRepoRun(ctx, cmd, flagRepo(cmd), true)
+}
 
-	client := github.NewClient(nil).WithAuthToken(token)
-	ctx := context.Background()
+// flagRepo is the repository of --repo, with the targets and filters of the root flags.
+func flagRepo(cmd *cli.Command) multirepo.Repo {
+	return multirepo.Repo{
+		Name:      cmd.String("repo"),
+		Path:      cmd.String("repo"),
+		FluxDir:   cmd.String("flux-dir"),
+		ArgoCDDir: cmd.String("argocd-dir"),
+		Only:      cmd.StringSlice("only"),
+		Skip:      cmd.StringSlice("skip"),
+	}
+}
 
-	err = json.Unmarshal(f, &dependencies)
-	if err != nil {
-		return fmt.Errorf("error unmarshalling versions JSON to dependencies: %s", err)
-	}
-
-	for dependency := range dependencies {
-		var updatedDependency VersionUpdateInfo
-		err := retry.Do0(context.Background(), 3, retry.Fixed(1*time.Second), func() error {
-			updatedDependency, err = getAndUpdateDependency(
-				ctx,
-				client,
-				dependency,
-				repoPath,
-				dependencies,
-			)
-			return err
-		})
-		if err != nil {
-			return fmt.Errorf("error getting and updating version/commit for "+dependency+": %s", err)
+// forEachRepo calls f with each repository of --repos, or with the one of --repo. A failing
+// repository doesn't stop the others, their errors are returned together.
+func forEachRepo(ctx context.Context, cmd *cli.Command, f func(ctx context.Context, repo multirepo.Repo) error) error {
+	path := cmd.String("repos")
+	if path == "" {
+		if cmd.String("repo") == "" {
+			return fmt.Errorf("%s needs --repo or --repos", cmd.Name)
 		}
-
-		if updatedDependency != (VersionUpdateInfo{}) {
-			updatedDependencies = append(updatedDependencies, updatedDependency)
+		return f(ctx, flagRepo(cmd))
+	}
+	for _, flag := range []string{"repo", "history-file", "checkpoint-file", "attestation", "github-action"} {
+		if cmd.IsSet(flag) {
+			return fmt.Errorf("--%s can't be combined with --repos, set it up per repository in the repos file", flag)
 		}
 	}
-
-	e := createVersionsEnv(repoPath, dependencies)
-	if e != nil {
-		return fmt.Errorf("error creating versions.env: %s", e)
+	config, err := multirepo.Load(path)
+	if err != nil {
+		return err
 	}
-
-	if (commit && updatedDependencies != nil) || (githubAction && updatedDependencies != nil) {
-		err := createCommitMessage(updatedDependencies, repoPath, githubAction)
-		if err != nil {
-			return fmt.Errorf("error creating commit message: %s", err)
+	var errs []error
+	for _, repo := range config.Repos {
+		if len(repo.Only) == 0 {
+			repo.Only = cmd.StringSlice("only")
+		}
+		if len(repo.Skip) == 0 {
+			repo.Skip = cmd.StringSlice("skip")
+		}
+		log.Printf("Repository %s at %s", repo.Name, repo.Path)
+		if err := f(ctx, repo); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.Name, err))
 		}
 	}
-
-	return nil
+	return errors.Join(errs...)
 }
 
-func createCommitMessage(updatedDependencies []VersionUpdateInfo, repoPath string, githubAction bool) error {
-	var repos []string
-	descriptionLines := []string{
-		"### Dependency Updates",
+// newRepoRun builds the options of a run on repo from the root flags, see newRun. Only
+// interactive runs, those of one-shot commands, ask before writing when run in a terminal.
+func newRepoRun(ctx context.Context, cmd *cli.Command, repo multirepo.Repo, interactive bool) (runner.Options, error) {
+	preflight := runner.PreflightOptions{
+		Enabled:        cmd.Bool("preflight"),
+		NodeRPC:        cmd.String("node-rpc"),
+		DataDir:        cmd.String("data-dir"),
+		MinFreeDisk:    cmd.Uint64("min-free-disk"),
+		MaxBehind:      cmd.Uint64("max-behind"),
+		HardforkWindow: cmd.Duration("hardfork-window"),
+	}
+	set, err := newSources(cmd)
+	if err != nil {
+		return runner.Options{}, err
 	}
 
-	commitTitle := "chore: updated "
+	pluginsDir := cmd.String("plugins-dir")
+	if pluginsDir == "" {
+		pluginsDir = repo.Path + "/dependency_updater/plugins"
+	}
+	loaded, err := plugins.Load(ctx, pluginsDir)
+	if err != nil {
+		return runner.Options{}, err
+	}
+	set.Add(loaded.Sources)
 
-	for _, dependency := range updatedDependencies {
-		repo, tag := dependency.Repo, dependency.To
-		descriptionLines = append(descriptionLines, fmt.Sprintf("**%s** - %s:  [diff](%s)", repo, tag, dependency.DiffUrl))
-		repos = append(repos, repo)
+	historyFile := cmd.String("history-file")
+	if historyFile == "" {
+		historyFile = filepath.Join(repo.Path, ".dependency_updater", "history.json")
+	}
+	db, err := history.Open(historyFile)
+	if err != nil {
+		return runner.Options{}, err
 	}
-	commitDescription := strings.Join(descriptionLines, "\n")
-	commitTitle += strings.Join(repos, ", ")
 
-	if githubAction {
-		err := writeToGithubOutput(commitTitle, commitDescription, repoPath)
-		if err != nil {
-			return fmt.Errorf("error creating git commit message: %s", err)
-		}
-	} else {
-		cmd := exec.Command("git", "commit", "-am", commitTitle, "-m", commitDescription)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to run git commit -m: %s", err)
+	checkpointFile := cmd.String("checkpoint-file")
+	if checkpointFile == "" {
+		checkpointFile = filepath.Join(repo.Path, ".dependency_updater", "checkpoint.json")
+	}
+	if cmd.Bool("fresh") && !cmd.Bool("dry-run") {
+		if err := os.Remove(checkpointFile); err != nil && !os.IsNotExist(err) {
+			return runner.Options{}, fmt.Errorf("error removing checkpoint: %s", err)
 		}
 	}
-	return nil
-}
-
-func getAndUpdateDependency(ctx context.Context, client *github.Client, dependencyType string, repoPath string, dependencies Dependencies) (VersionUpdateInfo, error) {
-	version, commit, updatedDependency, err := getVersionAndCommit(ctx, client, dependencies, dependencyType)
+	// A repository without a versions.json yet is one config init sets up.
+	manifest, err := os.ReadFile(filepath.Join(repo.Path, "versions.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return runner.Options{}, fmt.Errorf("error reading versions JSON: %s", err)
+	}
+	checkpoint, err := history.OpenCheckpoint(checkpointFile, manifest, time.Now())
 	if err != nil {
-		return VersionUpdateInfo{}, err
+		return runner.Options{}, err
+	}
+
+	checks := []runner.Check{
+		provenance.Check{Verifier: cmd.String("slsa-verifier")},
+		license.Check{},
+		breaking.Check{Markers: cmd.StringSlice("breaking-marker")},
+		consensus.Check{Sources: set, Registry: &ociartifact.Pusher{HTTP: http.DefaultClient}},
 	}
-	if updatedDependency != (VersionUpdateInfo{}) {
-		e := updateVersionTagAndCommit(commit, version, dependencyType, repoPath, dependencies)
-		if e != nil {
-			return VersionUpdateInfo{}, fmt.Errorf("error updating version tag and commit: %s", e)
+	if scanner := cmd.String("vuln-scanner"); scanner != "" {
+		threshold := strings.ToUpper(cmd.String("vuln-threshold"))
+		if threshold != "" && !slices.Contains(vulnscan.Severities, threshold) {
+			return runner.Options{}, fmt.Errorf("invalid --vuln-threshold %s, expected one of %s", threshold, strings.Join(vulnscan.Severities, ", "))
 		}
+		checks = append(checks, vulnscan.Check{Scanner: vulnscan.Scanner{Command: scanner}, Threshold: threshold})
 	}
 
-	return updatedDependency, nil
-}
+	if err := runner.ValidateGlobs(slices.Concat(repo.Only, repo.Skip)); err != nil {
+		return runner.Options{}, err
+	}
 
-func getVersionAndCommit(ctx context.Context, client *github.Client, dependencies Dependencies, dependencyType string) (string, string, VersionUpdateInfo, error) {
-	var selectedTag *github.RepositoryTag
-	var commit string
-	var diffUrl string
-	var updatedDependency VersionUpdateInfo
-	options := &github.ListOptions{Page: 1}
-	currentTag := dependencies[dependencyType].Tag
-	tagPrefix := dependencies[dependencyType].TagPrefix
-
-	if dependencies[dependencyType].Tracking == "tag" || dependencies[dependencyType].Tracking == "release" {
-		// Collect all valid tags across all pages, then find the max version
-		var validTags []*github.RepositoryTag
-		trackingMode := dependencies[dependencyType].Tracking
-
-		for {
-			tags, resp, err := client.Repositories.ListTags(
-				ctx,
-				dependencies[dependencyType].Owner,
-				dependencies[dependencyType].Repo,
-				options)
-
-			if err != nil {
-				return "", "", VersionUpdateInfo{}, fmt.Errorf("error getting tags: %s", err)
-			}
+	if mode := cmd.String("same-version"); !slices.Contains(runner.SameVersionModes, mode) {
+		return runner.Options{}, fmt.Errorf("invalid --same-version %s, expected one of %s", mode, strings.Join(runner.SameVersionModes, ", "))
+	}
 
-			for _, tag := range tags {
-				// Skip if tagPrefix is set and doesn't match
-				if tagPrefix != "" && !strings.HasPrefix(*tag.Name, tagPrefix) {
-					continue
-				}
-
-				// Filter based on tracking mode:
-				// - "release": only stable releases (no prerelease suffix)
-				// - "tag": releases and RC versions only (exclude -synctest, -alpha, etc.)
-				if trackingMode == "release" {
-					if !IsReleaseVersion(*tag.Name, tagPrefix) {
-						continue
-					}
-				} else if trackingMode == "tag" {
-					if !IsReleaseOrRCVersion(*tag.Name, tagPrefix) {
-						continue
-					}
-				}
-
-				// Check if this is a valid upgrade (not a downgrade)
-				if err := ValidateVersionUpgrade(currentTag, *tag.Name, tagPrefix); err != nil {
-					continue
-				}
-
-				validTags = append(validTags, tag)
-			}
+	var group func(name string) func()
+	if provider := ciProvider(cmd); provider != "" {
+		if !slices.Contains(cilog.Providers, provider) {
+			return runner.Options{}, fmt.Errorf("invalid --ci %s, expected one of %s", provider, strings.Join(cilog.Providers, ", "))
+		}
+		// CI logs carry their own timestamps.
+		log.SetFlags(0)
+		group = func(name string) func() { return cilog.Group(os.Stderr, provider, name) }
+	}
 
-			if resp.NextPage == 0 {
-				break
-			}
-			options.Page = resp.NextPage
+	var confirm func(ctx context.Context, edits []targets.Edit) error
+	if interactive && !cmd.Bool("yes") && ciProvider(cmd) == "" && isTerminal(os.Stdin) {
+		confirm = func(ctx context.Context, edits []targets.Edit) error {
+			return confirmEdits(os.Stdin, os.Stdout, edits)
 		}
+	}
 
-		// Find the maximum version among valid tags
-		for _, tag := range validTags {
-			// Skip if this tag can't be parsed
-			if _, err := ParseVersion(*tag.Name, tagPrefix); err != nil {
-				log.Printf("Skipping unparseable tag %s: %v", *tag.Name, err)
-				continue
-			}
+	runTargets := append(targets.Defaults(set), loaded.Targets...)
+	if dir := repo.FluxDir; dir != "" {
+		runTargets = append(runTargets, targets.FluxManifests{Dir: dir})
+	}
+	if dir := repo.ArgoCDDir; dir != "" {
+		runTargets = append(runTargets, targets.ArgoApplications{Dir: dir})
+	}
 
-			if selectedTag == nil {
-				selectedTag = tag
-				continue
-			}
+	return runner.Options{
+		RepoPath:      repo.Path,
+		Sources:       set,
+		Targets:       runTargets,
+		Notifiers:     loaded.Notifiers,
+		DryRun:        cmd.Bool("dry-run"),
+		Preflight:     preflight,
+		History:       db,
+		CheckInterval: cmd.Duration("check-interval"),
+		Force:         cmd.Bool("force"),
+		Checkpoint:    checkpoint,
+		Checks:        checks,
+		Only:          repo.Only,
+		Skip:          repo.Skip,
+		Group:         group,
+		Confirm:       confirm,
+		SameVersion:   cmd.String("same-version"),
+	}, nil
+}
 
-			cmp, err := CompareVersions(*tag.Name, *selectedTag.Name, tagPrefix)
-			if err != nil {
-				log.Printf("Error comparing versions %s and %s: %v", *tag.Name, *selectedTag.Name, err)
-				continue
-			}
-			if cmp > 0 {
-				selectedTag = tag
-			}
-		}
+var errNotConfirmed = errors.New("edits not confirmed, nothing written")
 
-		// If no valid version found, keep current version
-		if selectedTag == nil {
-			log.Printf("No valid upgrade found for %s, keeping %s", dependencyType, currentTag)
-			return currentTag, dependencies[dependencyType].Commit, VersionUpdateInfo{}, nil
-		}
+// confirmEdits prints the diff of the edits on out and asks for confirmation on in.
+func confirmEdits(in io.Reader, out io.Writer, edits []targets.Edit) error {
+	printEdits(out, edits)
+	fmt.Fprint(out, "Write these changes? [y/N]: ")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		return errNotConfirmed
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
 
-		if *selectedTag.Name != currentTag {
-			diffUrl = generateGithubRepoUrl(dependencies, dependencyType) + "/compare/" +
-				currentTag + "..." + *selectedTag.Name
+func printEdits(out io.Writer, edits []targets.Edit) {
+	for _, edit := range edits {
+		fmt.Fprintf(out, "--- %s (%s)\n", edit.Path, edit.Target)
+		for _, line := range targets.DiffLines(string(edit.Before), string(edit.After)) {
+			fmt.Fprintln(out, line)
 		}
+	}
+}
 
-		// Get commit SHA from the tag
-		commit = *selectedTag.Commit.SHA
+// ciProvider is the provider of --ci, github for GitHub Actions runs.
+func ciProvider(cmd *cli.Command) string {
+	if provider := cmd.String("ci"); provider != "" || !cmd.Bool("github-action") {
+		return provider
 	}
+	return "github"
+}
 
-	if diffUrl != "" {
-		updatedDependency = VersionUpdateInfo{
-			dependencies[dependencyType].Repo,
-			dependencies[dependencyType].Tag,
-			*selectedTag.Name,
-			diffUrl,
-		}
+// printCISummary prints the summary lines of a run with --ci on stderr, after its groups.
+func printCISummary(cmd *cli.Command, run runner.Options, result *runner.Result) {
+	if ciProvider(cmd) == "" {
+		return
+	}
+	for _, line := range cilog.Summary(result, run.DryRun) {
+		fmt.Fprintln(os.Stderr, line)
 	}
+}
 
-	if dependencies[dependencyType].Tracking == "branch" {
-		branchCommit, _, err := client.Repositories.ListCommits(
-			ctx,
-			dependencies[dependencyType].Owner,
-			dependencies[dependencyType].Repo,
-			&github.CommitsListOptions{
-				SHA: dependencies[dependencyType].Branch,
-			},
-		)
-		if err != nil {
-			return "", "", VersionUpdateInfo{}, fmt.Errorf("error listing commits for "+dependencyType+": %s", err)
+// newSources builds the version sources from the root flags, with their timeouts, rate limits
+// and cache.
+func newSources(cmd *cli.Command) (*sources.Set, error) {
+	timeout, timeouts, err := sources.ParseDurations(cmd.StringSlice("source-timeout"))
+	if err != nil {
+		return nil, err
+	}
+	cacheTTL, cacheTTLs, err := sources.ParseDurations(cmd.StringSlice("cache-ttl"))
+	if err != nil {
+		return nil, err
+	}
+	rateLimits, err := sources.ParseRates(cmd.StringSlice("rate-limit"))
+	if err != nil {
+		return nil, err
+	}
+	cacheDir := cmd.String("cache-dir")
+	if cacheDir == "" {
+		if userCache, err := os.UserCacheDir(); err == nil {
+			cacheDir = filepath.Join(userCache, "base-dependency-updater")
 		}
-		commit = *branchCommit[0].SHA
-		if dependencies[dependencyType].Commit != commit {
-			from, to := dependencies[dependencyType].Commit, commit
-			diffUrl = fmt.Sprintf("%s/compare/%s...%s", generateGithubRepoUrl(dependencies, dependencyType), from, to)
-			updatedDependency = VersionUpdateInfo{
-				dependencies[dependencyType].Repo,
-				dependencies[dependencyType].Tag,
-				commit,
-				diffUrl,
-			}
+	}
+	var client *http.Client
+	if spec := cmd.String("chaos"); spec != "" {
+		rules, err := chaos.ParseRules(spec)
+		if err != nil {
+			return nil, err
 		}
+		log.Printf("Injecting faults into source responses: %s (seed %d)", spec, cmd.Uint64("chaos-seed"))
+		client = chaos.New(nil, cmd.Uint64("chaos-seed"), rules...).Client()
 	}
+	return sources.NewSet(sources.Options{
+		GithubToken: cmd.String("token"),
+		HTTPClient:  client,
+		GithubURL:   cmd.String("github-api-url"),
+		Timeout:     timeout,
+		Timeouts:    timeouts,
+		CacheDir:    cacheDir,
+		CacheTTL:    cacheTTL,
+		CacheTTLs:   cacheTTLs,
+		Refresh:     cmd.Bool("refresh"),
+		RateLimits:  rateLimits,
+	}), nil
+}
 
-	if selectedTag != nil {
-		return *selectedTag.Name, commit, updatedDependency, nil
+// finish prints the edits of a dry run, or creates the commit or GitHub output for applied
+// updates if asked to.
+func finish(ctx context.Context, cmd *cli.Command, run runner.Options, result *runner.Result) error {
+	if run.DryRun {
+		printEdits(os.Stdout, result.Edits)
+		return nil
 	}
 
-	return "", commit, updatedDependency, nil
-}
+	if path := cmd.String("attestation"); path != "" && result.Edits != nil {
+		if err := writeAttestation(path, cmd.String("attestation-key"), run, result); err != nil {
+			return err
+		}
+	}
 
-func updateVersionTagAndCommit(
-	commit string,
-	tag string,
-	dependencyType string,
-	repoPath string,
-	dependencies Dependencies) error {
-	dependencies[dependencyType].Tag = tag
-	dependencies[dependencyType].Commit = commit
-	err := writeToVersionsJson(repoPath, dependencies)
-	if err != nil {
-		return fmt.Errorf("error writing to versions "+dependencyType+": %s", err)
+	githubAction := cmd.Bool("github-action")
+	if (cmd.Bool("commit") && result.Updates != nil) || (githubAction && result.Updates != nil) {
+		err := createCommitMessage(ctx, result, run.RepoPath, githubAction)
+		if err != nil {
+			return fmt.Errorf("error creating commit message: %s", err)
+		}
 	}
 
 	return nil
 }
 
-func writeToVersionsJson(repoPath string, dependencies Dependencies) error {
-	// formatting json
-	updatedJson, err := json.MarshalIndent(dependencies, "", "	  ")
+// writeAttestation signs an attestation of the result with the hex encoded ed25519 seed and
+// writes its envelope to path.
+func writeAttestation(path string, seed string, run runner.Options, result *runner.Result) error {
+	decoded, err := hex.DecodeString(seed)
+	if err != nil || len(decoded) != ed25519.SeedSize {
+		return fmt.Errorf("--attestation needs --attestation-key, a hex encoded %d byte ed25519 seed", ed25519.SeedSize)
+	}
+	dependencies, err := version.ReadDependencies(run.RepoPath)
 	if err != nil {
-		return fmt.Errorf("error marshaling dependencies json: %s", err)
+		return err
 	}
-
-	e := os.WriteFile(repoPath+"/versions.json", updatedJson, 0644)
-	if e != nil {
-		return fmt.Errorf("error writing to versions.json: %s", e)
+	repoURL := func(info *version.Info) string {
+		source, err := run.Sources.For(info.Source)
+		if err != nil {
+			return ""
+		}
+		return source.RepoURL(info.Owner, info.Repo)
+	}
+	statement, err := attestation.New(run.RepoPath, result, dependencies, repoURL, cmp.Or(buildVersion, "dev"), time.Now())
+	if err != nil {
+		return err
+	}
+	envelope, err := attestation.Sign(statement, ed25519.NewKeyFromSeed(decoded))
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(encoded, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing attestation: %s", err)
 	}
-
 	return nil
 }
 
-func createVersionsEnv(repoPath string, dependencies Dependencies) error {
-	envLines := []string{}
-
-	for dependency := range dependencies {
-		repoUrl := generateGithubRepoUrl(dependencies, dependency) + ".git"
-
-		dependencyPrefix := strings.ToUpper(dependency)
-
-		if dependencies[dependency].Tracking == "branch" {
-			dependencies[dependency].Tag = dependencies[dependency].Branch
+func createCommitMessage(ctx context.Context, result *runner.Result, repoPath string, githubAction bool) error {
+	commitTitle, commitDescription, risk := commitMessage(result)
+	if githubAction {
+		err := writeToGithubOutput(commitTitle, commitDescription, risk, repoPath)
+		if err != nil {
+			return fmt.Errorf("error creating git commit message: %s", err)
 		}
+	} else {
+		cmd := exec.CommandContext(ctx, "git", "commit", "-am", commitTitle, "-m", commitDescription)
+		cmd.Dir = repoPath
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to run git commit -m: %s", err)
+		}
+	}
+	return nil
+}
 
-		envLines = append(envLines, fmt.Sprintf("export %s_%s=%s",
-			dependencyPrefix, "TAG", dependencies[dependency].Tag))
-
-		envLines = append(envLines, fmt.Sprintf("export %s_%s=%s",
-			dependencyPrefix, "COMMIT", dependencies[dependency].Commit))
-
-		envLines = append(envLines, fmt.Sprintf("export %s_%s=%s",
-			dependencyPrefix, "REPO", repoUrl))
+// commitMessage is the title and description of the commit or pull request of a run's
+// updates, and their risk, high if a check put one in the high risk tier.
+func commitMessage(result *runner.Result) (string, string, string) {
+	var repos []string
+	descriptionLines := []string{
+		"### Dependency Updates",
+	}
+	risk := "normal"
+	if needsApproval := result.NeedsApproval(); needsApproval != nil {
+		risk = "high"
+		descriptionLines = append(descriptionLines, fmt.Sprintf("> [!WARNING]\n> High risk, needs approval before merging: %s", strings.Join(needsApproval, ", ")), "")
 	}
 
-	slices.Sort(envLines)
+	commitTitle := "chore: updated "
 
-	file, err := os.Create(repoPath + "/versions.env")
-	if err != nil {
-		return fmt.Errorf("error creating versions.env file: %s", err)
+	for _, dependency := range result.Updates {
+		repo, tag := dependency.Repo, dependency.To
+		line := fmt.Sprintf("**%s** - %s:  [diff](%s)", repo, tag, dependency.DiffUrl)
+		switch dependency.Kind {
+		case version.KindPromotion:
+			line += fmt.Sprintf(" promotes %s to stable", dependency.From)
+		case version.KindRetraction:
+			line += fmt.Sprintf(" replaces %s, retracted upstream", dependency.From)
+		}
+		descriptionLines = append(descriptionLines, line)
+		repos = append(repos, repo)
 	}
-	defer file.Close()
-
-	_, err = file.WriteString(strings.Join(envLines, "\n"))
-	if err != nil {
-		return fmt.Errorf("error writing to versions.env file: %s", err)
+	if len(result.Checks) > 0 {
+		descriptionLines = append(descriptionLines, "", "### Checks")
+		for _, check := range result.Checks {
+			line := fmt.Sprintf("%s %s %s %s", check.Dependency, check.Version, check.Check, check.Status())
+			if check.Status() != "passed" {
+				// Reviewers need to see these, a relicensing or a held update, among the passes.
+				line = "**" + line + "**"
+			}
+			descriptionLines = append(descriptionLines, fmt.Sprintf("- %s: %s", line, check.Detail))
+		}
 	}
-
-	return nil
+	if len(result.Rationale) > 0 {
+		descriptionLines = append(descriptionLines, "", "<details><summary>Version selection</summary>", "")
+		for _, rationale := range result.Rationale {
+			descriptionLines = append(descriptionLines, fmt.Sprintf("- **%s**: %s", rationale.Dependency, rationale))
+		}
+		descriptionLines = append(descriptionLines, "", "</details>")
+	}
+	commitDescription := strings.Join(descriptionLines, "\n")
+	commitTitle += strings.Join(repos, ", ")
+	return commitTitle, commitDescription, risk
 }
 
-func writeToGithubOutput(title string, description string, repoPath string) error {
+func writeToGithubOutput(title string, description string, risk string, repoPath string) error {
 	file := os.Getenv("GITHUB_OUTPUT")
 	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -403,9 +704,9 @@ func writeToGithubOutput(title string, description string, repoPath string) erro
 		return fmt.Errorf("failed to write to GITHUB_OUTPUT file: %s", err)
 	}
 
-	return nil
-}
+	if _, err := fmt.Fprintf(f, "RISK=%s\n", risk); err != nil {
+		return fmt.Errorf("failed to write to GITHUB_OUTPUT file: %s", err)
+	}
 
-func generateGithubRepoUrl(dependencies Dependencies, dependencyType string) string {
-	return "https://github.com/" + dependencies[dependencyType].Owner + "/" + dependencies[dependencyType].Repo
+	return nil
 }
@@ -3,12 +3,10 @@ module github.com/base/node/dependency_updater
 go 1.24.3
 
 require (
+	github.com/Masterminds/semver/v3 v3.4.0
 	github.com/ethereum-optimism/optimism v1.13.3
 	github.com/google/go-github/v72 v72.0.0
 	github.com/urfave/cli/v3 v3.3.8
 )
 
-require (
-	github.com/Masterminds/semver/v3 v3.4.0 // indirect
-	github.com/google/go-querystring v1.1.0 // indirect
-)
+require github.com/google/go-querystring v1.1.0 // indirect
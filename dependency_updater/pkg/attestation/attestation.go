@@ -0,0 +1,221 @@
+// Package attestation describes the updates a run applied as a signed in-toto statement, so
+// consumers of the version files can verify they came out of the update pipeline: which
+// upstream tags and commits went in, which checks were evaluated and which files came out.
+package attestation
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/targets"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://github.com/base/node/dependency_updater/update/v1"
+	PayloadType   = "application/vnd.in-toto+json"
+)
+
+// Statement is an in-toto statement whose subjects are the files a run wrote.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate records the inputs of the run and how it got from them to the subjects.
+type Predicate struct {
+	Builder   Builder              `json:"builder"`
+	Timestamp string               `json:"timestamp"`
+	Inputs    []Input              `json:"inputs"`
+	Checks    []runner.CheckResult `json:"checks,omitempty"`
+	Outputs   []Output             `json:"outputs"`
+}
+
+type Builder struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+}
+
+// Input is an upstream version a dependency was updated to, and the policy it was selected by.
+type Input struct {
+	Dependency string            `json:"dependency"`
+	URI        string            `json:"uri"`
+	Digest     map[string]string `json:"digest"`
+	From       string            `json:"from"`
+	To         string            `json:"to"`
+	Tracking   string            `json:"tracking"`
+	TagPrefix  string            `json:"tagPrefix,omitempty"`
+	Pinned     bool              `json:"pinned,omitempty"`
+}
+
+// Output is a file a target changed, with its digest before the run.
+type Output struct {
+	Path         string            `json:"path"`
+	Target       string            `json:"target"`
+	DigestBefore map[string]string `json:"digestBefore,omitempty"`
+}
+
+func sha256Digest(content []byte) map[string]string {
+	sum := sha256.Sum256(content)
+	return map[string]string{"sha256": hex.EncodeToString(sum[:])}
+}
+
+// New describes a run's result. dependencies is versions.json after the run, repoURL returns
+// the repository URL of a dependency, and builderVersion is the updater's version.
+func New(repoPath string, result *runner.Result, dependencies version.Dependencies, repoURL func(*version.Info) string, builderVersion string, now time.Time) (*Statement, error) {
+	statement := &Statement{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			Builder:   Builder{ID: "https://github.com/base/node/dependency_updater", Version: builderVersion},
+			Timestamp: now.UTC().Format(time.RFC3339),
+			Inputs:    []Input{},
+			Checks:    result.Checks,
+			Outputs:   []Output{},
+		},
+	}
+	for _, planned := range result.Planned {
+		info := dependencies[planned.Dependency]
+		if info == nil {
+			return nil, fmt.Errorf("unknown dependency %s", planned.Dependency)
+		}
+		ref := planned.Version
+		if ref == "" {
+			ref = planned.Commit
+		}
+		statement.Predicate.Inputs = append(statement.Predicate.Inputs, Input{
+			Dependency: planned.Dependency,
+			URI:        "git+" + repoURL(info) + "@" + ref,
+			Digest:     map[string]string{"gitCommit": planned.Commit},
+			From:       planned.Info.From,
+			To:         planned.Info.To,
+			Tracking:   info.Tracking,
+			TagPrefix:  info.TagPrefix,
+			Pinned:     planned.Pin,
+		})
+	}
+	for _, edit := range result.Edits {
+		name, err := relativePath(repoPath, edit)
+		if err != nil {
+			return nil, err
+		}
+		statement.Subject = append(statement.Subject, Subject{Name: name, Digest: sha256Digest(edit.After)})
+		output := Output{Path: name, Target: edit.Target}
+		if edit.Before != nil {
+			output.DigestBefore = sha256Digest(edit.Before)
+		}
+		statement.Predicate.Outputs = append(statement.Predicate.Outputs, output)
+	}
+	return statement, nil
+}
+
+func relativePath(repoPath string, edit targets.Edit) (string, error) {
+	if !filepath.IsAbs(edit.Path) {
+		return filepath.ToSlash(edit.Path), nil
+	}
+	rel, err := filepath.Rel(repoPath, edit.Path)
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s: %s", edit.Path, err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Envelope is a DSSE envelope.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// pae is the DSSE pre-authentication encoding of a payload, what is actually signed.
+func pae(payloadType string, payload []byte) []byte {
+	return []byte("DSSEv1 " + strconv.Itoa(len(payloadType)) + " " + payloadType + " " + strconv.Itoa(len(payload)) + " " + string(payload))
+}
+
+// KeyID identifies an ed25519 public key in envelopes, its hex encoding.
+func KeyID(key ed25519.PublicKey) string {
+	return hex.EncodeToString(key)
+}
+
+// Sign wraps the statement in an envelope signed with key.
+func Sign(statement *Statement, key ed25519.PrivateKey) (*Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{{
+			KeyID: KeyID(key.Public().(ed25519.PublicKey)),
+			Sig:   base64.StdEncoding.EncodeToString(ed25519.Sign(key, pae(PayloadType, payload))),
+		}},
+	}, nil
+}
+
+// Verify checks the envelope carries a valid signature by key and returns its statement.
+func Verify(envelope *Envelope, key ed25519.PublicKey) (*Statement, error) {
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding attestation payload: %s", err)
+	}
+	verified := false
+	for _, signature := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err == nil && ed25519.Verify(key, pae(envelope.PayloadType, payload), sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("attestation is not signed by %s", KeyID(key))
+	}
+	if envelope.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected attestation payload type %s", envelope.PayloadType)
+	}
+	var statement Statement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("error decoding attestation statement: %s", err)
+	}
+	if statement.PredicateType != PredicateType {
+		return nil, fmt.Errorf("unexpected attestation predicate type %s", statement.PredicateType)
+	}
+	return &statement, nil
+}
+
+// VerifySubjects checks that the files under repoPath still have the digests the statement
+// records for them.
+func VerifySubjects(statement *Statement, repoPath string) error {
+	for _, subject := range statement.Subject {
+		content, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(subject.Name)))
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", subject.Name, err)
+		}
+		if sha256Digest(content)["sha256"] != subject.Digest["sha256"] {
+			return fmt.Errorf("%s does not match the attestation", subject.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package attestation
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/targets"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	repo := t.TempDir()
+	after := []byte(`{"reth":{"tag":"v1.1.0","commit":"c110"}}`)
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), after, 0644); err != nil {
+		t.Fatal(err)
+	}
+	result := &runner.Result{
+		Planned: []version.PlannedUpdate{{Dependency: "reth", Version: "v1.1.0", Commit: "c110", Info: version.UpdateInfo{Repo: "reth", From: "v1.0.0", To: "v1.1.0"}}},
+		Edits:   []targets.Edit{{Target: "versions.json", Path: filepath.Join(repo, "versions.json"), Before: []byte("{}"), After: after}},
+		Checks:  []runner.CheckResult{{Dependency: "reth", Version: "v1.1.0", Check: "provenance", Passed: true}},
+	}
+	dependencies := version.Dependencies{"reth": {Tag: "v1.1.0", Commit: "c110", Owner: "paradigmxyz", Repo: "reth", Tracking: "release"}}
+	repoURL := func(info *version.Info) string { return "https://github.com/" + info.Owner + "/" + info.Repo }
+
+	statement, err := New(repo, result, dependencies, repoURL, "dependency_updater/v1.0.0", time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if len(statement.Subject) != 1 || statement.Subject[0].Name != "versions.json" {
+		t.Errorf("New() subjects = %+v, want versions.json", statement.Subject)
+	}
+	if input := statement.Predicate.Inputs[0]; input.URI != "git+https://github.com/paradigmxyz/reth@v1.1.0" || input.Digest["gitCommit"] != "c110" || input.Tracking != "release" {
+		t.Errorf("New() input = %+v", input)
+	}
+
+	public, private, _ := ed25519.GenerateKey(nil)
+	envelope, err := Sign(statement, private)
+	if err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+	verified, err := Verify(envelope, public)
+	if err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	if err := VerifySubjects(verified, repo); err != nil {
+		t.Errorf("VerifySubjects() unexpected error: %v", err)
+	}
+
+	other, _, _ := ed25519.GenerateKey(nil)
+	if _, err := Verify(envelope, other); err == nil {
+		t.Errorf("Verify() with another key expected error")
+	}
+	tampered := *envelope
+	tampered.Payload = envelope.Payload[:len(envelope.Payload)-4] + "AAAA"
+	if _, err := Verify(&tampered, public); err == nil {
+		t.Errorf("Verify() of a changed payload expected error")
+	}
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySubjects(verified, repo); err == nil {
+		t.Errorf("VerifySubjects() of a changed file expected error")
+	}
+}
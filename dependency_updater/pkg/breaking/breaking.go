@@ -0,0 +1,114 @@
+// Package breaking scans the release notes between the current and the candidate version for
+// markers of breaking changes, such as required resyncs, and puts matching updates in the
+// high risk tier that needs approval.
+package breaking
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/policy"
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// DefaultMarkers are matched case insensitively against release names and notes.
+var DefaultMarkers = []string{"breaking", "database migration", "requires resync", "flag removed"}
+
+// maxReleases bounds how many releases between the current and the candidate version are read.
+const maxReleases = 20
+
+// Match is a marker found in the notes of a release.
+type Match struct {
+	Release string
+	Marker  string
+	// Line is the line of the notes the marker is on.
+	Line string
+}
+
+// Scan returns the first line of text matching each marker.
+func Scan(text string, markers []string) []Match {
+	var matches []Match
+	lines := strings.Split(text, "\n")
+	for _, marker := range markers {
+		for _, line := range lines {
+			if strings.Contains(strings.ToLower(line), strings.ToLower(marker)) {
+				matches = append(matches, Match{Marker: marker, Line: strings.TrimSpace(line)})
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// Check scans the notes of every release after the current version up to the candidate, as
+// skipping a release skips none of its breaking changes.
+type Check struct {
+	// Markers are DefaultMarkers if empty.
+	Markers []string
+}
+
+func (Check) Name() string { return "breaking changes" }
+
+func (c Check) Check(ctx context.Context, source sources.VersionSource, info *version.Info, planned version.PlannedUpdate) *runner.CheckResult {
+	if planned.Version == "" {
+		return nil
+	}
+	markers := c.Markers
+	if len(markers) == 0 {
+		markers = DefaultMarkers
+	}
+	releases, err := between(ctx, source, info, planned.Version)
+	if err != nil {
+		return &runner.CheckResult{Detail: fmt.Sprintf("error listing releases: %s", err)}
+	}
+
+	var matches []Match
+	for _, tag := range releases {
+		release, err := source.GetRelease(ctx, info.Owner, info.Repo, tag)
+		if err != nil {
+			// Tags without a release have no notes to scan.
+			continue
+		}
+		for _, match := range Scan(release.Name+"\n"+release.Body, markers) {
+			match.Release = tag
+			matches = append(matches, match)
+		}
+	}
+	if len(matches) == 0 {
+		return &runner.CheckResult{Passed: true, Detail: fmt.Sprintf("no breaking change markers in %d releases", len(releases))}
+	}
+	var found []string
+	for _, match := range matches {
+		line := match.Line
+		if len(line) > 100 {
+			line = line[:100] + "..."
+		}
+		found = append(found, fmt.Sprintf("%s %q: %s", match.Release, match.Marker, line))
+	}
+	return &runner.CheckResult{NeedsApproval: true, Detail: strings.Join(found, "; ")}
+}
+
+// between returns the tags after the dependency's current tag up to and including candidate,
+// newest first, at most maxReleases of them.
+func between(ctx context.Context, source sources.VersionSource, info *version.Info, candidate string) ([]string, error) {
+	tags, err := source.ListTags(ctx, info.Owner, info.Repo)
+	if err != nil {
+		return nil, err
+	}
+	names, err := policy.Between(tags, info.TagPrefix, info.Tag, candidate)
+	if err != nil {
+		// Without versions to order by, only the candidate's notes are known to apply.
+		return []string{candidate}, nil
+	}
+	if len(names) > maxReleases {
+		names = names[:maxReleases]
+	}
+	if !slices.Contains(names, candidate) {
+		names = append([]string{candidate}, names...)
+	}
+	return names, nil
+}
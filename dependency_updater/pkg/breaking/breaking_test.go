@@ -0,0 +1,64 @@
+package breaking
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// notesSource serves tags and the notes of their releases.
+type notesSource struct {
+	sources.VersionSource
+	notes map[string]string
+}
+
+func (s notesSource) ListTags(ctx context.Context, owner string, repo string) ([]sources.Tag, error) {
+	var tags []sources.Tag
+	for name := range s.notes {
+		tags = append(tags, sources.Tag{Name: name})
+	}
+	return append(tags, sources.Tag{Name: "op-node/v1.3.0"}), nil
+}
+
+func (s notesSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*sources.Release, error) {
+	notes, ok := s.notes[tag]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &sources.Release{Tag: tag, Body: notes}, nil
+}
+
+func TestCheck(t *testing.T) {
+	source := notesSource{notes: map[string]string{
+		"op-node/v1.0.0":     "## Breaking\nDropped everything",
+		"op-node/v1.1.0":     "## Fixes\n- This release REQUIRES RESYNC of the node\n",
+		"op-node/v1.2.0-rc1": "Bug fixes",
+		"op-node/v1.2.0":     "Bug fixes",
+		"op-geth/v1.5.0":     "breaking",
+	}}
+	info := &version.Info{Tag: "op-node/v1.0.0", TagPrefix: "op-node", Tracking: "release"}
+
+	tests := []struct {
+		name         string
+		candidate    string
+		markers      []string
+		wantApproval bool
+		wantDetail   string
+	}{
+		{"skipped release with a marker", "op-node/v1.2.0", nil, true, `op-node/v1.1.0 "requires resync": - This release REQUIRES RESYNC of the node`},
+		{"only the current release matches", "op-node/v1.0.1", nil, false, "no breaking change markers in 1 releases"},
+		{"custom markers", "op-node/v1.2.0", []string{"dropped"}, false, "no breaking change markers in 3 releases"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Check{Markers: tt.markers}.Check(context.Background(), source, info, version.PlannedUpdate{Version: tt.candidate})
+			if result == nil || result.NeedsApproval != tt.wantApproval || !strings.Contains(result.Detail, tt.wantDetail) {
+				t.Errorf("Check() = %+v, want approval %v and %q", result, tt.wantApproval, tt.wantDetail)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package buildmatrix turns the updates of a run into a GitHub Actions build matrix of the
+// images that need rebuilding.
+package buildmatrix
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Entry is one image to rebuild, and the dependency versions that changed in it.
+type Entry struct {
+	// Name is the directory holding the Dockerfile, such as geth.
+	Name string `json:"name"`
+	// Version lists the updated dependencies as <dependency>@<version>, comma separated.
+	Version      string   `json:"version"`
+	Image        string   `json:"image"`
+	Dockerfile   string   `json:"dockerfile"`
+	Dependencies []string `json:"dependencies"`
+}
+
+// Matrix is the value of a strategy.matrix, an empty Include means nothing to build.
+type Matrix struct {
+	Include []Entry `json:"include"`
+}
+
+// Build returns an entry for every <repo>/<name>/Dockerfile sourcing versions.env for an
+// updated dependency, the <DEPENDENCY>_TAG variable being how Dockerfiles reference one.
+// Images are named imagePrefix + name.
+func Build(repoPath string, planned []version.PlannedUpdate, imagePrefix string) (Matrix, error) {
+	matrix := Matrix{Include: []Entry{}}
+	dockerfiles, err := filepath.Glob(filepath.Join(repoPath, "*", "Dockerfile"))
+	if err != nil {
+		return matrix, err
+	}
+	slices.Sort(dockerfiles)
+	updates := slices.Clone(planned)
+	slices.SortFunc(updates, func(a, b version.PlannedUpdate) int { return strings.Compare(a.Dependency, b.Dependency) })
+
+	for _, path := range dockerfiles {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return matrix, fmt.Errorf("error reading %s: %s", path, err)
+		}
+		name := filepath.Base(filepath.Dir(path))
+		entry := Entry{Name: name, Image: imagePrefix + name, Dockerfile: name + "/Dockerfile"}
+		var versions []string
+		for _, update := range updates {
+			if !strings.Contains(string(content), "$"+strings.ToUpper(update.Dependency)+"_TAG") {
+				continue
+			}
+			entry.Dependencies = append(entry.Dependencies, update.Dependency)
+			versions = append(versions, update.Dependency+"@"+cmp.Or(update.Version, update.Commit))
+		}
+		if len(entry.Dependencies) > 0 {
+			entry.Version = strings.Join(versions, ",")
+			matrix.Include = append(matrix.Include, entry)
+		}
+	}
+	return matrix, nil
+}
@@ -0,0 +1,46 @@
+package buildmatrix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+func TestBuild(t *testing.T) {
+	repo := t.TempDir()
+	dockerfiles := map[string]string{
+		"geth":       "RUN . /tmp/versions.env && git clone $OP_NODE_REPO --branch $OP_NODE_TAG\nRUN git clone $OP_GETH_REPO --branch $OP_GETH_TAG\n",
+		"reth":       "RUN . /tmp/versions.env && git clone $OP_NODE_REPO --branch $OP_NODE_TAG\n",
+		"nethermind": "RUN . /tmp/versions.env && git clone $NETHERMIND_REPO --branch $NETHERMIND_TAG\n",
+	}
+	for dir, content := range dockerfiles {
+		if err := os.MkdirAll(filepath.Join(repo, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, dir, "Dockerfile"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	planned := []version.PlannedUpdate{{Dependency: "op_node", Version: "op-node/v1.17.0"}, {Dependency: "op_geth", Version: "v1.101703.0"}}
+	matrix, err := Build(repo, planned, "ghcr.io/base/node-")
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if len(matrix.Include) != 2 {
+		t.Fatalf("Build() = %+v, want geth and reth", matrix.Include)
+	}
+	geth := matrix.Include[0]
+	if geth.Name != "geth" || geth.Image != "ghcr.io/base/node-geth" || geth.Dockerfile != "geth/Dockerfile" || geth.Version != "op_geth@v1.101703.0,op_node@op-node/v1.17.0" {
+		t.Errorf("geth entry = %+v", geth)
+	}
+	if matrix.Include[1].Name != "reth" || len(matrix.Include[1].Dependencies) != 1 {
+		t.Errorf("reth entry = %+v", matrix.Include[1])
+	}
+
+	if matrix, _ := Build(repo, nil, ""); matrix.Include == nil || len(matrix.Include) != 0 {
+		t.Errorf("Build() without updates = %+v, want an empty include", matrix)
+	}
+}
@@ -0,0 +1,241 @@
+// Package chaos injects faults into the HTTP responses of source clients, so tests, and runs
+// with the hidden --chaos flag, exercise the retry, rate limit, timeout and partial failure
+// handling against latency, 429s, truncated pages and malformed JSON.
+package chaos
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault is a kind of fault a Rule injects.
+type Fault string
+
+const (
+	// Latency delays the request by the rule's Delay.
+	Latency Fault = "latency"
+	// TooManyRequests answers with a 429 and a Retry-After header instead of sending the request.
+	TooManyRequests Fault = "429"
+	// Truncate cuts the response body in half, the client reads an unexpected EOF.
+	Truncate Fault = "truncate"
+	// Malformed replaces the response body with invalid JSON.
+	Malformed Fault = "malformed"
+)
+
+var faults = []Fault{Latency, TooManyRequests, Truncate, Malformed}
+
+// Rule injects a fault into matching requests.
+type Rule struct {
+	Fault Fault
+	// Path matches requests whose URL path contains it, every request if empty.
+	Path string
+	// Probability is the chance a matching request gets the fault, 1 if zero.
+	Probability float64
+	// Times is how many requests get the fault at most, unlimited if zero.
+	Times int
+	// Delay is the latency added by Latency faults.
+	Delay time.Duration
+}
+
+// Transport sends requests with Base, http.DefaultTransport if nil, injecting the faults of
+// its rules. It is safe for concurrent use.
+type Transport struct {
+	Base  http.RoundTripper
+	Rules []Rule
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	applied  []int
+	injected map[Fault]int
+}
+
+// New returns a Transport whose probabilistic faults are drawn from seed, so a failing run is
+// reproduced with the same seed.
+func New(base http.RoundTripper, seed uint64, rules ...Rule) *Transport {
+	return &Transport{
+		Base:     base,
+		Rules:    rules,
+		rand:     rand.New(rand.NewPCG(seed, seed)),
+		applied:  make([]int, len(rules)),
+		injected: map[Fault]int{},
+	}
+}
+
+// Client returns a client sending its requests through t.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// Injected returns how many times each fault was injected.
+func (t *Transport) Injected() map[Fault]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	injected := make(map[Fault]int, len(t.injected))
+	for fault, n := range t.injected {
+		injected[fault] = n
+	}
+	return injected
+}
+
+// pick returns the faults of the rules that fire for req, at most one of each.
+func (t *Transport) pick(req *http.Request) []Rule {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rand == nil {
+		t.rand = rand.New(rand.NewPCG(0, 0))
+	}
+	if len(t.applied) != len(t.Rules) {
+		t.applied = make([]int, len(t.Rules))
+	}
+	if t.injected == nil {
+		t.injected = map[Fault]int{}
+	}
+	var picked []Rule
+	seen := map[Fault]bool{}
+	for i, rule := range t.Rules {
+		if seen[rule.Fault] || !strings.Contains(req.URL.Path, rule.Path) || (rule.Times > 0 && t.applied[i] >= rule.Times) {
+			continue
+		}
+		if rule.Probability > 0 && t.rand.Float64() >= rule.Probability {
+			continue
+		}
+		t.applied[i]++
+		t.injected[rule.Fault]++
+		seen[rule.Fault] = true
+		picked = append(picked, rule)
+	}
+	return picked
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	picked := t.pick(req)
+	for _, rule := range picked {
+		if rule.Fault != Latency {
+			continue
+		}
+		timer := time.NewTimer(rule.Delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+	for _, rule := range picked {
+		if rule.Fault == TooManyRequests {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return response(req, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}, "Content-Type": {"application/json"}}, `{"message": "chaos: too many requests"}`), nil
+		}
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range picked {
+		switch rule.Fault {
+		case Truncate:
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			// The full length is still announced, like a connection dropped mid-body.
+			resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body[:len(body)/2]), errReader{io.ErrUnexpectedEOF}))
+		case Malformed:
+			resp.Body.Close()
+			body := `{"chaos": [malformed`
+			resp.Body = io.NopCloser(strings.NewReader(body))
+			resp.ContentLength = int64(len(body))
+			resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+	return resp, nil
+}
+
+func response(req *http.Request, status int, header http.Header, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// ParseRules parses the --chaos flag, comma separated rules of the form
+// <fault>[=<delay>][@<path>][:<probability>|:<times>x], such as
+// "latency=500ms:0.2,429@/tags:0.1,truncate:2x,malformed@/releases:0.05". Faults are latency,
+// which needs a delay, 429, truncate and malformed.
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, value := range strings.Split(spec, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		var rule Rule
+		rest, chance, hasChance := strings.Cut(value, ":")
+		if hasChance {
+			var err error
+			if times, ok := strings.CutSuffix(chance, "x"); ok {
+				rule.Times, err = strconv.Atoi(times)
+				if err == nil && rule.Times <= 0 {
+					err = fmt.Errorf("times must be positive")
+				}
+			} else {
+				rule.Probability, err = strconv.ParseFloat(chance, 64)
+				if err == nil && (rule.Probability <= 0 || rule.Probability > 1) {
+					err = fmt.Errorf("probability must be in (0, 1]")
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid chaos rule %q: %s", value, err)
+			}
+		}
+		rest, rule.Path, _ = strings.Cut(rest, "@")
+		fault, delay, hasDelay := strings.Cut(rest, "=")
+		rule.Fault = Fault(fault)
+		if !slices.Contains(faults, rule.Fault) {
+			return nil, fmt.Errorf("invalid chaos rule %q, expected a fault of latency, 429, truncate or malformed", value)
+		}
+		if hasDelay != (rule.Fault == Latency) {
+			return nil, fmt.Errorf("invalid chaos rule %q, only latency takes a delay and it needs one", value)
+		}
+		if hasDelay {
+			d, err := time.ParseDuration(delay)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chaos rule %q: %s", value, err)
+			}
+			rule.Delay = d
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,167 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/forgetest"
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// forge serves reth with 150 older tags, putting its releases on the second page, and writes
+// a repository tracking them at v1.0.0.
+func forge(t *testing.T) (*forgetest.Server, string) {
+	t.Helper()
+	s := forgetest.New(t)
+	for i := range 150 {
+		s.AddTag("paradigmxyz", "reth", fmt.Sprintf("v0.1.%d", i), fmt.Sprintf("r01%d", i))
+	}
+	s.AddTag("paradigmxyz", "reth", "v1.0.0", "r100")
+	s.AddTag("paradigmxyz", "reth", "v1.1.0", "r110")
+	s.AddRelease("paradigmxyz", "reth", sources.Release{Tag: "v1.1.0", PublishedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)})
+
+	dir := t.TempDir()
+	manifest := `{"reth": {"tag": "v1.0.0", "commit": "r100", "owner": "paradigmxyz", "repo": "reth", "tracking": "release"}}`
+	if err := os.WriteFile(filepath.Join(dir, "versions.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "versions.env"), []byte("export RETH_TAG=v1.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return s, dir
+}
+
+// run runs the updater against s with the faults of rules injected.
+func run(t *testing.T, s *forgetest.Server, dir string, timeout time.Duration, rules ...Rule) (*Transport, error) {
+	t.Helper()
+	transport := New(s.Client().Transport, 1, rules...)
+	opts := s.SourceOptions()
+	opts.HTTPClient, opts.Timeout = transport.Client(), timeout
+	_, err := runner.Run(context.Background(), runner.Options{RepoPath: dir, Sources: sources.NewSet(opts)})
+	return transport, err
+}
+
+func rethTag(t *testing.T, dir string) string {
+	t.Helper()
+	dependencies, err := version.ReadDependencies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dependencies["reth"].Tag
+}
+
+// Faults that clear up within the runner's three attempts are retried away.
+func TestTransientFaults(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"too many requests", Rule{Fault: TooManyRequests, Path: "/tags", Times: 1}},
+		{"truncated tags page", Rule{Fault: Truncate, Path: "/tags", Times: 1}},
+		{"malformed tags page", Rule{Fault: Malformed, Path: "/tags", Times: 2}},
+		{"latency", Rule{Fault: Latency, Delay: 10 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, dir := forge(t)
+			transport, err := run(t, s, dir, 0, tt.rule)
+			if err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			if injected := transport.Injected()[tt.rule.Fault]; injected == 0 {
+				t.Errorf("%s was never injected", tt.rule.Fault)
+			}
+			if tag := rethTag(t, dir); tag != "v1.1.0" {
+				t.Errorf("reth after Run() = %s, want v1.1.0", tag)
+			}
+		})
+	}
+}
+
+// A fault that persists fails the run without applying anything.
+func TestPersistentFault(t *testing.T) {
+	s, dir := forge(t)
+	transport, err := run(t, s, dir, 0, Rule{Fault: Malformed, Path: "/tags"})
+	if err == nil || !strings.Contains(err.Error(), "reth") {
+		t.Fatalf("Run() = %v, want an error naming reth", err)
+	}
+	if injected := transport.Injected()[Malformed]; injected != 3 {
+		t.Errorf("Malformed injected %d times, want once per attempt", injected)
+	}
+	if tag := rethTag(t, dir); tag != "v1.0.0" {
+		t.Errorf("reth after a failed Run() = %s, want v1.0.0", tag)
+	}
+	env, _ := os.ReadFile(filepath.Join(dir, "versions.env"))
+	if string(env) != "export RETH_TAG=v1.0.0\n" {
+		t.Errorf("versions.env after a failed Run() =\n%s", env)
+	}
+}
+
+// Releases only add metadata, the update goes through when they can't be read.
+func TestPartialFailure(t *testing.T) {
+	s, dir := forge(t)
+	transport, err := run(t, s, dir, 0, Rule{Fault: Malformed, Path: "/releases"})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if transport.Injected()[Malformed] == 0 {
+		t.Error("malformed was never injected")
+	}
+	if tag := rethTag(t, dir); tag != "v1.1.0" {
+		t.Errorf("reth after Run() = %s, want v1.1.0", tag)
+	}
+}
+
+// Latency beyond the source timeout fails with an error naming the source.
+func TestLatencyTimeout(t *testing.T) {
+	s, dir := forge(t)
+	_, err := run(t, s, dir, 50*time.Millisecond, Rule{Fault: Latency, Path: "/tags", Delay: time.Second})
+	if err == nil || !strings.Contains(err.Error(), "github ListTags timed out") {
+		t.Errorf("Run() = %v, want a github timeout", err)
+	}
+}
+
+func TestProbability(t *testing.T) {
+	s, _ := forge(t)
+	transport := New(s.Client().Transport, 7, Rule{Fault: TooManyRequests, Probability: 0.5})
+	client := transport.Client()
+	for range 100 {
+		resp, err := client.Get(s.URL + "/rate_limit")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if injected := transport.Injected()[TooManyRequests]; injected < 25 || injected > 75 {
+		t.Errorf("429 injected into %d of 100 requests, want about half", injected)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("latency=500ms:0.2, 429@/tags:0.1,truncate:2x,malformed@/releases")
+	if err != nil {
+		t.Fatalf("ParseRules() unexpected error: %v", err)
+	}
+	want := []Rule{
+		{Fault: Latency, Delay: 500 * time.Millisecond, Probability: 0.2},
+		{Fault: TooManyRequests, Path: "/tags", Probability: 0.1},
+		{Fault: Truncate, Times: 2},
+		{Fault: Malformed, Path: "/releases"},
+	}
+	if fmt.Sprint(rules) != fmt.Sprint(want) {
+		t.Errorf("ParseRules() = %+v, want %+v", rules, want)
+	}
+
+	for _, spec := range []string{"latency", "truncate=1s", "drop", "429:0", "429:1.5", "malformed:0x", "latency=soon"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("ParseRules(%q) = nil error, want an error", spec)
+		}
+	}
+}
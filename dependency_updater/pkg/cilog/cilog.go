@@ -0,0 +1,92 @@
+// Package cilog formats the log of a run for CI: it folds the lines of each dependency with
+// the provider's group syntax and ends with a summary of one line per dependency to grep.
+package cilog
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+)
+
+// Providers are the CI providers whose group syntax Group writes.
+var Providers = []string{"github", "gitlab"}
+
+var sectionID = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// Group writes the start of a folded group named name to w and returns the function writing
+// its end.
+func Group(w io.Writer, provider string, name string) func() {
+	switch provider {
+	case "github":
+		fmt.Fprintf(w, "::group::%s\n", name)
+		return func() { fmt.Fprintln(w, "::endgroup::") }
+	case "gitlab":
+		// GitLab section names only allow lowercase letters, digits, _, . and -.
+		id := sectionID.ReplaceAllString(strings.ToLower(name), "_")
+		fmt.Fprintf(w, "\x1b[0Ksection_start:%d:%s[collapsed=true]\r\x1b[0K%s\n", time.Now().Unix(), id, name)
+		return func() { fmt.Fprintf(w, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", time.Now().Unix(), id) }
+	}
+	return func() {}
+}
+
+// Summary is one line per dependency of a run's result, such as
+//
+//	updater-summary dependency=op_node status=updated from=v1.0.0 to=v1.1.0
+//
+// followed by the count of each status. The statuses are updated, or would-update for dry
+// runs, held, deselected, up-to-date, pinned and skipped.
+func Summary(result *runner.Result, dryRun bool) []string {
+	applied := map[string]string{}
+	for _, planned := range result.Planned {
+		applied[planned.Dependency] = planned.Info.To
+	}
+	needsApproval := result.NeedsApproval()
+	counts := map[string]int{}
+	var lines []string
+	for _, r := range result.Rationale {
+		fields := []string{"dependency=" + r.Dependency}
+		status := "up-to-date"
+		if to, ok := applied[r.Dependency]; ok {
+			status = "updated"
+			if dryRun {
+				status = "would-update"
+			}
+			fields = append(fields, "from="+r.Current, "to="+to)
+			if slices.Contains(needsApproval, r.Dependency) {
+				fields = append(fields, "approval=needed")
+			}
+		} else if i := slices.IndexFunc(result.Checks, func(c runner.CheckResult) bool { return c.Dependency == r.Dependency && c.Hold }); i >= 0 {
+			status = "held"
+			fields = append(fields, "from="+r.Current, "to="+result.Checks[i].Version, fmt.Sprintf("check=%q", result.Checks[i].Check))
+		} else {
+			switch {
+			case r.Skipped == "pinned":
+				status = "pinned"
+				fields = append(fields, "at="+r.Current)
+			case r.Skipped != "":
+				status = "skipped"
+				fields = append(fields, fmt.Sprintf("reason=%q", r.Skipped))
+			case r.Selected != "" && r.Selected != r.Current:
+				// Selected but left out by the run's Select, such as in an interactive review.
+				status = "deselected"
+				fields = append(fields, "from="+r.Current, "to="+r.Selected)
+			default:
+				fields = append(fields, "at="+r.Current)
+			}
+		}
+		counts[status]++
+		lines = append(lines, "updater-summary "+strings.Join(slices.Insert(fields, 1, "status="+status), " "))
+	}
+	var totals []string
+	for _, status := range []string{"updated", "would-update", "held", "deselected", "up-to-date", "pinned", "skipped"} {
+		if counts[status] > 0 {
+			totals = append(totals, fmt.Sprintf("%s=%d", status, counts[status]))
+		}
+	}
+	return append(lines, strings.TrimSpace("updater-summary total "+strings.Join(totals, " ")))
+}
@@ -0,0 +1,74 @@
+package cilog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/policy"
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+func TestGroup(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     []string
+	}{
+		{provider: "github", want: []string{"::group::op_node checks\n", "::endgroup::\n"}},
+		{provider: "gitlab", want: []string{"section_start:", ":op_node_checks[collapsed=true]\r\x1b[0Kop_node checks\n", "section_end:", ":op_node_checks\r"}},
+		{provider: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			var out strings.Builder
+			end := Group(&out, tt.provider, "op_node checks")
+			end()
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Group() wrote %q, want it to contain %q", out.String(), want)
+				}
+			}
+			if tt.want == nil && out.Len() > 0 {
+				t.Errorf("Group() wrote %q without a provider", out.String())
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	result := &runner.Result{
+		Planned: []version.PlannedUpdate{
+			{Dependency: "op_node", Info: version.UpdateInfo{From: "v1.0.0", To: "v1.1.0"}},
+			{Dependency: "reth", Info: version.UpdateInfo{From: "v1.0.0", To: "v2.0.0"}},
+		},
+		Checks: []runner.CheckResult{
+			{Dependency: "reth", Version: "v2.0.0", Check: "breaking changes", NeedsApproval: true},
+			{Dependency: "geth", Version: "v1.15.0", Check: "vulnerability scan", Hold: true},
+		},
+		Rationale: []policy.Rationale{
+			{Dependency: "geth", Current: "v1.14.0", Selected: "v1.15.0"},
+			{Dependency: "nethermind", Current: "1.31.0", Skipped: "pinned"},
+			{Dependency: "op_geth", Current: "v1.0.0", Selected: "v1.0.0"},
+			{Dependency: "op_node", Current: "v1.0.0", Selected: "v1.1.0"},
+			{Dependency: "op_reth", Current: "v1.0.0", Skipped: "excluded by the run's only and skip filters"},
+			{Dependency: "reth", Current: "v1.0.0", Selected: "v2.0.0"},
+			{Dependency: "base_reth", Current: "v1.0.0", Selected: "v1.0.1"},
+		},
+	}
+	want := []string{
+		`updater-summary dependency=geth status=held from=v1.14.0 to=v1.15.0 check="vulnerability scan"`,
+		`updater-summary dependency=nethermind status=pinned at=1.31.0`,
+		`updater-summary dependency=op_geth status=up-to-date at=v1.0.0`,
+		`updater-summary dependency=op_node status=would-update from=v1.0.0 to=v1.1.0`,
+		`updater-summary dependency=op_reth status=skipped reason="excluded by the run's only and skip filters"`,
+		`updater-summary dependency=reth status=would-update from=v1.0.0 to=v2.0.0 approval=needed`,
+		`updater-summary dependency=base_reth status=deselected from=v1.0.0 to=v1.0.1`,
+		`updater-summary total would-update=2 held=1 deselected=1 up-to-date=1 pinned=1 skipped=1`,
+	}
+	if got := Summary(result, true); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Summary() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := Summary(&runner.Result{}, false); len(got) != 1 || got[0] != "updater-summary total" {
+		t.Errorf("Summary() of an empty run = %q", got)
+	}
+}
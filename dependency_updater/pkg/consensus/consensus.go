@@ -0,0 +1,76 @@
+// Package consensus holds candidates until they are published in every place the dependency's
+// consensus policy names, such as a mirror's tags or the upstream image, so half published
+// releases aren't proposed.
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/ociartifact"
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Resolver resolves an image tag to the digest of its manifest, like ociartifact.Pusher.
+type Resolver interface {
+	Resolve(ctx context.Context, ref ociartifact.Reference) (string, error)
+}
+
+// Check is a runner.Check holding candidates missing from a source or registry of the
+// dependency's consensus, or listed there at a different commit.
+type Check struct {
+	// Sources resolves the source names of the consensus.
+	Sources *sources.Set
+	// Registry resolves the candidate's image tag.
+	Registry Resolver
+}
+
+func (Check) Name() string { return "consensus" }
+
+func (c Check) Check(ctx context.Context, source sources.VersionSource, info *version.Info, planned version.PlannedUpdate) *runner.CheckResult {
+	if info.Consensus == nil || planned.Version == "" {
+		return nil
+	}
+	var found []string
+	hold := func(detail string) *runner.CheckResult {
+		return &runner.CheckResult{Hold: true, Detail: detail}
+	}
+	for _, name := range info.Consensus.Sources {
+		other, err := c.Sources.For(name)
+		if err != nil {
+			return hold(err.Error())
+		}
+		tags, err := other.ListTags(ctx, info.Owner, info.Repo)
+		if err != nil {
+			return hold(fmt.Sprintf("error listing the tags of %s: %s", name, err))
+		}
+		i := slices.IndexFunc(tags, func(tag sources.Tag) bool { return tag.Name == planned.Version })
+		switch {
+		case i < 0:
+			return hold(fmt.Sprintf("%s doesn't list %s yet", name, planned.Version))
+		case tags[i].Commit != "" && planned.Commit != "" && tags[i].Commit != planned.Commit:
+			return hold(fmt.Sprintf("%s lists %s at %s, not %s", name, planned.Version, tags[i].Commit, planned.Commit))
+		}
+		found = append(found, name)
+	}
+	if info.Consensus.Image {
+		if info.Image == "" {
+			return hold("the consensus requires the image, but the dependency has none")
+		}
+		image := ociartifact.Qualify(info.Image) + ":" + info.ImageTagOf(planned.Version)
+		ref, err := ociartifact.ParseReference(image)
+		if err != nil {
+			return hold(err.Error())
+		}
+		digest, err := c.Registry.Resolve(ctx, ref)
+		if err != nil {
+			return hold(fmt.Sprintf("image %s is not published: %s", image, err))
+		}
+		found = append(found, "image "+digest)
+	}
+	return &runner.CheckResult{Passed: true, Detail: "published in " + strings.Join(found, ", ")}
+}
@@ -0,0 +1,94 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/ociartifact"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// mirrorSource lists fixed tags.
+type mirrorSource struct {
+	sources.VersionSource
+	tags []sources.Tag
+}
+
+func (s mirrorSource) ListTags(context.Context, string, string) ([]sources.Tag, error) {
+	return s.tags, nil
+}
+
+// registry serves digests of fixed references.
+type registry map[string]string
+
+func (r registry) Resolve(ctx context.Context, ref ociartifact.Reference) (string, error) {
+	if digest, ok := r[ref.String()]; ok {
+		return digest, nil
+	}
+	return "", errors.New("404 Not Found")
+}
+
+func TestCheck(t *testing.T) {
+	set := sources.NewSet(sources.Options{})
+	set.Add(map[string]sources.VersionSource{"mirror": mirrorSource{tags: []sources.Tag{{Name: "v1.1.0", Commit: "c110"}, {Name: "v1.2.0", Commit: "other"}}}})
+	check := Check{Sources: set, Registry: registry{"ghcr.io/o/reth:v1.1.0": "sha256:aa", "registry-1.docker.io/library/geth:v1.1.0": "sha256:bb"}}
+	tests := []struct {
+		name       string
+		info       version.Info
+		planned    version.PlannedUpdate
+		wantNil    bool
+		wantHold   bool
+		wantDetail string
+	}{
+		{name: "no consensus", info: version.Info{}, planned: version.PlannedUpdate{Version: "v1.1.0"}, wantNil: true},
+		{
+			name:       "published everywhere",
+			info:       version.Info{Image: "ghcr.io/o/reth", Consensus: &version.Consensus{Sources: []string{"mirror"}, Image: true}},
+			planned:    version.PlannedUpdate{Version: "v1.1.0", Commit: "c110"},
+			wantDetail: "published in mirror, image sha256:aa",
+		},
+		{
+			name:       "docker hub short name",
+			info:       version.Info{Image: "geth", Consensus: &version.Consensus{Image: true}},
+			planned:    version.PlannedUpdate{Version: "v1.1.0"},
+			wantDetail: "published in image sha256:bb",
+		},
+		{
+			name:       "missing from the mirror",
+			info:       version.Info{Consensus: &version.Consensus{Sources: []string{"mirror"}}},
+			planned:    version.PlannedUpdate{Version: "v1.3.0", Commit: "c130"},
+			wantHold:   true,
+			wantDetail: "mirror doesn't list v1.3.0 yet",
+		},
+		{
+			name:       "commits disagree",
+			info:       version.Info{Consensus: &version.Consensus{Sources: []string{"mirror"}}},
+			planned:    version.PlannedUpdate{Version: "v1.2.0", Commit: "c120"},
+			wantHold:   true,
+			wantDetail: "mirror lists v1.2.0 at other, not c120",
+		},
+		{
+			name:       "image not pushed yet",
+			info:       version.Info{Image: "ghcr.io/o/reth", Consensus: &version.Consensus{Image: true}},
+			planned:    version.PlannedUpdate{Version: "v1.2.0"},
+			wantHold:   true,
+			wantDetail: "image ghcr.io/o/reth:v1.2.0 is not published: 404 Not Found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := check.Check(context.Background(), nil, &tt.info, tt.planned)
+			if tt.wantNil {
+				if result != nil {
+					t.Errorf("Check() = %+v, want nil", result)
+				}
+				return
+			}
+			if result == nil || result.Hold != tt.wantHold || result.Passed == tt.wantHold || result.Detail != tt.wantDetail {
+				t.Errorf("Check() = %+v, want hold %v, %q", result, tt.wantHold, tt.wantDetail)
+			}
+		})
+	}
+}
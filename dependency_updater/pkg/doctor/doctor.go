@@ -0,0 +1,255 @@
+// Package doctor diagnoses the setup of the updater: its GitHub token and rate limit, the
+// registries of the tracked images, the files it writes, the repository's git state and
+// versions.json itself, with a fix for every problem it finds.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/policy"
+	"github.com/base/node/dependency_updater/pkg/version"
+	"github.com/google/go-github/v72/github"
+)
+
+// Finding is the outcome of one diagnosis.
+type Finding struct {
+	Check string `json:"check"`
+	// Status is "ok", "warn" or "fail".
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	// Fix is what to do about a warning or failure.
+	Fix string `json:"fix,omitempty"`
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("[%s] %s: %s", f.Status, f.Check, f.Detail)
+	if f.Fix != "" {
+		s += "\n       fix: " + f.Fix
+	}
+	return s
+}
+
+// Failed reports whether any finding failed.
+func Failed(findings []Finding) bool {
+	return slices.ContainsFunc(findings, func(f Finding) bool { return f.Status == "fail" })
+}
+
+// lowRateLimit is the remaining core requests below which a run may run out.
+const lowRateLimit = 100
+
+// GitHub checks that the token is accepted, lists its scopes and the remaining rate limit.
+// An empty token only gets the anonymous rate limit.
+func GitHub(ctx context.Context, client *github.Client, token string) []Finding {
+	var findings []Finding
+	if token == "" {
+		findings = append(findings, Finding{Check: "token", Status: "warn", Detail: "no GitHub token, requests are limited to 60 an hour", Fix: "export GITHUB_TOKEN, or pass --token"})
+	} else {
+		_, resp, err := client.Users.Get(ctx, "")
+		switch {
+		case resp != nil && resp.StatusCode == http.StatusUnauthorized:
+			return append(findings, Finding{Check: "token", Status: "fail", Detail: "GitHub rejects the token", Fix: "the token expired or was revoked, create a new one"})
+		case err != nil:
+			return append(findings, Finding{Check: "token", Status: "fail", Detail: fmt.Sprintf("error reaching GitHub: %s", err), Fix: "check the network and any HTTPS proxy"})
+		}
+		findings = append(findings, tokenScopes(resp.Header.Get("X-OAuth-Scopes")))
+	}
+
+	limits, _, err := client.RateLimit.Get(ctx)
+	if err != nil {
+		return append(findings, Finding{Check: "rate limit", Status: "fail", Detail: fmt.Sprintf("error getting the rate limit: %s", err), Fix: "check the network and any HTTPS proxy"})
+	}
+	core := limits.GetCore()
+	finding := Finding{Check: "rate limit", Status: "ok", Detail: fmt.Sprintf("%d of %d requests left, resets %s", core.Remaining, core.Limit, core.Reset.Format(time.RFC3339))}
+	if core.Remaining < lowRateLimit {
+		finding.Status = "warn"
+		finding.Fix = "wait for the reset, or lower the source rate with --rate-limit"
+		if token == "" {
+			finding.Fix = "set a token, which raises the limit to 5000 an hour"
+		}
+	}
+	return append(findings, finding)
+}
+
+// tokenScopes checks the scopes of a classic token. Fine-grained tokens don't list theirs.
+func tokenScopes(header string) Finding {
+	if header == "" {
+		return Finding{Check: "token", Status: "ok", Detail: "accepted, a fine-grained token or one without scopes"}
+	}
+	var scopes []string
+	for _, scope := range strings.Split(header, ",") {
+		scopes = append(scopes, strings.TrimSpace(scope))
+	}
+	finding := Finding{Check: "token", Status: "ok", Detail: "accepted with scopes " + strings.Join(scopes, ", ")}
+	if !slices.Contains(scopes, "repo") && !slices.Contains(scopes, "public_repo") {
+		finding.Status = "warn"
+		finding.Fix = "add the public_repo scope, or repo for private forks, so update runs can push branches"
+	}
+	return finding
+}
+
+// Registries checks that the registry of each dependency's image answers the registry API.
+// Registries asking for authentication count as reachable.
+func Registries(ctx context.Context, client *http.Client, dependencies version.Dependencies) []Finding {
+	var hosts []string
+	for _, name := range version.Names(dependencies) {
+		if image := dependencies[name].Image; image != "" {
+			if host := registryHost(image); !slices.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	var findings []Finding
+	for _, host := range hosts {
+		finding := Finding{Check: "registry " + host, Status: "ok", Detail: "reachable"}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/v2/", nil)
+		var resp *http.Response
+		if err == nil {
+			resp, err = client.Do(req)
+		}
+		if err != nil {
+			finding.Status, finding.Detail, finding.Fix = "fail", err.Error(), "check the network, DNS and any HTTPS proxy for "+host
+		} else {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+				finding.Status, finding.Detail, finding.Fix = "fail", "answered "+resp.Status, host+" may not be an OCI registry, check the image names in versions.json"
+			}
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// registryHost is the registry of an image reference, Docker Hub's for short names.
+func registryHost(image string) string {
+	host, _, ok := strings.Cut(image, "/")
+	if !ok || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return "registry-1.docker.io"
+	}
+	return host
+}
+
+// Writable checks that the files and directories the updater writes can be written, without
+// changing them. Missing paths are fine if their directory is writable.
+func Writable(paths []string) []Finding {
+	var findings []Finding
+	for _, path := range paths {
+		finding := Finding{Check: "write " + path, Status: "ok", Detail: "writable"}
+		if err := writable(path); err != nil {
+			finding.Status, finding.Detail = "fail", err.Error()
+			finding.Fix = fmt.Sprintf("give the user running the updater write access, such as chmod u+w %s", path)
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+func writable(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return writable(filepath.Dir(path))
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	f, err := os.CreateTemp(path, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Git checks that the repository has no uncommitted changes, which update runs would mix into
+// their commits. run executes git and returns its output, exec if nil.
+func Git(ctx context.Context, repoPath string, run func(ctx context.Context, name string, args ...string) ([]byte, error)) Finding {
+	if run == nil {
+		run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return exec.CommandContext(ctx, name, args...).Output()
+		}
+	}
+	out, err := run(ctx, "git", "-C", repoPath, "status", "--porcelain")
+	if err != nil {
+		return Finding{Check: "git", Status: "fail", Detail: fmt.Sprintf("git status failed: %s", err), Fix: "install git and point --repo at a clone of the repository"}
+	}
+	changed := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(out) == 0 {
+		return Finding{Check: "git", Status: "ok", Detail: "working tree clean"}
+	}
+	detail := fmt.Sprintf("%d uncommitted changes", len(changed))
+	if len(changed) <= 3 {
+		detail += ": " + strings.Join(changed, ", ")
+	}
+	return Finding{Check: "git", Status: "warn", Detail: detail, Fix: "commit or stash them before an update run"}
+}
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Config checks every dependency of versions.json for mistakes the updater would only trip
+// over in a run. known reports whether a source name is registered.
+func Config(dependencies version.Dependencies, known func(source string) bool) []Finding {
+	var findings []Finding
+	for _, name := range version.Names(dependencies) {
+		info := dependencies[name]
+		fail := func(detail string, fix string) {
+			findings = append(findings, Finding{Check: "config " + name, Status: "fail", Detail: detail, Fix: fix})
+		}
+		switch {
+		case info.Owner == "" || info.Repo == "":
+			fail("no owner or repo", "set owner and repo to the upstream repository")
+		case !slices.Contains([]string{"release", "tag", "branch", "nightly"}, info.Tracking):
+			fail(fmt.Sprintf("unknown tracking %q", info.Tracking), "set tracking to release, tag, branch or nightly")
+		case info.Tracking == "branch" && info.Branch == "":
+			fail("tracks a branch but has no branch", "set branch, such as main")
+		case info.Tracking != "branch" && info.Tag == "":
+			fail("has no tag", "set tag to the version currently built")
+		case info.TagPrefix != "" && info.Tracking != "branch" && !strings.HasPrefix(info.Tag, info.PrefixOf(info.Tag)+"/"):
+			fail(fmt.Sprintf("tag %s doesn't start with the tag prefix %s/", info.Tag, info.TagPrefix), "fix tagPrefix, or remove it if the repository tags releases without one")
+		case (info.Tracking == "release" || info.Tracking == "tag") && !parses(info):
+			fail(fmt.Sprintf("tag %s is not a semantic version", info.Tag), "use nightly tracking for dated builds, or set the tag prefix")
+		case !known(info.Source):
+			fail(fmt.Sprintf("unknown source %q", info.Source), "install the plugin providing it in --plugins-dir, or remove source to use github")
+		case !commitPattern.MatchString(info.Commit):
+			fail(fmt.Sprintf("commit %q is not a full commit hash", info.Commit), "set commit to the 40 character hash the tag points to, or run pin")
+		case info.CheckInterval != "" && !durationParses(info.CheckInterval):
+			fail(fmt.Sprintf("invalid checkInterval %q", info.CheckInterval), `use a duration such as "24h"`)
+		case info.Consensus != nil && slices.ContainsFunc(info.Consensus.Sources, func(source string) bool { return !known(source) }):
+			fail(fmt.Sprintf("unknown consensus source in %s", strings.Join(info.Consensus.Sources, ", ")), "install the plugin providing it in --plugins-dir, or remove it from consensus.sources")
+		case info.Consensus != nil && info.Consensus.Image && info.Image == "":
+			fail("consensus requires the image, but there is none", "set image, or remove consensus.image")
+		case info.TieBreak != "" && !slices.Contains(policy.TieBreaks, info.TieBreak):
+			fail(fmt.Sprintf("unknown tieBreak %q", info.TieBreak), "set tieBreak to "+strings.Join(policy.TieBreaks, ", ")+", or remove it")
+		case info.VPrefix != "" && !slices.Contains(version.VPrefixes, info.VPrefix):
+			fail(fmt.Sprintf("unknown vPrefix %q", info.VPrefix), "set vPrefix to "+strings.Join(version.VPrefixes, " or ")+", or remove it")
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, Finding{Check: "config", Status: "ok", Detail: fmt.Sprintf("%d dependencies", len(dependencies))})
+	}
+	return findings
+}
+
+func parses(info *version.Info) bool {
+	_, err := version.ParseVersion(info.Tag, info.PrefixOf(info.Tag))
+	return err == nil
+}
+
+func durationParses(s string) bool {
+	_, err := time.ParseDuration(s)
+	return err == nil
+}
@@ -0,0 +1,163 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+	"github.com/google/go-github/v72/github"
+)
+
+func statuses(findings []Finding) string {
+	var s []string
+	for _, f := range findings {
+		s = append(s, f.Check+"="+f.Status)
+	}
+	return strings.Join(s, " ")
+}
+
+func TestGitHub(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		userCode  int
+		scopes    string
+		remaining int
+		want      string
+	}{
+		{name: "scoped token", token: "t", userCode: http.StatusOK, scopes: "repo, workflow", remaining: 4000, want: "token=ok rate limit=ok"},
+		{name: "fine-grained token", token: "t", userCode: http.StatusOK, remaining: 4000, want: "token=ok rate limit=ok"},
+		{name: "token without repo scope", token: "t", userCode: http.StatusOK, scopes: "read:org", remaining: 4000, want: "token=warn rate limit=ok"},
+		{name: "rejected token", token: "t", userCode: http.StatusUnauthorized, want: "token=fail"},
+		{name: "anonymous and exhausted", remaining: 3, want: "token=warn rate limit=warn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /user", func(w http.ResponseWriter, req *http.Request) {
+				if tt.scopes != "" {
+					w.Header().Set("X-OAuth-Scopes", tt.scopes)
+				}
+				w.WriteHeader(tt.userCode)
+				w.Write([]byte(`{"login": "updater"}`))
+			})
+			mux.HandleFunc("GET /rate_limit", func(w http.ResponseWriter, req *http.Request) {
+				json.NewEncoder(w).Encode(map[string]any{"resources": map[string]any{"core": map[string]any{"limit": 5000, "remaining": tt.remaining, "reset": 1800000000}}})
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+			client := github.NewClient(server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			if got := statuses(GitHub(context.Background(), client, tt.token)); got != tt.want {
+				t.Errorf("GitHub() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistries(t *testing.T) {
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v2/" {
+			t.Errorf("requested %s", req.URL.Path)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer registry.Close()
+	host := strings.TrimPrefix(registry.URL, "https://")
+	dependencies := version.Dependencies{
+		"reth": {Image: host + "/paradigmxyz/reth"},
+		"geth": {Image: host + "/ethereum/geth"},
+		"down": {Image: "127.0.0.1:1/o/down"},
+		"repo": {},
+	}
+	got := statuses(Registries(context.Background(), registry.Client(), dependencies))
+	if want := "registry 127.0.0.1:1=fail registry " + host + "=ok"; got != want {
+		t.Errorf("Registries() = %s, want %s", got, want)
+	}
+	if host := registryHost("nethermind/nethermind"); host != "registry-1.docker.io" {
+		t.Errorf("registryHost() = %s, want Docker Hub", host)
+	}
+}
+
+func TestWritable(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "versions.json")
+	readOnly := filepath.Join(dir, "versions.env")
+	for path, mode := range map[string]os.FileMode{file: 0644, readOnly: 0444} {
+		if err := os.WriteFile(path, []byte("{}"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readOnlyStatus := "fail"
+	if os.Geteuid() == 0 {
+		// root writes read-only files.
+		readOnlyStatus = "ok"
+	}
+	got := statuses(Writable([]string{file, readOnly, filepath.Join(dir, "missing.json"), dir}))
+	want := "write " + file + "=ok write " + readOnly + "=" + readOnlyStatus + " write " + filepath.Join(dir, "missing.json") + "=ok write " + dir + "=ok"
+	if got != want {
+		t.Errorf("Writable() = %s, want %s", got, want)
+	}
+}
+
+func TestGit(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		err  error
+		want string
+	}{
+		{name: "clean", want: "ok"},
+		{name: "dirty", out: " M versions.json\n?? notes.txt\n", want: "warn"},
+		{name: "not a repository", err: errors.New("exit status 128"), want: "fail"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finding := Git(context.Background(), "/repo", func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				if got := name + " " + strings.Join(args, " "); got != "git -C /repo status --porcelain" {
+					t.Errorf("ran %s", got)
+				}
+				return []byte(tt.out), tt.err
+			})
+			if finding.Status != tt.want {
+				t.Errorf("Git() = %+v, want %s", finding, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	commit := strings.Repeat("a", 40)
+	dependencies := version.Dependencies{
+		"ok":       {Tag: "v1.0.0", Commit: commit, Owner: "o", Repo: "r", Tracking: "release"},
+		"branch":   {Commit: commit, Owner: "o", Repo: "r", Tracking: "branch"},
+		"renamed":  {Tag: "op-node/v1.0.0", TagPrefix: "node", Commit: commit, Owner: "o", Repo: "r", Tracking: "release", Aliases: []version.Alias{{TagPrefix: "op-node"}}},
+		"prefix":   {Tag: "v1.0.0", TagPrefix: "op-node", Commit: commit, Owner: "o", Repo: "r", Tracking: "release"},
+		"source":   {Tag: "v1.0.0", Commit: commit, Owner: "o", Repo: "r", Tracking: "release", Source: "gitlab"},
+		"commit":   {Tag: "v1.0.0", Commit: "abc", Owner: "o", Repo: "r", Tracking: "release"},
+		"interval": {Tag: "v1.0.0", Commit: commit, Owner: "o", Repo: "r", Tracking: "release", CheckInterval: "daily"},
+		"nightly":  {Tag: "nightly-2025-01-02", Commit: commit, Owner: "o", Repo: "r", Tracking: "nightly"},
+		"tiebreak": {Tag: "v1.0.0", Commit: commit, Owner: "o", Repo: "r", Tracking: "release", TieBreak: "newest"},
+		"mirror":   {Tag: "v1.0.0", Commit: commit, Owner: "o", Repo: "r", Tracking: "release", Consensus: &version.Consensus{Sources: []string{"gitea"}}},
+		"image":    {Tag: "v1.0.0", Commit: commit, Owner: "o", Repo: "r", Tracking: "release", Consensus: &version.Consensus{Image: true}},
+		"vprefix":  {Tag: "v1.0.0", Commit: commit, Owner: "o", Repo: "r", Tracking: "release", VPrefix: "drop"},
+	}
+	known := func(source string) bool { return source == "" || source == "github" }
+	got := statuses(Config(dependencies, known))
+	want := "config branch=fail config commit=fail config image=fail config interval=fail config mirror=fail config prefix=fail config source=fail config tiebreak=fail config vprefix=fail"
+	if got != want {
+		t.Errorf("Config() = %s, want %s", got, want)
+	}
+	if got := statuses(Config(version.Dependencies{"ok": dependencies["ok"]}, known)); got != "config=ok" {
+		t.Errorf("Config() of a valid versions.json = %s", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Package drift measures how far each dependency is behind upstream from the rationales of a
+// run, and words it for notifications and reports, such as "op_node: 2 minors behind, latest
+// rc available; op_geth: up to date".
+package drift
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/policy"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Drift is how far a dependency is behind the version its tracking mode selects.
+type Drift struct {
+	Dependency string `json:"dependency"`
+	Current    string `json:"current"`
+	// Latest is the selected version, empty if the dependency is up to date or wasn't
+	// checked.
+	Latest string `json:"latest,omitempty"`
+	// Majors, Minors and Patches count the releases between Current and Latest at the
+	// largest part that differs, the others are zero.
+	Majors  int `json:"majors,omitempty"`
+	Minors  int `json:"minors,omitempty"`
+	Patches int `json:"patches,omitempty"`
+	// RC is a release candidate newer than Latest, for dependencies tracking releases.
+	RC string `json:"rc,omitempty"`
+	// Unchecked is why the run didn't check the dependency, such as "pinned".
+	Unchecked string `json:"unchecked,omitempty"`
+	// Unversioned is set for branch and nightly tracking, whose distance can't be counted.
+	Unversioned bool `json:"unversioned,omitempty"`
+}
+
+// Of returns the drift of the dependency a rationale explains.
+func Of(r policy.Rationale) Drift {
+	d := Drift{Dependency: r.Dependency, Current: r.Current, RC: r.NewestRC, Unchecked: r.Skipped}
+	if r.Skipped != "" || r.Selected == "" || r.Selected == r.Current {
+		return d
+	}
+	d.Latest = r.Selected
+	current, currentErr := version.ParseVersion(r.Current, r.TagPrefix)
+	latest, latestErr := version.ParseVersion(r.Selected, r.TagPrefix)
+	if r.Tracking == "branch" || r.Tracking == "nightly" || currentErr != nil || latestErr != nil {
+		d.Unversioned = true
+		return d
+	}
+	switch {
+	case latest.Major() != current.Major():
+		d.Majors = int(latest.Major() - current.Major())
+	case latest.Minor() != current.Minor():
+		d.Minors = int(latest.Minor() - current.Minor())
+	case latest.Patch() != current.Patch():
+		d.Patches = int(latest.Patch() - current.Patch())
+	}
+	return d
+}
+
+// Levels are the drift levels from the smallest.
+var Levels = []string{"patch", "minor", "major"}
+
+// Level is the largest part of the version the dependency is behind in, "" if it is up to
+// date. Branch and nightly builds count as a patch.
+func (d Drift) Level() string {
+	switch {
+	case d.Majors > 0:
+		return "major"
+	case d.Minors > 0:
+		return "minor"
+	case d.Patches > 0, d.Unversioned:
+		return "patch"
+	}
+	return ""
+}
+
+// Beyond returns the drifts at level or above, such as those a minor or major behind for
+// "minor".
+func Beyond(drifts []Drift, level string) ([]Drift, error) {
+	threshold := slices.Index(Levels, level)
+	if threshold < 0 {
+		return nil, fmt.Errorf("unknown drift level %q, expected one of %s", level, strings.Join(Levels, ", "))
+	}
+	var beyond []Drift
+	for _, d := range drifts {
+		if d.Level() != "" && slices.Index(Levels, d.Level()) >= threshold {
+			beyond = append(beyond, d)
+		}
+	}
+	return beyond, nil
+}
+
+// All returns the drift of every rationale.
+func All(rationales []policy.Rationale) []Drift {
+	var drifts []Drift
+	for _, r := range rationales {
+		drifts = append(drifts, Of(r))
+	}
+	return drifts
+}
+
+func (d Drift) String() string {
+	var parts []string
+	switch {
+	case d.Unchecked == "pinned":
+		parts = append(parts, "pinned at "+d.Current)
+	case d.Unchecked != "":
+		parts = append(parts, "not checked")
+	case d.Majors > 0:
+		parts = append(parts, count(d.Majors, "major")+" behind")
+	case d.Minors > 0:
+		parts = append(parts, count(d.Minors, "minor")+" behind")
+	case d.Patches > 0:
+		parts = append(parts, count(d.Patches, "patch")+" behind")
+	case d.Latest != "":
+		// Branch heads and nightlies, whose distance can't be counted.
+		parts = append(parts, "newer build available")
+	default:
+		parts = append(parts, "up to date")
+	}
+	if d.RC != "" {
+		parts = append(parts, "latest rc available")
+	}
+	return d.Dependency + ": " + strings.Join(parts, ", ")
+}
+
+func count(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	if strings.HasSuffix(unit, "ch") {
+		return fmt.Sprintf("%d %ses", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// Summary is the drift of every dependency on one line, separated by semicolons.
+func Summary(drifts []Drift) string {
+	var lines []string
+	for _, d := range drifts {
+		lines = append(lines, d.String())
+	}
+	return strings.Join(lines, "; ")
+}
@@ -0,0 +1,63 @@
+package drift
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/policy"
+)
+
+func TestSummary(t *testing.T) {
+	rationales := []policy.Rationale{
+		{Dependency: "op_node", Tracking: "release", TagPrefix: "op-node", Current: "op-node/v1.14.2", Selected: "op-node/v1.16.0", NewestRC: "op-node/v1.17.0-rc.1"},
+		{Dependency: "op_geth", Tracking: "release", Current: "v1.101702.0", Selected: "v1.101702.0"},
+		{Dependency: "reth", Tracking: "release", Current: "v1.9.0", Selected: "v2.0.1"},
+		{Dependency: "nethermind", Tracking: "tag", Current: "1.36.2", Selected: "1.36.5"},
+		{Dependency: "explorer", Tracking: "branch", Current: "c1", Selected: "c2"},
+		{Dependency: "base_reth_node", Current: "v0.7.6", Skipped: "pinned"},
+		{Dependency: "kona", Current: "v1.0.0", Skipped: "last checked 2026-01-02T00:00:00Z, its check interval hasn't elapsed"},
+	}
+	want := "op_node: 2 minors behind, latest rc available; op_geth: up to date; reth: 1 major behind; nethermind: 3 patches behind; " +
+		"explorer: newer build available; base_reth_node: pinned at v0.7.6; kona: not checked"
+	if got := Summary(All(rationales)); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if d := Of(rationales[0]); d.Minors != 2 || d.Majors != 0 || d.Latest != "op-node/v1.16.0" {
+		t.Errorf("Of() = %+v, want 2 minors behind op-node/v1.16.0", d)
+	}
+}
+
+func TestBeyond(t *testing.T) {
+	drifts := []Drift{
+		{Dependency: "op_node", Minors: 2},
+		{Dependency: "op_geth"},
+		{Dependency: "reth", Majors: 1},
+		{Dependency: "nethermind", Patches: 3},
+		{Dependency: "explorer", Latest: "c2", Unversioned: true},
+		{Dependency: "base_reth_node", Unchecked: "pinned"},
+	}
+	tests := []struct {
+		level string
+		want  string
+	}{
+		{"patch", "op_node reth nethermind explorer"},
+		{"minor", "op_node reth"},
+		{"major", "reth"},
+	}
+	for _, tt := range tests {
+		beyond, err := Beyond(drifts, tt.level)
+		if err != nil {
+			t.Fatalf("Beyond(%s) unexpected error: %v", tt.level, err)
+		}
+		var got []string
+		for _, d := range beyond {
+			got = append(got, d.Dependency)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("Beyond(%s) = %v, want %s", tt.level, got, tt.want)
+		}
+	}
+	if _, err := Beyond(drifts, "minors"); err == nil {
+		t.Errorf("Beyond() of an unknown level expected an error")
+	}
+}
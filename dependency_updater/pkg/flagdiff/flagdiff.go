@@ -0,0 +1,159 @@
+// Package flagdiff compares the command line flags two versions of a client accept, read from
+// the --help output of their images, so removed or renamed flags and changed defaults are
+// known before a restart with the new version fails on them.
+package flagdiff
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Flag is a flag listed in a help output.
+type Flag struct {
+	Name    string
+	Default string
+}
+
+var (
+	flagName = regexp.MustCompile(`--[A-Za-z0-9][A-Za-z0-9._-]*`)
+	// defaults as urfave/cli (op-node, op-geth), clap (reth) and Nethermind print them.
+	defaultPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\(default: ([^)]*)\)`),
+		regexp.MustCompile(`\[default: ([^\]]*)\]`),
+		regexp.MustCompile(`Defaults to:? ([^\s]+?)\.?(?:\s|$)`),
+	}
+)
+
+// Parse returns the long flags of a help output by name. A flag's default is looked up on
+// its line and the description lines following it.
+func Parse(help string) map[string]Flag {
+	flags := map[string]Flag{}
+	var current string
+	var text strings.Builder
+	flush := func() {
+		if current == "" {
+			text.Reset()
+			return
+		}
+		flag := Flag{Name: current}
+		for _, pattern := range defaultPatterns {
+			if match := pattern.FindStringSubmatch(text.String()); match != nil {
+				flag.Default = strings.Trim(strings.TrimSpace(match[1]), `"`)
+				break
+			}
+		}
+		flags[current] = flag
+		current = ""
+		text.Reset()
+	}
+	for _, line := range strings.Split(help, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "-") {
+			flush()
+			if name := flagName.FindString(trimmed); name != "" {
+				current = name
+			}
+		} else if trimmed == "" {
+			// clap separates the paragraphs of a description, and its default, by blank lines.
+			continue
+		} else if !strings.HasPrefix(line, " ") {
+			// Section headings end a flag's description.
+			flush()
+			continue
+		}
+		text.WriteString(" " + trimmed)
+	}
+	flush()
+	return flags
+}
+
+// Change is a flag that differs between two versions.
+type Change struct {
+	Flag Flag
+	// Kind is "removed", "added" or "default changed".
+	Kind string
+	// From is the flag's default in the current version if it changed.
+	From string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case "default changed":
+		return fmt.Sprintf("%s default changed from %q to %q", c.Flag.Name, c.From, c.Flag.Default)
+	default:
+		return fmt.Sprintf("%s %s", c.Flag.Name, c.Kind)
+	}
+}
+
+// Diff returns the changes from current to candidate, removed flags first as those break
+// restarts, each kind sorted by name.
+func Diff(current map[string]Flag, candidate map[string]Flag) []Change {
+	var removed, added, changed []Change
+	for name, flag := range current {
+		next, ok := candidate[name]
+		switch {
+		case !ok:
+			removed = append(removed, Change{Flag: flag, Kind: "removed"})
+		case next.Default != flag.Default:
+			changed = append(changed, Change{Flag: next, Kind: "default changed", From: flag.Default})
+		}
+	}
+	for name, flag := range candidate {
+		if _, ok := current[name]; !ok {
+			added = append(added, Change{Flag: flag, Kind: "added"})
+		}
+	}
+	return slices.Concat(sorted(removed), sorted(changed), sorted(added))
+}
+
+func sorted(changes []Change) []Change {
+	slices.SortFunc(changes, func(a, b Change) int { return strings.Compare(a.Flag.Name, b.Flag.Name) })
+	return changes
+}
+
+// Reader runs images to print their help.
+type Reader struct {
+	// Command runs containers, "docker" if empty. Podman takes the same arguments.
+	Command string
+	// Args are passed to the image's entrypoint, such as "node --help" for reth.
+	Args []string
+	// Run executes the command and returns its combined output, exec if nil.
+	Run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %s %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// Read returns the flags the image's help lists.
+func (r Reader) Read(ctx context.Context, image string) (map[string]Flag, error) {
+	command := r.Command
+	if command == "" {
+		command = "docker"
+	}
+	execute := r.Run
+	if execute == nil {
+		execute = run
+	}
+	args := r.Args
+	if len(args) == 0 {
+		args = []string{"--help"}
+	}
+	out, err := execute(ctx, command, append([]string{"run", "--rm", image}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	flags := Parse(string(out))
+	if len(flags) == 0 {
+		return nil, fmt.Errorf("no flags in the help output of %s", image)
+	}
+	return flags, nil
+}
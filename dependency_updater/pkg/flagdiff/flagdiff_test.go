@@ -0,0 +1,120 @@
+package flagdiff
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		help string
+		want map[string]Flag
+	}{
+		{
+			name: "urfave/cli",
+			help: `NAME:
+   op-node - Optimism Rollup Node
+
+OPTIONS:
+   --l1 value                       Address of L1 User JSON-RPC endpoint to use (eth namespace required) (default: "http://127.0.0.1:8545") [$OP_NODE_L1_ETH_RPC]
+   --l1.trustrpc                    Trust the L1 RPC (default: false) [$OP_NODE_L1_TRUST_RPC]
+   --help, -h                       show help
+`,
+			want: map[string]Flag{
+				"--l1":          {Name: "--l1", Default: "http://127.0.0.1:8545"},
+				"--l1.trustrpc": {Name: "--l1.trustrpc", Default: "false"},
+				"--help":        {Name: "--help"},
+			},
+		},
+		{
+			name: "clap",
+			help: `Options:
+      --instance <INSTANCE>
+          Add a new instance of a node.
+
+          [default: 1]
+
+      --http
+          Enable the HTTP-RPC server
+  -h, --help
+          Print help
+`,
+			want: map[string]Flag{
+				"--instance": {Name: "--instance", Default: "1"},
+				"--http":     {Name: "--http"},
+				"--help":     {Name: "--help"},
+			},
+		},
+		{
+			name: "nethermind",
+			help: `  --JsonRpc.Port <value>    The JSON-RPC service HTTP port. Defaults to 8545.
+  --Sync.SnapSync <value>   Whether to use the Snap sync protocol.
+`,
+			want: map[string]Flag{
+				"--JsonRpc.Port":  {Name: "--JsonRpc.Port", Default: "8545"},
+				"--Sync.SnapSync": {Name: "--Sync.SnapSync"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.help); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := map[string]Flag{
+		"--l1":          {Name: "--l1", Default: "http://127.0.0.1:8545"},
+		"--l1.beacon":   {Name: "--l1.beacon"},
+		"--p2p.scoring": {Name: "--p2p.scoring", Default: "light"},
+	}
+	candidate := map[string]Flag{
+		"--l1":            {Name: "--l1", Default: "http://127.0.0.1:8545"},
+		"--l1.beacon-url": {Name: "--l1.beacon-url"},
+		"--p2p.scoring":   {Name: "--p2p.scoring", Default: "none"},
+		"--l1.cache":      {Name: "--l1.cache", Default: "100"},
+	}
+
+	var got []string
+	for _, change := range Diff(current, candidate) {
+		got = append(got, change.String())
+	}
+	want := []string{
+		"--l1.beacon removed",
+		`--p2p.scoring default changed from "light" to "none"`,
+		"--l1.beacon-url added",
+		"--l1.cache added",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+}
+
+func TestRead(t *testing.T) {
+	reader := Reader{Args: []string{"node", "--help"}, Run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if got := name + " " + strings.Join(args, " "); got != "docker run --rm ghcr.io/o/reth:v1 node --help" {
+			t.Errorf("ran %s", got)
+		}
+		return []byte("Options:\n      --http\n          Enable the HTTP-RPC server\n"), nil
+	}}
+	flags, err := reader.Read(context.Background(), "ghcr.io/o/reth:v1")
+	if err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+	if _, ok := flags["--http"]; !ok || len(flags) != 1 {
+		t.Errorf("Read() = %v, want --http", flags)
+	}
+
+	empty := Reader{Run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("unknown command"), nil
+	}}
+	if _, err := empty.Read(context.Background(), "ghcr.io/o/reth:v1"); err == nil {
+		t.Errorf("Read() without flags expected an error")
+	}
+}
@@ -0,0 +1,321 @@
+// Package forgetest serves enough of the GitHub REST API and of the OCI distribution API for
+// the updater's check, plan and apply pipeline to run against fake repositories and images,
+// without the network: tags, releases, commits and files of repositories with GitHub's
+// paging and rate limit headers, and image manifests behind a bearer token challenge.
+package forgetest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+)
+
+// token is the bearer token the registry hands out and requires.
+const token = "forgetest"
+
+// Server is a mock GitHub API and OCI registry.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	repos     map[string]*repository
+	images    map[string]map[string][]byte
+	rateLimit int
+	requests  int
+	reset     time.Time
+}
+
+type repository struct {
+	// tags are listed newest first, like GitHub does.
+	tags     []sources.Tag
+	releases map[string]sources.Release
+	branches map[string]string
+	// files are the contents of paths at each ref.
+	files map[string]map[string]string
+}
+
+// New starts a Server closed when the test ends. Its GitHub API allows 5000 requests.
+func New(t testing.TB) *Server {
+	s := &Server{
+		repos:     map[string]*repository{},
+		images:    map[string]map[string][]byte{},
+		rateLimit: 5000,
+		reset:     time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/{owner}/{repo}/tags", s.github(s.listTags))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/releases/tags/{tag...}", s.github(s.getRelease))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/commits", s.github(s.listCommits))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/contents/{path...}", s.github(s.getContents))
+	mux.HandleFunc("GET /rate_limit", s.rateLimitStatus)
+	mux.HandleFunc("GET /token", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"token": token})
+	})
+	mux.HandleFunc("/v2/", s.registry)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SourceOptions are the options of a sources.Set whose GitHub source calls the Server.
+func (s *Server) SourceOptions() sources.Options {
+	return sources.Options{GithubURL: s.URL + "/", HTTPClient: s.Client()}
+}
+
+// Registry is the host of image references the Server serves, over plain HTTP.
+func (s *Server) Registry() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+func (s *Server) repo(owner string, repo string) *repository {
+	key := owner + "/" + repo
+	if s.repos[key] == nil {
+		s.repos[key] = &repository{releases: map[string]sources.Release{}, branches: map[string]string{}, files: map[string]map[string]string{}}
+	}
+	return s.repos[key]
+}
+
+// AddTag adds a tag at commit, listed before the tags added earlier.
+func (s *Server) AddTag(owner string, repo string, tag string, commit string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(owner, repo)
+	r.tags = append([]sources.Tag{{Name: tag, Commit: commit}}, r.tags...)
+}
+
+// AddRelease publishes a release for its tag.
+func (s *Server) AddRelease(owner string, repo string, release sources.Release) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repo(owner, repo).releases[release.Tag] = release
+}
+
+// SetBranch points a branch at commit.
+func (s *Server) SetBranch(owner string, repo string, branch string, commit string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repo(owner, repo).branches[branch] = commit
+}
+
+// AddFile adds a file at a ref, a branch, tag or commit.
+func (s *Server) AddFile(owner string, repo string, ref string, path string, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(owner, repo)
+	if r.files[ref] == nil {
+		r.files[ref] = map[string]string{}
+	}
+	r.files[ref][path] = content
+}
+
+// PushImage tags an image manifest in a repository of the registry, replacing the manifest
+// the tag had, and returns its digest.
+func (s *Server) PushImage(repository string, tag string, manifest []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.images[repository] == nil {
+		s.images[repository] = map[string][]byte{}
+	}
+	s.images[repository][tag] = manifest
+	return digest(manifest)
+}
+
+// SetRateLimit sets the number of GitHub API requests allowed, counting those already made.
+// Further requests fail with GitHub's rate limit error.
+func (s *Server) SetRateLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = limit
+}
+
+// Requests is the number of GitHub API requests made, including rejected ones.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// github counts a request against the rate limit, sets the rate limit headers and, if the
+// limit is not exhausted, calls handle with the request's repository. Unknown repositories
+// are not found.
+func (s *Server) github(handle func(w http.ResponseWriter, req *http.Request, r *repository)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		remaining := max(s.rateLimit-s.requests, 0)
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.rateLimit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Used", strconv.Itoa(min(s.requests, s.rateLimit)))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.reset.Unix(), 10))
+		w.Header().Set("X-RateLimit-Resource", "core")
+		if s.requests > s.rateLimit {
+			writeJSON(w, http.StatusForbidden, map[string]string{"message": "API rate limit exceeded", "documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api"})
+			return
+		}
+		r := s.repos[req.PathValue("owner")+"/"+req.PathValue("repo")]
+		if r == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		handle(w, req, r)
+	}
+}
+
+func (s *Server) rateLimitStatus(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	core := map[string]any{"limit": s.rateLimit, "remaining": max(s.rateLimit-s.requests, 0), "used": min(s.requests, s.rateLimit), "reset": s.reset.Unix()}
+	writeJSON(w, http.StatusOK, map[string]any{"resources": map[string]any{"core": core}, "rate": core})
+}
+
+// listTags pages the tags like GitHub, linking the next page while there is one.
+func (s *Server) listTags(w http.ResponseWriter, req *http.Request, r *repository) {
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(req.URL.Query().Get("per_page"))
+	page, perPage = max(page, 1), min(max(perPage, 1), 100)
+	if req.URL.Query().Get("per_page") == "" {
+		perPage = 30
+	}
+	start := min((page-1)*perPage, len(r.tags))
+	end := min(start+perPage, len(r.tags))
+	if end < len(r.tags) {
+		next := *req.URL
+		query := next.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		query.Set("per_page", strconv.Itoa(perPage))
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="next"`, s.URL, next.RequestURI()))
+	}
+	tags := []map[string]any{}
+	for _, tag := range r.tags[start:end] {
+		tags = append(tags, map[string]any{"name": tag.Name, "commit": map[string]string{"sha": tag.Commit}})
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+func (s *Server) getRelease(w http.ResponseWriter, req *http.Request, r *repository) {
+	release, ok := r.releases[req.PathValue("tag")]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	assets := []map[string]string{}
+	for _, asset := range release.Assets {
+		assets = append(assets, map[string]string{"name": asset.Name, "browser_download_url": asset.URL})
+	}
+	body := map[string]any{
+		"tag_name":   release.Tag,
+		"name":       release.Name,
+		"body":       release.Body,
+		"html_url":   release.URL,
+		"prerelease": release.Prerelease,
+		"assets":     assets,
+	}
+	if !release.PublishedAt.IsZero() {
+		body["published_at"] = release.PublishedAt.UTC().Format(time.RFC3339)
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// listCommits lists the commit a branch, tag or commit sha points to.
+func (s *Server) listCommits(w http.ResponseWriter, req *http.Request, r *repository) {
+	commit := r.resolve(req.URL.Query().Get("sha"))
+	if commit == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "No commit found for SHA: " + req.URL.Query().Get("sha")})
+		return
+	}
+	writeJSON(w, http.StatusOK, []map[string]any{{"sha": commit, "commit": map[string]string{"message": "commit " + commit}}})
+}
+
+func (r *repository) resolve(ref string) string {
+	if commit, ok := r.branches[ref]; ok {
+		return commit
+	}
+	for _, tag := range r.tags {
+		if tag.Name == ref || tag.Commit == ref {
+			return tag.Commit
+		}
+	}
+	return ""
+}
+
+func (s *Server) getContents(w http.ResponseWriter, req *http.Request, r *repository) {
+	content, ok := r.files[req.URL.Query().Get("ref")][req.PathValue("path")]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"type":     "file",
+		"path":     req.PathValue("path"),
+		"encoding": "base64",
+		"size":     len(content),
+		"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+	})
+}
+
+// registry serves the manifests of pushed images at /v2/<repository>/manifests/<tag>, to
+// bearer tokens from /token only.
+func (s *Server) registry(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") != "Bearer "+token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="forgetest"`, s.URL))
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"errors": []map[string]string{{"code": "UNAUTHORIZED", "message": "authentication required"}}})
+		return
+	}
+	if req.URL.Path == "/v2/" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	repository, reference, ok := strings.Cut(strings.TrimPrefix(req.URL.Path, "/v2/"), "/manifests/")
+	if !ok || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		http.NotFound(w, req)
+		return
+	}
+	s.mu.Lock()
+	manifest, found := s.images[repository][reference]
+	if !found {
+		for _, m := range s.images[repository] {
+			if digest(m) == reference {
+				manifest, found = m, true
+			}
+		}
+	}
+	s.mu.Unlock()
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]any{"errors": []map[string]string{{"code": "MANIFEST_UNKNOWN", "message": "manifest unknown"}}})
+		return
+	}
+	var media struct {
+		MediaType string `json:"mediaType"`
+	}
+	json.Unmarshal(manifest, &media)
+	w.Header().Set("Content-Type", media.MediaType)
+	w.Header().Set("Docker-Content-Digest", digest(manifest))
+	w.Header().Set("Content-Length", strconv.Itoa(len(manifest)))
+	if req.Method == http.MethodGet {
+		w.Write(manifest)
+	}
+}
@@ -0,0 +1,104 @@
+package forgetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/mutation"
+	"github.com/base/node/dependency_updater/pkg/ociartifact"
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// repo writes a versions.json tracking paradigmxyz/reth releases and the main branch of
+// base/node, with reth's image in the server's registry.
+func repo(t *testing.T, s *Server) string {
+	t.Helper()
+	dir := t.TempDir()
+	manifest := fmt.Sprintf(`{
+		"base_node": {"branch": "main", "commit": "n1", "owner": "base", "repo": "node", "tracking": "branch"},
+		"reth": {"tag": "v1.0.0", "commit": "r100", "owner": "paradigmxyz", "repo": "reth", "tracking": "release", "image": "%s/paradigmxyz/reth"}
+	}`, s.Registry())
+	if err := os.WriteFile(filepath.Join(dir, "versions.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "versions.env"), []byte("export RETH_TAG=v1.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPipeline(t *testing.T) {
+	s := New(t)
+	// 150 older tags put v1.0.0 on the second page.
+	for i := range 150 {
+		s.AddTag("paradigmxyz", "reth", fmt.Sprintf("v0.1.%d", i), fmt.Sprintf("r01%d", i))
+	}
+	s.AddTag("paradigmxyz", "reth", "v1.0.0", "r100")
+	s.AddTag("paradigmxyz", "reth", "v1.1.0", "r110")
+	s.AddTag("paradigmxyz", "reth", "v1.2.0-rc.1", "r120")
+	s.AddRelease("paradigmxyz", "reth", sources.Release{Tag: "v1.1.0", Name: "Reth v1.1.0", PublishedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)})
+	s.AddRelease("paradigmxyz", "reth", sources.Release{Tag: "v1.2.0-rc.1", Prerelease: true})
+	s.SetBranch("base", "node", "main", "n2")
+	digest := s.PushImage("paradigmxyz/reth", "v1.1.0", []byte(`{"mediaType": "application/vnd.oci.image.index.v1+json", "manifests": []}`))
+
+	dir := repo(t, s)
+	set := sources.NewSet(s.SourceOptions())
+	result, err := runner.Run(context.Background(), runner.Options{RepoPath: dir, Sources: set})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	var updates []string
+	for _, planned := range result.Planned {
+		updates = append(updates, planned.Dependency+"@"+planned.Commit)
+	}
+	if got := strings.Join(updates, " "); got != "base_node@n2 reth@r110" {
+		t.Errorf("Run() planned %s, want base_node at n2 and reth at r110", got)
+	}
+
+	dependencies, err := version.ReadDependencies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reth := dependencies["reth"]; reth.Tag != "v1.1.0" || reth.Commit != "r110" {
+		t.Errorf("reth after Run() = %+v, want v1.1.0 at r110", reth)
+	}
+	env, _ := os.ReadFile(filepath.Join(dir, "versions.env"))
+	if !strings.Contains(string(env), "export RETH_TAG=v1.1.0\n") || !strings.Contains(string(env), "export BASE_NODE_COMMIT=n2\n") {
+		t.Errorf("versions.env after Run() =\n%s", env)
+	}
+
+	registry := &ociartifact.Pusher{HTTP: s.Client(), PlainHTTP: true}
+	reth := dependencies["reth"]
+	reth.ImageDigest = digest
+	dependencies["reth"] = reth
+	if mutated := mutation.Mutated(mutation.Check(context.Background(), registry, dependencies)); len(mutated) != 0 {
+		t.Errorf("Check() = %+v before the tag was re-pushed", mutated)
+	}
+	s.PushImage("paradigmxyz/reth", "v1.1.0", []byte(`{"mediaType": "application/vnd.oci.image.index.v1+json", "manifests": [{}]}`))
+	if mutated := mutation.Mutated(mutation.Check(context.Background(), registry, dependencies)); len(mutated) != 1 {
+		t.Errorf("Check() = %+v, want reth re-pushed", mutated)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := New(t)
+	s.AddTag("paradigmxyz", "reth", "v1.1.0", "r110")
+	s.SetBranch("base", "node", "main", "n2")
+	s.SetRateLimit(0)
+
+	_, err := runner.Run(context.Background(), runner.Options{RepoPath: repo(t, s), Sources: sources.NewSet(s.SourceOptions()), DryRun: true})
+	if err == nil || !strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("Run() = %v, want a rate limit error", err)
+	}
+	// The client doesn't retry once GitHub said the limit is exhausted.
+	if requests := s.Requests(); requests != 1 {
+		t.Errorf("Server got %d requests, want 1", requests)
+	}
+}
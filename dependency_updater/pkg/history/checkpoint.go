@@ -0,0 +1,85 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// checkpointMaxAge is how old a checkpoint may be to be resumed, later the fetched versions
+// are likely stale.
+const checkpointMaxAge = 24 * time.Hour
+
+// Checkpoint records the dependencies an unfinished run already resolved, so a run
+// interrupted by a crash, a signal or an exhausted rate limit resumes instead of fetching
+// them again.
+type Checkpoint struct {
+	path string
+	// Manifest is the sha256 of the versions.json the run started from. A checkpoint of a
+	// different versions.json is discarded.
+	Manifest  string    `json:"manifest"`
+	StartedAt time.Time `json:"startedAt"`
+	// Resolved maps dependencies to their update, nil if they were up to date.
+	Resolved map[string]*version.PlannedUpdate `json:"resolved"`
+}
+
+// OpenCheckpoint resumes the checkpoint at path if it was taken against manifest, the
+// content of versions.json, less than a day before now. Otherwise it starts a new one.
+func OpenCheckpoint(path string, manifest []byte, now time.Time) (*Checkpoint, error) {
+	sum := sha256.Sum256(manifest)
+	fresh := &Checkpoint{path: path, Manifest: hex.EncodeToString(sum[:]), StartedAt: now, Resolved: map[string]*version.PlannedUpdate{}}
+
+	f, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fresh, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint: %s", err)
+	}
+	var checkpoint Checkpoint
+	if json.Unmarshal(f, &checkpoint) != nil || checkpoint.Manifest != fresh.Manifest || now.Sub(checkpoint.StartedAt) > checkpointMaxAge || checkpoint.Resolved == nil {
+		return fresh, nil
+	}
+	checkpoint.path = path
+	return &checkpoint, nil
+}
+
+// Lookup returns the recorded update of name and whether it was resolved at all.
+func (c *Checkpoint) Lookup(name string) (*version.PlannedUpdate, bool) {
+	planned, ok := c.Resolved[name]
+	return planned, ok
+}
+
+// Record stores the update resolved for name, nil if up to date, and writes the checkpoint.
+func (c *Checkpoint) Record(name string, planned *version.PlannedUpdate) error {
+	c.Resolved[name] = planned
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error marshaling checkpoint: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("error creating checkpoint directory: %s", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing checkpoint: %s", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("error writing checkpoint: %s", err)
+	}
+	return nil
+}
+
+// Remove deletes the checkpoint once the run it belongs to finished.
+func (c *Checkpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing checkpoint: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Package history records what the updater did for each dependency across runs.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Component is the recorded state of one dependency.
+type Component struct {
+	LastChecked time.Time `json:"lastChecked"`
+	// LastVersion is the version the last check selected, empty if it was up to date.
+	LastVersion string `json:"lastVersion,omitempty"`
+	// LastApplied is when the updater last changed the dependency's version, and Previous
+	// the version it replaced.
+	LastApplied time.Time `json:"lastApplied,omitzero"`
+	Previous    *Pin      `json:"previous,omitempty"`
+}
+
+// Pin is a dependency version as versions.json records it.
+type Pin struct {
+	Tag    string `json:"tag,omitempty"`
+	Commit string `json:"commit"`
+}
+
+// String is the tag, or the commit of dependencies tracking a branch.
+func (p Pin) String() string {
+	if p.Tag != "" {
+		return p.Tag
+	}
+	return p.Commit
+}
+
+// DB is the history file, keyed by dependency name.
+type DB struct {
+	path       string
+	Components map[string]*Component `json:"components"`
+	// Audit is the log of pins and unpins, oldest first.
+	Audit []Event `json:"audit,omitempty"`
+	// Runs is the log of runs, oldest first, see Record.
+	Runs []Run `json:"runs,omitempty"`
+}
+
+// Event is a pin or unpin of a dependency.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Dependency string    `json:"dependency"`
+	// Action is "pin" or "unpin".
+	Action string `json:"action"`
+	Tag    string `json:"tag,omitempty"`
+	Commit string `json:"commit,omitempty"`
+	// Until is when the pin expires, if it does.
+	Until time.Time `json:"until,omitzero"`
+}
+
+// PinEvent is the event of applying planned at now.
+func PinEvent(planned version.PlannedUpdate, now time.Time) Event {
+	if !planned.Pin {
+		return Event{Time: now, Dependency: planned.Dependency, Action: "unpin"}
+	}
+	return Event{Time: now, Dependency: planned.Dependency, Action: "pin", Tag: planned.Version, Commit: planned.Commit, Until: planned.PinUntil}
+}
+
+// Open reads the history at path, an empty history if the file does not exist yet.
+func Open(path string) (*DB, error) {
+	db := &DB{path: path, Components: map[string]*Component{}}
+	f, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading history: %s", err)
+	}
+	if err := json.Unmarshal(f, db); err != nil {
+		return nil, fmt.Errorf("error parsing history %s: %s", path, err)
+	}
+	if db.Components == nil {
+		db.Components = map[string]*Component{}
+	}
+	return db, nil
+}
+
+// Save writes the history back to the path it was opened from.
+func (db *DB) Save() error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling history: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(db.path), 0755); err != nil {
+		return fmt.Errorf("error creating history directory: %s", err)
+	}
+	tmp := db.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing history: %s", err)
+	}
+	if err := os.Rename(tmp, db.path); err != nil {
+		return fmt.Errorf("error writing history: %s", err)
+	}
+	return nil
+}
+
+// Component returns the record for name, creating it if needed.
+func (db *DB) Component(name string) *Component {
+	c, ok := db.Components[name]
+	if !ok {
+		c = &Component{}
+		db.Components[name] = c
+	}
+	return c
+}
+
+// Due reports whether name should be checked at now given its check interval. Components
+// never checked and a zero interval are always due.
+func (db *DB) Due(name string, interval time.Duration, now time.Time) bool {
+	c, ok := db.Components[name]
+	if !ok || interval <= 0 {
+		return true
+	}
+	return !now.Before(c.LastChecked.Add(interval))
+}
+
+// Checked records a check of name at now that selected selected, empty if up to date.
+func (db *DB) Checked(name string, now time.Time, selected string) {
+	c := db.Component(name)
+	c.LastChecked = now
+	c.LastVersion = selected
+}
+
+// Applied records that the version of name was changed from previous at now.
+func (db *DB) Applied(name string, now time.Time, previous Pin) {
+	c := db.Component(name)
+	c.LastApplied = now
+	c.Previous = &previous
+}
@@ -0,0 +1,173 @@
+package history
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+func TestDue(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "history", "history.json")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() of a missing file unexpected error: %v", err)
+	}
+	db.Checked("op_node", now.Add(-2*time.Hour), "v1.2.0")
+	if err := db.Save(); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	if db, err = Open(path); err != nil || db.Components["op_node"].LastVersion != "v1.2.0" {
+		t.Fatalf("Open() after Save() = %v, %v", db.Components["op_node"], err)
+	}
+
+	tests := []struct {
+		name      string
+		component string
+		interval  time.Duration
+		want      bool
+	}{
+		{"never checked", "op_geth", 24 * time.Hour, true},
+		{"no interval", "op_node", 0, true},
+		{"interval elapsed", "op_node", 2 * time.Hour, true},
+		{"interval not elapsed", "op_node", 3 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := db.Due(tt.component, tt.interval, now); got != tt.want {
+				t.Errorf("Due(%s, %s) = %v, want %v", tt.component, tt.interval, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	manifest := []byte(`{"op_node": {}}`)
+	checkpoint, err := OpenCheckpoint(path, manifest, now)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint() unexpected error: %v", err)
+	}
+	if err := checkpoint.Record("op_node", &version.PlannedUpdate{Dependency: "op_node", Version: "v1.1.0"}); err != nil {
+		t.Fatalf("Record() unexpected error: %v", err)
+	}
+	if err := checkpoint.Record("op_geth", nil); err != nil {
+		t.Fatalf("Record() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		manifest []byte
+		at       time.Time
+		resumed  bool
+	}{
+		{"same run", manifest, now.Add(time.Hour), true},
+		{"versions.json changed", []byte(`{"op_node": {"tag": "v1.1.0"}}`), now.Add(time.Hour), false},
+		{"too old", manifest, now.Add(48 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resumed, err := OpenCheckpoint(path, tt.manifest, tt.at)
+			if err != nil {
+				t.Fatalf("OpenCheckpoint() unexpected error: %v", err)
+			}
+			planned, ok := resumed.Lookup("op_node")
+			if ok != tt.resumed || (ok && planned.Version != "v1.1.0") {
+				t.Errorf("Lookup(op_node) = %+v, %v, want resumed %v", planned, ok, tt.resumed)
+			}
+			if planned, ok := resumed.Lookup("op_geth"); ok != tt.resumed || planned != nil {
+				t.Errorf("Lookup(op_geth) = %+v, %v, want up to date", planned, ok)
+			}
+		})
+	}
+
+	if err := checkpoint.Remove(); err != nil {
+		t.Fatalf("Remove() unexpected error: %v", err)
+	}
+	if resumed, _ := OpenCheckpoint(path, manifest, now); len(resumed.Resolved) != 0 {
+		t.Errorf("OpenCheckpoint() after Remove() resumed %d dependencies", len(resumed.Resolved))
+	}
+}
+
+func TestCompare(t *testing.T) {
+	day := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	db := &DB{Components: map[string]*Component{}}
+	db.Record(Run{
+		Time:    day,
+		Applied: []Change{{Dependency: "op_node", From: "v1.0.0", To: "v1.1.0"}},
+		Held:    []Hold{{Dependency: "op_geth", Version: "v1.101.0", Check: "provenance"}},
+		Failed:  []Failure{{Dependency: "reth", Detail: "rate limit exceeded"}},
+	})
+	db.Record(Run{Time: day, Applied: []Change{{Dependency: "op_node", From: "v1.1.0", To: "v1.2.0"}}})
+	db.Record(Run{
+		Time:    day.Add(7 * 24 * time.Hour),
+		Applied: []Change{{Dependency: "op_node", From: "v1.2.0", To: "v1.3.0"}, {Dependency: "reth", From: "v1.0.0", To: "v1.1.0"}},
+		Held:    []Hold{{Dependency: "base_node", Version: "v0.2.0", Check: "vulnscan"}},
+		Failed:  []Failure{{Dependency: "op_geth", Check: "license", Detail: "MIT to BUSL"}},
+	})
+	db.Audit = []Event{{Time: day.Add(-time.Hour), Dependency: "op_node", Action: "pin"}, {Time: day.Add(time.Hour), Dependency: "op_node", Action: "unpin"}}
+
+	if db.Runs[0].ID != "20260302T120000Z" || db.Runs[1].ID != "20260302T120000Z-2" {
+		t.Fatalf("Record() IDs = %s, %s, want the start time with a suffix for the second run", db.Runs[0].ID, db.Runs[1].ID)
+	}
+
+	c, err := db.Compare("20260302T120000Z")
+	if err != nil {
+		t.Fatalf("Compare() unexpected error: %v", err)
+	}
+	if c.From.ID != db.Runs[0].ID || c.To.ID != db.Runs[2].ID || c.Runs != 2 || c.FailedRuns != 0 {
+		t.Errorf("Compare() = %s to %s over %d runs, %d failed", c.From.ID, c.To.ID, c.Runs, c.FailedRuns)
+	}
+	if want := []Change{{"op_node", "v1.1.0", "v1.3.0"}, {"reth", "v1.0.0", "v1.1.0"}}; !slices.Equal(c.Applied, want) {
+		t.Errorf("Compare() applied %+v, want %+v", c.Applied, want)
+	}
+	if len(c.NewlyHeld) != 1 || c.NewlyHeld[0].Check != "vulnscan" || len(c.Released) != 1 || c.Released[0].Dependency != "op_geth" {
+		t.Errorf("Compare() newly held %+v, released %+v", c.NewlyHeld, c.Released)
+	}
+	if len(c.NewFailures) != 1 || c.NewFailures[0].Check != "license" || len(c.Recovered) != 1 || c.Recovered[0].Dependency != "reth" {
+		t.Errorf("Compare() new failures %+v, recovered %+v", c.NewFailures, c.Recovered)
+	}
+	if len(c.Pins) != 1 || c.Pins[0].Action != "unpin" {
+		t.Errorf("Compare() pins %+v, want the unpin after the first run", c.Pins)
+	}
+
+	// The date of the first runs compares against none of them.
+	if c, err := db.Compare(day.Local().Format(time.DateOnly)); err != nil || c.From != nil || c.Runs != 3 || c.FailedRuns != 1 || len(c.Pins) != 2 {
+		t.Errorf("Compare(date) = %+v, %v, want all 3 runs", c, err)
+	}
+	if c, err := db.Compare(day.Add(30 * 24 * time.Hour).Format(time.RFC3339)); err != nil || c.To != nil || c.Runs != 0 {
+		t.Errorf("Compare(after the last run) = %+v, %v, want no runs", c, err)
+	}
+	if _, err := db.Compare("last week"); err == nil {
+		t.Error("Compare(last week) = nil error, want an error")
+	}
+}
+
+// Dependencies a run skips, because they were pinned or not due, keep their holds.
+func TestCompareSkipped(t *testing.T) {
+	day := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	db := &DB{Components: map[string]*Component{}}
+	db.Record(Run{
+		Time:    day,
+		Checked: []string{"op_geth", "op_node", "reth"},
+		Held:    []Hold{{Dependency: "op_geth", Version: "v1.101.0", Check: "provenance"}, {Dependency: "reth", Version: "v1.1.0", Check: "license"}},
+	})
+	db.Audit = []Event{{Time: day.Add(time.Hour), Dependency: "op_geth", Action: "pin"}}
+	db.Record(Run{Time: day.Add(2 * time.Hour), Checked: []string{"op_node", "reth"}})
+	db.Record(Run{Time: day.Add(3 * time.Hour), Checked: []string{"op_node"}})
+
+	c, err := db.Compare(db.Runs[0].ID)
+	if err != nil {
+		t.Fatalf("Compare() unexpected error: %v", err)
+	}
+	if len(c.NewlyHeld) != 0 || len(c.Released) != 1 || c.Released[0].Dependency != "reth" {
+		t.Errorf("Compare() newly held %+v, released %+v, want only reth released", c.NewlyHeld, c.Released)
+	}
+	if len(c.Pins) != 1 || c.Pins[0].Dependency != "op_geth" {
+		t.Errorf("Compare() pins %+v, want the pin of op_geth", c.Pins)
+	}
+}
@@ -0,0 +1,220 @@
+package history
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// maxRuns bounds the run log, older runs are dropped first.
+const maxRuns = 1000
+
+// Run is the record of one run that was not a dry run. Commands applying versions chosen by
+// hand, such as pins and rollbacks, check nothing and aren't runs.
+type Run struct {
+	// ID is the run's UTC start time, such as 20260101T120000Z, with a suffix if an earlier
+	// run started in the same second.
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Checked are the dependencies the run resolved, pinned dependencies and those whose
+	// check interval hadn't elapsed are not.
+	Checked []string `json:"checked,omitempty"`
+	// Applied are the version changes the run wrote.
+	Applied []Change `json:"applied,omitempty"`
+	// Held are the candidates a check kept back.
+	Held []Hold `json:"held,omitempty"`
+	// Failed are the dependencies that could not be resolved, which ends the run, and the
+	// checks that failed without holding.
+	Failed []Failure `json:"failed,omitempty"`
+}
+
+// Change is a dependency moving from one version to another.
+type Change struct {
+	Dependency string `json:"dependency"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+}
+
+// Hold is a candidate a check kept back.
+type Hold struct {
+	Dependency string `json:"dependency"`
+	Version    string `json:"version"`
+	Check      string `json:"check"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// Failure is a dependency that failed to resolve, or a failed check if Check is set.
+type Failure struct {
+	Dependency string `json:"dependency"`
+	Check      string `json:"check,omitempty"`
+	Detail     string `json:"detail"`
+}
+
+// Record appends run to the run log, giving it its ID.
+func (db *DB) Record(run Run) {
+	id := run.Time.UTC().Format("20060102T150405Z")
+	run.ID = id
+	for n := 2; slices.ContainsFunc(db.Runs, func(r Run) bool { return r.ID == run.ID }); n++ {
+		run.ID = fmt.Sprintf("%s-%d", id, n)
+	}
+	db.Runs = append(db.Runs, run)
+	if len(db.Runs) > maxRuns {
+		db.Runs = db.Runs[len(db.Runs)-maxRuns:]
+	}
+}
+
+// Comparison is what changed between two runs of the log.
+type Comparison struct {
+	// From is the run compared against, nil if since predates the log, and To the latest run.
+	From *Run `json:"from,omitempty"`
+	To   *Run `json:"to,omitempty"`
+	// Runs counts the runs after From, FailedRuns those of them that failed to resolve a
+	// dependency.
+	Runs       int `json:"runs"`
+	FailedRuns int `json:"failedRuns"`
+	// Applied is each dependency's first and last version of the changes made since From.
+	Applied []Change `json:"applied,omitempty"`
+	// NewlyHeld are the holds as of To that From did not have, Released those of From that
+	// To no longer has. A dependency's holds as of a run are those of the last run up to it
+	// that checked the dependency.
+	NewlyHeld []Hold `json:"newlyHeld,omitempty"`
+	Released  []Hold `json:"released,omitempty"`
+	// NewFailures are the failures since From that From did not have, Recovered those of
+	// From that To no longer has, as of each run like the holds.
+	NewFailures []Failure `json:"newFailures,omitempty"`
+	Recovered   []Failure `json:"recovered,omitempty"`
+	// Pins are the pins and unpins since From.
+	Pins []Event `json:"pins,omitempty"`
+}
+
+// Compare compares the latest run with the one since names, either a run ID or a date or
+// RFC 3339 time, which names the last run at or before it.
+func (db *DB) Compare(since string) (*Comparison, error) {
+	start := -1
+	if i := slices.IndexFunc(db.Runs, func(r Run) bool { return r.ID == since }); i >= 0 {
+		start = i
+	} else {
+		t, err := parseSince(since)
+		if err != nil {
+			return nil, err
+		}
+		for i, run := range db.Runs {
+			if run.Time.After(t) {
+				break
+			}
+			start = i
+		}
+	}
+
+	c := &Comparison{}
+	var from Run
+	var after time.Time
+	if start >= 0 {
+		from = db.Runs[start]
+		c.From, after = &from, from.Time
+	}
+	fromHeld, fromFailed := state(db.Runs[:start+1])
+	for _, event := range db.Audit {
+		if event.Time.After(after) {
+			c.Pins = append(c.Pins, event)
+		}
+	}
+	later := db.Runs[start+1:]
+	if len(later) == 0 {
+		return c, nil
+	}
+	to := later[len(later)-1]
+	c.To, c.Runs = &to, len(later)
+
+	applied := map[string]int{}
+	for _, run := range later {
+		failed := false
+		for _, change := range run.Applied {
+			if i, ok := applied[change.Dependency]; ok {
+				c.Applied[i].To = change.To
+				continue
+			}
+			applied[change.Dependency] = len(c.Applied)
+			c.Applied = append(c.Applied, change)
+		}
+		for _, failure := range run.Failed {
+			failed = failed || failure.Check == ""
+			if !slices.ContainsFunc(fromFailed, failure.same) && !slices.ContainsFunc(c.NewFailures, failure.same) {
+				c.NewFailures = append(c.NewFailures, failure)
+			}
+		}
+		if failed {
+			c.FailedRuns++
+		}
+	}
+	toHeld, toFailed := state(db.Runs)
+	for _, hold := range toHeld {
+		if !slices.ContainsFunc(fromHeld, hold.same) {
+			c.NewlyHeld = append(c.NewlyHeld, hold)
+		}
+	}
+	for _, hold := range fromHeld {
+		if !slices.ContainsFunc(toHeld, hold.same) {
+			c.Released = append(c.Released, hold)
+		}
+	}
+	for _, failure := range fromFailed {
+		if !slices.ContainsFunc(toFailed, failure.same) {
+			c.Recovered = append(c.Recovered, failure)
+		}
+	}
+	return c, nil
+}
+
+// state returns the holds and failures as of the last of runs, each dependency's from the
+// last run that checked it. Runs skip dependencies that are pinned or not due, which keep
+// the holds of their last check. A run checked the dependencies it lists as checked and
+// those it applied, held or failed.
+func state(runs []Run) ([]Hold, []Failure) {
+	held, failed := map[string][]Hold{}, map[string][]Failure{}
+	for _, run := range runs {
+		checked := slices.Clone(run.Checked)
+		for _, change := range run.Applied {
+			checked = append(checked, change.Dependency)
+		}
+		for _, hold := range run.Held {
+			checked = append(checked, hold.Dependency)
+		}
+		for _, failure := range run.Failed {
+			checked = append(checked, failure.Dependency)
+		}
+		for _, dependency := range checked {
+			held[dependency] = slices.DeleteFunc(slices.Clone(run.Held), func(h Hold) bool { return h.Dependency != dependency })
+			failed[dependency] = slices.DeleteFunc(slices.Clone(run.Failed), func(f Failure) bool { return f.Dependency != dependency })
+		}
+	}
+	var holds []Hold
+	var failures []Failure
+	for _, dependency := range slices.Sorted(maps.Keys(held)) {
+		holds = append(holds, held[dependency]...)
+		failures = append(failures, failed[dependency]...)
+	}
+	return holds, failures
+}
+
+// same reports whether h and other are the same check holding the same dependency, at any
+// version.
+func (h Hold) same(other Hold) bool {
+	return h.Dependency == other.Dependency && h.Check == other.Check
+}
+
+func (f Failure) same(other Failure) bool {
+	return f.Dependency == other.Dependency && f.Check == other.Check
+}
+
+func parseSince(since string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	// A date starts at midnight, its own runs are among those compared.
+	if t, err := time.ParseInLocation(time.DateOnly, since, time.Local); err == nil {
+		return t.Add(-time.Nanosecond), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q, expected a run ID, a date such as 2026-01-31 or an RFC 3339 time", since)
+}
@@ -0,0 +1,174 @@
+// Package httpreplay records the HTTP interactions of a client to a cassette file and replays
+// them, so the clients of GitHub and the image registries are tested end to end against
+// recorded responses without the network. Tests record anew with their -record flag.
+package httpreplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// RequestBody is the body of requests that have one, such as a GraphQL query.
+	RequestBody string      `json:"requestBody,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Transport replays the interactions of the cassette at Path in the order they were
+// recorded, repeated requests getting the next recorded response. With Record it sends the
+// requests with Base, http.DefaultTransport if nil, and Save writes them to Path.
+type Transport struct {
+	Path   string
+	Record bool
+	Base   http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New returns a Transport replaying the cassette at path, or recording to it.
+func New(path string, record bool) (*Transport, error) {
+	t := &Transport{Path: path, Record: record}
+	if record {
+		return t, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %s", err)
+	}
+	if err := json.Unmarshal(content, &t.cassette); err != nil {
+		return nil, fmt.Errorf("error decoding cassette %s: %s", path, err)
+	}
+	t.used = make([]bool, len(t.cassette.Interactions))
+	return t, nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if t.Record {
+		return t.record(req, body)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, interaction := range t.cassette.Interactions {
+		if t.used[i] || interaction.Method != req.Method || interaction.URL != req.URL.String() || interaction.RequestBody != string(body) {
+			continue
+		}
+		t.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode:    interaction.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Body))),
+			ContentLength: int64(len(interaction.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s has no recorded response left for %s %s", t.Path, req.Method, req.URL)
+}
+
+// secretHeaders are response headers never written to cassettes.
+var secretHeaders = []string{"Set-Cookie", "X-Github-Request-Id", "X-Request-Id"}
+
+// tokenField matches the tokens registries hand out in JSON bodies.
+var tokenField = regexp.MustCompile(`("(?:token|access_token|refresh_token)"\s*:\s*)"[^"]*"`)
+
+func (t *Transport) record(req *http.Request, body []byte) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := resp.Header.Clone()
+	for _, name := range secretHeaders {
+		header.Del(name)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(body),
+		Status:      resp.StatusCode,
+		Header:      header,
+		Body:        tokenField.ReplaceAllString(string(respBody), `$1"redacted"`),
+	})
+	return resp, nil
+}
+
+// Save writes the recorded interactions to Path. It does nothing when replaying.
+func (t *Transport) Save() error {
+	if !t.Record {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	encoded, err := json.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.Path), 0755); err != nil {
+		return fmt.Errorf("error creating the cassette directory: %s", err)
+	}
+	if err := os.WriteFile(t.Path, append(encoded, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing cassette: %s", err)
+	}
+	return nil
+}
+
+// Client returns a client replaying, or recording, the cassette at path for a test, saving
+// the recording when the test ends.
+func Client(t testing.TB, path string, record bool) *http.Client {
+	t.Helper()
+	transport, err := New(path, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := transport.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+	return &http.Client{Transport: transport}
+}
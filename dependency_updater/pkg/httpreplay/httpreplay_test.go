@@ -0,0 +1,78 @@
+package httpreplay
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		body, _ := io.ReadAll(req.Body)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s"})
+		w.Header().Set("X-Call", strings.Repeat("i", calls))
+		w.Write([]byte(`{"token": "secret", "path": "` + req.URL.Path + `", "query": "` + string(body) + `"}`))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "testdata", "cassette.json")
+
+	get := func(client *http.Client, body string) string {
+		t.Helper()
+		method := http.MethodGet
+		if body != "" {
+			method = http.MethodPost
+		}
+		req, _ := http.NewRequest(method, server.URL+"/tags", strings.NewReader(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s unexpected error: %v", method, err)
+		}
+		defer resp.Body.Close()
+		content, _ := io.ReadAll(resp.Body)
+		return resp.Header.Get("X-Call") + " " + string(content)
+	}
+
+	recorder, err := New(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recording := &http.Client{Transport: recorder}
+	first, second, query := get(recording, ""), get(recording, ""), get(recording, "q")
+	if !strings.Contains(first, `"secret"`) {
+		t.Errorf("recording returned %s, want the real response", first)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "secret") || strings.Contains(string(content), "session") {
+		t.Errorf("cassette kept a secret:\n%s", content)
+	}
+
+	replaying := Client(t, path, false)
+	redacted := strings.Replace(first, `"secret"`, `"redacted"`, 1)
+	if got := get(replaying, "q"); got != strings.Replace(query, `"secret"`, `"redacted"`, 1) {
+		t.Errorf("replayed POST = %s", got)
+	}
+	if got := get(replaying, ""); got != redacted {
+		t.Errorf("first replayed GET = %s, want %s", got, redacted)
+	}
+	if got := get(replaying, ""); got != strings.Replace(second, `"secret"`, `"redacted"`, 1) {
+		t.Errorf("second replayed GET = %s, want the second recorded response", got)
+	}
+	if _, err := replaying.Get(server.URL + "/tags"); err == nil || !strings.Contains(err.Error(), "no recorded response left") {
+		t.Errorf("third replayed GET = %v, want an error", err)
+	}
+	if calls != 3 {
+		t.Errorf("server called %d times, want 3 while recording only", calls)
+	}
+}
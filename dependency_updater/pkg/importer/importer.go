@@ -0,0 +1,287 @@
+// Package importer converts the dependencies tracked by Renovate and Dependabot configurations,
+// or found by scanning a repository, into versions.json entries.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Entry is a dependency found in a bot configuration. Its Info has no commit yet, the caller
+// resolves it from the source.
+type Entry struct {
+	Name string
+	Info *version.Info
+	// Origin is the file the current version was read from.
+	Origin string
+}
+
+// Result is what an import recognized, and a reason for each entry it could not convert.
+type Result struct {
+	Entries []Entry
+	Skipped []string
+}
+
+type renovateConfig struct {
+	EnabledManagers []string          `json:"enabledManagers"`
+	CustomManagers  []renovateManager `json:"customManagers"`
+	// RegexManagers is the name customManagers had before Renovate 36.
+	RegexManagers []renovateManager `json:"regexManagers"`
+}
+
+type renovateManager struct {
+	CustomType         string   `json:"customType"`
+	FileMatch          []string `json:"fileMatch"`
+	MatchStrings       []string `json:"matchStrings"`
+	DatasourceTemplate string   `json:"datasourceTemplate"`
+	DepNameTemplate    string   `json:"depNameTemplate"`
+}
+
+// Renovate converts the regex managers of a renovate.json, and the docker images of the
+// repository's Dockerfiles unless enabledManagers leaves out the dockerfile manager.
+func Renovate(repoPath string, config []byte) (*Result, error) {
+	var parsed renovateConfig
+	if err := json.Unmarshal(config, &parsed); err != nil {
+		return nil, fmt.Errorf("error unmarshalling renovate config: %s", err)
+	}
+	result := &Result{}
+	for _, manager := range append(parsed.CustomManagers, parsed.RegexManagers...) {
+		if manager.CustomType != "" && manager.CustomType != "regex" {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("custom manager of type %s", manager.CustomType))
+			continue
+		}
+		if err := result.regexManager(repoPath, manager); err != nil {
+			return nil, err
+		}
+	}
+	if len(parsed.EnabledManagers) == 0 || slices.Contains(parsed.EnabledManagers, "dockerfile") {
+		if err := result.dockerfiles(repoPath, repoPath, true); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Dependabot converts the docker entries of a dependabot.yml, reading the Dockerfiles in each
+// entry's directory. Other package ecosystems have no equivalent here and are skipped.
+func Dependabot(repoPath string, config []byte) (*Result, error) {
+	result := &Result{}
+	for _, update := range parseDependabot(config) {
+		if update.ecosystem != "docker" {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s updates in %s", update.ecosystem, update.directory))
+			continue
+		}
+		if err := result.dockerfiles(repoPath, filepath.Join(repoPath, update.directory), false); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (r *Result) regexManager(repoPath string, manager renovateManager) error {
+	var fileMatch []*regexp.Regexp
+	for _, pattern := range manager.FileMatch {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid renovate fileMatch %q: %s", pattern, err)
+		}
+		fileMatch = append(fileMatch, re)
+	}
+	var matchStrings []*regexp.Regexp
+	for _, pattern := range manager.MatchStrings {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid renovate matchStrings %q: %s", pattern, err)
+		}
+		matchStrings = append(matchStrings, re)
+	}
+
+	return walkFiles(repoPath, repoPath, true, func(path string, rel string) error {
+		if !matchesAny(fileMatch, rel) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", rel, err)
+		}
+		for _, re := range matchStrings {
+			for _, m := range re.FindAllSubmatch(content, -1) {
+				groups := map[string]string{}
+				for i, name := range re.SubexpNames() {
+					if name != "" && m[i] != nil {
+						groups[name] = string(m[i])
+					}
+				}
+				r.add(rel, expandTemplate(manager.DatasourceTemplate, groups, "datasource"), expandTemplate(manager.DepNameTemplate, groups, "depName"), groups["currentValue"])
+			}
+		}
+		return nil
+	})
+}
+
+var fromLine = regexp.MustCompile(`(?im)^FROM\s+(?:--platform=\S+\s+)?(\S+?):([^\s@]+)`)
+
+func (r *Result) dockerfiles(repoPath string, dir string, recursive bool) error {
+	return walkFiles(repoPath, dir, recursive, func(path string, rel string) error {
+		if !isDockerfile(rel) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", rel, err)
+		}
+		for _, m := range fromLine.FindAllStringSubmatch(string(content), -1) {
+			r.add(rel, "docker", m[1], m[2])
+		}
+		return nil
+	})
+}
+
+func isDockerfile(rel string) bool {
+	base := filepath.Base(rel)
+	return base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile.") || strings.HasSuffix(base, ".Dockerfile")
+}
+
+// add records the dependency depName at currentValue, if its datasource maps to a GitHub repository.
+func (r *Result) add(origin string, datasource string, depName string, currentValue string) {
+	if depName == "" || currentValue == "" || strings.Contains(currentValue, "$") {
+		r.Skipped = append(r.Skipped, fmt.Sprintf("match in %s without a literal depName and currentValue", origin))
+		return
+	}
+	var owner, repo, tracking, variant string
+	switch datasource {
+	case "github-releases", "github-tags":
+		owner, repo, _ = strings.Cut(depName, "/")
+		tracking = map[string]string{"github-releases": "release", "github-tags": "tag"}[datasource]
+	case "docker":
+		path, ok := strings.CutPrefix(depName, "ghcr.io/")
+		if !ok {
+			r.Skipped = append(r.Skipped, fmt.Sprintf("%s in %s is not published from GitHub", depName, origin))
+			return
+		}
+		owner, repo, _ = strings.Cut(path, "/")
+		tracking = "release"
+		// Variant tags such as v1.14.0-alltools are the release tag of a variant image.
+		currentValue, variant = version.SplitImageVariant(currentValue)
+	default:
+		r.Skipped = append(r.Skipped, fmt.Sprintf("%s in %s uses the unsupported datasource %q", depName, origin, datasource))
+		return
+	}
+	if owner == "" || repo == "" || strings.Contains(repo, "/") {
+		r.Skipped = append(r.Skipped, fmt.Sprintf("%s in %s does not name an owner/repo", depName, origin))
+		return
+	}
+
+	info := &version.Info{Tag: currentValue, Owner: owner, Repo: repo, Tracking: tracking, ImageVariant: variant}
+	if prefix, _, ok := cutLast(currentValue, "/"); ok {
+		info.TagPrefix = prefix
+	}
+	name := strings.ToLower(strings.NewReplacer("-", "_", ".", "_").Replace(repo))
+	if info.TagPrefix != "" {
+		name = strings.ToLower(strings.ReplaceAll(info.TagPrefix, "-", "_"))
+	}
+	r.addEntry(Entry{Name: name, Info: info, Origin: origin})
+}
+
+// expandTemplate fills the {{{name}}} and {{name}} placeholders of a Renovate template from
+// groups, or returns the group itself if the template is empty.
+func expandTemplate(template string, groups map[string]string, group string) string {
+	if template == "" {
+		return groups[group]
+	}
+	for name, value := range groups {
+		template = strings.NewReplacer("{{{"+name+"}}}", value, "{{"+name+"}}", value).Replace(template)
+	}
+	return template
+}
+
+type dependabotUpdate struct {
+	ecosystem string
+	directory string
+}
+
+// parseDependabot reads the package-ecosystem and directory of each entry under updates. It
+// only understands the block style dependabot.yml files are written in.
+func parseDependabot(config []byte) []dependabotUpdate {
+	var updates []dependabotUpdate
+	inUpdates := false
+	scanner := bufio.NewScanner(bytes.NewReader(config))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			inUpdates = strings.HasPrefix(trimmed, "updates:")
+			continue
+		}
+		if !inUpdates {
+			continue
+		}
+		item, isItem := strings.CutPrefix(trimmed, "- ")
+		if isItem {
+			updates = append(updates, dependabotUpdate{directory: "/"})
+		}
+		key, value, ok := strings.Cut(item, ":")
+		if !ok || len(updates) == 0 {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(key) {
+		case "package-ecosystem":
+			updates[len(updates)-1].ecosystem = value
+		case "directory":
+			updates[len(updates)-1].directory = value
+		}
+	}
+	return updates
+}
+
+// walkFiles calls f for the files in dir, with their path relative to repoPath. Hidden
+// directories are not entered.
+func walkFiles(repoPath string, dir string, recursive bool, f func(path string, rel string) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (!recursive || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(repoPath, path)
+		if err != nil {
+			return err
+		}
+		return f(path, filepath.ToSlash(rel))
+	})
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func cutLast(s string, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
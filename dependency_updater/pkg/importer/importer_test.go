@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	repo := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestRenovate(t *testing.T) {
+	repo := writeFiles(t, map[string]string{
+		"versions.env":      "export OP_NODE_TAG=op-node/v1.16.11\n",
+		"reth/Dockerfile":   "FROM ghcr.io/paradigmxyz/reth:v1.9.0 AS reth\nFROM ubuntu:24.04\n",
+		".git/Dockerfile":   "FROM ghcr.io/hidden/repo:v1.0.0\n",
+		"geth/Dockerfile":   "FROM golang:${GO_VERSION}\n",
+		"docs/Dockerfile.x": "FROM ghcr.io/paradigmxyz/reth:v1.8.0\n",
+	})
+	config := `{
+		"customManagers": [{
+			"customType": "regex",
+			"fileMatch": ["^versions\\.env$"],
+			"matchStrings": ["OP_NODE_TAG=(?<currentValue>\\S+)"],
+			"datasourceTemplate": "github-tags",
+			"depNameTemplate": "ethereum-optimism/optimism"
+		}]
+	}`
+
+	result, err := Renovate(repo, []byte(config))
+	if err != nil {
+		t.Fatalf("Renovate() unexpected error: %v", err)
+	}
+	if len(result.Entries) != 2 {
+		t.Fatalf("Renovate() = %+v, want op_node and reth", result.Entries)
+	}
+	node, reth := result.Entries[0], result.Entries[1]
+	if node.Name != "op_node" || node.Info.TagPrefix != "op-node" || node.Info.Owner != "ethereum-optimism" || node.Info.Tracking != "tag" {
+		t.Errorf("regex manager entry = %s %+v", node.Name, node.Info)
+	}
+	// The first Dockerfile found wins, docs/ sorts before reth/.
+	if reth.Name != "reth" || reth.Info.Tag != "v1.8.0" || reth.Info.Owner != "paradigmxyz" || reth.Origin != "docs/Dockerfile.x" {
+		t.Errorf("dockerfile entry = %s %+v from %s", reth.Name, reth.Info, reth.Origin)
+	}
+	if len(result.Skipped) != 2 {
+		t.Errorf("Renovate() skipped %v, want ubuntu and the templated golang image", result.Skipped)
+	}
+}
+
+func TestDependabot(t *testing.T) {
+	repo := writeFiles(t, map[string]string{
+		"reth/Dockerfile":        "FROM ghcr.io/paradigmxyz/reth:v1.9.0\n",
+		"reth/nested/Dockerfile": "FROM ghcr.io/other/image:v2.0.0\n",
+	})
+	config := `version: 2
+updates:
+  # Base images
+  - package-ecosystem: "docker"
+    directory: "/reth"
+    schedule:
+      interval: daily
+  - package-ecosystem: gomod
+    directory: /dependency_updater
+`
+
+	result, err := Dependabot(repo, []byte(config))
+	if err != nil {
+		t.Fatalf("Dependabot() unexpected error: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Name != "reth" || result.Entries[0].Info.Tag != "v1.9.0" {
+		t.Errorf("Dependabot() = %+v, want reth only", result.Entries)
+	}
+	if len(result.Skipped) != 1 {
+		t.Errorf("Dependabot() skipped %v, want the gomod entry", result.Skipped)
+	}
+}
+
+func TestScan(t *testing.T) {
+	repo := writeFiles(t, map[string]string{
+		"versions.env": "export OP_NODE_REPO=https://github.com/ethereum-optimism/optimism.git\nexport OP_NODE_TAG=op-node/v1.16.11\nexport OP_NODE_COMMIT=cba7aba\n" +
+			"export GITLAB_REPO=https://gitlab.com/o/r.git\nexport GITLAB_TAG=v1.0.0\n",
+		"envs/dev.env":       "EXPLORER_REPO=https://github.com/o/explorer\nEXPLORER_BRANCH=main\n",
+		"reth/Dockerfile":    "FROM ubuntu:24.04\nRUN git clone https://github.com/paradigmxyz/reth.git --branch v1.9.0 --single-branch .\n",
+		"geth/Dockerfile":    "RUN git clone $OP_GETH_REPO --branch $OP_GETH_TAG .\n",
+		"docker-compose.yml": "services:\n  node:\n    image: ghcr.io/ethereum-optimism/op-node:op-node/v1.16.11\n  proxy:\n    image: \"ghcr.io/o/proxy:v2.0.0-rc.1\"\n  tools:\n    image: ghcr.io/o/geth:v1.14.0-alltools\n",
+	})
+	result, err := Scan(repo)
+	if err != nil {
+		t.Fatalf("Scan() unexpected error: %v", err)
+	}
+	got := map[string]version.Info{}
+	for _, entry := range result.Entries {
+		got[entry.Name] = *entry.Info
+	}
+	want := map[string]version.Info{
+		"explorer": {Owner: "o", Repo: "explorer", Branch: "main", Tracking: "branch"},
+		"op_node":  {Tag: "op-node/v1.16.11", Commit: "cba7aba", TagPrefix: "op-node", Owner: "ethereum-optimism", Repo: "optimism", Tracking: "release"},
+		"reth":     {Tag: "v1.9.0", Owner: "paradigmxyz", Repo: "reth", Tracking: "release"},
+		"proxy":    {Tag: "v2.0.0-rc.1", Owner: "o", Repo: "proxy", Tracking: "release"},
+		"geth":     {Tag: "v1.14.0", Owner: "o", Repo: "geth", Tracking: "release", ImageVariant: "alltools"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() = %+v, want %+v", got, want)
+	}
+	if len(result.Skipped) == 0 || !strings.Contains(strings.Join(result.Skipped, "\n"), "GITLAB_REPO") {
+		t.Errorf("Scan() skipped %v, want the GitLab repository", result.Skipped)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	entries := []Entry{
+		{Name: "op_node", Info: &version.Info{Tag: "op-node/v1.16.11", TagPrefix: "op-node", Tracking: "release"}},
+		{Name: "reth", Info: &version.Info{Tag: "v1.9.0", Tracking: "release"}},
+		{Name: "proxy", Info: &version.Info{Tag: "v2.0.0", Tracking: "release"}},
+	}
+	var out strings.Builder
+	kept, err := Prompt(strings.NewReader("\n\n\nn\ny\ntag\n-\n"), &out, entries)
+	if err != nil {
+		t.Fatalf("Prompt() unexpected error: %v", err)
+	}
+	if len(kept) != 2 || kept[0].Name != "op_node" || kept[0].Info.TagPrefix != "op-node" || kept[1].Name != "proxy" || kept[1].Info.Tracking != "tag" || kept[1].Info.TagPrefix != "" {
+		t.Errorf("Prompt() kept %+v", kept)
+	}
+	if _, err := Prompt(strings.NewReader("y\n"), &out, entries); err == nil {
+		t.Errorf("Prompt() with too few answers expected an error")
+	}
+}
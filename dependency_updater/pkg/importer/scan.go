@@ -0,0 +1,205 @@
+package importer
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+var (
+	envLine    = regexp.MustCompile(`^(?:export\s+)?([A-Z][A-Z0-9_]*)_(REPO|TAG|COMMIT|BRANCH)=["']?([^"'\s]*)`)
+	githubRepo = regexp.MustCompile(`^(?:https://|git@)github\.com[/:]([^/\s]+)/([^/\s]+?)(?:\.git)?/?$`)
+	gitClone   = regexp.MustCompile(`git clone\s+(?:-\S+\s+)*(https://github\.com/[^\s]+?)(?:\.git)?\s+(?:.*?\s)?(?:--branch|-b)\s+([^\s$]+)`)
+	imageLine  = regexp.MustCompile(`(?m)^\s*image:\s*["']?([^\s"':]+):([^\s"'@]+)`)
+)
+
+// Scan guesses the dependencies of a repository without a versions.json from what it builds:
+// the <NAME>_REPO, _TAG, _COMMIT and _BRANCH variables of its env files, the ghcr.io images
+// and tagged git clones of its Dockerfiles, and the ghcr.io images of its compose files.
+// Env files come first, as they also name the commit.
+func Scan(repoPath string) (*Result, error) {
+	result := &Result{}
+	if err := walkFiles(repoPath, repoPath, true, func(path string, rel string) error {
+		if !isEnvFile(rel) {
+			return nil
+		}
+		return result.envFile(path, rel)
+	}); err != nil {
+		return nil, err
+	}
+	if err := result.dockerfiles(repoPath, repoPath, true); err != nil {
+		return nil, err
+	}
+	if err := walkFiles(repoPath, repoPath, true, func(path string, rel string) error {
+		if !isDockerfile(rel) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", rel, err)
+		}
+		result.dockerfileClones(string(content), rel)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := walkFiles(repoPath, repoPath, true, func(path string, rel string) error {
+		base := filepath.Base(rel)
+		if !strings.HasPrefix(base, "docker-compose") && !strings.HasPrefix(base, "compose") || (!strings.HasSuffix(base, ".yml") && !strings.HasSuffix(base, ".yaml")) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", rel, err)
+		}
+		for _, m := range imageLine.FindAllStringSubmatch(string(content), -1) {
+			result.add(rel, "docker", m[1], m[2])
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// isEnvFile matches versions.env, .env files and any file in an env or envs directory.
+func isEnvFile(rel string) bool {
+	base := filepath.Base(rel)
+	if strings.HasSuffix(base, ".env") || strings.HasPrefix(base, ".env") {
+		return true
+	}
+	dir := filepath.Base(filepath.Dir(rel))
+	return dir == "env" || dir == "envs"
+}
+
+func (r *Result) envFile(path string, rel string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %s", rel, err)
+	}
+	vars := map[string]map[string]string{}
+	var names []string
+	for _, line := range strings.Split(string(content), "\n") {
+		m := envLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		if vars[m[1]] == nil {
+			vars[m[1]] = map[string]string{}
+			names = append(names, m[1])
+		}
+		vars[m[1]][m[2]] = m[3]
+	}
+	for _, name := range names {
+		v := vars[name]
+		if v["REPO"] == "" {
+			continue
+		}
+		m := githubRepo.FindStringSubmatch(v["REPO"])
+		if m == nil {
+			r.Skipped = append(r.Skipped, fmt.Sprintf("%s_REPO in %s is not a GitHub repository", name, rel))
+			continue
+		}
+		info := &version.Info{Tag: v["TAG"], Commit: v["COMMIT"], Owner: m[1], Repo: m[2], Branch: v["BRANCH"]}
+		switch {
+		case info.Tag != "":
+			if prefix, _, ok := cutLast(info.Tag, "/"); ok {
+				info.TagPrefix = prefix
+			}
+			info.Tracking = guessTracking(info.Tag, info.TagPrefix)
+		case info.Branch != "":
+			info.Tracking = "branch"
+		default:
+			r.Skipped = append(r.Skipped, fmt.Sprintf("%s in %s has neither a _TAG nor a _BRANCH", name, rel))
+			continue
+		}
+		r.addEntry(Entry{Name: strings.ToLower(name), Info: info, Origin: rel})
+	}
+	return nil
+}
+
+// dockerfileClones records the tagged GitHub clones of a Dockerfile, skipping those whose
+// tag is a build argument.
+func (r *Result) dockerfileClones(content string, rel string) {
+	for _, m := range gitClone.FindAllStringSubmatch(content, -1) {
+		r.add(rel, "github-releases", strings.TrimPrefix(m[1], "https://github.com/"), m[2])
+	}
+}
+
+// guessTracking follows release candidates if the current tag is one, else stable releases.
+func guessTracking(tag string, tagPrefix string) string {
+	if version.IsRCVersion(tag, tagPrefix) {
+		return "tag"
+	}
+	return "release"
+}
+
+// addEntry adds an entry unless one with the same name, or the same repository and tag
+// prefix, was found before.
+func (r *Result) addEntry(entry Entry) {
+	if slices.ContainsFunc(r.Entries, func(e Entry) bool {
+		return e.Name == entry.Name || (e.Info.IsRepo(entry.Info.Owner, entry.Info.Repo) && e.Info.TagPrefix == entry.Info.TagPrefix)
+	}) {
+		return
+	}
+	r.Entries = append(r.Entries, entry)
+}
+
+// Prompt asks on out whether to keep each entry, and for its tracking mode and tag prefix,
+// reading the answers from in. An empty answer keeps the guess.
+func Prompt(in io.Reader, out io.Writer, entries []Entry) ([]Entry, error) {
+	scanner := bufio.NewScanner(in)
+	ask := func(question string, guess string) (string, error) {
+		fmt.Fprintf(out, "  %s [%s]: ", question, guess)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.ErrUnexpectedEOF
+		}
+		if answer := strings.TrimSpace(scanner.Text()); answer != "" {
+			return answer, nil
+		}
+		return guess, nil
+	}
+
+	var kept []Entry
+	for _, entry := range entries {
+		info := entry.Info
+		ref := info.Tag
+		if info.Tracking == "branch" {
+			ref = info.Branch
+		}
+		fmt.Fprintf(out, "%s: %s/%s at %s, from %s\n", entry.Name, info.Owner, info.Repo, ref, entry.Origin)
+		answer, err := ask("Add it? (y/n)", "y")
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(strings.ToLower(answer), "y") {
+			continue
+		}
+		if info.Tracking, err = ask("Tracking (release, tag, branch, nightly)", info.Tracking); err != nil {
+			return nil, err
+		}
+		if !slices.Contains([]string{"release", "tag", "branch", "nightly"}, info.Tracking) {
+			return nil, fmt.Errorf("unknown tracking mode %q for %s", info.Tracking, entry.Name)
+		}
+		if info.Tracking != "branch" {
+			prefix, err := ask("Tag prefix, - for none", cmp.Or(info.TagPrefix, "-"))
+			if err != nil {
+				return nil, err
+			}
+			info.TagPrefix = strings.TrimPrefix(prefix, "-")
+		}
+		kept = append(kept, entry)
+	}
+	return kept, nil
+}
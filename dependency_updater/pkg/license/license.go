@@ -0,0 +1,114 @@
+// Package license compares the licenses an upstream declares at the current and the candidate
+// version, so a relicensing shows up in the report before it is deployed.
+package license
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Files are the names license files are looked up under at the repository root. Dual
+// licensed projects such as reth have several.
+var Files = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "LICENCE", "COPYING", "COPYING.LESSER", "LICENSE-APACHE", "LICENSE-MIT"}
+
+// identifiers recognize licenses by phrases of their text, the most specific first. Titles
+// include the version, as the GPL's text mentions the LGPL.
+var identifiers = []struct {
+	id      string
+	phrases []string
+}{
+	{"BUSL-1.1", []string{"business source license"}},
+	{"SSPL-1.0", []string{"server side public license"}},
+	{"Elastic-2.0", []string{"elastic license 2.0"}},
+	{"AGPL-3.0", []string{"gnu affero general public license version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license version 3"}},
+	{"GPL-2.0", []string{"gnu general public license version 2"}},
+	{"MPL-2.0", []string{"mozilla public license version 2.0"}},
+	{"Apache-2.0", []string{"apache license version 2.0"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+}
+
+// Identify returns the SPDX identifier of a license text, or "unrecognized".
+func Identify(text string) string {
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, identifier := range identifiers {
+		matched := true
+		for _, phrase := range identifier.phrases {
+			matched = matched && strings.Contains(text, phrase)
+		}
+		if matched {
+			return identifier.id
+		}
+	}
+	return "unrecognized"
+}
+
+// Licenses maps the license files of a repository at a ref to their identifiers.
+type Licenses map[string]string
+
+func (l Licenses) String() string {
+	if len(l) == 0 {
+		return "no license file"
+	}
+	var parts []string
+	for _, name := range slices.Sorted(maps.Keys(l)) {
+		parts = append(parts, fmt.Sprintf("%s (%s)", l[name], name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Read looks up Files in the repository at ref.
+func Read(ctx context.Context, source sources.VersionSource, owner string, repo string, ref string) (Licenses, error) {
+	licenses := Licenses{}
+	for _, name := range Files {
+		content, err := sources.ReadFile(ctx, source, owner, repo, ref, name)
+		if errors.Is(err, sources.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		licenses[name] = Identify(string(content))
+	}
+	return licenses, nil
+}
+
+// Check flags candidates whose license files or identified licenses differ from the current
+// version's. It doesn't hold them, relicensing needs a decision rather than a retry.
+type Check struct{}
+
+func (Check) Name() string { return "license" }
+
+func (Check) Check(ctx context.Context, source sources.VersionSource, info *version.Info, planned version.PlannedUpdate) *runner.CheckResult {
+	currentRef, candidateRef := info.Tag, planned.Version
+	if info.Tracking == "branch" {
+		currentRef, candidateRef = info.Commit, planned.Commit
+	}
+	current, err := Read(ctx, source, info.Owner, info.Repo, currentRef)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return &runner.CheckResult{Detail: fmt.Sprintf("error reading the license at %s: %s", currentRef, err)}
+	}
+	candidate, err := Read(ctx, source, info.Owner, info.Repo, candidateRef)
+	if err != nil {
+		return &runner.CheckResult{Detail: fmt.Sprintf("error reading the license at %s: %s", candidateRef, err)}
+	}
+	if !maps.Equal(current, candidate) {
+		return &runner.CheckResult{Detail: fmt.Sprintf("license changed from %s to %s", current, candidate)}
+	}
+	return &runner.CheckResult{Passed: true, Detail: current.String()}
+}
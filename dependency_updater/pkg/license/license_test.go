@@ -0,0 +1,64 @@
+package license
+
+import (
+	"context"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+const (
+	apache = "Apache License\n                           Version 2.0, January 2004\n"
+	mit    = "MIT License\n\nPermission is hereby granted, free of charge, to any person obtaining a copy"
+	busl   = "License text copyright (c) 2023 MariaDB plc, All Rights Reserved.\n\"Business Source License\" is a trademark of MariaDB plc."
+	gpl3   = "GNU GENERAL PUBLIC LICENSE\n Version 3, 29 June 2007\n... use the GNU Lesser General Public License instead of this License."
+)
+
+func TestIdentify(t *testing.T) {
+	tests := map[string]string{apache: "Apache-2.0", mit: "MIT", busl: "BUSL-1.1", gpl3: "GPL-3.0", "All rights reserved.": "unrecognized"}
+	for text, want := range tests {
+		if got := Identify(text); got != want {
+			t.Errorf("Identify(%.30q) = %s, want %s", text, got, want)
+		}
+	}
+}
+
+// fileSource serves license files by ref.
+type fileSource struct {
+	sources.VersionSource
+	files map[string]map[string]string
+}
+
+func (s fileSource) ReadFile(ctx context.Context, owner string, repo string, ref string, path string) ([]byte, error) {
+	content, ok := s.files[ref][path]
+	if !ok {
+		return nil, sources.ErrNotFound
+	}
+	return []byte(content), nil
+}
+
+func TestCheck(t *testing.T) {
+	source := fileSource{files: map[string]map[string]string{
+		"v1.0.0": {"LICENSE-APACHE": apache, "LICENSE-MIT": mit},
+		"v1.1.0": {"LICENSE-APACHE": apache + "\nCopyright 2026", "LICENSE-MIT": mit},
+		"v2.0.0": {"LICENSE": busl},
+	}}
+	info := &version.Info{Tag: "v1.0.0", Tracking: "release"}
+
+	same := Check{}.Check(context.Background(), source, info, version.PlannedUpdate{Version: "v1.1.0"})
+	if same == nil || !same.Passed || same.Detail != "Apache-2.0 (LICENSE-APACHE), MIT (LICENSE-MIT)" {
+		t.Errorf("Check() of the same licenses = %+v", same)
+	}
+	changed := Check{}.Check(context.Background(), source, info, version.PlannedUpdate{Version: "v2.0.0"})
+	if changed == nil || changed.Passed || changed.Hold || changed.Detail != "license changed from Apache-2.0 (LICENSE-APACHE), MIT (LICENSE-MIT) to BUSL-1.1 (LICENSE)" {
+		t.Errorf("Check() of a relicensed version = %+v", changed)
+	}
+	if result := (Check{}).Check(context.Background(), tagOnlySource{}, info, version.PlannedUpdate{Version: "v2.0.0"}); result != nil {
+		t.Errorf("Check() of a source without files = %+v, want nil", result)
+	}
+}
+
+type tagOnlySource struct {
+	sources.VersionSource
+}
@@ -0,0 +1,175 @@
+// Package multirepo lets one updater instance manage several node repositories, such as the
+// mainnet and testnet repositories or those of several chains. A repos file lists them, each
+// with its own targets, dependency filters and pull request automation.
+package multirepo
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// Config is the content of a repos file:
+//
+//	{
+//	  "repos": [
+//	    {"name": "mainnet", "path": "node-mainnet", "fluxDir": "node-mainnet/deploy",
+//	     "pullRequest": {"owner": "base", "repo": "node-mainnet", "labels": ["dependencies"]}},
+//	    {"name": "testnet", "path": "node-testnet", "skip": ["nethermind"]}
+//	  ]
+//	}
+type Config struct {
+	Repos []Repo `json:"repos"`
+}
+
+// Repo is one repository managed by the updater.
+type Repo struct {
+	// Name identifies the repository in logs and errors.
+	Name string `json:"name"`
+	// Path is the repository's checkout. Relative paths, here and in FluxDir and ArgoCDDir,
+	// are relative to the repos file.
+	Path string `json:"path"`
+	// FluxDir and ArgoCDDir are the repository's --flux-dir and --argocd-dir targets.
+	FluxDir   string `json:"fluxDir,omitempty"`
+	ArgoCDDir string `json:"argocdDir,omitempty"`
+	// Only and Skip filter the repository's dependencies like --only and --skip, which
+	// apply to repositories without them.
+	Only []string `json:"only,omitempty"`
+	Skip []string `json:"skip,omitempty"`
+	// PullRequest, if set, opens a pull request with the updates of each run.
+	PullRequest *PullRequest `json:"pullRequest,omitempty"`
+}
+
+// PullRequest is where the updates of a repository are proposed.
+type PullRequest struct {
+	// Owner and Repo name the GitHub repository, whose remote is the checkout's origin.
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	// Base is the branch the pull request targets, main if empty.
+	Base string `json:"base,omitempty"`
+	// Branch is the branch the updates are pushed to, dependency-updater if empty. Every run
+	// force pushes it, so the open pull request always has the latest updates.
+	Branch string   `json:"branch,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Load reads a repos file, resolving the paths in it.
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading repos file: %s", err)
+	}
+	config := &Config{}
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("error parsing repos file %s: %s", path, err)
+	}
+	if len(config.Repos) == 0 {
+		return nil, fmt.Errorf("repos file %s lists no repositories", path)
+	}
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	names := map[string]bool{}
+	for i := range config.Repos {
+		repo := &config.Repos[i]
+		switch {
+		case repo.Name == "":
+			return nil, fmt.Errorf("repository %d of %s has no name", i+1, path)
+		case names[repo.Name]:
+			return nil, fmt.Errorf("repository %s is listed twice in %s", repo.Name, path)
+		case repo.Path == "":
+			return nil, fmt.Errorf("repository %s has no path", repo.Name)
+		case repo.PullRequest != nil && (repo.PullRequest.Owner == "" || repo.PullRequest.Repo == ""):
+			return nil, fmt.Errorf("pull request of repository %s needs an owner and repo", repo.Name)
+		}
+		names[repo.Name] = true
+		repo.Path, repo.FluxDir, repo.ArgoCDDir = resolve(repo.Path), resolve(repo.FluxDir), resolve(repo.ArgoCDDir)
+	}
+	return config, nil
+}
+
+func (p PullRequest) base() string {
+	return cmp.Or(p.Base, "main")
+}
+
+func (p PullRequest) branch() string {
+	return cmp.Or(p.Branch, "dependency-updater")
+}
+
+// Prepare checks out the base branch at its latest commit on origin, so a run starts from
+// what is merged rather than from an earlier run's updates.
+func (p PullRequest) Prepare(ctx context.Context, dir string) error {
+	for _, args := range [][]string{
+		{"fetch", "origin", p.base()},
+		{"checkout", p.base()},
+		{"merge", "--ff-only", "origin/" + p.base()},
+	} {
+		if err := git(ctx, dir, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Publish commits the changes of the checkout to the pull request's branch, force pushes it
+// and opens the pull request, or updates the title and body of the open one. It checks the
+// base branch out again, the next run starts from it. It returns the pull request's URL.
+func (p PullRequest) Publish(ctx context.Context, client *github.Client, dir string, title string, body string) (string, error) {
+	if err := git(ctx, dir, "checkout", "-B", p.branch()); err != nil {
+		return "", err
+	}
+	err := git(ctx, dir, "commit", "-am", title, "-m", body)
+	if err == nil {
+		err = git(ctx, dir, "push", "--force", "origin", p.branch())
+	}
+	if checkout := git(ctx, dir, "checkout", p.base()); err == nil {
+		err = checkout
+	}
+	if err != nil {
+		return "", err
+	}
+
+	open, _, err := client.PullRequests.List(ctx, p.Owner, p.Repo, &github.PullRequestListOptions{State: "open", Head: p.Owner + ":" + p.branch(), Base: p.base()})
+	if err != nil {
+		return "", fmt.Errorf("error listing pull requests of %s/%s: %s", p.Owner, p.Repo, err)
+	}
+	if len(open) > 0 {
+		pr, _, err := client.PullRequests.Edit(ctx, p.Owner, p.Repo, open[0].GetNumber(), &github.PullRequest{Title: &title, Body: &body})
+		if err != nil {
+			return "", fmt.Errorf("error updating pull request #%d of %s/%s: %s", open[0].GetNumber(), p.Owner, p.Repo, err)
+		}
+		return pr.GetHTMLURL(), nil
+	}
+	pr, _, err := client.PullRequests.Create(ctx, p.Owner, p.Repo, &github.NewPullRequest{Title: &title, Body: &body, Head: github.Ptr(p.branch()), Base: github.Ptr(p.base())})
+	if err != nil {
+		return "", fmt.Errorf("error opening a pull request on %s/%s: %s", p.Owner, p.Repo, err)
+	}
+	if len(p.Labels) > 0 {
+		if _, _, err := client.Issues.AddLabelsToIssue(ctx, p.Owner, p.Repo, pr.GetNumber(), p.Labels); err != nil {
+			return "", fmt.Errorf("error labeling pull request #%d of %s/%s: %s", pr.GetNumber(), p.Owner, p.Repo, err)
+		}
+	}
+	return pr.GetHTMLURL(), nil
+}
+
+func git(ctx context.Context, dir string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %s: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package multirepo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v72/github"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "repos.json")
+	content := `{"repos": [
+		{"name": "mainnet", "path": "node-mainnet", "fluxDir": "node-mainnet/deploy", "pullRequest": {"owner": "base", "repo": "node-mainnet"}},
+		{"name": "testnet", "path": "/srv/node-testnet", "skip": ["nethermind"]}
+	]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	mainnet, testnet := config.Repos[0], config.Repos[1]
+	if mainnet.Path != filepath.Join(dir, "node-mainnet") || mainnet.FluxDir != filepath.Join(dir, "node-mainnet/deploy") || mainnet.ArgoCDDir != "" {
+		t.Errorf("mainnet = %+v, want paths relative to the repos file", mainnet)
+	}
+	if testnet.Path != "/srv/node-testnet" || testnet.Skip[0] != "nethermind" || testnet.PullRequest != nil {
+		t.Errorf("testnet = %+v", testnet)
+	}
+
+	for _, invalid := range []string{
+		`{"repos": []}`,
+		`{"repos": [{"path": "a"}]}`,
+		`{"repos": [{"name": "a"}]}`,
+		`{"repos": [{"name": "a", "path": "a"}, {"name": "a", "path": "b"}]}`,
+		`{"repos": [{"name": "a", "path": "a", "pullRequest": {"owner": "base"}}]}`,
+	} {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(%s) = nil error, want an error", invalid)
+		}
+	}
+}
+
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestPublish(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "updater")
+	t.Setenv("GIT_AUTHOR_EMAIL", "updater@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "updater")
+	t.Setenv("GIT_COMMITTER_EMAIL", "updater@example.com")
+	origin, checkout := filepath.Join(t.TempDir(), "origin.git"), t.TempDir()
+	run(t, t.TempDir(), "init", "--bare", "-b", "main", origin)
+	run(t, checkout, "init", "-b", "main")
+	run(t, checkout, "remote", "add", "origin", origin)
+	if err := os.WriteFile(filepath.Join(checkout, "versions.env"), []byte("export RETH_TAG=v1.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, checkout, "add", ".")
+	run(t, checkout, "commit", "-m", "init")
+	run(t, checkout, "push", "origin", "main")
+
+	var created, edited []map[string]any
+	open := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/base/node/pulls", func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("head") != "base:dependency-updater" || req.URL.Query().Get("base") != "main" {
+			t.Errorf("listed pull requests with %s", req.URL.RawQuery)
+		}
+		if open {
+			w.Write([]byte(`[{"number": 7}]`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("POST /repos/base/node/pulls", func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]any
+		json.NewDecoder(req.Body).Decode(&body)
+		created, open = append(created, body), true
+		w.Write([]byte(`{"number": 7, "html_url": "https://github.com/base/node/pull/7"}`))
+	})
+	mux.HandleFunc("PATCH /repos/base/node/pulls/7", func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]any
+		json.NewDecoder(req.Body).Decode(&body)
+		edited = append(edited, body)
+		w.Write([]byte(`{"number": 7, "html_url": "https://github.com/base/node/pull/7"}`))
+	})
+	mux.HandleFunc("POST /repos/base/node/issues/7/labels", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	pr := PullRequest{Owner: "base", Repo: "node", Labels: []string{"dependencies"}}
+	for i, tag := range []string{"v1.1.0", "v1.2.0"} {
+		if err := pr.Prepare(context.Background(), checkout); err != nil {
+			t.Fatalf("Prepare() unexpected error: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(checkout, "versions.env"), []byte("export RETH_TAG="+tag+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		prURL, err := pr.Publish(context.Background(), client, checkout, "chore: updated reth", "reth "+tag)
+		if err != nil {
+			t.Fatalf("Publish() unexpected error: %v", err)
+		}
+		if prURL != "https://github.com/base/node/pull/7" || len(created) != 1 || len(edited) != i {
+			t.Errorf("Publish() = %s after %d creates and %d edits", prURL, len(created), len(edited))
+		}
+		if branch := run(t, checkout, "rev-parse", "--abbrev-ref", "HEAD"); branch != "main" {
+			t.Errorf("checked out %s after Publish(), want main", branch)
+		}
+		if pushed := run(t, origin, "show", "dependency-updater:versions.env"); pushed != "export RETH_TAG="+tag {
+			t.Errorf("pushed versions.env = %q, want %s", pushed, tag)
+		}
+	}
+	if created[0]["head"] != "dependency-updater" || created[0]["base"] != "main" || edited[0]["body"] != "reth v1.2.0" {
+		t.Errorf("created %+v, edited %+v", created, edited)
+	}
+	if env, _ := os.ReadFile(filepath.Join(checkout, "versions.env")); string(env) != "export RETH_TAG=v1.0.0\n" {
+		t.Errorf("versions.env on main = %q, want the merged version", env)
+	}
+}
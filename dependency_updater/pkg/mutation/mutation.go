@@ -0,0 +1,77 @@
+// Package mutation catches image tags re-pushed after they were pinned: it compares the digest
+// each dependency recorded for the image tag of its version with the registry's current one.
+package mutation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/base/node/dependency_updater/pkg/ociartifact"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Resolver resolves an image tag to the digest of its manifest, like ociartifact.Pusher.
+type Resolver interface {
+	Resolve(ctx context.Context, ref ociartifact.Reference) (string, error)
+}
+
+// Finding is the digest comparison of one dependency's image tag.
+type Finding struct {
+	Dependency string `json:"dependency"`
+	Image      string `json:"image"`
+	// Recorded is the digest in versions.json, empty if none was recorded.
+	Recorded string `json:"recorded,omitempty"`
+	// Current is the registry's digest, empty if it couldn't be resolved.
+	Current string `json:"current,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Mutated reports whether the tag was re-pushed since its digest was recorded.
+func (f Finding) Mutated() bool {
+	return f.Recorded != "" && f.Current != "" && f.Recorded != f.Current
+}
+
+func (f Finding) String() string {
+	switch {
+	case f.Error != "":
+		return fmt.Sprintf("%s %s: error resolving the digest: %s", f.Dependency, f.Image, f.Error)
+	case f.Mutated():
+		return fmt.Sprintf("%s %s was re-pushed: recorded %s, now %s", f.Dependency, f.Image, f.Recorded, f.Current)
+	case f.Recorded == "":
+		return fmt.Sprintf("%s %s is at %s, no digest recorded", f.Dependency, f.Image, f.Current)
+	}
+	return fmt.Sprintf("%s %s is unchanged at %s", f.Dependency, f.Image, f.Current)
+}
+
+// Check resolves the image tag of every dependency with an image, in name order. Branch
+// tracking dependencies have no image tag of their own and are skipped.
+func Check(ctx context.Context, registry Resolver, dependencies version.Dependencies) []Finding {
+	var findings []Finding
+	for _, name := range version.Names(dependencies) {
+		info := dependencies[name]
+		if info.Image == "" || info.Tracking == "branch" || info.Tag == "" {
+			continue
+		}
+		finding := Finding{Dependency: name, Image: ociartifact.Qualify(info.Image) + ":" + info.ImageTagOf(info.Tag), Recorded: info.ImageDigest}
+		ref, err := ociartifact.ParseReference(finding.Image)
+		if err == nil {
+			finding.Current, err = registry.Resolve(ctx, ref)
+		}
+		if err != nil {
+			finding.Error = err.Error()
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// Mutated returns the findings whose tag was re-pushed.
+func Mutated(findings []Finding) []Finding {
+	var mutated []Finding
+	for _, f := range findings {
+		if f.Mutated() {
+			mutated = append(mutated, f)
+		}
+	}
+	return mutated
+}
@@ -0,0 +1,53 @@
+package mutation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/ociartifact"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// registry serves digests of fixed references.
+type registry map[string]string
+
+func (r registry) Resolve(ctx context.Context, ref ociartifact.Reference) (string, error) {
+	if digest, ok := r[ref.String()]; ok {
+		return digest, nil
+	}
+	return "", errors.New("404 Not Found")
+}
+
+func TestCheck(t *testing.T) {
+	dependencies := version.Dependencies{
+		"reth":       {Tag: "v1.1.0", Tracking: "release", Image: "ghcr.io/o/reth", ImageDigest: "sha256:aa"},
+		"geth":       {Tag: "v1.14.0", Tracking: "release", Image: "ethereum/client-go", ImageVariant: "alltools", ImageDigest: "sha256:old"},
+		"nethermind": {Tag: "1.35.3", Tracking: "release", Image: "nethermind/nethermind"},
+		"gone":       {Tag: "v2.0.0", Tracking: "release", Image: "ghcr.io/o/gone", ImageDigest: "sha256:cc"},
+		"branch":     {Branch: "main", Tracking: "branch", Image: "ghcr.io/o/branch"},
+		"no_image":   {Tag: "v1.0.0", Tracking: "release"},
+	}
+	findings := Check(context.Background(), registry{
+		"ghcr.io/o/reth:v1.1.0": "sha256:aa",
+		"registry-1.docker.io/ethereum/client-go:v1.14.0-alltools": "sha256:new",
+		"registry-1.docker.io/nethermind/nethermind:1.35.3":        "sha256:dd",
+	}, dependencies)
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	want := []string{
+		"geth registry-1.docker.io/ethereum/client-go:v1.14.0-alltools was re-pushed: recorded sha256:old, now sha256:new",
+		"gone ghcr.io/o/gone:v2.0.0: error resolving the digest: 404 Not Found",
+		"nethermind registry-1.docker.io/nethermind/nethermind:1.35.3 is at sha256:dd, no digest recorded",
+		"reth ghcr.io/o/reth:v1.1.0 is unchanged at sha256:aa",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Check() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if mutated := Mutated(findings); len(mutated) != 1 || mutated[0].Dependency != "geth" {
+		t.Errorf("Mutated() = %+v, want geth", mutated)
+	}
+}
@@ -0,0 +1,377 @@
+// Package ociartifact pushes the version manifests to an OCI registry as an artifact, so
+// clusters and tools can pull the version set from a registry instead of the git repository.
+package ociartifact
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Media types of the artifact: the manifest's artifactType, its files, and the empty config
+// artifacts without a config use.
+const (
+	ArtifactType     = "application/vnd.base.node.versions.v1"
+	VersionsJSONType = "application/vnd.base.node.versions.v1+json"
+	VersionsEnvType  = "application/vnd.base.node.versions.env.v1"
+	ManifestType     = "application/vnd.oci.image.manifest.v1+json"
+	emptyConfigType  = "application/vnd.oci.empty.v1+json"
+)
+
+// emptyConfig is the empty JSON object the OCI image spec prescribes as config for artifacts.
+var emptyConfig = []byte("{}")
+
+// Reference is a registry/repository:tag reference, such as ghcr.io/base/node-versions:v1.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+}
+
+// ErrInvalidReference is wrapped by the errors of ParseReference.
+var ErrInvalidReference = errors.New("invalid OCI reference")
+
+var (
+	registryPattern   = regexp.MustCompile(`^(?:[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)*(?::[0-9]{1,5})?$`)
+	repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+)
+
+// maxRepositoryLength is the longest repository name the distribution spec allows.
+const maxRepositoryLength = 255
+
+// ParseReference parses a reference, its tag is "latest" if omitted. The registry, repository
+// and tag must follow the OCI distribution spec's grammar, and digest references are refused
+// as Reference has no digest.
+func ParseReference(s string) (Reference, error) {
+	if strings.Contains(s, "@") {
+		return Reference{}, fmt.Errorf("%w %q: digest references are not supported, expected registry/repository[:tag]", ErrInvalidReference, s)
+	}
+	registry, repository, ok := strings.Cut(s, "/")
+	if !ok || registry == "" || repository == "" || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+		return Reference{}, fmt.Errorf("%w %q, expected registry/repository[:tag]", ErrInvalidReference, s)
+	}
+	ref := Reference{Registry: registry, Repository: repository, Tag: "latest"}
+	if i := strings.LastIndex(repository, ":"); i >= 0 {
+		ref.Repository, ref.Tag = repository[:i], repository[i+1:]
+	}
+	if ref.Repository == "" || ref.Tag == "" {
+		return Reference{}, fmt.Errorf("%w %q, expected registry/repository[:tag]", ErrInvalidReference, s)
+	}
+	switch {
+	case !registryPattern.MatchString(ref.Registry):
+		return Reference{}, fmt.Errorf("%w %q: registry %q is not a host name with an optional port", ErrInvalidReference, s, ref.Registry)
+	case len(ref.Repository) > maxRepositoryLength || !repositoryPattern.MatchString(ref.Repository):
+		return Reference{}, fmt.Errorf("%w %q: repository %q must be lowercase letters, digits and separators, at most %d characters", ErrInvalidReference, s, ref.Repository, maxRepositoryLength)
+	case !tagPattern.MatchString(ref.Tag):
+		return Reference{}, fmt.Errorf("%w %q: tag %q must be letters, digits, underscores, periods and dashes, at most 128 characters", ErrInvalidReference, s, ref.Tag)
+	}
+	return ref, nil
+}
+
+// Qualify qualifies Docker Hub's short image names with its registry, such as
+// "registry-1.docker.io/library/ubuntu" for "ubuntu", so they parse as references.
+func Qualify(image string) string {
+	host, _, ok := strings.Cut(image, "/")
+	switch {
+	case !ok:
+		return "registry-1.docker.io/library/" + image
+	case !strings.ContainsAny(host, ".:") && host != "localhost":
+		return "registry-1.docker.io/" + image
+	}
+	return image
+}
+
+func (r Reference) String() string {
+	return r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// File is one file of the artifact.
+type File struct {
+	Name      string
+	MediaType string
+	Content   []byte
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        descriptor        `json:"config"`
+	Layers        []descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+func digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Pusher uploads artifacts with the OCI distribution API, and resolves tags. Username and Password are used for
+// basic auth, or to obtain a bearer token when the registry asks for one.
+type Pusher struct {
+	HTTP     *http.Client
+	Username string
+	Password string
+	// PlainHTTP talks to the registry without TLS, for local registries.
+	PlainHTTP bool
+
+	token string
+}
+
+// Push uploads files as an artifact tagged ref.Tag and returns the manifest's digest.
+func (p *Pusher) Push(ctx context.Context, ref Reference, files []File, created time.Time) (string, error) {
+	m := manifest{
+		SchemaVersion: 2,
+		MediaType:     ManifestType,
+		ArtifactType:  ArtifactType,
+		Config:        descriptor{MediaType: emptyConfigType, Digest: digest(emptyConfig), Size: len(emptyConfig)},
+		Annotations:   map[string]string{"org.opencontainers.image.created": created.UTC().Format(time.RFC3339)},
+	}
+	if err := p.pushBlob(ctx, ref, emptyConfig); err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if err := p.pushBlob(ctx, ref, file.Content); err != nil {
+			return "", fmt.Errorf("error pushing %s: %s", file.Name, err)
+		}
+		m.Layers = append(m.Layers, descriptor{
+			MediaType:   file.MediaType,
+			Digest:      digest(file.Content),
+			Size:        len(file.Content),
+			Annotations: map[string]string{"org.opencontainers.image.title": file.Name},
+		})
+	}
+
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	resp, err := p.do(ctx, ref, http.MethodPut, p.url(ref, "/manifests/"+ref.Tag), ManifestType, encoded, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("error pushing manifest: %s", resp.Status)
+	}
+	return digest(encoded), nil
+}
+
+// manifestTypes are the manifest media types Resolve accepts, images being pushed as multi
+// platform indexes or single manifests.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	ManifestType,
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Resolve returns the digest of the manifest ref.Tag points to.
+func (p *Pusher) Resolve(ctx context.Context, ref Reference) (string, error) {
+	resp, err := p.do(ctx, ref, http.MethodHead, p.url(ref, "/manifests/"+ref.Tag), "", nil, strings.Join(manifestTypes, ", "))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error resolving %s: %s", ref, resp.Status)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry %s returned no digest for %s", ref.Registry, ref)
+	}
+	return digest, nil
+}
+
+// ImageSize returns the compressed size of an image's config and layers. If ref points to a
+// multi platform index, the size of the platform's manifest, such as linux/amd64.
+func (p *Pusher) ImageSize(ctx context.Context, ref Reference, platform string) (int64, error) {
+	target := ref.Tag
+	for range 2 {
+		resp, err := p.do(ctx, ref, http.MethodGet, p.url(ref, "/manifests/"+target), "", nil, strings.Join(manifestTypes, ", "))
+		if err != nil {
+			return 0, err
+		}
+		var m struct {
+			Manifests []struct {
+				Digest   string `json:"digest"`
+				Platform struct {
+					OS           string `json:"os"`
+					Architecture string `json:"architecture"`
+				} `json:"platform"`
+			} `json:"manifests"`
+			Config descriptor   `json:"config"`
+			Layers []descriptor `json:"layers"`
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&m)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("error getting the manifest of %s: %s", ref, resp.Status)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error decoding the manifest of %s: %s", ref, err)
+		}
+		if len(m.Manifests) == 0 {
+			size := int64(m.Config.Size)
+			for _, layer := range m.Layers {
+				size += int64(layer.Size)
+			}
+			return size, nil
+		}
+		target = ""
+		for _, manifest := range m.Manifests {
+			if manifest.Platform.OS+"/"+manifest.Platform.Architecture == platform {
+				target = manifest.Digest
+				break
+			}
+		}
+		if target == "" {
+			return 0, fmt.Errorf("%s has no %s image", ref, platform)
+		}
+	}
+	return 0, fmt.Errorf("%s is an index of indexes", ref)
+}
+
+func (p *Pusher) pushBlob(ctx context.Context, ref Reference, content []byte) error {
+	resp, err := p.do(ctx, ref, http.MethodHead, p.url(ref, "/blobs/"+digest(content)), "", nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = p.do(ctx, ref, http.MethodPost, p.url(ref, "/blobs/uploads/"), "", nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("error starting blob upload: %s", resp.Status)
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location: %s", err)
+	}
+	query := location.Query()
+	query.Set("digest", digest(content))
+	location.RawQuery = query.Encode()
+
+	resp, err = p.do(ctx, ref, http.MethodPut, location.String(), "application/octet-stream", content, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("error uploading blob: %s", resp.Status)
+	}
+	return nil
+}
+
+func (p *Pusher) url(ref Reference, path string) string {
+	scheme := "https"
+	if p.PlainHTTP {
+		scheme = "http"
+	}
+	return scheme + "://" + ref.Registry + "/v2/" + ref.Repository + path
+}
+
+// do sends a request, authenticating and retrying once if the registry answers 401.
+func (p *Pusher) do(ctx context.Context, ref Reference, method string, target string, contentType string, body []byte, accept string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = int64(len(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if p.token != "" {
+			req.Header.Set("Authorization", "Bearer "+p.token)
+		} else if p.Username != "" {
+			req.SetBasicAuth(p.Username, p.Password)
+		}
+		resp, err := p.HTTP.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error calling registry %s: %s", ref.Registry, err)
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+			return nil, fmt.Errorf("registry %s requires credentials", ref.Registry)
+		}
+		if p.token, err = p.fetchToken(ctx, ref, challenge); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fetchToken obtains a push token from the realm of a bearer challenge.
+func (p *Pusher) fetchToken(ctx context.Context, ref Reference, challenge string) (string, error) {
+	params := map[string]string{}
+	for _, part := range strings.Split(challenge[len("bearer "):], ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			params[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+	}
+	if params["realm"] == "" {
+		return "", fmt.Errorf("registry %s sent a bearer challenge without a realm", ref.Registry)
+	}
+	query := url.Values{"scope": {"repository:" + ref.Repository + ":pull,push"}}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if p.Username != "" {
+		req.SetBasicAuth(p.Username, p.Password)
+	}
+	resp, err := p.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting registry token: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("error requesting registry token: %s %s", resp.Status, bytes.TrimSpace(message))
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("error decoding registry token: %s", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return token.Token, nil
+}
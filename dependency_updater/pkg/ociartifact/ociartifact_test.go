@@ -0,0 +1,216 @@
+package ociartifact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/httpreplay"
+)
+
+var record = flag.Bool("record", false, "record the cassettes in testdata from the real registries")
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Reference
+		wantErr bool
+	}{
+		{"ghcr.io/base/node-versions:v1", Reference{"ghcr.io", "base/node-versions", "v1"}, false},
+		{"localhost:5000/versions", Reference{"localhost:5000", "versions", "latest"}, false},
+		{"base/node-versions:v1", Reference{}, true},
+		{"ghcr.io/base:", Reference{}, true},
+		{"ghcr.io/base/node@sha256:abc", Reference{}, true},
+		{"ghcr.io/Base/Node:v1", Reference{}, true},
+		{"ghcr.io/base/node:v1/x", Reference{}, true},
+		{"ghcr.io/base/node:-v1", Reference{}, true},
+		{"ghcr..io/base/node:v1", Reference{}, true},
+		{"registry-1.docker.io/library/ubuntu:24.04", Reference{"registry-1.docker.io", "library/ubuntu", "24.04"}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseReference(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want || (err != nil && !errors.Is(err, ErrInvalidReference)) {
+			t.Errorf("ParseReference(%q) = %+v, %v", tt.in, got, err)
+		}
+	}
+	for image, want := range map[string]string{"ubuntu": "registry-1.docker.io/library/ubuntu", "nethermind/nethermind": "registry-1.docker.io/nethermind/nethermind", "ghcr.io/base/node": "ghcr.io/base/node"} {
+		if got := Qualify(image); got != want {
+			t.Errorf("Qualify(%s) = %s, want %s", image, got, want)
+		}
+	}
+}
+
+// fakeRegistry stores blobs and manifests, requiring a bearer token from its realm.
+type fakeRegistry struct {
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	realm     string
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		if user, pass, _ := req.BasicAuth(); user != "ci" || pass != "secret" || req.URL.Query().Get("scope") != "repository:base/versions:pull,push" {
+			http.Error(w, "denied", http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "t0k"})
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer t0k" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.realm+`",service="fake"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(req.URL.Path, "/v2/base/versions")
+	switch {
+	case req.Method == http.MethodHead && strings.HasPrefix(path, "/blobs/"):
+		if _, ok := r.blobs[strings.TrimPrefix(path, "/blobs/")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case req.Method == http.MethodPost && path == "/blobs/uploads/":
+		w.Header().Set("Location", "/v2/base/versions/blobs/uploads/1?state=x")
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodPut && path == "/blobs/uploads/1":
+		body, _ := io.ReadAll(req.Body)
+		if req.URL.Query().Get("state") != "x" || digest(body) != req.URL.Query().Get("digest") {
+			http.Error(w, "bad upload", http.StatusBadRequest)
+			return
+		}
+		r.blobs[digest(body)] = body
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodHead && strings.HasPrefix(path, "/manifests/"):
+		manifest, ok := r.manifests[strings.TrimPrefix(path, "/manifests/")]
+		if !ok || !strings.Contains(req.Header.Get("Accept"), ManifestType) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest(manifest))
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "/manifests/"):
+		manifest, ok := r.manifests[strings.TrimPrefix(path, "/manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(manifest)
+	case req.Method == http.MethodPut && strings.HasPrefix(path, "/manifests/"):
+		body, _ := io.ReadAll(req.Body)
+		r.manifests[strings.TrimPrefix(path, "/manifests/")] = body
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func TestPush(t *testing.T) {
+	registry := &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	registry.realm = server.URL + "/token"
+
+	ref := Reference{Registry: strings.TrimPrefix(server.URL, "http://"), Repository: "base/versions", Tag: "v1"}
+	pusher := &Pusher{HTTP: server.Client(), Username: "ci", Password: "secret", PlainHTTP: true}
+	files := []File{{Name: "versions.json", MediaType: VersionsJSONType, Content: []byte(`{"reth":{}}`)}}
+	got, err := pusher.Push(context.Background(), ref, files, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Push() unexpected error: %v", err)
+	}
+
+	pushed := registry.manifests["v1"]
+	if got != digest(pushed) {
+		t.Errorf("Push() = %s, want the digest of the pushed manifest", got)
+	}
+	var m manifest
+	if err := json.Unmarshal(pushed, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ArtifactType != ArtifactType || len(m.Layers) != 1 || m.Layers[0].Annotations["org.opencontainers.image.title"] != "versions.json" {
+		t.Errorf("manifest = %s", pushed)
+	}
+	if string(registry.blobs[m.Layers[0].Digest]) != `{"reth":{}}` || string(registry.blobs[m.Config.Digest]) != "{}" {
+		t.Errorf("blobs = %v", registry.blobs)
+	}
+
+	if resolved, err := pusher.Resolve(context.Background(), ref); err != nil || resolved != got {
+		t.Errorf("Resolve() = %s, %v, want %s", resolved, err, got)
+	}
+	ref.Tag = "v2"
+	if _, err := pusher.Resolve(context.Background(), ref); err == nil {
+		t.Errorf("Resolve() of a missing tag expected an error")
+	}
+}
+
+func TestImageSize(t *testing.T) {
+	image := `{"config": {"size": 10}, "layers": [{"size": 100}, {"size": 1000}]}`
+	registry := &fakeRegistry{manifests: map[string][]byte{
+		"single":         []byte(image),
+		"sha256:amd64":   []byte(image),
+		"multi-platform": []byte(`{"manifests": [{"digest": "sha256:arm64", "platform": {"os": "linux", "architecture": "arm64"}}, {"digest": "sha256:amd64", "platform": {"os": "linux", "architecture": "amd64"}}]}`),
+	}}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	registry.realm = server.URL + "/token"
+	pusher := &Pusher{HTTP: server.Client(), Username: "ci", Password: "secret", PlainHTTP: true}
+
+	for _, tag := range []string{"single", "multi-platform"} {
+		ref := Reference{Registry: strings.TrimPrefix(server.URL, "http://"), Repository: "base/versions", Tag: tag}
+		if size, err := pusher.ImageSize(context.Background(), ref, "linux/amd64"); err != nil || size != 1110 {
+			t.Errorf("ImageSize(%s) = %d, %v, want 1110", tag, size, err)
+		}
+		if tag == "multi-platform" {
+			if _, err := pusher.ImageSize(context.Background(), ref, "windows/amd64"); err == nil {
+				t.Errorf("ImageSize() of a missing platform expected an error")
+			}
+		}
+	}
+}
+
+func TestResolveReplay(t *testing.T) {
+	tests := []struct {
+		cassette string
+		image    string
+		want     string
+	}{
+		{cassette: "ghcr", image: "ghcr.io/paradigmxyz/reth:v1.4.8", want: "sha256:" + strings.Repeat("e5", 32)},
+		{cassette: "dockerhub", image: Qualify("ethereum/client-go") + ":v1.15.11", want: "sha256:" + strings.Repeat("f6", 32)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cassette, func(t *testing.T) {
+			ref, err := ParseReference(tt.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pusher := &Pusher{HTTP: httpreplay.Client(t, "testdata/"+tt.cassette+".json", *record)}
+			if got, err := pusher.Resolve(context.Background(), ref); err != nil || got != tt.want {
+				t.Errorf("Resolve() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParseReference(f *testing.F) {
+	for _, s := range []string{"ghcr.io/base/node-versions:v1", "localhost:5000/versions", "registry-1.docker.io/library/ubuntu:24.04", "ghcr.io/base/node@sha256:abc", "base/node:v1", "ghcr.io/a__b/c--d:_x"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		ref, err := ParseReference(s)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidReference) {
+				t.Fatalf("ParseReference(%q) = %v, want ErrInvalidReference", s, err)
+			}
+			return
+		}
+		if again, err := ParseReference(ref.String()); err != nil || again != ref {
+			t.Fatalf("ParseReference(%q) = %+v, whose String() parses as %+v, %v", s, ref, again, err)
+		}
+		if qualified := Qualify(ref.Registry + "/" + ref.Repository); qualified != ref.Registry+"/"+ref.Repository {
+			t.Fatalf("Qualify() changed the qualified %s to %s", ref.Registry+"/"+ref.Repository, qualified)
+		}
+	})
+}
@@ -0,0 +1,311 @@
+// Package operator runs the updater inside Kubernetes against NodeComponent custom resources:
+// each resource describes a tracked component the way a versions.json entry does, and the
+// operator records the version its policy selects in the resource's status.
+package operator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/policy"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// The NodeComponent resource, defined by dependency_updater/deploy/nodecomponent-crd.yaml.
+const (
+	Group    = "nodes.base.org"
+	Version  = "v1alpha1"
+	Resource = "nodecomponents"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// NodeComponent is a tracked component. Its spec is a versions.json entry, plus AutoUpdate
+// which lets the operator move the spec to the selected version instead of only reporting it.
+type NodeComponent struct {
+	Metadata struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+		// Generation only changes with the spec, the status is a subresource.
+		Generation int64 `json:"generation,omitempty"`
+	} `json:"metadata"`
+	Spec   Spec   `json:"spec"`
+	Status Status `json:"status"`
+}
+
+type Spec struct {
+	version.Info
+	AutoUpdate bool `json:"autoUpdate,omitempty"`
+}
+
+// Status is what the last check found. Its fields are always sent, so a merge patch clears
+// the ones that no longer apply.
+type Status struct {
+	// ObservedGeneration is the generation of the spec the status was computed for.
+	ObservedGeneration int64     `json:"observedGeneration"`
+	LastChecked        time.Time `json:"lastChecked,omitzero"`
+	// Available is the version the policy selects, empty if the spec is current.
+	Available       string `json:"available"`
+	AvailableCommit string `json:"availableCommit"`
+	DiffURL         string `json:"diffUrl"`
+	// LastApplied is when AutoUpdate last moved the spec, to AppliedVersion.
+	LastApplied    time.Time `json:"lastApplied,omitzero"`
+	AppliedVersion string    `json:"appliedVersion"`
+	Error          string    `json:"error"`
+}
+
+// Client talks to the Kubernetes API server.
+type Client struct {
+	Server string
+	Token  string
+	HTTP   *http.Client
+}
+
+// InCluster returns a client using the pod's service account.
+func InCluster() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster, KUBERNETES_SERVICE_HOST is unset")
+	}
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("error reading service account token: %s", err)
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("error reading service account CA: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid service account CA")
+	}
+	return &Client{
+		Server: "https://" + host + ":" + port,
+		Token:  strings.TrimSpace(string(token)),
+		HTTP:   &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}},
+	}, nil
+}
+
+func (c *Client) path(namespace string, name string) string {
+	path := "/apis/" + Group + "/" + Version
+	if namespace != "" {
+		path += "/namespaces/" + namespace
+	}
+	path += "/" + Resource
+	if name != "" {
+		path += "/" + name
+	}
+	return path
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, contentType string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.Server+path, reader)
+	if err != nil {
+		return err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling the Kubernetes API: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s %s", method, path, resp.Status, bytes.TrimSpace(message))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// List returns the NodeComponents in namespace, in every namespace if it is empty.
+func (c *Client) List(ctx context.Context, namespace string) ([]NodeComponent, string, error) {
+	var list struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Items []NodeComponent `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.path(namespace, ""), "", nil, &list); err != nil {
+		return nil, "", err
+	}
+	return list.Items, list.Metadata.ResourceVersion, nil
+}
+
+// Watch calls changed for every NodeComponent added or modified after resourceVersion, until
+// the server ends the watch or ctx is done.
+func (c *Client) Watch(ctx context.Context, namespace string, resourceVersion string, changed func(NodeComponent)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Server+c.path(namespace, "")+"?watch=true&resourceVersion="+resourceVersion, nil)
+	if err != nil {
+		return err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("error watching NodeComponents: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error watching NodeComponents: %s", resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var event struct {
+			Type   string        `json:"type"`
+			Object NodeComponent `json:"object"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("error decoding watch event: %s", err)
+		}
+		if event.Type == "ADDED" || event.Type == "MODIFIED" {
+			changed(event.Object)
+		}
+	}
+	return scanner.Err()
+}
+
+// UpdateStatus replaces the status of a NodeComponent.
+func (c *Client) UpdateStatus(ctx context.Context, component NodeComponent) error {
+	patch := map[string]any{"status": component.Status}
+	return c.do(ctx, http.MethodPatch, c.path(component.Metadata.Namespace, component.Metadata.Name)+"/status", "application/merge-patch+json", patch, nil)
+}
+
+// UpdateSpec moves a NodeComponent's spec to a version and returns the updated resource.
+func (c *Client) UpdateSpec(ctx context.Context, component NodeComponent, tag string, commit string) (NodeComponent, error) {
+	patch := map[string]any{"spec": map[string]string{"tag": tag, "commit": commit}}
+	var updated NodeComponent
+	err := c.do(ctx, http.MethodPatch, c.path(component.Metadata.Namespace, component.Metadata.Name), "application/merge-patch+json", patch, &updated)
+	return updated, err
+}
+
+// Operator checks NodeComponents and records the results in their status.
+type Operator struct {
+	Client    *Client
+	Sources   *sources.Set
+	Namespace string
+	// Interval is how often every NodeComponent is checked again, besides when it changes.
+	Interval time.Duration
+	// Now is time.Now if nil.
+	Now func() time.Time
+}
+
+// Reconcile checks one NodeComponent, applies the update if AutoUpdate is set, and writes the
+// status. A failed check is reported in the status rather than returned.
+func (o *Operator) Reconcile(ctx context.Context, component NodeComponent) error {
+	now := o.Now
+	if now == nil {
+		now = time.Now
+	}
+	status := Status{
+		ObservedGeneration: component.Metadata.Generation,
+		LastChecked:        now(),
+		LastApplied:        component.Status.LastApplied,
+		AppliedVersion:     component.Status.AppliedVersion,
+	}
+	var planned *version.PlannedUpdate
+	var err error
+	if !component.Spec.Pinned {
+		planned, err = o.resolve(ctx, component.Metadata.Name, &component.Spec.Info)
+	}
+	switch {
+	case err != nil:
+		status.Error = err.Error()
+	case planned != nil && component.Spec.AutoUpdate:
+		updated, err := o.Client.UpdateSpec(ctx, component, planned.Version, planned.Commit)
+		if err != nil {
+			status.Error = err.Error()
+			break
+		}
+		log.Printf("Updated %s/%s to %s", component.Metadata.Namespace, component.Metadata.Name, planned.Info.To)
+		status.ObservedGeneration = updated.Metadata.Generation
+		status.LastApplied, status.AppliedVersion = status.LastChecked, planned.Info.To
+	case planned != nil:
+		status.Available, status.AvailableCommit, status.DiffURL = planned.Info.To, planned.Commit, planned.Info.DiffUrl
+	}
+	component.Status = status
+	return o.Client.UpdateStatus(ctx, component)
+}
+
+func (o *Operator) resolve(ctx context.Context, name string, info *version.Info) (*version.PlannedUpdate, error) {
+	source, err := o.Sources.For(info.Source)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Resolve(ctx, source, name, info)
+}
+
+// ReconcileAll checks every NodeComponent and returns the resource version to watch from.
+func (o *Operator) ReconcileAll(ctx context.Context) (string, error) {
+	components, resourceVersion, err := o.Client.List(ctx, o.Namespace)
+	if err != nil {
+		return "", err
+	}
+	for _, component := range components {
+		if err := o.Reconcile(ctx, component); err != nil {
+			log.Printf("Error reconciling %s/%s: %s", component.Metadata.Namespace, component.Metadata.Name, err)
+		}
+	}
+	return resourceVersion, nil
+}
+
+// Run checks every NodeComponent each Interval, and a NodeComponent whenever its spec changes,
+// until ctx is done.
+func (o *Operator) Run(ctx context.Context) error {
+	for {
+		resourceVersion, err := o.ReconcileAll(ctx)
+		if err != nil {
+			log.Printf("Error listing NodeComponents: %s", err)
+		}
+
+		watchCtx, cancel := context.WithTimeout(ctx, o.Interval)
+		if err == nil {
+			err = o.Client.Watch(watchCtx, o.Namespace, resourceVersion, func(component NodeComponent) {
+				// Status updates are changes too, only new specs need another check.
+				if component.Metadata.Generation != 0 && component.Metadata.Generation == component.Status.ObservedGeneration {
+					return
+				}
+				if err := o.Reconcile(watchCtx, component); err != nil {
+					log.Printf("Error reconciling %s/%s: %s", component.Metadata.Namespace, component.Metadata.Name, err)
+				}
+			})
+			if err != nil && watchCtx.Err() == nil {
+				log.Printf("Watch ended: %s", err)
+			}
+		}
+		<-watchCtx.Done()
+		cancel()
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// fakeSource serves fixed tags.
+type fakeSource struct {
+	tags []sources.Tag
+}
+
+func (s *fakeSource) ListTags(ctx context.Context, owner string, repo string) ([]sources.Tag, error) {
+	return s.tags, nil
+}
+
+func (s *fakeSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*sources.Release, error) {
+	return &sources.Release{Tag: tag}, nil
+}
+
+func (s *fakeSource) ResolveRef(ctx context.Context, owner string, repo string, ref string) (string, error) {
+	return "", nil
+}
+
+func (s *fakeSource) RepoURL(owner string, repo string) string {
+	return "https://forge.example/" + owner + "/" + repo
+}
+
+func (s *fakeSource) CompareURL(owner string, repo string, from string, to string) string {
+	return s.RepoURL(owner, repo) + "/compare/" + from + "..." + to
+}
+
+// fakeAPI serves one NodeComponent and records the patches it receives.
+type fakeAPI struct {
+	component NodeComponent
+	patches   map[string]map[string]json.RawMessage
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	base := "/apis/" + Group + "/" + Version + "/namespaces/base/" + Resource
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == base:
+		json.NewEncoder(w).Encode(map[string]any{"metadata": map[string]string{"resourceVersion": "7"}, "items": []NodeComponent{a.component}})
+	case r.Method == http.MethodPatch && (r.URL.Path == base+"/reth" || r.URL.Path == base+"/reth/status"):
+		body, _ := io.ReadAll(r.Body)
+		var patch map[string]json.RawMessage
+		json.Unmarshal(body, &patch)
+		a.patches[r.URL.Path] = patch
+		updated := a.component
+		updated.Metadata.Generation++
+		json.NewEncoder(w).Encode(updated)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		autoUpdate bool
+		pinned     bool
+		wantSpec   bool
+		wantStatus Status
+	}{
+		{"reports available", false, false, false, Status{ObservedGeneration: 3, LastChecked: now, Available: "v1.1.0", AvailableCommit: "c110", DiffURL: "https://forge.example/paradigmxyz/reth/compare/v1.0.0...v1.1.0"}},
+		{"applies with autoUpdate", true, false, true, Status{ObservedGeneration: 4, LastChecked: now, LastApplied: now, AppliedVersion: "v1.1.0"}},
+		{"pinned", false, true, false, Status{ObservedGeneration: 3, LastChecked: now}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{patches: map[string]map[string]json.RawMessage{}}
+			api.component.Metadata.Name, api.component.Metadata.Namespace, api.component.Metadata.Generation = "reth", "base", 3
+			api.component.Spec = Spec{
+				Info:       version.Info{Tag: "v1.0.0", Commit: "c100", Owner: "paradigmxyz", Repo: "reth", Tracking: "release", Pinned: tt.pinned},
+				AutoUpdate: tt.autoUpdate,
+			}
+			// A stale result from an earlier check is cleared.
+			api.component.Status.Error = "timeout"
+			server := httptest.NewServer(api)
+			defer server.Close()
+
+			set := sources.NewSet(sources.Options{})
+			set.Add(map[string]sources.VersionSource{sources.Default: &fakeSource{tags: []sources.Tag{
+				{Name: "v1.1.0", Commit: "c110"},
+				{Name: "v1.0.0", Commit: "c100"},
+			}}})
+			o := &Operator{
+				Client:    &Client{Server: server.URL, HTTP: server.Client()},
+				Sources:   set,
+				Namespace: "base",
+				Now:       func() time.Time { return now },
+			}
+
+			resourceVersion, err := o.ReconcileAll(context.Background())
+			if err != nil || resourceVersion != "7" {
+				t.Fatalf("ReconcileAll() = %q, %v", resourceVersion, err)
+			}
+			base := "/apis/" + Group + "/" + Version + "/namespaces/base/" + Resource + "/reth"
+			if _, ok := api.patches[base]; ok != tt.wantSpec {
+				t.Errorf("spec patched = %v, want %v", ok, tt.wantSpec)
+			}
+			if tt.wantSpec && string(api.patches[base]["spec"]) != `{"commit":"c110","tag":"v1.1.0"}` {
+				t.Errorf("spec patch = %s", api.patches[base]["spec"])
+			}
+			var status Status
+			if err := json.Unmarshal(api.patches[base+"/status"]["status"], &status); err != nil {
+				t.Fatalf("status patch: %v", err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %+v, want %+v", status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+// Package plugins loads sources, targets and notifiers shipped as separate executables.
+//
+// Plugins are executables named updater-* in the plugins directory. Every call starts the
+// plugin, writes one request to its stdin and reads one response from its stdout:
+//
+//	request:  {"protocol": 1, "method": "listTags", "params": {"owner": "...", "repo": "..."}}
+//	response: {"result": [...]} or {"error": "message"}
+//
+// Anything written to stderr is passed through to the updater's log. Each plugin must answer
+// "describe" with {"protocol": 1, "kind": "source"|"target"|"notifier", "name": "..."}.
+//
+// Sources answer listTags, getRelease, resolveRef, repoURL and compareURL with the params and
+// results of the sources.VersionSource methods, and are used by versions.json entries whose "source"
+// is the plugin name. Targets answer read, plan, apply and verify like UpdateTarget, with Edit
+// contents base64 encoded. Their edits are applied after those of the built-in targets, or
+// after the targets listed in "dependsOn" of describe. Notifiers answer notify with the
+// applied updates, those with "kind": "promotion" promote a running release candidate to its
+// stable release. After a run, notify also has a "summary" of how far each dependency was
+// behind, such as "op_node: 2 minors behind; op_geth: up to date".
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/targets"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Protocol is the version of the plugin protocol, checked against describe.
+const Protocol = 1
+
+// Prefix is the file name prefix plugins are discovered by.
+const Prefix = "updater-"
+
+// pluginTimeout bounds every plugin call so a hung plugin can't block a run.
+const pluginTimeout = 2 * time.Minute
+
+type Info struct {
+	Protocol int    `json:"protocol"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	// DependsOn names the targets a target plugin's edits wait for.
+	DependsOn []string `json:"dependsOn,omitempty"`
+	Path      string   `json:"-"`
+}
+
+// Plugins are the plugins found in a directory, grouped by kind.
+type Plugins struct {
+	Sources   map[string]sources.VersionSource
+	Targets   []targets.UpdateTarget
+	Notifiers []runner.Notifier
+}
+
+// Load describes every plugin in dir. A missing directory means no plugins.
+func Load(ctx context.Context, dir string) (*Plugins, error) {
+	loaded := &Plugins{Sources: map[string]sources.VersionSource{}}
+	if dir == "" {
+		return loaded, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return loaded, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading plugins directory: %s", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), Prefix) {
+			continue
+		}
+		info, err := describePlugin(ctx, filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		client := &pluginClient{info: info}
+		switch info.Kind {
+		case "source":
+			if sources.Registered(info.Name) {
+				return nil, fmt.Errorf("plugin %s: source %s is built in", info.Path, info.Name)
+			}
+			if _, ok := loaded.Sources[info.Name]; ok {
+				return nil, fmt.Errorf("plugin %s: source %s is provided by another plugin", info.Path, info.Name)
+			}
+			loaded.Sources[info.Name] = pluginSource{client}
+		case "target":
+			loaded.Targets = append(loaded.Targets, pluginTarget{client})
+		case "notifier":
+			loaded.Notifiers = append(loaded.Notifiers, pluginNotifier{client})
+		default:
+			return nil, fmt.Errorf("plugin %s: unknown kind %q", info.Path, info.Kind)
+		}
+		log.Printf("Loaded %s plugin %s from %s", info.Kind, info.Name, info.Path)
+	}
+	return loaded, nil
+}
+
+func describePlugin(ctx context.Context, path string) (Info, error) {
+	var info Info
+	client := &pluginClient{info: Info{Name: filepath.Base(path), Path: path}}
+	if err := client.call(ctx, "describe", struct{}{}, &info); err != nil {
+		return Info{}, err
+	}
+	if info.Protocol != Protocol {
+		return Info{}, fmt.Errorf("plugin %s speaks protocol %d, expected %d", path, info.Protocol, Protocol)
+	}
+	if info.Name == "" {
+		return Info{}, fmt.Errorf("plugin %s did not describe its name", path)
+	}
+	info.Path = path
+	return info, nil
+}
+
+type pluginClient struct {
+	info Info
+}
+
+func (c *pluginClient) call(ctx context.Context, method string, params any, result any) error {
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+
+	request, err := json.Marshal(map[string]any{"protocol": Protocol, "method": method, "params": params})
+	if err != nil {
+		return fmt.Errorf("error encoding %s request for plugin %s: %s", method, c.info.Name, err)
+	}
+	cmd := exec.CommandContext(ctx, c.info.Path)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("plugin %s %s failed: %s", c.info.Name, method, err)
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}
+	if err := json.Unmarshal(out, &response); err != nil {
+		return fmt.Errorf("error decoding %s response from plugin %s: %s", method, c.info.Name, err)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("plugin %s %s: %s", c.info.Name, method, response.Error)
+	}
+	if result == nil || len(response.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("error decoding %s result from plugin %s: %s", method, c.info.Name, err)
+	}
+	return nil
+}
+
+type pluginSource struct {
+	client *pluginClient
+}
+
+type repoParams struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	Tag   string `json:"tag,omitempty"`
+	Ref   string `json:"ref,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+func (s pluginSource) ListTags(ctx context.Context, owner string, repo string) ([]sources.Tag, error) {
+	var tags []sources.Tag
+	err := s.client.call(ctx, "listTags", repoParams{Owner: owner, Repo: repo}, &tags)
+	return tags, err
+}
+
+func (s pluginSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*sources.Release, error) {
+	var release sources.Release
+	if err := s.client.call(ctx, "getRelease", repoParams{Owner: owner, Repo: repo, Tag: tag}, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+func (s pluginSource) ResolveRef(ctx context.Context, owner string, repo string, ref string) (string, error) {
+	var commit string
+	err := s.client.call(ctx, "resolveRef", repoParams{Owner: owner, Repo: repo, Ref: ref}, &commit)
+	return commit, err
+}
+
+// RepoURL and CompareURL can't return errors, a failing plugin leaves the URL empty.
+func (s pluginSource) RepoURL(owner string, repo string) string {
+	var url string
+	if err := s.client.call(context.Background(), "repoURL", repoParams{Owner: owner, Repo: repo}, &url); err != nil {
+		log.Printf("%s", err)
+	}
+	return url
+}
+
+func (s pluginSource) CompareURL(owner string, repo string, from string, to string) string {
+	var url string
+	if err := s.client.call(context.Background(), "compareURL", repoParams{Owner: owner, Repo: repo, From: from, To: to}, &url); err != nil {
+		log.Printf("%s", err)
+	}
+	return url
+}
+
+type pluginTarget struct {
+	client *pluginClient
+}
+
+type targetParams struct {
+	RepoPath     string               `json:"repoPath"`
+	Dependencies version.Dependencies `json:"dependencies,omitempty"`
+	Edit         *targets.Edit        `json:"edit,omitempty"`
+}
+
+func (t pluginTarget) Name() string { return t.client.info.Name }
+
+func (t pluginTarget) DependsOn() []string {
+	if t.client.info.DependsOn != nil {
+		return t.client.info.DependsOn
+	}
+	var builtin []string
+	for _, target := range targets.Defaults(nil) {
+		builtin = append(builtin, target.Name())
+	}
+	return builtin
+}
+
+func (t pluginTarget) Read(ctx context.Context, repoPath string) (map[string]targets.Pin, error) {
+	var pins map[string]targets.Pin
+	err := t.client.call(ctx, "read", targetParams{RepoPath: repoPath}, &pins)
+	return pins, err
+}
+
+func (t pluginTarget) Plan(ctx context.Context, repoPath string, dependencies version.Dependencies) ([]targets.Edit, error) {
+	var edits []targets.Edit
+	if err := t.client.call(ctx, "plan", targetParams{RepoPath: repoPath, Dependencies: dependencies}, &edits); err != nil {
+		return nil, err
+	}
+	// Edits are attributed to the plugin whatever it reported, so Apply is routed back to it.
+	for i := range edits {
+		edits[i].Target = t.Name()
+	}
+	return edits, nil
+}
+
+func (t pluginTarget) Apply(ctx context.Context, edit targets.Edit) error {
+	return t.client.call(ctx, "apply", targetParams{Edit: &edit}, nil)
+}
+
+func (t pluginTarget) Verify(ctx context.Context, repoPath string, dependencies version.Dependencies) error {
+	return t.client.call(ctx, "verify", targetParams{RepoPath: repoPath, Dependencies: dependencies}, nil)
+}
+
+type pluginNotifier struct {
+	client *pluginClient
+}
+
+func (n pluginNotifier) Notify(ctx context.Context, updates []version.UpdateInfo) error {
+	return n.client.call(ctx, "notify", map[string]any{"updates": updates}, nil)
+}
+
+func (n pluginNotifier) NotifySummary(ctx context.Context, updates []version.UpdateInfo, summary string) error {
+	return n.client.call(ctx, "notify", map[string]any{"updates": updates, "summary": summary}, nil)
+}
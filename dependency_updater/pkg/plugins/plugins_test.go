@@ -0,0 +1,95 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// TestMain lets the test binary act as a plugin, the kind is taken from UPDATER_TEST_PLUGIN.
+func TestMain(m *testing.M) {
+	if kind := os.Getenv("UPDATER_TEST_PLUGIN"); kind != "" {
+		runTestPlugin(kind)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runTestPlugin(kind string) {
+	var request struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	json.NewDecoder(os.Stdin).Decode(&request)
+	var result any
+	switch request.Method {
+	case "describe":
+		result = Info{Protocol: Protocol, Kind: kind, Name: "test" + kind}
+	case "listTags":
+		result = []sources.Tag{{Name: "v1.0.0", Commit: "abc"}}
+	case "repoURL":
+		result = "https://plugin.example/owner/repo"
+	case "notify":
+		var params struct {
+			Updates []version.UpdateInfo `json:"updates"`
+		}
+		json.Unmarshal(request.Params, &params)
+		os.WriteFile(os.Getenv("UPDATER_TEST_NOTIFY"), []byte(params.Updates[0].To), 0644)
+	default:
+		json.NewEncoder(os.Stdout).Encode(map[string]any{"error": "unsupported method " + request.Method})
+		return
+	}
+	json.NewEncoder(os.Stdout).Encode(map[string]any{"result": result})
+}
+
+func installTestPlugin(t *testing.T, dir string, kind string) {
+	t.Helper()
+	script := fmt.Sprintf("#!/bin/sh\nUPDATER_TEST_PLUGIN=%s exec %s\n", kind, os.Args[0])
+	if err := os.WriteFile(filepath.Join(dir, Prefix+kind), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlugins(t *testing.T) {
+	dir := t.TempDir()
+	installTestPlugin(t, dir, "source")
+	installTestPlugin(t, dir, "notifier")
+	notified := filepath.Join(t.TempDir(), "notified")
+	t.Setenv("UPDATER_TEST_NOTIFY", notified)
+
+	loaded, err := Load(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	source, ok := loaded.Sources["testsource"]
+	if !ok || len(loaded.Notifiers) != 1 {
+		t.Fatalf("Load() = %+v, want a source and a notifier", loaded)
+	}
+
+	tags, err := source.ListTags(context.Background(), "owner", "repo")
+	if err != nil || len(tags) != 1 || tags[0] != (sources.Tag{Name: "v1.0.0", Commit: "abc"}) {
+		t.Errorf("ListTags() = %v, %v", tags, err)
+	}
+	if url := source.RepoURL("owner", "repo"); url != "https://plugin.example/owner/repo" {
+		t.Errorf("RepoURL() = %s", url)
+	}
+	if _, err := source.ResolveRef(context.Background(), "owner", "repo", "main"); err == nil {
+		t.Errorf("ResolveRef() expected the plugin's error")
+	}
+
+	runner.NotifyAll(context.Background(), loaded.Notifiers, []version.UpdateInfo{{Repo: "repo", To: "v1.0.0"}})
+	if got, _ := os.ReadFile(notified); string(got) != "v1.0.0" {
+		t.Errorf("notifier received %q, want v1.0.0", got)
+	}
+
+	if loaded, err := Load(context.Background(), filepath.Join(dir, "missing")); err != nil || len(loaded.Sources) != 0 {
+		t.Errorf("Load() on a missing directory = %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,676 @@
+// Package policy selects the version a dependency should be updated to.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Resolve returns the update for a dependency, or nil if it is already at the version its
+// tracking mode selects. Tag and release tracking pick the highest matching tag that is not
+// a downgrade, branch tracking follows the branch head and nightly tracking the newest
+// nightly build tag.
+func Resolve(ctx context.Context, source sources.VersionSource, name string, dependency *version.Info) (*version.PlannedUpdate, error) {
+	planned, _, err := ResolveWithRationale(ctx, source, name, dependency)
+	return planned, err
+}
+
+// Rationale is why Resolve selected what it did for a dependency, for results and PR bodies.
+type Rationale struct {
+	Dependency string `json:"dependency"`
+	Tracking   string `json:"tracking"`
+	TagPrefix  string `json:"tagPrefix,omitempty"`
+	Current    string `json:"current"`
+	// Skipped is why the dependency wasn't resolved in a run, such as "pinned", those
+	// rationales have nothing else set.
+	Skipped string `json:"skipped,omitempty"`
+	// Tags is how many tags were read, Resolve stops paging once pages only hold older tags.
+	// Matched is how many of them passed the filters and Newer how many of those are newer
+	// than Current.
+	Tags    int `json:"tags,omitempty"`
+	Matched int `json:"matched,omitempty"`
+	Newer   int `json:"newer,omitempty"`
+	// Filtered counts the tags each filter removed: "tag prefix", "unparseable",
+	// "prerelease", "not an rc", "other prerelease", "no build date", "retracted" and
+	// "downgrade".
+	Filtered map[string]int `json:"filtered,omitempty"`
+	// Selected is the winning tag, or the branch head for branch tracking.
+	Selected string `json:"selected,omitempty"`
+	// TieBreak says how Selected won over tags of the same version, if there were any.
+	TieBreak string `json:"tieBreak,omitempty"`
+	// NewestRC is the newest release candidate release tracking passed over that is newer
+	// than both Current and Selected, if there is one.
+	NewestRC string `json:"newestRC,omitempty"`
+	// Outcome summarizes the result in a sentence.
+	Outcome string `json:"outcome"`
+}
+
+func (r Rationale) String() string {
+	if r.Skipped != "" {
+		return "skipped, " + r.Skipped
+	}
+	line := r.Outcome
+	if r.Tracking != "branch" && r.Outcome != "" {
+		line += fmt.Sprintf(" (%d tags read, %d matched, %d newer", r.Tags, r.Matched, r.Newer)
+		for _, filter := range slices.Sorted(maps.Keys(r.Filtered)) {
+			line += fmt.Sprintf(", %d %s", r.Filtered[filter], filter)
+		}
+		line += ")"
+	}
+	if r.TieBreak != "" {
+		line += ", " + r.TieBreak
+	}
+	return line
+}
+
+// UnparseableCurrentError is returned for a strict dependency whose current tag doesn't parse,
+// leaving no version to protect against downgrades from.
+type UnparseableCurrentError struct {
+	Dependency string
+	Tag        string
+	Err        error
+}
+
+func (e *UnparseableCurrentError) Error() string {
+	return fmt.Sprintf("current tag %q of %s doesn't parse, a strict dependency isn't updated from it: %s", e.Tag, e.Dependency, e.Err)
+}
+
+func (e *UnparseableCurrentError) Unwrap() error {
+	return e.Err
+}
+
+// checkCurrent returns an UnparseableCurrentError if the dependency is strict and its current
+// tag doesn't parse for its tracking mode.
+func checkCurrent(name string, dependency *version.Info) error {
+	if !dependency.Strict {
+		return nil
+	}
+	var err error
+	switch dependency.Tracking {
+	case "release", "tag":
+		_, err = version.ParseVersion(dependency.Tag, dependency.PrefixOf(dependency.Tag))
+	case "nightly":
+		_, err = version.ParseNightly(dependency.Tag, dependency.PrefixOf(dependency.Tag))
+	}
+	if err != nil {
+		return &UnparseableCurrentError{Dependency: name, Tag: dependency.Tag, Err: err}
+	}
+	return nil
+}
+
+// TieBreaks are the values of a dependency's tieBreak.
+var TieBreaks = []string{"published", "build", "current"}
+
+// Validate returns the errors of a dependency's entry that resolving it again won't fix, a
+// current tag strict mode can't parse and unknown tieBreak or vPrefix values.
+func Validate(name string, dependency *version.Info) error {
+	if err := checkCurrent(name, dependency); err != nil {
+		return err
+	}
+	if dependency.TieBreak != "" && !slices.Contains(TieBreaks, dependency.TieBreak) {
+		return fmt.Errorf("unknown tieBreak %q for %s, expected one of %s", dependency.TieBreak, name, strings.Join(TieBreaks, ", "))
+	}
+	if dependency.VPrefix != "" && !slices.Contains(version.VPrefixes, dependency.VPrefix) {
+		return fmt.Errorf("unknown vPrefix %q for %s, expected one of %s", dependency.VPrefix, name, strings.Join(version.VPrefixes, ", "))
+	}
+	return nil
+}
+
+// ResolveWithRationale is Resolve, also returning the rationale of its selection.
+func ResolveWithRationale(ctx context.Context, source sources.VersionSource, name string, dependency *version.Info) (*version.PlannedUpdate, *Rationale, error) {
+	if err := Validate(name, dependency); err != nil {
+		return nil, nil, err
+	}
+	var selectedTag *sources.Tag
+	// retracted is set when the current tag is retracted upstream, and fellBack when the
+	// selection moves off it to an earlier version.
+	var retracted, fellBack bool
+	var commit string
+	var diffUrl string
+	var updatedDependency version.UpdateInfo
+	currentTag := dependency.Tag
+	rationale := &Rationale{Dependency: name, Tracking: dependency.Tracking, TagPrefix: dependency.TagPrefix, Current: currentTag}
+
+	if dependency.Tracking == "tag" || dependency.Tracking == "release" || dependency.Tracking == "nightly" {
+		selector, err := selectTagStream(sources.TagPages(ctx, source, dependency.Owner, dependency.Repo), dependency)
+		if err != nil {
+			return nil, nil, err
+		}
+		selector.breakTie(ctx, source)
+		retracted, fellBack = selector.currentRetracted, selector.moveOffRetracted()
+		selectedTag = selector.selected
+		rationale.Tags, rationale.Matched, rationale.Newer = selector.tags, selector.matched, selector.newer
+		rationale.Filtered, rationale.TieBreak = selector.filtered, selector.tieBreak
+		rationale.NewestRC = selector.newerRC()
+
+		// If no valid version found, keep current version
+		if selectedTag == nil {
+			if selector.currentRetracted {
+				log.Printf("Warning: %s %s is retracted upstream, but there is no other valid version to move to", name, currentTag)
+				rationale.Outcome = fmt.Sprintf("no valid version, keeping %s although it is retracted upstream", currentTag)
+				return nil, rationale, nil
+			}
+			log.Printf("No valid upgrade found for %s, keeping %s", name, currentTag)
+			rationale.Outcome = fmt.Sprintf("no valid version, keeping %s", currentTag)
+			return nil, rationale, nil
+		}
+		rationale.Selected = selectedTag.Name
+
+		if selectedTag.Name != currentTag {
+			if selectedTag.Published.IsZero() {
+				// Tags without a release have no publish time.
+				if release, err := source.GetRelease(ctx, dependency.Owner, dependency.Repo, selectedTag.Name); err == nil {
+					selectedTag.Published = release.PublishedAt
+				}
+			}
+			diffUrl = source.CompareURL(dependency.Owner, dependency.Repo, currentTag, selectedTag.Name)
+			rationale.Outcome = fmt.Sprintf("selected %s, the highest valid version", selectedTag.Name)
+		} else {
+			rationale.Outcome = fmt.Sprintf("up to date, %s is the highest valid version", currentTag)
+		}
+
+		commit = selectedTag.Commit
+	}
+
+	if diffUrl != "" {
+		updatedDependency = version.UpdateInfo{
+			Repo:      dependency.Repo,
+			From:      dependency.Tag,
+			To:        selectedTag.Name,
+			DiffUrl:   diffUrl,
+			Published: selectedTag.Published,
+		}
+		switch {
+		case fellBack:
+			updatedDependency.Kind = version.KindRetraction
+			rationale.Outcome = fmt.Sprintf("%s is retracted upstream, selected %s, the nearest earlier version", currentTag, selectedTag.Name)
+		case retracted:
+			updatedDependency.Kind = version.KindRetraction
+			rationale.Outcome = fmt.Sprintf("selected %s, the highest valid version, replacing the retracted %s", selectedTag.Name, currentTag)
+		case version.IsPromotion(dependency.Tag, selectedTag.Name, dependency.TagPrefix):
+			updatedDependency.Kind = version.KindPromotion
+			rationale.Outcome = fmt.Sprintf("selected %s, the stable release of the running release candidate", selectedTag.Name)
+		}
+	}
+
+	if dependency.Tracking == "branch" {
+		branchCommit, err := source.ResolveRef(ctx, dependency.Owner, dependency.Repo, dependency.Branch)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error resolving branch for "+name+": %s", err)
+		}
+		commit = branchCommit
+		rationale.Current, rationale.Selected = dependency.Commit, commit
+		rationale.Outcome = fmt.Sprintf("branch %s is still at %s", dependency.Branch, commit)
+		if dependency.Commit != commit {
+			from, to := dependency.Commit, commit
+			diffUrl = source.CompareURL(dependency.Owner, dependency.Repo, from, to)
+			updatedDependency = version.UpdateInfo{
+				Repo:    dependency.Repo,
+				From:    dependency.Tag,
+				To:      commit,
+				DiffUrl: diffUrl,
+			}
+			rationale.Outcome = fmt.Sprintf("branch %s moved to %s", dependency.Branch, commit)
+		}
+	}
+
+	if updatedDependency == (version.UpdateInfo{}) {
+		return nil, rationale, nil
+	}
+	planned := &version.PlannedUpdate{Dependency: name, Commit: commit, Info: updatedDependency}
+	if selectedTag != nil {
+		planned.Version = selectedTag.Name
+	}
+	return planned, rationale, nil
+}
+
+// Released says how long before now a release was published, such as "released 3 days ago",
+// or "" if the publish time is unknown.
+func Released(published time.Time, now time.Time) string {
+	if published.IsZero() {
+		return ""
+	}
+	switch days := int(now.Sub(published).Hours() / 24); {
+	case days <= 0:
+		return "released today"
+	case days == 1:
+		return "released 1 day ago"
+	default:
+		return fmt.Sprintf("released %d days ago", days)
+	}
+}
+
+// Explanation is what Resolve saw and selected for a dependency.
+type Explanation struct {
+	Dependency string
+	Tracking   string
+	TagPrefix  string
+	Current    string
+	// Tags is how many tags the source listed, Matched how many of them match the prefix and
+	// tracking mode and Newer how many of those are newer than Current.
+	Tags    int
+	Matched int
+	Newer   int
+	// Selected is the highest valid tag, or the branch head for branch tracking.
+	Selected string
+	// Update is what Resolve returns, nil if the dependency is up to date.
+	Update *version.PlannedUpdate
+}
+
+// Explain resolves a dependency like Resolve and reports the tags it considered. Unlike
+// Resolve it reads every tag page.
+func Explain(ctx context.Context, source sources.VersionSource, name string, dependency *version.Info) (*Explanation, error) {
+	if err := checkCurrent(name, dependency); err != nil {
+		return nil, err
+	}
+	explanation := &Explanation{Dependency: name, Tracking: dependency.Tracking, TagPrefix: dependency.TagPrefix, Current: dependency.Tag}
+	if dependency.Tracking == "tag" || dependency.Tracking == "release" || dependency.Tracking == "nightly" {
+		tags, err := source.ListTags(ctx, dependency.Owner, dependency.Repo)
+		if err != nil {
+			return nil, err
+		}
+		selector := newTagSelector(dependency)
+		explanation.Tags = len(tags)
+		explanation.Matched, explanation.Newer = selector.add(tags)
+		selector.breakTie(ctx, source)
+		selector.moveOffRetracted()
+		if selector.selected != nil {
+			explanation.Selected = selector.selected.Name
+		}
+	}
+	if dependency.Tracking == "branch" {
+		explanation.Current = dependency.Commit
+	}
+
+	update, err := Resolve(ctx, source, name, dependency)
+	if err != nil {
+		return nil, err
+	}
+	explanation.Update = update
+	if dependency.Tracking == "branch" {
+		explanation.Selected = dependency.Commit
+		if update != nil {
+			explanation.Selected = update.Commit
+		}
+	}
+	return explanation, nil
+}
+
+// Matching returns the tags whose version, after the tag prefix, satisfies constraints, such
+// as ">= 1.2, < 2".
+func Matching(tags []sources.Tag, tagPrefix string, constraints *semver.Constraints) []sources.Tag {
+	var matching []sources.Tag
+	for _, tag := range tags {
+		if tagPrefix != "" && !strings.HasPrefix(tag.Name, tagPrefix) {
+			continue
+		}
+		if v, err := version.ParseVersion(tag.Name, tagPrefix); err == nil && constraints.Check(v) {
+			matching = append(matching, tag)
+		}
+	}
+	return matching
+}
+
+// Compatible returns the tags whose version is not a breaking bump from the dependency's
+// current version, see version.Bump, or every tag if the current one doesn't parse.
+func Compatible(tags []sources.Tag, dependency *version.Info) []sources.Tag {
+	current, err := version.ParseVersion(dependency.Tag, dependency.PrefixOf(dependency.Tag))
+	if err != nil {
+		return tags
+	}
+	var compatible []sources.Tag
+	for _, tag := range tags {
+		if v, err := version.ParseVersion(tag.Name, dependency.PrefixOf(tag.Name)); err == nil && version.Bump(current, v, dependency.PreOneBreaking) != "major" {
+			compatible = append(compatible, tag)
+		}
+	}
+	return compatible
+}
+
+// Between returns the tags with the dependency's prefix after from up to and including to,
+// newest first. Tags that don't parse as versions are left out.
+func Between(tags []sources.Tag, tagPrefix string, from string, to string) ([]string, error) {
+	first, err := version.ParseVersion(from, tagPrefix)
+	if err != nil {
+		return nil, err
+	}
+	last, err := version.ParseVersion(to, tagPrefix)
+	if err != nil {
+		return nil, err
+	}
+	type tagVersion struct {
+		name    string
+		version *semver.Version
+	}
+	var found []tagVersion
+	for _, tag := range tags {
+		if tagPrefix != "" && !strings.HasPrefix(tag.Name, tagPrefix) {
+			continue
+		}
+		v, err := version.ParseVersion(tag.Name, tagPrefix)
+		if err != nil || !v.GreaterThan(first) || v.GreaterThan(last) {
+			continue
+		}
+		found = append(found, tagVersion{tag.Name, v})
+	}
+	slices.SortFunc(found, func(a, b tagVersion) int { return b.version.Compare(a.version) })
+	names := []string{}
+	for _, tag := range found {
+		names = append(names, tag.name)
+	}
+	return names, nil
+}
+
+// stopAfterOlderPages is how many consecutive tag pages with matching tags but none newer
+// than the current one end the stream. Forges list tags roughly newest first, so by then
+// the remaining pages are history.
+const stopAfterOlderPages = 2
+
+// SelectTag returns the highest tag matching the dependency's prefix and tracking mode
+// that is not a downgrade, or nil if there is none.
+func SelectTag(tags []sources.Tag, dependency *version.Info) *sources.Tag {
+	selector := newTagSelector(dependency)
+	selector.add(tags)
+	selector.breakTie(context.Background(), nil)
+	selector.moveOffRetracted()
+	return selector.selected
+}
+
+// selectTagStream is SelectTag over a stream of tag pages, which stops paging once
+// stopAfterOlderPages pages in a row had nothing newer than the current tag. Without a current
+// tag every page is read, and with a retracted one pages are read until one below it is
+// found. It returns the selector, with the selected tag and the counts.
+func selectTagStream(pages iter.Seq2[[]sources.Tag, error], dependency *version.Info) (*tagSelector, error) {
+	selector := newTagSelector(dependency)
+	olderPages := 0
+	for page, err := range pages {
+		if err != nil {
+			return nil, err
+		}
+		matched, newer := selector.add(page)
+		if dependency.Tag == "" || matched == 0 || (selector.currentRetracted && selector.fallback == nil) {
+			continue
+		}
+		if newer > 0 {
+			olderPages = 0
+		} else if olderPages++; olderPages >= stopAfterOlderPages {
+			break
+		}
+	}
+	return selector, nil
+}
+
+// tagSelector keeps the highest valid tag of the pages added so far. Each tag is parsed
+// once, repositories like go-ethereum have thousands.
+type tagSelector struct {
+	dependency *version.Info
+	selected   *sources.Tag
+	// current is the parsed current tag, nil if unset or unparseable.
+	current         *semver.Version
+	currentParsed   bool
+	selectedVersion *semver.Version
+	// The same for nightly tracking, by build date. currentNightly is the current tag as
+	// listed, with its publish time once a page has it.
+	currentDate    time.Time
+	currentNightly sources.Tag
+	selectedDate   time.Time
+
+	// Totals over all pages for the rationale.
+	tags, matched, newer int
+	filtered             map[string]int
+	tieBreak             string
+	// ties are the other tags of the selected version, which only differ in build metadata
+	// or the "v" prefix.
+	ties []sources.Tag
+	// The newest release candidate newer than the current tag that release tracking skipped.
+	newestRC        *sources.Tag
+	newestRCVersion *semver.Version
+	// currentRetracted is set if upstream retracted the current tag, and fallback is then the
+	// highest valid tag below it.
+	currentRetracted bool
+	fallback         *sources.Tag
+	fallbackVersion  *semver.Version
+	fallbackDate     time.Time
+}
+
+// moveOffRetracted selects the fallback if the current tag is retracted and no tag above it
+// is valid, and reports whether it did.
+func (s *tagSelector) moveOffRetracted() bool {
+	if !s.currentRetracted || s.selected != nil || s.fallback == nil {
+		return false
+	}
+	s.selected, s.selectedVersion, s.selectedDate, s.tieBreak = s.fallback, s.fallbackVersion, s.fallbackDate, ""
+	return true
+}
+
+// newerRC is the name of the newest skipped release candidate if it is newer than the
+// selected tag.
+func (s *tagSelector) newerRC() string {
+	if s.newestRC == nil || (s.selectedVersion != nil && !s.newestRCVersion.GreaterThan(s.selectedVersion)) {
+		return ""
+	}
+	return s.newestRC.Name
+}
+
+func newTagSelector(dependency *version.Info) *tagSelector {
+	return &tagSelector{dependency: dependency, filtered: map[string]int{}}
+}
+
+// add considers tags and returns how many matched the dependency's prefix and tracking
+// mode, and how many of those are newer than its current tag.
+func (s *tagSelector) add(tags []sources.Tag) (matched int, newer int) {
+	defer func() {
+		s.tags, s.matched, s.newer = s.tags+len(tags), s.matched+matched, s.newer+newer
+	}()
+	if s.dependency.Tracking == "nightly" {
+		return s.addNightly(tags)
+	}
+	if !s.currentParsed {
+		s.current, _ = version.ParseVersion(s.dependency.Tag, s.dependency.PrefixOf(s.dependency.Tag))
+		s.currentRetracted = s.dependency.IsRetracted(s.dependency.Tag)
+		s.currentParsed = true
+	}
+
+	for i, tag := range tags {
+		// Skip tags with neither the tag prefix nor that of an alias
+		if !s.dependency.HasPrefix(tag.Name) {
+			s.filtered["tag prefix"]++
+			continue
+		}
+
+		v, err := version.ParseVersion(tag.Name, s.dependency.PrefixOf(tag.Name))
+		if err != nil {
+			// Release and tag tracking only match parseable tags
+			if s.dependency.Tracking != "release" && s.dependency.Tracking != "tag" {
+				log.Printf("Skipping unparseable tag %s: %v", tag.Name, err)
+			}
+			s.filtered["unparseable"]++
+			continue
+		}
+
+		// Filter based on tracking mode:
+		// - "release": only stable releases (no prerelease suffix)
+		// - "tag": releases and RC versions only (exclude -synctest, -alpha, etc.), or the
+		//   dependency's prerelease types
+		if s.dependency.Tracking == "release" {
+			if v.Prerelease() != "" {
+				s.filtered["prerelease"]++
+				if version.IsRCPrerelease(v.Prerelease()) && (s.current == nil || v.GreaterThan(s.current)) && (s.newestRC == nil || v.GreaterThan(s.newestRCVersion)) {
+					s.newestRC, s.newestRCVersion = &tags[i], v
+				}
+				continue
+			}
+		} else if s.dependency.Tracking == "tag" {
+			if len(s.dependency.Prereleases) > 0 {
+				if v.Prerelease() != "" && !slices.Contains(s.dependency.Prereleases, version.PrereleaseTypeOf(v.Prerelease())) {
+					s.filtered["other prerelease"]++
+					continue
+				}
+			} else if v.Prerelease() != "" && !version.IsRCPrerelease(v.Prerelease()) {
+				s.filtered["not an rc"]++
+				continue
+			}
+		}
+		matched++
+
+		if s.dependency.IsRetracted(tag.Name) {
+			s.filtered["retracted"]++
+			continue
+		}
+
+		// Skip downgrades, any version is valid if the current one is unset or unparseable
+		if s.current != nil && v.LessThan(s.current) {
+			s.filtered["downgrade"]++
+			if s.currentRetracted && (s.fallback == nil || v.GreaterThan(s.fallbackVersion)) {
+				s.fallback, s.fallbackVersion = &tags[i], v
+			}
+			continue
+		}
+		if s.current == nil || v.GreaterThan(s.current) {
+			newer++
+		}
+
+		if s.selected == nil || v.GreaterThan(s.selectedVersion) {
+			s.selected = &tags[i]
+			s.selectedVersion = v
+			s.tieBreak, s.ties = "", nil
+		} else if v.Equal(s.selectedVersion) && tag.Name != s.selected.Name {
+			s.tieBreak = fmt.Sprintf("%s and %s are the same version, kept %s as it was listed first", s.selected.Name, tag.Name, s.selected.Name)
+			s.ties = append(s.ties, tag)
+		}
+	}
+	return matched, newer
+}
+
+// sameDayLater reports whether nightly a is a later build than b of the same day, and what
+// says so: the publish times if both have one, else the names.
+func sameDayLater(a sources.Tag, b sources.Tag) (bool, string) {
+	if !a.Published.IsZero() && !b.Published.IsZero() {
+		return a.Published.After(b.Published), "published"
+	}
+	return a.Name > b.Name, "name"
+}
+
+
+var buildCounter = regexp.MustCompile(`(\d+)$`)
+
+// breakTie picks between the selected tag and its ties with the dependency's tieBreak. The
+// published tie-break reads the release of each from source, and keeps the tag listed first
+// without a source.
+func (s *tagSelector) breakTie(ctx context.Context, source sources.VersionSource) {
+	if len(s.ties) == 0 || s.dependency.TieBreak == "" {
+		return
+	}
+	candidates := append([]sources.Tag{*s.selected}, s.ties...)
+	names := make([]string, len(candidates))
+	for i, tag := range candidates {
+		names[i] = tag.Name
+	}
+	winner, reason := 0, ""
+	switch s.dependency.TieBreak {
+	case "current":
+		if i := slices.Index(names, s.dependency.Tag); i >= 0 {
+			winner, reason = i, "the current tag"
+		}
+	case "build":
+		highest := -1
+		for i, tag := range candidates {
+			v, err := version.ParseVersion(tag.Name, s.dependency.PrefixOf(tag.Name))
+			if err != nil {
+				continue
+			}
+			if m := buildCounter.FindString(v.Metadata()); m != "" {
+				if n, _ := strconv.Atoi(m); n > highest {
+					winner, reason, highest = i, "the highest build", n
+				}
+			}
+		}
+	case "published":
+		if source == nil {
+			break
+		}
+		var latest time.Time
+		for i, tag := range candidates {
+			// Tags without a release are never the latest published.
+			release, err := source.GetRelease(ctx, s.dependency.Owner, s.dependency.Repo, tag.Name)
+			if err == nil && release.PublishedAt.After(latest) {
+				winner, reason, latest = i, "the latest published", release.PublishedAt
+			}
+		}
+	}
+	if reason == "" {
+		return
+	}
+	s.selected = &candidates[winner]
+	s.tieBreak = fmt.Sprintf("%s are the same version, kept %s, %s", strings.Join(names, " and "), s.selected.Name, reason)
+}
+
+// addNightly is add for nightly tracking, which orders tags by build date and, for builds of
+// the same day, by publish time or, without one, by name. Builds of the current tag's day
+// are downgrades by the same order.
+func (s *tagSelector) addNightly(tags []sources.Tag) (matched int, newer int) {
+	if !s.currentParsed {
+		s.currentDate, _ = version.ParseNightly(s.dependency.Tag, s.dependency.PrefixOf(s.dependency.Tag))
+		s.currentNightly = sources.Tag{Name: s.dependency.Tag}
+		s.currentRetracted = s.dependency.IsRetracted(s.dependency.Tag)
+		s.currentParsed = true
+	}
+	if i := slices.IndexFunc(tags, func(tag sources.Tag) bool { return tag.Name == s.dependency.Tag }); i >= 0 {
+		s.currentNightly = tags[i]
+	}
+	after := func(date time.Time, tag sources.Tag, than time.Time, thanTag sources.Tag) bool {
+		later, _ := sameDayLater(tag, thanTag)
+		return date.After(than) || (date.Equal(than) && later)
+	}
+
+	for i, tag := range tags {
+		if !s.dependency.HasPrefix(tag.Name) {
+			continue
+		}
+		date, err := version.ParseNightly(tag.Name, s.dependency.PrefixOf(tag.Name))
+		if err != nil {
+			s.filtered["no build date"]++
+			continue
+		}
+		matched++
+
+		if s.dependency.IsRetracted(tag.Name) {
+			s.filtered["retracted"]++
+			continue
+		}
+
+		if !s.currentDate.IsZero() && tag.Name != s.dependency.Tag && !after(date, tag, s.currentDate, s.currentNightly) {
+			s.filtered["downgrade"]++
+			if s.currentRetracted && (s.fallback == nil || after(date, tag, s.fallbackDate, *s.fallback)) {
+				s.fallback, s.fallbackDate = &tags[i], date
+			}
+			continue
+		}
+		if s.currentDate.IsZero() || tag.Name != s.dependency.Tag {
+			newer++
+		}
+
+		if s.selected == nil || date.After(s.selectedDate) {
+			s.selected, s.selectedDate, s.tieBreak = &tags[i], date, ""
+		} else if date.Equal(s.selectedDate) {
+			later, by := sameDayLater(tag, *s.selected)
+			s.tieBreak = fmt.Sprintf("%s and %s were built the same day, kept the later %s", s.selected.Name, tag.Name, by)
+			if later {
+				s.selected = &tags[i]
+			}
+		}
+	}
+	return matched, newer
+}
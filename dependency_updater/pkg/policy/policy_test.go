@@ -0,0 +1,603 @@
+package policy
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// fakeSource serves fixed tags, branch heads and the publish times of releases.
+type fakeSource struct {
+	tags      []sources.Tag
+	branches  map[string]string
+	published map[string]time.Time
+}
+
+func (s *fakeSource) ListTags(ctx context.Context, owner string, repo string) ([]sources.Tag, error) {
+	return s.tags, nil
+}
+
+func (s *fakeSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*sources.Release, error) {
+	if s.published != nil {
+		if _, ok := s.published[tag]; !ok {
+			return nil, fmt.Errorf("no release for %s", tag)
+		}
+	}
+	return &sources.Release{Tag: tag, PublishedAt: s.published[tag]}, nil
+}
+
+func (s *fakeSource) ResolveRef(ctx context.Context, owner string, repo string, ref string) (string, error) {
+	return s.branches[ref], nil
+}
+
+func (s *fakeSource) RepoURL(owner string, repo string) string {
+	return "https://forge.example/" + owner + "/" + repo
+}
+
+func (s *fakeSource) CompareURL(owner string, repo string, from string, to string) string {
+	return s.RepoURL(owner, repo) + "/compare/" + from + "..." + to
+}
+
+func TestResolve(t *testing.T) {
+	source := &fakeSource{
+		tags: []sources.Tag{
+			{Name: "v1.2.0", Commit: "c120"},
+			{Name: "v1.3.0-rc1", Commit: "c130rc1"},
+			{Name: "v1.2.1", Commit: "c121"},
+			{Name: "v1.1.0", Commit: "c110"},
+			{Name: "v1.3.0-synctest.0", Commit: "csync"},
+		},
+		branches: map[string]string{"main": "cmain"},
+	}
+
+	tests := []struct {
+		name        string
+		info        version.Info
+		wantUpdate  bool
+		wantVersion string
+		wantCommit  string
+	}{
+		{"release tracking", version.Info{Tag: "v1.2.0", Commit: "c120", Tracking: "release"}, true, "v1.2.1", "c121"},
+		{"tag tracking includes rc", version.Info{Tag: "v1.2.0", Commit: "c120", Tracking: "tag"}, true, "v1.3.0-rc1", "c130rc1"},
+		{"already latest", version.Info{Tag: "v1.2.1", Commit: "c121", Tracking: "release"}, false, "", ""},
+		{"no downgrade", version.Info{Tag: "v2.0.0", Commit: "c200", Tracking: "release"}, false, "", ""},
+		{"branch tracking", version.Info{Branch: "main", Commit: "cold", Tracking: "branch"}, true, "", "cmain"},
+		{"branch unchanged", version.Info{Branch: "main", Commit: "cmain", Tracking: "branch"}, false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.info
+			info.Owner, info.Repo = "owner", "repo"
+			planned, err := Resolve(context.Background(), source, "dep", &info)
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if (planned != nil) != tt.wantUpdate {
+				t.Fatalf("Resolve() = %+v, want update %v", planned, tt.wantUpdate)
+			}
+			if planned == nil {
+				return
+			}
+			if planned.Version != tt.wantVersion || planned.Commit != tt.wantCommit || planned.Dependency != "dep" {
+				t.Errorf("Resolve() = %+v, want %s %s", planned, tt.wantVersion, tt.wantCommit)
+			}
+			if !strings.HasPrefix(planned.Info.DiffUrl, "https://forge.example/owner/repo/compare/") {
+				t.Errorf("Resolve() diff URL = %s", planned.Info.DiffUrl)
+			}
+		})
+	}
+}
+
+func TestResolveStrict(t *testing.T) {
+	source := &fakeSource{tags: []sources.Tag{{Name: "v1.2.0", Commit: "c120"}, {Name: "v1.1.0", Commit: "c110"}}}
+	tests := []struct {
+		name        string
+		info        version.Info
+		wantErr     bool
+		wantVersion string
+	}{
+		{"unparseable allows any update", version.Info{Tag: "latest", Tracking: "release"}, false, "v1.2.0"},
+		{"strict unparseable", version.Info{Tag: "latest", Tracking: "release", Strict: true}, true, ""},
+		{"strict parseable", version.Info{Tag: "v1.1.0", Tracking: "release", Strict: true}, false, "v1.2.0"},
+		{"strict nightly without a build date", version.Info{Tag: "nightly", Tracking: "nightly", Strict: true}, true, ""},
+		{"strict branch", version.Info{Branch: "main", Commit: "cmain", Tracking: "branch", Strict: true}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned, err := Resolve(context.Background(), source, "dep", &tt.info)
+			var unparseable *UnparseableCurrentError
+			if errors.As(err, &unparseable) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, want an UnparseableCurrentError %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if unparseable.Dependency != "dep" || unparseable.Tag != tt.info.Tag {
+					t.Errorf("Resolve() error = %+v", unparseable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			got := ""
+			if planned != nil {
+				got = planned.Version
+			}
+			if got != tt.wantVersion {
+				t.Errorf("Resolve() = %+v, want %s", planned, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestResolveWithRationale(t *testing.T) {
+	source := &fakeSource{
+		tags: []sources.Tag{
+			{Name: "v1.2.0", Commit: "c120"},
+			{Name: "v1.3.0-rc1", Commit: "c130rc1"},
+			{Name: "v1.2.1", Commit: "c121"},
+			{Name: "1.2.1", Commit: "c121"},
+			{Name: "v1.1.0", Commit: "c110"},
+			{Name: "v1.3.0-synctest.0", Commit: "csync"},
+		},
+		branches: map[string]string{"main": "cmain"},
+	}
+	tests := []struct {
+		name string
+		info version.Info
+		want string
+		rc   string
+	}{
+		{
+			"release tracking",
+			version.Info{Tag: "v1.2.0", Tracking: "release"},
+			"selected v1.2.1, the highest valid version (6 tags read, 4 matched, 2 newer, 1 downgrade, 2 prerelease), v1.2.1 and 1.2.1 are the same version, kept v1.2.1 as it was listed first",
+			"v1.3.0-rc1",
+		},
+		{
+			"tag tracking",
+			version.Info{Tag: "v1.3.0-rc1", Tracking: "tag"},
+			"up to date, v1.3.0-rc1 is the highest valid version (6 tags read, 5 matched, 0 newer, 4 downgrade, 1 not an rc)",
+			"",
+		},
+		{
+			"tag tracking of other prereleases",
+			version.Info{Tag: "v1.2.1", Tracking: "tag", Prereleases: []string{version.PrereleaseSynctest}},
+			"selected v1.3.0-synctest.0, the highest valid version (6 tags read, 5 matched, 1 newer, 2 downgrade, 1 other prerelease)",
+			"",
+		},
+		{"branch tracking", version.Info{Branch: "main", Commit: "cold", Tracking: "branch"}, "branch main moved to cmain", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.info
+			info.Owner, info.Repo = "owner", "repo"
+			_, rationale, err := ResolveWithRationale(context.Background(), source, "dep", &info)
+			if err != nil {
+				t.Fatalf("ResolveWithRationale() unexpected error: %v", err)
+			}
+			if got := rationale.String(); got != tt.want {
+				t.Errorf("ResolveWithRationale() rationale = %q, want %q", got, tt.want)
+			}
+			if rationale.NewestRC != tt.rc {
+				t.Errorf("ResolveWithRationale() newest rc = %q, want %q", rationale.NewestRC, tt.rc)
+			}
+		})
+	}
+	if got := (Rationale{Skipped: "pinned"}).String(); got != "skipped, pinned" {
+		t.Errorf("String() = %q, want skipped, pinned", got)
+	}
+}
+
+func TestMatching(t *testing.T) {
+	tags := []sources.Tag{{Name: "op-node/v1.3.0"}, {Name: "op-node/v1.2.0"}, {Name: "op-node/v2.0.0"}, {Name: "op-geth/v1.2.5"}, {Name: "op-node/v1.4.0-rc1"}}
+	constraints, err := semver.NewConstraint(">= 1.2.1, < 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, tag := range Matching(tags, "op-node", constraints) {
+		got = append(got, tag.Name)
+	}
+	if strings.Join(got, " ") != "op-node/v1.3.0" {
+		t.Errorf("Matching() = %v, want op-node/v1.3.0", got)
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	tags := []sources.Tag{{Name: "v0.3.0"}, {Name: "v0.2.5"}, {Name: "v1.0.0"}, {Name: "nightly"}}
+	tests := []struct {
+		name string
+		info version.Info
+		want string
+	}{
+		{"0.x", version.Info{Tag: "v0.2.3"}, "v0.3.0 v0.2.5"},
+		{"0.x with preOneBreaking", version.Info{Tag: "v0.2.3", PreOneBreaking: true}, "v0.2.5"},
+		{"unparseable current", version.Info{Tag: "latest", PreOneBreaking: true}, "v0.3.0 v0.2.5 v1.0.0 nightly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tag := range Compatible(tags, &tt.info) {
+				got = append(got, tag.Name)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("Compatible() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePromotion(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		current  string
+		wantTo   string
+		wantKind string
+	}{
+		{"stable of the running rc", []string{"v1.3.0", "v1.3.0-rc2", "v1.3.0-rc1"}, "v1.3.0-rc1", "v1.3.0", version.KindPromotion},
+		{"newer rc", []string{"v1.3.0-rc2", "v1.3.0-rc1"}, "v1.3.0-rc1", "v1.3.0-rc2", ""},
+		{"stable of a later version", []string{"v1.3.1", "v1.3.0", "v1.3.0-rc1"}, "v1.3.0-rc1", "v1.3.1", ""},
+		{"from stable", []string{"v1.3.0", "v1.2.0"}, "v1.2.0", "v1.3.0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeSource{}
+			for _, name := range tt.tags {
+				source.tags = append(source.tags, sources.Tag{Name: name, Commit: "c" + name})
+			}
+			info := version.Info{Tag: tt.current, Owner: "owner", Repo: "repo", Tracking: "tag"}
+			planned, err := Resolve(context.Background(), source, "dep", &info)
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if planned == nil || planned.Info.To != tt.wantTo || planned.Info.Kind != tt.wantKind {
+				t.Errorf("Resolve() = %+v, want %s kind %q", planned, tt.wantTo, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestResolveTieBreak(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	source := &fakeSource{
+		tags: []sources.Tag{
+			{Name: "v1.2.0+build.2", Commit: "c2"},
+			{Name: "v1.2.0+build.10", Commit: "c10"},
+			{Name: "v1.2.0+build.1", Commit: "c1"},
+			{Name: "v1.1.0", Commit: "c110"},
+		},
+		published: map[string]time.Time{"v1.2.0+build.2": day, "v1.2.0+build.1": day.Add(time.Hour)},
+	}
+	tests := []struct {
+		tieBreak string
+		current  string
+		wantTo   string
+		wantWhy  string
+	}{
+		{"", "v1.1.0", "v1.2.0+build.2", "v1.2.0+build.2 and v1.2.0+build.1 are the same version, kept v1.2.0+build.2 as it was listed first"},
+		{"published", "v1.1.0", "v1.2.0+build.1", "v1.2.0+build.2 and v1.2.0+build.10 and v1.2.0+build.1 are the same version, kept v1.2.0+build.1, the latest published"},
+		{"build", "v1.1.0", "v1.2.0+build.10", "v1.2.0+build.2 and v1.2.0+build.10 and v1.2.0+build.1 are the same version, kept v1.2.0+build.10, the highest build"},
+		{"current", "v1.2.0+build.1", "", "v1.2.0+build.2 and v1.2.0+build.10 and v1.2.0+build.1 are the same version, kept v1.2.0+build.1, the current tag"},
+		{"current", "v1.1.0", "v1.2.0+build.2", "v1.2.0+build.2 and v1.2.0+build.1 are the same version, kept v1.2.0+build.2 as it was listed first"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tieBreak+" from "+tt.current, func(t *testing.T) {
+			info := version.Info{Tag: tt.current, Owner: "owner", Repo: "repo", Tracking: "release", TieBreak: tt.tieBreak}
+			planned, rationale, err := ResolveWithRationale(context.Background(), source, "dep", &info)
+			if err != nil {
+				t.Fatalf("ResolveWithRationale() unexpected error: %v", err)
+			}
+			got := ""
+			if planned != nil {
+				got = planned.Version
+			}
+			if got != tt.wantTo || rationale.TieBreak != tt.wantWhy {
+				t.Errorf("ResolveWithRationale() = %q, %q, want %q, %q", got, rationale.TieBreak, tt.wantTo, tt.wantWhy)
+			}
+			explanation, err := Explain(context.Background(), source, "dep", &info)
+			if err != nil {
+				t.Fatalf("Explain() unexpected error: %v", err)
+			}
+			if want := cmp.Or(tt.wantTo, tt.current); explanation.Selected != want {
+				t.Errorf("Explain() selected %s, want %s", explanation.Selected, want)
+			}
+		})
+	}
+
+	info := version.Info{Tag: "v1.1.0", Tracking: "release", TieBreak: "newest"}
+	if _, err := Resolve(context.Background(), source, "dep", &info); err == nil {
+		t.Error("Resolve() with an unknown tieBreak succeeded")
+	}
+	info = version.Info{Tag: "v1.1.0", Tracking: "release", VPrefix: "drop"}
+	if _, err := Resolve(context.Background(), source, "dep", &info); err == nil {
+		t.Error("Resolve() with an unknown vPrefix succeeded")
+	}
+}
+
+func TestResolvePublished(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		tags     []sources.Tag
+		info     version.Info
+		wantTo   string
+		wantWhy  string
+		released time.Time
+	}{
+		{
+			name:    "same version published later",
+			tags:    []sources.Tag{{Name: "v1.2.0", Published: day}, {Name: "1.2.0", Published: day.Add(time.Hour)}},
+			info:    version.Info{Tag: "v1.1.0", Tracking: "release"},
+			wantTo:  "v1.2.0",
+			wantWhy: "v1.2.0 and 1.2.0 are the same version, kept v1.2.0 as it was listed first",
+		},
+		{
+			name:    "same version by the published tieBreak",
+			tags:    []sources.Tag{{Name: "v1.2.0", Published: day}, {Name: "1.2.0", Published: day.Add(time.Hour)}},
+			info:    version.Info{Tag: "v1.1.0", Tracking: "release", TieBreak: "published"},
+			wantTo:  "1.2.0",
+			wantWhy: "v1.2.0 and 1.2.0 are the same version, kept 1.2.0, the latest published",
+		},
+		{
+			name:    "same version without publish times",
+			tags:    []sources.Tag{{Name: "v1.2.0"}, {Name: "1.2.0", Published: day}},
+			info:    version.Info{Tag: "v1.1.0", Tracking: "release"},
+			wantTo:  "v1.2.0",
+			wantWhy: "v1.2.0 and 1.2.0 are the same version, kept v1.2.0 as it was listed first",
+		},
+		{
+			name:    "nightlies of a day by publish time",
+			tags:    []sources.Tag{{Name: "nightly-2026-10-13.b", Published: day}, {Name: "nightly-2026-10-13.a", Published: day.Add(time.Hour)}},
+			info:    version.Info{Tag: "nightly-2026-10-12", Tracking: "nightly"},
+			wantTo:  "nightly-2026-10-13.a",
+			wantWhy: "nightly-2026-10-13.b and nightly-2026-10-13.a were built the same day, kept the later published",
+		},
+		{
+			name:    "nightlies of a day by name",
+			tags:    []sources.Tag{{Name: "nightly-2026-10-13.a"}, {Name: "nightly-2026-10-13.b"}},
+			info:    version.Info{Tag: "nightly-2026-10-12", Tracking: "nightly"},
+			wantTo:  "nightly-2026-10-13.b",
+			wantWhy: "nightly-2026-10-13.a and nightly-2026-10-13.b were built the same day, kept the later name",
+		},
+		{
+			name:    "nightly of the current day published later",
+			tags:    []sources.Tag{{Name: "nightly-2026-10-13.b", Published: day}, {Name: "nightly-2026-10-13.a", Published: day.Add(time.Hour)}},
+			info:    version.Info{Tag: "nightly-2026-10-13.b", Tracking: "nightly"},
+			wantTo:  "nightly-2026-10-13.a",
+			wantWhy: "nightly-2026-10-13.b and nightly-2026-10-13.a were built the same day, kept the later published",
+		},
+		{
+			name:    "nightlies of the current day without publish times",
+			tags:    []sources.Tag{{Name: "nightly-2026-10-13.b"}, {Name: "nightly-2026-10-13.c"}, {Name: "nightly-2026-10-13.a", Published: day.Add(time.Hour)}},
+			info:    version.Info{Tag: "nightly-2026-10-13.b", Tracking: "nightly"},
+			wantTo:  "nightly-2026-10-13.c",
+			wantWhy: "nightly-2026-10-13.b and nightly-2026-10-13.c were built the same day, kept the later name",
+		},
+		{
+			name:     "publish time from the release",
+			tags:     []sources.Tag{{Name: "v1.2.0"}},
+			info:     version.Info{Tag: "v1.1.0", Tracking: "release"},
+			wantTo:   "v1.2.0",
+			released: day,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeSource{tags: tt.tags, published: map[string]time.Time{"v1.2.0": day, "1.2.0": day.Add(time.Hour)}}
+			planned, rationale, err := ResolveWithRationale(context.Background(), source, "dep", &tt.info)
+			if err != nil {
+				t.Fatalf("ResolveWithRationale() unexpected error: %v", err)
+			}
+			if planned == nil || planned.Version != tt.wantTo || rationale.TieBreak != tt.wantWhy {
+				t.Fatalf("ResolveWithRationale() = %+v, %q, want %s, %q", planned, rationale.TieBreak, tt.wantTo, tt.wantWhy)
+			}
+			if !tt.released.IsZero() && !planned.Info.Published.Equal(tt.released) {
+				t.Errorf("ResolveWithRationale() published = %s, want %s", planned.Info.Published, tt.released)
+			}
+		})
+	}
+}
+
+func TestReleased(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		published time.Time
+		want      string
+	}{
+		{time.Time{}, ""},
+		{now.Add(-time.Hour), "released today"},
+		{now.Add(-30 * time.Hour), "released 1 day ago"},
+		{now.Add(-10 * 24 * time.Hour), "released 10 days ago"},
+	}
+	for _, tt := range tests {
+		if got := Released(tt.published, now); got != tt.want {
+			t.Errorf("Released(%s) = %q, want %q", tt.published, got, tt.want)
+		}
+	}
+}
+
+func TestResolveAcrossRename(t *testing.T) {
+	source := &fakeSource{tags: []sources.Tag{
+		{Name: "node-reth/v1.3.0", Commit: "c130"},
+		{Name: "op-reth/v1.2.0", Commit: "c120"},
+		{Name: "op-reth/v1.1.0", Commit: "c110"},
+		{Name: "other/v9.0.0", Commit: "c900"},
+	}}
+	tests := []struct {
+		name    string
+		current string
+		wantTo  string
+	}{
+		{"from the old prefix", "op-reth/v1.1.0", "node-reth/v1.3.0"},
+		{"no downgrade to the old prefix", "node-reth/v1.4.0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := version.Info{Tag: tt.current, TagPrefix: "node-reth", Tracking: "release", Strict: true, Aliases: []version.Alias{{TagPrefix: "op-reth"}}}
+			planned, rationale, err := ResolveWithRationale(context.Background(), source, "dep", &info)
+			if err != nil {
+				t.Fatalf("ResolveWithRationale() unexpected error: %v", err)
+			}
+			got := ""
+			if planned != nil {
+				got = planned.Version
+			}
+			if got != tt.wantTo || rationale.Filtered["tag prefix"] != 1 {
+				t.Errorf("ResolveWithRationale() = %q, filtered %v, want %q", got, rationale.Filtered, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestResolveRetracted(t *testing.T) {
+	source := &fakeSource{tags: []sources.Tag{
+		{Name: "v1.3.0", Commit: "c130"},
+		{Name: "v1.2.0", Commit: "c120"},
+		{Name: "v1.1.0", Commit: "c110"},
+		{Name: "v1.0.0", Commit: "c100"},
+	}}
+	tests := []struct {
+		name      string
+		current   string
+		retracted []string
+		wantTo    string
+		wantKind  string
+	}{
+		{"skips a retracted candidate", "v1.1.0", []string{"v1.3.0"}, "v1.2.0", ""},
+		{"upgrades off a retracted current", "v1.1.0", []string{"1.1.0"}, "v1.3.0", version.KindRetraction},
+		{"downgrades to the nearest earlier", "v1.3.0", []string{"v1.3.0"}, "v1.2.0", version.KindRetraction},
+		{"skips retracted earlier versions", "v1.3.0", []string{"v1.3.0", "v1.2.0"}, "v1.1.0", version.KindRetraction},
+		{"keeps without an alternative", "v1.0.0", []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := version.Info{Tag: tt.current, Tracking: "release", Retracted: tt.retracted}
+			planned, rationale, err := ResolveWithRationale(context.Background(), source, "dep", &info)
+			if err != nil {
+				t.Fatalf("ResolveWithRationale() unexpected error: %v", err)
+			}
+			got, kind := "", ""
+			if planned != nil {
+				got, kind = planned.Version, planned.Info.Kind
+			}
+			if got != tt.wantTo || kind != tt.wantKind {
+				t.Errorf("ResolveWithRationale() = %q kind %q, want %q kind %q (%s)", got, kind, tt.wantTo, tt.wantKind, rationale.Outcome)
+			}
+		})
+	}
+}
+
+func TestResolveNightly(t *testing.T) {
+	source := &fakeSource{tags: []sources.Tag{
+		{Name: "v1.0.0", Commit: "c100"},
+		{Name: "nightly-2026-10-13.1", Commit: "c13b"},
+		{Name: "nightly-2026-10-13", Commit: "c13"},
+		{Name: "nightly-20261012", Commit: "c12"},
+	}}
+	tests := []struct {
+		name    string
+		current string
+		wantTo  string
+	}{
+		{"newest build", "nightly-20261012", "nightly-2026-10-13.1"},
+		{"second build of the day", "nightly-2026-10-13", "nightly-2026-10-13.1"},
+		{"up to date", "nightly-2026-10-13.1", ""},
+		{"no downgrade", "nightly-2026-10-14", ""},
+		{"from a release", "v1.0.0", "nightly-2026-10-13.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := version.Info{Tag: tt.current, Owner: "owner", Repo: "repo", Tracking: "nightly"}
+			planned, err := Resolve(context.Background(), source, "dep", &info)
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			got := ""
+			if planned != nil {
+				got = planned.Version
+			}
+			if got != tt.wantTo {
+				t.Errorf("Resolve() = %q, want %q", got, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestSelectTagStream(t *testing.T) {
+	pages := [][]sources.Tag{
+		{{Name: "v1.4.0"}, {Name: "v1.3.0"}},
+		{{Name: "v1.2.0"}, {Name: "v1.1.0"}},
+		{{Name: "v1.0.0"}},
+		{{Name: "v0.9.0"}},
+		{{Name: "v0.8.0"}},
+	}
+	tests := []struct {
+		name      string
+		current   string
+		want      string
+		wantPages int
+	}{
+		{"stops after older pages", "v1.2.0", "v1.4.0", 3},
+		{"reads everything without a current tag", "", "v1.4.0", 5},
+		{"up to date", "v1.4.0", "v1.4.0", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read := 0
+			stream := func(yield func([]sources.Tag, error) bool) {
+				for _, page := range pages {
+					read++
+					if !yield(page, nil) {
+						return
+					}
+				}
+			}
+			selector, err := selectTagStream(stream, &version.Info{Tag: tt.current, Tracking: "release"})
+			if err != nil || selector.selected == nil || selector.selected.Name != tt.want {
+				t.Fatalf("selectTagStream() = %v, %v, want %s", selector, err, tt.want)
+			}
+			if read != tt.wantPages {
+				t.Errorf("selectTagStream() read %d pages, want %d", read, tt.wantPages)
+			}
+		})
+	}
+}
+
+// benchmarkTags is a go-ethereum sized tag list from a monorepo with several tag prefixes,
+// releases, RCs and other prereleases.
+func benchmarkTags() []sources.Tag {
+	var tags []sources.Tag
+	for _, prefix := range []string{"v", "op-node/v", "op-batcher/v"} {
+		for minor := 0; minor < 40; minor++ {
+			for patch := 0; patch < 20; patch++ {
+				name := fmt.Sprintf("%s1.%d.%d", prefix, minor, patch)
+				tags = append(tags, sources.Tag{Name: name}, sources.Tag{Name: name + "-rc1"}, sources.Tag{Name: name + "-synctest.0"})
+			}
+		}
+	}
+	return tags
+}
+
+func BenchmarkSelectTag(b *testing.B) {
+	tags := benchmarkTags()
+	for _, tracking := range []string{"release", "tag"} {
+		info := &version.Info{Tag: "op-node/v1.20.0", TagPrefix: "op-node", Tracking: tracking}
+		b.Run(tracking, func(b *testing.B) {
+			for b.Loop() {
+				if selected := SelectTag(tags, info); selected == nil || selected.Name != "op-node/v1.39.19" {
+					b.Fatalf("SelectTag() = %v", selected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+// Package provenance checks the SLSA provenance upstreams attach to their releases before a
+// candidate is proposed. It checks what the provenance attests to, the builder and the source
+// repository, ref and commit. The Sigstore signature of the envelope needs the transparency
+// log and is verified by slsa-verifier, without it candidates are only reported unverified.
+package provenance
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// DefaultAsset matches the provenance slsa-github-generator attaches to releases.
+const DefaultAsset = "*.intoto.jsonl"
+
+const (
+	slsaV02 = "https://slsa.dev/provenance/v0.2"
+	slsaV1  = "https://slsa.dev/provenance/v1"
+)
+
+// envelope is a DSSE envelope, on its own or inside a Sigstore bundle.
+type envelope struct {
+	PayloadType  string    `json:"payloadType"`
+	Payload      string    `json:"payload"`
+	DSSEEnvelope *envelope `json:"dsseEnvelope"`
+}
+
+type statement struct {
+	Subject []struct {
+		Name string `json:"name"`
+	} `json:"subject"`
+	PredicateType string `json:"predicateType"`
+	Predicate     struct {
+		// SLSA v0.2
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Invocation struct {
+			ConfigSource struct {
+				URI    string            `json:"uri"`
+				Digest map[string]string `json:"digest"`
+			} `json:"configSource"`
+		} `json:"invocation"`
+		// SLSA v1
+		BuildDefinition struct {
+			ResolvedDependencies []struct {
+				URI    string            `json:"uri"`
+				Digest map[string]string `json:"digest"`
+			} `json:"resolvedDependencies"`
+		} `json:"buildDefinition"`
+		RunDetails struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+		} `json:"runDetails"`
+	} `json:"predicate"`
+}
+
+// Attestation is what a provenance statement says about a build.
+type Attestation struct {
+	Builder string
+	// Repository is the source repository's URL, without the git+ scheme prefix.
+	Repository string
+	Ref        string
+	Commit     string
+	// Subjects are the names of the artifacts the provenance is about.
+	Subjects []string
+}
+
+// Parse reads the SLSA provenance statements of an .intoto.jsonl file, or a Sigstore bundle,
+// skipping statements of other predicate types.
+func Parse(content []byte) ([]Attestation, error) {
+	var attestations []Attestation
+	// Both one envelope per line and a single indented bundle are a stream of JSON values.
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		var env envelope
+		if err := decoder.Decode(&env); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error decoding provenance envelope: %s", err)
+		}
+		if env.DSSEEnvelope != nil {
+			env = *env.DSSEEnvelope
+		}
+		if env.PayloadType != "application/vnd.in-toto+json" {
+			continue
+		}
+		payload, err := base64.StdEncoding.DecodeString(env.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding provenance payload: %s", err)
+		}
+		var s statement
+		if err := json.Unmarshal(payload, &s); err != nil {
+			return nil, fmt.Errorf("error decoding provenance statement: %s", err)
+		}
+		var subjects []string
+		for _, subject := range s.Subject {
+			subjects = append(subjects, subject.Name)
+		}
+		switch s.PredicateType {
+		case slsaV02:
+			a := Attestation{Builder: s.Predicate.Builder.ID, Commit: s.Predicate.Invocation.ConfigSource.Digest["sha1"], Subjects: subjects}
+			a.Repository, a.Ref = splitSourceURI(s.Predicate.Invocation.ConfigSource.URI)
+			attestations = append(attestations, a)
+		case slsaV1:
+			a := Attestation{Builder: s.Predicate.RunDetails.Builder.ID, Subjects: subjects}
+			for _, dependency := range s.Predicate.BuildDefinition.ResolvedDependencies {
+				if strings.HasPrefix(dependency.URI, "git+") {
+					a.Repository, a.Ref = splitSourceURI(dependency.URI)
+					a.Commit = dependency.Digest["gitCommit"]
+					break
+				}
+			}
+			attestations = append(attestations, a)
+		}
+	}
+	return attestations, nil
+}
+
+// splitSourceURI splits a git+https://github.com/owner/repo@refs/tags/v1 source URI.
+func splitSourceURI(uri string) (string, string) {
+	uri = strings.TrimPrefix(uri, "git+")
+	repository, ref, _ := strings.Cut(uri, "@")
+	return strings.TrimSuffix(repository, ".git"), ref
+}
+
+// Verify checks that one of the attestations was made by the policy's builder from the
+// dependency's repository at tag, and at commit if the provenance records it.
+func Verify(attestations []Attestation, policy *version.Provenance, repoURL string, tag string, commit string) (Attestation, error) {
+	if len(attestations) == 0 {
+		return Attestation{}, fmt.Errorf("no SLSA provenance statement")
+	}
+	var problems []string
+	for _, a := range attestations {
+		switch {
+		case !builderMatches(a.Builder, policy.Builder):
+			problems = append(problems, fmt.Sprintf("built by %q", a.Builder))
+		case !strings.EqualFold(a.Repository, repoURL):
+			problems = append(problems, fmt.Sprintf("built from %s", a.Repository))
+		case a.Ref != "refs/tags/"+tag:
+			problems = append(problems, fmt.Sprintf("built from ref %s", a.Ref))
+		case a.Commit != "" && commit != "" && a.Commit != commit:
+			problems = append(problems, fmt.Sprintf("built from commit %s, the tag points to %s", a.Commit, commit))
+		default:
+			return a, nil
+		}
+	}
+	return Attestation{}, fmt.Errorf("provenance does not match: %s", strings.Join(problems, "; "))
+}
+
+// builderMatches reports whether builder is want, or starts with it and continues at a path
+// or ref boundary, so a policy of the generator's repository doesn't also match a repository
+// whose name merely starts like it.
+func builderMatches(builder string, want string) bool {
+	if want == "" || !strings.HasPrefix(builder, want) {
+		return false
+	}
+	rest := builder[len(want):]
+	return rest == "" || strings.HasSuffix(want, "/") || strings.HasSuffix(want, "@") || rest[0] == '/' || rest[0] == '@'
+}
+
+// Check verifies the provenance of candidates of dependencies with a provenance policy. A
+// candidate without matching provenance is held. Matching provenance passes if Verifier
+// verified its signature, and is unverified without a Verifier.
+type Check struct {
+	HTTP *http.Client
+	// Verifier is the slsa-verifier command verifying the signature of the provenance of a
+	// release artifact it attests to.
+	Verifier string
+	// Run executes the verifier and returns its output, exec if nil.
+	Run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+func (Check) Name() string { return "provenance" }
+
+func (c Check) Check(ctx context.Context, source sources.VersionSource, info *version.Info, planned version.PlannedUpdate) *runner.CheckResult {
+	// Branch tracking has no release to verify.
+	if info.Provenance == nil || planned.Version == "" {
+		return nil
+	}
+	release, content, err := c.provenance(ctx, source, info, planned)
+	if err != nil {
+		return &runner.CheckResult{Hold: true, Detail: err.Error()}
+	}
+	attestations, err := Parse(content)
+	if err != nil {
+		return &runner.CheckResult{Hold: true, Detail: err.Error()}
+	}
+	repoURL := source.RepoURL(info.Owner, info.Repo)
+	attestation, err := Verify(attestations, info.Provenance, repoURL, planned.Version, planned.Commit)
+	if err != nil {
+		return &runner.CheckResult{Hold: true, Detail: err.Error()}
+	}
+	detail := fmt.Sprintf("built by %s from %s@%s", attestation.Builder, strings.TrimPrefix(attestation.Repository, "https://"), attestation.Ref)
+	if c.Verifier == "" {
+		return &runner.CheckResult{Unverified: true, Detail: detail + ", signature not verified, run with --slsa-verifier to verify it"}
+	}
+	if err := c.verifySignature(ctx, release, content, attestation, repoURL, planned.Version); err != nil {
+		return &runner.CheckResult{Hold: true, Detail: err.Error()}
+	}
+	return &runner.CheckResult{Passed: true, Detail: detail + ", signature verified"}
+}
+
+// provenance returns the release of the candidate and the content of its provenance asset.
+func (c Check) provenance(ctx context.Context, source sources.VersionSource, info *version.Info, planned version.PlannedUpdate) (*sources.Release, []byte, error) {
+	release, err := source.GetRelease(ctx, info.Owner, info.Repo, planned.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	pattern := info.Provenance.Asset
+	if pattern == "" {
+		pattern = DefaultAsset
+	}
+	var content []byte
+	for _, asset := range release.Assets {
+		if matched, _ := path.Match(pattern, asset.Name); !matched {
+			continue
+		}
+		if content, err = c.fetch(ctx, asset.URL); err != nil {
+			return nil, nil, fmt.Errorf("error fetching %s: %s", asset.Name, err)
+		}
+		break
+	}
+	if content == nil {
+		return nil, nil, fmt.Errorf("release %s has no provenance asset matching %s", planned.Version, pattern)
+	}
+	return release, content, nil
+}
+
+// verifySignature downloads the first release asset the attestation is about and has the
+// verifier check the provenance's signature, builder and source for it.
+func (c Check) verifySignature(ctx context.Context, release *sources.Release, content []byte, attestation Attestation, repoURL string, tag string) error {
+	var artifact *sources.Asset
+	for i, asset := range release.Assets {
+		if slices.Contains(attestation.Subjects, asset.Name) {
+			artifact = &release.Assets[i]
+			break
+		}
+	}
+	if artifact == nil {
+		return fmt.Errorf("release %s has no asset the provenance is about", tag)
+	}
+	dir, err := os.MkdirTemp("", "provenance")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	provenancePath, artifactPath := filepath.Join(dir, "provenance.intoto.jsonl"), filepath.Join(dir, filepath.Base(artifact.Name))
+	if err := os.WriteFile(provenancePath, content, 0644); err != nil {
+		return err
+	}
+	if err := c.download(ctx, artifact.URL, artifactPath); err != nil {
+		return fmt.Errorf("error fetching %s: %s", artifact.Name, err)
+	}
+	builder, _, _ := strings.Cut(attestation.Builder, "@")
+	args := []string{"verify-artifact", artifactPath, "--provenance-path", provenancePath, "--source-uri", strings.TrimPrefix(repoURL, "https://"), "--source-tag", tag, "--builder-id", builder}
+	run := c.Run
+	if run == nil {
+		run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return exec.CommandContext(ctx, name, args...).CombinedOutput()
+		}
+	}
+	if out, err := run(ctx, c.Verifier, args...); err != nil {
+		return fmt.Errorf("provenance signature of %s not verified: %s %s", artifact.Name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// download writes the content at url to path.
+func (c Check) download(ctx context.Context, url string, path string) error {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, io.LimitReader(resp.Body, 4<<30)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (c Check) fetch(ctx context.Context, url string) ([]byte, error) {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+}
+
+func (c Check) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,130 @@
+package provenance
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+const generator = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v2.0.0"
+
+func envelopeFor(t *testing.T, predicateType string, predicate string) string {
+	t.Helper()
+	payload := `{"_type":"https://in-toto.io/Statement/v0.1","subject":[{"name":"reth"}],"predicateType":"` + predicateType + `","predicate":` + predicate + `}`
+	encoded, err := json.Marshal(map[string]string{"payloadType": "application/vnd.in-toto+json", "payload": base64.StdEncoding.EncodeToString([]byte(payload))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(encoded)
+}
+
+func TestParseAndVerify(t *testing.T) {
+	v02 := envelopeFor(t, slsaV02, `{"builder":{"id":"`+generator+`"},"invocation":{"configSource":{"uri":"git+https://github.com/paradigmxyz/reth@refs/tags/v1.1.0","digest":{"sha1":"c110"}}}}`)
+	v1 := envelopeFor(t, slsaV1, `{"buildDefinition":{"resolvedDependencies":[{"uri":"git+https://github.com/paradigmxyz/reth@refs/tags/v1.1.0","digest":{"gitCommit":"c110"}}]},"runDetails":{"builder":{"id":"`+generator+`"}}}`)
+	other := envelopeFor(t, "https://spdx.dev/Document", `{}`)
+	policy := &version.Provenance{Builder: "https://github.com/slsa-framework/slsa-github-generator/"}
+
+	tests := []struct {
+		name    string
+		content string
+		policy  *version.Provenance
+		tag     string
+		commit  string
+		wantErr bool
+	}{
+		{"slsa v0.2", v02, policy, "v1.1.0", "c110", false},
+		{"slsa v1 in a bundle", `{"dsseEnvelope": ` + v1 + `}`, policy, "v1.1.0", "c110", false},
+		{"other statements skipped", other + "\n" + v02 + "\n", policy, "v1.1.0", "c110", false},
+		{"exact builder", v02, &version.Provenance{Builder: generator}, "v1.1.0", "c110", false},
+		{"builder at a ref boundary", v02, &version.Provenance{Builder: strings.TrimSuffix(generator, "@refs/tags/v2.0.0")}, "v1.1.0", "c110", false},
+		{"builder at a path boundary", v02, &version.Provenance{Builder: "https://github.com/slsa-framework/slsa-github-generator"}, "v1.1.0", "c110", false},
+		{"builder within a name", v02, &version.Provenance{Builder: "https://github.com/slsa-framework/slsa-github-gen"}, "v1.1.0", "c110", true},
+		{"wrong builder", v02, &version.Provenance{Builder: "https://github.com/other/"}, "v1.1.0", "c110", true},
+		{"wrong tag", v02, policy, "v1.2.0", "c110", true},
+		{"wrong commit", v1, policy, "v1.1.0", "c999", true},
+		{"no slsa statement", other, policy, "v1.1.0", "c110", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestations, err := Parse([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			_, err = Verify(attestations, tt.policy, "https://github.com/paradigmxyz/reth", tt.tag, tt.commit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// releaseSource serves a release with one provenance asset.
+type releaseSource struct {
+	sources.VersionSource
+	assetURL string
+}
+
+func (s releaseSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*sources.Release, error) {
+	return &sources.Release{Tag: tag, Assets: []sources.Asset{{Name: "reth", URL: s.assetURL + "/reth"}, {Name: "reth.intoto.jsonl", URL: s.assetURL + "/provenance"}}}, nil
+}
+
+func (releaseSource) RepoURL(owner string, repo string) string {
+	return "https://github.com/" + owner + "/" + repo
+}
+
+func TestCheck(t *testing.T) {
+	provenance := envelopeFor(t, slsaV02, `{"builder":{"id":"`+generator+`"},"invocation":{"configSource":{"uri":"git+https://github.com/paradigmxyz/reth@refs/tags/v1.1.0"}}}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/provenance":
+			w.Write([]byte(provenance))
+		case "/reth":
+			w.Write([]byte("binary"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	source := releaseSource{assetURL: server.URL}
+	info := &version.Info{Owner: "paradigmxyz", Repo: "reth", Tracking: "release", Provenance: &version.Provenance{Builder: "https://github.com/slsa-framework/"}}
+
+	planned := version.PlannedUpdate{Version: "v1.1.0", Commit: "c110"}
+	result := Check{HTTP: server.Client()}.Check(context.Background(), source, info, planned)
+	if result == nil || result.Status() != "unverified" || !strings.Contains(result.Detail, "github.com/paradigmxyz/reth@refs/tags/v1.1.0") {
+		t.Errorf("Check() without a verifier = %+v, want unverified", result)
+	}
+
+	var args []string
+	verifier := Check{HTTP: server.Client(), Verifier: "slsa-verifier", Run: func(ctx context.Context, name string, arg ...string) ([]byte, error) {
+		args = arg
+		return nil, nil
+	}}
+	if result := verifier.Check(context.Background(), source, info, planned); result == nil || result.Status() != "passed" {
+		t.Errorf("Check() with a verifier = %+v, want passed", result)
+	}
+	want := "--source-uri github.com/paradigmxyz/reth --source-tag v1.1.0 --builder-id " + strings.TrimSuffix(generator, "@refs/tags/v2.0.0")
+	if len(args) < 2 || args[0] != "verify-artifact" || !strings.HasSuffix(args[1], "/reth") || !strings.Contains(strings.Join(args, " "), want) {
+		t.Errorf("ran slsa-verifier %v, want the artifact verified with %s", args, want)
+	}
+	verifier.Run = func(ctx context.Context, name string, arg ...string) ([]byte, error) {
+		return []byte("FAILED: signature mismatch"), errors.New("exit status 1")
+	}
+	if result := verifier.Check(context.Background(), source, info, planned); result == nil || !result.Hold || !strings.Contains(result.Detail, "signature mismatch") {
+		t.Errorf("Check() with a failed verification = %+v, want held", result)
+	}
+	info.Provenance.Asset = "*.sigstore"
+	if result := (Check{HTTP: server.Client()}).Check(context.Background(), source, info, version.PlannedUpdate{Version: "v1.1.0"}); result == nil || !result.Hold {
+		t.Errorf("Check() without a matching asset = %+v, want held", result)
+	}
+	if result := (Check{}).Check(context.Background(), source, &version.Info{}, version.PlannedUpdate{Version: "v1.1.0"}); result != nil {
+		t.Errorf("Check() without a policy = %+v, want nil", result)
+	}
+}
@@ -0,0 +1,226 @@
+// Package rebuild rebuilds the repository's own images after the client versions they are
+// built from changed, with docker buildx or by dispatching a GitHub Actions workflow, and
+// records the digests of the rebuilt images in images.lock.json.
+package rebuild
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/buildmatrix"
+	"github.com/google/go-github/v72/github"
+)
+
+// LockfileName is the lockfile's name at the repository root.
+const LockfileName = "images.lock.json"
+
+// Image is the last build of an image.
+type Image struct {
+	Image  string `json:"image"`
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+	// Version is the buildmatrix entry's version, the dependency versions the image was
+	// rebuilt for.
+	Version string    `json:"version"`
+	BuiltAt time.Time `json:"builtAt"`
+}
+
+// Lockfile maps the directories of the repository's Dockerfiles to their last build.
+type Lockfile map[string]Image
+
+// ReadLockfile reads the lockfile of the repository at repoPath, empty if it has none.
+func ReadLockfile(repoPath string) (Lockfile, error) {
+	content, err := os.ReadFile(filepath.Join(repoPath, LockfileName))
+	if os.IsNotExist(err) {
+		return Lockfile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", LockfileName, err)
+	}
+	lock := Lockfile{}
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %s", LockfileName, err)
+	}
+	return lock, nil
+}
+
+// Write writes the lockfile to the repository at repoPath.
+func (l Lockfile) Write(repoPath string) error {
+	encoded, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, LockfileName), append(encoded, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %s", LockfileName, err)
+	}
+	return nil
+}
+
+// Tag is the tag an entry is built as, derived from its dependency versions so rebuilding an
+// unchanged set reuses the tag.
+func Tag(entry buildmatrix.Entry) string {
+	sum := sha256.Sum256([]byte(entry.Version))
+	return "deps-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// Builder builds and pushes the image of an entry as tag and returns its digest.
+type Builder interface {
+	Build(ctx context.Context, entry buildmatrix.Entry, tag string) (string, error)
+}
+
+// Rebuild builds every entry of the matrix and records the digests in lock. It stops at the
+// first failure, the lockfile keeping the builds that succeeded.
+func Rebuild(ctx context.Context, builder Builder, matrix buildmatrix.Matrix, lock Lockfile, now func() time.Time) error {
+	for _, entry := range matrix.Include {
+		tag := Tag(entry)
+		digest, err := builder.Build(ctx, entry, tag)
+		if err != nil {
+			return fmt.Errorf("error rebuilding %s: %s", entry.Image, err)
+		}
+		lock[entry.Name] = Image{Image: entry.Image, Tag: tag, Digest: digest, Version: entry.Version, BuiltAt: now().UTC()}
+	}
+	return nil
+}
+
+// Buildx builds with docker buildx from the repository at RepoPath.
+type Buildx struct {
+	RepoPath string
+	// Command is "docker" if empty.
+	Command string
+	// Platforms are passed to --platform, the builder's platform if empty.
+	Platforms []string
+	// Run executes the command and returns its combined output, exec if nil.
+	Run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %s %s", name, err, lastLines(string(out), 5))
+	}
+	return out, nil
+}
+
+// lastLines keeps the end of a build log, where the failing step is.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (b Buildx) Build(ctx context.Context, entry buildmatrix.Entry, tag string) (string, error) {
+	command := b.Command
+	if command == "" {
+		command = "docker"
+	}
+	execute := b.Run
+	if execute == nil {
+		execute = run
+	}
+	metadata, err := os.CreateTemp("", "buildx-metadata-*.json")
+	if err != nil {
+		return "", err
+	}
+	metadata.Close()
+	defer os.Remove(metadata.Name())
+
+	args := []string{"buildx", "build", "--push", "--file", filepath.Join(b.RepoPath, entry.Dockerfile), "--tag", entry.Image + ":" + tag, "--metadata-file", metadata.Name()}
+	if len(b.Platforms) > 0 {
+		args = append(args, "--platform", strings.Join(b.Platforms, ","))
+	}
+	if _, err := execute(ctx, command, append(args, b.RepoPath)...); err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(metadata.Name())
+	if err != nil {
+		return "", fmt.Errorf("error reading the build metadata: %s", err)
+	}
+	var result struct {
+		Digest string `json:"containerimage.digest"`
+	}
+	if err := json.Unmarshal(content, &result); err != nil || result.Digest == "" {
+		return "", fmt.Errorf("no image digest in the build metadata")
+	}
+	return result.Digest, nil
+}
+
+// Workflow dispatches a GitHub Actions workflow for each entry, with the image, dockerfile and
+// tag inputs, waits for its run and resolves the digest it pushed.
+type Workflow struct {
+	Client   *github.Client
+	Owner    string
+	Repo     string
+	Workflow string
+	// Ref is the branch the workflow runs on, which has to have the updated versions.
+	Ref string
+	// Poll is the time between checks of the run, 10s if zero.
+	Poll time.Duration
+	// Resolve returns the digest of an image reference once the run pushed it.
+	Resolve func(ctx context.Context, image string) (string, error)
+}
+
+func (w Workflow) Build(ctx context.Context, entry buildmatrix.Entry, tag string) (string, error) {
+	poll := w.Poll
+	if poll == 0 {
+		poll = 10 * time.Second
+	}
+	dispatched := time.Now()
+	_, err := w.Client.Actions.CreateWorkflowDispatchEventByFileName(ctx, w.Owner, w.Repo, w.Workflow, github.CreateWorkflowDispatchEventRequest{
+		Ref:    w.Ref,
+		Inputs: map[string]any{"image": entry.Image, "dockerfile": entry.Dockerfile, "tag": tag},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error dispatching %s: %s", w.Workflow, err)
+	}
+
+	for {
+		run, err := w.findRun(ctx, dispatched)
+		if err != nil {
+			return "", err
+		}
+		if run != nil && run.GetStatus() == "completed" {
+			if run.GetConclusion() != "success" {
+				return "", fmt.Errorf("workflow run %s concluded %s", run.GetHTMLURL(), run.GetConclusion())
+			}
+			return w.Resolve(ctx, entry.Image+":"+tag)
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("waiting for %s: %s", w.Workflow, ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
+
+// findRun returns the dispatched run of the workflow created after the dispatch, nil until
+// GitHub lists it. The dispatch API doesn't return the run, so clock skew is allowed for.
+func (w Workflow) findRun(ctx context.Context, dispatched time.Time) (*github.WorkflowRun, error) {
+	runs, _, err := w.Client.Actions.ListWorkflowRunsByFileName(ctx, w.Owner, w.Repo, w.Workflow, &github.ListWorkflowRunsOptions{
+		Event:       "workflow_dispatch",
+		Branch:      w.Ref,
+		ListOptions: github.ListOptions{PerPage: 10},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing runs of %s: %s", w.Workflow, err)
+	}
+	var found *github.WorkflowRun
+	for _, run := range runs.WorkflowRuns {
+		if run.GetCreatedAt().Before(dispatched.Add(-time.Minute)) {
+			continue
+		}
+		if found == nil || run.GetCreatedAt().Before(found.GetCreatedAt().Time) {
+			found = run
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,114 @@
+package rebuild
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/buildmatrix"
+	"github.com/google/go-github/v72/github"
+)
+
+var entry = buildmatrix.Entry{Name: "reth", Version: "reth@v1.1.0", Image: "ghcr.io/base/node-reth", Dockerfile: "reth/Dockerfile"}
+
+func TestBuildx(t *testing.T) {
+	builder := Buildx{RepoPath: "/repo", Run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		i := slices.Index(args, "--metadata-file")
+		if name != "docker" || i < 0 || !slices.Contains(args, "/repo/reth/Dockerfile") || !slices.Contains(args, "ghcr.io/base/node-reth:deps-1") {
+			t.Errorf("ran %s %v", name, args)
+			return nil, nil
+		}
+		return nil, os.WriteFile(args[i+1], []byte(`{"containerimage.digest": "sha256:abc"}`), 0644)
+	}}
+	digest, err := builder.Build(context.Background(), entry, "deps-1")
+	if err != nil || digest != "sha256:abc" {
+		t.Errorf("Build() = %s, %v, want sha256:abc", digest, err)
+	}
+}
+
+func TestWorkflow(t *testing.T) {
+	dispatched := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/base/node/actions/workflows/docker.yml/dispatches", func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Ref    string            `json:"ref"`
+			Inputs map[string]string `json:"inputs"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		if body.Ref != "updates" || body.Inputs["tag"] != "deps-1" || body.Inputs["dockerfile"] != "reth/Dockerfile" {
+			t.Errorf("dispatched %+v", body)
+		}
+		dispatched = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	polls := 0
+	mux.HandleFunc("GET /repos/base/node/actions/workflows/docker.yml/runs", func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("event") != "workflow_dispatch" || req.URL.Query().Get("branch") != "updates" {
+			t.Errorf("listed runs with %s", req.URL.RawQuery)
+		}
+		polls++
+		runs := []map[string]any{
+			{"id": 1, "status": "completed", "conclusion": "failure", "created_at": time.Now().Add(-time.Hour)},
+		}
+		if polls > 1 {
+			status := map[bool]string{true: "completed", false: "in_progress"}[polls > 2]
+			runs = append(runs, map[string]any{"id": 2, "status": status, "conclusion": "success", "created_at": time.Now()})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"total_count": len(runs), "workflow_runs": runs})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	builder := Workflow{Client: client, Owner: "base", Repo: "node", Workflow: "docker.yml", Ref: "updates", Poll: time.Millisecond,
+		Resolve: func(ctx context.Context, image string) (string, error) {
+			if image != "ghcr.io/base/node-reth:deps-1" {
+				t.Errorf("resolved %s", image)
+			}
+			return "sha256:def", nil
+		},
+	}
+	digest, err := builder.Build(context.Background(), entry, "deps-1")
+	if err != nil || digest != "sha256:def" || !dispatched || polls != 3 {
+		t.Errorf("Build() = %s, %v after %d polls, want sha256:def after 3", digest, err, polls)
+	}
+}
+
+type fakeBuilder map[string]string
+
+func (b fakeBuilder) Build(ctx context.Context, entry buildmatrix.Entry, tag string) (string, error) {
+	return b[entry.Name], nil
+}
+
+func TestRebuild(t *testing.T) {
+	repo := t.TempDir()
+	lock, err := ReadLockfile(repo)
+	if err != nil || len(lock) != 0 {
+		t.Fatalf("ReadLockfile() without a lockfile = %v, %v", lock, err)
+	}
+	lock["geth"] = Image{Image: "ghcr.io/base/node-geth", Digest: "sha256:old"}
+	now := func() time.Time { return time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC) }
+	matrix := buildmatrix.Matrix{Include: []buildmatrix.Entry{entry}}
+	if err := Rebuild(context.Background(), fakeBuilder{"reth": "sha256:abc"}, matrix, lock, now); err != nil {
+		t.Fatalf("Rebuild() unexpected error: %v", err)
+	}
+	if err := lock.Write(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadLockfile(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Image{Image: "ghcr.io/base/node-reth", Tag: Tag(entry), Digest: "sha256:abc", Version: "reth@v1.1.0", BuiltAt: now()}
+	if got["reth"] != want || got["geth"].Digest != "sha256:old" {
+		t.Errorf("lockfile = %+v, want reth %+v and geth kept", got, want)
+	}
+}
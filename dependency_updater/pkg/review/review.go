@@ -0,0 +1,184 @@
+// Package review lets an operator go through the updates of a run in the terminal before they
+// are applied: each with its versions, release notes, check results and a risk score, and
+// select or deselect them.
+package review
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// ErrAborted is returned when the operator quits without applying.
+var ErrAborted = errors.New("review aborted, nothing applied")
+
+// Item is one update under review.
+type Item struct {
+	Planned   version.PlannedUpdate
+	Tracking  string
+	TagPrefix string
+	// PreOneBreaking scores minor bumps of 0.x versions as major, see version.Bump.
+	PreOneBreaking bool
+	Checks         []runner.CheckResult
+	// Notes are the candidate's release notes, empty if it has no release.
+	Notes    string
+	Selected bool
+}
+
+// Risk scores an update from 0 to 10: how big a version step it is, and what its checks found.
+func (i Item) Risk() int {
+	score := 0
+	from, fromErr := version.ParseVersion(i.Planned.Info.From, i.TagPrefix)
+	to, toErr := version.ParseVersion(i.Planned.Info.To, i.TagPrefix)
+	switch {
+	case i.Tracking == "branch" || i.Tracking == "nightly" || fromErr != nil || toErr != nil:
+		// Unreleased builds carry no semver promise.
+		score += 3
+	default:
+		score += map[string]int{"major": 4, "minor": 2, "patch": 1}[version.Bump(from, to, i.PreOneBreaking)]
+	}
+	if toErr == nil && to.Prerelease() != "" {
+		score++
+	}
+	for _, check := range i.Checks {
+		switch check.Status() {
+		case "needs approval":
+			score += 4
+		case "failed":
+			score += 2
+		}
+	}
+	return min(score, 10)
+}
+
+// Level names a risk score, "high" from 6.
+func Level(risk int) string {
+	switch {
+	case risk >= 6:
+		return "high"
+	case risk >= 3:
+		return "medium"
+	}
+	return "low"
+}
+
+// Items builds the items of a run's planned updates, fetching the release notes of each
+// candidate. Every item starts out selected.
+func Items(ctx context.Context, set *sources.Set, dependencies version.Dependencies, planned []version.PlannedUpdate, checks []runner.CheckResult) ([]Item, error) {
+	var items []Item
+	for _, p := range planned {
+		info := dependencies[p.Dependency]
+		item := Item{Planned: p, Tracking: info.Tracking, TagPrefix: info.TagPrefix, PreOneBreaking: info.PreOneBreaking, Selected: true}
+		for _, check := range checks {
+			if check.Dependency == p.Dependency {
+				item.Checks = append(item.Checks, check)
+			}
+		}
+		if p.Version != "" {
+			source, err := set.For(info.Source)
+			if err != nil {
+				return nil, err
+			}
+			// A tag without a release has no notes to preview.
+			if release, err := source.GetRelease(ctx, info.Owner, info.Repo, p.Version); err == nil {
+				item.Notes = release.Body
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// previewLines is how many lines of release notes the list shows per item.
+const previewLines = 3
+
+// Prompt lists the items on out and reads commands from in until the operator applies the
+// selected ones or quits:
+//
+//	<n>    selects or deselects item n
+//	n <n>  shows the full release notes of item n
+//	a      applies the selected items
+//	q      quits without applying anything
+func Prompt(in io.Reader, out io.Writer, items []Item) ([]version.PlannedUpdate, error) {
+	scanner := bufio.NewScanner(in)
+	render(out, items)
+	for {
+		fmt.Fprint(out, "[number] toggle, n [number] notes, a apply, q quit: ")
+		if !scanner.Scan() {
+			return nil, ErrAborted
+		}
+		command := strings.Fields(scanner.Text())
+		switch {
+		case len(command) == 0:
+			continue
+		case command[0] == "a":
+			var selected []version.PlannedUpdate
+			for _, item := range items {
+				if item.Selected {
+					selected = append(selected, item.Planned)
+				}
+			}
+			return selected, nil
+		case command[0] == "q":
+			return nil, ErrAborted
+		case command[0] == "n" && len(command) == 2:
+			if i, ok := index(command[1], items); ok {
+				fmt.Fprintf(out, "--- %s %s\n%s\n---\n", items[i].Planned.Dependency, items[i].Planned.Info.To, strings.TrimSpace(notes(items[i])))
+				continue
+			}
+		case len(command) == 1:
+			if i, ok := index(command[0], items); ok {
+				items[i].Selected = !items[i].Selected
+				render(out, items)
+				continue
+			}
+		}
+		fmt.Fprintf(out, "Unknown command %q\n", scanner.Text())
+	}
+}
+
+func index(s string, items []Item) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > len(items) {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+func notes(item Item) string {
+	if strings.TrimSpace(item.Notes) == "" {
+		return "no release notes"
+	}
+	return item.Notes
+}
+
+func render(out io.Writer, items []Item) {
+	for i, item := range items {
+		mark := " "
+		if item.Selected {
+			mark = "x"
+		}
+		risk := item.Risk()
+		fmt.Fprintf(out, "%2d [%s] %s %s -> %s, risk %d (%s)\n", i+1, mark, item.Planned.Dependency, item.Planned.Info.From, item.Planned.Info.To, risk, Level(risk))
+		for _, check := range item.Checks {
+			if check.Status() != "passed" {
+				fmt.Fprintf(out, "        %s %s: %s\n", check.Check, check.Status(), check.Detail)
+			}
+		}
+		lines := strings.Split(strings.TrimSpace(notes(item)), "\n")
+		if len(lines) > previewLines {
+			lines = append(lines[:previewLines], "...")
+		}
+		for _, line := range lines {
+			fmt.Fprintf(out, "        | %s\n", strings.TrimSpace(line))
+		}
+	}
+}
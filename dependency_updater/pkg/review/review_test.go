@@ -0,0 +1,83 @@
+package review
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+func item(dependency string, from string, to string, checks ...runner.CheckResult) Item {
+	return Item{
+		Planned:  version.PlannedUpdate{Dependency: dependency, Version: to, Info: version.UpdateInfo{From: from, To: to}},
+		Tracking: "release",
+		Checks:   checks,
+		Selected: true,
+	}
+}
+
+func TestRisk(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want int
+	}{
+		{"patch", item("reth", "v1.0.0", "v1.0.1"), 1},
+		{"minor", item("reth", "v1.0.0", "v1.1.0"), 2},
+		{"major", item("reth", "v1.0.0", "v2.0.0"), 4},
+		{"release candidate", item("reth", "v1.0.0", "v1.1.0-rc1"), 3},
+		{"needs approval", item("reth", "v1.0.0", "v2.0.0", runner.CheckResult{NeedsApproval: true}, runner.CheckResult{Passed: true}), 8},
+		{"failed check", item("reth", "v1.0.0", "v1.0.1", runner.CheckResult{}), 3},
+		{"capped", item("reth", "v1.0.0", "v2.0.0-rc1", runner.CheckResult{NeedsApproval: true}, runner.CheckResult{}), 10},
+		{"0.x minor", item("op_supervisor", "v0.2.0", "v0.3.0"), 2},
+		{"0.x minor with preOneBreaking", Item{Planned: version.PlannedUpdate{Info: version.UpdateInfo{From: "v0.2.0", To: "v0.3.0"}}, Tracking: "release", PreOneBreaking: true}, 4},
+		{"branch", Item{Tracking: "branch", Planned: version.PlannedUpdate{Info: version.UpdateInfo{To: "abc"}}}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.item.Risk(); got != tt.want {
+				t.Errorf("Risk() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	items := []Item{
+		item("op_node", "v1.0.0", "v1.1.0"),
+		item("reth", "v1.0.0", "v2.0.0", runner.CheckResult{Check: "breaking changes", NeedsApproval: true, Detail: "requires resync"}),
+	}
+	items[1].Notes = "Breaking: requires resync\nline 2\nline 3\nline 4"
+
+	var out strings.Builder
+	selected, err := Prompt(strings.NewReader("n 2\n2\nfoo\n1\n2\na\n"), &out, items)
+	if err != nil {
+		t.Fatalf("Prompt() unexpected error: %v", err)
+	}
+	if len(selected) != 1 || selected[0].Dependency != "reth" {
+		t.Errorf("Prompt() = %+v, want reth", selected)
+	}
+	for _, want := range []string{
+		" 2 [x] reth v1.0.0 -> v2.0.0, risk 8 (high)",
+		"breaking changes needs approval: requires resync",
+		"| Breaking: requires resync",
+		"| ...",
+		"line 4\n---",
+		" 2 [ ] reth",
+		"Unknown command \"foo\"",
+		"| no release notes",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Prompt() output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	if _, err := Prompt(strings.NewReader("1\nq\n"), &out, items); !errors.Is(err, ErrAborted) {
+		t.Errorf("Prompt() after q = %v, want ErrAborted", err)
+	}
+	if _, err := Prompt(strings.NewReader(""), &out, items); !errors.Is(err, ErrAborted) {
+		t.Errorf("Prompt() at end of input = %v, want ErrAborted", err)
+	}
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Check inspects a selected update before it is applied, such as the provenance of the
+// candidate.
+type Check interface {
+	Name() string
+	// Check returns the result for planned, an update of the dependency info from source, or
+	// nil if the check doesn't apply to the dependency.
+	Check(ctx context.Context, source sources.VersionSource, info *version.Info, planned version.PlannedUpdate) *CheckResult
+}
+
+// CheckResult is what a check found for one update. Hold keeps the dependency at its current
+// version for this run, Detail says why. NeedsApproval applies the update but puts it in the
+// high risk tier, which a person has to approve before it is merged. Unverified results
+// neither pass nor hold, the check could only go part of the way, such as provenance whose
+// signature wasn't verified.
+type CheckResult struct {
+	Dependency    string `json:"dependency"`
+	Version       string `json:"version"`
+	Check         string `json:"check"`
+	Passed        bool   `json:"passed"`
+	Hold          bool   `json:"hold,omitempty"`
+	NeedsApproval bool   `json:"needsApproval,omitempty"`
+	Unverified    bool   `json:"unverified,omitempty"`
+	Detail        string `json:"detail"`
+}
+
+// Status is "passed", "held", "needs approval", "unverified", or "failed" for results that
+// only warn.
+func (r CheckResult) Status() string {
+	switch {
+	case r.Hold:
+		return "held"
+	case r.NeedsApproval:
+		return "needs approval"
+	case r.Unverified:
+		return "unverified"
+	case !r.Passed:
+		return "failed"
+	}
+	return "passed"
+}
+
+// NeedsApproval returns the applied updates a check put in the high risk tier.
+func (r *Result) NeedsApproval() []string {
+	applied := map[string]bool{}
+	for _, planned := range r.Planned {
+		applied[planned.Dependency] = true
+	}
+	var dependencies []string
+	for _, check := range r.Checks {
+		if check.NeedsApproval && applied[check.Dependency] && !slices.Contains(dependencies, check.Dependency) {
+			dependencies = append(dependencies, check.Dependency)
+		}
+	}
+	return dependencies
+}
+
+// runChecks runs every check on the planned updates and returns the updates no check holds.
+func runChecks(ctx context.Context, opts Options, dependencies version.Dependencies, planned []version.PlannedUpdate) ([]version.PlannedUpdate, []CheckResult, error) {
+	if len(opts.Checks) == 0 {
+		return planned, nil, nil
+	}
+	var kept []version.PlannedUpdate
+	var results []CheckResult
+	for _, p := range planned {
+		info := dependencies[p.Dependency]
+		source, err := opts.Sources.For(info.Source)
+		if err != nil {
+			return nil, nil, err
+		}
+		held := false
+		end := opts.group(p.Dependency + " checks")
+		for _, check := range opts.Checks {
+			result := check.Check(ctx, source, info, p)
+			if result == nil {
+				continue
+			}
+			result.Dependency, result.Version, result.Check = p.Dependency, p.Info.To, check.Name()
+			results = append(results, *result)
+			if result.Hold {
+				log.Printf("Holding %s at %s, %s check failed for %s: %s", p.Dependency, info.Tag, check.Name(), p.Info.To, result.Detail)
+				held = true
+			}
+		}
+		end()
+		if !held {
+			kept = append(kept, p)
+		}
+	}
+	return kept, results, nil
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// BlockRef mirrors op-node's L2BlockRef JSON encoding.
+type BlockRef struct {
+	Hash      string `json:"hash"`
+	Number    uint64 `json:"number"`
+	Timestamp uint64 `json:"timestamp"`
+}
+
+// SyncStatus is the subset of op-node's optimism_syncStatus result the updater uses.
+type SyncStatus struct {
+	UnsafeL2    BlockRef `json:"unsafe_l2"`
+	SafeL2      BlockRef `json:"safe_l2"`
+	FinalizedL2 BlockRef `json:"finalized_l2"`
+}
+
+var nodeHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// callNodeRPC makes a JSON-RPC call against the live node and decodes the result into result.
+func callNodeRPC(ctx context.Context, url string, result any, method string, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return fmt.Errorf("error encoding %s request: %s", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating %s request: %s", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := nodeHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s on %s: %s", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("error decoding %s response: %s", method, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("error calling %s on %s: %d %s", method, url, rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("error decoding %s result: %s", method, err)
+	}
+	return nil
+}
+
+func getSyncStatus(ctx context.Context, nodeRPC string) (*SyncStatus, error) {
+	var status SyncStatus
+	if err := callNodeRPC(ctx, nodeRPC, &status, "optimism_syncStatus"); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// blocksBehind estimates how far the unsafe head is behind the chain head from its timestamp,
+// Base produces a block every two seconds.
+func blocksBehind(unsafe BlockRef, now time.Time) uint64 {
+	lag := now.Unix() - int64(unsafe.Timestamp)
+	if lag <= 0 {
+		return 0
+	}
+	return uint64(lag) / 2
+}
@@ -0,0 +1,189 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+type PreflightOptions struct {
+	Enabled        bool
+	NodeRPC        string
+	DataDir        string
+	MinFreeDisk    uint64
+	MaxBehind      uint64
+	HardforkWindow time.Duration
+}
+
+// Migration is a known version which requires a database migration or resync when upgraded across.
+type Migration struct {
+	Dependency  string `json:"dependency"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type preflightResult struct {
+	Check  string
+	Passed bool
+	Detail string
+}
+
+func runPreflightChecks(ctx context.Context, repoPath string, dependencies version.Dependencies, planned []version.PlannedUpdate, opts PreflightOptions) error {
+	migrations, err := readMigrations(repoPath + "/dependency_updater/migrations.json")
+	if err != nil {
+		return err
+	}
+
+	results := []preflightResult{
+		checkSynced(ctx, opts.NodeRPC, opts.MaxBehind),
+		checkDiskHeadroom(opts.DataDir, opts.MinFreeDisk),
+		checkHardforks(ctx, opts.NodeRPC, opts.HardforkWindow, time.Now()),
+	}
+	for _, p := range planned {
+		results = append(results, checkMigrations(dependencies[p.Dependency], p, migrations))
+	}
+
+	var failed []string
+	for _, r := range results {
+		status := "ok"
+		if !r.Passed {
+			status = "FAIL"
+			failed = append(failed, r.Check)
+		}
+		log.Printf("Preflight %-4s %s: %s", status, r.Check, r.Detail)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("preflight checks failed, not applying updates: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func checkSynced(ctx context.Context, nodeRPC string, maxBehind uint64) preflightResult {
+	result := preflightResult{Check: "synced"}
+	status, err := getSyncStatus(ctx, nodeRPC)
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	behind := blocksBehind(status.UnsafeL2, time.Now())
+	result.Passed = behind <= maxBehind
+	result.Detail = fmt.Sprintf("unsafe head %d is %d blocks behind (max %d)", status.UnsafeL2.Number, behind, maxBehind)
+	return result
+}
+
+func checkDiskHeadroom(dataDir string, minFreeGiB uint64) preflightResult {
+	result := preflightResult{Check: "disk headroom"}
+	if dataDir == "" {
+		result.Passed = true
+		result.Detail = "skipped, no --data-dir set"
+		return result
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dataDir, &st); err != nil {
+		result.Detail = fmt.Sprintf("error reading volume stats for %s: %s", dataDir, err)
+		return result
+	}
+	freeGiB := st.Bavail * uint64(st.Bsize) >> 30
+	result.Passed = freeGiB >= minFreeGiB
+	result.Detail = fmt.Sprintf("%d GiB free on %s (min %d GiB)", freeGiB, dataDir, minFreeGiB)
+	return result
+}
+
+func checkHardforks(ctx context.Context, nodeRPC string, window time.Duration, now time.Time) preflightResult {
+	result := preflightResult{Check: "hardfork activation"}
+	var rollupConfig map[string]any
+	if err := callNodeRPC(ctx, nodeRPC, &rollupConfig, "optimism_rollupConfig"); err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+
+	imminent := upcomingHardforks(rollupConfig, now, window)
+	result.Passed = len(imminent) == 0
+	if result.Passed {
+		result.Detail = fmt.Sprintf("no hardfork activates within %s", window)
+	} else {
+		result.Detail = "activating soon: " + strings.Join(imminent, ", ")
+	}
+	return result
+}
+
+// upcomingHardforks returns the forks in an op-node rollup config ("<fork>_time" fields)
+// activating between now and now+window.
+func upcomingHardforks(rollupConfig map[string]any, now time.Time, window time.Duration) []string {
+	var forks []string
+	for key, value := range rollupConfig {
+		fork, ok := strings.CutSuffix(key, "_time")
+		if !ok {
+			continue
+		}
+		ts, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		activation := time.Unix(int64(ts), 0)
+		if activation.After(now) && !activation.After(now.Add(window)) {
+			forks = append(forks, fmt.Sprintf("%s at %s", fork, activation.UTC().Format(time.RFC3339)))
+		}
+	}
+	slices.Sort(forks)
+	return forks
+}
+
+func checkMigrations(current *version.Info, planned version.PlannedUpdate, migrations []Migration) preflightResult {
+	result := preflightResult{Check: "database compatibility " + planned.Dependency, Passed: true}
+	crossed := crossedMigrations(planned.Dependency, current.Tag, planned.Version, current.TagPrefix, migrations)
+	if len(crossed) == 0 {
+		result.Detail = fmt.Sprintf("no known migrations between %s and %s", current.Tag, planned.Version)
+		return result
+	}
+	result.Passed = false
+	var details []string
+	for _, m := range crossed {
+		details = append(details, m.Version+": "+m.Description)
+	}
+	result.Detail = strings.Join(details, "; ")
+	return result
+}
+
+// crossedMigrations returns the migrations for dependency with current < version <= candidate.
+func crossedMigrations(dependency string, currentTag string, candidateTag string, tagPrefix string, migrations []Migration) []Migration {
+	var crossed []Migration
+	for _, m := range migrations {
+		if m.Dependency != dependency {
+			continue
+		}
+		afterCurrent, err := version.CompareVersions(m.Version, currentTag, tagPrefix)
+		if err != nil || afterCurrent <= 0 {
+			continue
+		}
+		beforeCandidate, err := version.CompareVersions(m.Version, candidateTag, tagPrefix)
+		if err != nil || beforeCandidate > 0 {
+			continue
+		}
+		crossed = append(crossed, m)
+	}
+	return crossed
+}
+
+func readMigrations(path string) ([]Migration, error) {
+	var migrations []Migration
+	f, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations JSON: %s", err)
+	}
+	if err := json.Unmarshal(f, &migrations); err != nil {
+		return nil, fmt.Errorf("error unmarshalling migrations JSON: %s", err)
+	}
+	return migrations, nil
+}
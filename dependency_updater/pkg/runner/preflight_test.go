@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+func TestCrossedMigrations(t *testing.T) {
+	migrations := []Migration{
+		{Dependency: "op_geth", Version: "v1.101600.0", Description: "path scheme migration"},
+		{Dependency: "op_node", Version: "op-node/v1.17.0", Description: "new safe db"},
+	}
+
+	tests := []struct {
+		name       string
+		dependency string
+		current    string
+		candidate  string
+		tagPrefix  string
+		want       int
+	}{
+		{"crosses migration", "op_geth", "v1.101500.0", "v1.101602.0", "", 1},
+		{"lands on migration", "op_geth", "v1.101500.0", "v1.101600.0", "", 1},
+		{"already past migration", "op_geth", "v1.101600.0", "v1.101602.0", "", 0},
+		{"before migration", "op_geth", "v1.101500.0", "v1.101503.0", "", 0},
+		{"other dependency", "nethermind", "1.30.0", "1.36.0", "", 0},
+		{"with tag prefix", "op_node", "op-node/v1.16.11", "op-node/v1.17.1", "op-node", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := crossedMigrations(tt.dependency, tt.current, tt.candidate, tt.tagPrefix, migrations)
+			if len(got) != tt.want {
+				t.Errorf("crossedMigrations(%q, %q, %q) = %v, want %d", tt.dependency, tt.current, tt.candidate, got, tt.want)
+			}
+		})
+	}
+}
+
+// The shipped migration list is what the database compatibility preflight holds updates
+// with, each entry has to name a dependency of the repository at a version it can compare.
+func TestShippedMigrations(t *testing.T) {
+	migrations, err := readMigrations("../../migrations.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("migrations.json lists no migrations, the database compatibility preflight can't hold anything")
+	}
+	dependencies, err := version.ReadDependencies("../../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations {
+		info, ok := dependencies[m.Dependency]
+		if !ok {
+			t.Errorf("migration %s %s names no dependency of versions.json", m.Dependency, m.Version)
+			continue
+		}
+		if _, err := version.CompareVersions(m.Version, info.Tag, info.TagPrefix); err != nil || m.Description == "" {
+			t.Errorf("migration %s %s = %v, want a comparable version and a description", m.Dependency, m.Version, err)
+		}
+	}
+}
+
+func TestUpcomingHardforks(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	config := map[string]any{
+		"l2_chain_id":   float64(8453),
+		"ecotone_time":  float64(1600000000),
+		"isthmus_time":  float64(1700000000 + 3600),
+		"jovian_time":   float64(1700000000 + 30*24*3600),
+		"block_time":    float64(2),
+		"regolith_time": float64(0),
+		"holocene_time": nil,
+	}
+
+	got := upcomingHardforks(config, now, 48*time.Hour)
+	if len(got) != 1 {
+		t.Errorf("upcomingHardforks() = %v, want only isthmus", got)
+	}
+}
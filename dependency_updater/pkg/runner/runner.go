@@ -0,0 +1,426 @@
+// Package runner checks every dependency in versions.json for updates and applies them to
+// the version files, the workflow behind the updater CLI.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/drift"
+	"github.com/base/node/dependency_updater/pkg/history"
+	"github.com/base/node/dependency_updater/pkg/policy"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/targets"
+	"github.com/base/node/dependency_updater/pkg/version"
+	"github.com/ethereum-optimism/optimism/op-service/retry"
+)
+
+// Options configures a run.
+type Options struct {
+	RepoPath string
+	// Sources resolves the source of each dependency.
+	Sources *sources.Set
+	// Targets are the files kept in sync, targets.Defaults(Sources) if empty.
+	Targets []targets.UpdateTarget
+	// Notifiers are told about applied updates, not about dry runs.
+	Notifiers []Notifier
+	DryRun    bool
+	Preflight PreflightOptions
+	// History skips dependencies checked less than their check interval ago, and records the
+	// checks of every run that is not a dry run. Nil checks everything.
+	History *history.DB
+	// CheckInterval applies to dependencies without a "checkInterval" in versions.json.
+	CheckInterval time.Duration
+	// Force checks every dependency regardless of its interval.
+	Force bool
+	// Checkpoint, if set, records each resolved dependency so an interrupted run resumes
+	// without fetching them again. It is removed once the run finished. Dry runs leave it
+	// alone.
+	Checkpoint *history.Checkpoint
+	// Checks inspect the selected updates, any of them can hold an update back.
+	Checks []Check
+	// Select, if set, is given the updates the checks kept and returns those to apply, such
+	// as the ones an operator picked.
+	Select func(ctx context.Context, dependencies version.Dependencies, planned []version.PlannedUpdate, checks []CheckResult) ([]version.PlannedUpdate, error)
+	// Confirm, if set, is given the planned edits before any is written, and returning an
+	// error writes none, such as when an operator declines them. Dry runs don't call it.
+	Confirm func(ctx context.Context, edits []targets.Edit) error
+	// Group, if set, is called before the work on a dependency and returns the function
+	// called after it, such as to fold its log lines in CI.
+	Group func(name string) (end func())
+	// Only and Skip are path.Match globs of dependency names. If Only is set, a run only
+	// checks the dependencies matching it, and it never checks those matching Skip.
+	Only []string
+	Skip []string
+	// SameVersion is what a run does about dependencies resolving to their current version:
+	// nothing if empty or "noop", log a warning with "warn", or fail with ErrSameVersion
+	// before applying anything with "error".
+	SameVersion string
+}
+
+// SameVersionModes are the values of Options.SameVersion.
+var SameVersionModes = []string{"noop", "warn", "error"}
+
+// ErrSameVersion is returned by runs with SameVersion "error" that resolved a dependency to
+// its current version.
+var ErrSameVersion = errors.New("resolved to the current version")
+
+// Selects reports whether a run checks the dependency name under Only and Skip. Hyphens and
+// underscores match each other, so "op-*" selects op_node.
+func (o Options) Selects(name string) bool {
+	matches := func(patterns []string) bool {
+		normalized := strings.ReplaceAll(name, "-", "_")
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ReplaceAll(pattern, "-", "_"), normalized); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(o.Only) == 0 || matches(o.Only)) && !matches(o.Skip)
+}
+
+func (o Options) group(name string) func() {
+	if o.Group == nil {
+		return func() {}
+	}
+	return o.Group(name)
+}
+
+// ValidateGlobs returns an error naming the first malformed pattern.
+func ValidateGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Result is what a run selected and, unless it was a dry run, applied.
+type Result struct {
+	Updates []version.UpdateInfo
+	// Planned are the updates behind Updates, with the dependency each one changes.
+	Planned []version.PlannedUpdate
+	Edits   []targets.Edit
+	// Checks are the results of Options.Checks, including those of held updates.
+	Checks []CheckResult
+	// Rationale says for every dependency of versions.json why it got the version it did.
+	Rationale []policy.Rationale
+	// Drift is how far each dependency was behind before the run, from its rationale.
+	Drift []drift.Drift
+}
+
+// Notifier is told about the updates a run applied.
+type Notifier interface {
+	Notify(ctx context.Context, updates []version.UpdateInfo) error
+}
+
+// SummaryNotifier is a Notifier that is also given the drift summary of the run, see
+// drift.Summary. Notifiers implementing it are called with NotifySummary instead of Notify.
+type SummaryNotifier interface {
+	NotifySummary(ctx context.Context, updates []version.UpdateInfo, summary string) error
+}
+
+// Run selects the updates for every due dependency that is not pinned, runs the preflight
+// checks if enabled and applies the updates to all targets.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	var plannedUpdates []version.PlannedUpdate
+	// A dry run, such as check or report --drift, resolves what is current rather than
+	// resuming an interrupted update, whose checkpoint it keeps for the update to resume.
+	if opts.DryRun {
+		opts.Checkpoint = nil
+	}
+
+	dependencies, err := version.ReadDependencies(opts.RepoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	checked := map[string]string{}
+	var rationales []policy.Rationale
+	var same []string
+	for _, dependency := range version.Names(dependencies) {
+		info := dependencies[dependency]
+		skipped := policy.Rationale{Dependency: dependency, Tracking: info.Tracking, TagPrefix: info.TagPrefix, Current: info.Tag}
+		if !opts.Selects(dependency) {
+			skipped.Skipped = "excluded by the run's only and skip filters"
+			rationales = append(rationales, skipped)
+			continue
+		}
+		if info.Pinned {
+			log.Printf("Skipping %s, pinned at %s", dependency, info.Tag)
+			skipped.Skipped = "pinned"
+			rationales = append(rationales, skipped)
+			continue
+		}
+		due, err := isDue(opts, dependency, info, now)
+		if err != nil {
+			return nil, err
+		}
+		if !due {
+			lastChecked := opts.History.Components[dependency].LastChecked.Format(time.RFC3339)
+			log.Printf("Skipping %s, checked %s", dependency, lastChecked)
+			skipped.Skipped = "last checked " + lastChecked + ", its check interval hasn't elapsed"
+			rationales = append(rationales, skipped)
+			continue
+		}
+		end := opts.group(dependency)
+		planned, rationale, err := resolve(ctx, opts, dependency, info)
+		end()
+		if err != nil {
+			recordFailure(opts, now, dependency, err)
+			return nil, err
+		}
+		rationales = append(rationales, *rationale)
+		if planned == nil && rationale.Selected != "" && rationale.Selected == rationale.Current {
+			switch opts.SameVersion {
+			case "warn":
+				log.Printf("Warning: %s resolved to its current version %s", dependency, rationale.Current)
+			case "error":
+				same = append(same, dependency)
+			}
+		}
+
+		checked[dependency] = ""
+		if planned != nil {
+			plannedUpdates = append(plannedUpdates, *planned)
+			checked[dependency] = planned.Version
+		}
+	}
+
+	if same != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSameVersion, strings.Join(same, ", "))
+	}
+
+	plannedUpdates, checks, err := runChecks(ctx, opts, dependencies, plannedUpdates)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Select != nil && len(plannedUpdates) > 0 {
+		if plannedUpdates, err = opts.Select(ctx, dependencies, plannedUpdates, checks); err != nil {
+			return nil, err
+		}
+	}
+	drifts := drift.All(rationales)
+	result, err := apply(ctx, opts, dependencies, plannedUpdates, checked, checks, drift.Summary(drifts), now)
+	if err != nil {
+		return nil, err
+	}
+	result.Checks, result.Rationale, result.Drift = checks, rationales, drifts
+	if opts.Checkpoint != nil {
+		if err := opts.Checkpoint.Remove(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// resolve returns the update for a dependency and its rationale, from the checkpoint if an
+// earlier attempt of the run already resolved it.
+func resolve(ctx context.Context, opts Options, name string, info *version.Info) (*version.PlannedUpdate, *policy.Rationale, error) {
+	if opts.Checkpoint != nil {
+		if planned, ok := opts.Checkpoint.Lookup(name); ok {
+			log.Printf("Resuming %s from checkpoint", name)
+			rationale := &policy.Rationale{Dependency: name, Tracking: info.Tracking, TagPrefix: info.TagPrefix, Current: info.Tag, Outcome: "resumed from the checkpoint of an interrupted run"}
+			if planned != nil {
+				rationale.Selected = planned.Info.To
+			}
+			return planned, rationale, nil
+		}
+	}
+	source, err := opts.Sources.For(info.Source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting version source for "+name+": %s", err)
+	}
+	// Only reading the source is retried, an invalid entry fails the same way every time.
+	if err := policy.Validate(name, info); err != nil {
+		return nil, nil, err
+	}
+	var planned *version.PlannedUpdate
+	var rationale *policy.Rationale
+	err = retry.Do0(ctx, 3, retry.Fixed(1*time.Second), func() error {
+		planned, rationale, err = policy.ResolveWithRationale(ctx, source, name, info)
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting version/commit for "+name+": %s", err)
+	}
+	if opts.Checkpoint != nil {
+		if err := opts.Checkpoint.Record(name, planned); err != nil {
+			return nil, nil, err
+		}
+	}
+	return planned, rationale, nil
+}
+
+// Apply applies updates chosen by the caller, such as pins and rollbacks, like Run applies
+// the ones it selects.
+func Apply(ctx context.Context, opts Options, plannedUpdates []version.PlannedUpdate) (*Result, error) {
+	dependencies, err := version.ReadDependencies(opts.RepoPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, planned := range plannedUpdates {
+		if dependencies[planned.Dependency] == nil {
+			return nil, fmt.Errorf("unknown dependency %s", planned.Dependency)
+		}
+	}
+	return apply(ctx, opts, dependencies, plannedUpdates, nil, nil, "", time.Now())
+}
+
+func apply(ctx context.Context, opts Options, dependencies version.Dependencies, plannedUpdates []version.PlannedUpdate, checked map[string]string, checks []CheckResult, summary string, now time.Time) (*Result, error) {
+	var updatedDependencies []version.UpdateInfo
+	var updatedPlans []version.PlannedUpdate
+
+	if opts.Preflight.Enabled && len(plannedUpdates) > 0 {
+		if err := runPreflightChecks(ctx, opts.RepoPath, dependencies, plannedUpdates, opts.Preflight); err != nil {
+			return nil, err
+		}
+	}
+
+	previous := map[string]history.Pin{}
+	var pinEvents []history.Event
+	for _, planned := range plannedUpdates {
+		dependency := dependencies[planned.Dependency]
+		if planned.Version != dependency.Tag || planned.Commit != dependency.Commit {
+			previous[planned.Dependency] = history.Pin{Tag: dependency.Tag, Commit: dependency.Commit}
+		}
+		if planned.Pin != dependency.Pinned || !planned.PinUntil.Equal(dependency.PinnedUntil) {
+			pinEvents = append(pinEvents, history.PinEvent(planned, now))
+		}
+		if planned.ImageDigest != "" || planned.Version != dependency.Tag {
+			dependency.ImageDigest = planned.ImageDigest
+		}
+		dependency.Tag = planned.Version
+		dependency.Commit = planned.Commit
+		dependency.Pinned = planned.Pin
+		dependency.PinnedUntil = planned.PinUntil
+		if planned.Info != (version.UpdateInfo{}) {
+			updatedDependencies = append(updatedDependencies, planned.Info)
+			updatedPlans = append(updatedPlans, planned)
+		}
+	}
+
+	runTargets := opts.Targets
+	if len(runTargets) == 0 {
+		runTargets = targets.Defaults(opts.Sources)
+	}
+	if opts.Confirm != nil && !opts.DryRun {
+		planned, err := targets.Apply(ctx, opts.RepoPath, runTargets, dependencies, true)
+		if err != nil {
+			return nil, fmt.Errorf("error updating version files: %s", err)
+		}
+		if len(planned) > 0 {
+			if err := opts.Confirm(ctx, planned); err != nil {
+				return nil, err
+			}
+		}
+	}
+	edits, err := targets.Apply(ctx, opts.RepoPath, runTargets, dependencies, opts.DryRun)
+	if err != nil {
+		return nil, fmt.Errorf("error updating version files: %s", err)
+	}
+
+	if !opts.DryRun && opts.History != nil {
+		for dependency, selected := range checked {
+			opts.History.Checked(dependency, now, selected)
+		}
+		for dependency, pin := range previous {
+			opts.History.Applied(dependency, now, pin)
+		}
+		opts.History.Audit = append(opts.History.Audit, pinEvents...)
+		// Apply's pins and rollbacks check nothing, only Run's runs are recorded.
+		if checked != nil {
+			opts.History.Record(runRecord(now, plannedUpdates, previous, checked, checks))
+		}
+		if err := opts.History.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.DryRun && updatedDependencies != nil {
+		notifyAll(ctx, opts.Notifiers, updatedDependencies, summary)
+	}
+	return &Result{Updates: updatedDependencies, Planned: updatedPlans, Edits: edits}, nil
+}
+
+// runRecord is the history record of a run checking the dependencies of checked and applying
+// planned, whose checks held back or failed some candidates.
+func runRecord(now time.Time, planned []version.PlannedUpdate, previous map[string]history.Pin, checked map[string]string, checks []CheckResult) history.Run {
+	run := history.Run{Time: now, Checked: slices.Sorted(maps.Keys(checked))}
+	for _, update := range planned {
+		if from, ok := previous[update.Dependency]; ok {
+			to := history.Pin{Tag: update.Version, Commit: update.Commit}
+			run.Applied = append(run.Applied, history.Change{Dependency: update.Dependency, From: from.String(), To: to.String()})
+		}
+	}
+	for _, check := range checks {
+		switch check.Status() {
+		case "held":
+			run.Held = append(run.Held, history.Hold{Dependency: check.Dependency, Version: check.Version, Check: check.Check, Detail: check.Detail})
+		case "failed":
+			run.Failed = append(run.Failed, history.Failure{Dependency: check.Dependency, Check: check.Check, Detail: check.Detail})
+		}
+	}
+	return run
+}
+
+// recordFailure records a run ended by failing to resolve dependency. The run's error is
+// what matters, a history that can't be saved is only logged.
+func recordFailure(opts Options, now time.Time, dependency string, err error) {
+	if opts.DryRun || opts.History == nil {
+		return
+	}
+	opts.History.Record(history.Run{Time: now, Checked: []string{dependency}, Failed: []history.Failure{{Dependency: dependency, Detail: err.Error()}}})
+	if err := opts.History.Save(); err != nil {
+		log.Printf("Failed to record the failed run in the history: %s", err)
+	}
+}
+
+func isDue(opts Options, name string, info *version.Info, now time.Time) (bool, error) {
+	if opts.History == nil || opts.Force {
+		return true, nil
+	}
+	interval := opts.CheckInterval
+	if info.CheckInterval != "" {
+		var err error
+		if interval, err = time.ParseDuration(info.CheckInterval); err != nil {
+			return false, fmt.Errorf("invalid checkInterval for %s: %s", name, err)
+		}
+	}
+	return opts.History.Due(name, interval, now), nil
+}
+
+// NotifyAll tells every notifier about the applied updates. Failures are logged rather than
+// returned, the updates have been written by then.
+func NotifyAll(ctx context.Context, notifiers []Notifier, updates []version.UpdateInfo) {
+	notifyAll(ctx, notifiers, updates, "")
+}
+
+// notifyAll is NotifyAll giving SummaryNotifiers the summary, if there is one.
+func notifyAll(ctx context.Context, notifiers []Notifier, updates []version.UpdateInfo, summary string) {
+	var errs []error
+	for _, n := range notifiers {
+		var err error
+		if s, ok := n.(SummaryNotifier); ok && summary != "" {
+			err = s.NotifySummary(ctx, updates, summary)
+		} else {
+			err = n.Notify(ctx, updates)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		log.Printf("Error notifying: %s", err)
+	}
+}
@@ -0,0 +1,439 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/history"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/targets"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// tagSource lists the same tags for every repository.
+type tagSource struct {
+	sources.VersionSource
+	tags []sources.Tag
+}
+
+func (s tagSource) ListTags(context.Context, string, string) ([]sources.Tag, error) {
+	return s.tags, nil
+}
+
+func (tagSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*sources.Release, error) {
+	return nil, errors.New("no release for " + tag)
+}
+
+func (tagSource) CompareURL(owner string, repo string, from string, to string) string {
+	return "https://forge.example/" + owner + "/" + repo + "/compare/" + from + "..." + to
+}
+
+// summaryNotifier records the summary it was notified with.
+type summaryNotifier struct {
+	summary *string
+}
+
+func (n summaryNotifier) Notify(ctx context.Context, updates []version.UpdateInfo) error {
+	return errors.New("Notify called on a SummaryNotifier")
+}
+
+func (n summaryNotifier) NotifySummary(ctx context.Context, updates []version.UpdateInfo, summary string) error {
+	*n.summary = summary
+	return nil
+}
+
+func TestRunAndApply(t *testing.T) {
+	repo := t.TempDir()
+	manifest := `{
+		"op_geth": {"tag": "v1.0.0", "commit": "g100", "owner": "o", "repo": "op-geth", "tracking": "release"},
+		"op_node": {"tag": "v1.0.0", "commit": "n100", "owner": "o", "repo": "op-node", "tracking": "release", "pinned": true}
+	}`
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, _ := history.Open(filepath.Join(repo, "history.json"))
+	set := sources.NewSet(sources.Options{})
+	set.Add(map[string]sources.VersionSource{sources.Default: tagSource{tags: []sources.Tag{{Name: "v1.1.0", Commit: "c110"}}}})
+	var summary string
+	opts := Options{RepoPath: repo, Sources: set, Targets: []targets.UpdateTarget{targets.VersionsJSON{}}, History: db, Notifiers: []Notifier{summaryNotifier{&summary}}}
+
+	result, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if want := "op_geth: 1 minor behind; op_node: pinned at v1.0.0"; summary != want || len(result.Drift) != 2 {
+		t.Errorf("notified summary = %q with %d drifts, want %q", summary, len(result.Drift), want)
+	}
+	if len(result.Updates) != 1 || result.Updates[0].Repo != "op-geth" {
+		t.Fatalf("Run() updates = %+v, want only the unpinned op_geth", result.Updates)
+	}
+	if previous := db.Components["op_geth"].Previous; previous == nil || previous.Tag != "v1.0.0" {
+		t.Errorf("history previous = %+v, want v1.0.0", previous)
+	}
+	if _, ok := db.Components["op_node"]; ok {
+		t.Errorf("pinned op_node was checked")
+	}
+	if len(db.Runs) != 1 || len(db.Runs[0].Applied) != 1 || db.Runs[0].Applied[0] != (history.Change{Dependency: "op_geth", From: "v1.0.0", To: "v1.1.0"}) {
+		t.Errorf("history runs = %+v, want a run applying op_geth v1.1.0", db.Runs)
+	}
+
+	rollback := version.PlannedUpdate{Dependency: "op_geth", Version: "v1.0.0", Commit: "g100", Pin: true, Info: version.UpdateInfo{Repo: "op-geth", From: "v1.1.0", To: "v1.0.0"}}
+	if _, err := Apply(context.Background(), opts, []version.PlannedUpdate{rollback}); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	dependencies, _ := version.ReadDependencies(repo)
+	if got := dependencies["op_geth"]; got.Tag != "v1.0.0" || got.Commit != "g100" || !got.Pinned {
+		t.Errorf("op_geth after Apply() = %+v, want pinned at v1.0.0", got)
+	}
+	if len(db.Audit) != 1 || db.Audit[0].Action != "pin" || db.Audit[0].Dependency != "op_geth" || db.Audit[0].Tag != "v1.0.0" {
+		t.Errorf("audit after the pinned rollback = %+v, want a pin of op_geth", db.Audit)
+	}
+	if len(db.Runs) != 1 || !slices.Equal(db.Runs[0].Checked, []string{"op_geth"}) {
+		t.Errorf("history runs after Apply() = %+v, want only the run checking op_geth", db.Runs)
+	}
+
+	until := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	bulk := []version.PlannedUpdate{
+		{Dependency: "op_geth", Version: "v1.0.0", Commit: "g100", Pin: true, PinUntil: until},
+		{Dependency: "op_node", Version: "v1.0.0", Commit: "n100"},
+	}
+	if _, err := Apply(context.Background(), opts, bulk); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	dependencies, _ = version.ReadDependencies(repo)
+	if !dependencies["op_geth"].PinnedUntil.Equal(until) || dependencies["op_node"].Pinned {
+		t.Errorf("after Apply() op_geth = %+v, op_node = %+v, want op_geth pinned until %s and op_node unpinned", dependencies["op_geth"], dependencies["op_node"], until)
+	}
+	if len(db.Audit) != 3 || !db.Audit[1].Until.Equal(until) || db.Audit[2].Action != "unpin" || db.Audit[2].Dependency != "op_node" {
+		t.Errorf("audit = %+v, want the expiring pin of op_geth and the unpin of op_node", db.Audit)
+	}
+	for _, tt := range []struct {
+		planned version.PlannedUpdate
+		want    string
+	}{
+		{version.PlannedUpdate{Dependency: "op_node", Version: "v1.0.0", Commit: "n100", ImageDigest: "sha256:aa"}, "sha256:aa"},
+		{version.PlannedUpdate{Dependency: "op_node", Version: "v1.0.0", Commit: "n100"}, "sha256:aa"},
+		{version.PlannedUpdate{Dependency: "op_node", Version: "v1.1.0", Commit: "c110"}, ""},
+	} {
+		if _, err := Apply(context.Background(), opts, []version.PlannedUpdate{tt.planned}); err != nil {
+			t.Fatalf("Apply() unexpected error: %v", err)
+		}
+		if dependencies, _ = version.ReadDependencies(repo); dependencies["op_node"].ImageDigest != tt.want {
+			t.Errorf("image digest after Apply(%+v) = %q, want %q", tt.planned, dependencies["op_node"].ImageDigest, tt.want)
+		}
+	}
+	if _, err := Apply(context.Background(), opts, []version.PlannedUpdate{{Dependency: "missing"}}); err == nil {
+		t.Errorf("Apply() of an unknown dependency expected error")
+	}
+}
+
+// failingSource fails every tag listing, like an exhausted rate limit, and interrupts the
+// run so it is not retried.
+type failingSource struct {
+	tagSource
+	interrupt context.CancelFunc
+}
+
+func (s failingSource) ListTags(context.Context, string, string) ([]sources.Tag, error) {
+	s.interrupt()
+	return nil, errors.New("rate limit exceeded")
+}
+
+// An invalid entry fails the same way on every attempt, it is not retried.
+func TestRunInvalidEntry(t *testing.T) {
+	repo := t.TempDir()
+	manifest := `{"op_geth": {"tag": "v1.0.0", "commit": "g100", "owner": "o", "repo": "op-geth", "tracking": "release", "tieBreak": "newest"}}`
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set := sources.NewSet(sources.Options{})
+	set.Add(map[string]sources.VersionSource{sources.Default: tagSource{}})
+	start := time.Now()
+	_, err := Run(context.Background(), Options{RepoPath: repo, Sources: set, Targets: []targets.UpdateTarget{targets.VersionsJSON{}}})
+	if err == nil || !strings.Contains(err.Error(), "unknown tieBreak") {
+		t.Fatalf("Run() = %v, want the unknown tieBreak", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Run() took %s, want no retries", elapsed)
+	}
+}
+
+func TestRunResumesCheckpoint(t *testing.T) {
+	repo := t.TempDir()
+	manifest := []byte(`{
+		"op_geth": {"tag": "v1.0.0", "commit": "g100", "owner": "o", "repo": "op-geth", "tracking": "release"},
+		"op_node": {"tag": "v1.0.0", "commit": "n100", "owner": "o", "repo": "op-node", "tracking": "release", "source": "flaky"}
+	}`)
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), manifest, 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(repo, "checkpoint.json")
+	checkpoint, _ := history.OpenCheckpoint(path, manifest, time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	set := sources.NewSet(sources.Options{})
+	set.Add(map[string]sources.VersionSource{
+		sources.Default: tagSource{tags: []sources.Tag{{Name: "v1.1.0", Commit: "c110"}}},
+		"flaky":         failingSource{interrupt: cancel},
+	})
+	opts := Options{RepoPath: repo, Sources: set, Targets: []targets.UpdateTarget{targets.VersionsJSON{}}, Checkpoint: checkpoint}
+
+	if _, err := Run(ctx, opts); err == nil {
+		t.Fatalf("Run() with a failing source expected error")
+	}
+
+	opts.Checkpoint, _ = history.OpenCheckpoint(path, manifest, time.Now())
+	if _, ok := opts.Checkpoint.Lookup("op_geth"); !ok {
+		t.Fatalf("checkpoint did not record op_geth")
+	}
+	set.Add(map[string]sources.VersionSource{
+		sources.Default: failingSource{interrupt: func() {}},
+		"flaky":         tagSource{tags: []sources.Tag{{Name: "v1.2.0", Commit: "c120"}}},
+	})
+	result, err := Run(context.Background(), opts)
+	if err != nil || len(result.Updates) != 2 {
+		t.Fatalf("Run() resuming = %+v, %v, want both updates", result, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("checkpoint not removed after the run finished")
+	}
+}
+
+// A dry run neither resumes an interrupted run's checkpoint nor removes it.
+func TestDryRunKeepsCheckpoint(t *testing.T) {
+	repo := t.TempDir()
+	manifest := []byte(`{"op_geth": {"tag": "v1.0.0", "commit": "g100", "owner": "o", "repo": "op-geth", "tracking": "release"}}`)
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), manifest, 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(repo, "checkpoint.json")
+	checkpoint, _ := history.OpenCheckpoint(path, manifest, time.Now())
+	if err := checkpoint.Record("op_geth", &version.PlannedUpdate{Dependency: "op_geth", Version: "v1.1.0", Commit: "c110", Info: version.UpdateInfo{Repo: "op-geth", From: "v1.0.0", To: "v1.1.0"}}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := sources.NewSet(sources.Options{})
+	set.Add(map[string]sources.VersionSource{sources.Default: tagSource{tags: []sources.Tag{{Name: "v1.2.0", Commit: "c120"}}}})
+	opts := Options{RepoPath: repo, Sources: set, Targets: []targets.UpdateTarget{targets.VersionsJSON{}}, Checkpoint: checkpoint, DryRun: true}
+
+	result, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if len(result.Updates) != 1 || result.Updates[0].To != "v1.2.0" {
+		t.Errorf("dry Run() updates = %+v, want the current v1.2.0, not the checkpoint's v1.1.0", result.Updates)
+	}
+	if after, err := os.ReadFile(path); err != nil || string(after) != string(before) {
+		t.Errorf("checkpoint after a dry Run() = %s, %v, want it untouched", after, err)
+	}
+}
+
+// holdCheck holds the updates of one dependency and asks for approval of op-node's.
+type holdCheck struct {
+	dependency string
+}
+
+func (holdCheck) Name() string { return "hold" }
+
+func (c holdCheck) Check(ctx context.Context, source sources.VersionSource, info *version.Info, planned version.PlannedUpdate) *CheckResult {
+	if info.Repo == "op-node" {
+		return &CheckResult{NeedsApproval: true, Detail: "test"}
+	}
+	return &CheckResult{Passed: planned.Dependency != c.dependency, Hold: planned.Dependency == c.dependency, Detail: "test"}
+}
+
+func TestRunChecks(t *testing.T) {
+	repo := t.TempDir()
+	manifest := `{
+		"op_geth": {"tag": "v1.0.0", "commit": "g100", "owner": "o", "repo": "op-geth", "tracking": "release"},
+		"op_node": {"tag": "v1.0.0", "commit": "n100", "owner": "o", "repo": "op-node", "tracking": "release"},
+		"reth": {"tag": "v1.0.0", "commit": "r100", "owner": "o", "repo": "reth", "tracking": "release"}
+	}`
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set := sources.NewSet(sources.Options{})
+	set.Add(map[string]sources.VersionSource{sources.Default: tagSource{tags: []sources.Tag{{Name: "v1.1.0", Commit: "c110"}}}})
+	opts := Options{RepoPath: repo, Sources: set, Targets: []targets.UpdateTarget{targets.VersionsJSON{}}, Checks: []Check{holdCheck{dependency: "reth"}}}
+
+	result, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if len(result.Updates) != 2 || result.Updates[0].Repo != "op-geth" || result.Updates[1].Repo != "op-node" {
+		t.Errorf("Run() updates = %+v, want op_geth and op_node", result.Updates)
+	}
+	want := []CheckResult{
+		{Dependency: "op_geth", Version: "v1.1.0", Check: "hold", Passed: true, Detail: "test"},
+		{Dependency: "op_node", Version: "v1.1.0", Check: "hold", NeedsApproval: true, Detail: "test"},
+		{Dependency: "reth", Version: "v1.1.0", Check: "hold", Hold: true, Detail: "test"},
+	}
+	if len(result.Checks) != len(want) || result.Checks[0] != want[0] || result.Checks[1] != want[1] || result.Checks[2] != want[2] {
+		t.Errorf("Run() checks = %+v, want %+v", result.Checks, want)
+	}
+	if got := result.NeedsApproval(); len(got) != 1 || got[0] != "op_node" {
+		t.Errorf("NeedsApproval() = %v, want op_node", got)
+	}
+	dependencies, _ := version.ReadDependencies(repo)
+	if dependencies["reth"].Tag != "v1.0.0" {
+		t.Errorf("held reth was updated to %s", dependencies["reth"].Tag)
+	}
+}
+
+func TestRunSelect(t *testing.T) {
+	repo := t.TempDir()
+	manifest := `{
+		"op_geth": {"tag": "v1.0.0", "commit": "g100", "owner": "o", "repo": "op-geth", "tracking": "release"},
+		"op_node": {"tag": "v1.0.0", "commit": "n100", "owner": "o", "repo": "op-node", "tracking": "release"}
+	}`
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set := sources.NewSet(sources.Options{})
+	set.Add(map[string]sources.VersionSource{sources.Default: tagSource{tags: []sources.Tag{{Name: "v1.1.0", Commit: "c110"}}}})
+	opts := Options{RepoPath: repo, Sources: set, Targets: []targets.UpdateTarget{targets.VersionsJSON{}},
+		Select: func(ctx context.Context, dependencies version.Dependencies, planned []version.PlannedUpdate, checks []CheckResult) ([]version.PlannedUpdate, error) {
+			if len(planned) != 2 {
+				t.Errorf("Select() got %+v, want both updates", planned)
+			}
+			return planned[1:], nil
+		},
+	}
+
+	result, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if len(result.Updates) != 1 || result.Updates[0].Repo != "op-node" {
+		t.Errorf("Run() updates = %+v, want op_node", result.Updates)
+	}
+	if len(result.Rationale) != 2 || result.Rationale[0].Dependency != "op_geth" || result.Rationale[0].Selected != "v1.1.0" {
+		t.Errorf("Run() rationale = %+v, want one for each dependency", result.Rationale)
+	}
+	dependencies, _ := version.ReadDependencies(repo)
+	if dependencies["op_geth"].Tag != "v1.0.0" || dependencies["op_node"].Tag != "v1.1.0" {
+		t.Errorf("versions.json = op_geth %s, op_node %s, want only op_node updated", dependencies["op_geth"].Tag, dependencies["op_node"].Tag)
+	}
+}
+
+func TestRunConfirm(t *testing.T) {
+	declined := errors.New("declined")
+	tests := []struct {
+		name    string
+		confirm error
+		want    string
+	}{
+		{name: "confirmed", want: "v1.1.0"},
+		{name: "declined", confirm: declined, want: "v1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			manifest := `{"op_node": {"tag": "v1.0.0", "commit": "n100", "owner": "o", "repo": "op-node", "tracking": "release"}}`
+			if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte(manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			set := sources.NewSet(sources.Options{})
+			set.Add(map[string]sources.VersionSource{sources.Default: tagSource{tags: []sources.Tag{{Name: "v1.1.0", Commit: "c110"}}}})
+			var confirmed []targets.Edit
+			opts := Options{RepoPath: repo, Sources: set, Targets: []targets.UpdateTarget{targets.VersionsJSON{}},
+				Confirm: func(ctx context.Context, edits []targets.Edit) error {
+					confirmed = edits
+					return tt.confirm
+				},
+			}
+
+			_, err := Run(context.Background(), opts)
+			if !errors.Is(err, tt.confirm) {
+				t.Errorf("Run() error = %v, want %v", err, tt.confirm)
+			}
+			if len(confirmed) != 1 || !strings.Contains(string(confirmed[0].After), "v1.1.0") {
+				t.Errorf("Confirm() got %+v, want the versions.json edit", confirmed)
+			}
+			dependencies, _ := version.ReadDependencies(repo)
+			if dependencies["op_node"].Tag != tt.want {
+				t.Errorf("versions.json op_node = %s, want %s", dependencies["op_node"].Tag, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunSameVersion(t *testing.T) {
+	tests := []struct {
+		mode    string
+		wantErr bool
+	}{
+		{mode: ""},
+		{mode: "warn"},
+		{mode: "error", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			repo := t.TempDir()
+			manifest := `{
+				"op_geth": {"tag": "v1.1.0", "commit": "c110", "owner": "o", "repo": "op-geth", "tracking": "release"},
+				"op_node": {"tag": "v1.0.0", "commit": "n100", "owner": "o", "repo": "op-node", "tracking": "release"}
+			}`
+			if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte(manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			set := sources.NewSet(sources.Options{})
+			set.Add(map[string]sources.VersionSource{sources.Default: tagSource{tags: []sources.Tag{{Name: "v1.1.0", Commit: "c110"}}}})
+			opts := Options{RepoPath: repo, Sources: set, Targets: []targets.UpdateTarget{targets.VersionsJSON{}}, SameVersion: tt.mode}
+
+			result, err := Run(context.Background(), opts)
+			if tt.wantErr {
+				if !errors.Is(err, ErrSameVersion) || !strings.HasSuffix(err.Error(), ": op_geth") {
+					t.Fatalf("Run() error = %v, want ErrSameVersion for op_geth", err)
+				}
+				dependencies, _ := version.ReadDependencies(repo)
+				if dependencies["op_node"].Tag != "v1.0.0" {
+					t.Errorf("versions.json op_node = %s, want nothing applied", dependencies["op_node"].Tag)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			if len(result.Updates) != 1 || result.Updates[0].Repo != "op-node" {
+				t.Errorf("Run() updates = %+v, want op_node", result.Updates)
+			}
+		})
+	}
+}
+
+func TestSelects(t *testing.T) {
+	tests := []struct {
+		only []string
+		skip []string
+		want string
+	}{
+		{want: "nethermind op_geth op_node"},
+		{only: []string{"op-*"}, want: "op_geth op_node"},
+		{only: []string{"op_*"}, skip: []string{"op_geth"}, want: "op_node"},
+		{skip: []string{"nethermind", "op-node"}, want: "op_geth"},
+	}
+	for _, tt := range tests {
+		opts := Options{Only: tt.only, Skip: tt.skip}
+		var got []string
+		for _, name := range []string{"nethermind", "op_geth", "op_node"} {
+			if opts.Selects(name) {
+				got = append(got, name)
+			}
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("Selects() with only %v and skip %v = %v, want %s", tt.only, tt.skip, got, tt.want)
+		}
+	}
+	if err := ValidateGlobs([]string{"op-*", "[op"}); err == nil {
+		t.Errorf("ValidateGlobs() of a malformed pattern expected an error")
+	}
+}
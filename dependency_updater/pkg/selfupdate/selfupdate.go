@@ -0,0 +1,166 @@
+// Package selfupdate replaces the updater binary with the newest release of this repository.
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/policy"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Owner, Repo and TagPrefix locate the updater's own releases, tagged dependency_updater/vX.Y.Z.
+const (
+	Owner     = "base"
+	Repo      = "node"
+	TagPrefix = "dependency_updater"
+)
+
+// ChecksumsAsset lists the sha256 of every binary of a release, in sha256sum format, and
+// SignatureAsset is its ed25519 signature.
+const (
+	ChecksumsAsset = "checksums.txt"
+	SignatureAsset = "checksums.txt.sig"
+)
+
+// AssetName is the name of the release binary for a platform.
+func AssetName(goos string, goarch string) string {
+	return fmt.Sprintf("dependency_updater_%s_%s", goos, goarch)
+}
+
+// Latest returns the newest release newer than current, a dependency_updater/vX.Y.Z tag or
+// empty for development builds. It returns nil if current is the newest.
+func Latest(ctx context.Context, source sources.VersionSource, current string) (*sources.Release, error) {
+	info := &version.Info{Owner: Owner, Repo: Repo, Tag: current, TagPrefix: TagPrefix, Tracking: "release"}
+	tags, err := source.ListTags(ctx, Owner, Repo)
+	if err != nil {
+		return nil, err
+	}
+	selected := policy.SelectTag(tags, info)
+	if selected == nil || selected.Name == current {
+		return nil, nil
+	}
+	return source.GetRelease(ctx, Owner, Repo, selected.Name)
+}
+
+// Download fetches the binary for the running platform from release and verifies it against
+// the release checksums. With a public key the checksums must carry a valid signature, a
+// release without one fails. A nil key skips the signature check.
+func Download(ctx context.Context, release *sources.Release, publicKey ed25519.PublicKey) ([]byte, error) {
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	binary, err := fetchAsset(ctx, release, name)
+	if err != nil {
+		return nil, err
+	}
+	checksums, err := fetchAsset(ctx, release, ChecksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+	if publicKey != nil {
+		signature, err := fetchAsset(ctx, release, SignatureAsset)
+		if err != nil {
+			return nil, fmt.Errorf("release %s is not signed: %s", release.Tag, err)
+		}
+		if err := VerifySignature(checksums, signature, publicKey); err != nil {
+			return nil, err
+		}
+	}
+	if err := VerifyChecksum(binary, name, checksums); err != nil {
+		return nil, err
+	}
+	return binary, nil
+}
+
+// VerifyChecksum checks data against the entry for name in sha256sum formatted checksums.
+func VerifyChecksum(data []byte, name string, checksums []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != strings.ToLower(fields[0]) {
+			return fmt.Errorf("checksum mismatch for %s", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("no checksum for %s", name)
+}
+
+// VerifySignature checks the ed25519 signature of the checksums, raw or hex encoded.
+func VerifySignature(checksums []byte, signature []byte, publicKey ed25519.PublicKey) error {
+	if decoded, err := hex.DecodeString(strings.TrimSpace(string(signature))); err == nil {
+		signature = decoded
+	}
+	if !ed25519.Verify(publicKey, checksums, signature) {
+		return fmt.Errorf("invalid signature for %s", ChecksumsAsset)
+	}
+	return nil
+}
+
+// Replace atomically replaces the executable at path with binary, keeping its permissions.
+// The new file is written next to it and renamed over it, so a failure leaves the old one.
+func Replace(path string, binary []byte) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %s", path, err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %s", path, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return fmt.Errorf("error writing new binary: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing new binary: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing new binary: %s", err)
+	}
+	if err := os.Chmod(tmp.Name(), stat.Mode().Perm()); err != nil {
+		return fmt.Errorf("error writing new binary: %s", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing %s: %s", path, err)
+	}
+	return nil
+}
+
+func fetchAsset(ctx context.Context, release *sources.Release, name string) ([]byte, error) {
+	for _, asset := range release.Assets {
+		if asset.Name != name {
+			continue
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error downloading %s: %s", name, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("error downloading %s: %s", name, resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}
+	return nil, fmt.Errorf("release %s has no asset %s", release.Tag, name)
+}
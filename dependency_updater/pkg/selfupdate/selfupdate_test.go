@@ -0,0 +1,130 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+)
+
+type releaseSource struct {
+	sources.VersionSource
+	tags []sources.Tag
+}
+
+func (s releaseSource) ListTags(context.Context, string, string) ([]sources.Tag, error) {
+	return s.tags, nil
+}
+
+func (releaseSource) GetRelease(_ context.Context, _ string, _ string, tag string) (*sources.Release, error) {
+	return &sources.Release{Tag: tag}, nil
+}
+
+func TestLatest(t *testing.T) {
+	source := releaseSource{tags: []sources.Tag{{Name: "dependency_updater/v1.1.0"}, {Name: "dependency_updater/v1.2.0-rc1"}, {Name: "v9.0.0"}}}
+	tests := []struct {
+		current string
+		want    string
+	}{
+		{"", "dependency_updater/v1.1.0"},
+		{"dependency_updater/v1.0.0", "dependency_updater/v1.1.0"},
+		{"dependency_updater/v1.1.0", ""},
+	}
+	for _, tt := range tests {
+		release, err := Latest(context.Background(), source, tt.current)
+		if err != nil {
+			t.Fatalf("Latest(%q) unexpected error: %v", tt.current, err)
+		}
+		got := ""
+		if release != nil {
+			got = release.Tag
+		}
+		if got != tt.want {
+			t.Errorf("Latest(%q) = %q, want %q", tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	checksums := []byte(fmt.Sprintf("%s  %s\n%s  other\n", hex.EncodeToString(sum[:]), AssetName("linux", "amd64"), hex.EncodeToString(sum[:])))
+
+	if err := VerifyChecksum(binary, AssetName("linux", "amd64"), checksums); err != nil {
+		t.Errorf("VerifyChecksum() unexpected error: %v", err)
+	}
+	if err := VerifyChecksum([]byte("tampered"), AssetName("linux", "amd64"), checksums); err == nil {
+		t.Errorf("VerifyChecksum() of a tampered binary expected error")
+	}
+	if err := VerifyChecksum(binary, AssetName("darwin", "arm64"), checksums); err == nil {
+		t.Errorf("VerifyChecksum() without an entry expected error")
+	}
+
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	signature := []byte(hex.EncodeToString(ed25519.Sign(privateKey, checksums)))
+	if err := VerifySignature(checksums, signature, publicKey); err != nil {
+		t.Errorf("VerifySignature() unexpected error: %v", err)
+	}
+	if err := VerifySignature(append(checksums, '\n'), signature, publicKey); err == nil {
+		t.Errorf("VerifySignature() of modified checksums expected error")
+	}
+}
+
+func TestDownloadUnsigned(t *testing.T) {
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ChecksumsAsset) {
+			fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+			return
+		}
+		w.Write(binary)
+	}))
+	defer server.Close()
+	release := &sources.Release{Tag: "dependency_updater/v1.1.0", Assets: []sources.Asset{
+		{Name: name, URL: server.URL + "/" + name},
+		{Name: ChecksumsAsset, URL: server.URL + "/" + ChecksumsAsset},
+	}}
+
+	publicKey, _, _ := ed25519.GenerateKey(nil)
+	if _, err := Download(context.Background(), release, publicKey); err == nil {
+		t.Errorf("Download() of a release without %s expected error", SignatureAsset)
+	}
+	if got, err := Download(context.Background(), release, nil); err != nil || string(got) != string(binary) {
+		t.Errorf("Download() checking only checksums = %q, %v", got, err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dependency_updater")
+	if err := os.WriteFile(path, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "updater")
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := Replace(link, []byte("new")); err != nil {
+		t.Fatalf("Replace() unexpected error: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	stat, _ := os.Stat(path)
+	if string(got) != "new" || stat.Mode().Perm() != 0755 {
+		t.Errorf("Replace() left %q with mode %v", got, stat.Mode())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Replace() left %d files, want the binary and the link", len(entries))
+	}
+}
@@ -0,0 +1,31 @@
+// Command sign prints the hex encoded ed25519 signature of a release's checksums file, the
+// checksums.txt.sig asset self-update verifies. The hex encoded 32 byte seed of the key is
+// read from UPDATER_SIGNING_KEY, and must match UPDATER_RELEASE_PUBLIC_KEY if that is set.
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: sign <checksums.txt>")
+	}
+	seed, err := hex.DecodeString(os.Getenv("UPDATER_SIGNING_KEY"))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		log.Fatalf("UPDATER_SIGNING_KEY must be a hex encoded %d byte seed", ed25519.SeedSize)
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	if public := os.Getenv("UPDATER_RELEASE_PUBLIC_KEY"); public != "" && public != hex.EncodeToString(key.Public().(ed25519.PublicKey)) {
+		log.Fatal("UPDATER_SIGNING_KEY does not match UPDATER_RELEASE_PUBLIC_KEY")
+	}
+	checksums, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(hex.EncodeToString(ed25519.Sign(key, checksums)))
+}
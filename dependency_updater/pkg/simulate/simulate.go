@@ -0,0 +1,331 @@
+// Package simulate injects hypothetical upstream releases, read from a scenario file, into the
+// sources of a run, so the selection policies and checks can be tried against releases that
+// don't exist yet.
+package simulate
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Release is a hypothetical upstream release of a dependency.
+type Release struct {
+	Dependency string
+	Tag        string
+	// Commit defaults to a made-up commit derived from the dependency and tag.
+	Commit     string
+	Published  time.Time
+	Prerelease bool
+	// Notes are the release notes, which the breaking change check reads.
+	Notes string
+}
+
+// Scenario is the content of a scenario file.
+type Scenario struct {
+	// Offline leaves out everything upstream, the dependencies only have their current tag
+	// and the scenario's releases.
+	Offline  bool
+	Releases []Release
+}
+
+// ParseScenario reads a scenario file. It only understands the block style it is documented
+// in, with one key per line:
+//
+//	offline: true
+//	releases:
+//	  - dependency: op_node
+//	    tag: op-node/v1.17.0
+//	    published: 2026-11-01
+//	    prerelease: false
+//	    notes: "Removes the --l2.enginekind flag"
+//
+// Published takes a date or an RFC 3339 time. Comments start with "#".
+func ParseScenario(content []byte) (*Scenario, error) {
+	scenario := &Scenario{}
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		item, isItem := strings.CutPrefix(trimmed, "- ")
+		key, value, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value, got %q", n, trimmed)
+		}
+		key, value = strings.TrimSpace(key), scalar(value)
+
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			section = key
+			switch key {
+			case "offline":
+				parsed, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: offline must be true or false, got %q", n, value)
+				}
+				scenario.Offline = parsed
+			case "releases":
+				if value != "" {
+					return nil, fmt.Errorf("line %d: releases must be a list of releases", n)
+				}
+			default:
+				return nil, fmt.Errorf("line %d: unknown key %q, expected offline or releases", n, key)
+			}
+			continue
+		}
+		if section != "releases" {
+			return nil, fmt.Errorf("line %d: unexpected indented line %q", n, trimmed)
+		}
+		if isItem {
+			scenario.Releases = append(scenario.Releases, Release{})
+		} else if len(scenario.Releases) == 0 {
+			return nil, fmt.Errorf("line %d: release keys must follow a \"- \" list item", n)
+		}
+		release := &scenario.Releases[len(scenario.Releases)-1]
+		switch key {
+		case "dependency":
+			release.Dependency = value
+		case "tag":
+			release.Tag = version.SanitizeTag(value)
+		case "commit":
+			release.Commit = value
+		case "notes":
+			release.Notes = value
+		case "prerelease":
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: prerelease must be true or false, got %q", n, value)
+			}
+			release.Prerelease = parsed
+		case "published":
+			published, err := parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", n, err)
+			}
+			release.Published = published
+		default:
+			return nil, fmt.Errorf("line %d: unknown release key %q, expected dependency, tag, commit, published, prerelease or notes", n, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading scenario: %s", err)
+	}
+	for i, release := range scenario.Releases {
+		if release.Dependency == "" || release.Tag == "" {
+			return nil, fmt.Errorf("release %d needs a dependency and a tag", i+1)
+		}
+	}
+	return scenario, nil
+}
+
+// scalar unquotes a value, or strips the comment after an unquoted one.
+func scalar(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "'") {
+		if end := strings.IndexByte(value[1:], '\''); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if strings.HasPrefix(value, `"`) {
+		if quoted, err := strconv.QuotedPrefix(value); err == nil {
+			unquoted, _ := strconv.Unquote(quoted)
+			return unquoted
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid published %q, expected a date or an RFC 3339 time", value)
+	}
+	return t, nil
+}
+
+// Inject adds the scenario's releases to the sources of their dependencies in set. It fails
+// for releases of unknown or branch tracking dependencies.
+func (s *Scenario) Inject(set *sources.Set, dependencies version.Dependencies) error {
+	bySource := map[string]*source{}
+	for _, release := range s.Releases {
+		info := dependencies[release.Dependency]
+		if info == nil {
+			return fmt.Errorf("the scenario releases %s, which is not in versions.json", release.Dependency)
+		}
+		if info.Tracking == "branch" {
+			return fmt.Errorf("the scenario releases %s, which tracks a branch", release.Dependency)
+		}
+		name := cmp.Or(info.Source, sources.Default)
+		if bySource[name] == nil {
+			bySource[name] = &source{offline: s.Offline, releases: map[string][]injected{}, current: map[string][]sources.Tag{}}
+		}
+		if release.Commit == "" {
+			sum := sha256.Sum256([]byte(release.Dependency + "@" + release.Tag))
+			release.Commit = hex.EncodeToString(sum[:20])
+		}
+		repo := info.Owner + "/" + info.Repo
+		bySource[name].releases[repo] = append(bySource[name].releases[repo], injected{Release: release, base: info.Tag})
+	}
+	for _, name := range version.Names(dependencies) {
+		info := dependencies[name]
+		if wrapper, ok := bySource[cmp.Or(info.Source, sources.Default)]; ok && info.Tag != "" {
+			repo := info.Owner + "/" + info.Repo
+			wrapper.current[repo] = append(wrapper.current[repo], sources.Tag{Name: info.Tag, Commit: info.Commit})
+		}
+	}
+	for name, wrapper := range bySource {
+		if err := set.Intercept(name, func(upstream sources.VersionSource) sources.VersionSource {
+			wrapper.VersionSource = upstream
+			return wrapper
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type injected struct {
+	Release
+	// base is the current tag of the dependency, whose files the release is assumed to keep.
+	base string
+}
+
+// source lists the injected releases before the upstream tags, the scenario's last release
+// first, and answers for them itself. Injected tags hide upstream tags of the same name.
+type source struct {
+	sources.VersionSource
+	offline  bool
+	releases map[string][]injected
+	// current are the current tags of the dependencies, all offline sources list.
+	current map[string][]sources.Tag
+}
+
+// errOffline is returned for what an offline scenario doesn't know.
+var errOffline = fmt.Errorf("offline simulation: %w", errors.ErrUnsupported)
+
+func (s *source) find(owner string, repo string, ref string) (injected, bool) {
+	for _, release := range s.releases[owner+"/"+repo] {
+		if release.Tag == ref || release.Commit == ref {
+			return release, true
+		}
+	}
+	return injected{}, false
+}
+
+func (s *source) injectedTags(owner string, repo string) []sources.Tag {
+	releases := s.releases[owner+"/"+repo]
+	var tags []sources.Tag
+	for i := len(releases) - 1; i >= 0; i-- {
+		tags = append(tags, sources.Tag{Name: releases[i].Tag, Commit: releases[i].Commit, Published: releases[i].Published})
+	}
+	return tags
+}
+
+// upstream drops the upstream tags an injected one hides.
+func (s *source) upstream(owner string, repo string, tags []sources.Tag) []sources.Tag {
+	return slices.DeleteFunc(tags, func(tag sources.Tag) bool {
+		_, hidden := s.find(owner, repo, tag.Name)
+		return hidden
+	})
+}
+
+func (s *source) ListTags(ctx context.Context, owner string, repo string) ([]sources.Tag, error) {
+	tags := s.injectedTags(owner, repo)
+	if s.offline {
+		return append(tags, s.upstream(owner, repo, slices.Clone(s.current[owner+"/"+repo]))...), nil
+	}
+	upstream, err := s.VersionSource.ListTags(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	return append(tags, s.upstream(owner, repo, upstream)...), nil
+}
+
+func (s *source) ListTagPages(ctx context.Context, owner string, repo string) iter.Seq2[[]sources.Tag, error] {
+	return func(yield func([]sources.Tag, error) bool) {
+		if s.offline {
+			tags, err := s.ListTags(ctx, owner, repo)
+			yield(tags, err)
+			return
+		}
+		if tags := s.injectedTags(owner, repo); len(tags) > 0 && !yield(tags, nil) {
+			return
+		}
+		for page, err := range sources.TagPages(ctx, s.VersionSource, owner, repo) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(s.upstream(owner, repo, page), nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *source) GetRelease(ctx context.Context, owner string, repo string, tag string) (*sources.Release, error) {
+	if release, ok := s.find(owner, repo, tag); ok {
+		return &sources.Release{Tag: release.Tag, Name: release.Tag, Body: release.Notes, Prerelease: release.Prerelease, PublishedAt: release.Published}, nil
+	}
+	if s.offline {
+		return nil, fmt.Errorf("no release %s in the scenario: %w", tag, errOffline)
+	}
+	return s.VersionSource.GetRelease(ctx, owner, repo, tag)
+}
+
+func (s *source) ResolveRef(ctx context.Context, owner string, repo string, ref string) (string, error) {
+	if release, ok := s.find(owner, repo, ref); ok {
+		return release.Commit, nil
+	}
+	if s.offline {
+		for _, tag := range s.current[owner+"/"+repo] {
+			if tag.Name == ref {
+				return tag.Commit, nil
+			}
+		}
+		return "", fmt.Errorf("no ref %s in the scenario: %w", ref, errOffline)
+	}
+	return s.VersionSource.ResolveRef(ctx, owner, repo, ref)
+}
+
+// ReadFile reads the files of injected releases at the current tag of their dependency.
+func (s *source) ReadFile(ctx context.Context, owner string, repo string, ref string, path string) ([]byte, error) {
+	if s.offline {
+		return nil, errOffline
+	}
+	if release, ok := s.find(owner, repo, ref); ok {
+		ref = release.base
+	}
+	return sources.ReadFile(ctx, s.VersionSource, owner, repo, ref, path)
+}
+
+func (s *source) CompareCommits(ctx context.Context, owner string, repo string, base string, head string) (*sources.Comparison, error) {
+	_, injectedBase := s.find(owner, repo, base)
+	_, injectedHead := s.find(owner, repo, head)
+	if s.offline || injectedBase || injectedHead {
+		return nil, fmt.Errorf("no commits of simulated releases: %w", errors.ErrUnsupported)
+	}
+	return sources.CompareCommits(ctx, s.VersionSource, owner, repo, base, head)
+}
@@ -0,0 +1,157 @@
+package simulate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/policy"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// fakeSource serves fixed upstream tags.
+type fakeSource struct {
+	tags []sources.Tag
+}
+
+func (s *fakeSource) ListTags(ctx context.Context, owner string, repo string) ([]sources.Tag, error) {
+	return s.tags, nil
+}
+
+func (s *fakeSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*sources.Release, error) {
+	return &sources.Release{Tag: tag}, nil
+}
+
+func (s *fakeSource) ResolveRef(ctx context.Context, owner string, repo string, ref string) (string, error) {
+	for _, tag := range s.tags {
+		if tag.Name == ref {
+			return tag.Commit, nil
+		}
+	}
+	return "", errors.New("unknown ref " + ref)
+}
+
+func (s *fakeSource) RepoURL(owner string, repo string) string {
+	return "https://forge.example/" + owner + "/" + repo
+}
+
+func (s *fakeSource) CompareURL(owner string, repo string, from string, to string) string {
+	return s.RepoURL(owner, repo) + "/compare/" + from + "..." + to
+}
+
+func TestParseScenario(t *testing.T) {
+	scenario, err := ParseScenario([]byte(`# a future release
+offline: true
+releases:
+  - dependency: reth
+    tag: v1.3.0
+    published: 2026-11-01
+    notes: "Breaking: drops \"--legacy\"" # quoted
+  - dependency: reth
+    tag: v1.4.0-rc.1 # comment
+    commit: c140rc1
+    published: 2026-11-02T10:00:00Z
+    prerelease: true
+`))
+	if err != nil {
+		t.Fatalf("ParseScenario() unexpected error: %v", err)
+	}
+	if !scenario.Offline || len(scenario.Releases) != 2 {
+		t.Fatalf("ParseScenario() = %+v, want offline with 2 releases", scenario)
+	}
+	first, second := scenario.Releases[0], scenario.Releases[1]
+	if first.Tag != "v1.3.0" || first.Notes != `Breaking: drops "--legacy"` || !first.Published.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("first release = %+v", first)
+	}
+	if second.Tag != "v1.4.0-rc.1" || second.Commit != "c140rc1" || !second.Prerelease || second.Published.Hour() != 10 {
+		t.Errorf("second release = %+v", second)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown key", "freeze: true\n", `line 1: unknown key "freeze"`},
+		{"invalid offline", "offline: maybe\n", "line 1: offline must be true or false"},
+		{"not a key", "releases:\n  - reth\n", "line 2: expected key: value"},
+		{"key before item", "releases:\n    tag: v1.0.0\n", "line 2: release keys must follow"},
+		{"unknown release key", "releases:\n  - dependency: reth\n    soak: 3d\n", `line 3: unknown release key "soak"`},
+		{"invalid published", "releases:\n  - dependency: reth\n    published: tomorrow\n", `line 3: invalid published "tomorrow"`},
+		{"missing tag", "releases:\n  - dependency: reth\n", "release 1 needs a dependency and a tag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScenario([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseScenario() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	upstream := &fakeSource{tags: []sources.Tag{{Name: "v1.2.0", Commit: "c120"}, {Name: "v1.1.0", Commit: "c110"}}}
+	scenario := &Scenario{Releases: []Release{
+		{Dependency: "reth", Tag: "v1.3.0", Notes: "Breaking: drops --legacy"},
+		{Dependency: "reth", Tag: "v1.4.0-rc.1", Commit: "c140rc1", Prerelease: true},
+	}}
+
+	tests := []struct {
+		name        string
+		offline     bool
+		tracking    string
+		wantVersion string
+		wantTags    int
+	}{
+		{"release tracking", false, "release", "v1.3.0", 4},
+		{"tag tracking takes the rc", false, "tag", "v1.4.0-rc.1", 4},
+		{"offline", true, "release", "v1.3.0", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dependencies := version.Dependencies{
+				"reth": {Tag: "v1.1.0", Commit: "c110", Owner: "paradigmxyz", Repo: "reth", Tracking: tt.tracking, Source: "fake"},
+			}
+			set := sources.NewSet(sources.Options{})
+			set.Add(map[string]sources.VersionSource{"fake": upstream})
+			scenario.Offline = tt.offline
+			if err := scenario.Inject(set, dependencies); err != nil {
+				t.Fatalf("Inject() unexpected error: %v", err)
+			}
+			source, err := set.For("fake")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tags, err := source.ListTags(context.Background(), "paradigmxyz", "reth")
+			if err != nil {
+				t.Fatalf("ListTags() unexpected error: %v", err)
+			}
+			if len(tags) != tt.wantTags || tags[0].Name != "v1.4.0-rc.1" {
+				t.Errorf("ListTags() = %v, want %d tags, the scenario's last release first", tags, tt.wantTags)
+			}
+			planned, err := policy.Resolve(context.Background(), source, "reth", dependencies["reth"])
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if planned == nil || planned.Version != tt.wantVersion {
+				t.Fatalf("Resolve() = %+v, want %s", planned, tt.wantVersion)
+			}
+			release, err := source.GetRelease(context.Background(), "paradigmxyz", "reth", "v1.3.0")
+			if err != nil || release.Body != "Breaking: drops --legacy" {
+				t.Errorf("GetRelease() = %+v, %v, want the scenario's notes", release, err)
+			}
+			if _, err := sources.CompareCommits(context.Background(), source, "paradigmxyz", "reth", "v1.1.0", "v1.3.0"); !errors.Is(err, errors.ErrUnsupported) {
+				t.Errorf("CompareCommits() error = %v, want ErrUnsupported", err)
+			}
+		})
+	}
+
+	unknown := &Scenario{Releases: []Release{{Dependency: "geth", Tag: "v1.0.0"}}}
+	if err := unknown.Inject(sources.NewSet(sources.Options{}), version.Dependencies{}); err == nil {
+		t.Error("Inject() of an unknown dependency succeeded, want an error")
+	}
+}
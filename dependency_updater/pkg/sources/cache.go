@@ -0,0 +1,139 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheSource keeps the tags and releases of the wrapped source on disk, so frequent runs
+// don't fetch every tag page again. Refs are always resolved live, branches move.
+type cacheSource struct {
+	VersionSource
+	dir     string
+	ttl     time.Duration
+	refresh bool
+}
+
+type cacheEntry[T any] struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	Value     T         `json:"value"`
+}
+
+// cachedTags is a cached tag listing. A stream the caller stopped early only caches the
+// pages it read, Complete is false then.
+type cachedTags struct {
+	Tags     []Tag `json:"tags"`
+	Complete bool  `json:"complete"`
+}
+
+func (s cacheSource) tagsPath(owner string, repo string) string {
+	return filepath.Join(s.dir, owner, repo, "tags.json")
+}
+
+func (s cacheSource) ListTags(ctx context.Context, owner string, repo string) ([]Tag, error) {
+	var all []Tag
+	for page, err := range s.ListTagPages(ctx, owner, repo) {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+	}
+	return all, nil
+}
+
+// ListTagPages serves the cached tags as the first page. If the cached listing is partial
+// and the caller wants more, the source is streamed again past the cached tags.
+func (s cacheSource) ListTagPages(ctx context.Context, owner string, repo string) iter.Seq2[[]Tag, error] {
+	return func(yield func([]Tag, error) bool) {
+		path := s.tagsPath(owner, repo)
+		var entry cacheEntry[cachedTags]
+		if !s.refresh {
+			if f, err := os.ReadFile(path); err != nil || json.Unmarshal(f, &entry) != nil || time.Since(entry.FetchedAt) >= s.ttl {
+				entry = cacheEntry[cachedTags]{}
+			}
+		}
+		if entry.Value.Complete {
+			yield(entry.Value.Tags, nil)
+			return
+		}
+		if len(entry.Value.Tags) > 0 && !yield(entry.Value.Tags, nil) {
+			return
+		}
+
+		skip := len(entry.Value.Tags)
+		fetched := cachedTags{Complete: true}
+		failed := false
+		defer func() {
+			// Keep the longer listing, a stream stopped within the cached tags adds nothing.
+			if failed || len(fetched.Tags) < len(entry.Value.Tags) {
+				return
+			}
+			if err := writeCacheEntry(path, cacheEntry[cachedTags]{FetchedAt: time.Now(), Value: fetched}); err != nil {
+				log.Printf("Error caching %s: %s", path, err)
+			}
+		}()
+		for page, err := range TagPages(ctx, s.VersionSource, owner, repo) {
+			if err != nil {
+				failed = true
+				yield(nil, err)
+				return
+			}
+			fetched.Tags = append(fetched.Tags, page...)
+			if skip >= len(page) {
+				skip -= len(page)
+				continue
+			}
+			page, skip = page[skip:], 0
+			if !yield(page, nil) {
+				fetched.Complete = false
+				return
+			}
+		}
+	}
+}
+
+func (s cacheSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*Release, error) {
+	return cached(s, filepath.Join(s.dir, owner, repo, "releases", url.PathEscape(tag)+".json"), func() (*Release, error) {
+		return s.VersionSource.GetRelease(ctx, owner, repo, tag)
+	})
+}
+
+// cached returns the entry at path if it is younger than the TTL, and otherwise fetches and
+// stores it. Unreadable entries count as misses and failing to store one is only logged.
+func cached[T any](s cacheSource, path string, fetch func() (T, error)) (T, error) {
+	if !s.refresh {
+		var entry cacheEntry[T]
+		if f, err := os.ReadFile(path); err == nil && json.Unmarshal(f, &entry) == nil && time.Since(entry.FetchedAt) < s.ttl {
+			return entry.Value, nil
+		}
+	}
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+	if err := writeCacheEntry(path, cacheEntry[T]{FetchedAt: time.Now(), Value: value}); err != nil {
+		log.Printf("Error caching %s: %s", path, err)
+	}
+	return value, nil
+}
+
+func writeCacheEntry(path string, entry any) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
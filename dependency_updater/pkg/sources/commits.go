@@ -0,0 +1,60 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+)
+
+// Comparison is the commit range between two refs.
+type Comparison struct {
+	// TotalCommits is how many commits head is ahead of base, Commits may list fewer.
+	TotalCommits int      `json:"totalCommits"`
+	Commits      []Commit `json:"commits"`
+}
+
+type Commit struct {
+	SHA string `json:"sha"`
+	// Message is the first line of the commit message.
+	Message string `json:"message"`
+}
+
+// CommitComparer is implemented by sources that can list the commits between two refs.
+type CommitComparer interface {
+	CompareCommits(ctx context.Context, owner string, repo string, base string, head string) (*Comparison, error)
+}
+
+// CompareCommits returns the commits between base and head. It fails with
+// errors.ErrUnsupported if source doesn't implement CommitComparer.
+func CompareCommits(ctx context.Context, source VersionSource, owner string, repo string, base string, head string) (*Comparison, error) {
+	comparer, ok := source.(CommitComparer)
+	if !ok {
+		return nil, fmt.Errorf("source can't compare commits: %w", errors.ErrUnsupported)
+	}
+	return comparer.CompareCommits(ctx, owner, repo, base, head)
+}
+
+func (s rateLimitedSource) CompareCommits(ctx context.Context, owner string, repo string, base string, head string) (*Comparison, error) {
+	if err := s.bucket.wait(ctx); err != nil {
+		return nil, err
+	}
+	return CompareCommits(ctx, s.VersionSource, owner, repo, base, head)
+}
+
+func (s timeoutSource) CompareCommits(ctx context.Context, owner string, repo string, base string, head string) (*Comparison, error) {
+	var comparison *Comparison
+	err := s.call(ctx, "CompareCommits", func(ctx context.Context) (err error) {
+		comparison, err = CompareCommits(ctx, s.VersionSource, owner, repo, base, head)
+		return err
+	})
+	return comparison, err
+}
+
+// CompareCommits caches comparisons by their refs, between tags they rarely change.
+func (s cacheSource) CompareCommits(ctx context.Context, owner string, repo string, base string, head string) (*Comparison, error) {
+	return cached(s, filepath.Join(s.dir, owner, repo, "compare", url.PathEscape(base+"..."+head)+".json"), func() (*Comparison, error) {
+		return CompareCommits(ctx, s.VersionSource, owner, repo, base, head)
+	})
+}
@@ -0,0 +1,50 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+)
+
+// ErrNotFound is returned by ReadFile for files that don't exist at the ref.
+var ErrNotFound = errors.New("not found")
+
+// FileReader is implemented by sources that can read a repository's files at a ref.
+type FileReader interface {
+	ReadFile(ctx context.Context, owner string, repo string, ref string, path string) ([]byte, error)
+}
+
+// ReadFile reads the file at path in the repository at ref. It fails with
+// errors.ErrUnsupported if source doesn't implement FileReader.
+func ReadFile(ctx context.Context, source VersionSource, owner string, repo string, ref string, path string) ([]byte, error) {
+	reader, ok := source.(FileReader)
+	if !ok {
+		return nil, fmt.Errorf("source can't read repository files: %w", errors.ErrUnsupported)
+	}
+	return reader.ReadFile(ctx, owner, repo, ref, path)
+}
+
+func (s rateLimitedSource) ReadFile(ctx context.Context, owner string, repo string, ref string, path string) ([]byte, error) {
+	if err := s.bucket.wait(ctx); err != nil {
+		return nil, err
+	}
+	return ReadFile(ctx, s.VersionSource, owner, repo, ref, path)
+}
+
+func (s timeoutSource) ReadFile(ctx context.Context, owner string, repo string, ref string, path string) ([]byte, error) {
+	var content []byte
+	err := s.call(ctx, "ReadFile", func(ctx context.Context) (err error) {
+		content, err = ReadFile(ctx, s.VersionSource, owner, repo, ref, path)
+		return err
+	})
+	return content, err
+}
+
+// ReadFile caches files by ref, for tags they rarely change.
+func (s cacheSource) ReadFile(ctx context.Context, owner string, repo string, ref string, path string) ([]byte, error) {
+	return cached(s, filepath.Join(s.dir, owner, repo, "files", url.PathEscape(ref), url.PathEscape(path)+".json"), func() ([]byte, error) {
+		return ReadFile(ctx, s.VersionSource, owner, repo, ref, path)
+	})
+}
@@ -0,0 +1,136 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v72/github"
+)
+
+func init() {
+	Register("github", func(opts Options) (VersionSource, error) {
+		client := github.NewClient(opts.HTTPClient).WithAuthToken(opts.GithubToken)
+		if opts.GithubURL != "" {
+			baseURL, err := url.Parse(strings.TrimSuffix(opts.GithubURL, "/") + "/")
+			if err != nil {
+				return nil, fmt.Errorf("invalid GitHub API URL %s: %s", opts.GithubURL, err)
+			}
+			client.BaseURL = baseURL
+		}
+		return &githubSource{client: client}, nil
+	})
+}
+
+type githubSource struct {
+	client *github.Client
+}
+
+func (s *githubSource) ListTags(ctx context.Context, owner string, repo string) ([]Tag, error) {
+	var all []Tag
+	for page, err := range s.ListTagPages(ctx, owner, repo) {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+	}
+	return all, nil
+}
+
+func (s *githubSource) ListTagPages(ctx context.Context, owner string, repo string) iter.Seq2[[]Tag, error] {
+	return func(yield func([]Tag, error) bool) {
+		options := &github.ListOptions{Page: 1, PerPage: 100}
+		for {
+			if err := beforeRequest(ctx); err != nil {
+				yield(nil, err)
+				return
+			}
+			tags, resp, err := s.client.Repositories.ListTags(ctx, owner, repo, options)
+			if err != nil {
+				yield(nil, fmt.Errorf("error getting tags: %s", err))
+				return
+			}
+			page := make([]Tag, 0, len(tags))
+			for _, tag := range tags {
+				page = append(page, Tag{Name: tag.GetName(), Commit: tag.GetCommit().GetSHA()})
+			}
+			if !yield(page, nil) || resp.NextPage == 0 {
+				return
+			}
+			options.Page = resp.NextPage
+		}
+	}
+}
+
+func (s *githubSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*Release, error) {
+	release, _, err := s.client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+	if err != nil {
+		return nil, fmt.Errorf("error getting release %s: %s", tag, err)
+	}
+	var assets []Asset
+	for _, asset := range release.Assets {
+		assets = append(assets, Asset{Name: asset.GetName(), URL: asset.GetBrowserDownloadURL()})
+	}
+	return &Release{
+		Tag:         release.GetTagName(),
+		Name:        release.GetName(),
+		Body:        release.GetBody(),
+		URL:         release.GetHTMLURL(),
+		Prerelease:  release.GetPrerelease(),
+		PublishedAt: release.GetPublishedAt().Time,
+		Assets:      assets,
+	}, nil
+}
+
+func (s *githubSource) ResolveRef(ctx context.Context, owner string, repo string, ref string) (string, error) {
+	commits, _, err := s.client.Repositories.ListCommits(ctx, owner, repo, &github.CommitsListOptions{SHA: ref})
+	if err != nil {
+		return "", fmt.Errorf("error listing commits for %s: %s", ref, err)
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("no commits found for %s", ref)
+	}
+	return commits[0].GetSHA(), nil
+}
+
+func (s *githubSource) RepoURL(owner string, repo string) string {
+	return "https://github.com/" + owner + "/" + repo
+}
+
+func (s *githubSource) CompareURL(owner string, repo string, from string, to string) string {
+	return s.RepoURL(owner, repo) + "/compare/" + from + "..." + to
+}
+
+func (s *githubSource) ReadFile(ctx context.Context, owner string, repo string, ref string, path string) ([]byte, error) {
+	file, _, resp, err := s.client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s at %s: %w", path, ref, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s at %s: %s", path, ref, err)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%s at %s is a directory", path, ref)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s at %s: %s", path, ref, err)
+	}
+	return []byte(content), nil
+}
+
+func (s *githubSource) CompareCommits(ctx context.Context, owner string, repo string, base string, head string) (*Comparison, error) {
+	comparison, _, err := s.client.Repositories.CompareCommits(ctx, owner, repo, base, head, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, fmt.Errorf("error comparing %s...%s: %s", base, head, err)
+	}
+	result := &Comparison{TotalCommits: comparison.GetTotalCommits()}
+	for _, commit := range comparison.Commits {
+		message, _, _ := strings.Cut(commit.GetCommit().GetMessage(), "\n")
+		result.Commits = append(result.Commits, Commit{SHA: commit.GetSHA(), Message: message})
+	}
+	return result, nil
+}
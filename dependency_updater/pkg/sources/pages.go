@@ -0,0 +1,24 @@
+package sources
+
+import (
+	"context"
+	"iter"
+)
+
+// TagPager is implemented by sources that can list tags a page at a time, so callers can
+// stop paging once they have seen enough. Pages should come newest first where the forge
+// allows it. Each page request must be preceded by beforeRequest.
+type TagPager interface {
+	ListTagPages(ctx context.Context, owner string, repo string) iter.Seq2[[]Tag, error]
+}
+
+// TagPages streams the tags of a repository from source, as a single page if it does not
+// implement TagPager. A failing page ends the stream with its error.
+func TagPages(ctx context.Context, source VersionSource, owner string, repo string) iter.Seq2[[]Tag, error] {
+	if pager, ok := source.(TagPager); ok {
+		return pager.ListTagPages(ctx, owner, repo)
+	}
+	return func(yield func([]Tag, error) bool) {
+		yield(source.ListTags(ctx, owner, repo))
+	}
+}
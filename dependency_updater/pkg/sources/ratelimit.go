@@ -0,0 +1,134 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is a request budget of Count requests per Per.
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
+
+// ParseRates parses rate limit flags of the form <source>=<count>/<unit>, such as
+// github=5000/h. Units are s, m and h.
+func ParseRates(values []string) (map[string]Rate, error) {
+	rates := map[string]Rate{}
+	for _, value := range values {
+		name, limit, ok := strings.Cut(value, "=")
+		count, unit, ok2 := strings.Cut(limit, "/")
+		per, ok3 := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}[unit]
+		n, err := strconv.Atoi(count)
+		if !ok || !ok2 || !ok3 || err != nil || n <= 0 || name == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, expected <source>=<count>/<s|m|h>", value)
+		}
+		rates[name] = Rate{Count: n, Per: per}
+	}
+	return rates, nil
+}
+
+// bucket is a token bucket shared by every call to one source. It holds up to a minute's
+// worth of requests, at least one, so short bursts go through at once.
+type bucket struct {
+	mu       sync.Mutex
+	interval time.Duration
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newBucket(rate Rate) *bucket {
+	interval := rate.Per / time.Duration(rate.Count)
+	capacity := max(1, float64(time.Minute/interval))
+	return &bucket{interval: interval, capacity: capacity, tokens: capacity, last: time.Now()}
+}
+
+// wait takes a token, blocking until one is available or ctx is done.
+func (b *bucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.capacity, b.tokens+float64(now.Sub(b.last))/float64(b.interval))
+	b.last = now
+	b.tokens--
+	// A negative balance reserves the next token, later callers queue behind it.
+	delay := time.Duration(-b.tokens * float64(b.interval))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// rateLimitedSource takes a token from the source's bucket before every request it makes.
+type rateLimitedSource struct {
+	VersionSource
+	bucket *bucket
+}
+
+func (s rateLimitedSource) ListTags(ctx context.Context, owner string, repo string) ([]Tag, error) {
+	var all []Tag
+	for page, err := range s.ListTagPages(ctx, owner, repo) {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+	}
+	return all, nil
+}
+
+// ListTagPages takes a token right before each page request, so a caller stopping after a
+// page, or the last page, costs no extra token. Sources that do not page make one request.
+func (s rateLimitedSource) ListTagPages(ctx context.Context, owner string, repo string) iter.Seq2[[]Tag, error] {
+	if pager, ok := s.VersionSource.(TagPager); ok {
+		return pager.ListTagPages(context.WithValue(ctx, requestHookKey{}, s.bucket.wait), owner, repo)
+	}
+	return func(yield func([]Tag, error) bool) {
+		if err := s.bucket.wait(ctx); err != nil {
+			yield(nil, err)
+			return
+		}
+		yield(s.VersionSource.ListTags(ctx, owner, repo))
+	}
+}
+
+type requestHookKey struct{}
+
+// beforeRequest must be called by TagPager implementations before each page request, it
+// blocks while a rate limit applying to the listing is used up.
+func beforeRequest(ctx context.Context) error {
+	if hook, ok := ctx.Value(requestHookKey{}).(func(context.Context) error); ok {
+		return hook(ctx)
+	}
+	return nil
+}
+
+func (s rateLimitedSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*Release, error) {
+	if err := s.bucket.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.VersionSource.GetRelease(ctx, owner, repo, tag)
+}
+
+func (s rateLimitedSource) ResolveRef(ctx context.Context, owner string, repo string, ref string) (string, error) {
+	if err := s.bucket.wait(ctx); err != nil {
+		return "", err
+	}
+	return s.VersionSource.ResolveRef(ctx, owner, repo, ref)
+}
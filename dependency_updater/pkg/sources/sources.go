@@ -0,0 +1,287 @@
+// Package sources fetches the tags, releases and refs of dependencies from forges and registries.
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default is used for dependencies in versions.json without a "source" field.
+const Default = "github"
+
+// Tag is a tag and the commit it points to.
+type Tag struct {
+	Name   string `json:"name"`
+	Commit string `json:"commit"`
+	// Published is when the tag's release was published, zero if the listing doesn't say.
+	// Tag selection orders tags of the same version, and nightlies of the same day, by it.
+	Published time.Time `json:"published,omitzero"`
+}
+
+// Release is the published release for a tag.
+type Release struct {
+	Tag         string    `json:"tag"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	URL         string    `json:"url"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Assets      []Asset   `json:"assets,omitempty"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// VersionSource fetches the tags, releases and refs of a dependency from a forge or registry.
+// Candidate selection only works on what a source returns, so adding a forge only needs a
+// new implementation registered with Register.
+type VersionSource interface {
+	// ListTags returns all tags of the repository.
+	ListTags(ctx context.Context, owner string, repo string) ([]Tag, error)
+	// GetRelease returns the release published for tag.
+	GetRelease(ctx context.Context, owner string, repo string, tag string) (*Release, error)
+	// ResolveRef returns the commit a branch or other ref currently points to.
+	ResolveRef(ctx context.Context, owner string, repo string, ref string) (string, error)
+	// RepoURL is the browsable URL of the repository, cloning appends ".git".
+	RepoURL(owner string, repo string) string
+	// CompareURL links the changes between two refs.
+	CompareURL(owner string, repo string, from string, to string) string
+}
+
+// Options carries the credentials sources may need and bounds their calls.
+type Options struct {
+	GithubToken string
+	// HTTPClient sends the requests of HTTP sources, http.DefaultClient if nil. Tests replay
+	// recorded responses through it.
+	HTTPClient *http.Client
+	// GithubURL is the base URL of the GitHub API, https://api.github.com/ if empty, such as
+	// a GitHub Enterprise Server's or a mock server's.
+	GithubURL string
+	// Timeout bounds every call to a source, unless Timeouts has an entry for it. Zero means no limit.
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
+	// CacheDir persists tags and releases between runs for CacheTTL, or CacheTTLs for a
+	// source. Refresh ignores cached entries but still updates them. An empty CacheDir or a
+	// zero TTL disables caching.
+	CacheDir  string
+	CacheTTL  time.Duration
+	CacheTTLs map[string]time.Duration
+	Refresh   bool
+	// RateLimits bounds the requests to each named source. Every call through the Set, from
+	// any goroutine, shares the source's budget.
+	RateLimits map[string]Rate
+}
+
+type Factory func(opts Options) (VersionSource, error)
+
+var factories = map[string]Factory{}
+
+// Register makes a source available to versions.json entries under name. It is meant to be
+// called from init and panics if name is already registered.
+func Register(name string, factory Factory) {
+	if _, ok := factories[name]; ok {
+		panic("version source " + name + " registered twice")
+	}
+	factories[name] = factory
+}
+
+// Registered reports whether a source is registered under name.
+func Registered(name string) bool {
+	_, ok := factories[name]
+	return ok
+}
+
+// Set creates each source once, the first time a dependency uses it. It is safe for
+// concurrent use.
+type Set struct {
+	mu      sync.Mutex
+	opts    Options
+	sources map[string]VersionSource
+	buckets map[string]*bucket
+}
+
+func NewSet(opts Options) *Set {
+	return &Set{opts: opts, sources: map[string]VersionSource{}, buckets: map[string]*bucket{}}
+}
+
+// For returns the source named by a versions.json entry's "source" field, Default if empty.
+func (s *Set) For(name string) (VersionSource, error) {
+	if name == "" {
+		name = Default
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if source, ok := s.sources[name]; ok {
+		return source, nil
+	}
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown version source %q, expected one of %s", name, strings.Join(s.names(), ", "))
+	}
+	source, err := factory(s.opts)
+	if err != nil {
+		return nil, fmt.Errorf("error creating version source %s: %s", name, err)
+	}
+	s.sources[name] = s.wrap(name, source)
+	return s.sources[name], nil
+}
+
+// Add makes sources that are not registered, such as plugins, available by name.
+func (s *Set) Add(sources map[string]VersionSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, source := range sources {
+		s.sources[name] = s.wrap(name, source)
+	}
+}
+
+// Intercept replaces the source named name, created if needed, with intercept's wrapper of
+// it. The wrapper sits outside the source's rate limit, timeout and cache, so nothing it adds
+// is cached.
+func (s *Set) Intercept(name string, intercept func(VersionSource) VersionSource) error {
+	if name == "" {
+		name = Default
+	}
+	source, err := s.For(name)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources[name] = intercept(source)
+	return nil
+}
+
+// wrap applies the rate limit, timeout and cache options to a source, the cache outermost so
+// hits neither wait on the source nor use up its rate limit. The caller holds s.mu.
+func (s *Set) wrap(name string, source VersionSource) VersionSource {
+	if rate, ok := s.opts.RateLimits[name]; ok {
+		if s.buckets[name] == nil {
+			s.buckets[name] = newBucket(rate)
+		}
+		source = rateLimitedSource{VersionSource: source, bucket: s.buckets[name]}
+	}
+	if timeout := perSource(name, s.opts.Timeout, s.opts.Timeouts); timeout > 0 {
+		source = timeoutSource{VersionSource: source, name: name, timeout: timeout}
+	}
+	if ttl := perSource(name, s.opts.CacheTTL, s.opts.CacheTTLs); ttl > 0 && s.opts.CacheDir != "" {
+		source = cacheSource{VersionSource: source, dir: filepath.Join(s.opts.CacheDir, name), ttl: ttl, refresh: s.opts.Refresh}
+	}
+	return source
+}
+
+func perSource(name string, all time.Duration, byName map[string]time.Duration) time.Duration {
+	if d, ok := byName[name]; ok {
+		return d
+	}
+	return all
+}
+
+func (s *Set) names() []string {
+	var names []string
+	for name := range factories {
+		names = append(names, name)
+	}
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// ParseDurations parses per-source flags such as timeouts and cache TTLs, each either a
+// duration applying to all sources or <source>=<duration> for one source.
+func ParseDurations(values []string) (time.Duration, map[string]time.Duration, error) {
+	var all time.Duration
+	perSource := map[string]time.Duration{}
+	for _, value := range values {
+		name, d, ok := strings.Cut(value, "=")
+		if !ok {
+			name, d = "", value
+		}
+		timeout, err := time.ParseDuration(d)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid source duration %q: %s", value, err)
+		}
+		if name == "" {
+			all = timeout
+		} else {
+			perSource[name] = timeout
+		}
+	}
+	return all, perSource, nil
+}
+
+// timeoutSource bounds each call of the wrapped source, so a stuck request fails with an
+// error naming the source instead of hanging the run.
+type timeoutSource struct {
+	VersionSource
+	name    string
+	timeout time.Duration
+}
+
+func (s timeoutSource) call(parent context.Context, op string, f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(parent, s.timeout)
+	defer cancel()
+	return s.wrapErr(parent, ctx, op, f(ctx))
+}
+
+// wrapErr names the source in err if ctx hit the source's own deadline, a cancelled or
+// expired run is the caller's.
+func (s timeoutSource) wrapErr(parent context.Context, ctx context.Context, op string, err error) error {
+	if err != nil && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s %s timed out after %s: %s", s.name, op, s.timeout, err)
+	}
+	return err
+}
+
+func (s timeoutSource) ListTags(ctx context.Context, owner string, repo string) ([]Tag, error) {
+	var tags []Tag
+	err := s.call(ctx, "ListTags", func(ctx context.Context) (err error) {
+		tags, err = s.VersionSource.ListTags(ctx, owner, repo)
+		return err
+	})
+	return tags, err
+}
+
+// ListTagPages bounds the whole stream, not each page.
+func (s timeoutSource) ListTagPages(parent context.Context, owner string, repo string) iter.Seq2[[]Tag, error] {
+	return func(yield func([]Tag, error) bool) {
+		ctx, cancel := context.WithTimeout(parent, s.timeout)
+		defer cancel()
+		for page, err := range TagPages(ctx, s.VersionSource, owner, repo) {
+			if !yield(page, s.wrapErr(parent, ctx, "ListTags", err)) {
+				return
+			}
+		}
+	}
+}
+
+func (s timeoutSource) GetRelease(ctx context.Context, owner string, repo string, tag string) (*Release, error) {
+	var release *Release
+	err := s.call(ctx, "GetRelease", func(ctx context.Context) (err error) {
+		release, err = s.VersionSource.GetRelease(ctx, owner, repo, tag)
+		return err
+	})
+	return release, err
+}
+
+func (s timeoutSource) ResolveRef(ctx context.Context, owner string, repo string, ref string) (string, error) {
+	var commit string
+	err := s.call(ctx, "ResolveRef", func(ctx context.Context) (err error) {
+		commit, err = s.VersionSource.ResolveRef(ctx, owner, repo, ref)
+		return err
+	})
+	return commit, err
+}
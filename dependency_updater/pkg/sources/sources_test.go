@@ -0,0 +1,327 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"iter"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/base/node/dependency_updater/pkg/httpreplay"
+)
+
+var record = flag.Bool("record", false, "record the cassettes in testdata from the real services")
+
+type nopSource struct{}
+
+func (nopSource) ListTags(context.Context, string, string) ([]Tag, error) { return nil, nil }
+func (nopSource) GetRelease(context.Context, string, string, string) (*Release, error) {
+	return nil, nil
+}
+func (nopSource) ResolveRef(context.Context, string, string, string) (string, error) { return "", nil }
+func (nopSource) RepoURL(string, string) string                                      { return "" }
+func (nopSource) CompareURL(string, string, string, string) string                   { return "" }
+
+// slowSource blocks every tag listing until ctx is done.
+type slowSource struct{ nopSource }
+
+func (slowSource) ListTags(ctx context.Context, _ string, _ string) ([]Tag, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSet(t *testing.T) {
+	set := NewSet(Options{})
+	if _, err := set.For(""); err != nil {
+		t.Errorf("For() with the default source unexpected error: %v", err)
+	}
+	if _, err := set.For("missing"); err == nil {
+		t.Errorf("For() with an unregistered source expected error")
+	}
+	set.Add(map[string]VersionSource{"plugin": nopSource{}})
+	if source, err := set.For("plugin"); err != nil || source != (nopSource{}) {
+		t.Errorf("For() with an added source = %v, %v", source, err)
+	}
+	if !Registered("github") || Registered("plugin") {
+		t.Errorf("Registered() should only report built-in sources")
+	}
+}
+
+func TestParseDurations(t *testing.T) {
+	all, perSource, err := ParseDurations([]string{"1m", "plugin=5s"})
+	if err != nil || all != time.Minute || perSource["plugin"] != 5*time.Second {
+		t.Errorf("ParseDurations() = %v, %v, %v", all, perSource, err)
+	}
+	if _, _, err := ParseDurations([]string{"plugin=soon"}); err == nil {
+		t.Errorf("ParseDurations() with an invalid duration expected error")
+	}
+}
+
+func TestTimeoutSource(t *testing.T) {
+	set := NewSet(Options{Timeout: time.Hour, Timeouts: map[string]time.Duration{"slow": 10 * time.Millisecond}})
+	set.Add(map[string]VersionSource{"slow": slowSource{}})
+	source, _ := set.For("slow")
+
+	_, err := source.ListTags(context.Background(), "owner", "repo")
+	if err == nil || !strings.Contains(err.Error(), "slow ListTags timed out") {
+		t.Errorf("ListTags() past the source timeout error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := source.ListTags(ctx, "owner", "repo"); err != context.Canceled {
+		t.Errorf("ListTags() with a cancelled run error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// countingSource counts the tag listings that reached it.
+type countingSource struct {
+	nopSource
+	calls *int
+}
+
+func (s countingSource) ListTags(context.Context, string, string) ([]Tag, error) {
+	*s.calls++
+	return []Tag{{Name: "v1.0.0", Commit: "aaa"}}, nil
+}
+
+func TestCacheSource(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+	list := func(opts Options) []Tag {
+		opts.CacheDir = dir
+		set := NewSet(opts)
+		set.Add(map[string]VersionSource{"counting": countingSource{calls: &calls}})
+		source, _ := set.For("counting")
+		tags, err := source.ListTags(context.Background(), "owner", "repo")
+		if err != nil {
+			t.Fatalf("ListTags() unexpected error: %v", err)
+		}
+		return tags
+	}
+
+	tests := []struct {
+		name  string
+		opts  Options
+		calls int
+	}{
+		{"miss", Options{CacheTTL: time.Hour}, 1},
+		{"hit", Options{CacheTTL: time.Hour}, 1},
+		{"refresh", Options{CacheTTL: time.Hour, Refresh: true}, 2},
+		{"expired for source", Options{CacheTTL: time.Hour, CacheTTLs: map[string]time.Duration{"counting": time.Nanosecond}}, 3},
+		{"disabled", Options{}, 4},
+	}
+	for _, tt := range tests {
+		if tags := list(tt.opts); len(tags) != 1 || tags[0].Name != "v1.0.0" {
+			t.Errorf("%s: ListTags() = %v", tt.name, tags)
+		}
+		if calls != tt.calls {
+			t.Errorf("%s: source called %d times, want %d", tt.name, calls, tt.calls)
+		}
+	}
+}
+
+// pagedSource streams two tag pages and counts the pages fetched.
+type pagedSource struct {
+	nopSource
+	fetched *int
+}
+
+func (s pagedSource) ListTagPages(ctx context.Context, _ string, _ string) iter.Seq2[[]Tag, error] {
+	return func(yield func([]Tag, error) bool) {
+		for _, page := range [][]Tag{{{Name: "v2.0.0"}}, {{Name: "v1.0.0"}}} {
+			if err := beforeRequest(ctx); err != nil {
+				yield(nil, err)
+				return
+			}
+			*s.fetched++
+			if !yield(page, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestCacheSourcePages(t *testing.T) {
+	fetched := 0
+	set := NewSet(Options{CacheDir: t.TempDir(), CacheTTL: time.Hour})
+	set.Add(map[string]VersionSource{"paged": pagedSource{fetched: &fetched}})
+	source, _ := set.For("paged")
+
+	for range TagPages(context.Background(), source, "owner", "repo") {
+		break
+	}
+	if fetched != 1 {
+		t.Fatalf("stopping after the first page fetched %d pages", fetched)
+	}
+	for page := range TagPages(context.Background(), source, "owner", "repo") {
+		if page[0].Name != "v2.0.0" {
+			t.Errorf("cached first page = %v", page)
+		}
+		break
+	}
+	if fetched != 1 {
+		t.Errorf("cached first page fetched again")
+	}
+
+	tags, err := source.ListTags(context.Background(), "owner", "repo")
+	if err != nil || len(tags) != 2 || tags[1].Name != "v1.0.0" {
+		t.Fatalf("ListTags() past the cached pages = %v, %v", tags, err)
+	}
+	if _, err := source.ListTags(context.Background(), "owner", "repo"); err != nil || fetched != 3 {
+		t.Errorf("complete listing not cached, fetched %d pages", fetched)
+	}
+}
+
+func TestParseRates(t *testing.T) {
+	rates, err := ParseRates([]string{"github=5000/h", "registry=10/s"})
+	if err != nil || rates["github"] != (Rate{Count: 5000, Per: time.Hour}) || rates["registry"] != (Rate{Count: 10, Per: time.Second}) {
+		t.Errorf("ParseRates() = %v, %v", rates, err)
+	}
+	for _, value := range []string{"github", "github=5000", "github=0/h", "github=5000/d", "=1/s"} {
+		if _, err := ParseRates([]string{value}); err == nil {
+			t.Errorf("ParseRates(%q) expected error", value)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	calls := 0
+	set := NewSet(Options{RateLimits: map[string]Rate{"counting": {Count: 1, Per: time.Hour}}})
+	set.Add(map[string]VersionSource{"counting": countingSource{calls: &calls}})
+	source, _ := set.For("counting")
+	if _, err := source.ListTags(context.Background(), "owner", "repo"); err != nil {
+		t.Fatalf("ListTags() within the budget unexpected error: %v", err)
+	}
+
+	// Replacing the source keeps its bucket, the budget is used up for every caller.
+	set.Add(map[string]VersionSource{"counting": countingSource{calls: &calls}})
+	source, _ = set.For("counting")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := source.ListTags(ctx, "owner", "repo"); err != context.DeadlineExceeded {
+		t.Errorf("ListTags() over the budget error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if calls != 1 {
+		t.Errorf("source called %d times, want 1", calls)
+	}
+
+	// Only pages actually requested take a token.
+	fetched := 0
+	set = NewSet(Options{RateLimits: map[string]Rate{"paged": {Count: 1, Per: time.Hour}}})
+	set.Add(map[string]VersionSource{"paged": pagedSource{fetched: &fetched}})
+	source, _ = set.For("paged")
+	for range TagPages(context.Background(), source, "owner", "repo") {
+		break
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	for _, err := range TagPages(ctx, source, "owner", "repo") {
+		if err != context.DeadlineExceeded {
+			t.Errorf("page over the budget error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("fetched %d pages, want 1", fetched)
+	}
+}
+
+// fileSource serves one file and counts the reads.
+type fileSource struct {
+	nopSource
+	reads *int
+}
+
+func (s fileSource) ReadFile(ctx context.Context, owner string, repo string, ref string, path string) ([]byte, error) {
+	*s.reads++
+	if path != "LICENSE" {
+		return nil, ErrNotFound
+	}
+	return []byte("MIT " + ref), nil
+}
+
+func TestReadFile(t *testing.T) {
+	reads := 0
+	set := NewSet(Options{Timeout: time.Second, CacheDir: t.TempDir(), CacheTTL: time.Hour})
+	set.Add(map[string]VersionSource{"files": fileSource{reads: &reads}, "plain": nopSource{}})
+	files, _ := set.For("files")
+	for range 2 {
+		if content, err := ReadFile(context.Background(), files, "o", "r", "v1", "LICENSE"); err != nil || string(content) != "MIT v1" {
+			t.Fatalf("ReadFile() = %q, %v", content, err)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("ReadFile() read %d times, want 1 with the cache", reads)
+	}
+	if _, err := ReadFile(context.Background(), files, "o", "r", "v1", "COPYING"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReadFile() of a missing file = %v, want ErrNotFound", err)
+	}
+	plain, _ := set.For("plain")
+	if _, err := ReadFile(context.Background(), plain, "o", "r", "v1", "LICENSE"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ReadFile() from a source without files = %v, want ErrUnsupported", err)
+	}
+}
+
+func (s fileSource) CompareCommits(ctx context.Context, owner string, repo string, base string, head string) (*Comparison, error) {
+	*s.reads++
+	return &Comparison{TotalCommits: 1, Commits: []Commit{{SHA: "c1", Message: base + " to " + head}}}, nil
+}
+
+func TestCompareCommits(t *testing.T) {
+	reads := 0
+	set := NewSet(Options{Timeout: time.Second, CacheDir: t.TempDir(), CacheTTL: time.Hour})
+	set.Add(map[string]VersionSource{"commits": fileSource{reads: &reads}, "plain": nopSource{}})
+	commits, _ := set.For("commits")
+	for range 2 {
+		comparison, err := CompareCommits(context.Background(), commits, "o", "r", "v1", "v2")
+		if err != nil || comparison.TotalCommits != 1 || comparison.Commits[0].Message != "v1 to v2" {
+			t.Fatalf("CompareCommits() = %+v, %v", comparison, err)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("CompareCommits() compared %d times, want 1 with the cache", reads)
+	}
+	plain, _ := set.For("plain")
+	if _, err := CompareCommits(context.Background(), plain, "o", "r", "v1", "v2"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("CompareCommits() from a source without commits = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestGitHubReplay(t *testing.T) {
+	set := NewSet(Options{GithubToken: os.Getenv("GITHUB_TOKEN"), HTTPClient: httpreplay.Client(t, "testdata/github.json", *record)})
+	source, err := set.For("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tags, err := source.ListTags(ctx, "paradigmxyz", "reth")
+	if err != nil {
+		t.Fatalf("ListTags() unexpected error: %v", err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if got := strings.Join(names, " "); got != "v1.4.8 v1.4.7 v1.4.6" || tags[0].Commit != strings.Repeat("a1", 20) {
+		t.Errorf("ListTags() = %+v, want the tags of both pages", tags)
+	}
+
+	release, err := source.GetRelease(ctx, "paradigmxyz", "reth", "v1.4.8")
+	if err != nil {
+		t.Fatalf("GetRelease() unexpected error: %v", err)
+	}
+	if release.Name != "Reth v1.4.8" || release.Prerelease || release.PublishedAt.IsZero() || len(release.Assets) != 1 || !strings.HasPrefix(release.Body, "## Summary") {
+		t.Errorf("GetRelease() = %+v", release)
+	}
+	if _, err := source.GetRelease(ctx, "paradigmxyz", "reth", "v0.0.0"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("GetRelease() of a missing release = %v, want a 404 error", err)
+	}
+
+	if sha, err := source.ResolveRef(ctx, "paradigmxyz", "reth", "main"); err != nil || sha != strings.Repeat("d4", 20) {
+		t.Errorf("ResolveRef() = %s, %v", sha, err)
+	}
+}
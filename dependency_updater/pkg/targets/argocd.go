@@ -0,0 +1,185 @@
+package targets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// ArgoCD annotations naming the dependency an Application's targetRevision follows, and the
+// helm parameters set to dependency image tags as <parameter>=<dependency>[,...].
+const (
+	ArgoTargetRevisionAnnotation = "dependency-updater.base.org/target-revision"
+	ArgoHelmParametersAnnotation = "dependency-updater.base.org/helm-parameters"
+)
+
+var (
+	argoAnnotation      = regexp.MustCompile(`^\s+(` + regexp.QuoteMeta(ArgoTargetRevisionAnnotation) + `|` + regexp.QuoteMeta(ArgoHelmParametersAnnotation) + `):\s*["']?([^"'#]*?)["']?\s*$`)
+	argoTargetRevision  = regexp.MustCompile(`^(\s*targetRevision:\s*)(["']?)([^\s"'#]*)(["']?)(.*)$`)
+	argoParameterName   = regexp.MustCompile(`^\s*-\s+name:\s*["']?([^\s"'#]+)["']?\s*$`)
+	argoParameterValue  = regexp.MustCompile(`^(\s*value:\s*)(["']?)([^\s"'#]*)(["']?)(.*)$`)
+	argoDocumentDivider = regexp.MustCompile(`^---\s*$`)
+)
+
+// ArgoApplications updates ArgoCD Application manifests under Dir, so GitOps managed
+// deployments follow the versions this tool selects. Only Applications opting in with the
+// annotations above are changed: targetRevision is set to the dependency's tag, a helm
+// parameter's value to the tag without its prefix, the form image tags use.
+type ArgoApplications struct {
+	Dir string
+}
+
+func (ArgoApplications) Name() string { return "argocd" }
+
+// DependsOn orders the Applications after versions.json, which they are derived from.
+func (ArgoApplications) DependsOn() []string { return []string{"versions.json"} }
+
+// argoValue is a value an Application takes from a dependency.
+type argoValue struct {
+	Dependency string
+	Value      string
+	// ImageTag is set for helm parameters, which get the tag without its prefix.
+	ImageTag bool
+}
+
+// rewriteArgo calls update for every annotated value of a manifest and replaces it with the
+// result.
+func rewriteArgo(content string, update func(argoValue) string) string {
+	lines := strings.Split(content, "\n")
+	start := 0
+	for end := 0; end <= len(lines); end++ {
+		if end < len(lines) && !argoDocumentDivider.MatchString(lines[end]) {
+			continue
+		}
+		rewriteArgoDocument(lines[start:end], update)
+		start = end + 1
+	}
+	return strings.Join(lines, "\n")
+}
+
+func rewriteArgoDocument(lines []string, update func(argoValue) string) {
+	revision, parameters := "", map[string]string{}
+	for _, line := range lines {
+		m := argoAnnotation.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if m[1] == ArgoTargetRevisionAnnotation {
+			revision = strings.TrimSpace(m[2])
+			continue
+		}
+		for _, pair := range strings.Split(m[2], ",") {
+			if name, dependency, ok := strings.Cut(pair, "="); ok {
+				parameters[strings.TrimSpace(name)] = strings.TrimSpace(dependency)
+			}
+		}
+	}
+
+	parameter := ""
+	for i, line := range lines {
+		if m := argoTargetRevision.FindStringSubmatch(line); m != nil && revision != "" {
+			lines[i] = m[1] + m[2] + update(argoValue{Dependency: revision, Value: m[3]}) + m[4] + m[5]
+			continue
+		}
+		if m := argoParameterName.FindStringSubmatch(line); m != nil {
+			parameter = m[1]
+			continue
+		}
+		if m := argoParameterValue.FindStringSubmatch(line); m != nil && parameters[parameter] != "" {
+			lines[i] = m[1] + m[2] + update(argoValue{Dependency: parameters[parameter], Value: m[3], ImageTag: true}) + m[4] + m[5]
+		}
+		if strings.Contains(line, "value:") {
+			parameter = ""
+		}
+	}
+}
+
+func argoWanted(value argoValue, info *version.Info) string {
+	if value.ImageTag {
+		return imageTag(info)
+	}
+	return info.Tag
+}
+
+// Read returns the values the Applications currently set, in Pin.Tag.
+func (t ArgoApplications) Read(ctx context.Context, repoPath string) (map[string]Pin, error) {
+	paths, err := yamlFiles(t.Dir)
+	if err != nil {
+		return nil, err
+	}
+	pins := map[string]Pin{}
+	for _, path := range paths {
+		content, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rewriteArgo(content, func(value argoValue) string {
+			pins[value.Dependency] = Pin{Tag: value.Value}
+			return value.Value
+		})
+	}
+	return pins, nil
+}
+
+func (t ArgoApplications) Plan(ctx context.Context, repoPath string, dependencies version.Dependencies) ([]Edit, error) {
+	paths, err := yamlFiles(t.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var edits []Edit
+	for _, path := range paths {
+		content, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		updated := rewriteArgo(content, func(value argoValue) string {
+			info := dependencies[value.Dependency]
+			if info == nil || info.Tracking == "branch" {
+				return value.Value
+			}
+			return argoWanted(value, info)
+		})
+		planned, err := PlanFileEdit(t.Name(), path, []byte(updated))
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, planned...)
+	}
+	return edits, nil
+}
+
+func (ArgoApplications) Apply(ctx context.Context, edit Edit) error { return WriteEdit(edit) }
+
+// Verify checks that no annotated value differs from its dependency, and that every annotation
+// names a dependency in versions.json.
+func (t ArgoApplications) Verify(ctx context.Context, repoPath string, dependencies version.Dependencies) error {
+	paths, err := yamlFiles(t.Dir)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, path := range paths {
+		content, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		rewriteArgo(content, func(value argoValue) string {
+			info := dependencies[value.Dependency]
+			switch {
+			case info == nil:
+				problems = append(problems, fmt.Sprintf("%s references unknown dependency %s", path, value.Dependency))
+			case info.Tracking != "branch" && value.Value != argoWanted(value, info):
+				problems = append(problems, fmt.Sprintf("%s sets %s to %s, expected %s", path, value.Dependency, value.Value, argoWanted(value, info)))
+			}
+			return value.Value
+		})
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package targets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// fluxMarker matches a YAML value followed by a Flux image automation marker, such as
+// `image: ghcr.io/base/node-reth:v0.7.6 # {"$imagepolicy": "flux-system:base-reth-node"}`.
+// The value may be given a YAML anchor, as compose files do to share an image between services.
+var fluxMarker = regexp.MustCompile(`^(\s*(?:-\s+)?[\w.-]+:\s*(?:&\S+\s+)?)(["']?)([^\s"'#]+)(["']?)(\s*#\s*\{\s*"\$imagepolicy"\s*:\s*"([^"]+)"\s*\}\s*)$`)
+
+// FluxManifests updates the Kubernetes manifests under Dir whose image references carry Flux
+// image automation markers, so Flux applies the versions this tool selects. The ImagePolicy
+// a marker names is matched to the dependency of the same name, dashes standing for
+// underscores, and image tags are the dependency's tag without its tag prefix, followed by
+// its image variant if it has one. Markers naming other policies are left to Flux.
+type FluxManifests struct {
+	Dir string
+}
+
+func (FluxManifests) Name() string { return "flux" }
+
+// DependsOn orders the manifests after versions.json, which they are derived from.
+func (FluxManifests) DependsOn() []string { return []string{"versions.json"} }
+
+// fluxValue is one marked value: a full image reference, or with a ":tag" policy suffix only its tag.
+type fluxValue struct {
+	Dependency string
+	Tag        string
+	TagOnly    bool
+}
+
+func parseFluxLine(line string) (fluxValue, []string, bool) {
+	m := fluxMarker.FindStringSubmatch(line)
+	if m == nil {
+		return fluxValue{}, nil, false
+	}
+	parts := strings.Split(m[6], ":")
+	if len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "tag") {
+		// ":name" markers only hold the image name, which versions never change.
+		return fluxValue{}, nil, false
+	}
+	value := fluxValue{Dependency: strings.ReplaceAll(parts[1], "-", "_"), TagOnly: len(parts) == 3}
+	if value.TagOnly {
+		value.Tag = m[3]
+	} else if i := strings.LastIndex(m[3], ":"); i > strings.LastIndex(m[3], "/") {
+		value.Tag = m[3][i+1:]
+	} else {
+		return fluxValue{}, nil, false
+	}
+	return value, m, true
+}
+
+// Read returns the image tags of the marked values, in Pin.Tag, the commit is not recorded.
+func (t FluxManifests) Read(ctx context.Context, repoPath string) (map[string]Pin, error) {
+	paths, err := yamlFiles(t.Dir)
+	if err != nil {
+		return nil, err
+	}
+	pins := map[string]Pin{}
+	for _, path := range paths {
+		content, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(content, "\n") {
+			if value, _, ok := parseFluxLine(line); ok {
+				pins[value.Dependency] = Pin{Tag: value.Tag}
+			}
+		}
+	}
+	return pins, nil
+}
+
+func (t FluxManifests) Plan(ctx context.Context, repoPath string, dependencies version.Dependencies) ([]Edit, error) {
+	paths, err := yamlFiles(t.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var edits []Edit
+	for _, path := range paths {
+		content, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			value, m, ok := parseFluxLine(line)
+			info := dependencies[value.Dependency]
+			if !ok || info == nil || info.Tracking == "branch" {
+				continue
+			}
+			updated := imageTag(info)
+			if !value.TagOnly {
+				updated = m[3][:strings.LastIndex(m[3], ":")+1] + updated
+			}
+			lines[i] = m[1] + m[2] + updated + m[4] + m[5]
+		}
+		planned, err := PlanFileEdit(t.Name(), path, []byte(strings.Join(lines, "\n")))
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, planned...)
+	}
+	return edits, nil
+}
+
+func (FluxManifests) Apply(ctx context.Context, edit Edit) error { return WriteEdit(edit) }
+
+func (t FluxManifests) Verify(ctx context.Context, repoPath string, dependencies version.Dependencies) error {
+	pins, err := t.Read(ctx, repoPath)
+	if err != nil {
+		return err
+	}
+	var mismatched []string
+	for _, name := range slices.Sorted(maps.Keys(pins)) {
+		info, pin := dependencies[name], pins[name]
+		if info != nil && info.Tracking != "branch" && pin.Tag != imageTag(info) {
+			mismatched = append(mismatched, fmt.Sprintf("%s is %s, expected %s", name, pin.Tag, imageTag(info)))
+		}
+	}
+	if len(mismatched) > 0 {
+		return errors.New(strings.Join(mismatched, "; "))
+	}
+	return nil
+}
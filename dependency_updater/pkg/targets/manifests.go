@@ -0,0 +1,42 @@
+package targets
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// yamlFiles lists the YAML manifests under dir in lexical order.
+func yamlFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(path); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing manifests in %s: %s", dir, err)
+	}
+	return paths, nil
+}
+
+func readFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %s", path, err)
+	}
+	return string(content), nil
+}
+
+// imageTag is the tag of a dependency without its tag prefix, the form image tags take as
+// they can't contain slashes, with its image variant suffix.
+func imageTag(info *version.Info) string {
+	return info.ImageTagOf(info.Tag)
+}
@@ -0,0 +1,342 @@
+// Package targets plans, applies and rolls back edits to the files recording dependency versions.
+package targets
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Pin is the version of a dependency as recorded by a target.
+type Pin struct {
+	Tag    string `json:"tag"`
+	Commit string `json:"commit"`
+}
+
+// Edit replaces the content of one file. Before is nil if the file does not exist yet.
+type Edit struct {
+	Target string `json:"target"`
+	Path   string `json:"path"`
+	Before []byte `json:"before"`
+	After  []byte `json:"after"`
+}
+
+// UpdateTarget is a file, or set of files, recording dependency versions. Targets only
+// describe their edits, planning, dry runs and rollback are shared by Apply.
+type UpdateTarget interface {
+	Name() string
+	// Read returns the versions currently recorded by the target.
+	Read(ctx context.Context, repoPath string) (map[string]Pin, error)
+	// Plan returns the edits that make the target record dependencies.
+	Plan(ctx context.Context, repoPath string, dependencies version.Dependencies) ([]Edit, error)
+	// Apply writes a planned edit.
+	Apply(ctx context.Context, edit Edit) error
+	// Verify checks that the target records dependencies after the edits were applied.
+	Verify(ctx context.Context, repoPath string, dependencies version.Dependencies) error
+}
+
+// Defaults are the files the updater keeps in sync with the selected versions.
+func Defaults(set *sources.Set) []UpdateTarget {
+	return []UpdateTarget{VersionsJSON{}, VersionsEnv{Sources: set}}
+}
+
+// Apply plans all edits before writing any, and restores every written file if an edit or
+// verification fails, or ctx is cancelled part way. Edits to different files are applied in
+// parallel, in the stages Dependent targets require, and returned sorted by path and target.
+// Each edit is planned from the file on disk, so two edits of one file are rejected, the
+// later would undo the earlier. With dryRun the planned edits are only returned.
+func Apply(ctx context.Context, repoPath string, targets []UpdateTarget, dependencies version.Dependencies, dryRun bool) ([]Edit, error) {
+	var edits []Edit
+	for _, target := range targets {
+		planned, err := target.Plan(ctx, repoPath, dependencies)
+		if err != nil {
+			return nil, fmt.Errorf("error planning %s: %s", target.Name(), err)
+		}
+		edits = append(edits, planned...)
+	}
+	slices.SortStableFunc(edits, func(a, b Edit) int {
+		return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Target, b.Target))
+	})
+	for i := 1; i < len(edits); i++ {
+		if edits[i].Path == edits[i-1].Path {
+			return nil, fmt.Errorf("%s and %s both edit %s", edits[i-1].Target, edits[i].Target, edits[i].Path)
+		}
+	}
+
+	if dryRun {
+		return edits, nil
+	}
+
+	byName := map[string]UpdateTarget{}
+	for _, target := range targets {
+		byName[target.Name()] = target
+	}
+	stages, err := applyStages(edits, byName)
+	if err != nil {
+		return nil, err
+	}
+	var applied []Edit
+	fail := func(err error) ([]Edit, error) {
+		if rollbackErr := Rollback(applied); rollbackErr != nil {
+			return nil, fmt.Errorf("%s, and rolling back failed: %s", err, rollbackErr)
+		}
+		return nil, fmt.Errorf("%s, all edits were rolled back", err)
+	}
+	for _, stage := range stages {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var errs []error
+		for _, fileEdits := range stage {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, edit := range fileEdits {
+					err := ctx.Err()
+					if err != nil {
+						err = fmt.Errorf("interrupted before applying %s: %s", edit.Path, err)
+					} else if err = byName[edit.Target].Apply(ctx, edit); err != nil {
+						err = fmt.Errorf("error applying %s edit to %s: %s", edit.Target, edit.Path, err)
+					}
+					mu.Lock()
+					if err != nil {
+						errs = append(errs, err)
+					} else {
+						applied = append(applied, edit)
+					}
+					mu.Unlock()
+					if err != nil {
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if len(errs) > 0 {
+			return fail(errors.Join(errs...))
+		}
+	}
+	for _, target := range targets {
+		if err := target.Verify(ctx, repoPath, dependencies); err != nil {
+			return fail(fmt.Errorf("%s verification failed: %s", target.Name(), err))
+		}
+	}
+	return edits, nil
+}
+
+// Dependent is implemented by targets whose files reference the files of other targets.
+// Their edits are only applied once the edits of the targets named by DependsOn succeeded.
+type Dependent interface {
+	DependsOn() []string
+}
+
+// applyStages orders edits into stages that are applied one after another. A stage holds the
+// edits of targets whose dependencies are in earlier stages, grouped by file: files are
+// written in parallel, the edits to one file in order.
+func applyStages(edits []Edit, byName map[string]UpdateTarget) ([][][]Edit, error) {
+	levels := map[string]int{}
+	var level func(name string, visiting []string) (int, error)
+	level = func(name string, visiting []string) (int, error) {
+		if l, ok := levels[name]; ok {
+			return l, nil
+		}
+		if slices.Contains(visiting, name) {
+			return 0, fmt.Errorf("targets depend on each other: %s", strings.Join(append(visiting, name), " -> "))
+		}
+		l := 0
+		if dependent, ok := byName[name].(Dependent); ok {
+			for _, dependency := range dependent.DependsOn() {
+				if _, ok := byName[dependency]; !ok {
+					continue
+				}
+				dl, err := level(dependency, append(visiting, name))
+				if err != nil {
+					return 0, err
+				}
+				l = max(l, dl+1)
+			}
+		}
+		levels[name] = l
+		return l, nil
+	}
+
+	var stages [][][]Edit
+	for _, edit := range edits {
+		l, err := level(edit.Target, nil)
+		if err != nil {
+			return nil, err
+		}
+		for len(stages) <= l {
+			stages = append(stages, nil)
+		}
+		i := slices.IndexFunc(stages[l], func(fileEdits []Edit) bool { return fileEdits[0].Path == edit.Path })
+		if i < 0 {
+			stages[l] = append(stages[l], nil)
+			i = len(stages[l]) - 1
+		}
+		stages[l][i] = append(stages[l][i], edit)
+	}
+	return stages, nil
+}
+
+// Rollback restores the files touched by edits in reverse order.
+func Rollback(edits []Edit) error {
+	var errs []error
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		var err error
+		if edit.Before == nil {
+			err = os.Remove(edit.Path)
+		} else {
+			err = writeFile(edit.Path, edit.Before)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			log.Printf("Rolled back %s", edit.Path)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PlanFileEdit returns an edit replacing path with after, or nothing if it already matches.
+// After takes the line endings, final newline and byte order mark of the existing file, so
+// checkouts with CRLF line endings don't get whole-file diffs.
+func PlanFileEdit(target string, path string, after []byte) ([]Edit, error) {
+	before, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading %s: %s", path, err)
+	}
+	if err == nil {
+		after = matchFormat(before, after)
+	}
+	if err == nil && bytes.Equal(before, after) {
+		return nil, nil
+	}
+	return []Edit{{Target: target, Path: path, Before: before, After: after}}, nil
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// matchFormat gives after the format of before: CRLF line endings if every line of before
+// ends with one, a final newline only if before has one, and before's UTF-8 byte order mark.
+// Files with mixed line endings keep those of after, whose unchanged lines kept theirs.
+func matchFormat(before []byte, after []byte) []byte {
+	if len(before) == 0 {
+		return after
+	}
+	bom := bytes.HasPrefix(before, utf8BOM)
+	after = bytes.TrimPrefix(after, utf8BOM)
+	lines := bytes.Count(before, []byte("\n"))
+	crlf := lines > 0 && bytes.Count(before, []byte("\r\n")) == lines
+	newline := []byte("\n")
+	if crlf {
+		after = bytes.ReplaceAll(bytes.ReplaceAll(after, []byte("\r\n"), newline), newline, []byte("\r\n"))
+		newline = []byte("\r\n")
+	}
+	switch hasNewline := bytes.HasSuffix(before, []byte("\n")); {
+	case hasNewline && len(after) > 0 && !bytes.HasSuffix(after, []byte("\n")):
+		after = append(after, newline...)
+	case !hasNewline:
+		after = bytes.TrimSuffix(bytes.TrimSuffix(after, []byte("\n")), []byte("\r"))
+	}
+	if bom {
+		after = append(bytes.Clone(utf8BOM), after...)
+	}
+	return after
+}
+
+// WriteEdit is the Apply shared by targets whose edits are whole-file replacements.
+func WriteEdit(edit Edit) error {
+	return writeFile(edit.Path, edit.After)
+}
+
+// writeFile replaces path with data through a temporary file, so an interrupted write leaves
+// either the old or the new content. The file keeps its mode, new files get 0644.
+func writeFile(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(path); err == nil {
+		mode = stat.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// VerifyPins compares the versions a target read back with the expected ones.
+func VerifyPins(got map[string]Pin, dependencies version.Dependencies) error {
+	var mismatched []string
+	for name, info := range dependencies {
+		want := Pin{Tag: info.Tag, Commit: info.Commit}
+		if info.Tracking == "branch" {
+			want.Tag = info.Branch
+		}
+		if got[name] != want {
+			mismatched = append(mismatched, fmt.Sprintf("%s is %s@%s, expected %s@%s", name, got[name].Tag, got[name].Commit, want.Tag, want.Commit))
+		}
+	}
+	if len(mismatched) > 0 {
+		return errors.New(strings.Join(mismatched, "; "))
+	}
+	return nil
+}
+
+// DiffLines returns a line diff of a and b, removed lines prefixed with "-" and added ones with "+".
+func DiffLines(a string, b string) []string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+x[i])
+			i++
+		default:
+			diff = append(diff, "+"+y[j])
+			j++
+		}
+	}
+	return diff
+}
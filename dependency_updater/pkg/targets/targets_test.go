@@ -0,0 +1,454 @@
+package targets
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+var update = flag.Bool("update", false, "rewrite the after directories of testdata/golden with the targets' output")
+
+// forgeSource only implements RepoURL, which is all the versions.env target needs.
+type forgeSource struct {
+	sources.VersionSource
+}
+
+func (forgeSource) RepoURL(owner string, repo string) string {
+	return "https://forge.example/" + owner + "/" + repo
+}
+
+// failingTarget plans nothing and always fails verification.
+type failingTarget struct{}
+
+func (failingTarget) Name() string                                         { return "failing" }
+func (failingTarget) Read(context.Context, string) (map[string]Pin, error) { return nil, nil }
+func (failingTarget) Plan(context.Context, string, version.Dependencies) ([]Edit, error) {
+	return nil, nil
+}
+func (failingTarget) Apply(context.Context, Edit) error { return nil }
+func (failingTarget) Verify(context.Context, string, version.Dependencies) error {
+	return errors.New("always fails")
+}
+
+func TestApply(t *testing.T) {
+	repo := t.TempDir()
+	original := `{"op_node": {"tag": "v1.0.0", "commit": "aaa", "owner": "o", "repo": "r", "tracking": "release"}}`
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+	set := sources.NewSet(sources.Options{})
+	set.Add(map[string]sources.VersionSource{sources.Default: forgeSource{}})
+	dependencies := func() version.Dependencies {
+		return version.Dependencies{"op_node": {Tag: "v1.1.0", Commit: "bbb", Owner: "o", Repo: "r", Tracking: "release"}}
+	}
+
+	edits, err := Apply(context.Background(), repo, Defaults(set), dependencies(), true)
+	if err != nil || len(edits) != 2 {
+		t.Fatalf("Apply() dry run = %d edits, %v, want 2 edits", len(edits), err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "versions.env")); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote versions.env")
+	}
+	reversed := Defaults(set)
+	slices.Reverse(reversed)
+	if again, _ := Apply(context.Background(), repo, reversed, dependencies(), true); !slices.EqualFunc(edits, again, func(a, b Edit) bool {
+		return a.Path == b.Path && a.Target == b.Target && bytes.Equal(a.After, b.After)
+	}) {
+		t.Errorf("Apply() edit order depends on the target order")
+	}
+
+	targets := append(Defaults(set), failingTarget{})
+	if _, err := Apply(context.Background(), repo, targets, dependencies(), false); err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Errorf("Apply() with a failing verification = %v, want rollback", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo, "versions.json")); string(got) != original {
+		t.Errorf("versions.json not restored, got %s", got)
+	}
+	if stat, err := os.Stat(filepath.Join(repo, "versions.json")); err != nil || stat.Mode().Perm() != 0600 {
+		t.Errorf("versions.json mode after rollback = %v, %v, want 0600", stat.Mode(), err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "versions.env")); !os.IsNotExist(err) {
+		t.Errorf("versions.env not removed on rollback")
+	}
+
+	if _, err := Apply(context.Background(), repo, Defaults(set), dependencies(), false); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if stat, err := os.Stat(filepath.Join(repo, "versions.json")); err != nil || stat.Mode().Perm() != 0600 {
+		t.Errorf("versions.json mode after Apply() = %v, %v, want 0600", stat.Mode(), err)
+	}
+	if entries, _ := os.ReadDir(repo); len(entries) != 2 {
+		t.Errorf("Apply() left %d files, want versions.json and versions.env without temporary files", len(entries))
+	}
+	env, _ := os.ReadFile(filepath.Join(repo, "versions.env"))
+	if !strings.Contains(string(env), "export OP_NODE_TAG=v1.1.0") || !strings.Contains(string(env), "export OP_NODE_REPO=https://forge.example/o/r.git") {
+		t.Errorf("versions.env = %s", env)
+	}
+	if edits, _ := Apply(context.Background(), repo, Defaults(set), dependencies(), true); len(edits) != 0 {
+		t.Errorf("Apply() after applying planned %d edits, want none", len(edits))
+	}
+}
+
+func TestVersionsEnvVPrefix(t *testing.T) {
+	repo := t.TempDir()
+	set := sources.NewSet(sources.Options{})
+	set.Add(map[string]sources.VersionSource{sources.Default: forgeSource{}})
+	dependencies := version.Dependencies{
+		"nethermind": {Tag: "v1.35.3", Commit: "aaa", Owner: "o", Repo: "nethermind", Tracking: "release", VPrefix: "omit"},
+		"op_node":    {Tag: "op-node/1.16.2", TagPrefix: "op-node", Commit: "bbb", Owner: "o", Repo: "optimism", Tracking: "release", VPrefix: "include"},
+	}
+	env := VersionsEnv{Sources: set}
+	if _, err := Apply(context.Background(), repo, []UpdateTarget{env}, dependencies, false); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	// The Dockerfiles check the tags out, vPrefix only applies to image tags.
+	got, _ := os.ReadFile(filepath.Join(repo, "versions.env"))
+	for _, want := range []string{"export NETHERMIND_TAG=v1.35.3\n", "export OP_NODE_TAG=op-node/1.16.2"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("versions.env missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestPlanFileEditFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{"crlf", "a=1\r\nb=1\r\n", "a=1\nb=2", "a=1\r\nb=2\r\n"},
+		{"no final newline", "a=1\nb=1", "a=1\nb=2\n", "a=1\nb=2"},
+		{"crlf without final newline", "a=1\r\nb=1", "a=1\r\nb=2\r\n", "a=1\r\nb=2"},
+		{"byte order mark", "\ufeffa=1\n", "a=2", "\ufeffa=2\n"},
+		{"mixed line endings", "a=1\r\nb=1\n", "a=1\r\nb=2\n", "a=1\r\nb=2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "versions.env")
+			if err := os.WriteFile(path, []byte(tt.before), 0644); err != nil {
+				t.Fatal(err)
+			}
+			edits, err := PlanFileEdit("env", path, []byte(tt.after))
+			if err != nil || len(edits) != 1 {
+				t.Fatalf("PlanFileEdit() = %d edits, %v", len(edits), err)
+			}
+			if got := string(edits[0].After); got != tt.want {
+				t.Errorf("PlanFileEdit() after = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	path := filepath.Join(t.TempDir(), "versions.env")
+	if err := os.WriteFile(path, []byte("a=1\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if edits, _ := PlanFileEdit("env", path, []byte("a=1")); len(edits) != 0 {
+		t.Errorf("PlanFileEdit() of the same content with other line endings = %q", edits[0].After)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := DiffLines("a\nb\nc\n", "a\nB\nc\nd\n")
+	want := []string{"-b", "+B", "+d"}
+	if !slices.Equal(got, want) {
+		t.Errorf("DiffLines() = %q, want %q", got, want)
+	}
+}
+
+// fileTarget writes one file per dependency, named after file or else the target, and
+// depends on the targets in after.
+type fileTarget struct {
+	name  string
+	file  string
+	after []string
+	fail  bool
+}
+
+func (t fileTarget) Name() string                                       { return t.name }
+func (t fileTarget) DependsOn() []string                                { return t.after }
+func (fileTarget) Read(context.Context, string) (map[string]Pin, error) { return nil, nil }
+func (t fileTarget) Plan(_ context.Context, repoPath string, dependencies version.Dependencies) ([]Edit, error) {
+	var edits []Edit
+	for _, name := range version.Names(dependencies) {
+		planned, err := PlanFileEdit(t.name, filepath.Join(repoPath, cmp.Or(t.file, t.name)+"-"+name), []byte(dependencies[name].Tag))
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, planned...)
+	}
+	return edits, nil
+}
+func (t fileTarget) Apply(_ context.Context, edit Edit) error {
+	if t.fail {
+		return errors.New("disk full")
+	}
+	return WriteEdit(edit)
+}
+func (fileTarget) Verify(context.Context, string, version.Dependencies) error { return nil }
+
+func TestApplyStages(t *testing.T) {
+	byName := map[string]UpdateTarget{
+		"compose": fileTarget{name: "compose", after: []string{"env", "missing"}},
+		"env":     fileTarget{name: "env", after: []string{"json"}},
+		"json":    fileTarget{name: "json"},
+		"helm":    fileTarget{name: "helm"},
+	}
+	edits := []Edit{
+		{Target: "compose", Path: "c"}, {Target: "env", Path: "e"}, {Target: "helm", Path: "h"},
+		{Target: "json", Path: "j"}, {Target: "json", Path: "j"},
+	}
+	stages, err := applyStages(edits, byName)
+	if err != nil {
+		t.Fatalf("applyStages() unexpected error: %v", err)
+	}
+	var got [][]string
+	for _, stage := range stages {
+		var files []string
+		for _, fileEdits := range stage {
+			files = append(files, strings.Repeat(fileEdits[0].Path, len(fileEdits)))
+		}
+		got = append(got, files)
+	}
+	want := [][]string{{"h", "jj"}, {"e"}, {"c"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("applyStages() = %v, want %v", got, want)
+	}
+
+	byName["json"] = fileTarget{name: "json", after: []string{"compose"}}
+	if _, err := applyStages(edits, byName); err == nil || !strings.Contains(err.Error(), "depend on each other") {
+		t.Errorf("applyStages() with a cycle = %v, want error", err)
+	}
+}
+
+func TestApplyParallelRollback(t *testing.T) {
+	repo := t.TempDir()
+	dependencies := version.Dependencies{"op_geth": {Tag: "v1"}, "op_node": {Tag: "v2"}, "reth": {Tag: "v3"}}
+	targets := []UpdateTarget{fileTarget{name: "json"}, fileTarget{name: "env", after: []string{"json"}, fail: true}}
+	if _, err := Apply(context.Background(), repo, targets, dependencies, false); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("Apply() with a failing target = %v, want error", err)
+	}
+	if entries, _ := os.ReadDir(repo); len(entries) != 0 {
+		t.Errorf("Apply() left %d files after rolling back", len(entries))
+	}
+
+	targets[1] = fileTarget{name: "env", after: []string{"json"}}
+	edits, err := Apply(context.Background(), repo, targets, dependencies, false)
+	if err != nil || len(edits) != 6 {
+		t.Fatalf("Apply() = %d edits, %v, want 6", len(edits), err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo, "env-reth")); string(got) != "v3" {
+		t.Errorf("env-reth = %q", got)
+	}
+}
+
+// Edits are planned from disk, a second target editing the same file would undo the first.
+func TestApplyOverlappingTargets(t *testing.T) {
+	repo := t.TempDir()
+	dependencies := version.Dependencies{"reth": {Tag: "v3"}}
+	targets := []UpdateTarget{fileTarget{name: "json"}, fileTarget{name: "env", file: "json"}}
+	if _, err := Apply(context.Background(), repo, targets, dependencies, true); err == nil || !strings.Contains(err.Error(), "env and json both edit") {
+		t.Errorf("Apply() with overlapping targets = %v, want error", err)
+	}
+	if entries, _ := os.ReadDir(repo); len(entries) != 0 {
+		t.Errorf("Apply() with overlapping targets wrote %d files", len(entries))
+	}
+}
+
+func TestFluxManifests(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `spec:
+  containers:
+    - name: node
+      image: ghcr.io/base/node-reth:v0.7.5 # {"$imagepolicy": "flux-system:base-reth-node"}
+    - name: op-node
+      image: "ghcr.io/base/op-node:v1.16.10" # {"$imagepolicy": "flux-system:op-node"}
+  values:
+    tag: v1.16.10 # {"$imagepolicy": "flux-system:op-node:tag"}
+    repository: ghcr.io/base/op-node # {"$imagepolicy": "flux-system:op-node:name"}
+    other: ghcr.io/other/image:v1 # {"$imagepolicy": "flux-system:other"}
+    - name: geth
+      image: ethereum/client-go:v1.14.0-alltools # {"$imagepolicy": "flux-system:geth"}
+`
+	path := filepath.Join(dir, "node.yaml")
+	if err := os.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dependencies := version.Dependencies{
+		"base_reth_node": {Tag: "v0.7.6", Tracking: "release"},
+		"op_node":        {Tag: "op-node/v1.16.11", TagPrefix: "op-node", Tracking: "release"},
+		"geth":           {Tag: "v1.14.1", Tracking: "release", ImageVariant: "alltools"},
+	}
+
+	flux := FluxManifests{Dir: dir}
+	if _, err := Apply(context.Background(), dir, []UpdateTarget{flux}, dependencies, false); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	for _, want := range []string{
+		`image: ghcr.io/base/node-reth:v0.7.6 # {"$imagepolicy": "flux-system:base-reth-node"}`,
+		`image: "ghcr.io/base/op-node:v1.16.11" # {"$imagepolicy": "flux-system:op-node"}`,
+		`tag: v1.16.11 # {"$imagepolicy": "flux-system:op-node:tag"}`,
+		`repository: ghcr.io/base/op-node # {"$imagepolicy": "flux-system:op-node:name"}`,
+		`other: ghcr.io/other/image:v1 # {"$imagepolicy": "flux-system:other"}`,
+		`image: ethereum/client-go:v1.14.1-alltools # {"$imagepolicy": "flux-system:geth"}`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("updated manifest missing %s:\n%s", want, got)
+		}
+	}
+}
+
+func TestArgoApplications(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: base-node
+  annotations:
+    dependency-updater.base.org/target-revision: op_node
+    dependency-updater.base.org/helm-parameters: "opNode.image.tag=op_node, reth.image.tag=base_reth_node"
+spec:
+  source:
+    targetRevision: op-node/v1.16.10
+    helm:
+      parameters:
+        - name: opNode.image.tag
+          value: v1.16.10
+        - name: reth.image.tag
+          value: "v0.7.5"
+        - name: replicas
+          value: "1"
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: unrelated
+spec:
+  source:
+    targetRevision: main
+`
+	path := filepath.Join(dir, "apps.yaml")
+	if err := os.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dependencies := version.Dependencies{
+		"base_reth_node": {Tag: "v0.7.6", Tracking: "release"},
+		"op_node":        {Tag: "op-node/v1.16.11", TagPrefix: "op-node", Tracking: "release"},
+	}
+
+	argo := ArgoApplications{Dir: dir}
+	if _, err := Apply(context.Background(), dir, []UpdateTarget{argo}, dependencies, false); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	want := strings.NewReplacer(
+		"targetRevision: op-node/v1.16.10", "targetRevision: op-node/v1.16.11",
+		"value: v1.16.10", "value: v1.16.11",
+		`value: "v0.7.5"`, `value: "v0.7.6"`,
+	).Replace(manifest)
+	if string(got) != want {
+		t.Errorf("updated manifest:\n%s\nwant:\n%s", got, want)
+	}
+
+	pins, _ := argo.Read(context.Background(), dir)
+	if pins["op_node"].Tag != "v1.16.11" || pins["base_reth_node"].Tag != "v0.7.6" {
+		t.Errorf("Read() = %v", pins)
+	}
+}
+
+// readTree returns the files under dir by their slash separated path relative to it.
+func readTree(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		content, err := os.ReadFile(path)
+		files[filepath.ToSlash(rel)] = content
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func writeTree(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestGolden applies the dependencies.json of every case in testdata/golden to a copy of its
+// before directory with all built-in targets, and compares the files byte for byte with its
+// after directory. go test ./pkg/targets -update rewrites the after directories.
+func TestGolden(t *testing.T) {
+	cases, err := os.ReadDir("testdata/golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(c.Name(), func(t *testing.T) {
+			dir := filepath.Join("testdata/golden", c.Name())
+			content, err := os.ReadFile(filepath.Join(dir, "dependencies.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var dependencies version.Dependencies
+			if err := json.Unmarshal(content, &dependencies); err != nil {
+				t.Fatal(err)
+			}
+			repo := t.TempDir()
+			writeTree(t, repo, readTree(t, filepath.Join(dir, "before")))
+
+			all := []UpdateTarget{VersionsJSON{}, VersionsEnv{Sources: sources.NewSet(sources.Options{})}, FluxManifests{Dir: repo}, ArgoApplications{Dir: repo}}
+			if _, err := Apply(context.Background(), repo, all, dependencies, false); err != nil {
+				t.Fatalf("Apply() unexpected error: %v", err)
+			}
+			got := readTree(t, repo)
+			after := filepath.Join(dir, "after")
+			if *update {
+				if err := os.RemoveAll(after); err != nil {
+					t.Fatal(err)
+				}
+				writeTree(t, after, got)
+				return
+			}
+			want := readTree(t, after)
+			for _, rel := range slices.Sorted(maps.Keys(got)) {
+				if _, ok := want[rel]; !ok {
+					t.Errorf("%s was written, but is not in %s", rel, after)
+				} else if !bytes.Equal(got[rel], want[rel]) {
+					t.Errorf("%s =\n%q\nwant\n%q", rel, got[rel], want[rel])
+				}
+			}
+			for rel := range want {
+				if _, ok := got[rel]; !ok {
+					t.Errorf("%s is in %s, but was not written", rel, after)
+				}
+			}
+		})
+	}
+}
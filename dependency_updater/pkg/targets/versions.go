@@ -0,0 +1,129 @@
+package targets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// VersionsJSON is versions.json itself, the record every other target is derived from.
+type VersionsJSON struct{}
+
+func (VersionsJSON) Name() string { return "versions.json" }
+
+func (VersionsJSON) Read(ctx context.Context, repoPath string) (map[string]Pin, error) {
+	dependencies, err := version.ReadDependencies(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	pins := map[string]Pin{}
+	for name, info := range dependencies {
+		pin := Pin{Tag: info.Tag, Commit: info.Commit}
+		if info.Tracking == "branch" {
+			pin.Tag = info.Branch
+		}
+		pins[name] = pin
+	}
+	return pins, nil
+}
+
+func (t VersionsJSON) Plan(ctx context.Context, repoPath string, dependencies version.Dependencies) ([]Edit, error) {
+	// formatting json
+	updatedJson, err := json.MarshalIndent(dependencies, "", "	  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling dependencies json: %s", err)
+	}
+	return PlanFileEdit(t.Name(), repoPath+"/versions.json", updatedJson)
+}
+
+func (VersionsJSON) Apply(ctx context.Context, edit Edit) error { return WriteEdit(edit) }
+
+func (t VersionsJSON) Verify(ctx context.Context, repoPath string, dependencies version.Dependencies) error {
+	pins, err := t.Read(ctx, repoPath)
+	if err != nil {
+		return err
+	}
+	return VerifyPins(pins, dependencies)
+}
+
+// VersionsEnv is versions.env, which the Dockerfiles source for the repos, tags and
+// commits they build.
+type VersionsEnv struct {
+	Sources *sources.Set
+}
+
+func (VersionsEnv) Name() string { return "versions.env" }
+
+// DependsOn orders versions.env after versions.json, which it is derived from.
+func (VersionsEnv) DependsOn() []string { return []string{"versions.json"} }
+
+func (VersionsEnv) Read(ctx context.Context, repoPath string) (map[string]Pin, error) {
+	f, err := os.ReadFile(repoPath + "/versions.env")
+	if err != nil {
+		return nil, fmt.Errorf("error reading versions.env: %s", err)
+	}
+	pins := map[string]Pin{}
+	for _, line := range strings.Split(strings.TrimPrefix(string(f), "\ufeff"), "\n") {
+		key, value, ok := strings.Cut(strings.TrimPrefix(strings.TrimSuffix(line, "\r"), "export "), "=")
+		if !ok {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(key, "_TAG"); ok {
+			name := strings.ToLower(prefix)
+			pins[name] = Pin{Tag: value, Commit: pins[name].Commit}
+		} else if prefix, ok := strings.CutSuffix(key, "_COMMIT"); ok {
+			name := strings.ToLower(prefix)
+			pins[name] = Pin{Tag: pins[name].Tag, Commit: value}
+		}
+	}
+	return pins, nil
+}
+
+func (t VersionsEnv) Plan(ctx context.Context, repoPath string, dependencies version.Dependencies) ([]Edit, error) {
+	envLines := []string{}
+
+	for _, dependency := range version.Names(dependencies) {
+		source, err := t.Sources.For(dependencies[dependency].Source)
+		if err != nil {
+			return nil, err
+		}
+		repoUrl := source.RepoURL(dependencies[dependency].Owner, dependencies[dependency].Repo) + ".git"
+
+		dependencyPrefix := strings.ToUpper(dependency)
+
+		// The Dockerfiles check the tag out, so it stays upstream's whatever the vPrefix.
+		tag := dependencies[dependency].Tag
+		if dependencies[dependency].Tracking == "branch" {
+			tag = dependencies[dependency].Branch
+		}
+
+		envLines = append(envLines, fmt.Sprintf("export %s_%s=%s",
+			dependencyPrefix, "TAG", tag))
+
+		envLines = append(envLines, fmt.Sprintf("export %s_%s=%s",
+			dependencyPrefix, "COMMIT", dependencies[dependency].Commit))
+
+		envLines = append(envLines, fmt.Sprintf("export %s_%s=%s",
+			dependencyPrefix, "REPO", repoUrl))
+	}
+
+	slices.Sort(envLines)
+
+	return PlanFileEdit(t.Name(), repoPath+"/versions.env", []byte(strings.Join(envLines, "\n")))
+}
+
+func (VersionsEnv) Apply(ctx context.Context, edit Edit) error { return WriteEdit(edit) }
+
+func (t VersionsEnv) Verify(ctx context.Context, repoPath string, dependencies version.Dependencies) error {
+	pins, err := t.Read(ctx, repoPath)
+	if err != nil {
+		return err
+	}
+	return VerifyPins(pins, dependencies)
+}
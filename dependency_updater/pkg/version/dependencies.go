@@ -0,0 +1,282 @@
+package version
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Info is one dependency in versions.json.
+type Info struct {
+	Tag       string `json:"tag,omitempty"`
+	Commit    string `json:"commit"`
+	TagPrefix string `json:"tagPrefix,omitempty"`
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	Branch    string `json:"branch,omitempty"`
+	// Tracking is "release", "tag", "branch" or "nightly", which follows the newest dated
+	// build tag, see ParseNightly, for components run at bleeding edge builds.
+	Tracking string `json:"tracking"`
+	// Prereleases are the prerelease types tag tracking follows besides stable releases, such
+	// as ["rc", "beta"], see PrereleaseType. Only release candidates if empty.
+	Prereleases []string `json:"prereleases,omitempty"`
+	// Source names the VersionSource the dependency is fetched from, github if unset.
+	Source string `json:"source,omitempty"`
+	// CheckInterval is the minimum time between checks of the dependency, such as "24h",
+	// overriding the run's default.
+	CheckInterval string `json:"checkInterval,omitempty"`
+	// Pinned holds the dependency at its version, runs don't check it for updates.
+	Pinned bool `json:"pinned,omitempty"`
+	// PinnedUntil is when a pin expires, after which unpin --expired releases it. The pin
+	// holds until it is released.
+	PinnedUntil time.Time `json:"pinnedUntil,omitzero"`
+	// Strict makes a current tag that doesn't parse an error instead of allowing any update,
+	// downgrades included, for components where a downgrade is dangerous, such as on mainnet.
+	Strict bool `json:"strict,omitempty"`
+	// PreOneBreaking treats minor bumps of 0.x versions, and patch bumps of 0.0.x ones, as
+	// breaking, like Cargo and npm carets do, for pre-1.0 components.
+	PreOneBreaking bool `json:"preOneBreaking,omitempty"`
+	// TieBreak picks between tags of the same version that differ in build metadata, such as
+	// respins: "published" keeps the latest published release, "build" the highest build
+	// counter and "current" the current tag. Unset keeps the tag listed first.
+	TieBreak string `json:"tieBreak,omitempty"`
+	// Image is the upstream's container image, such as "ghcr.io/paradigmxyz/reth", tagged
+	// with ImageTagOf each release.
+	Image string `json:"image,omitempty"`
+	// VPrefix is the component's convention for the leading "v" of its image tags, "include"
+	// or "omit", such as nethermind's 1.35.3 images. Unset tags images like upstream's tag.
+	// versions.json and versions.env always keep upstream's tag, the Dockerfiles check it out.
+	VPrefix string `json:"vPrefix,omitempty"`
+	// ImageDigest is the digest the image tag of Tag had when it was recorded, such as
+	// "sha256:...", to catch the tag being re-pushed with other content.
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// ImageVariant is the variant of the image that is run, such as "alltools" for
+	// ethereum/client-go's v1.14.0-alltools, so its image tags carry the variant suffix.
+	ImageVariant string `json:"imageVariant,omitempty"`
+	// Provenance, if set, holds candidates whose SLSA provenance doesn't match it.
+	Provenance *Provenance `json:"provenance,omitempty"`
+	// Aliases are earlier names of the upstream, so tags from before a rename of the
+	// repository or the tag prefix still parse and compare.
+	Aliases []Alias `json:"aliases,omitempty"`
+	// Consensus, if set, holds candidates until they are published everywhere it names.
+	Consensus *Consensus `json:"consensus,omitempty"`
+	// Retracted are versions, or tags, upstream has yanked. They are never selected, and a
+	// dependency at one moves to the nearest version that isn't, even if that is a downgrade.
+	Retracted []string `json:"retracted,omitempty"`
+}
+
+// IsRetracted reports whether tag is retracted upstream: it is listed by name, or is the same
+// version as a listed one.
+func (i *Info) IsRetracted(tag string) bool {
+	if len(i.Retracted) == 0 {
+		return false
+	}
+	if slices.Contains(i.Retracted, tag) {
+		return true
+	}
+	v, err := ParseVersion(tag, i.PrefixOf(tag))
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(i.Retracted, func(retracted string) bool {
+		r, err := ParseVersion(retracted, i.PrefixOf(retracted))
+		return err == nil && r.Equal(v)
+	})
+}
+
+// Alias is an earlier name of a dependency's upstream: the repository before a rename, or the
+// tag prefix of its older tags. Empty fields didn't change.
+type Alias struct {
+	Owner     string `json:"owner,omitempty"`
+	Repo      string `json:"repo,omitempty"`
+	TagPrefix string `json:"tagPrefix,omitempty"`
+}
+
+// PrefixOf returns the tag prefix to parse tag with: the alias prefix it starts with, if it
+// doesn't start with TagPrefix, else TagPrefix.
+func (i *Info) PrefixOf(tag string) string {
+	if i.TagPrefix != "" && strings.HasPrefix(tag, i.TagPrefix+"/") {
+		return i.TagPrefix
+	}
+	for _, alias := range i.Aliases {
+		if alias.TagPrefix != "" && strings.HasPrefix(tag, alias.TagPrefix+"/") {
+			return alias.TagPrefix
+		}
+	}
+	return i.TagPrefix
+}
+
+// HasPrefix reports whether tag starts with TagPrefix or an alias prefix. Without a TagPrefix
+// every tag does.
+func (i *Info) HasPrefix(tag string) bool {
+	if i.TagPrefix == "" || strings.HasPrefix(tag, i.TagPrefix) {
+		return true
+	}
+	return slices.ContainsFunc(i.Aliases, func(alias Alias) bool {
+		return alias.TagPrefix != "" && strings.HasPrefix(tag, alias.TagPrefix)
+	})
+}
+
+// IsRepo reports whether owner/repo is the dependency's repository, or an earlier name of it.
+func (i *Info) IsRepo(owner string, repo string) bool {
+	if strings.EqualFold(owner, i.Owner) && strings.EqualFold(repo, i.Repo) {
+		return true
+	}
+	return slices.ContainsFunc(i.Aliases, func(alias Alias) bool {
+		return strings.EqualFold(owner, cmp.Or(alias.Owner, i.Owner)) && strings.EqualFold(repo, cmp.Or(alias.Repo, i.Repo))
+	})
+}
+
+// Consensus is where else a candidate has to be published before it is valid, against
+// releases that are only half published.
+type Consensus struct {
+	// Sources are other version sources, such as a mirror, that have to list the candidate's
+	// tag, at the same commit if they name one.
+	Sources []string `json:"sources,omitempty"`
+	// Image requires the dependency's image to be published with the candidate's image tag.
+	Image bool `json:"image,omitempty"`
+}
+
+// Provenance is what a candidate's SLSA provenance has to attest to. The source repository
+// and tag are always checked against the dependency and the candidate.
+type Provenance struct {
+	// Builder is the builder ID, or a prefix of it ending at a "/" or "@", such as
+	// "https://github.com/slsa-framework/slsa-github-generator".
+	Builder string `json:"builder"`
+	// Asset is a glob matching the name of the release asset holding the provenance,
+	// "*.intoto.jsonl" if empty.
+	Asset string `json:"asset,omitempty"`
+}
+
+// ImageTag is the image tag of a release tag, the tag without its tag prefix.
+func ImageTag(tag string, tagPrefix string) string {
+	if tagPrefix != "" {
+		return strings.TrimPrefix(tag, tagPrefix+"/")
+	}
+	return tag
+}
+
+// ImageTagOf is the dependency's image tag of a release tag, ImageTag followed by the
+// ImageVariant suffix, such as "v1.14.0-alltools".
+func (i *Info) ImageTagOf(tag string) string {
+	imageTag := ImageTag(i.WithVPrefix(tag), i.PrefixOf(tag))
+	if i.ImageVariant != "" {
+		imageTag += "-" + i.ImageVariant
+	}
+	return imageTag
+}
+
+// VPrefixes are the values of a dependency's vPrefix.
+var VPrefixes = []string{"include", "omit"}
+
+// WithVPrefix returns tag with the leading "v" of its version, after the tag prefix, added or
+// removed as VPrefix has it, such as "1.35.3" for "v1.35.3" with "omit". Tags whose version
+// doesn't start with a digit, like branch names, are returned as they are.
+func (i *Info) WithVPrefix(tag string) string {
+	prefix := i.PrefixOf(tag)
+	rest := tag
+	if prefix != "" && strings.HasPrefix(tag, prefix+"/") {
+		prefix, rest = tag[:len(prefix)+1], tag[len(prefix)+1:]
+	} else {
+		prefix = ""
+	}
+	bare := rest
+	if len(rest) > 1 && (rest[0] == 'v' || rest[0] == 'V') {
+		bare = rest[1:]
+	}
+	if bare == "" || bare[0] < '0' || bare[0] > '9' {
+		return tag
+	}
+	switch i.VPrefix {
+	case "include":
+		return prefix + "v" + bare
+	case "omit":
+		return prefix + bare
+	}
+	return tag
+}
+
+// ImageVariants are the variant suffixes client images commonly ship besides their plain
+// release tags.
+var ImageVariants = []string{"alltools", "slim", "alpine", "distroless"}
+
+// SplitImageVariant splits an image tag such as "v1.14.0-alltools" into its release tag and
+// the variant, one of ImageVariants, or returns it whole if it has none.
+func SplitImageVariant(imageTag string) (tag string, variant string) {
+	for _, variant := range ImageVariants {
+		if tag, ok := strings.CutSuffix(imageTag, "-"+variant); ok && tag != "" {
+			return tag, variant
+		}
+	}
+	return imageTag, ""
+}
+
+// Dependencies is the content of versions.json keyed by dependency name.
+type Dependencies = map[string]*Info
+
+// Names returns the dependency names in sorted order, so everything iterating dependencies
+// reports and plans in the same order on every run.
+func Names(dependencies Dependencies) []string {
+	return slices.Sorted(maps.Keys(dependencies))
+}
+
+// UpdateInfo describes an update for commit messages and notifications.
+type UpdateInfo struct {
+	Repo    string `json:"repo"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	DiffUrl string `json:"diffUrl"`
+	// Kind is KindPromotion for the stable release of the release candidate the dependency is
+	// at, KindRetraction for moving off a retracted version, empty for other updates.
+	Kind string `json:"kind,omitempty"`
+	// Published is when the release of To was published, zero if unknown.
+	Published time.Time `json:"published,omitzero"`
+}
+
+// KindPromotion marks updates promoting a release candidate to its stable release.
+const KindPromotion = "promotion"
+
+// KindRetraction marks updates moving off a version upstream retracted, which may be
+// downgrades.
+const KindRetraction = "retraction"
+
+// PlannedUpdate is a selected version that has not been written to versions.json yet.
+type PlannedUpdate struct {
+	Dependency string
+	Version    string
+	Commit     string
+	Info       UpdateInfo
+	// Pin sets the dependency's "pinned" field, holding it at Version, and PinUntil its
+	// "pinnedUntil".
+	Pin      bool
+	PinUntil time.Time
+	// ImageDigest, if set, is recorded as the dependency's "imageDigest". Updates to another
+	// version without one drop the recorded digest, which was that of the previous tag.
+	ImageDigest string
+}
+
+// ReadDependencies reads versions.json from the root of the repository at repoPath.
+func ReadDependencies(repoPath string) (Dependencies, error) {
+	var dependencies Dependencies
+	f, err := os.ReadFile(repoPath + "/versions.json")
+	if err != nil {
+		return nil, fmt.Errorf("error reading versions JSON: %s", err)
+	}
+	// Editors on Windows may save it with a byte order mark.
+	if err := json.Unmarshal(bytes.TrimPrefix(f, []byte("\xef\xbb\xbf")), &dependencies); err != nil {
+		return nil, fmt.Errorf("error unmarshalling versions JSON to dependencies: %s", err)
+	}
+	for _, info := range dependencies {
+		// Tags pasted from release pages may carry invisible characters, written back clean.
+		if info != nil {
+			info.Tag, info.TagPrefix = SanitizeTag(info.Tag), SanitizeTag(info.TagPrefix)
+		}
+	}
+	return dependencies, nil
+}
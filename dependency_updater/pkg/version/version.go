@@ -0,0 +1,365 @@
+// Package version parses and compares dependency tags and models versions.json, the
+// manifest of pinned dependencies.
+package version
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// rcPattern matches various RC formats: -rc1, -rc.1, -rc-1, -RC1, etc.
+var rcPattern = regexp.MustCompile(`(?i)-rc[.-]?(\d+)`)
+
+// ErrInvalidVersion is wrapped by the errors of ParseVersion for tags that are not versions.
+var ErrInvalidVersion = errors.New("invalid version format")
+
+// maxTagLength bounds the tags that are parsed at all. Git allows longer ones, but they are
+// no versions, and upstream tags are untrusted input.
+const maxTagLength = 256
+
+// errTooLong describes a tag longer than maxTagLength, quoting only its start.
+func errTooLong(tag string) error {
+	return fmt.Errorf("tag %q... is longer than %d bytes", tag[:32], maxTagLength)
+}
+
+// parsed caches the results of ParseVersion by tag and prefix, runs parse the same tags over
+// and over while filtering, sorting and reporting. It is cleared once it holds
+// maxParsed results, so long running operators don't grow it without bound.
+var parsed = struct {
+	sync.Mutex
+	results map[parseKey]parseResult
+}{results: map[parseKey]parseResult{}}
+
+const maxParsed = 1 << 16
+
+type parseKey struct{ tag, tagPrefix string }
+
+type parseResult struct {
+	version *semver.Version
+	err     error
+}
+
+// ParseVersion extracts and normalizes a semantic version from a tag string.
+// It handles tagPrefix stripping, v-prefix normalization, and RC format normalization.
+// Results are cached, callers must not modify the returned version.
+func ParseVersion(tag string, tagPrefix string) (*semver.Version, error) {
+	key := parseKey{tag, tagPrefix}
+	parsed.Lock()
+	result, ok := parsed.results[key]
+	parsed.Unlock()
+	if ok {
+		return result.version, result.err
+	}
+	result.version, result.err = parseVersion(tag, tagPrefix)
+	parsed.Lock()
+	if len(parsed.results) >= maxParsed {
+		clear(parsed.results)
+	}
+	parsed.results[key] = result
+	parsed.Unlock()
+	return result.version, result.err
+}
+
+func parseVersion(tag string, tagPrefix string) (*semver.Version, error) {
+	if len(tag) > maxTagLength {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidVersion, errTooLong(tag))
+	}
+
+	// Step 0: Drop invisible characters and normalize dashes of copied tags
+	versionStr := SanitizeTag(tag)
+
+	// Step 1: Strip tagPrefix if present (e.g., "op-node/v1.16.2" -> "v1.16.2")
+	if tagPrefix != "" && strings.HasPrefix(versionStr, tagPrefix) {
+		versionStr = strings.TrimPrefix(versionStr, tagPrefix)
+		versionStr = strings.TrimPrefix(versionStr, "/")
+	}
+
+	// Step 2: Normalize RC formats to semver-compatible format
+	// "-rc1" -> "-rc.1", "-rc-1" -> "-rc.1"
+	versionStr = normalizeRCFormat(versionStr)
+
+	// Step 3: Parse using Masterminds/semver (handles v prefix automatically)
+	v, err := semver.NewVersion(versionStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidVersion, tag, err)
+	}
+
+	return v, nil
+}
+
+// nightlyDatePattern matches the build date of nightly tags such as "nightly-2024-06-01",
+// "nightly-20240601" or "develop.20240601.abc1234".
+var nightlyDatePattern = regexp.MustCompile(`(?:^|[^0-9])(\d{4})-?(\d{2})-?(\d{2})(?:[^0-9]|$)`)
+
+// ParseNightly returns the build date of a nightly tag, after stripping tagPrefix like
+// ParseVersion.
+func ParseNightly(tag string, tagPrefix string) (time.Time, error) {
+	if len(tag) > maxTagLength {
+		return time.Time{}, fmt.Errorf("invalid nightly tag: %s", errTooLong(tag))
+	}
+	name := SanitizeTag(tag)
+	if tagPrefix != "" && strings.HasPrefix(name, tagPrefix) {
+		name = strings.TrimPrefix(strings.TrimPrefix(name, tagPrefix), "/")
+	}
+	match := nightlyDatePattern.FindStringSubmatch(name)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("no build date in nightly tag %q", tag)
+	}
+	date, err := time.Parse("20060102", match[1]+match[2]+match[3])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid build date in nightly tag %q: %w", tag, err)
+	}
+	return date, nil
+}
+
+// SanitizeTag cleans a tag copied from a release page or YAML: it trims whitespace, drops
+// byte order marks, zero-width characters and soft hyphens, and replaces unicode dashes and
+// minus signs with "-". Examples:
+//   - "\ufeffv1.0.0\n" -> "v1.0.0"
+//   - "v1.0.0\u2013rc1" -> "v1.0.0-rc1"
+func SanitizeTag(tag string) string {
+	clean := true
+	for i := 0; i < len(tag); i++ {
+		if tag[i] >= utf8.RuneSelf || tag[i] <= ' ' {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return tag
+	}
+	var b strings.Builder
+	for _, r := range strings.TrimFunc(tag, unicode.IsSpace) {
+		switch {
+		case r == '\ufeff', r == '\u00ad', r >= '\u200b' && r <= '\u200d', r == '\u2060':
+		case r >= '\u2010' && r <= '\u2015', r == '\u2212', r == '\ufe58', r == '\ufe63', r == '\uff0d':
+			b.WriteByte('-')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	// Whitespace can hide behind a byte order mark.
+	return strings.TrimFunc(b.String(), unicode.IsSpace)
+}
+
+// normalizeRCFormat converts various RC formats to semver-compatible format.
+// Examples: "-rc1" -> "-rc.1", "-rc-2" -> "-rc.2"
+func normalizeRCFormat(version string) string {
+	// Most tags have no RC suffix, skip the regex for them.
+	if !hasRCMarker(version) {
+		return version
+	}
+	return rcPattern.ReplaceAllString(version, "-rc.$1")
+}
+
+// hasRCMarker reports whether version contains "-rc" in any case.
+func hasRCMarker(version string) bool {
+	for i := 0; i+3 <= len(version); i++ {
+		if version[i] == '-' && (version[i+1] == 'r' || version[i+1] == 'R') && (version[i+2] == 'c' || version[i+2] == 'C') {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateVersionUpgrade checks if transitioning from currentTag to newTag
+// is a valid upgrade (not a downgrade).
+// Returns nil if valid, error explaining why if invalid.
+func ValidateVersionUpgrade(currentTag, newTag, tagPrefix string) error {
+	// First-time setup: no current version, any valid version is acceptable
+	if currentTag == "" {
+		_, err := ParseVersion(newTag, tagPrefix)
+		return err
+	}
+
+	// Parse current version
+	currentVersion, err := ParseVersion(currentTag, tagPrefix)
+	if err != nil {
+		// Current version unparseable - still validate new version is parseable
+		_, newErr := ParseVersion(newTag, tagPrefix)
+		return newErr
+	}
+
+	// Parse new version
+	newVersion, err := ParseVersion(newTag, tagPrefix)
+	if err != nil {
+		return fmt.Errorf("new version %q is not a valid semver: %w", newTag, err)
+	}
+
+	// Check for downgrade
+	if newVersion.LessThan(currentVersion) {
+		return fmt.Errorf(
+			"version downgrade detected: %s -> %s",
+			currentTag, newTag,
+		)
+	}
+
+	return nil
+}
+
+// CompareVersions compares two version tags and returns:
+// -1 if v1 < v2, 0 if v1 == v2, 1 if v1 > v2
+// Returns 0 and error if either version cannot be parsed.
+func CompareVersions(v1Tag, v2Tag, tagPrefix string) (int, error) {
+	v1, err := ParseVersion(v1Tag, tagPrefix)
+	if err != nil {
+		return 0, err
+	}
+	v2, err := ParseVersion(v2Tag, tagPrefix)
+	if err != nil {
+		return 0, err
+	}
+	return v1.Compare(v2), nil
+}
+
+// IsReleaseVersion returns true if the tag is a stable release (no prerelease suffix).
+// Examples:
+//   - "v1.0.0" -> true
+//   - "v1.0.0-rc1" -> false
+//   - "v1.0.0-synctest.0" -> false
+func IsReleaseVersion(tag string, tagPrefix string) bool {
+	v, err := ParseVersion(tag, tagPrefix)
+	if err != nil {
+		return false
+	}
+	return v.Prerelease() == ""
+}
+
+// IsRCVersion returns true if the tag is a release candidate version.
+// This matches versions with -rc, -rc.N, -rc-N, -rcN suffixes.
+// Examples:
+//   - "v1.0.0-rc1" -> true
+//   - "v1.0.0-rc.2" -> true
+//   - "v1.0.0" -> false (stable release, not RC)
+//   - "v1.0.0-synctest.0" -> false (not an RC)
+//   - "v1.0.0-alpha" -> false (not an RC)
+func IsRCVersion(tag string, tagPrefix string) bool {
+	v, err := ParseVersion(tag, tagPrefix)
+	if err != nil {
+		return false
+	}
+	return IsRCPrerelease(v.Prerelease())
+}
+
+// IsRCPrerelease returns true if the prerelease of a parsed version is ONLY an RC format
+// (e.g., "rc.1", "rc1", "rc-1"), not -synctest, -alpha, etc. It matches (?i)^rc[.-]?\d+$
+// by hand, it runs for every tag of large tag lists.
+func IsRCPrerelease(prerelease string) bool {
+	if len(prerelease) < 3 || (prerelease[0] != 'r' && prerelease[0] != 'R') || (prerelease[1] != 'c' && prerelease[1] != 'C') {
+		return false
+	}
+	digits := prerelease[2:]
+	if digits[0] == '.' || digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if digits == "" {
+		return false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Prerelease types of the channels upstreams commonly tag. Other labels, such as a fork's
+// "-base.1", are their own type.
+const (
+	PrereleaseRC       = "rc"
+	PrereleaseBeta     = "beta"
+	PrereleaseAlpha    = "alpha"
+	PrereleaseSynctest = "synctest"
+	PrereleaseNightly  = "nightly"
+)
+
+// PrereleaseType returns the type of a tag's prerelease, "" for stable releases and tags that
+// don't parse. Examples:
+//   - "v1.0.0-rc1" -> "rc"
+//   - "v1.0.0-beta.2" -> "beta"
+//   - "v1.0.0-synctest.0" -> "synctest"
+//   - "v1.0.0" -> ""
+func PrereleaseType(tag string, tagPrefix string) string {
+	v, err := ParseVersion(tag, tagPrefix)
+	if err != nil {
+		return ""
+	}
+	return PrereleaseTypeOf(v.Prerelease())
+}
+
+// PrereleaseTypeOf returns the type of the prerelease of a parsed version: its leading
+// letters in lower case, such as "rc" for "RC.1", or its first identifier if it starts with
+// none, such as "0" for "0.3". It is "" for no prerelease.
+func PrereleaseTypeOf(prerelease string) string {
+	end := 0
+	for end < len(prerelease) && (prerelease[end] >= 'a' && prerelease[end] <= 'z' || prerelease[end] >= 'A' && prerelease[end] <= 'Z') {
+		end++
+	}
+	if end == 0 {
+		end = strings.IndexAny(prerelease, ".-")
+		if end < 0 {
+			end = len(prerelease)
+		}
+	}
+	return strings.ToLower(prerelease[:end])
+}
+
+// IsPrereleaseOfType returns true if the tag is a prerelease of one of types, such as
+// IsPrereleaseOfType(tag, prefix, PrereleaseRC, PrereleaseBeta). Stable releases are of no
+// type.
+func IsPrereleaseOfType(tag string, tagPrefix string, types ...string) bool {
+	prereleaseType := PrereleaseType(tag, tagPrefix)
+	return prereleaseType != "" && slices.Contains(types, prereleaseType)
+}
+
+// IsPromotion returns true if from is a release candidate and to the stable release of the
+// same version, such as "v1.0.0-rc2" -> "v1.0.0".
+func IsPromotion(from string, to string, tagPrefix string) bool {
+	fromVersion, err := ParseVersion(from, tagPrefix)
+	if err != nil || !IsRCPrerelease(fromVersion.Prerelease()) {
+		return false
+	}
+	toVersion, err := ParseVersion(to, tagPrefix)
+	if err != nil || toVersion.Prerelease() != "" {
+		return false
+	}
+	return fromVersion.Major() == toVersion.Major() && fromVersion.Minor() == toVersion.Minor() && fromVersion.Patch() == toVersion.Patch()
+}
+
+// Bump is the largest part of the version that changes from from to to: "major", "minor",
+// "patch", or "" if none does. With preOneBreaking, a breaking change of a 0.x version, a
+// minor bump or a patch bump of 0.0.x, counts as "major", as does leaving 0.x.
+func Bump(from *semver.Version, to *semver.Version, preOneBreaking bool) string {
+	if preOneBreaking && from.Major() == 0 {
+		switch {
+		case to.Major() != 0, to.Minor() != from.Minor():
+			return "major"
+		case to.Patch() != from.Patch() && from.Minor() == 0:
+			return "major"
+		}
+	}
+	switch {
+	case to.Major() != from.Major():
+		return "major"
+	case to.Minor() != from.Minor():
+		return "minor"
+	case to.Patch() != from.Patch():
+		return "patch"
+	}
+	return ""
+}
+
+// IsReleaseOrRCVersion returns true if the tag is either a stable release or an RC version.
+// This excludes other prereleases like -alpha, -beta, -synctest, etc.
+func IsReleaseOrRCVersion(tag string, tagPrefix string) bool {
+	return IsReleaseVersion(tag, tagPrefix) || IsRCVersion(tag, tagPrefix)
+}
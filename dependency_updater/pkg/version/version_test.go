@@ -0,0 +1,561 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+func TestNormalizeRCFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"v0.3.0-rc1", "v0.3.0-rc.1"},
+		{"v0.3.0-rc.1", "v0.3.0-rc.1"},
+		{"v0.3.0-rc-1", "v0.3.0-rc.1"},
+		{"v0.3.0-RC1", "v0.3.0-rc.1"},
+		{"v0.3.0-rc12", "v0.3.0-rc.12"},
+		{"v0.3.0", "v0.3.0"},
+		{"v0.3.0-alpha", "v0.3.0-alpha"},
+		{"v0.3.0-beta.1", "v0.3.0-beta.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := normalizeRCFormat(tt.input)
+			if result != tt.expected {
+				t.Errorf("normalizeRCFormat(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag       string
+		tagPrefix string
+		wantErr   bool
+	}{
+		// Standard versions
+		{"v0.2.2", "", false},
+		{"v0.3.0", "", false},
+		{"1.35.3", "", false}, // nethermind style - no v prefix
+
+		// RC versions
+		{"v0.3.0-rc1", "", false},
+		{"v0.3.0-rc.1", "", false},
+		{"v0.3.0-rc-1", "", false},
+		{"v0.3.0-rc.2", "", false},
+
+		// With tagPrefix
+		{"op-node/v1.16.2", "op-node", false},
+		{"op-node/v1.16.3-rc1", "op-node", false},
+
+		// Non-standard but parseable
+		{"v1.101603.5", "", false}, // op-geth style
+
+		// Invalid
+		{"not-a-version", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			_, err := ParseVersion(tt.tag, tt.tagPrefix)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseVersion(%q, %q) error = %v, wantErr %v", tt.tag, tt.tagPrefix, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVersionUpgrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		currentTag string
+		newTag     string
+		tagPrefix  string
+		wantErr    bool
+	}{
+		// Valid upgrades
+		{"stable to rc", "v0.2.2", "v0.3.0-rc1", "", false},
+		{"rc to rc", "v0.3.0-rc1", "v0.3.0-rc2", "", false},
+		{"rc to stable", "v0.3.0-rc2", "v0.3.0", "", false},
+		{"stable to stable", "v0.2.2", "v0.3.0", "", false},
+		{"patch upgrade", "v0.2.2", "v0.2.3", "", false},
+		{"minor upgrade", "v0.2.2", "v0.3.0", "", false},
+		{"major upgrade", "v0.2.2", "v1.0.0", "", false},
+		{"same version", "v0.2.2", "v0.2.2", "", false},
+
+		// With tagPrefix
+		{"prefix upgrade", "op-node/v1.16.2", "op-node/v1.16.3", "op-node", false},
+		{"prefix rc upgrade", "op-node/v1.16.2", "op-node/v1.17.0-rc1", "op-node", false},
+
+		// No v prefix (nethermind style)
+		{"no v prefix upgrade", "1.35.3", "1.35.4", "", false},
+
+		// Invalid downgrades
+		{"downgrade major", "v0.3.0", "v0.2.2", "", true},
+		{"downgrade minor", "v0.3.0", "v0.2.9", "", true},
+		{"downgrade patch", "v0.3.1", "v0.3.0", "", true},
+		{"stable to rc same version", "v0.3.0", "v0.3.0-rc2", "", true},
+		{"stable to rc older version", "v0.3.0", "v0.2.0-rc1", "", true},
+
+		// Edge cases
+		{"empty current - valid new", "", "v0.3.0", "", false},
+		{"empty current - invalid new", "", "not-a-version", "", true},
+		{"unparseable current allows update", "not-semver", "v0.3.0", "", false},
+
+		// Unparseable current with unparseable new should fail
+		{"unparseable current - unparseable new", "rollup-boost/v0.7.11", "websocket-proxy/v0.0.2", "", true},
+		{"unparseable current - valid new", "rollup-boost/v0.7.11", "v0.8.0", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVersionUpgrade(tt.currentTag, tt.newTag, tt.tagPrefix)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateVersionUpgrade(%q, %q, %q) error = %v, wantErr %v",
+					tt.currentTag, tt.newTag, tt.tagPrefix, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name      string
+		v1        string
+		v2        string
+		tagPrefix string
+		want      int
+	}{
+		{"v1 less than v2", "v0.2.2", "v0.3.0", "", -1},
+		{"v1 greater than v2", "v0.3.0", "v0.2.2", "", 1},
+		{"equal versions", "v0.3.0", "v0.3.0", "", 0},
+		{"rc less than stable", "v0.3.0-rc1", "v0.3.0", "", -1},
+		{"rc1 less than rc2", "v0.3.0-rc1", "v0.3.0-rc2", "", -1},
+		{"stable greater than rc", "v0.3.0", "v0.3.0-rc2", "", 1},
+		{"with prefix", "op-node/v1.16.2", "op-node/v1.16.3", "op-node", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CompareVersions(tt.v1, tt.v2, tt.tagPrefix)
+			if err != nil {
+				t.Errorf("CompareVersions(%q, %q, %q) unexpected error: %v", tt.v1, tt.v2, tt.tagPrefix, err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CompareVersions(%q, %q, %q) = %d, want %d", tt.v1, tt.v2, tt.tagPrefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsReleaseVersion(t *testing.T) {
+	tests := []struct {
+		tag       string
+		tagPrefix string
+		want      bool
+	}{
+		// Stable releases
+		{"v1.0.0", "", true},
+		{"v0.2.2", "", true},
+		{"1.35.3", "", true}, // nethermind style
+		{"v1.101603.5", "", true}, // op-geth style
+
+		// With prefix
+		{"op-node/v1.16.2", "op-node", true},
+
+		// Pre-release versions (should return false)
+		{"v1.0.0-rc1", "", false},
+		{"v1.0.0-rc.1", "", false},
+		{"v1.0.0-rc-1", "", false},
+		{"v1.0.0-synctest.0", "", false},
+		{"v1.0.0-alpha", "", false},
+		{"v1.0.0-beta.1", "", false},
+		{"op-node/v1.16.6-synctest.0", "op-node", false},
+		{"op-node/v1.16.3-rc1", "op-node", false},
+
+		// Invalid versions (should return false)
+		{"not-a-version", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got := IsReleaseVersion(tt.tag, tt.tagPrefix)
+			if got != tt.want {
+				t.Errorf("IsReleaseVersion(%q, %q) = %v, want %v", tt.tag, tt.tagPrefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRCVersion(t *testing.T) {
+	tests := []struct {
+		tag       string
+		tagPrefix string
+		want      bool
+	}{
+		// RC versions
+		{"v1.0.0-rc1", "", true},
+		{"v1.0.0-rc.1", "", true},
+		{"v1.0.0-rc-1", "", true},
+		{"v1.0.0-RC1", "", true},
+		{"v1.0.0-rc12", "", true},
+		{"op-node/v1.16.3-rc1", "op-node", true},
+		{"op-node/v1.16.3-rc.2", "op-node", true},
+
+		// Stable releases (not RC)
+		{"v1.0.0", "", false},
+		{"v0.2.2", "", false},
+		{"op-node/v1.16.2", "op-node", false},
+
+		// Other pre-release versions (not RC)
+		{"v1.0.0-synctest.0", "", false},
+		{"op-node/v1.16.6-synctest.0", "op-node", false},
+		{"v1.0.0-alpha", "", false},
+		{"v1.0.0-beta.1", "", false},
+		{"v1.0.0-alpha.rc1", "", false}, // rc is part of another prerelease
+
+		// Invalid versions
+		{"not-a-version", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got := IsRCVersion(tt.tag, tt.tagPrefix)
+			if got != tt.want {
+				t.Errorf("IsRCVersion(%q, %q) = %v, want %v", tt.tag, tt.tagPrefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsReleaseOrRCVersion(t *testing.T) {
+	tests := []struct {
+		tag       string
+		tagPrefix string
+		want      bool
+	}{
+		// Stable releases - should pass
+		{"v1.0.0", "", true},
+		{"v0.2.2", "", true},
+		{"op-node/v1.16.2", "op-node", true},
+
+		// RC versions - should pass
+		{"v1.0.0-rc1", "", true},
+		{"v1.0.0-rc.1", "", true},
+		{"op-node/v1.16.3-rc1", "op-node", true},
+
+		// Other pre-release versions - should NOT pass
+		{"v1.0.0-synctest.0", "", false},
+		{"op-node/v1.16.6-synctest.0", "op-node", false},
+		{"v1.0.0-alpha", "", false},
+		{"v1.0.0-beta.1", "", false},
+
+		// Invalid versions
+		{"not-a-version", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got := IsReleaseOrRCVersion(tt.tag, tt.tagPrefix)
+			if got != tt.want {
+				t.Errorf("IsReleaseOrRCVersion(%q, %q) = %v, want %v", tt.tag, tt.tagPrefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRCVersionOrdering(t *testing.T) {
+	// Verify that RC versions are ordered correctly
+	versions := []string{
+		"v0.2.2",
+		"v0.3.0-rc.1",
+		"v0.3.0-rc.2",
+		"v0.3.0",
+		"v0.3.1",
+	}
+
+	for i := 0; i < len(versions)-1; i++ {
+		current := versions[i]
+		next := versions[i+1]
+		t.Run(current+" -> "+next, func(t *testing.T) {
+			err := ValidateVersionUpgrade(current, next, "")
+			if err != nil {
+				t.Errorf("Expected %s -> %s to be valid upgrade, got error: %v", current, next, err)
+			}
+		})
+	}
+
+	// Verify reverse order is invalid
+	for i := len(versions) - 1; i > 0; i-- {
+		current := versions[i]
+		previous := versions[i-1]
+		t.Run(current+" -> "+previous+" (downgrade)", func(t *testing.T) {
+			err := ValidateVersionUpgrade(current, previous, "")
+			if err == nil {
+				t.Errorf("Expected %s -> %s to be invalid downgrade", current, previous)
+			}
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	dependencies := Dependencies{"reth": {}, "op_node": {}, "base_reth_node": {}, "op_geth": {}}
+	want := []string{"base_reth_node", "op_geth", "op_node", "reth"}
+	for i := 0; i < 10; i++ {
+		if got := Names(dependencies); !slices.Equal(got, want) {
+			t.Fatalf("Names() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestIsRCPrerelease(t *testing.T) {
+	pattern := regexp.MustCompile(`(?i)^-rc[.-]?\d+$`)
+	for _, prerelease := range []string{"rc.1", "rc1", "rc-1", "RC12", "Rc.3", "rc", "rc.", "rc.1a", "rc..1", "rc.1.2", "synctest.0", "alpha", "r", ""} {
+		if got, want := IsRCPrerelease(prerelease), pattern.MatchString("-"+prerelease); got != want {
+			t.Errorf("IsRCPrerelease(%q) = %v, want %v", prerelease, got, want)
+		}
+	}
+}
+
+func BenchmarkParseVersion(b *testing.B) {
+	for b.Loop() {
+		for _, tag := range []string{"v1.101702.0", "op-node/v1.16.2", "v1.3.0-rc1", "v1.3.0-synctest.0"} {
+			if _, err := ParseVersion(tag, "op-node"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		from           string
+		to             string
+		preOneBreaking bool
+		want           string
+	}{
+		{"1.2.3", "2.0.0", false, "major"},
+		{"1.2.3", "1.3.0", false, "minor"},
+		{"1.2.3", "1.2.4", false, "patch"},
+		{"1.2.3", "1.2.3", false, ""},
+		{"1.2.3", "1.3.0", true, "minor"},
+		{"0.2.3", "0.3.0", false, "minor"},
+		{"0.2.3", "0.3.0", true, "major"},
+		{"0.2.3", "0.2.4", true, "patch"},
+		{"0.0.3", "0.0.4", false, "patch"},
+		{"0.0.3", "0.0.4", true, "major"},
+		{"0.9.0", "1.0.0", true, "major"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s to %s strict %v", tt.from, tt.to, tt.preOneBreaking), func(t *testing.T) {
+			if got := Bump(semver.MustParse(tt.from), semver.MustParse(tt.to), tt.preOneBreaking); got != tt.want {
+				t.Errorf("Bump() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliases(t *testing.T) {
+	info := &Info{Owner: "base", Repo: "node-reth", TagPrefix: "node-reth", Aliases: []Alias{{Repo: "op-reth-base", TagPrefix: "op-reth"}, {Owner: "Coinbase"}}}
+	for tag, want := range map[string]string{"node-reth/v1.2.0": "node-reth", "op-reth/v1.1.0": "op-reth", "other/v1.0.0": "node-reth"} {
+		if got := info.PrefixOf(tag); got != want {
+			t.Errorf("PrefixOf(%s) = %s, want %s", tag, got, want)
+		}
+	}
+	for tag, want := range map[string]bool{"node-reth/v1.2.0": true, "op-reth/v1.1.0": true, "other/v1.0.0": false} {
+		if got := info.HasPrefix(tag); got != want {
+			t.Errorf("HasPrefix(%s) = %v, want %v", tag, got, want)
+		}
+	}
+	for repo, want := range map[string]bool{"base/node-reth": true, "base/op-reth-base": true, "coinbase/node-reth": true, "coinbase/op-reth-base": false} {
+		owner, name, _ := strings.Cut(repo, "/")
+		if got := info.IsRepo(owner, name); got != want {
+			t.Errorf("IsRepo(%s) = %v, want %v", repo, got, want)
+		}
+	}
+}
+
+func TestImageVariant(t *testing.T) {
+	info := &Info{TagPrefix: "op-geth", ImageVariant: "alltools"}
+	if got := info.ImageTagOf("op-geth/v1.14.0"); got != "v1.14.0-alltools" {
+		t.Errorf("ImageTagOf() = %s, want v1.14.0-alltools", got)
+	}
+	for imageTag, want := range map[string][2]string{
+		"v1.14.0-alltools":  {"v1.14.0", "alltools"},
+		"v1.14.0-rc.1-slim": {"v1.14.0-rc.1", "slim"},
+		"v1.14.0-rc.1":      {"v1.14.0-rc.1", ""},
+		"slim":              {"slim", ""},
+	} {
+		if tag, variant := SplitImageVariant(imageTag); tag != want[0] || variant != want[1] {
+			t.Errorf("SplitImageVariant(%s) = %s, %s, want %s, %s", imageTag, tag, variant, want[0], want[1])
+		}
+	}
+}
+
+func TestPrereleaseType(t *testing.T) {
+	tests := []struct {
+		tag       string
+		tagPrefix string
+		want      string
+	}{
+		{"v1.0.0-rc1", "", PrereleaseRC},
+		{"op-node/v1.0.0-RC.2", "op-node", PrereleaseRC},
+		{"v1.0.0-beta.2", "", PrereleaseBeta},
+		{"v1.0.0-alpha", "", PrereleaseAlpha},
+		{"v1.0.0-synctest.0", "", PrereleaseSynctest},
+		{"v1.0.0-nightly.20260101", "", PrereleaseNightly},
+		{"v1.0.0-base2.1", "", "base"},
+		{"v1.0.0-0.3", "", "0"},
+		{"v1.0.0", "", ""},
+		{"not-a-version", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := PrereleaseType(tt.tag, tt.tagPrefix); got != tt.want {
+				t.Errorf("PrereleaseType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if !IsPrereleaseOfType("v1.0.0-beta.1", "", PrereleaseRC, PrereleaseBeta) || IsPrereleaseOfType("v1.0.0-alpha.1", "", PrereleaseRC, PrereleaseBeta) || IsPrereleaseOfType("v1.0.0", "", "") {
+		t.Errorf("IsPrereleaseOfType() matched the wrong types")
+	}
+}
+
+func TestParseVersionCache(t *testing.T) {
+	first, err := ParseVersion("op-node/v1.16.2", "op-node")
+	if err != nil {
+		t.Fatalf("ParseVersion() unexpected error: %v", err)
+	}
+	if again, _ := ParseVersion("op-node/v1.16.2", "op-node"); again != first {
+		t.Errorf("ParseVersion() parsed a cached tag again")
+	}
+	if other, _ := ParseVersion("op-node/v1.16.2", ""); other == first {
+		t.Errorf("ParseVersion() shared the result of another prefix")
+	}
+	_, err = ParseVersion("not-a-version", "")
+	if _, again := ParseVersion("not-a-version", ""); err == nil || again != err {
+		t.Errorf("ParseVersion() error = %v, cached %v, want the same error", err, again)
+	}
+}
+
+func BenchmarkParseVersionUncached(b *testing.B) {
+	for b.Loop() {
+		for _, tag := range []string{"v1.101702.0", "op-node/v1.16.2", "v1.3.0-rc1", "v1.3.0-synctest.0"} {
+			if _, err := parseVersion(tag, "op-node"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestSanitizeTag(t *testing.T) {
+	for tag, want := range map[string]string{
+		"v1.0.0":                  "v1.0.0",
+		" v1.0.0\n":               "v1.0.0",
+		"\ufeffv1.0.0":            "v1.0.0",
+		"\ufeff v1.0.0":           "v1.0.0",
+		"v1.0.0\u200b":            "v1.0.0",
+		"op\u2011node/v1.0.0":     "op-node/v1.0.0",
+		"v1.0.0\u2013rc1":         "v1.0.0-rc1",
+		"nightly\u22122026-01-02": "nightly-2026-01-02",
+	} {
+		if got := SanitizeTag(tag); got != want {
+			t.Errorf("SanitizeTag(%q) = %q, want %q", tag, got, want)
+		}
+	}
+	if v, err := ParseVersion("\ufeffop-node/v1.16.2\u2013rc1 ", "op-node"); err != nil || v.String() != "1.16.2-rc.1" {
+		t.Errorf("ParseVersion() of an unclean tag = %v, %v", v, err)
+	}
+}
+
+func TestWithVPrefix(t *testing.T) {
+	tests := []struct {
+		vPrefix string
+		tag     string
+		want    string
+	}{
+		{"omit", "v1.35.3", "1.35.3"},
+		{"omit", "1.35.3", "1.35.3"},
+		{"include", "1.35.3", "v1.35.3"},
+		{"include", "op-node/1.16.2", "op-node/v1.16.2"},
+		{"omit", "op-node/v1.16.2", "op-node/1.16.2"},
+		{"omit", "nightly-2026-01-02", "nightly-2026-01-02"},
+		{"", "v1.35.3", "v1.35.3"},
+	}
+	for _, tt := range tests {
+		info := &Info{TagPrefix: "op-node", VPrefix: tt.vPrefix}
+		if got := info.WithVPrefix(tt.tag); got != tt.want {
+			t.Errorf("WithVPrefix(%s) with %q = %s, want %s", tt.tag, tt.vPrefix, got, tt.want)
+		}
+	}
+	if got := (&Info{VPrefix: "omit"}).ImageTagOf("v1.35.3"); got != "1.35.3" {
+		t.Errorf("ImageTagOf() = %s, want 1.35.3", got)
+	}
+}
+
+func TestParseVersionErrors(t *testing.T) {
+	for _, tag := range []string{"latest", "", "v1..0", "v" + strings.Repeat("1", maxTagLength)} {
+		if v, err := ParseVersion(tag, ""); v != nil || !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("ParseVersion(%.40q) = %v, %v, want ErrInvalidVersion", tag, v, err)
+		}
+	}
+	if _, err := ParseNightly("nightly-2025-01-02"+strings.Repeat("x", maxTagLength), ""); err == nil {
+		t.Errorf("ParseNightly() of an overlong tag expected an error")
+	}
+}
+
+// fuzzTags seed the fuzz targets with the tag shapes found upstream.
+var fuzzTags = []string{
+	"v1.16.2", "op-node/v1.16.2", "v0.3.0-rc1", "v0.3.0-RC-2", "1.35.3", "v1.4.8-alpha.1+build.5",
+	"nightly-2024-06-01", "develop.20240601.abc1234", "\ufeffv1.0.0\u2013rc1\n", "v01.2.3", "-rc-rc1", "v1.2.3-rc.-rc1",
+}
+
+func FuzzParseVersion(f *testing.F) {
+	for _, tag := range fuzzTags {
+		f.Add(tag, "")
+		f.Add(tag, "op-node")
+	}
+	f.Fuzz(func(t *testing.T, tag string, tagPrefix string) {
+		v, err := ParseVersion(tag, tagPrefix)
+		if err != nil {
+			if v != nil || !errors.Is(err, ErrInvalidVersion) {
+				t.Fatalf("ParseVersion(%q, %q) = %v, %v, want ErrInvalidVersion", tag, tagPrefix, v, err)
+			}
+			return
+		}
+		again, err := ParseVersion(v.String(), "")
+		if err != nil || !again.Equal(v) {
+			t.Fatalf("ParseVersion(%q) = %s, which parses as %v, %v", tag, v, again, err)
+		}
+		ParseNightly(tag, tagPrefix)
+		PrereleaseType(tag, tagPrefix)
+	})
+}
+
+func FuzzNormalizeRCFormat(f *testing.F) {
+	for _, tag := range fuzzTags {
+		f.Add(tag)
+	}
+	f.Fuzz(func(t *testing.T, version string) {
+		normalized := normalizeRCFormat(version)
+		if !hasRCMarker(version) && normalized != version {
+			t.Fatalf("normalizeRCFormat(%q) = %q, changed a version without an RC", version, normalized)
+		}
+		if again := normalizeRCFormat(normalized); again != normalized {
+			t.Fatalf("normalizeRCFormat(%q) = %q, but normalizing it again gives %q", version, normalized, again)
+		}
+	})
+}
@@ -0,0 +1,185 @@
+// Package vulnscan scans the container images of candidates with trivy or grype, so reports
+// show their known vulnerabilities next to the update, and holds candidates that add findings
+// at or above a severity threshold compared to the current image.
+package vulnscan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/base/node/dependency_updater/pkg/runner"
+	"github.com/base/node/dependency_updater/pkg/sources"
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+// Severities from most to least severe, as trivy and grype report them.
+var Severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// rank orders severities, lower is more severe. Unrecognized ones rank as UNKNOWN.
+func rank(severity string) int {
+	if i := slices.Index(Severities, strings.ToUpper(severity)); i >= 0 {
+		return i
+	}
+	return len(Severities) - 1
+}
+
+// Finding is one vulnerability reported for an image.
+type Finding struct {
+	ID       string
+	Severity string
+}
+
+// Scanner runs a scanner command and parses its JSON report.
+type Scanner struct {
+	// Command is "trivy" or "grype".
+	Command string
+	// Run executes the command and returns its standard output, exec if nil.
+	Run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("%s failed: %s %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+// Scan returns the findings for image, deduplicated by ID.
+func (s Scanner) Scan(ctx context.Context, image string) ([]Finding, error) {
+	execute := s.Run
+	if execute == nil {
+		execute = run
+	}
+	var findings []Finding
+	switch s.Command {
+	case "trivy":
+		out, err := execute(ctx, "trivy", "image", "--quiet", "--format", "json", image)
+		if err != nil {
+			return nil, err
+		}
+		var report struct {
+			Results []struct {
+				Vulnerabilities []struct {
+					VulnerabilityID string
+					Severity        string
+				}
+			}
+		}
+		if err := json.Unmarshal(out, &report); err != nil {
+			return nil, fmt.Errorf("error decoding trivy report: %s", err)
+		}
+		for _, result := range report.Results {
+			for _, v := range result.Vulnerabilities {
+				findings = append(findings, Finding{ID: v.VulnerabilityID, Severity: v.Severity})
+			}
+		}
+	case "grype":
+		out, err := execute(ctx, "grype", "--quiet", "--output", "json", image)
+		if err != nil {
+			return nil, err
+		}
+		var report struct {
+			Matches []struct {
+				Vulnerability struct {
+					ID       string `json:"id"`
+					Severity string `json:"severity"`
+				} `json:"vulnerability"`
+			} `json:"matches"`
+		}
+		if err := json.Unmarshal(out, &report); err != nil {
+			return nil, fmt.Errorf("error decoding grype report: %s", err)
+		}
+		for _, match := range report.Matches {
+			findings = append(findings, Finding{ID: match.Vulnerability.ID, Severity: match.Vulnerability.Severity})
+		}
+	default:
+		return nil, fmt.Errorf("unknown vulnerability scanner %q, expected trivy or grype", s.Command)
+	}
+
+	seen := map[string]bool{}
+	unique := findings[:0]
+	for _, finding := range findings {
+		if !seen[finding.ID] {
+			seen[finding.ID] = true
+			unique = append(unique, finding)
+		}
+	}
+	return unique, nil
+}
+
+// Summary counts findings by severity, "CRITICAL 1, HIGH 4", leaving out severities without any.
+func Summary(findings []Finding) string {
+	counts := make([]int, len(Severities))
+	for _, finding := range findings {
+		counts[rank(finding.Severity)]++
+	}
+	var parts []string
+	for i, count := range counts {
+		if count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", Severities[i], count))
+		}
+	}
+	if len(parts) == 0 {
+		return "no known vulnerabilities"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// New returns the findings of candidate that current doesn't have, at or above threshold.
+func New(current []Finding, candidate []Finding, threshold string) []Finding {
+	known := map[string]bool{}
+	for _, finding := range current {
+		known[finding.ID] = true
+	}
+	var added []Finding
+	for _, finding := range candidate {
+		if !known[finding.ID] && rank(finding.Severity) <= rank(threshold) {
+			added = append(added, finding)
+		}
+	}
+	return added
+}
+
+// Check scans the images of candidates of dependencies with an image in versions.json. With a
+// Threshold, candidates adding findings at or above it compared to the current image are held.
+type Check struct {
+	Scanner Scanner
+	// Threshold is a severity, such as CRITICAL. Empty only reports.
+	Threshold string
+}
+
+func (Check) Name() string { return "vulnerabilities" }
+
+func (c Check) Check(ctx context.Context, source sources.VersionSource, info *version.Info, planned version.PlannedUpdate) *runner.CheckResult {
+	if info.Image == "" || planned.Version == "" {
+		return nil
+	}
+	candidate, err := c.Scanner.Scan(ctx, info.Image+":"+info.ImageTagOf(planned.Version))
+	if err != nil {
+		// Without a threshold the scan only informs, so a failed one doesn't hold the update.
+		return &runner.CheckResult{Hold: c.Threshold != "", Detail: fmt.Sprintf("scan failed: %s", err)}
+	}
+	result := &runner.CheckResult{Passed: true, Detail: Summary(candidate)}
+	if c.Threshold == "" {
+		return result
+	}
+	current, err := c.Scanner.Scan(ctx, info.Image+":"+info.ImageTagOf(info.Tag))
+	if err != nil {
+		// Every finding counts as new then.
+		result.Detail += fmt.Sprintf(", current image scan failed: %s", err)
+	}
+	if added := New(current, candidate, c.Threshold); len(added) > 0 {
+		var ids []string
+		for _, finding := range added {
+			ids = append(ids, finding.ID)
+		}
+		result.Passed, result.Hold = false, true
+		result.Detail += fmt.Sprintf(", new at %s or above: %s", strings.ToUpper(c.Threshold), strings.Join(ids, " "))
+	}
+	return result
+}
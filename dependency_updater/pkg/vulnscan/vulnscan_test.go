@@ -0,0 +1,75 @@
+package vulnscan
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/base/node/dependency_updater/pkg/version"
+)
+
+func TestScan(t *testing.T) {
+	reports := map[string]string{
+		"trivy": `{"Results": [
+			{"Vulnerabilities": [{"VulnerabilityID": "CVE-1", "Severity": "CRITICAL"}, {"VulnerabilityID": "CVE-2", "Severity": "HIGH"}]},
+			{"Vulnerabilities": [{"VulnerabilityID": "CVE-1", "Severity": "CRITICAL"}]}
+		]}`,
+		"grype": `{"matches": [
+			{"vulnerability": {"id": "CVE-1", "severity": "Critical"}},
+			{"vulnerability": {"id": "CVE-2", "severity": "High"}}
+		]}`,
+	}
+	for command, report := range reports {
+		scanner := Scanner{Command: command, Run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name != command || args[len(args)-1] != "ghcr.io/o/reth:v1" {
+				t.Errorf("ran %s %v", name, args)
+			}
+			return []byte(report), nil
+		}}
+		findings, err := scanner.Scan(context.Background(), "ghcr.io/o/reth:v1")
+		if err != nil {
+			t.Fatalf("%s Scan() unexpected error: %v", command, err)
+		}
+		if got := Summary(findings); got != "CRITICAL 1, HIGH 1" {
+			t.Errorf("%s Summary() = %q, want CRITICAL 1, HIGH 1", command, got)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	findings := map[string]string{
+		"ghcr.io/o/op-node:v1.0.0": `{"Results": [{"Vulnerabilities": [{"VulnerabilityID": "CVE-1", "Severity": "CRITICAL"}]}]}`,
+		"ghcr.io/o/op-node:v1.1.0": `{"Results": [{"Vulnerabilities": [{"VulnerabilityID": "CVE-1", "Severity": "CRITICAL"}, {"VulnerabilityID": "CVE-2", "Severity": "HIGH"}]}]}`,
+	}
+	scanner := Scanner{Command: "trivy", Run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if report, ok := findings[args[len(args)-1]]; ok {
+			return []byte(report), nil
+		}
+		return nil, errors.New("manifest unknown")
+	}}
+	info := &version.Info{Tag: "op-node/v1.0.0", TagPrefix: "op-node", Image: "ghcr.io/o/op-node"}
+	planned := version.PlannedUpdate{Version: "op-node/v1.1.0"}
+
+	tests := []struct {
+		threshold  string
+		wantHold   bool
+		wantDetail string
+	}{
+		{"", false, "CRITICAL 1, HIGH 1"},
+		{"CRITICAL", false, "CRITICAL 1, HIGH 1"},
+		{"HIGH", true, "new at HIGH or above: CVE-2"},
+	}
+	for _, tt := range tests {
+		result := Check{Scanner: scanner, Threshold: tt.threshold}.Check(context.Background(), nil, info, planned)
+		if result == nil || result.Hold != tt.wantHold || !strings.Contains(result.Detail, tt.wantDetail) {
+			t.Errorf("Check() with threshold %q = %+v", tt.threshold, result)
+		}
+	}
+	if result := (Check{Scanner: scanner, Threshold: "HIGH"}).Check(context.Background(), nil, info, version.PlannedUpdate{Version: "op-node/v2.0.0"}); result == nil || !result.Hold {
+		t.Errorf("Check() of an image that fails to scan = %+v, want held", result)
+	}
+	if result := (Check{Scanner: scanner}).Check(context.Background(), nil, &version.Info{}, planned); result != nil {
+		t.Errorf("Check() without an image = %+v, want nil", result)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+var webhookFlag = &cli.StringFlag{
+	Name:    "webhook",
+	Usage:   "Webhook URL alerts are POSTed to as {\"text\": ...} (Slack compatible), alerts are only logged if unset",
+	Sources: cli.EnvVars("NODE_TOOLS_WEBHOOK"),
+}
+
+type alerter struct {
+	webhook string
+	client  *http.Client
+}
+
+func newAlerter(webhook string) *alerter {
+	return &alerter{webhook: webhook, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// alert logs msg and forwards it to the webhook if one is configured.
+// Webhook failures are logged rather than returned so a flaky webhook never stops a monitor.
+func (a *alerter) alert(ctx context.Context, msg string) {
+	log.Printf("ALERT %s", msg)
+	if a.webhook == "" {
+		return
+	}
+	if err := a.post(ctx, msg); err != nil {
+		log.Printf("Error sending alert to webhook: %s", err)
+	}
+}
+
+func (a *alerter) post(ctx context.Context, msg string) error {
+	body, err := json.Marshal(map[string]string{"text": msg})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// restartOrder is the order services are restarted in, op-node can't make progress without the execution client.
+var restartOrder = []string{"execution", "node"}
+
+func applyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "apply",
+		Usage: "Rebuilds and restarts the containers running versions that differ from versions.json, execution client first, and waits for them to be healthy",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:    "env-file",
+				Usage:   "Network env file passed to docker compose",
+				Sources: cli.EnvVars("NETWORK_ENV"),
+				Value:   ".env.mainnet",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client the node runs (geth, reth, nethermind)",
+				Sources: cli.EnvVars("CLIENT"),
+				Value:   "geth",
+			},
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint",
+				Value: defaultNodeRPC,
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint",
+				Value: defaultELRPC,
+			},
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "Restart both containers even if they already run the pinned versions",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "How long each container has to become healthy",
+				Value: 10 * time.Minute,
+			},
+			&cli.DurationFlag{
+				Name:  "soak",
+				Usage: "How long the node must stay healthy before the update is recorded as current, 0 records it immediately",
+				Value: 10 * time.Minute,
+			},
+			&cli.Uint64Flag{
+				Name:  "max-lag",
+				Usage: "Blocks the unsafe head may fall behind the estimated chain head during the soak",
+				Value: 30,
+			},
+			webhookFlag,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			check := upgradeCheck{
+				RepoPath: cmd.String("repo"),
+				Client:   cmd.String("client"),
+				Node:     newRPCClient(cmd.String("node-rpc")),
+				EL:       newRPCClient(cmd.String("el-rpc")),
+				Timeout:  cmd.Duration("timeout"),
+			}
+			err := applyUpdate(ctx, check, cmd.String("env-file"), cmd.Bool("all"))
+			return finalizeUpdate(ctx, check, newAlerter(cmd.String("webhook")), err, cmd.Duration("soak"), cmd.Uint64("max-lag"))
+		},
+	}
+}
+
+func applyUpdate(ctx context.Context, check upgradeCheck, envFile string, all bool) error {
+	deployed, err := checkDeployedVersions(ctx, check.RepoPath, check.Client, check.EL, check.Node)
+	if err != nil {
+		log.Printf("Could not read running versions, restarting both containers: %s", err)
+		all = true
+	}
+	services := servicesToRestart(deployed, all)
+	if len(services) == 0 {
+		log.Printf("Containers already run the versions in versions.json, nothing to apply")
+		return nil
+	}
+
+	before, err := takeHeadSnapshot(ctx, check.EL)
+	haveSnapshot := err == nil
+	if haveSnapshot {
+		if err := writeHeadSnapshot(upgradeSnapshotPath(check.RepoPath), before); err != nil {
+			return err
+		}
+		log.Printf("Recorded head %d (%s) before restarting", before.Number, before.Hash)
+	} else {
+		log.Printf("Could not record the head before restarting, skipping the resume check: %s", err)
+	}
+
+	if err := runCompose(ctx, check.RepoPath, envFile, check.Client, "build"); err != nil {
+		return err
+	}
+	for _, service := range services {
+		log.Printf("Restarting %s", service)
+		if err := runCompose(ctx, check.RepoPath, envFile, check.Client, "up", "-d", "--no-deps", "--force-recreate", service); err != nil {
+			return err
+		}
+		if err := waitForService(ctx, check, service); err != nil {
+			return fmt.Errorf("%s did not become healthy within %s: %s", service, check.Timeout, err)
+		}
+		log.Printf("%s is healthy", service)
+	}
+
+	if !haveSnapshot {
+		log.Printf("Restarted %v, run node-tools version-drift to confirm the running versions", services)
+		return nil
+	}
+	failures := verifyUpgrade(ctx, check, before)
+	if len(failures) > 0 {
+		for _, f := range failures {
+			log.Printf("FAIL %s", f)
+		}
+		fmt.Println(rollbackRecommendation(before))
+		return fmt.Errorf("update applied but verification failed")
+	}
+	log.Printf("Update applied: restarted %v, node resumed from %d and is advancing on the pinned versions", services, before.Number)
+	return nil
+}
+
+// servicesToRestart returns the compose services whose component drifted from versions.json, in restart order.
+func servicesToRestart(deployed []deployedVersion, all bool) []string {
+	drifted := map[string]bool{}
+	for _, d := range deployed {
+		if d.Drift == "" {
+			continue
+		}
+		if d.Dependency == nodeDependency {
+			drifted["node"] = true
+		} else {
+			drifted["execution"] = true
+		}
+	}
+
+	var services []string
+	for _, service := range restartOrder {
+		if all || drifted[service] {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
+func waitForService(ctx context.Context, check upgradeCheck, service string) error {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	if service == "execution" {
+		_, err := waitForBlock(ctx, check.EL, "latest")
+		return err
+	}
+	for {
+		_, err := check.Node.syncStatus(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
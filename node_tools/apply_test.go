@@ -0,0 +1,35 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestServicesToRestart(t *testing.T) {
+	el := deployedVersion{Component: "geth", Dependency: "op_geth"}
+	node := deployedVersion{Component: "op-node", Dependency: nodeDependency}
+	elDrift := el
+	elDrift.Drift = "running 1.101701.0, pinned 1.101702.0"
+	nodeDrift := node
+	nodeDrift.Drift = "running 1.16.10, pinned 1.16.11"
+
+	tests := []struct {
+		name     string
+		deployed []deployedVersion
+		all      bool
+		want     []string
+	}{
+		{"up to date", []deployedVersion{el, node}, false, nil},
+		{"client drifted", []deployedVersion{elDrift, node}, false, []string{"execution"}},
+		{"op-node drifted", []deployedVersion{el, nodeDrift}, false, []string{"node"}},
+		{"both drifted, execution first", []deployedVersion{nodeDrift, elDrift}, false, []string{"execution", "node"}},
+		{"all", nil, true, []string{"execution", "node"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := servicesToRestart(tt.deployed, tt.all); !slices.Equal(got, tt.want) {
+				t.Errorf("servicesToRestart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
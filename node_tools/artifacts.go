@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// Artifact is a network config file (rollup config, genesis) with its known-good hash.
+type Artifact struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// pinnedRegistryURL matches raw GitHub URLs at a commit rather than a branch or tag.
+var pinnedRegistryURL = regexp.MustCompile(`^https://raw\.githubusercontent\.com/[^/]+/[^/]+/[0-9a-f]{7,40}/`)
+
+// ArtifactManifest maps network name -> artifact name -> artifact.
+type ArtifactManifest = map[string]map[string]*Artifact
+
+func artifactsCommand() *cli.Command {
+	repoFlag := &cli.StringFlag{
+		Name:  "repo",
+		Usage: "Path to the node repository",
+		Value: ".",
+	}
+	networkFlag := &cli.StringFlag{
+		Name:     "network",
+		Usage:    "Network to fetch or verify artifacts for (mainnet, sepolia)",
+		Required: true,
+	}
+	manifestFlag := &cli.StringFlag{
+		Name:  "manifest",
+		Usage: "Path to the artifact manifest, defaults to node_tools/artifacts.json in the repo",
+	}
+	dirFlag := &cli.StringFlag{
+		Name:  "dir",
+		Usage: "Directory holding the local copies, defaults to <repo>/<network>",
+	}
+
+	return &cli.Command{
+		Name:  "artifacts",
+		Usage: "Fetches and validates the network's rollup config and genesis against known-good hashes",
+		Commands: []*cli.Command{
+			{
+				Name:  "fetch",
+				Usage: "Downloads the network artifacts, verifies their hashes and writes them to the local directory",
+				Flags: []cli.Flag{
+					repoFlag, networkFlag, manifestFlag, dirFlag,
+					&cli.BoolFlag{
+						Name:  "record",
+						Usage: "Record the downloaded hashes as known-good for artifacts that have none yet",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return fetchArtifacts(ctx, cmd.String("repo"), cmd.String("network"), cmd.String("manifest"), cmd.String("dir"), cmd.Bool("record"))
+				},
+			},
+			{
+				Name:  "verify",
+				Usage: "Checks that the local copies match the known-good hashes",
+				Flags: []cli.Flag{repoFlag, networkFlag, manifestFlag, dirFlag},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return verifyArtifacts(cmd.String("repo"), cmd.String("network"), cmd.String("manifest"), cmd.String("dir"))
+				},
+			},
+		},
+	}
+}
+
+func fetchArtifacts(ctx context.Context, repoPath string, networkName string, manifestPath string, dir string, record bool) error {
+	network, manifestPath, dir, err := resolveArtifactPaths(repoPath, networkName, manifestPath, dir)
+	if err != nil {
+		return err
+	}
+	manifest, err := readArtifactManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	artifacts, ok := manifest[network.Name]
+	if !ok {
+		return fmt.Errorf("no artifacts configured for network %s in %s", network.Name, manifestPath)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating %s: %s", dir, err)
+	}
+
+	recorded := false
+	for _, name := range sortedArtifactNames(artifacts) {
+		artifact := artifacts[name]
+		data, err := downloadArtifact(ctx, artifact.URL)
+		if err != nil {
+			return fmt.Errorf("error downloading %s: %s", name, err)
+		}
+		sum := sha256Hex(data)
+
+		switch {
+		case artifact.SHA256 == "" && record && !pinnedRegistryURL.MatchString(artifact.URL):
+			return fmt.Errorf("refusing to record a hash for %s, %s is not pinned to a commit", name, artifact.URL)
+		case artifact.SHA256 == "" && record:
+			log.Printf("Recording known-good hash for %s: %s", name, sum)
+			artifact.SHA256 = sum
+			recorded = true
+		case artifact.SHA256 == "":
+			return fmt.Errorf("no known-good hash recorded for %s, re-run with --record after checking the download", name)
+		case artifact.SHA256 != sum:
+			return fmt.Errorf("hash mismatch for %s: expected %s, got %s", name, artifact.SHA256, sum)
+		}
+
+		dest := filepath.Join(dir, path.Base(artifact.URL))
+		if err := writeFileAtomic(dest, data, 0644); err != nil {
+			return err
+		}
+		log.Printf("Fetched %s to %s", name, dest)
+	}
+
+	if recorded {
+		return writeArtifactManifest(manifestPath, manifest)
+	}
+	return nil
+}
+
+func verifyArtifacts(repoPath string, networkName string, manifestPath string, dir string) error {
+	network, manifestPath, dir, err := resolveArtifactPaths(repoPath, networkName, manifestPath, dir)
+	if err != nil {
+		return err
+	}
+	manifest, err := readArtifactManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	artifacts, ok := manifest[network.Name]
+	if !ok {
+		return fmt.Errorf("no artifacts configured for network %s in %s", network.Name, manifestPath)
+	}
+
+	var drifted []string
+	for _, name := range sortedArtifactNames(artifacts) {
+		artifact := artifacts[name]
+		local := filepath.Join(dir, path.Base(artifact.URL))
+		status, err := checkArtifact(local, artifact.SHA256)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-10s %-9s %s\n", name, status, local)
+		if status != "ok" {
+			drifted = append(drifted, name)
+		}
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("local artifacts do not match known-good hashes: %v", drifted)
+	}
+	return nil
+}
+
+// checkArtifact returns "ok", "missing", "unpinned" or "mismatch" for the local copy at path.
+func checkArtifact(path string, expected string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "missing", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %s", path, err)
+	}
+	if expected == "" {
+		return "unpinned", nil
+	}
+	if sha256Hex(data) != expected {
+		return "mismatch", nil
+	}
+	return "ok", nil
+}
+
+func resolveArtifactPaths(repoPath string, networkName string, manifestPath string, dir string) (Network, string, string, error) {
+	network, err := lookupNetwork(networkName)
+	if err != nil {
+		return Network{}, "", "", err
+	}
+	if manifestPath == "" {
+		manifestPath = filepath.Join(repoPath, "node_tools", "artifacts.json")
+	}
+	if dir == "" {
+		dir = filepath.Join(repoPath, network.Name)
+	}
+	return network, manifestPath, dir, nil
+}
+
+func readArtifactManifest(path string) (ArtifactManifest, error) {
+	var manifest ArtifactManifest
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading artifact manifest: %s", err)
+	}
+	if err := json.Unmarshal(f, &manifest); err != nil {
+		return nil, fmt.Errorf("error unmarshalling artifact manifest: %s", err)
+	}
+	return manifest, nil
+}
+
+func writeArtifactManifest(path string, manifest ArtifactManifest) error {
+	updated, err := json.MarshalIndent(manifest, "", "	  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling artifact manifest: %s", err)
+	}
+	return writeFileAtomic(path, append(updated, '\n'), 0644)
+}
+
+func downloadArtifact(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func sortedArtifactNames(artifacts map[string]*Artifact) []string {
+	var names []string
+	for name := range artifacts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckArtifact(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "rollup.json")
+	if err := os.WriteFile(local, []byte(`{"l2_chain_id":8453}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	good := sha256Hex([]byte(`{"l2_chain_id":8453}`))
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+		want     string
+	}{
+		{"matching hash", local, good, "ok"},
+		{"different hash", local, sha256Hex([]byte("other")), "mismatch"},
+		{"no recorded hash", local, "", "unpinned"},
+		{"missing file", filepath.Join(dir, "genesis.json"), good, "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkArtifact(tt.path, tt.expected)
+			if err != nil {
+				t.Fatalf("checkArtifact(%q, %q) unexpected error: %v", tt.path, tt.expected, err)
+			}
+			if got != tt.want {
+				t.Errorf("checkArtifact(%q, %q) = %q, want %q", tt.path, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinnedRegistryURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://raw.githubusercontent.com/ethereum-optimism/superchain-registry/2c60e5723c64/superchain/configs/mainnet/base.toml", true},
+		{"https://raw.githubusercontent.com/ethereum-optimism/superchain-registry/main/superchain/configs/mainnet/base.toml", false},
+		{"https://example.com/2c60e5723c64/base.toml", false},
+	}
+
+	for _, tt := range tests {
+		if got := pinnedRegistryURL.MatchString(tt.url); got != tt.want {
+			t.Errorf("pinnedRegistryURL.MatchString(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+// The shipped manifest is what init verifies downloads against, every artifact needs a pinned
+// URL and its known-good hash.
+func TestShippedArtifacts(t *testing.T) {
+	manifest, err := readArtifactManifest("artifacts.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, network := range networkNames() {
+		artifacts, ok := manifest[network]
+		if !ok {
+			t.Errorf("artifacts.json has no artifacts for %s", network)
+			continue
+		}
+		for _, name := range sortedArtifactNames(artifacts) {
+			artifact := artifacts[name]
+			if !pinnedRegistryURL.MatchString(artifact.URL) {
+				t.Errorf("%s %s URL %s is not pinned to a commit", network, name, artifact.URL)
+			}
+			if sum, err := hex.DecodeString(artifact.SHA256); err != nil || len(sum) != sha256.Size {
+				t.Errorf("%s %s has no known-good sha256, got %q", network, name, artifact.SHA256)
+			}
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// backupMagic prefixes passphrase encrypted archives.
+var backupMagic = []byte("BASENODEBK1")
+
+const (
+	backupSaltSize   = 16
+	backupIterations = 600000
+)
+
+// backupRepoFiles are archived relative to the repo: env files carry the JWT secret and L1 endpoints.
+var backupRepoFiles = []string{".env", ".env.mainnet", ".env.sepolia", "versions.json", "versions.env"}
+
+// backupDataFiles are the execution client identity keys, relative to the data directory.
+var backupDataFiles = []string{"geth/nodekey", "discovery-secret", "keystore/node.key.plain"}
+
+type backupOptions struct {
+	RepoPath     string
+	DataDir      string
+	EnvFile      string
+	Passphrase   string
+	AgeRecipient string
+	AgeIdentity  string
+	Force        bool
+}
+
+func backupFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "repo",
+			Usage: "Path to the node repository",
+			Value: ".",
+		},
+		&cli.StringFlag{
+			Name:  "data-dir",
+			Usage: "Chain data directory holding the execution client node key, defaults to <repo>/<client>-data",
+		},
+		&cli.StringFlag{
+			Name:    "client",
+			Usage:   "Execution client, used for the default data directory",
+			Sources: cli.EnvVars("CLIENT"),
+			Value:   "geth",
+		},
+		&cli.StringFlag{
+			Name:    "passphrase",
+			Usage:   "Passphrase used to encrypt the archive",
+			Sources: cli.EnvVars("NODE_TOOLS_BACKUP_PASSPHRASE"),
+		},
+	}
+}
+
+func backupCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "backup",
+		Usage:     "Archives identity keys, env files (including the JWT secret) and version pins to a local path, s3:// or gs://",
+		ArgsUsage: "<destination>",
+		Flags: append(backupFlags(),
+			&cli.StringFlag{
+				Name:    "env-file",
+				Usage:   "Network env file, read for OP_NODE_P2P_PRIV_PATH",
+				Sources: cli.EnvVars("NETWORK_ENV"),
+				Value:   ".env.mainnet",
+			},
+			&cli.StringFlag{
+				Name:  "age-recipient",
+				Usage: "Encrypt with age to this recipient instead of a passphrase (requires the age binary)",
+			},
+		),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() != 1 {
+				return fmt.Errorf("expected a single destination argument")
+			}
+			return backupNode(ctx, backupOptionsFromCommand(cmd), cmd.Args().First())
+		},
+	}
+}
+
+func restoreCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "restore",
+		Usage:     "Restores an archive created by backup into the repo and data directory",
+		ArgsUsage: "<source>",
+		Flags: append(backupFlags(),
+			&cli.StringFlag{
+				Name:  "age-identity",
+				Usage: "Decrypt with age using this identity file instead of a passphrase (requires the age binary)",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Overwrite files that already exist",
+			},
+		),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() != 1 {
+				return fmt.Errorf("expected a single source argument")
+			}
+			return restoreNode(ctx, backupOptionsFromCommand(cmd), cmd.Args().First())
+		},
+	}
+}
+
+func backupOptionsFromCommand(cmd *cli.Command) backupOptions {
+	dataDir := cmd.String("data-dir")
+	if dataDir == "" {
+		dataDir = filepath.Join(cmd.String("repo"), cmd.String("client")+"-data")
+	}
+	return backupOptions{
+		RepoPath:     cmd.String("repo"),
+		DataDir:      dataDir,
+		EnvFile:      cmd.String("env-file"),
+		Passphrase:   cmd.String("passphrase"),
+		AgeRecipient: cmd.String("age-recipient"),
+		AgeIdentity:  cmd.String("age-identity"),
+		Force:        cmd.Bool("force"),
+	}
+}
+
+func backupNode(ctx context.Context, opts backupOptions, dest string) error {
+	if opts.Passphrase == "" && opts.AgeRecipient == "" {
+		return fmt.Errorf("either --passphrase (or NODE_TOOLS_BACKUP_PASSPHRASE) or --age-recipient is required")
+	}
+
+	files := map[string]string{}
+	for _, f := range backupRepoFiles {
+		files["repo/"+f] = filepath.Join(opts.RepoPath, f)
+	}
+	for _, f := range backupDataFiles {
+		files["data/"+f] = filepath.Join(opts.DataDir, f)
+	}
+	if env, err := readEnvFile(filepath.Join(opts.RepoPath, opts.EnvFile)); err == nil && env["OP_NODE_P2P_PRIV_PATH"] != "" {
+		files["node/p2p-priv"] = env["OP_NODE_P2P_PRIV_PATH"]
+	}
+
+	archive, included, err := createBackupArchive(files)
+	if err != nil {
+		return err
+	}
+	if len(included) == 0 {
+		return fmt.Errorf("nothing to back up, no known files found in %s or %s", opts.RepoPath, opts.DataDir)
+	}
+
+	var encrypted []byte
+	if opts.AgeRecipient != "" {
+		encrypted, err = runFilter(ctx, archive, "age", "--encrypt", "--recipient", opts.AgeRecipient)
+	} else {
+		encrypted, err = encryptBackup(archive, opts.Passphrase)
+	}
+	if err != nil {
+		return fmt.Errorf("error encrypting backup: %s", err)
+	}
+
+	if err := storeBackup(ctx, encrypted, dest); err != nil {
+		return err
+	}
+	log.Printf("Backed up %d files to %s: %s", len(included), dest, strings.Join(included, ", "))
+	return nil
+}
+
+func restoreNode(ctx context.Context, opts backupOptions, source string) error {
+	encrypted, err := loadBackup(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	var archive []byte
+	if opts.AgeIdentity != "" {
+		archive, err = runFilter(ctx, encrypted, "age", "--decrypt", "--identity", opts.AgeIdentity)
+	} else if opts.Passphrase != "" {
+		archive, err = decryptBackup(encrypted, opts.Passphrase)
+	} else {
+		return fmt.Errorf("either --passphrase (or NODE_TOOLS_BACKUP_PASSPHRASE) or --age-identity is required")
+	}
+	if err != nil {
+		return fmt.Errorf("error decrypting backup: %s", err)
+	}
+
+	roots := map[string]string{"repo": opts.RepoPath, "data": opts.DataDir}
+	restored, err := extractBackupArchive(archive, roots, opts.Force)
+	if err != nil {
+		return err
+	}
+	log.Printf("Restored %d files: %s", len(restored), strings.Join(restored, ", "))
+	return nil
+}
+
+// createBackupArchive writes the files that exist into a tar.gz, keyed by archive name.
+func createBackupArchive(files map[string]string) ([]byte, []string, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	var included []string
+	for _, name := range sortedKeys(files) {
+		data, err := os.ReadFile(files[name])
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %s", files[name], err)
+		}
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, nil, err
+		}
+		included = append(included, name)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), included, nil
+}
+
+// extractBackupArchive restores entries under the root matching their first path element.
+// node/ entries are skipped since their destination is configured per host.
+func extractBackupArchive(archive []byte, roots map[string]string, force bool) ([]string, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup archive: %s", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var restored []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup archive: %s", err)
+		}
+
+		root, rel, ok := strings.Cut(hdr.Name, "/")
+		base, known := roots[root]
+		if !ok || !known {
+			log.Printf("Skipping %s, restore it manually", hdr.Name)
+			continue
+		}
+		if !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("refusing to restore %s outside of %s", hdr.Name, base)
+		}
+		dest := filepath.Join(base, rel)
+		if _, err := os.Stat(dest); err == nil && !force {
+			return nil, fmt.Errorf("%s already exists, use --force to overwrite", dest)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+			return nil, fmt.Errorf("error creating %s: %s", filepath.Dir(dest), err)
+		}
+		if err := writeFileAtomic(dest, data, 0600); err != nil {
+			return nil, err
+		}
+		restored = append(restored, hdr.Name)
+	}
+	return restored, nil
+}
+
+func encryptBackup(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, backupSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := backupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append(append(append([]byte{}, backupMagic...), salt...), nonce...)
+	return aead.Seal(out, nonce, plaintext, backupMagic), nil
+}
+
+func decryptBackup(data []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(data, backupMagic) {
+		return nil, fmt.Errorf("not a passphrase encrypted backup")
+	}
+	data = data[len(backupMagic):]
+	if len(data) < backupSaltSize {
+		return nil, fmt.Errorf("backup is truncated")
+	}
+	salt, data := data[:backupSaltSize], data[backupSaltSize:]
+	aead, err := backupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("backup is truncated")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, backupMagic)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted backup")
+	}
+	return plaintext, nil
+}
+
+func backupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, backupIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// storeBackup writes data to a local path or uploads it with the aws/gcloud CLI.
+func storeBackup(ctx context.Context, data []byte, dest string) error {
+	switch {
+	case strings.HasPrefix(dest, "s3://"):
+		_, err := runFilter(ctx, data, "aws", "s3", "cp", "-", dest)
+		return err
+	case strings.HasPrefix(dest, "gs://"):
+		_, err := runFilter(ctx, data, "gcloud", "storage", "cp", "-", dest)
+		return err
+	default:
+		return writeFileAtomic(dest, data, 0600)
+	}
+}
+
+func loadBackup(ctx context.Context, source string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(source, "s3://"):
+		return runFilter(ctx, nil, "aws", "s3", "cp", source, "-")
+	case strings.HasPrefix(source, "gs://"):
+		return runFilter(ctx, nil, "gcloud", "storage", "cat", source)
+	default:
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup: %s", err)
+		}
+		return data, nil
+	}
+}
+
+// runFilter pipes input through an external command and returns its stdout.
+func runFilter(ctx context.Context, input []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %s", name, strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
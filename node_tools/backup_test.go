@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptDecryptBackup(t *testing.T) {
+	plaintext := []byte("BASE_NODE_L2_ENGINE_AUTH_RAW=688f5d737bad920bdfb2fc2f488d6b6209eebda1dae949a8de91398d932c517a")
+
+	encrypted, err := encryptBackup(plaintext, "correct horse")
+	if err != nil {
+		t.Fatalf("encryptBackup() unexpected error: %v", err)
+	}
+	if bytes.Contains(encrypted, plaintext) {
+		t.Fatalf("encryptBackup() output contains the plaintext")
+	}
+
+	decrypted, err := decryptBackup(encrypted, "correct horse")
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decryptBackup() = %q, %v, want original plaintext", decrypted, err)
+	}
+	if _, err := decryptBackup(encrypted, "wrong"); err == nil {
+		t.Errorf("decryptBackup() with wrong passphrase expected error")
+	}
+	if _, err := decryptBackup(encrypted[:20], "correct horse"); err == nil {
+		t.Errorf("decryptBackup() of truncated data expected error")
+	}
+}
+
+func TestBackupArchiveRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, ".env.mainnet"), []byte("A=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(src, "geth"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "geth", "nodekey"), []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, included, err := createBackupArchive(map[string]string{
+		"repo/.env.mainnet": filepath.Join(src, ".env.mainnet"),
+		"repo/.env.sepolia": filepath.Join(src, ".env.sepolia"),
+		"data/geth/nodekey": filepath.Join(src, "geth", "nodekey"),
+	})
+	if err != nil {
+		t.Fatalf("createBackupArchive() unexpected error: %v", err)
+	}
+	if len(included) != 2 {
+		t.Fatalf("createBackupArchive() included %v, want the two existing files", included)
+	}
+
+	repo, data := t.TempDir(), t.TempDir()
+	roots := map[string]string{"repo": repo, "data": data}
+	if _, err := extractBackupArchive(archive, roots, false); err != nil {
+		t.Fatalf("extractBackupArchive() unexpected error: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(data, "geth", "nodekey")); string(got) != "key" {
+		t.Errorf("restored nodekey = %q, want %q", got, "key")
+	}
+	if _, err := extractBackupArchive(archive, roots, false); err == nil {
+		t.Errorf("extractBackupArchive() over existing files without force expected error")
+	}
+	if _, err := extractBackupArchive(archive, roots, true); err != nil {
+		t.Errorf("extractBackupArchive() with force unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// netCounters are cumulative bytes received and sent by a container since it started.
+type netCounters struct {
+	RX uint64 `json:"rx"`
+	TX uint64 `json:"tx"`
+}
+
+// bandwidthHistory keeps the last counters per container and daily totals keyed by
+// UTC date, then container.
+type bandwidthHistory struct {
+	Last  map[string]netCounters            `json:"last"`
+	Daily map[string]map[string]netCounters `json:"daily"`
+}
+
+var dockerSizeUnits = map[string]float64{
+	"B":   1,
+	"kB":  1e3,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+func bandwidthCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "bandwidth",
+		Usage: "Tracks network traffic per container and reports daily and monthly totals",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:    "env-file",
+				Usage:   "Network env file passed to docker compose",
+				Sources: cli.EnvVars("NETWORK_ENV"),
+				Value:   ".env.mainnet",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client passed to docker compose",
+				Sources: cli.EnvVars("CLIENT"),
+			},
+			&cli.StringFlag{
+				Name:  "history",
+				Usage: "Sample history file, defaults to <repo>/.node_tools/bandwidth-history.json",
+			},
+			&cli.IntFlag{
+				Name:  "days",
+				Usage: "Number of days to report",
+				Value: 7,
+			},
+			&cli.DurationFlag{
+				Name:  "watch",
+				Usage: "Keep sampling at this interval instead of taking a single sample",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			history := cmd.String("history")
+			if history == "" {
+				history = filepath.Join(cmd.String("repo"), stateDir, "bandwidth-history.json")
+			}
+			for {
+				err := sampleBandwidth(ctx, cmd.String("repo"), cmd.String("env-file"), cmd.String("client"), history, int(cmd.Int("days")))
+				if cmd.Duration("watch") == 0 {
+					return err
+				}
+				if err != nil {
+					log.Printf("WARN %s", err)
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(cmd.Duration("watch")):
+				}
+			}
+		},
+	}
+}
+
+func sampleBandwidth(ctx context.Context, repoPath string, envFile string, client string, historyPath string, days int) error {
+	ids, err := composeOutput(ctx, repoPath, envFile, client, "ps", "-q")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(ids) == "" {
+		return fmt.Errorf("no running containers found")
+	}
+	args := append([]string{"stats", "--no-stream", "--format", "{{.Name}}\t{{.NetIO}}"}, strings.Fields(ids)...)
+	out, err := exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		return fmt.Errorf("docker stats failed: %s", err)
+	}
+	counters, err := parseDockerNetIO(string(out))
+	if err != nil {
+		return err
+	}
+
+	history, err := readBandwidthHistory(historyPath)
+	if err != nil {
+		return err
+	}
+	history.record(counters, time.Now())
+	if err := writeBandwidthHistory(historyPath, history); err != nil {
+		return err
+	}
+	fmt.Print(history.report(time.Now(), days))
+	return nil
+}
+
+// record adds the traffic since the last sample to today's totals. A counter going
+// backwards means the container restarted, so the new value is all new traffic.
+func (h *bandwidthHistory) record(counters map[string]netCounters, now time.Time) {
+	day := now.UTC().Format(time.DateOnly)
+	if h.Daily[day] == nil {
+		h.Daily[day] = map[string]netCounters{}
+	}
+	for name, c := range counters {
+		last, seen := h.Last[name]
+		// The first sample of a container only establishes the baseline.
+		if seen {
+			total := h.Daily[day][name]
+			total.RX += counterDelta(last.RX, c.RX)
+			total.TX += counterDelta(last.TX, c.TX)
+			h.Daily[day][name] = total
+		}
+		h.Last[name] = c
+	}
+}
+
+func counterDelta(last uint64, current uint64) uint64 {
+	if current < last {
+		return current
+	}
+	return current - last
+}
+
+// report prints the last days daily totals and the current month's total per container.
+func (h *bandwidthHistory) report(now time.Time, days int) string {
+	var b strings.Builder
+	month := now.UTC().Format("2006-01")
+	monthly := map[string]netCounters{}
+	for day, containers := range h.Daily {
+		if !strings.HasPrefix(day, month) {
+			continue
+		}
+		for name, c := range containers {
+			total := monthly[name]
+			total.RX += c.RX
+			total.TX += c.TX
+			monthly[name] = total
+		}
+	}
+
+	var dates []string
+	for day := range h.Daily {
+		dates = append(dates, day)
+	}
+	slices.Sort(dates)
+	if len(dates) > days {
+		dates = dates[len(dates)-days:]
+	}
+	for _, day := range dates {
+		for _, name := range sortedKeys(h.Daily[day]) {
+			c := h.Daily[day][name]
+			fmt.Fprintf(&b, "%s %-30s in %-10s out %s\n", day, name, formatBytes(c.RX), formatBytes(c.TX))
+		}
+	}
+	for _, name := range sortedKeys(monthly) {
+		c := monthly[name]
+		fmt.Fprintf(&b, "%s %-30s in %-10s out %s (month to date)\n", month, name, formatBytes(c.RX), formatBytes(c.TX))
+	}
+	return b.String()
+}
+
+// parseDockerNetIO parses "name\t1.2GB / 340MB" lines from docker stats.
+func parseDockerNetIO(out string) (map[string]netCounters, error) {
+	counters := map[string]netCounters{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		name, netIO, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		rx, tx, ok := strings.Cut(netIO, " / ")
+		if !ok {
+			return nil, fmt.Errorf("unexpected NetIO %q for %s", netIO, name)
+		}
+		rxBytes, err := parseDockerSize(rx)
+		if err != nil {
+			return nil, err
+		}
+		txBytes, err := parseDockerSize(tx)
+		if err != nil {
+			return nil, err
+		}
+		counters[name] = netCounters{RX: rxBytes, TX: txBytes}
+	}
+	return counters, nil
+}
+
+func parseDockerSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %s", s, err)
+	}
+	unit, ok := dockerSizeUnits[s[i:]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in %q", s)
+	}
+	return uint64(value * unit), nil
+}
+
+func readBandwidthHistory(path string) (*bandwidthHistory, error) {
+	history := &bandwidthHistory{Last: map[string]netCounters{}, Daily: map[string]map[string]netCounters{}}
+	f, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading bandwidth history: %s", err)
+	}
+	if err := json.Unmarshal(f, history); err != nil {
+		return nil, fmt.Errorf("error unmarshalling bandwidth history: %s", err)
+	}
+	return history, nil
+}
+
+func writeBandwidthHistory(path string, history *bandwidthHistory) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating %s: %s", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling bandwidth history: %s", err)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDockerNetIO(t *testing.T) {
+	out := "base-execution-1\t1.5GB / 320MB\nbase-node-1\t12.3kB / 0B\n"
+	got, err := parseDockerNetIO(out)
+	if err != nil {
+		t.Fatalf("parseDockerNetIO() unexpected error: %v", err)
+	}
+	if got["base-execution-1"] != (netCounters{RX: 1_500_000_000, TX: 320_000_000}) {
+		t.Errorf("base-execution-1 = %+v", got["base-execution-1"])
+	}
+	if got["base-node-1"] != (netCounters{RX: 12_300, TX: 0}) {
+		t.Errorf("base-node-1 = %+v", got["base-node-1"])
+	}
+	if _, err := parseDockerNetIO("x\t1.5XB / 0B"); err == nil {
+		t.Errorf("parseDockerNetIO() with an unknown unit expected error")
+	}
+}
+
+func TestBandwidthHistoryRecord(t *testing.T) {
+	h := &bandwidthHistory{Last: map[string]netCounters{}, Daily: map[string]map[string]netCounters{}}
+	day1 := time.Date(2026, 10, 13, 23, 0, 0, 0, time.UTC)
+
+	h.record(map[string]netCounters{"node": {RX: 1000, TX: 100}}, day1)
+	h.record(map[string]netCounters{"node": {RX: 1500, TX: 300}}, day1.Add(30*time.Minute))
+	// Restarted after midnight, counters start from zero again.
+	h.record(map[string]netCounters{"node": {RX: 400, TX: 50}}, day1.Add(2*time.Hour))
+
+	if got := h.Daily["2026-10-13"]["node"]; got != (netCounters{RX: 500, TX: 200}) {
+		t.Errorf("2026-10-13 = %+v, want rx 500 tx 200", got)
+	}
+	if got := h.Daily["2026-10-14"]["node"]; got != (netCounters{RX: 400, TX: 50}) {
+		t.Errorf("2026-10-14 = %+v, want rx 400 tx 50", got)
+	}
+
+	report := h.report(day1.Add(2*time.Hour), 1)
+	if strings.Contains(report, "2026-10-13 node") || !strings.Contains(report, "2026-10-14 node") {
+		t.Errorf("report() should only list the last day:\n%s", report)
+	}
+	if !strings.Contains(report, "2026-10 node") || !strings.Contains(report, "month to date") {
+		t.Errorf("report() missing the monthly total:\n%s", report)
+	}
+}
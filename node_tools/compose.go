@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runCompose runs docker compose in repoPath with NETWORK_ENV (and CLIENT, if set) exported
+// the same way the README instructs, so the right env file and Dockerfile are used.
+func runCompose(ctx context.Context, repoPath string, envFile string, client string, args ...string) error {
+	cmd, err := dockerCompose(ctx, repoPath, envFile, client, args)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker compose %s failed: %s", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// composeOutput is like runCompose but returns stdout instead of streaming it.
+func composeOutput(ctx context.Context, repoPath string, envFile string, client string, args ...string) (string, error) {
+	cmd, err := dockerCompose(ctx, repoPath, envFile, client, args)
+	if err != nil {
+		return "", err
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("docker compose %s failed: %s", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+// streamCompose starts docker compose and returns its stdout, wait reaps the process.
+func streamCompose(ctx context.Context, repoPath string, envFile string, client string, args ...string) (io.Reader, func() error, error) {
+	cmd, err := dockerCompose(ctx, repoPath, envFile, client, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("docker compose %s failed: %s", strings.Join(args, " "), err)
+	}
+	return out, cmd.Wait, nil
+}
+
+// dockerCompose is docker compose with args, on the project of the copy rollout made live.
+// Args that already name a project, as rollout's own do, are left as they are.
+func dockerCompose(ctx context.Context, repoPath string, envFile string, client string, args []string) (*exec.Cmd, error) {
+	if len(args) == 0 || args[0] != "-p" {
+		state, err := readRolloutState(filepath.Join(repoPath, stateDir, "rollout.json"))
+		if err != nil {
+			return nil, err
+		}
+		args = append(rolloutProjectArgs(state.Active), args...)
+	}
+	cmd := exec.CommandContext(ctx, "docker", append([]string{"compose"}, args...)...)
+	cmd.Dir = repoPath
+	cmd.Env = composeEnv(envFile, client)
+	return cmd, nil
+}
+
+func composeEnv(envFile string, client string) []string {
+	env := os.Environ()
+	if envFile != "" {
+		env = append(env, "NETWORK_ENV="+envFile)
+	}
+	if client != "" {
+		env = append(env, "CLIENT="+client)
+	}
+	return env
+}
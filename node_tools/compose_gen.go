@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/urfave/cli/v3"
+)
+
+// versionSetLabel records the digest of the version set a compose file was generated from.
+const versionSetLabel = "base.node.version-set"
+
+var versionSetLabelPattern = regexp.MustCompile(versionSetLabel + `=([0-9a-f]+)`)
+
+var composeTemplate = template.Must(template.New("compose").Parse(`{{.Header}}
+x-base-node: &base-node
+  build:
+    context: .
+    dockerfile: {{.Client}}/Dockerfile
+  image: base-node-{{.Client}}:{{.Digest}}
+  restart: unless-stopped
+  environment:
+    - USE_BASE_CONSENSUS=${USE_BASE_CONSENSUS:-false}
+  env_file:
+    - {{.EnvFile}}
+  labels:
+    - {{.Label}}={{.Digest}}
+{{- range .Pins}}
+    - base.node.{{.Name}}={{.Tag}}@{{.Commit}}
+{{- end}}
+services:
+  execution:
+    <<: *base-node
+    ports:
+      - "{{.HostPort 8545}}:8545" # RPC
+      - "{{.HostPort 8546}}:8546" # websocket
+      - "{{.HostPort 7301}}:6060" # metrics
+      - "{{.HostPort 30303}}:30303" # P2P TCP
+      - "{{.HostPort 30303}}:30303/udp" # P2P UDP
+    command: ["bash", "./execution-entrypoint"]
+    volumes:
+      - {{.DataDir}}:/data
+  node:
+    <<: *base-node
+    depends_on:
+      - execution
+    ports:
+      - "{{.HostPort 7545}}:8545" # RPC
+      - "{{.HostPort 9222}}:9222" # P2P TCP
+      - "{{.HostPort 9222}}:9222/udp" # P2P UDP
+      - "{{.HostPort 7300}}:7300" # metrics
+      - "{{.HostPort 6060}}:6060" # pprof
+    command: ["bash", "./consensus-entrypoint"]
+`))
+
+type composePin struct {
+	Name   string
+	Tag    string
+	Commit string
+}
+
+type composeSetup struct {
+	// Header is the comment at the top of the rendered file, saying how to regenerate it.
+	Header  string
+	Network string
+	Client  string
+	EnvFile string
+	Label   string
+	Digest  string
+	Pins    []composePin
+	DataDir string
+	// PortOffset is added to every published host port, so a second copy of the node can run alongside.
+	PortOffset int
+}
+
+func (s *composeSetup) HostPort(port int) int {
+	return port + s.PortOffset
+}
+
+func composeCommand() *cli.Command {
+	repoFlag := &cli.StringFlag{
+		Name:  "repo",
+		Usage: "Path to the node repository",
+		Value: ".",
+	}
+
+	return &cli.Command{
+		Name:  "compose",
+		Usage: "Generate docker compose files pinned to the version set in versions.json",
+		Commands: []*cli.Command{
+			{
+				Name:  "generate",
+				Usage: "Writes docker-compose.<network>-<client>.yml and a matching .env file for one network and client",
+				Flags: []cli.Flag{
+					repoFlag,
+					&cli.StringFlag{
+						Name:  "network",
+						Usage: "Network to generate for (" + strings.Join(networkNames(), ", ") + ")",
+						Value: "mainnet",
+					},
+					&cli.StringFlag{
+						Name:    "client",
+						Usage:   "Execution client (geth, reth, nethermind)",
+						Sources: cli.EnvVars("CLIENT"),
+						Value:   "geth",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					network, err := lookupNetwork(cmd.String("network"))
+					if err != nil {
+						return err
+					}
+					return generateCompose(cmd.String("repo"), network, cmd.String("client"))
+				},
+			},
+			{
+				Name:      "check",
+				Usage:     "Fails if a generated compose file no longer matches versions.json",
+				ArgsUsage: "<compose file>",
+				Flags:     []cli.Flag{repoFlag},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Args().Len() != 1 {
+						return fmt.Errorf("expected a single compose file argument")
+					}
+					return checkGeneratedCompose(cmd.String("repo"), cmd.Args().First())
+				},
+			},
+		},
+	}
+}
+
+func generateCompose(repoPath string, network Network, client string) error {
+	if err := checkSupported(repoPath, "", network.Name, client); err != nil {
+		return err
+	}
+	setup, err := newComposeSetup(repoPath, network, client)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("docker-compose.%s-%s", network.Name, client)
+	composePath := filepath.Join(repoPath, name+".yml")
+	if err := writeComposeFile(composePath, setup); err != nil {
+		return err
+	}
+	envPath := filepath.Join(repoPath, name+".env")
+	if err := writeFileAtomic(envPath, []byte(composeEnvSettings(setup)), 0644); err != nil {
+		return err
+	}
+
+	log.Printf("Wrote %s and %s for version set %s", composePath, envPath, setup.Digest)
+	log.Printf("Start it with: docker compose -f %s --env-file %s up -d --build", filepath.Base(composePath), filepath.Base(envPath))
+	return nil
+}
+
+func writeComposeFile(path string, setup *composeSetup) error {
+	var compose bytes.Buffer
+	if err := composeTemplate.Execute(&compose, setup); err != nil {
+		return fmt.Errorf("error rendering compose file: %s", err)
+	}
+	return writeFileAtomic(path, compose.Bytes(), 0644)
+}
+
+func checkGeneratedCompose(repoPath string, composePath string) error {
+	data, err := os.ReadFile(composePath)
+	if err != nil {
+		return fmt.Errorf("error reading compose file: %s", err)
+	}
+	m := versionSetLabelPattern.FindSubmatch(data)
+	if m == nil {
+		return fmt.Errorf("%s has no %s label, it was not generated by compose generate", composePath, versionSetLabel)
+	}
+
+	versions, err := readPinnedVersions(repoPath)
+	if err != nil {
+		return err
+	}
+	client, err := composeFileClient(data)
+	if err != nil {
+		return err
+	}
+	want, err := versionSetDigest(versions, composeDependencies(client))
+	if err != nil {
+		return err
+	}
+	if string(m[1]) != want {
+		return fmt.Errorf("%s was generated from version set %s but versions.json is now %s, regenerate it", composePath, m[1], want)
+	}
+	log.Printf("%s matches versions.json (version set %s)", composePath, want)
+	return nil
+}
+
+func newComposeSetup(repoPath string, network Network, client string) (*composeSetup, error) {
+	if _, ok := clientDependencies[client]; !ok {
+		return nil, fmt.Errorf("unknown client %q", client)
+	}
+	versions, err := readPinnedVersions(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	env, err := readEnvFile(filepath.Join(repoPath, "versions.env"))
+	if err != nil {
+		return nil, err
+	}
+
+	deps := composeDependencies(client)
+	if err := checkVersionsEnv(versions, env, deps); err != nil {
+		return nil, err
+	}
+	digest, err := versionSetDigest(versions, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	setup := &composeSetup{
+		Header: "# Generated by node-tools compose generate from versions.json, do not edit.\n" +
+			"# Regenerate after updating versions.json: node-tools compose generate --network " + network.Name + " --client " + client,
+		Network: network.Name,
+		Client:  client,
+		EnvFile: network.EnvFile,
+		Label:   versionSetLabel,
+		Digest:  digest,
+		DataDir: "${HOST_DATA_DIR:-./" + client + "-data}",
+	}
+	for _, dep := range deps {
+		setup.Pins = append(setup.Pins, composePin{Name: dep, Tag: pinnedRef(versions[dep]), Commit: versions[dep].Commit})
+	}
+	return setup, nil
+}
+
+func composeEnvSettings(setup *composeSetup) string {
+	lines := []string{
+		"# Generated by node-tools compose generate, do not edit.",
+		"CLIENT=" + setup.Client,
+		"NETWORK_ENV=" + setup.EnvFile,
+		"HOST_DATA_DIR=./" + setup.Client + "-data",
+		"BASE_NODE_VERSION_SET=" + setup.Digest,
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// composeDependencies returns the versions.json entries baked into a client image, sorted.
+func composeDependencies(client string) []string {
+	deps := []string{nodeDependency, clientDependencies[client]}
+	slices.Sort(deps)
+	return deps
+}
+
+// composeFileClient recovers the client from the Dockerfile path of a generated compose file.
+func composeFileClient(data []byte) (string, error) {
+	for client := range clientDependencies {
+		if bytes.Contains(data, []byte("dockerfile: "+client+"/Dockerfile")) {
+			return client, nil
+		}
+	}
+	return "", fmt.Errorf("could not determine the client from the compose file")
+}
+
+// pinnedRef is what the Dockerfiles check out: the branch for branch tracked dependencies, otherwise the tag.
+func pinnedRef(p *PinnedVersion) string {
+	if p.Tracking == "branch" {
+		return p.Branch
+	}
+	return p.Tag
+}
+
+// versionSetDigest hashes the pinned ref and commit of each dependency.
+func versionSetDigest(versions map[string]*PinnedVersion, deps []string) (string, error) {
+	h := sha256.New()
+	for _, dep := range deps {
+		p, ok := versions[dep]
+		if !ok {
+			return "", fmt.Errorf("%s is missing from versions.json", dep)
+		}
+		fmt.Fprintf(h, "%s=%s@%s\n", dep, pinnedRef(p), p.Commit)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// checkVersionsEnv verifies versions.env, which the Dockerfiles build from, agrees with versions.json.
+func checkVersionsEnv(versions map[string]*PinnedVersion, env map[string]string, deps []string) error {
+	var mismatches []string
+	for _, dep := range deps {
+		p, ok := versions[dep]
+		if !ok {
+			return fmt.Errorf("%s is missing from versions.json", dep)
+		}
+		prefix := strings.ToUpper(dep)
+		want := map[string]string{
+			prefix + "_TAG":    pinnedRef(p),
+			prefix + "_COMMIT": p.Commit,
+			prefix + "_REPO":   "https://github.com/" + p.Owner + "/" + p.Repo + ".git",
+		}
+		for _, key := range sortedKeys(want) {
+			if env[key] != want[key] {
+				mismatches = append(mismatches, fmt.Sprintf("%s is %q, versions.json has %q", key, env[key], want[key]))
+			}
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("versions.env does not match versions.json, run the dependency updater: %s", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testPinnedVersions() map[string]*PinnedVersion {
+	return map[string]*PinnedVersion{
+		"op_node":    {Tag: "op-node/v1.16.11", Commit: "cba7aba0", TagPrefix: "op-node", Owner: "ethereum-optimism", Repo: "optimism", Tracking: "release"},
+		"op_geth":    {Tag: "v1.101702.0", Commit: "d0734fd5", Owner: "ethereum-optimism", Repo: "op-geth", Tracking: "release"},
+		"nethermind": {Tag: "1.36.2", Branch: "master", Commit: "f5507dec", Owner: "NethermindEth", Repo: "nethermind", Tracking: "branch"},
+	}
+}
+
+func TestVersionSetDigest(t *testing.T) {
+	versions := testPinnedVersions()
+	deps := composeDependencies("geth")
+
+	before, err := versionSetDigest(versions, deps)
+	if err != nil {
+		t.Fatalf("versionSetDigest() unexpected error: %v", err)
+	}
+	versions["nethermind"].Commit = "00000000"
+	if after, _ := versionSetDigest(versions, deps); after != before {
+		t.Errorf("versionSetDigest() changed for a dependency outside the set")
+	}
+	versions["op_geth"].Commit = "00000000"
+	if after, _ := versionSetDigest(versions, deps); after == before {
+		t.Errorf("versionSetDigest() did not change after the commit changed")
+	}
+	if _, err := versionSetDigest(versions, composeDependencies("reth")); err == nil {
+		t.Errorf("versionSetDigest() with a missing dependency expected error")
+	}
+}
+
+func TestCheckVersionsEnv(t *testing.T) {
+	versions := testPinnedVersions()
+	env := map[string]string{
+		"OP_NODE_TAG":       "op-node/v1.16.11",
+		"OP_NODE_COMMIT":    "cba7aba0",
+		"OP_NODE_REPO":      "https://github.com/ethereum-optimism/optimism.git",
+		"NETHERMIND_TAG":    "master",
+		"NETHERMIND_COMMIT": "f5507dec",
+		"NETHERMIND_REPO":   "https://github.com/NethermindEth/nethermind.git",
+	}
+
+	if err := checkVersionsEnv(versions, env, composeDependencies("nethermind")); err != nil {
+		t.Errorf("checkVersionsEnv() unexpected error: %v", err)
+	}
+	env["OP_NODE_COMMIT"] = "deadbeef"
+	err := checkVersionsEnv(versions, env, composeDependencies("nethermind"))
+	if err == nil || !strings.Contains(err.Error(), "OP_NODE_COMMIT") {
+		t.Errorf("checkVersionsEnv() = %v, want an OP_NODE_COMMIT mismatch", err)
+	}
+}
+
+func TestComposeTemplate(t *testing.T) {
+	setup := &composeSetup{
+		Header:     "# Regenerate after updating versions.json: node-tools compose generate --network sepolia --client reth",
+		Network:    "sepolia",
+		Client:     "reth",
+		EnvFile:    ".env.sepolia",
+		Label:      versionSetLabel,
+		Digest:     "abcdef012345",
+		Pins:       []composePin{{Name: "op_node", Tag: "op-node/v1.16.11", Commit: "cba7aba0"}},
+		DataDir:    "/srv/green",
+		PortOffset: 10000,
+	}
+	var out bytes.Buffer
+	if err := composeTemplate.Execute(&out, setup); err != nil {
+		t.Fatalf("composeTemplate.Execute() unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"# Regenerate after updating versions.json: node-tools compose generate --network sepolia --client reth\nx-base-node:",
+		"dockerfile: reth/Dockerfile",
+		"- .env.sepolia",
+		"- base.node.op_node=op-node/v1.16.11@cba7aba0",
+		"- /srv/green:/data",
+		`- "18545:8545" # RPC`,
+		`- "17545:8545" # RPC`,
+		`- "40303:30303/udp" # P2P UDP`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("rendered compose file missing %q", want)
+		}
+	}
+	if m := versionSetLabelPattern.FindSubmatch(out.Bytes()); m == nil || string(m[1]) != "abcdef012345" {
+		t.Errorf("rendered compose file version set label = %q, want abcdef012345", m)
+	}
+	if client, err := composeFileClient(out.Bytes()); err != nil || client != "reth" {
+		t.Errorf("composeFileClient() = %q, %v, want reth", client, err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDocker puts a docker on PATH that prints its arguments and appends them to the
+// returned log, one call per line.
+func fakeDocker(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$@\"\necho \"$@\" >> " + calls + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return calls
+}
+
+// After a rollout, commands act on the live copy's project rather than the retired default one.
+func TestComposeAfterRollout(t *testing.T) {
+	calls := fakeDocker(t)
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, ".env.mainnet"), []byte("BASE_NODE_L2_ENGINE_AUTH_RAW=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rotateJWTSecret(context.Background(), repo, ".env.mainnet", "", true); err != nil {
+		t.Fatalf("rotateJWTSecret() before a rollout unexpected error: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(repo, stateDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeRolloutState(filepath.Join(repo, stateDir, "rollout.json"), rolloutState{Active: "green", DataDir: "/srv/base-green", Time: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rotateJWTSecret(context.Background(), repo, ".env.mainnet", "", true); err != nil {
+		t.Fatalf("rotateJWTSecret() after a rollout unexpected error: %v", err)
+	}
+	out, err := composeOutput(context.Background(), repo, ".env.mainnet", "geth", "ps", "-q")
+	if err != nil {
+		t.Fatalf("composeOutput() unexpected error: %v", err)
+	}
+	if want := "compose -p base-green -f docker-compose.green.yml ps -q"; strings.TrimSpace(out) != want {
+		t.Errorf("composeOutput() ran docker %s, want docker %s", out, want)
+	}
+	// Rollout names the projects it acts on itself.
+	if err := runCompose(context.Background(), repo, ".env.mainnet", "geth", append(rolloutProjectArgs("blue"), "up", "-d")...); err != nil {
+		t.Fatalf("runCompose() unexpected error: %v", err)
+	}
+
+	logged, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"compose up -d --force-recreate --no-deps execution node",
+		"compose -p base-green -f docker-compose.green.yml up -d --force-recreate --no-deps execution node",
+		"compose -p base-green -f docker-compose.green.yml ps -q",
+		"compose -p base-blue -f docker-compose.blue.yml up -d",
+	}
+	if got := strings.Split(strings.TrimSpace(string(logged)), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("docker calls =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
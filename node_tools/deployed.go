@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// deployedSet is a version set that was applied to the node.
+type deployedSet struct {
+	Digest string            `json:"digest"`
+	Pins   map[string]string `json:"pins"`
+	Time   time.Time         `json:"time"`
+	Reason string            `json:"reason,omitempty"`
+}
+
+// deployedState tracks what actually runs on the node, as opposed to versions.json which
+// records what should. An applied update stays Pending until it passes the health soak.
+type deployedState struct {
+	Current *deployedSet `json:"current,omitempty"`
+	Pending *deployedSet `json:"pending,omitempty"`
+	Failed  *deployedSet `json:"failed,omitempty"`
+}
+
+func deployedStatePath(repoPath string) string {
+	return filepath.Join(repoPath, stateDir, "deployed.json")
+}
+
+// currentVersionSet returns the versions.json set the client's image is built from.
+func currentVersionSet(repoPath string, client string) (*deployedSet, error) {
+	if _, ok := clientDependencies[client]; !ok {
+		return nil, fmt.Errorf("unknown client %q", client)
+	}
+	versions, err := readPinnedVersions(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	deps := composeDependencies(client)
+	digest, err := versionSetDigest(versions, deps)
+	if err != nil {
+		return nil, err
+	}
+	set := &deployedSet{Digest: digest, Pins: map[string]string{}, Time: time.Now().UTC()}
+	for _, dep := range deps {
+		set.Pins[dep] = pinnedRef(versions[dep]) + "@" + versions[dep].Commit
+	}
+	return set, nil
+}
+
+func (s *deployedState) markPending(set *deployedSet) {
+	s.Pending = set
+}
+
+func (s *deployedState) markCurrent() {
+	if s.Pending == nil {
+		return
+	}
+	s.Current, s.Pending = s.Pending, nil
+	s.Current.Time = time.Now().UTC()
+}
+
+func (s *deployedState) markFailed(reason string) {
+	if s.Pending == nil {
+		return
+	}
+	s.Failed, s.Pending = s.Pending, nil
+	s.Failed.Time = time.Now().UTC()
+	s.Failed.Reason = reason
+}
+
+// soakUpdate fails unless the node keeps advancing and stays within maxLag blocks of the
+// estimated chain head for the whole duration.
+func soakUpdate(ctx context.Context, check upgradeCheck, duration time.Duration, interval time.Duration, maxLag uint64) error {
+	deadline := time.Now().Add(duration)
+	last, err := check.EL.blockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		n, err := check.EL.blockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("execution client RPC failed: %s", err)
+		}
+		if n <= last {
+			return fmt.Errorf("head stalled at %d", n)
+		}
+		last = n
+		lag, err := headLag(ctx, check.Node)
+		if err != nil {
+			return fmt.Errorf("op-node RPC failed: %s", err)
+		}
+		if lag > maxLag {
+			return fmt.Errorf("unsafe head is %d blocks behind", lag)
+		}
+		log.Printf("Healthy at %d, %s of the soak left", n, time.Until(deadline).Round(time.Second))
+	}
+	return nil
+}
+
+// finalizeUpdate records the result of apply: a failed apply or soak marks the version set
+// failed, otherwise it becomes current once it has been healthy for the soak duration.
+func finalizeUpdate(ctx context.Context, check upgradeCheck, alerter *alerter, applyErr error, soak time.Duration, maxLag uint64) error {
+	path := deployedStatePath(check.RepoPath)
+	state, err := readDeployedState(path)
+	if err != nil {
+		return err
+	}
+	set, err := currentVersionSet(check.RepoPath, check.Client)
+	if err != nil {
+		return err
+	}
+	if applyErr == nil && state.Current != nil && state.Current.Digest == set.Digest {
+		log.Printf("Version set %s is already current", set.Digest)
+		return nil
+	}
+	state.markPending(set)
+	if err := writeDeployedState(path, state); err != nil {
+		return err
+	}
+
+	err = applyErr
+	if err == nil {
+		log.Printf("Update %s pending, checking health for %s", set.Digest, soak)
+		err = soakUpdate(ctx, check, soak, 30*time.Second, maxLag)
+	}
+	if err != nil {
+		state.markFailed(err.Error())
+		if writeErr := writeDeployedState(path, state); writeErr != nil {
+			log.Printf("Error recording the failed update: %s", writeErr)
+		}
+		alerter.alert(ctx, fmt.Sprintf("Update %s failed: %s", set.Digest, err))
+		fmt.Println(deployedRollbackHint(state))
+		return fmt.Errorf("update failed: %s", err)
+	}
+
+	state.markCurrent()
+	if err := writeDeployedState(path, state); err != nil {
+		return err
+	}
+	alerter.alert(ctx, fmt.Sprintf("Update %s is healthy and now current: %s", set.Digest, formatPins(set.Pins)))
+	return nil
+}
+
+func deployedRollbackHint(state deployedState) string {
+	if state.Current == nil {
+		return "Rollback recommended: no earlier healthy version set is recorded, restore the previous versions.json and versions.env from git."
+	}
+	return fmt.Sprintf("Rollback recommended: the last healthy version set was %s (%s), restore versions.json and versions.env to it and run apply again.",
+		state.Current.Digest, formatPins(state.Current.Pins))
+}
+
+func formatPins(pins map[string]string) string {
+	var parts []string
+	for _, dep := range sortedKeys(pins) {
+		parts = append(parts, dep+"="+pins[dep])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func readDeployedState(path string) (deployedState, error) {
+	var state deployedState
+	f, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("error reading deployed state: %s", err)
+	}
+	if err := json.Unmarshal(f, &state); err != nil {
+		return state, fmt.Errorf("error unmarshalling deployed state: %s", err)
+	}
+	return state, nil
+}
+
+func writeDeployedState(path string, state deployedState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating %s: %s", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling deployed state: %s", err)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
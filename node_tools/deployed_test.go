@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeployedStateTransitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deployed.json")
+	state, err := readDeployedState(path)
+	if err != nil || state.Current != nil {
+		t.Fatalf("readDeployedState() of a missing file = %+v, %v, want empty state", state, err)
+	}
+
+	first := &deployedSet{Digest: "aaa", Pins: map[string]string{"op_node": "op-node/v1.16.10@abc"}}
+	state.markPending(first)
+	state.markCurrent()
+	if state.Current != first || state.Pending != nil {
+		t.Fatalf("markCurrent() state = %+v, want first set current", state)
+	}
+
+	second := &deployedSet{Digest: "bbb", Pins: map[string]string{"op_node": "op-node/v1.16.11@def"}}
+	state.markPending(second)
+	state.markFailed("head stalled at 100")
+	if state.Current != first || state.Failed != second || state.Failed.Reason != "head stalled at 100" {
+		t.Errorf("markFailed() state = %+v, want first current and second failed", state)
+	}
+	if hint := deployedRollbackHint(state); !strings.Contains(hint, "aaa") || !strings.Contains(hint, "op-node/v1.16.10@abc") {
+		t.Errorf("deployedRollbackHint() = %q, want the last healthy set", hint)
+	}
+
+	if err := writeDeployedState(path, state); err != nil {
+		t.Fatalf("writeDeployedState() unexpected error: %v", err)
+	}
+	read, err := readDeployedState(path)
+	if err != nil || read.Current.Digest != "aaa" || read.Failed.Digest != "bbb" {
+		t.Errorf("readDeployedState() = %+v, %v, want the written state", read, err)
+	}
+}
+
+func TestSoakUpdateStalled(t *testing.T) {
+	el := newFakeRPC(t, map[string]func([]json.RawMessage) any{
+		"eth_blockNumber": func([]json.RawMessage) any { return "0x64" },
+	})
+	check := upgradeCheck{EL: el}
+
+	err := soakUpdate(context.Background(), check, time.Second, 10*time.Millisecond, 10)
+	if err == nil || !strings.Contains(err.Error(), "stalled") {
+		t.Errorf("soakUpdate() = %v, want a stalled head error", err)
+	}
+}
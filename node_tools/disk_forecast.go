@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// maxDiskSamples bounds the history file, at one sample an hour this is about three months.
+const maxDiskSamples = 2000
+
+type diskSample struct {
+	Time        time.Time `json:"time"`
+	Client      string    `json:"client"`
+	Mode        string    `json:"mode"`
+	UsedBytes   uint64    `json:"usedBytes"`
+	VolumeFree  uint64    `json:"volumeFree"`
+	VolumeTotal uint64    `json:"volumeTotal"`
+}
+
+type diskForecast struct {
+	GrowthPerDay float64
+	// DaysUntilFull is negative when the data directory is not growing.
+	DaysUntilFull float64
+}
+
+func diskForecastCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "disk-forecast",
+		Usage: "Samples chain data growth and projects when the volume will fill",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:  "client",
+				Usage: "Execution client whose data directory is sampled (geth, reth, nethermind)",
+				Value: "geth",
+			},
+			&cli.StringFlag{
+				Name:  "mode",
+				Usage: "Node mode, samples are tracked separately per client and mode (full, archive)",
+				Value: "full",
+			},
+			&cli.StringFlag{
+				Name:  "data-dir",
+				Usage: "Chain data directory, defaults to <repo>/<client>-data like docker-compose.yml",
+			},
+			&cli.StringFlag{
+				Name:  "history",
+				Usage: "Sample history file, defaults to <repo>/.node_tools/disk-history.json",
+			},
+			&cli.IntFlag{
+				Name:  "warn-days",
+				Usage: "Exit with an error if the volume is projected to fill within this many days",
+				Value: 21,
+			},
+			&cli.DurationFlag{
+				Name:  "watch",
+				Usage: "Keep sampling at this interval instead of taking a single sample",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			repoPath := cmd.String("repo")
+			dataDir := cmd.String("data-dir")
+			if dataDir == "" {
+				dataDir = filepath.Join(repoPath, cmd.String("client")+"-data")
+			}
+			history := cmd.String("history")
+			if history == "" {
+				history = filepath.Join(repoPath, stateDir, "disk-history.json")
+			}
+
+			for {
+				err := sampleAndForecastDisk(dataDir, history, cmd.String("client"), cmd.String("mode"), int(cmd.Int("warn-days")))
+				if cmd.Duration("watch") == 0 {
+					return err
+				}
+				if err != nil {
+					log.Printf("WARN %s", err)
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(cmd.Duration("watch")):
+				}
+			}
+		},
+	}
+}
+
+func sampleAndForecastDisk(dataDir string, historyPath string, client string, mode string, warnDays int) error {
+	used, err := dirSize(dataDir)
+	if err != nil {
+		return err
+	}
+	volume, err := statVolume(dataDir)
+	if err != nil {
+		return err
+	}
+
+	samples, err := readDiskHistory(historyPath)
+	if err != nil {
+		return err
+	}
+	samples = append(samples, diskSample{
+		Time:        time.Now().UTC(),
+		Client:      client,
+		Mode:        mode,
+		UsedBytes:   used,
+		VolumeFree:  volume.Free,
+		VolumeTotal: volume.Total,
+	})
+	if len(samples) > maxDiskSamples {
+		samples = samples[len(samples)-maxDiskSamples:]
+	}
+	if err := writeDiskHistory(historyPath, samples); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s/%s data %s, volume %s free of %s\n", client, mode, formatBytes(used), formatBytes(volume.Free), formatBytes(volume.Total))
+
+	forecast, ok := forecastDisk(filterDiskSamples(samples, client, mode), volume.Free)
+	if !ok {
+		fmt.Println("not enough history yet, run again later to get a projection")
+		return nil
+	}
+	if forecast.DaysUntilFull < 0 {
+		fmt.Printf("growth %s/day, volume is not filling up\n", formatBytes(uint64(max(forecast.GrowthPerDay, 0))))
+		return nil
+	}
+
+	full := time.Now().Add(time.Duration(forecast.DaysUntilFull * 24 * float64(time.Hour)))
+	fmt.Printf("growth %s/day, volume projected full in %.1f days (%s)\n",
+		formatBytes(uint64(forecast.GrowthPerDay)), forecast.DaysUntilFull, full.Format("2006-01-02"))
+	if forecast.DaysUntilFull < float64(warnDays) {
+		return fmt.Errorf("volume holding %s is projected to fill in %.1f days", dataDir, forecast.DaysUntilFull)
+	}
+	return nil
+}
+
+func filterDiskSamples(samples []diskSample, client string, mode string) []diskSample {
+	var filtered []diskSample
+	for _, s := range samples {
+		if s.Client == client && s.Mode == mode {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// forecastDisk fits a line through the used bytes over time and projects when free space runs out.
+// It needs at least two samples spanning an hour to produce a forecast.
+func forecastDisk(samples []diskSample, free uint64) (diskForecast, bool) {
+	if len(samples) < 2 || samples[len(samples)-1].Time.Sub(samples[0].Time) < time.Hour {
+		return diskForecast{}, false
+	}
+
+	start := samples[0].Time
+	xs := make([]float64, len(samples))
+	ys := make([]float64, len(samples))
+	for i, s := range samples {
+		xs[i] = s.Time.Sub(start).Hours() / 24
+		ys[i] = float64(s.UsedBytes)
+	}
+
+	slope := linearSlope(xs, ys)
+	if slope <= 0 {
+		return diskForecast{GrowthPerDay: slope, DaysUntilFull: -1}, true
+	}
+	return diskForecast{GrowthPerDay: slope, DaysUntilFull: float64(free) / slope}, true
+}
+
+// linearSlope returns the least squares slope of ys over xs.
+func linearSlope(xs []float64, ys []float64) float64 {
+	n := float64(len(xs))
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+		sumXY += xs[i] * ys[i]
+		sumXX += xs[i] * xs[i]
+	}
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom
+}
+
+func readDiskHistory(path string) ([]diskSample, error) {
+	var samples []diskSample
+	f, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading disk history: %s", err)
+	}
+	if err := json.Unmarshal(f, &samples); err != nil {
+		return nil, fmt.Errorf("error unmarshalling disk history: %s", err)
+	}
+	return samples, nil
+}
+
+func writeDiskHistory(path string, samples []diskSample) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating %s: %s", filepath.Dir(path), err)
+	}
+	data, err := json.Marshal(samples)
+	if err != nil {
+		return fmt.Errorf("error marshaling disk history: %s", err)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestForecastDisk(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	day := 24 * time.Hour
+	gib := uint64(1 << 30)
+
+	tests := []struct {
+		name     string
+		samples  []diskSample
+		free     uint64
+		wantOk   bool
+		wantDays float64
+	}{
+		{
+			name: "steady growth",
+			samples: []diskSample{
+				{Time: start, UsedBytes: 100 * gib},
+				{Time: start.Add(day), UsedBytes: 110 * gib},
+				{Time: start.Add(2 * day), UsedBytes: 120 * gib},
+			},
+			free:     200 * gib,
+			wantOk:   true,
+			wantDays: 20,
+		},
+		{
+			name: "shrinking after prune",
+			samples: []diskSample{
+				{Time: start, UsedBytes: 120 * gib},
+				{Time: start.Add(day), UsedBytes: 100 * gib},
+			},
+			free:     200 * gib,
+			wantOk:   true,
+			wantDays: -1,
+		},
+		{
+			name:    "single sample",
+			samples: []diskSample{{Time: start, UsedBytes: 100 * gib}},
+			free:    200 * gib,
+			wantOk:  false,
+		},
+		{
+			name: "samples too close together",
+			samples: []diskSample{
+				{Time: start, UsedBytes: 100 * gib},
+				{Time: start.Add(time.Minute), UsedBytes: 101 * gib},
+			},
+			free:   200 * gib,
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := forecastDisk(tt.samples, tt.free)
+			if ok != tt.wantOk {
+				t.Fatalf("forecastDisk() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && math.Abs(got.DaysUntilFull-tt.wantDays) > 0.01 {
+				t.Errorf("forecastDisk() days until full = %.2f, want %.2f", got.DaysUntilFull, tt.wantDays)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readEnvFile parses a docker compose style env file into a map.
+// Comments, blank lines and inline " # comment" suffixes on unquoted values are ignored.
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading env file %s: %s", path, err)
+	}
+	return parseEnv(string(f)), nil
+}
+
+func parseEnv(content string) map[string]string {
+	env := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := parseEnvLine(line)
+		if ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+func parseEnvLine(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	line = strings.TrimPrefix(line, "export ")
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", false
+	}
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return key, value[1 : end+1], true
+		}
+		return key, value[1:], true
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return key, value, true
+}
+
+// setEnvValue replaces the value of key in the env file at path, or appends it
+// if the key is not set. All other lines, including comments, are left untouched.
+func setEnvValue(path string, key string, value string) error {
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading env file %s: %s", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading env file %s: %s", path, err)
+	}
+
+	updated := replaceEnvValue(string(f), key, value)
+
+	return writeFileAtomic(path, []byte(updated), info.Mode().Perm())
+}
+
+func replaceEnvValue(content string, key string, value string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		k, _, ok := parseEnvLine(line)
+		if ok && k == key {
+			lines[i] = key + "=" + value
+			return strings.Join(lines, "\n")
+		}
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + key + "=" + value + "\n"
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temp file for %s: %s", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing temp file for %s: %s", path, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error setting permissions on %s: %s", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temp file for %s: %s", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing %s: %s", path, err)
+	}
+	return nil
+}
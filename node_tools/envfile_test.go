@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseEnvLine(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantKey   string
+		wantValue string
+		wantOk    bool
+	}{
+		{"OP_NODE_NETWORK=base-mainnet", "OP_NODE_NETWORK", "base-mainnet", true},
+		{`OP_NODE_L1_RPC_KIND="debug_geth"`, "OP_NODE_L1_RPC_KIND", "debug_geth", true},
+		{`GETH_CACHE="20480" # 20GB`, "GETH_CACHE", "20480", true},
+		{"OP_NODE_LOG_LEVEL=info # comment", "OP_NODE_LOG_LEVEL", "info", true},
+		{"export OP_NODE_TAG=op-node/v1.16.11", "OP_NODE_TAG", "op-node/v1.16.11", true},
+		{"EMPTY=", "EMPTY", "", true},
+		{"# OP_GETH_SYNCMODE=snap", "", "", false},
+		{"", "", "", false},
+		{"not an assignment", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			key, value, ok := parseEnvLine(tt.line)
+			if key != tt.wantKey || value != tt.wantValue || ok != tt.wantOk {
+				t.Errorf("parseEnvLine(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.line, key, value, ok, tt.wantKey, tt.wantValue, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestReplaceEnvValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		key      string
+		value    string
+		expected string
+	}{
+		{"replace existing", "# header\nA=1\nB=2\n", "A", "3", "# header\nA=3\nB=2\n"},
+		{"commented key is not replaced", "# A=1\nB=2\n", "A", "3", "# A=1\nB=2\nA=3\n"},
+		{"append without trailing newline", "B=2", "A", "3", "B=2\nA=3\n"},
+		{"empty file", "", "A", "3", "A=3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := replaceEnvValue(tt.content, tt.key, tt.value)
+			if result != tt.expected {
+				t.Errorf("replaceEnvValue(%q, %q, %q) = %q, want %q", tt.content, tt.key, tt.value, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// ResourceProfile is the expected footprint of one network, client and mode.
+type ResourceProfile struct {
+	DiskGB               uint64 `json:"diskGB"`
+	DiskGrowthGBPerMonth uint64 `json:"diskGrowthGBPerMonth"`
+	RAMGB                uint64 `json:"ramGB"`
+	CPUCores             int    `json:"cpuCores"`
+	BandwidthMbps        uint64 `json:"bandwidthMbps"`
+	// SyncHours is how long a sync from scratch typically takes.
+	SyncHours int `json:"syncHours"`
+}
+
+type ResourceProfiles struct {
+	Updated  string                                           `json:"updated"`
+	Profiles map[string]map[string]map[string]ResourceProfile `json:"profiles"`
+}
+
+type hostResources struct {
+	DiskBytes uint64
+	RAMBytes  uint64
+	CPUCores  int
+}
+
+type resourceCheck struct {
+	Resource string
+	Required string
+	Host     string
+	// Passed is nil for resources the host can't be measured for.
+	Passed *bool
+}
+
+// diskBuffer is the headroom on top of the projected size, in line with the 20% the README recommends.
+const diskBuffer = 1.2
+
+func estimateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "estimate",
+		Usage: "Reports the expected disk, RAM, CPU and bandwidth needs of a setup and checks them against this host",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:  "network",
+				Usage: "Network to estimate for (" + strings.Join(networkNames(), ", ") + ")",
+				Value: "mainnet",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client (geth, reth, nethermind)",
+				Sources: cli.EnvVars("CLIENT"),
+				Value:   "geth",
+			},
+			&cli.StringFlag{
+				Name:  "mode",
+				Usage: "Node mode (full, archive)",
+				Value: "full",
+			},
+			&cli.IntFlag{
+				Name:  "months",
+				Usage: "Months of chain growth the disk must hold",
+				Value: 6,
+			},
+			&cli.StringFlag{
+				Name:  "data-dir",
+				Usage: "Directory on the volume the chain data will live on, defaults to <repo>",
+			},
+			&cli.StringFlag{
+				Name:  "profiles",
+				Usage: "Resource profiles file, defaults to <repo>/node_tools/resource-profiles.json",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			repoPath := cmd.String("repo")
+			network, err := lookupNetwork(cmd.String("network"))
+			if err != nil {
+				return err
+			}
+			profilesPath := cmd.String("profiles")
+			if profilesPath == "" {
+				profilesPath = filepath.Join(repoPath, "node_tools", "resource-profiles.json")
+			}
+			profiles, err := readResourceProfiles(profilesPath)
+			if err != nil {
+				return err
+			}
+			profile, ok := profiles.Profiles[network.Name][cmd.String("client")][cmd.String("mode")]
+			if !ok {
+				return fmt.Errorf("no resource profile for %s %s %s in %s", network.Name, cmd.String("client"), cmd.String("mode"), profilesPath)
+			}
+
+			dataDir := cmd.String("data-dir")
+			if dataDir == "" {
+				dataDir = repoPath
+			}
+			host, err := measureHost(dataDir)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s %s %s (profiles updated %s)\n", network.Name, cmd.String("client"), cmd.String("mode"), profiles.Updated)
+			failed := 0
+			for _, c := range checkResources(profile, host, int(cmd.Int("months"))) {
+				status := "info"
+				if c.Passed != nil && *c.Passed {
+					status = "PASS"
+				} else if c.Passed != nil {
+					status = "FAIL"
+					failed++
+				}
+				fmt.Printf("%-4s %-9s need %-28s host %s\n", status, c.Resource, c.Required, c.Host)
+			}
+			if failed > 0 {
+				return fmt.Errorf("host does not meet %d requirement(s)", failed)
+			}
+			return nil
+		},
+	}
+}
+
+func checkResources(p ResourceProfile, host hostResources, months int) []resourceCheck {
+	check := func(ok bool) *bool { return &ok }
+	const gb = 1 << 30
+
+	disk := uint64(float64(p.DiskGB+p.DiskGrowthGBPerMonth*uint64(months)) * diskBuffer)
+	return []resourceCheck{
+		{
+			Resource: "disk",
+			Required: fmt.Sprintf("%d GiB (%d GiB + %d months growth + 20%%)", disk, p.DiskGB, months),
+			Host:     formatBytes(host.DiskBytes) + " volume",
+			Passed:   check(host.DiskBytes >= disk*gb),
+		},
+		{
+			Resource: "ram",
+			Required: fmt.Sprintf("%d GiB", p.RAMGB),
+			Host:     formatBytes(host.RAMBytes),
+			// MemTotal excludes memory reserved by the kernel, allow 5% below the nominal size.
+			Passed: check(host.RAMBytes >= p.RAMGB*gb*95/100),
+		},
+		{
+			Resource: "cpu",
+			Required: fmt.Sprintf("%d cores", p.CPUCores),
+			Host:     fmt.Sprintf("%d cores", host.CPUCores),
+			Passed:   check(host.CPUCores >= p.CPUCores),
+		},
+		{
+			Resource: "bandwidth",
+			Required: fmt.Sprintf("%d Mbps", p.BandwidthMbps),
+			Host:     "not measured, see node-tools bandwidth",
+		},
+	}
+}
+
+func measureHost(dataDir string) (hostResources, error) {
+	volume, err := statVolume(dataDir)
+	if err != nil {
+		return hostResources{}, err
+	}
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return hostResources{}, fmt.Errorf("error reading memory size: %s", err)
+	}
+	defer f.Close()
+	ram, err := parseMemTotal(f)
+	if err != nil {
+		return hostResources{}, err
+	}
+	return hostResources{DiskBytes: volume.Total, RAMBytes: ram, CPUCores: runtime.NumCPU()}, nil
+}
+
+// parseMemTotal reads MemTotal from /proc/meminfo, which is reported in kB.
+func parseMemTotal(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid MemTotal: %s", err)
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, fmt.Errorf("MemTotal not found in /proc/meminfo")
+}
+
+func readResourceProfiles(path string) (ResourceProfiles, error) {
+	var profiles ResourceProfiles
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return profiles, fmt.Errorf("error reading resource profiles: %s", err)
+	}
+	if err := json.Unmarshal(f, &profiles); err != nil {
+		return profiles, fmt.Errorf("error unmarshalling resource profiles: %s", err)
+	}
+	return profiles, nil
+}
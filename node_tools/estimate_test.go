@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckResources(t *testing.T) {
+	const gb = 1 << 30
+	profile := ResourceProfile{DiskGB: 1000, DiskGrowthGBPerMonth: 100, RAMGB: 32, CPUCores: 8, BandwidthMbps: 100}
+
+	tests := []struct {
+		name string
+		host hostResources
+		want map[string]bool
+	}{
+		{
+			// 32 GB machines report slightly less than 32 GiB in MemTotal.
+			name: "meets requirements",
+			host: hostResources{DiskBytes: 2000 * gb, RAMBytes: 31 * gb, CPUCores: 16},
+			want: map[string]bool{"disk": true, "ram": true, "cpu": true},
+		},
+		{
+			// (1000 + 6*100) * 1.2 = 1920 GB
+			name: "too small",
+			host: hostResources{DiskBytes: 1900 * gb, RAMBytes: 16 * gb, CPUCores: 4},
+			want: map[string]bool{"disk": false, "ram": false, "cpu": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range checkResources(profile, tt.host, 6) {
+				want, measured := tt.want[c.Resource]
+				if !measured {
+					if c.Passed != nil {
+						t.Errorf("%s should not be checked", c.Resource)
+					}
+					continue
+				}
+				if c.Passed == nil || *c.Passed != want {
+					t.Errorf("%s passed = %v, want %v", c.Resource, c.Passed, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMemTotal(t *testing.T) {
+	meminfo := "MemTotal:       65843216 kB\nMemFree:         1234567 kB\n"
+	got, err := parseMemTotal(strings.NewReader(meminfo))
+	if err != nil || got != 65843216*1024 {
+		t.Errorf("parseMemTotal() = %d, %v, want %d", got, err, 65843216*1024)
+	}
+	if _, err := parseMemTotal(strings.NewReader("MemFree: 1 kB\n")); err == nil {
+		t.Errorf("parseMemTotal() without MemTotal expected error")
+	}
+}
+
+func TestResourceProfiles(t *testing.T) {
+	profiles, err := readResourceProfiles("resource-profiles.json")
+	if err != nil {
+		t.Fatalf("readResourceProfiles() unexpected error: %v", err)
+	}
+	for _, network := range networkNames() {
+		for client := range clientDependencies {
+			if _, ok := profiles.Profiles[network][client]["full"]; !ok {
+				t.Errorf("missing full node profile for %s %s", network, client)
+			}
+		}
+	}
+}
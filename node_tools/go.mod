@@ -0,0 +1,5 @@
+module github.com/base/node/node_tools
+
+go 1.24.3
+
+require github.com/urfave/cli/v3 v3.3.8
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// HardforkSupport maps fork names to the minimum version of each versions.json dependency that supports it.
+type HardforkSupport map[string]map[string]string
+
+type hardfork struct {
+	Name       string
+	Activation time.Time
+}
+
+type forkSupport struct {
+	Dependency string
+	Pinned     string
+	Required   string
+	// Supported is nil when the support file has no minimum for the dependency.
+	Supported *bool
+}
+
+func hardforksCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "hardforks",
+		Usage: "Prints upcoming hardfork activations and whether the pinned client versions support them",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client the node runs (geth, reth, nethermind)",
+				Sources: cli.EnvVars("CLIENT"),
+				Value:   "geth",
+			},
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint to read the rollup config from",
+				Value: defaultNodeRPC,
+			},
+			&cli.StringFlag{
+				Name:  "rollup-file",
+				Usage: "Read activation times from a superchain registry config (e.g. <repo>/mainnet/base.toml) or rollup.json instead of the op-node RPC",
+			},
+			&cli.StringFlag{
+				Name:  "support",
+				Usage: "Fork support file, defaults to <repo>/node_tools/hardfork-support.json",
+			},
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "Also list forks that are already active",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			repoPath := cmd.String("repo")
+			var forks []hardfork
+			var err error
+			if cmd.String("rollup-file") != "" {
+				forks, err = readHardforksFile(cmd.String("rollup-file"))
+			} else {
+				forks, err = fetchHardforks(ctx, newRPCClient(cmd.String("node-rpc")))
+			}
+			if err != nil {
+				return err
+			}
+
+			supportPath := cmd.String("support")
+			if supportPath == "" {
+				supportPath = filepath.Join(repoPath, "node_tools", "hardfork-support.json")
+			}
+			support, err := readHardforkSupport(supportPath)
+			if err != nil {
+				return err
+			}
+			versions, err := readPinnedVersions(repoPath)
+			if err != nil {
+				return err
+			}
+			dep, ok := clientDependencies[cmd.String("client")]
+			if !ok {
+				return fmt.Errorf("unknown client %q", cmd.String("client"))
+			}
+
+			now := time.Now()
+			var unsupported []string
+			shown := 0
+			for _, fork := range forks {
+				active := !fork.Activation.After(now)
+				if active && !cmd.Bool("all") {
+					continue
+				}
+				shown++
+
+				when := "in " + formatCountdown(fork.Activation.Sub(now))
+				if active {
+					when = "active"
+				}
+				fmt.Printf("%-10s %s (%s)\n", fork.Name, fork.Activation.UTC().Format(time.RFC3339), when)
+				for _, s := range checkForkSupport(support[fork.Name], versions, []string{nodeDependency, dep}) {
+					fmt.Printf("  %-15s %-22s %s\n", s.Dependency, s.Pinned, describeForkSupport(s))
+					if s.Supported != nil && !*s.Supported && !active {
+						unsupported = append(unsupported, fmt.Sprintf("%s (%s needs %s)", fork.Name, s.Dependency, s.Required))
+					}
+				}
+			}
+			if shown == 0 {
+				fmt.Println("No upcoming hardforks scheduled")
+			}
+
+			if len(unsupported) > 0 {
+				return fmt.Errorf("pinned versions do not support upcoming forks: %s", strings.Join(unsupported, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+func describeForkSupport(s forkSupport) string {
+	switch {
+	case s.Supported == nil:
+		return "unknown"
+	case *s.Supported:
+		return "supported (>= " + s.Required + ")"
+	default:
+		return "NOT SUPPORTED, needs >= " + s.Required
+	}
+}
+
+func checkForkSupport(minimums map[string]string, versions map[string]*PinnedVersion, deps []string) []forkSupport {
+	var result []forkSupport
+	for _, dep := range deps {
+		s := forkSupport{Dependency: dep, Pinned: "unpinned", Required: minimums[dep]}
+		pin, ok := versions[dep]
+		if ok {
+			s.Pinned = pinnedVersionString(pin)
+		}
+		if s.Required != "" && ok && pin.Tracking != "branch" {
+			supported := compareVersions(s.Pinned, s.Required) >= 0
+			s.Supported = &supported
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func fetchHardforks(ctx context.Context, node *rpcClient) ([]hardfork, error) {
+	var config map[string]any
+	if err := node.call(ctx, &config, "optimism_rollupConfig"); err != nil {
+		return nil, fmt.Errorf("error fetching rollup config: %s", err)
+	}
+	times := map[string]uint64{}
+	for key, value := range config {
+		if ts, ok := value.(float64); ok {
+			times[key] = uint64(ts)
+		}
+	}
+	return parseHardforks(times), nil
+}
+
+// readHardforksFile reads activation times from a rollup.json or a superchain registry TOML config.
+func readHardforksFile(path string) ([]hardfork, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rollup config: %s", err)
+	}
+
+	times := map[string]uint64{}
+	if strings.HasSuffix(path, ".json") {
+		var config map[string]any
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("error unmarshalling rollup config: %s", err)
+		}
+		for key, value := range config {
+			if ts, ok := value.(float64); ok {
+				times[key] = uint64(ts)
+			}
+		}
+		return parseHardforks(times), nil
+	}
+
+	// The registry TOML lists forks as "<fork>_time = <unix> # <date>", other keys are ignored.
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value, _, _ = strings.Cut(value, "#")
+		ts, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		times[strings.TrimSpace(key)] = ts
+	}
+	return parseHardforks(times), scanner.Err()
+}
+
+// parseHardforks picks the "<fork>_time" entries out of a config and sorts them by activation.
+func parseHardforks(values map[string]uint64) []hardfork {
+	var forks []hardfork
+	for key, ts := range values {
+		name, ok := strings.CutSuffix(key, "_time")
+		if !ok || name == "block" || name == "genesis" {
+			continue
+		}
+		forks = append(forks, hardfork{Name: name, Activation: time.Unix(int64(ts), 0)})
+	}
+	slices.SortFunc(forks, func(a, b hardfork) int {
+		if c := a.Activation.Compare(b.Activation); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return forks
+}
+
+func readHardforkSupport(path string) (HardforkSupport, error) {
+	var support HardforkSupport
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading hardfork support file: %s", err)
+	}
+	if err := json.Unmarshal(f, &support); err != nil {
+		return nil, fmt.Errorf("error unmarshalling hardfork support file: %s", err)
+	}
+	return support, nil
+}
+
+func formatCountdown(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, d/time.Minute)
+	}
+	return fmt.Sprintf("%dh %dm", hours, d/time.Minute)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadHardforksFileTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "base.toml")
+	config := `name = "Base"
+chain_id = 8453
+block_time = 2
+
+[hardforks]
+  canyon_time = 1704992401 # Thu 11 Jan 2024 17:00:01 UTC
+  ecotone_time = 1710374401 # Thu 14 Mar 2024 00:00:01 UTC
+  delta_time = 1708560000 # Thu 22 Feb 2024 00:00:00 UTC
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	forks, err := readHardforksFile(path)
+	if err != nil {
+		t.Fatalf("readHardforksFile() unexpected error: %v", err)
+	}
+	var names []string
+	for _, f := range forks {
+		names = append(names, f.Name)
+	}
+	if len(names) != 3 || names[0] != "canyon" || names[1] != "delta" || names[2] != "ecotone" {
+		t.Errorf("readHardforksFile() forks = %v, want [canyon delta ecotone]", names)
+	}
+}
+
+func TestCheckForkSupport(t *testing.T) {
+	versions := map[string]*PinnedVersion{
+		"op_node": {Tag: "op-node/v1.16.11", TagPrefix: "op-node", Tracking: "release"},
+		"op_geth": {Tag: "v1.101601.0", Tracking: "release"},
+	}
+	minimums := map[string]string{"op_node": "1.16.0", "op_geth": "1.101602.0"}
+
+	got := checkForkSupport(minimums, versions, []string{"op_node", "op_geth", "nethermind"})
+	if len(got) != 3 {
+		t.Fatalf("checkForkSupport() returned %d results, want 3", len(got))
+	}
+	if got[0].Supported == nil || !*got[0].Supported {
+		t.Errorf("op_node %s should support minimum %s", got[0].Pinned, got[0].Required)
+	}
+	if got[1].Supported == nil || *got[1].Supported {
+		t.Errorf("op_geth %s should not support minimum %s", got[1].Pinned, got[1].Required)
+	}
+	if got[2].Supported != nil {
+		t.Errorf("nethermind without a minimum should be unknown")
+	}
+}
+
+func TestFormatCountdown(t *testing.T) {
+	if got := formatCountdown(3*24*time.Hour + 4*time.Hour + 5*time.Minute); got != "3d 4h 5m" {
+		t.Errorf("formatCountdown() = %q, want %q", got, "3d 4h 5m")
+	}
+	if got := formatCountdown(90 * time.Minute); got != "1h 30m" {
+		t.Errorf("formatCountdown() = %q, want %q", got, "1h 30m")
+	}
+}
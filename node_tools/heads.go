@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// headTracker remembers when the safe and finalized heads last advanced.
+type headTracker struct {
+	safeStall      time.Duration
+	finalizedStall time.Duration
+
+	safe             uint64
+	safeChanged      time.Time
+	safeAlerted      bool
+	finalized        uint64
+	finalizedChanged time.Time
+	finalizedAlerted bool
+	initialized      bool
+}
+
+func headsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "heads",
+		Usage: "Monitors the unsafe/safe/finalized head gaps and alerts when safe or finalized stop advancing",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint",
+				Value: defaultNodeRPC,
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "Time between samples",
+				Value: 30 * time.Second,
+			},
+			&cli.DurationFlag{
+				Name:  "safe-stall",
+				Usage: "Alert when the safe head has not advanced for this long",
+				Value: 10 * time.Minute,
+			},
+			&cli.DurationFlag{
+				Name:  "finalized-stall",
+				Usage: "Alert when the finalized head has not advanced for this long",
+				Value: 30 * time.Minute,
+			},
+			webhookFlag,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			tracker := &headTracker{safeStall: cmd.Duration("safe-stall"), finalizedStall: cmd.Duration("finalized-stall")}
+			return monitorHeads(ctx, newRPCClient(cmd.String("node-rpc")), tracker, newAlerter(cmd.String("webhook")), cmd.Duration("interval"))
+		},
+	}
+}
+
+func monitorHeads(ctx context.Context, node *rpcClient, tracker *headTracker, alerts *alerter, interval time.Duration) error {
+	for {
+		status, err := node.syncStatus(ctx)
+		if err != nil {
+			log.Printf("Error fetching sync status: %s", err)
+		} else {
+			fmt.Printf("%s unsafe %d safe %d (-%d) finalized %d (-%d) l1 head %d current %d\n",
+				time.Now().Format(time.RFC3339),
+				status.UnsafeL2.Number,
+				status.SafeL2.Number, gap(status.UnsafeL2.Number, status.SafeL2.Number),
+				status.FinalizedL2.Number, gap(status.UnsafeL2.Number, status.FinalizedL2.Number),
+				status.HeadL1.Number, status.CurrentL1.Number)
+
+			for _, msg := range tracker.update(status, time.Now()) {
+				alerts.alert(ctx, msg)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// update records the latest sync status and returns any new stall or recovery alerts.
+func (h *headTracker) update(status *SyncStatus, now time.Time) []string {
+	if !h.initialized {
+		h.safe, h.safeChanged = status.SafeL2.Number, now
+		h.finalized, h.finalizedChanged = status.FinalizedL2.Number, now
+		h.initialized = true
+		return nil
+	}
+
+	var alerts []string
+	if status.SafeL2.Number != h.safe {
+		if h.safeAlerted {
+			alerts = append(alerts, fmt.Sprintf("safe head advancing again at %d", status.SafeL2.Number))
+		}
+		h.safe, h.safeChanged, h.safeAlerted = status.SafeL2.Number, now, false
+	} else if !h.safeAlerted && now.Sub(h.safeChanged) >= h.safeStall {
+		alerts = append(alerts, fmt.Sprintf("safe head stuck at %d for %s (unsafe %d, l1 head %d), check L1 connectivity and derivation",
+			h.safe, now.Sub(h.safeChanged).Round(time.Second), status.UnsafeL2.Number, status.HeadL1.Number))
+		h.safeAlerted = true
+	}
+
+	if status.FinalizedL2.Number != h.finalized {
+		if h.finalizedAlerted {
+			alerts = append(alerts, fmt.Sprintf("finalized head advancing again at %d", status.FinalizedL2.Number))
+		}
+		h.finalized, h.finalizedChanged, h.finalizedAlerted = status.FinalizedL2.Number, now, false
+	} else if !h.finalizedAlerted && now.Sub(h.finalizedChanged) >= h.finalizedStall {
+		alerts = append(alerts, fmt.Sprintf("finalized head stuck at %d for %s (l1 finalized %d)",
+			h.finalized, now.Sub(h.finalizedChanged).Round(time.Second), status.FinalizedL1.Number))
+		h.finalizedAlerted = true
+	}
+	return alerts
+}
+
+func gap(a uint64, b uint64) uint64 {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
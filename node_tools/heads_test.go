@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeadTrackerUpdate(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	status := func(safe, finalized uint64) *SyncStatus {
+		return &SyncStatus{
+			UnsafeL2:    BlockRef{Number: safe + 100},
+			SafeL2:      BlockRef{Number: safe},
+			FinalizedL2: BlockRef{Number: finalized},
+		}
+	}
+
+	tracker := &headTracker{safeStall: 10 * time.Minute, finalizedStall: 30 * time.Minute}
+	steps := []struct {
+		at         time.Duration
+		status     *SyncStatus
+		wantAlerts int
+	}{
+		{0, status(1000, 900), 0},
+		{5 * time.Minute, status(1150, 900), 0},
+		{14 * time.Minute, status(1150, 900), 0},
+		{15 * time.Minute, status(1150, 900), 1},  // safe stalled for 10m
+		{20 * time.Minute, status(1150, 900), 0},  // already alerted
+		{30 * time.Minute, status(1150, 900), 1},  // finalized stalled for 30m
+		{31 * time.Minute, status(1300, 1000), 2}, // both recovered
+	}
+
+	for _, step := range steps {
+		got := tracker.update(step.status, start.Add(step.at))
+		if len(got) != step.wantAlerts {
+			t.Errorf("update at %s = %v, want %d alerts", step.at, got, step.wantAlerts)
+		}
+	}
+}
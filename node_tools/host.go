@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"syscall"
+)
+
+// stateDir is where the tools keep sample histories, relative to the repo.
+const stateDir = ".node_tools"
+
+type volumeUsage struct {
+	Total uint64
+	Free  uint64
+}
+
+func statVolume(path string) (volumeUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return volumeUsage{}, fmt.Errorf("error reading volume stats for %s: %s", path, err)
+	}
+	return volumeUsage{
+		Total: st.Blocks * uint64(st.Bsize),
+		Free:  st.Bavail * uint64(st.Bsize),
+	}, nil
+}
+
+// dirSize returns the total size of all regular files under path.
+func dirSize(path string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += uint64(info.Size())
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error measuring %s: %s", path, err)
+	}
+	return size, nil
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+type initOptions struct {
+	L1RPC            string
+	L1Beacon         string
+	L1BeaconArchiver string
+	JWTSecret        string
+}
+
+func initCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "init",
+		Usage: "Sets up the repo for a network and client: env files, JWT secret, config artifacts and a compose file pinned to versions.json",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:     "network",
+				Usage:    "Network to run (" + strings.Join(networkNames(), ", ") + ")",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "client",
+				Usage:    "Execution client (geth, reth, nethermind)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "l1-rpc",
+				Usage: "L1 execution RPC endpoint",
+			},
+			&cli.StringFlag{
+				Name:  "l1-beacon",
+				Usage: "L1 beacon API endpoint",
+			},
+			&cli.StringFlag{
+				Name:  "l1-beacon-archiver",
+				Usage: "L1 beacon archiver endpoint, for blobs older than the beacon node keeps",
+			},
+			&cli.BoolFlag{
+				Name:  "keep-jwt",
+				Usage: "Keep the JWT secret already in the env file instead of generating a new one",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-artifacts",
+				Usage: "Don't download the rollup config and genesis",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			repoPath := cmd.String("repo")
+			network, err := lookupNetwork(cmd.String("network"))
+			if err != nil {
+				return err
+			}
+			client := cmd.String("client")
+			if _, ok := clientDependencies[client]; !ok {
+				return fmt.Errorf("unknown client %q, expected one of: %s", client, strings.Join(sortedKeys(clientDependencies), ", "))
+			}
+			if err := checkSupported(repoPath, "", network.Name, client); err != nil {
+				return err
+			}
+
+			opts := initOptions{
+				L1RPC:            cmd.String("l1-rpc"),
+				L1Beacon:         cmd.String("l1-beacon"),
+				L1BeaconArchiver: cmd.String("l1-beacon-archiver"),
+			}
+			if !cmd.Bool("keep-jwt") {
+				if opts.JWTSecret, err = generateJWTSecret(); err != nil {
+					return err
+				}
+			}
+			if err := initEnvFiles(repoPath, network, client, opts); err != nil {
+				return err
+			}
+
+			if !cmd.Bool("skip-artifacts") {
+				// Only artifacts with a known-good hash in the manifest are accepted.
+				if err := fetchArtifacts(ctx, repoPath, network.Name, "", "", false); err != nil {
+					return err
+				}
+			}
+			if err := generateCompose(repoPath, network, client); err != nil {
+				return err
+			}
+
+			findings, err := lintRepo(repoPath, client, network.EnvFile)
+			if err != nil {
+				return err
+			}
+			for _, f := range findings {
+				log.Printf("lint: %s:%d: %s", f.File, f.Line, f.Message)
+			}
+			if len(findings) > 0 {
+				return fmt.Errorf("setup is incomplete, fix the lint findings above and run node-tools lint")
+			}
+			log.Printf("Setup complete for %s with %s", network.Name, client)
+			return nil
+		},
+	}
+}
+
+func initEnvFiles(repoPath string, network Network, client string, opts initOptions) error {
+	dotEnv := filepath.Join(repoPath, ".env")
+	if err := updateEnvFile(dotEnv, composeDefaultValues(client)); err != nil {
+		return err
+	}
+	envFile := filepath.Join(repoPath, network.EnvFile)
+	if err := updateEnvFile(envFile, networkEnvValues(opts)); err != nil {
+		return err
+	}
+	if opts.JWTSecret != "" {
+		log.Printf("Generated a new JWT secret in %s, recreate both containers if they are already running", network.EnvFile)
+	}
+	return nil
+}
+
+func updateEnvFile(path string, values map[string]string) error {
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading env file %s: %s", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading env file %s: %s", path, err)
+	}
+	content := string(f)
+	for _, key := range sortedKeys(values) {
+		content = replaceEnvValue(content, key, values[key])
+	}
+	if content == string(f) {
+		return nil
+	}
+	log.Printf("Updated %s: %s", path, strings.Join(sortedKeys(values), ", "))
+	return writeFileAtomic(path, []byte(content), info.Mode().Perm())
+}
+
+// composeDefaultValues are the .env settings for a client. base-consensus is only
+// turned off for clients whose image doesn't include it, reth keeps whatever is configured.
+func composeDefaultValues(client string) map[string]string {
+	values := map[string]string{
+		"CLIENT":        client,
+		"HOST_DATA_DIR": "./" + client + "-data",
+	}
+	if !slices.Contains(baseConsensusClients, client) {
+		values["USE_BASE_CONSENSUS"] = "false"
+	}
+	return values
+}
+
+// networkEnvValues sets the L1 endpoints for both op-node and base-consensus, so switching
+// USE_BASE_CONSENSUS later doesn't leave one of them on the placeholders.
+func networkEnvValues(opts initOptions) map[string]string {
+	values := map[string]string{}
+	if opts.L1RPC != "" {
+		values["OP_NODE_L1_ETH_RPC"] = opts.L1RPC
+		values["BASE_NODE_L1_ETH_RPC"] = opts.L1RPC
+	}
+	if opts.L1Beacon != "" {
+		values["OP_NODE_L1_BEACON"] = opts.L1Beacon
+		values["BASE_NODE_L1_BEACON"] = opts.L1Beacon
+	}
+	if opts.L1BeaconArchiver != "" {
+		values["OP_NODE_L1_BEACON_ARCHIVER"] = opts.L1BeaconArchiver
+	}
+	if opts.JWTSecret != "" {
+		values[jwtEnvKey] = opts.JWTSecret
+	}
+	return values
+}
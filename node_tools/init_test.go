@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitEnvFiles(t *testing.T) {
+	repo := t.TempDir()
+	dotEnv := "CLIENT=${CLIENT:-geth}\nHOST_DATA_DIR=./${CLIENT}-data\nUSE_BASE_CONSENSUS=true\n"
+	networkEnv := "# L1\nOP_NODE_L1_ETH_RPC=<your-preferred-l1-rpc>\nOP_NODE_L1_BEACON=<your-preferred-l1-beacon>\nBASE_NODE_L1_ETH_RPC=<your-preferred-l1-rpc>\nBASE_NODE_L2_ENGINE_AUTH_RAW=688f5d737bad920bdfb2fc2f488d6b6209eebda1dae949a8de91398d932c517a\n"
+	if err := os.WriteFile(filepath.Join(repo, ".env"), []byte(dotEnv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, ".env.sepolia"), []byte(networkEnv), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := initOptions{L1RPC: "https://l1.example", L1Beacon: "https://beacon.example", JWTSecret: "ab"}
+	if err := initEnvFiles(repo, networks["sepolia"], "geth", opts); err != nil {
+		t.Fatalf("initEnvFiles() unexpected error: %v", err)
+	}
+
+	env, err := readEnvFile(filepath.Join(repo, ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["CLIENT"] != "geth" || env["HOST_DATA_DIR"] != "./geth-data" || env["USE_BASE_CONSENSUS"] != "false" {
+		t.Errorf(".env = %v, want geth without base-consensus", env)
+	}
+
+	env, err = readEnvFile(filepath.Join(repo, ".env.sepolia"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"OP_NODE_L1_ETH_RPC":   "https://l1.example",
+		"BASE_NODE_L1_ETH_RPC": "https://l1.example",
+		"OP_NODE_L1_BEACON":    "https://beacon.example",
+		"BASE_NODE_L1_BEACON":  "https://beacon.example",
+		jwtEnvKey:              "ab",
+	}
+	for key, value := range want {
+		if env[key] != value {
+			t.Errorf(".env.sepolia %s = %q, want %q", key, env[key], value)
+		}
+	}
+}
+
+func TestComposeDefaultValues(t *testing.T) {
+	if _, ok := composeDefaultValues("reth")["USE_BASE_CONSENSUS"]; ok {
+		t.Errorf("composeDefaultValues(reth) should leave USE_BASE_CONSENSUS as configured")
+	}
+	if got := composeDefaultValues("nethermind")["USE_BASE_CONSENSUS"]; got != "false" {
+		t.Errorf("composeDefaultValues(nethermind) USE_BASE_CONSENSUS = %q, want false", got)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// jwtEnvKey is the env variable the entrypoints write to the engine auth file on startup.
+const jwtEnvKey = "BASE_NODE_L2_ENGINE_AUTH_RAW"
+
+// jwtSecretLength is the engine API secret size in bytes (64 hex characters).
+const jwtSecretLength = 32
+
+func jwtCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "jwt",
+		Usage: "Generates and rotates the engine API JWT secret shared by the execution client and op-node",
+		Commands: []*cli.Command{
+			{
+				Name:  "generate",
+				Usage: "Generates a new JWT secret and prints it or writes it to a file",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "Path to write the secret to (written with 0600 permissions), prints to stdout if unset",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					secret, err := generateJWTSecret()
+					if err != nil {
+						return err
+					}
+					if cmd.String("out") == "" {
+						fmt.Println(secret)
+						return nil
+					}
+					return writeJWTSecret(cmd.String("out"), secret)
+				},
+			},
+			{
+				Name:  "rotate",
+				Usage: "Writes a new JWT secret to the network env file and recreates both containers so they pick it up together",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "repo",
+						Usage: "Path to the node repository containing docker-compose.yml",
+						Value: ".",
+					},
+					&cli.StringFlag{
+						Name:    "env-file",
+						Usage:   "Network env file holding " + jwtEnvKey + ", relative to the repo",
+						Sources: cli.EnvVars("NETWORK_ENV"),
+						Value:   ".env.mainnet",
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "Optional additional path to write the secret to (written with 0600 permissions)",
+					},
+					&cli.BoolFlag{
+						Name:  "no-restart",
+						Usage: "Only write the new secret, do not recreate the containers",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return rotateJWTSecret(ctx, cmd.String("repo"), cmd.String("env-file"), cmd.String("out"), !cmd.Bool("no-restart"))
+				},
+			},
+		},
+	}
+}
+
+func generateJWTSecret() (string, error) {
+	b := make([]byte, jwtSecretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating JWT secret: %s", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validateJWTSecret checks that secret is a 32 byte hex string, optionally 0x prefixed.
+func validateJWTSecret(secret string) error {
+	s := strings.TrimPrefix(strings.TrimSpace(secret), "0x")
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("JWT secret is not valid hex: %s", err)
+	}
+	if len(b) != jwtSecretLength {
+		return fmt.Errorf("JWT secret must be %d bytes, got %d", jwtSecretLength, len(b))
+	}
+	return nil
+}
+
+func writeJWTSecret(path string, secret string) error {
+	if err := writeFileAtomic(path, []byte(secret+"\n"), 0600); err != nil {
+		return fmt.Errorf("error writing JWT secret: %s", err)
+	}
+	return nil
+}
+
+func rotateJWTSecret(ctx context.Context, repoPath string, envFile string, out string, restart bool) error {
+	envPath := filepath.Join(repoPath, envFile)
+	if _, err := readEnvFile(envPath); err != nil {
+		return err
+	}
+
+	secret, err := generateJWTSecret()
+	if err != nil {
+		return err
+	}
+
+	if out != "" {
+		if err := writeJWTSecret(out, secret); err != nil {
+			return err
+		}
+	}
+
+	if err := setEnvValue(envPath, jwtEnvKey, secret); err != nil {
+		return fmt.Errorf("error updating %s: %s", envPath, err)
+	}
+	log.Printf("Wrote new JWT secret to %s", envPath)
+
+	if !restart {
+		log.Printf("Skipping restart, recreate the execution and node containers to apply the new secret")
+		return nil
+	}
+
+	// Both sides read the secret at startup, so they have to be recreated
+	// together or the engine API connection fails until the other one restarts.
+	if err := runCompose(ctx, repoPath, envFile, "", "up", "-d", "--force-recreate", "--no-deps", "execution", "node"); err != nil {
+		return fmt.Errorf("failed to recreate containers with new JWT secret: %s", err)
+	}
+
+	return nil
+}
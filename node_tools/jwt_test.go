@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGenerateJWTSecret(t *testing.T) {
+	secret, err := generateJWTSecret()
+	if err != nil {
+		t.Fatalf("generateJWTSecret() unexpected error: %v", err)
+	}
+	if err := validateJWTSecret(secret); err != nil {
+		t.Errorf("generateJWTSecret() = %q, not a valid secret: %v", secret, err)
+	}
+}
+
+func TestValidateJWTSecret(t *testing.T) {
+	tests := []struct {
+		secret  string
+		wantErr bool
+	}{
+		{"688f5d737bad920bdfb2fc2f488d6b6209eebda1dae949a8de91398d932c517a", false},
+		{"0x688f5d737bad920bdfb2fc2f488d6b6209eebda1dae949a8de91398d932c517a", false},
+		{"688f5d737bad920bdfb2fc2f488d6b62", true},
+		{"zz8f5d737bad920bdfb2fc2f488d6b6209eebda1dae949a8de91398d932c517a", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.secret, func(t *testing.T) {
+			err := validateJWTSecret(tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateJWTSecret(%q) error = %v, wantErr %v", tt.secret, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	l1RPCBackupsKey    = "NODE_TOOLS_L1_RPC_BACKUPS"
+	l1BeaconBackupsKey = "NODE_TOOLS_L1_BEACON_BACKUPS"
+	l1SlotTime         = 12 * time.Second
+)
+
+// l1RPCKeys and l1BeaconKeys are the env variables op-node and base-consensus read L1 endpoints from.
+var (
+	l1RPCKeys    = []string{"OP_NODE_L1_ETH_RPC", "BASE_NODE_L1_ETH_RPC"}
+	l1BeaconKeys = []string{"OP_NODE_L1_BEACON", "BASE_NODE_L1_BEACON"}
+)
+
+type endpointStats struct {
+	URL       string
+	Kind      string
+	Samples   int
+	Errors    int
+	Latency   time.Duration
+	Freshness time.Duration
+	LastError string
+}
+
+type l1Thresholds struct {
+	MaxErrorRate float64
+	MaxStaleness time.Duration
+}
+
+func l1Command() *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "repo",
+			Usage: "Path to the node repository",
+			Value: ".",
+		},
+		&cli.StringFlag{
+			Name:    "env-file",
+			Usage:   "Network env file holding the L1 endpoints, relative to the repo",
+			Sources: cli.EnvVars("NETWORK_ENV"),
+			Value:   ".env.mainnet",
+		},
+		&cli.IntFlag{
+			Name:  "samples",
+			Usage: "Number of requests made to each endpoint",
+			Value: 5,
+		},
+		&cli.FloatFlag{
+			Name:  "max-error-rate",
+			Usage: "Error rate above which an endpoint is unhealthy",
+			Value: 0.2,
+		},
+		&cli.DurationFlag{
+			Name:  "max-staleness",
+			Usage: "Head age above which an endpoint is unhealthy",
+			Value: time.Minute,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "l1",
+		Usage: "Benchmarks the configured L1 RPC and beacon endpoints and fails over to healthy backups",
+		Description: "Backups are read from the comma separated " + l1RPCBackupsKey + " and " + l1BeaconBackupsKey +
+			" variables in the env file.",
+		Commands: []*cli.Command{
+			{
+				Name:  "bench",
+				Usage: "Measures latency, error rate and head freshness of the primary and backup endpoints",
+				Flags: flags,
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					env, err := readEnvFile(filepath.Join(cmd.String("repo"), cmd.String("env-file")))
+					if err != nil {
+						return err
+					}
+					thresholds := l1ThresholdsFromCommand(cmd)
+					for _, kind := range []string{"rpc", "beacon"} {
+						primary, backups := l1Endpoints(env, kind)
+						for _, url := range append([]string{primary}, backups...) {
+							if url == "" {
+								continue
+							}
+							stats := benchEndpoint(ctx, kind, url, int(cmd.Int("samples")))
+							printEndpointStats(stats, url == primary, thresholds)
+						}
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "failover",
+				Usage: "Rewrites the env file to the healthiest backup when the primary endpoint is unhealthy",
+				Flags: append(flags,
+					&cli.BoolFlag{
+						Name:  "restart",
+						Usage: "Recreate the node container after switching endpoints",
+					},
+				),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return failoverL1(ctx, cmd.String("repo"), cmd.String("env-file"), int(cmd.Int("samples")), l1ThresholdsFromCommand(cmd), cmd.Bool("restart"))
+				},
+			},
+		},
+	}
+}
+
+func l1ThresholdsFromCommand(cmd *cli.Command) l1Thresholds {
+	return l1Thresholds{MaxErrorRate: cmd.Float("max-error-rate"), MaxStaleness: cmd.Duration("max-staleness")}
+}
+
+// l1Endpoints returns the primary endpoint and configured backups for kind ("rpc" or "beacon").
+func l1Endpoints(env map[string]string, kind string) (string, []string) {
+	keys, backupsKey := l1RPCKeys, l1RPCBackupsKey
+	if kind == "beacon" {
+		keys, backupsKey = l1BeaconKeys, l1BeaconBackupsKey
+	}
+
+	var primary string
+	for _, key := range keys {
+		if isConfiguredEndpoint(env[key]) {
+			primary = env[key]
+			break
+		}
+	}
+	var backups []string
+	for _, url := range strings.Split(env[backupsKey], ",") {
+		url = strings.TrimSpace(url)
+		if isConfiguredEndpoint(url) && url != primary {
+			backups = append(backups, url)
+		}
+	}
+	return primary, backups
+}
+
+// isConfiguredEndpoint filters out empty values and the <your-preferred-...> placeholders.
+func isConfiguredEndpoint(url string) bool {
+	return url != "" && !strings.HasPrefix(url, "<")
+}
+
+func failoverL1(ctx context.Context, repoPath string, envFile string, samples int, thresholds l1Thresholds, restart bool) error {
+	envPath := filepath.Join(repoPath, envFile)
+	env, err := readEnvFile(envPath)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, kind := range []string{"rpc", "beacon"} {
+		primary, backups := l1Endpoints(env, kind)
+		if primary == "" {
+			continue
+		}
+		primaryStats := benchEndpoint(ctx, kind, primary, samples)
+		if thresholds.healthy(primaryStats) {
+			log.Printf("Primary L1 %s endpoint is healthy", kind)
+			continue
+		}
+
+		var candidates []endpointStats
+		for _, url := range backups {
+			candidates = append(candidates, benchEndpoint(ctx, kind, url, samples))
+		}
+		best, ok := pickFailover(candidates, thresholds)
+		if !ok {
+			log.Printf("WARN primary L1 %s endpoint is unhealthy but no healthy backup is available", kind)
+			continue
+		}
+
+		keys, backupsKey := l1RPCKeys, l1RPCBackupsKey
+		if kind == "beacon" {
+			keys, backupsKey = l1BeaconKeys, l1BeaconBackupsKey
+		}
+		for _, key := range keys {
+			if env[key] == primary {
+				if err := setEnvValue(envPath, key, best.URL); err != nil {
+					return err
+				}
+			}
+		}
+		// Keep the old primary as a backup so it can be failed back to once it recovers.
+		remaining := slices.DeleteFunc(slices.Clone(backups), func(url string) bool { return url == best.URL })
+		if err := setEnvValue(envPath, backupsKey, strings.Join(append(remaining, primary), ",")); err != nil {
+			return err
+		}
+		log.Printf("Failed over L1 %s endpoint from %s to %s", kind, primary, best.URL)
+		changed = true
+	}
+
+	if changed && restart {
+		return runCompose(ctx, repoPath, envFile, "", "up", "-d", "--force-recreate", "--no-deps", "node")
+	}
+	return nil
+}
+
+func (t l1Thresholds) healthy(s endpointStats) bool {
+	if s.Samples == 0 || s.Errors == s.Samples {
+		return false
+	}
+	return float64(s.Errors)/float64(s.Samples) <= t.MaxErrorRate && s.Freshness <= t.MaxStaleness
+}
+
+// pickFailover returns the healthy candidate with the fewest errors, then lowest latency.
+func pickFailover(candidates []endpointStats, thresholds l1Thresholds) (endpointStats, bool) {
+	var healthy []endpointStats
+	for _, c := range candidates {
+		if thresholds.healthy(c) {
+			healthy = append(healthy, c)
+		}
+	}
+	if len(healthy) == 0 {
+		return endpointStats{}, false
+	}
+	slices.SortStableFunc(healthy, func(a, b endpointStats) int {
+		if a.Errors != b.Errors {
+			return a.Errors - b.Errors
+		}
+		return int(a.Latency - b.Latency)
+	})
+	return healthy[0], true
+}
+
+func benchEndpoint(ctx context.Context, kind string, url string, samples int) endpointStats {
+	stats := endpointStats{URL: url, Kind: kind}
+	var latencies []time.Duration
+	for i := 0; i < samples; i++ {
+		start := time.Now()
+		var headTime time.Time
+		var err error
+		if kind == "beacon" {
+			headTime, err = beaconHeadTime(ctx, url)
+		} else {
+			headTime, err = rpcHeadTime(ctx, url)
+		}
+		stats.Samples++
+		if err != nil {
+			stats.Errors++
+			stats.LastError = err.Error()
+			continue
+		}
+		latencies = append(latencies, time.Since(start))
+		stats.Freshness = time.Since(headTime)
+	}
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		stats.Latency = latencies[len(latencies)/2]
+	}
+	return stats
+}
+
+func rpcHeadTime(ctx context.Context, url string) (time.Time, error) {
+	block, err := newRPCClient(url).blockByNumber(ctx, "latest")
+	if err != nil {
+		return time.Time{}, err
+	}
+	ts, err := parseQuantity(block.Timestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(ts), 0), nil
+}
+
+func beaconHeadTime(ctx context.Context, url string) (time.Time, error) {
+	var genesis struct {
+		Data struct {
+			GenesisTime string `json:"genesis_time"`
+		} `json:"data"`
+	}
+	if err := beaconGet(ctx, url, "/eth/v1/beacon/genesis", &genesis); err != nil {
+		return time.Time{}, err
+	}
+	var header struct {
+		Data struct {
+			Header struct {
+				Message struct {
+					Slot string `json:"slot"`
+				} `json:"message"`
+			} `json:"header"`
+		} `json:"data"`
+	}
+	if err := beaconGet(ctx, url, "/eth/v1/beacon/headers/head", &header); err != nil {
+		return time.Time{}, err
+	}
+
+	genesisTime, err := strconv.ParseInt(genesis.Data.GenesisTime, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid genesis time %q", genesis.Data.GenesisTime)
+	}
+	slot, err := strconv.ParseInt(header.Data.Header.Message.Slot, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid head slot %q", header.Data.Header.Message.Slot)
+	}
+	return time.Unix(genesisTime, 0).Add(time.Duration(slot) * l1SlotTime), nil
+}
+
+func beaconGet(ctx context.Context, baseURL string, path string, result any) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func printEndpointStats(s endpointStats, primary bool, thresholds l1Thresholds) {
+	role := "backup"
+	if primary {
+		role = "primary"
+	}
+	status := "healthy"
+	if !thresholds.healthy(s) {
+		status = "UNHEALTHY"
+	}
+	fmt.Printf("%-6s %-7s %-9s latency %-8s errors %d/%d head age %-8s %s\n",
+		s.Kind, role, status, s.Latency.Round(time.Millisecond), s.Errors, s.Samples, s.Freshness.Round(time.Second), s.URL)
+	if s.LastError != "" {
+		fmt.Printf("       last error: %s\n", s.LastError)
+	}
+}
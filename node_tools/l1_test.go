@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestL1Endpoints(t *testing.T) {
+	env := map[string]string{
+		"OP_NODE_L1_ETH_RPC":   "https://primary.example",
+		"BASE_NODE_L1_ETH_RPC": "https://primary.example",
+		l1RPCBackupsKey:        "https://backup-a.example, https://primary.example,,https://backup-b.example",
+		"OP_NODE_L1_BEACON":    "<your-preferred-l1-beacon>",
+		"BASE_NODE_L1_BEACON":  "https://beacon.example",
+	}
+
+	primary, backups := l1Endpoints(env, "rpc")
+	if primary != "https://primary.example" {
+		t.Errorf("rpc primary = %q", primary)
+	}
+	if want := []string{"https://backup-a.example", "https://backup-b.example"}; !slices.Equal(backups, want) {
+		t.Errorf("rpc backups = %v, want %v", backups, want)
+	}
+
+	primary, backups = l1Endpoints(env, "beacon")
+	if primary != "https://beacon.example" || len(backups) != 0 {
+		t.Errorf("beacon endpoints = %q %v, want placeholder skipped and no backups", primary, backups)
+	}
+}
+
+func TestPickFailover(t *testing.T) {
+	thresholds := l1Thresholds{MaxErrorRate: 0.2, MaxStaleness: time.Minute}
+
+	tests := []struct {
+		name       string
+		candidates []endpointStats
+		wantURL    string
+		wantOk     bool
+	}{
+		{
+			name: "lowest latency among healthy",
+			candidates: []endpointStats{
+				{URL: "slow", Samples: 5, Latency: 300 * time.Millisecond, Freshness: 10 * time.Second},
+				{URL: "fast", Samples: 5, Latency: 50 * time.Millisecond, Freshness: 10 * time.Second},
+				{URL: "stale", Samples: 5, Latency: 10 * time.Millisecond, Freshness: 10 * time.Minute},
+			},
+			wantURL: "fast",
+			wantOk:  true,
+		},
+		{
+			name: "fewer errors wins over latency",
+			candidates: []endpointStats{
+				{URL: "flaky", Samples: 5, Errors: 1, Latency: 10 * time.Millisecond, Freshness: time.Second},
+				{URL: "steady", Samples: 5, Latency: 100 * time.Millisecond, Freshness: time.Second},
+			},
+			wantURL: "steady",
+			wantOk:  true,
+		},
+		{
+			name: "no healthy candidates",
+			candidates: []endpointStats{
+				{URL: "down", Samples: 5, Errors: 5},
+				{URL: "erroring", Samples: 5, Errors: 3, Freshness: time.Second},
+			},
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pickFailover(tt.candidates, thresholds)
+			if ok != tt.wantOk || got.URL != tt.wantURL {
+				t.Errorf("pickFailover() = %q, %v, want %q, %v", got.URL, ok, tt.wantURL, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+type lintFinding struct {
+	File    string
+	Line    int
+	Warning bool
+	Message string
+}
+
+var (
+	composeServicePattern = regexp.MustCompile(`^  ([A-Za-z0-9_-]+):\s*$`)
+	composePortPattern    = regexp.MustCompile(`^\s*-\s*"?(\d+):(\d+)(/udp)?"?`)
+	placeholderPattern    = regexp.MustCompile(`^<.*>$`)
+	commitPattern         = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// requiredEnv must be set to a real value in every network env file, for op-node and base-consensus respectively.
+var (
+	requiredEnv = []string{
+		"OP_NODE_NETWORK",
+		"OP_NODE_L1_ETH_RPC",
+		"OP_NODE_L1_BEACON",
+		"OP_NODE_L2_ENGINE_RPC",
+		"BASE_NODE_L2_ENGINE_AUTH",
+		jwtEnvKey,
+	}
+	requiredBaseConsensusEnv = []string{
+		"BASE_NODE_NETWORK",
+		"BASE_NODE_L1_ETH_RPC",
+		"BASE_NODE_L1_BEACON",
+		"BASE_NODE_L2_ENGINE_RPC",
+		"BASE_NODE_L2_ENGINE_AUTH",
+		jwtEnvKey,
+	}
+)
+
+// baseConsensusClients are the clients whose image ships base-consensus.
+var baseConsensusClients = []string{"reth"}
+
+// clientEnv lists the variables each execution client's entrypoint reads, and the
+// network setting they must agree with.
+var clientEnv = map[string]map[string]func(Network) string{
+	"geth": {
+		"OP_GETH_OP_NETWORK":     func(n Network) string { return n.OPNodeNetwork },
+		"OP_GETH_SEQUENCER_HTTP": func(n Network) string { return n.SequencerHTTP },
+	},
+	"reth": {
+		"RETH_CHAIN":          func(n Network) string { return n.RethChain },
+		"RETH_SEQUENCER_HTTP": func(n Network) string { return n.SequencerHTTP },
+	},
+	"nethermind": {
+		"OP_SEQUENCER_HTTP": func(n Network) string { return n.SequencerHTTP },
+	},
+}
+
+// containerPorts are the op-node settings that must match a container port published in docker-compose.yml.
+var containerPorts = map[string]string{
+	"OP_NODE_RPC_PORT":            "node",
+	"OP_NODE_P2P_LISTEN_TCP_PORT": "node",
+	"OP_NODE_METRICS_PORT":        "node",
+}
+
+func lintCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "lint",
+		Usage: "Validates the env files, docker-compose.yml and versions.env before docker compose up",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client to validate the env files for (geth, reth, nethermind), defaults to CLIENT from .env",
+				Sources: cli.EnvVars("CLIENT"),
+			},
+			&cli.StringFlag{
+				Name:    "env-file",
+				Usage:   "Only lint this network env file instead of every one",
+				Sources: cli.EnvVars("NETWORK_ENV"),
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			findings, err := lintRepo(cmd.String("repo"), cmd.String("client"), cmd.String("env-file"))
+			if err != nil {
+				return err
+			}
+			errors := 0
+			for _, f := range findings {
+				level := "error"
+				if f.Warning {
+					level = "warning"
+				} else {
+					errors++
+				}
+				location := f.File
+				if f.Line > 0 {
+					location = fmt.Sprintf("%s:%d", f.File, f.Line)
+				}
+				fmt.Printf("%s: %s: %s\n", location, level, f.Message)
+			}
+			if errors > 0 {
+				return fmt.Errorf("%d error(s) found", errors)
+			}
+			fmt.Println("No errors found")
+			return nil
+		},
+	}
+}
+
+func lintRepo(repoPath string, client string, envFile string) ([]lintFinding, error) {
+	var findings []lintFinding
+
+	composeDefaults, err := os.ReadFile(filepath.Join(repoPath, ".env"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading .env: %s", err)
+	}
+	if client == "" {
+		client = resolveDefault(parseEnv(string(composeDefaults))["CLIENT"])
+	}
+	if _, ok := clientDependencies[client]; !ok {
+		findings = append(findings, lintFinding{File: ".env", Line: envKeyLine(string(composeDefaults), "CLIENT"),
+			Message: fmt.Sprintf("CLIENT %q is not one of %s", client, strings.Join(sortedKeys(clientDependencies), ", "))})
+	}
+	if parseEnv(string(composeDefaults))["HOST_DATA_DIR"] == "" {
+		findings = append(findings, lintFinding{File: ".env", Message: "HOST_DATA_DIR is not set, docker compose has nowhere to mount /data"})
+	}
+	baseConsensus := os.Getenv("USE_BASE_CONSENSUS")
+	if baseConsensus == "" {
+		baseConsensus = parseEnv(string(composeDefaults))["USE_BASE_CONSENSUS"]
+	}
+	useBaseConsensus := baseConsensus == "true"
+	if useBaseConsensus && !slices.Contains(baseConsensusClients, client) {
+		findings = append(findings, lintFinding{File: ".env", Line: envKeyLine(string(composeDefaults), "USE_BASE_CONSENSUS"),
+			Message: fmt.Sprintf("USE_BASE_CONSENSUS=true but the %s image does not include base-consensus (supported: %s), the node container will exit",
+				client, strings.Join(baseConsensusClients, ", "))})
+	}
+
+	compose, err := os.ReadFile(filepath.Join(repoPath, "docker-compose.yml"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading docker-compose.yml: %s", err)
+	}
+	composeFindings, published := lintCompose(string(compose))
+	findings = append(findings, composeFindings...)
+
+	var networkList []Network
+	if envFile != "" {
+		network, ok := networkForEnvFile(envFile)
+		if !ok {
+			return nil, fmt.Errorf("can't tell which network %s is for", envFile)
+		}
+		networkList = append(networkList, network)
+	} else {
+		for _, name := range networkNames() {
+			networkList = append(networkList, networks[name])
+		}
+	}
+	for _, network := range networkList {
+		content, err := os.ReadFile(filepath.Join(repoPath, network.EnvFile))
+		if err != nil {
+			findings = append(findings, lintFinding{File: network.EnvFile, Message: err.Error()})
+			continue
+		}
+		findings = append(findings, lintNetworkEnv(network.EnvFile, string(content), network, client, useBaseConsensus, published)...)
+	}
+
+	findings = append(findings, lintVersions(repoPath)...)
+	return findings, nil
+}
+
+// lintCompose checks for host ports published twice and returns the container ports published per service.
+func lintCompose(content string) ([]lintFinding, map[string][]int) {
+	var findings []lintFinding
+	published := map[string][]int{}
+	hostPorts := map[string]string{}
+
+	service := ""
+	for i, line := range strings.Split(content, "\n") {
+		if m := composeServicePattern.FindStringSubmatch(line); m != nil {
+			service = m[1]
+			continue
+		}
+		m := composePortPattern.FindStringSubmatch(line)
+		if m == nil || service == "" {
+			continue
+		}
+		host, _ := strconv.Atoi(m[1])
+		container, _ := strconv.Atoi(m[2])
+		proto := "tcp"
+		if m[3] != "" {
+			proto = "udp"
+		}
+
+		key := fmt.Sprintf("%d/%s", host, proto)
+		if other, ok := hostPorts[key]; ok {
+			findings = append(findings, lintFinding{File: "docker-compose.yml", Line: i + 1,
+				Message: fmt.Sprintf("host port %s of %s is already published by %s", key, service, other)})
+		}
+		hostPorts[key] = service
+		if !slices.Contains(published[service], container) {
+			published[service] = append(published[service], container)
+		}
+	}
+	return findings, published
+}
+
+func lintNetworkEnv(file string, content string, network Network, client string, baseConsensus bool, published map[string][]int) []lintFinding {
+	var findings []lintFinding
+	add := func(key string, warning bool, format string, args ...any) {
+		findings = append(findings, lintFinding{File: file, Line: envKeyLine(content, key), Warning: warning, Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := map[string]int{}
+	for i, line := range strings.Split(content, "\n") {
+		key, _, ok := parseEnvLine(line)
+		if !ok {
+			continue
+		}
+		if first, dup := seen[key]; dup {
+			findings = append(findings, lintFinding{File: file, Line: i + 1, Warning: true,
+				Message: fmt.Sprintf("%s is also set on line %d, the last value wins", key, first)})
+		}
+		seen[key] = i + 1
+	}
+	env := parseEnv(content)
+
+	required := requiredEnv
+	if baseConsensus {
+		required = requiredBaseConsensusEnv
+	}
+	for _, key := range required {
+		switch value := env[key]; {
+		case value == "" && key == "OP_NODE_NETWORK" && env["OP_NODE_ROLLUP_CONFIG"] != "":
+		case value == "":
+			add(key, false, "%s is required", key)
+		case placeholderPattern.MatchString(value):
+			add(key, false, "%s is still the placeholder %s", key, value)
+		}
+	}
+	if value := env[jwtEnvKey]; value != "" {
+		if err := validateJWTSecret(value); err != nil {
+			add(jwtEnvKey, false, "%s: %s", jwtEnvKey, err)
+		}
+	}
+
+	if value := env["OP_NODE_NETWORK"]; value != "" && value != network.OPNodeNetwork {
+		add("OP_NODE_NETWORK", false, "OP_NODE_NETWORK is %s but %s is the %s env file (%s)", value, file, network.Name, network.OPNodeNetwork)
+	}
+	for _, key := range sortedKeys(clientEnv[client]) {
+		want := clientEnv[client][key](network)
+		switch value := env[key]; {
+		case value == "":
+			add(key, false, "%s is required by the %s entrypoint", key, client)
+		case value != want:
+			add(key, false, "%s is %s, expected %s for %s", key, value, want, network.Name)
+		}
+	}
+
+	for _, key := range sortedKeys(containerPorts) {
+		value, ok := env[key]
+		if !ok {
+			continue
+		}
+		port, err := strconv.Atoi(value)
+		if err != nil || port <= 0 || port > 65535 {
+			add(key, false, "%s is %q, not a valid port", key, value)
+			continue
+		}
+		service := containerPorts[key]
+		if !slices.Contains(published[service], port) {
+			add(key, true, "%s is %d but docker-compose.yml does not publish container port %d for %s", key, port, port, service)
+		}
+	}
+	return findings
+}
+
+func lintVersions(repoPath string) []lintFinding {
+	var findings []lintFinding
+	versions, err := readPinnedVersions(repoPath)
+	if err != nil {
+		return append(findings, lintFinding{File: "versions.json", Message: err.Error()})
+	}
+	content, err := os.ReadFile(filepath.Join(repoPath, "versions.env"))
+	if err != nil {
+		return append(findings, lintFinding{File: "versions.env", Message: err.Error()})
+	}
+	env := parseEnv(string(content))
+
+	for _, dep := range sortedKeys(versions) {
+		p := versions[dep]
+		if !commitPattern.MatchString(p.Commit) {
+			findings = append(findings, lintFinding{File: "versions.json", Message: fmt.Sprintf("%s commit %q is not a full commit hash", dep, p.Commit)})
+		}
+		if pinnedRef(p) == "" {
+			findings = append(findings, lintFinding{File: "versions.json", Message: fmt.Sprintf("%s has no tag or branch", dep)})
+		}
+	}
+	if err := checkVersionsEnv(versions, env, sortedKeys(versions)); err != nil {
+		findings = append(findings, lintFinding{File: "versions.env", Message: err.Error()})
+	}
+	return findings
+}
+
+// envKeyLine returns the line number key is last set on, or 0.
+func envKeyLine(content string, key string) int {
+	line := 0
+	for i, l := range strings.Split(content, "\n") {
+		if k, _, ok := parseEnvLine(l); ok && k == key {
+			line = i + 1
+		}
+	}
+	return line
+}
+
+// resolveDefault expands a compose style "${VAR:-default}" using the environment.
+func resolveDefault(value string) string {
+	inner, ok := strings.CutPrefix(value, "${")
+	if !ok {
+		return value
+	}
+	inner = strings.TrimSuffix(inner, "}")
+	name, def, _ := strings.Cut(inner, ":-")
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func networkForEnvFile(envFile string) (Network, bool) {
+	for _, network := range networks {
+		if filepath.Base(envFile) == network.EnvFile {
+			return network, true
+		}
+	}
+	return Network{}, false
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintCompose(t *testing.T) {
+	compose := `services:
+  execution:
+    ports:
+      - "8545:8545" # RPC
+      - "30303:30303/udp" # P2P UDP
+  node:
+    ports:
+      - "7545:8545" # RPC
+      - "8545:9545" # clashes with execution
+      - "30303:9222/udp"
+`
+	findings, published := lintCompose(compose)
+	if len(findings) != 2 {
+		t.Fatalf("lintCompose() findings = %+v, want 2", findings)
+	}
+	if findings[0].Line != 9 || !strings.Contains(findings[0].Message, "8545/tcp") {
+		t.Errorf("lintCompose() first finding = %+v, want the 8545/tcp clash on line 9", findings[0])
+	}
+	if got := published["node"]; len(got) != 3 || got[0] != 8545 || got[1] != 9545 || got[2] != 9222 {
+		t.Errorf("lintCompose() node container ports = %v", got)
+	}
+}
+
+func TestLintNetworkEnv(t *testing.T) {
+	published := map[string][]int{"node": {8545, 9222, 7300}}
+	env := `OP_NODE_NETWORK=base-sepolia
+OP_NODE_L1_ETH_RPC=<your-preferred-l1-rpc>
+OP_NODE_L1_BEACON=https://beacon.example
+OP_NODE_L2_ENGINE_RPC=http://execution:8551
+BASE_NODE_L2_ENGINE_AUTH=/tmp/engine-auth-jwt
+BASE_NODE_L2_ENGINE_AUTH_RAW=688f5d737bad920bdfb2fc2f488d6b6209eebda1dae949a8de91398d932c517a
+RETH_CHAIN=base
+OP_NODE_RPC_PORT=8545
+OP_NODE_METRICS_PORT=7301
+OP_NODE_RPC_PORT=8545
+`
+	findings := lintNetworkEnv(".env.mainnet", env, networks["mainnet"], "reth", false, published)
+
+	want := []struct {
+		line    int
+		warning bool
+		message string
+	}{
+		{10, true, "OP_NODE_RPC_PORT is also set on line 8"},
+		{2, false, "OP_NODE_L1_ETH_RPC is still the placeholder"},
+		{1, false, "OP_NODE_NETWORK is base-sepolia"},
+		{0, false, "RETH_SEQUENCER_HTTP is required by the reth entrypoint"},
+		{9, true, "OP_NODE_METRICS_PORT is 7301"},
+	}
+	for _, w := range want {
+		found := false
+		for _, f := range findings {
+			if f.Line == w.line && f.Warning == w.warning && strings.Contains(f.Message, w.message) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing finding on line %d: %q in %+v", w.line, w.message, findings)
+		}
+	}
+	for _, f := range findings {
+		if strings.Contains(f.Message, "RETH_CHAIN is base,") {
+			t.Errorf("RETH_CHAIN base is correct for mainnet, got %q", f.Message)
+		}
+	}
+}
+
+func TestLintRepo(t *testing.T) {
+	findings, err := lintRepo("..", "reth", "")
+	if err != nil {
+		t.Fatalf("lintRepo() unexpected error: %v", err)
+	}
+	for _, f := range findings {
+		if !f.Warning && !strings.Contains(f.Message, "placeholder") {
+			t.Errorf("lintRepo() on the repo itself reported %s:%d: %s", f.File, f.Line, f.Message)
+		}
+	}
+}
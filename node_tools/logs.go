@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// LogSignature is a known log pattern in log-signatures.json. Signatures are matched in
+// order, so more specific patterns must come before general ones.
+type LogSignature struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Pattern     string `json:"pattern"`
+	Description string `json:"description"`
+	Hint        string `json:"hint"`
+
+	re *regexp.Regexp
+}
+
+type logEvent struct {
+	Signature *LogSignature
+	Service   string
+	Line      string
+	Time      time.Time
+	// Count includes occurrences suppressed since the event was last emitted.
+	Count int
+}
+
+type signatureState struct {
+	lastEmitted time.Time
+	suppressed  int
+	total       int
+}
+
+// logAnalyzer classifies log lines and suppresses repeats of a signature within the dedup window.
+type logAnalyzer struct {
+	signatures []*LogSignature
+	window     time.Duration
+	seen       map[string]*signatureState
+}
+
+func logsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "logs",
+		Usage: "Classify client logs against known error signatures",
+		Commands: []*cli.Command{
+			{
+				Name:  "analyze",
+				Usage: "Reads docker compose logs (or --file) and prints deduplicated, classified events",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "repo",
+						Usage: "Path to the node repository",
+						Value: ".",
+					},
+					&cli.StringFlag{
+						Name:    "env-file",
+						Usage:   "Network env file passed to docker compose",
+						Sources: cli.EnvVars("NETWORK_ENV"),
+						Value:   ".env.mainnet",
+					},
+					&cli.StringFlag{
+						Name:    "client",
+						Usage:   "Execution client passed to docker compose",
+						Sources: cli.EnvVars("CLIENT"),
+					},
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "Only read logs newer than this (passed to docker compose logs --since)",
+						Value: "1h",
+					},
+					&cli.BoolFlag{
+						Name:  "follow",
+						Usage: "Keep tailing the logs",
+					},
+					&cli.StringFlag{
+						Name:  "file",
+						Usage: "Read logs from a file instead of docker compose, - for stdin",
+					},
+					&cli.StringFlag{
+						Name:  "signatures",
+						Usage: "Signature database, defaults to <repo>/node_tools/log-signatures.json",
+					},
+					&cli.DurationFlag{
+						Name:  "dedup-window",
+						Usage: "Report repeats of the same signature at most once per window",
+						Value: 10 * time.Minute,
+					},
+					webhookFlag,
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					sigPath := cmd.String("signatures")
+					if sigPath == "" {
+						sigPath = filepath.Join(cmd.String("repo"), "node_tools", "log-signatures.json")
+					}
+					signatures, err := readLogSignatures(sigPath)
+					if err != nil {
+						return err
+					}
+					analyzer := newLogAnalyzer(signatures, cmd.Duration("dedup-window"))
+
+					var input io.Reader
+					switch cmd.String("file") {
+					case "":
+						args := []string{"logs", "--no-color", "--since", cmd.String("since")}
+						if cmd.Bool("follow") {
+							args = append(args, "--follow")
+						}
+						logs, wait, err := streamCompose(ctx, cmd.String("repo"), cmd.String("env-file"), cmd.String("client"), args...)
+						if err != nil {
+							return err
+						}
+						defer wait()
+						input = logs
+					case "-":
+						input = os.Stdin
+					default:
+						f, err := os.Open(cmd.String("file"))
+						if err != nil {
+							return fmt.Errorf("error opening log file: %s", err)
+						}
+						defer f.Close()
+						input = f
+					}
+
+					alerter := newAlerter(cmd.String("webhook"))
+					return analyzeLogs(ctx, input, analyzer, func(e *logEvent) {
+						fmt.Println(formatLogEvent(e))
+						if e.Signature.Severity == "critical" {
+							alerter.alert(ctx, fmt.Sprintf("%s: %s", e.Service, e.Signature.Description))
+						}
+					})
+				},
+			},
+		},
+	}
+}
+
+func analyzeLogs(ctx context.Context, input io.Reader, analyzer *logAnalyzer, emit func(*logEvent)) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			break
+		}
+		service, line := splitComposeLogLine(scanner.Text())
+		if e := analyzer.process(service, line, time.Now()); e != nil {
+			emit(e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading logs: %s", err)
+	}
+
+	summary := analyzer.summary()
+	if len(summary) == 0 {
+		log.Printf("No known error signatures found")
+		return nil
+	}
+	log.Printf("Summary:")
+	for _, s := range summary {
+		log.Printf("  %-8s %-28s %d", s.Severity, s.ID, analyzer.seen[s.ID].total)
+	}
+	return nil
+}
+
+func newLogAnalyzer(signatures []*LogSignature, window time.Duration) *logAnalyzer {
+	return &logAnalyzer{signatures: signatures, window: window, seen: map[string]*signatureState{}}
+}
+
+// process returns an event for the first matching signature, or nil if the line
+// matches nothing or repeats a signature already reported within the window.
+func (a *logAnalyzer) process(service string, line string, now time.Time) *logEvent {
+	var sig *LogSignature
+	for _, s := range a.signatures {
+		if s.re.MatchString(line) {
+			sig = s
+			break
+		}
+	}
+	if sig == nil {
+		return nil
+	}
+
+	state, ok := a.seen[sig.ID]
+	if !ok {
+		state = &signatureState{}
+		a.seen[sig.ID] = state
+	}
+	state.total++
+	if ok && now.Sub(state.lastEmitted) < a.window {
+		state.suppressed++
+		return nil
+	}
+
+	e := &logEvent{Signature: sig, Service: service, Line: line, Time: now, Count: state.suppressed + 1}
+	state.lastEmitted = now
+	state.suppressed = 0
+	return e
+}
+
+// summary returns the signatures seen so far, most frequent first.
+func (a *logAnalyzer) summary() []*LogSignature {
+	var seen []*LogSignature
+	for _, s := range a.signatures {
+		if _, ok := a.seen[s.ID]; ok {
+			seen = append(seen, s)
+		}
+	}
+	slices.SortStableFunc(seen, func(x, y *LogSignature) int {
+		return a.seen[y.ID].total - a.seen[x.ID].total
+	})
+	return seen
+}
+
+func formatLogEvent(e *logEvent) string {
+	repeats := ""
+	if e.Count > 1 {
+		repeats = fmt.Sprintf(" (%d occurrences since last report)", e.Count)
+	}
+	line := e.Line
+	if len(line) > 200 {
+		line = line[:200] + "..."
+	}
+	return fmt.Sprintf("%s %-8s %s [%s] %s%s\n  %s\n  > %s",
+		e.Time.Format(time.RFC3339), strings.ToUpper(e.Signature.Severity), e.Signature.ID, orUnknown(e.Service),
+		e.Signature.Description, repeats, e.Signature.Hint, line)
+}
+
+// splitComposeLogLine splits the "node-1  | " prefix docker compose logs adds.
+func splitComposeLogLine(line string) (string, string) {
+	prefix, rest, ok := strings.Cut(line, " | ")
+	if !ok || strings.ContainsAny(strings.TrimSpace(prefix), " =") {
+		return "", line
+	}
+	service := strings.TrimSpace(prefix)
+	if i := strings.LastIndex(service, "-"); i > 0 {
+		service = service[:i]
+	}
+	return service, rest
+}
+
+func readLogSignatures(path string) ([]*LogSignature, error) {
+	var signatures []*LogSignature
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading log signatures: %s", err)
+	}
+	if err := json.Unmarshal(f, &signatures); err != nil {
+		return nil, fmt.Errorf("error unmarshalling log signatures: %s", err)
+	}
+	for _, s := range signatures {
+		s.re, err = regexp.Compile(s.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for log signature %s: %s", s.ID, err)
+		}
+	}
+	return signatures, nil
+}
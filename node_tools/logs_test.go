@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogSignatures(t *testing.T) {
+	signatures, err := readLogSignatures("log-signatures.json")
+	if err != nil {
+		t.Fatalf("readLogSignatures() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`Fatal: Failed to write block: write /data/geth/chaindata/012345.ldb: no space left on device`, "out-of-disk"},
+		{`ERROR Database error: mdbx error: MDBX_CORRUPTED: Maybe free space is over on disk`, "db-corruption"},
+		{`WARN [10-14|12:00:00.000] Served eth_call err="missing trie node 1a2b3c (path ) state 0x1a2b3c is not available"`, "missing-trie-node"},
+		{`t=2026-10-14T12:00:00Z lvl=warn msg="Derivation process temporary error" attempts=3 err="engine stage failed"`, "derivation-temporary-error"},
+		{`t=2026-10-14T12:00:00Z lvl=warn msg="Derivation process reset" err="reset: new L1 origin"`, "derivation-reset"},
+		{`WARN [10-14|12:00:00.000] Post-merge network, but no beacon client seen. Please launch one to follow the chain!`, "no-consensus-client"},
+		{`t=2026-10-14T12:00:00Z lvl=info msg="banning peer" peer=16Uiu2HAm score=-45`, "peer-ban"},
+		{`t=2026-10-14T12:00:00Z lvl=info msg="Received signed execution payload from p2p" id=0xabc:123`, ""},
+	}
+
+	analyzer := newLogAnalyzer(signatures, 0)
+	for _, tt := range tests {
+		got := ""
+		if e := analyzer.process("", tt.line, time.Now()); e != nil {
+			got = e.Signature.ID
+		}
+		if got != tt.want {
+			t.Errorf("process(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLogAnalyzerDedup(t *testing.T) {
+	signatures, err := readLogSignatures("log-signatures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer := newLogAnalyzer(signatures, 10*time.Minute)
+	line := "no space left on device"
+	start := time.Unix(1_700_000_000, 0)
+
+	if e := analyzer.process("execution", line, start); e == nil || e.Count != 1 {
+		t.Fatalf("first occurrence = %+v, want an event with count 1", e)
+	}
+	for i := 1; i <= 3; i++ {
+		if e := analyzer.process("execution", line, start.Add(time.Duration(i)*time.Minute)); e != nil {
+			t.Errorf("repeat within window emitted %+v", e)
+		}
+	}
+	e := analyzer.process("execution", line, start.Add(11*time.Minute))
+	if e == nil || e.Count != 4 {
+		t.Errorf("occurrence after window = %+v, want an event with count 4", e)
+	}
+	if got := analyzer.seen["out-of-disk"].total; got != 5 {
+		t.Errorf("total = %d, want 5", got)
+	}
+}
+
+func TestSplitComposeLogLine(t *testing.T) {
+	tests := []struct {
+		line, service, rest string
+	}{
+		{"node-1  | t=2026 lvl=info msg=hi", "node", "t=2026 lvl=info msg=hi"},
+		{"base-execution-1 | INFO ok", "base-execution", "INFO ok"},
+		{"msg=\"a | b\"", "", "msg=\"a | b\""},
+	}
+	for _, tt := range tests {
+		service, rest := splitComposeLogLine(tt.line)
+		if service != tt.service || rest != tt.rest {
+			t.Errorf("splitComposeLogLine(%q) = %q, %q, want %q, %q", tt.line, service, rest, tt.service, tt.rest)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/urfave/cli/v3"
+)
+
+func main() {
+	cmd := &cli.Command{
+		Name:  "node-tools",
+		Usage: "Operator tooling for running and maintaining a Base node",
+		Commands: []*cli.Command{
+			jwtCommand(),
+			artifactsCommand(),
+			syncStatusCommand(),
+			peersCommand(),
+			diskForecastCommand(),
+			pruneCommand(),
+			headsCommand(),
+			smoketestCommand(),
+			l1Command(),
+			versionDriftCommand(),
+			verifyUpgradeCommand(),
+			backupCommand(),
+			restoreCommand(),
+			composeCommand(),
+			supportCommand(),
+			hardforksCommand(),
+			logsCommand(),
+			applyCommand(),
+			rolloutCommand(),
+			reorgsCommand(),
+			verifyHeadCommand(),
+			estimateCommand(),
+			lintCommand(),
+			initCommand(),
+			statusCommand(),
+			bandwidthCommand(),
+			stallCommand(),
+			verifyDataCommand(),
+			modeCommand(),
+		},
+	}
+
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// modeEnvKeys are the env file settings selecting archive or full mode per client.
+var modeEnvKeys = map[string]string{
+	"geth": "OP_GETH_GCMODE",
+	"reth": "RETH_ARCHIVE",
+}
+
+// modePlan is the supported way to move a node between archive and full mode.
+type modePlan struct {
+	Client   string
+	From     string
+	To       string
+	EnvKey   string
+	EnvValue string
+	// Resync is true when the target mode needs a new data directory synced from scratch,
+	// otherwise the switch is a restart with the new setting.
+	Resync bool
+	Reason string
+}
+
+func modeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "mode",
+		Usage: "Advises on (and with --apply carries out) switching between archive and full mode",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:  "network",
+				Usage: "Network the node runs on (" + strings.Join(networkNames(), ", ") + ")",
+				Value: "mainnet",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client (geth, reth)",
+				Sources: cli.EnvVars("CLIENT"),
+				Value:   "geth",
+			},
+			&cli.StringFlag{
+				Name:     "to",
+				Usage:    "Target mode (archive, full)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "data-dir",
+				Usage: "Data directory the node is resynced into when the switch needs a resync",
+			},
+			&cli.BoolFlag{
+				Name:  "apply",
+				Usage: "Carry out the switch: restart with the new setting, or resync alongside the running node and switch over with rollout",
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint of the running node",
+				Value: defaultELRPC,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			repoPath, client := cmd.String("repo"), cmd.String("client")
+			network, err := lookupNetwork(cmd.String("network"))
+			if err != nil {
+				return err
+			}
+			envPath := filepath.Join(repoPath, network.EnvFile)
+			env, err := readEnvFile(envPath)
+			if err != nil {
+				return err
+			}
+			from, err := currentMode(client, env)
+			if err != nil {
+				return err
+			}
+			plan, err := planModeSwitch(client, from, cmd.String("to"))
+			if err != nil {
+				return err
+			}
+			profiles, err := readResourceProfiles(filepath.Join(repoPath, "node_tools", "resource-profiles.json"))
+			if err != nil {
+				return err
+			}
+			profile, hasProfile := profiles.Profiles[network.Name][client][plan.To]
+
+			fmt.Printf("%s %s: %s -> %s\n", network.Name, client, plan.From, plan.To)
+			if plan.From == plan.To {
+				fmt.Println("Already in the requested mode")
+				return nil
+			}
+			fmt.Println(plan.Reason)
+			fmt.Printf("Setting: %s=%s in %s\n", plan.EnvKey, plan.EnvValue, network.EnvFile)
+			if !plan.Resync {
+				fmt.Println("Disk: no additional space needed")
+				fmt.Println("Downtime: one restart of the execution service, typically a few minutes")
+			} else {
+				if hasProfile {
+					fmt.Printf("Disk: %d GiB for the new data directory (%d GiB + 20%%), the current one is only freed after the switch\n",
+						uint64(float64(profile.DiskGB)*diskBuffer), profile.DiskGB)
+					fmt.Printf("Downtime: none with --apply, the new copy syncs alongside for about %dh and is switched over by rollout; restarting on an empty data directory instead is down for that long\n", profile.SyncHours)
+				} else {
+					fmt.Printf("Disk and downtime: no resource profile for %s %s %s\n", network.Name, client, plan.To)
+				}
+			}
+
+			if !cmd.Bool("apply") {
+				return nil
+			}
+			if !plan.Resync {
+				if err := setEnvValue(envPath, plan.EnvKey, plan.EnvValue); err != nil {
+					return err
+				}
+				return runCompose(ctx, repoPath, network.EnvFile, client, "up", "-d", "--no-deps", "--force-recreate", "execution")
+			}
+
+			if cmd.String("data-dir") == "" {
+				return fmt.Errorf("--data-dir is required for a switch that needs a resync")
+			}
+			dataDir, err := filepath.Abs(cmd.String("data-dir"))
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(dataDir, 0755); err != nil {
+				return fmt.Errorf("error creating %s: %s", dataDir, err)
+			}
+			if hasProfile {
+				volume, err := statVolume(dataDir)
+				if err != nil {
+					return err
+				}
+				if need := uint64(float64(profile.DiskGB)*diskBuffer) << 30; volume.Free < need {
+					return fmt.Errorf("%s has %s free, the %s data directory needs %s", dataDir, formatBytes(volume.Free), plan.To, formatBytes(need))
+				}
+			}
+			// The running node shares the env file, so it is restored if the new copy never takes over.
+			previous, err := os.ReadFile(envPath)
+			if err != nil {
+				return fmt.Errorf("error reading env file %s: %s", envPath, err)
+			}
+			info, err := os.Stat(envPath)
+			if err != nil {
+				return fmt.Errorf("error reading env file %s: %s", envPath, err)
+			}
+			if err := setEnvValue(envPath, plan.EnvKey, plan.EnvValue); err != nil {
+				return err
+			}
+			log.Printf("Set %s=%s, the running node keeps its mode until its container is recreated", plan.EnvKey, plan.EnvValue)
+			timeout := 2 * time.Duration(max(profile.SyncHours, 24)) * time.Hour
+			rolloutErr := runRollout(ctx, rolloutOptions{
+				RepoPath:   repoPath,
+				Network:    network,
+				Client:     client,
+				DataDir:    dataDir,
+				PortOffset: 10000,
+				MaxLag:     5,
+				Timeout:    timeout,
+				EL:         newRPCClient(cmd.String("el-rpc")),
+			})
+			if rolloutErr != nil {
+				if err := writeFileAtomic(envPath, previous, info.Mode().Perm()); err != nil {
+					return fmt.Errorf("%s, and restoring %s failed: %s", rolloutErr, envPath, err)
+				}
+				log.Printf("Restored %s in %s after the rollout failed", plan.EnvKey, envPath)
+			}
+			return rolloutErr
+		},
+	}
+}
+
+func currentMode(client string, env map[string]string) (string, error) {
+	switch client {
+	case "geth":
+		if env["OP_GETH_GCMODE"] == "archive" {
+			return "archive", nil
+		}
+		return "full", nil
+	case "reth":
+		if env["RETH_ARCHIVE"] == "true" {
+			return "archive", nil
+		}
+		return "full", nil
+	}
+	return "", fmt.Errorf("switching modes is not supported for %s", client)
+}
+
+func planModeSwitch(client string, from string, to string) (modePlan, error) {
+	if to != "archive" && to != "full" {
+		return modePlan{}, fmt.Errorf("invalid mode %q, expected archive or full", to)
+	}
+	plan := modePlan{Client: client, From: from, To: to, EnvKey: modeEnvKeys[client]}
+	switch client {
+	case "geth":
+		plan.EnvValue = to
+		if to == "full" {
+			plan.Reason = "geth can keep running on the existing data directory, it stops writing historical state but the archive state already on disk is not reclaimed without a resync"
+		} else {
+			plan.Resync = true
+			plan.Reason = "a full node has discarded historical state, which geth can only rebuild by syncing from genesis with --gcmode=archive"
+		}
+	case "reth":
+		plan.EnvValue = fmt.Sprint(to == "archive")
+		plan.Resync = true
+		plan.Reason = "reth fixes the node type at the first sync and does not support converting an existing data directory"
+	default:
+		return modePlan{}, fmt.Errorf("switching modes is not supported for %s", client)
+	}
+	return plan, nil
+}
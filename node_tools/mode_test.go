@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestPlanModeSwitch(t *testing.T) {
+	tests := []struct {
+		client     string
+		env        map[string]string
+		to         string
+		wantFrom   string
+		wantValue  string
+		wantResync bool
+		wantErr    bool
+	}{
+		{client: "geth", env: map[string]string{}, to: "archive", wantFrom: "full", wantValue: "archive", wantResync: true},
+		{client: "geth", env: map[string]string{"OP_GETH_GCMODE": "archive"}, to: "full", wantFrom: "archive", wantValue: "full"},
+		{client: "reth", env: map[string]string{}, to: "archive", wantFrom: "full", wantValue: "true", wantResync: true},
+		{client: "reth", env: map[string]string{"RETH_ARCHIVE": "true"}, to: "full", wantFrom: "archive", wantValue: "false", wantResync: true},
+		{client: "geth", env: map[string]string{}, to: "pruned", wantFrom: "full", wantErr: true},
+		{client: "nethermind", env: map[string]string{}, to: "archive", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		from, err := currentMode(tt.client, tt.env)
+		if err != nil {
+			if !tt.wantErr {
+				t.Errorf("currentMode(%s) unexpected error: %v", tt.client, err)
+			}
+			continue
+		}
+		if from != tt.wantFrom {
+			t.Errorf("currentMode(%s, %v) = %s, want %s", tt.client, tt.env, from, tt.wantFrom)
+		}
+		plan, err := planModeSwitch(tt.client, from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("planModeSwitch(%s, %s, %s) error = %v, wantErr %v", tt.client, from, tt.to, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if plan.EnvValue != tt.wantValue || plan.Resync != tt.wantResync || plan.EnvKey != modeEnvKeys[tt.client] {
+			t.Errorf("planModeSwitch(%s, %s, %s) = %+v", tt.client, from, tt.to, plan)
+		}
+	}
+}
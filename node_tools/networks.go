@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Network struct {
+	Name          string
+	EnvFile       string
+	OPNodeNetwork string
+	ChainID       uint64
+	PublicRPC     string
+	SequencerHTTP string
+	RethChain     string
+}
+
+var networks = map[string]Network{
+	"mainnet": {
+		Name:          "mainnet",
+		EnvFile:       ".env.mainnet",
+		OPNodeNetwork: "base-mainnet",
+		ChainID:       8453,
+		PublicRPC:     "https://mainnet.base.org",
+		SequencerHTTP: "https://mainnet-sequencer.base.org",
+		RethChain:     "base",
+	},
+	"sepolia": {
+		Name:          "sepolia",
+		EnvFile:       ".env.sepolia",
+		OPNodeNetwork: "base-sepolia",
+		ChainID:       84532,
+		PublicRPC:     "https://sepolia.base.org",
+		SequencerHTTP: "https://sepolia-sequencer.base.org",
+		RethChain:     "base-sepolia",
+	},
+}
+
+// lookupNetwork accepts either the short name ("mainnet") or the op-node network name ("base-mainnet").
+func lookupNetwork(name string) (Network, error) {
+	if n, ok := networks[name]; ok {
+		return n, nil
+	}
+	for _, n := range networks {
+		if n.OPNodeNetwork == name {
+			return n, nil
+		}
+	}
+	return Network{}, fmt.Errorf("unknown network %q, expected one of: %s", name, strings.Join(networkNames(), ", "))
+}
+
+func networkNames() []string {
+	var names []string
+	for name := range networks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
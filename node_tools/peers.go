@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// PeerDump is the subset of op-node's opp2p_peers result the monitor uses.
+type PeerDump struct {
+	TotalConnected uint                `json:"totalConnected"`
+	Peers          map[string]PeerInfo `json:"peers"`
+	BannedPeers    []string            `json:"bannedPeers"`
+}
+
+type PeerInfo struct {
+	PeerID    string     `json:"peerID"`
+	UserAgent string     `json:"userAgent"`
+	Addresses []string   `json:"addresses"`
+	Scores    PeerScores `json:"scores"`
+}
+
+type PeerScores struct {
+	Gossip struct {
+		Total float64 `json:"total"`
+	} `json:"gossip"`
+}
+
+type peerSample struct {
+	Time        time.Time
+	NodePeers   uint
+	ELPeers     uint64
+	LowScore    int
+	AvgScore    float64
+	Banned      int
+	ScoredPeers int
+}
+
+type peerThresholds struct {
+	MinNodePeers uint
+	MinELPeers   uint64
+	MinScore     float64
+}
+
+func peersCommand() *cli.Command {
+	nodeFlag := &cli.StringFlag{
+		Name:  "node-rpc",
+		Usage: "op-node RPC endpoint",
+		Value: defaultNodeRPC,
+	}
+	elFlag := &cli.StringFlag{
+		Name:  "el-rpc",
+		Usage: "Execution client RPC endpoint",
+		Value: defaultELRPC,
+	}
+
+	return &cli.Command{
+		Name:  "peers",
+		Usage: "Monitors op-node and execution client peer health",
+		Commands: []*cli.Command{
+			{
+				Name:  "monitor",
+				Usage: "Samples peer counts and scores and warns when they fall below thresholds",
+				Flags: []cli.Flag{
+					nodeFlag, elFlag,
+					&cli.DurationFlag{
+						Name:  "interval",
+						Usage: "Time between samples",
+						Value: 30 * time.Second,
+					},
+					&cli.UintFlag{
+						Name:  "min-node-peers",
+						Usage: "Warn when op-node has fewer connected peers",
+						Value: 10,
+					},
+					&cli.Uint64Flag{
+						Name:  "min-el-peers",
+						Usage: "Warn when the execution client has fewer peers",
+						Value: 10,
+					},
+					&cli.FloatFlag{
+						Name:  "min-score",
+						Usage: "Gossip score below which an op-node peer counts as low scoring",
+						Value: 0,
+					},
+					&cli.IntFlag{
+						Name:  "consecutive",
+						Usage: "Number of consecutive unhealthy samples before warning",
+						Value: 3,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					thresholds := peerThresholds{
+						MinNodePeers: cmd.Uint("min-node-peers"),
+						MinELPeers:   cmd.Uint64("min-el-peers"),
+						MinScore:     cmd.Float("min-score"),
+					}
+					return monitorPeers(ctx, newRPCClient(cmd.String("node-rpc")), newRPCClient(cmd.String("el-rpc")),
+						thresholds, cmd.Duration("interval"), int(cmd.Int("consecutive")))
+				},
+			},
+			{
+				Name:  "dump",
+				Usage: "Prints full peer details as JSON for debugging connectivity or NAT issues",
+				Flags: []cli.Flag{
+					nodeFlag, elFlag,
+					&cli.BoolFlag{
+						Name:  "el",
+						Usage: "Also dump execution client peers via admin_peers (requires the admin API)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return dumpPeers(ctx, newRPCClient(cmd.String("node-rpc")), newRPCClient(cmd.String("el-rpc")), cmd.Bool("el"))
+				},
+			},
+		},
+	}
+}
+
+func monitorPeers(ctx context.Context, node *rpcClient, el *rpcClient, thresholds peerThresholds, interval time.Duration, consecutive int) error {
+	unhealthy := 0
+	for {
+		sample, err := takePeerSample(ctx, node, el, thresholds.MinScore)
+		if err != nil {
+			log.Printf("Error sampling peers: %s", err)
+		} else {
+			fmt.Printf("%s node peers %d (banned %d, low score %d, avg score %.2f) el peers %d\n",
+				sample.Time.Format(time.RFC3339), sample.NodePeers, sample.Banned, sample.LowScore, sample.AvgScore, sample.ELPeers)
+
+			warnings := evaluatePeerHealth(sample, thresholds)
+			if len(warnings) == 0 {
+				unhealthy = 0
+			} else {
+				unhealthy++
+				if unhealthy >= consecutive {
+					for _, w := range warnings {
+						log.Printf("WARN %s (for %d consecutive samples)", w, unhealthy)
+					}
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func takePeerSample(ctx context.Context, node *rpcClient, el *rpcClient, minScore float64) (peerSample, error) {
+	var dump PeerDump
+	if err := node.call(ctx, &dump, "opp2p_peers", true); err != nil {
+		return peerSample{}, err
+	}
+	var elPeers string
+	if err := el.call(ctx, &elPeers, "net_peerCount"); err != nil {
+		return peerSample{}, err
+	}
+	elCount, err := parseQuantity(elPeers)
+	if err != nil {
+		return peerSample{}, err
+	}
+
+	sample := peerSample{
+		Time:      time.Now(),
+		NodePeers: dump.TotalConnected,
+		ELPeers:   elCount,
+		Banned:    len(dump.BannedPeers),
+	}
+	total := 0.0
+	for _, peer := range dump.Peers {
+		score := peer.Scores.Gossip.Total
+		total += score
+		sample.ScoredPeers++
+		if score < minScore {
+			sample.LowScore++
+		}
+	}
+	if sample.ScoredPeers > 0 {
+		sample.AvgScore = total / float64(sample.ScoredPeers)
+	}
+	return sample, nil
+}
+
+func evaluatePeerHealth(sample peerSample, thresholds peerThresholds) []string {
+	var warnings []string
+	if sample.NodePeers < thresholds.MinNodePeers {
+		warnings = append(warnings, fmt.Sprintf("op-node has %d peers, below minimum of %d", sample.NodePeers, thresholds.MinNodePeers))
+	}
+	if sample.ELPeers < thresholds.MinELPeers {
+		warnings = append(warnings, fmt.Sprintf("execution client has %d peers, below minimum of %d", sample.ELPeers, thresholds.MinELPeers))
+	}
+	if sample.ScoredPeers > 0 && sample.LowScore*2 > sample.ScoredPeers {
+		warnings = append(warnings, fmt.Sprintf("%d of %d op-node peers have a gossip score below %.2f", sample.LowScore, sample.ScoredPeers, thresholds.MinScore))
+	}
+	return warnings
+}
+
+func dumpPeers(ctx context.Context, node *rpcClient, el *rpcClient, includeEL bool) error {
+	dump := map[string]json.RawMessage{}
+
+	var nodePeers json.RawMessage
+	if err := node.call(ctx, &nodePeers, "opp2p_peers", true); err != nil {
+		return err
+	}
+	dump["node"] = nodePeers
+
+	var nodeStats json.RawMessage
+	if err := node.call(ctx, &nodeStats, "opp2p_peerStats"); err != nil {
+		return err
+	}
+	dump["nodeStats"] = nodeStats
+
+	if includeEL {
+		var elPeers json.RawMessage
+		if err := el.call(ctx, &elPeers, "admin_peers"); err != nil {
+			return err
+		}
+		dump["execution"] = elPeers
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dump)
+}
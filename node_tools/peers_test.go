@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEvaluatePeerHealth(t *testing.T) {
+	thresholds := peerThresholds{MinNodePeers: 10, MinELPeers: 10, MinScore: 0}
+
+	tests := []struct {
+		name         string
+		sample       peerSample
+		wantWarnings int
+	}{
+		{"healthy", peerSample{NodePeers: 40, ELPeers: 50, ScoredPeers: 40, LowScore: 2}, 0},
+		{"low node peers", peerSample{NodePeers: 3, ELPeers: 50, ScoredPeers: 3}, 1},
+		{"low el peers", peerSample{NodePeers: 40, ELPeers: 0, ScoredPeers: 40}, 1},
+		{"mostly low scores", peerSample{NodePeers: 40, ELPeers: 50, ScoredPeers: 40, LowScore: 30}, 1},
+		{"everything unhealthy", peerSample{NodePeers: 4, ELPeers: 1, ScoredPeers: 4, LowScore: 4}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := evaluatePeerHealth(tt.sample, thresholds)
+			if len(got) != tt.wantWarnings {
+				t.Errorf("evaluatePeerHealth(%+v) = %v, want %d warnings", tt.sample, got, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// minRethPruneDistance is the smallest prune distance reth accepts for an op-stack node,
+// see the PRUNING section of the env files.
+const minRethPruneDistance = 10064
+
+var rethPruneDistancePattern = regexp.MustCompile(`--prune\.([a-z]+)\.distance=(\S+)`)
+
+type pruneWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+type pruneOptions struct {
+	RepoPath  string
+	EnvFile   string
+	Client    string
+	NodeRPC   string
+	ELRPC     string
+	MaxBehind uint64
+}
+
+type pruneState struct {
+	LastPrune map[string]time.Time `json:"lastPrune"`
+}
+
+func pruneCommand() *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "repo",
+			Usage: "Path to the node repository containing docker-compose.yml",
+			Value: ".",
+		},
+		&cli.StringFlag{
+			Name:    "env-file",
+			Usage:   "Network env file, relative to the repo",
+			Sources: cli.EnvVars("NETWORK_ENV"),
+			Value:   ".env.mainnet",
+		},
+		&cli.StringFlag{
+			Name:    "client",
+			Usage:   "Execution client (geth, reth, nethermind)",
+			Sources: cli.EnvVars("CLIENT"),
+			Value:   "geth",
+		},
+		&cli.StringFlag{
+			Name:  "node-rpc",
+			Usage: "op-node RPC endpoint used for the health check",
+			Value: defaultNodeRPC,
+		},
+		&cli.StringFlag{
+			Name:  "el-rpc",
+			Usage: "Execution client RPC endpoint",
+			Value: defaultELRPC,
+		},
+		&cli.Uint64Flag{
+			Name:  "max-behind",
+			Usage: "Refuse to prune when the node is more than this many blocks behind the head",
+			Value: 300,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "prune",
+		Usage: "Runs client-appropriate pruning, gated on the node being close to the chain head",
+		Commands: []*cli.Command{
+			{
+				Name:  "run",
+				Usage: "Prunes now if the node is healthy",
+				Flags: flags,
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return pruneNode(ctx, pruneOptionsFromCommand(cmd))
+				},
+			},
+			{
+				Name:  "schedule",
+				Usage: "Waits for the configured low-traffic window and prunes at most once per interval",
+				Flags: append(flags,
+					&cli.StringFlag{
+						Name:     "window",
+						Usage:    "Daily UTC window in which pruning may run, e.g. 02:00-05:00",
+						Required: true,
+					},
+					&cli.DurationFlag{
+						Name:  "min-interval",
+						Usage: "Minimum time between prunes",
+						Value: 7 * 24 * time.Hour,
+					},
+				),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					window, err := parsePruneWindow(cmd.String("window"))
+					if err != nil {
+						return err
+					}
+					return schedulePrune(ctx, pruneOptionsFromCommand(cmd), window, cmd.Duration("min-interval"))
+				},
+			},
+		},
+	}
+}
+
+func pruneOptionsFromCommand(cmd *cli.Command) pruneOptions {
+	return pruneOptions{
+		RepoPath:  cmd.String("repo"),
+		EnvFile:   cmd.String("env-file"),
+		Client:    cmd.String("client"),
+		NodeRPC:   cmd.String("node-rpc"),
+		ELRPC:     cmd.String("el-rpc"),
+		MaxBehind: cmd.Uint64("max-behind"),
+	}
+}
+
+func schedulePrune(ctx context.Context, opts pruneOptions, window pruneWindow, minInterval time.Duration) error {
+	statePath := filepath.Join(opts.RepoPath, stateDir, "prune-state.json")
+	for {
+		now := time.Now().UTC()
+		state, err := readPruneState(statePath)
+		if err != nil {
+			return err
+		}
+
+		if window.contains(now) && now.Sub(state.LastPrune[opts.Client]) >= minInterval {
+			if err := pruneNode(ctx, opts); err != nil {
+				log.Printf("WARN pruning skipped or failed: %s", err)
+			} else {
+				state.LastPrune[opts.Client] = now
+				if err := writePruneState(statePath, state); err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
+func pruneNode(ctx context.Context, opts pruneOptions) error {
+	if opts.Client == "reth" {
+		// reth prunes continuously based on its startup flags, so only the config can be checked.
+		return checkRethPruneConfig(filepath.Join(opts.RepoPath, opts.EnvFile))
+	}
+
+	behind, err := headLag(ctx, newRPCClient(opts.NodeRPC))
+	if err != nil {
+		return fmt.Errorf("health check failed: %s", err)
+	}
+	if behind > opts.MaxBehind {
+		return fmt.Errorf("node is %d blocks behind the head (max %d), not pruning", behind, opts.MaxBehind)
+	}
+
+	switch opts.Client {
+	case "geth":
+		return pruneGeth(ctx, opts)
+	case "nethermind":
+		var result any
+		if err := newRPCClient(opts.ELRPC).call(ctx, &result, "admin_prune"); err != nil {
+			return fmt.Errorf("error starting nethermind full pruning (requires the admin RPC module): %s", err)
+		}
+		log.Printf("Nethermind full pruning started: %v", result)
+		return nil
+	default:
+		return fmt.Errorf("pruning is not supported for client %q", opts.Client)
+	}
+}
+
+// gethStateSchemeProbe prints the state scheme of the running geth's database, path scheme
+// databases keep their state history in an ancient/state freezer.
+const gethStateSchemeProbe = `if [ -d "${GETH_DATA_DIR:-/data}/geth/chaindata/ancient/state" ]; then echo path; else echo hash; fi`
+
+// pruneGeth stops the execution container, runs an offline state prune and starts it again.
+// Offline pruning only applies to the hash state scheme, path scheme databases prune themselves,
+// so the scheme is checked first and geth keeps running if there is nothing to prune.
+func pruneGeth(ctx context.Context, opts pruneOptions) error {
+	scheme, err := gethStateScheme(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if scheme != "hash" {
+		log.Printf("geth uses the %s state scheme, which prunes itself, skipping offline pruning", scheme)
+		return nil
+	}
+
+	log.Printf("Stopping execution client for offline state pruning")
+	if err := runCompose(ctx, opts.RepoPath, opts.EnvFile, opts.Client, "stop", "execution"); err != nil {
+		return err
+	}
+
+	pruneErr := runCompose(ctx, opts.RepoPath, opts.EnvFile, opts.Client,
+		"run", "--rm", "--no-deps", "execution", "./geth", "snapshot", "prune-state", "--datadir=/data")
+
+	log.Printf("Starting execution client")
+	if err := runCompose(ctx, opts.RepoPath, opts.EnvFile, opts.Client, "start", "execution"); err != nil {
+		return err
+	}
+	return pruneErr
+}
+
+// gethStateScheme returns OP_GETH_STATE_SCHEME from the env file, or inspects the database of
+// the running execution container if it is not set.
+func gethStateScheme(ctx context.Context, opts pruneOptions) (string, error) {
+	env, err := readEnvFile(filepath.Join(opts.RepoPath, opts.EnvFile))
+	if err != nil {
+		return "", err
+	}
+	if scheme := env["OP_GETH_STATE_SCHEME"]; scheme != "" {
+		return scheme, nil
+	}
+	out, err := composeOutput(ctx, opts.RepoPath, opts.EnvFile, opts.Client, "exec", "-T", "execution", "sh", "-c", gethStateSchemeProbe)
+	if err != nil {
+		return "", fmt.Errorf("error detecting the geth state scheme, set OP_GETH_STATE_SCHEME: %s", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func checkRethPruneConfig(envPath string) error {
+	env, err := readEnvFile(envPath)
+	if err != nil {
+		return err
+	}
+	args, ok := env["RETH_PRUNING_ARGS"]
+	if !ok || args == "" {
+		log.Printf("RETH_PRUNING_ARGS is not set, reth runs as an archive node and does not prune")
+		return nil
+	}
+
+	problems := checkRethPruneArgs(args)
+	for _, p := range problems {
+		log.Printf("WARN %s", p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("RETH_PRUNING_ARGS in %s has %d problem(s)", envPath, len(problems))
+	}
+	log.Printf("RETH_PRUNING_ARGS is valid, reth prunes continuously")
+	return nil
+}
+
+func checkRethPruneArgs(args string) []string {
+	var problems []string
+	matches := rethPruneDistancePattern.FindAllStringSubmatch(args, -1)
+	if len(matches) == 0 {
+		problems = append(problems, "no --prune.<segment>.distance arguments found")
+	}
+	for _, m := range matches {
+		distance, err := strconv.ParseUint(strings.ReplaceAll(m[2], "_", ""), 10, 64)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("--prune.%s.distance has invalid value %q", m[1], m[2]))
+			continue
+		}
+		if distance < minRethPruneDistance {
+			problems = append(problems, fmt.Sprintf("--prune.%s.distance=%d is below the minimum of %d", m[1], distance, minRethPruneDistance))
+		}
+	}
+	return problems
+}
+
+// parsePruneWindow parses "HH:MM-HH:MM", windows may wrap around midnight.
+func parsePruneWindow(s string) (pruneWindow, error) {
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return pruneWindow{}, fmt.Errorf("invalid window %q, expected HH:MM-HH:MM", s)
+	}
+	startTime, err := time.Parse("15:04", strings.TrimSpace(start))
+	if err != nil {
+		return pruneWindow{}, fmt.Errorf("invalid window start %q: %s", start, err)
+	}
+	endTime, err := time.Parse("15:04", strings.TrimSpace(end))
+	if err != nil {
+		return pruneWindow{}, fmt.Errorf("invalid window end %q: %s", end, err)
+	}
+	return pruneWindow{
+		Start: time.Duration(startTime.Hour())*time.Hour + time.Duration(startTime.Minute())*time.Minute,
+		End:   time.Duration(endTime.Hour())*time.Hour + time.Duration(endTime.Minute())*time.Minute,
+	}, nil
+}
+
+func (w pruneWindow) contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+func readPruneState(path string) (pruneState, error) {
+	state := pruneState{LastPrune: map[string]time.Time{}}
+	f, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("error reading prune state: %s", err)
+	}
+	if err := json.Unmarshal(f, &state); err != nil {
+		return state, fmt.Errorf("error unmarshalling prune state: %s", err)
+	}
+	if state.LastPrune == nil {
+		state.LastPrune = map[string]time.Time{}
+	}
+	return state, nil
+}
+
+func writePruneState(path string, state pruneState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating %s: %s", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling prune state: %s", err)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneWindowContains(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		window string
+		at     time.Duration
+		want   bool
+	}{
+		{"02:00-05:00", 3 * time.Hour, true},
+		{"02:00-05:00", 2 * time.Hour, true},
+		{"02:00-05:00", 5 * time.Hour, false},
+		{"02:00-05:00", 12 * time.Hour, false},
+		{"23:00-01:30", 23*time.Hour + 30*time.Minute, true},
+		{"23:00-01:30", time.Hour, true},
+		{"23:00-01:30", 2 * time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window+" "+tt.at.String(), func(t *testing.T) {
+			w, err := parsePruneWindow(tt.window)
+			if err != nil {
+				t.Fatalf("parsePruneWindow(%q) unexpected error: %v", tt.window, err)
+			}
+			if got := w.contains(day.Add(tt.at)); got != tt.want {
+				t.Errorf("window %q contains %s = %v, want %v", tt.window, tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePruneWindowInvalid(t *testing.T) {
+	for _, s := range []string{"", "02:00", "2am-5am", "02:00-25:00"} {
+		if _, err := parsePruneWindow(s); err == nil {
+			t.Errorf("parsePruneWindow(%q) expected error", s)
+		}
+	}
+}
+
+func TestCheckRethPruneArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         string
+		wantProblems int
+	}{
+		{"env file example", "--prune.senderrecovery.distance=50000 --prune.transactionlookup.distance=50000 --prune.receipts.distance=50000", 0},
+		{"underscored distance", "--prune.receipts.distance=1_339_200", 0},
+		{"too small", "--prune.receipts.distance=5000 --prune.bodies.distance=50000", 1},
+		{"invalid value", "--prune.receipts.distance=lots", 1},
+		{"no distances", "--prune.receipts.full", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkRethPruneArgs(tt.args)
+			if len(got) != tt.wantProblems {
+				t.Errorf("checkRethPruneArgs(%q) = %v, want %d problems", tt.args, got, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestPruneGethPathScheme(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, ".env.mainnet"), []byte("OP_GETH_STATE_SCHEME=path\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Reaching docker compose would fail here, so a nil error means geth was never stopped.
+	opts := pruneOptions{RepoPath: repo, EnvFile: ".env.mainnet", Client: "geth"}
+	if err := pruneGeth(context.Background(), opts); err != nil {
+		t.Errorf("pruneGeth() with the path scheme unexpected error: %v", err)
+	}
+}
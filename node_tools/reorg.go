@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// reorgWindow is how many recent block hashes the detector keeps, deeper reorgs are reported at this depth.
+const reorgWindow = 256
+
+type reorgEvent struct {
+	Time     time.Time
+	Depth    uint64
+	Ancestor uint64
+	OldHead  BlockRef
+	NewHead  BlockRef
+}
+
+// hashFetcher returns the canonical hash at a block number.
+type hashFetcher func(ctx context.Context, number uint64) (string, error)
+
+// reorgDetector tracks the hashes of the blocks below the unsafe head so a changed
+// hash can be walked back to the common ancestor.
+type reorgDetector struct {
+	hashes map[uint64]string
+	head   BlockRef
+	recent []time.Time
+}
+
+func reorgsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "reorgs",
+		Usage: "Watches the unsafe head for reorgs and alerts on deep or frequent ones",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint",
+				Value: defaultNodeRPC,
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint",
+				Value: defaultELRPC,
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "Time between samples",
+				Value: 4 * time.Second,
+			},
+			&cli.Uint64Flag{
+				Name:  "max-depth",
+				Usage: "Alert on reorgs replacing more than this many blocks",
+				Value: 2,
+			},
+			&cli.IntFlag{
+				Name:  "max-per-hour",
+				Usage: "Alert when more reorgs than this happen within an hour",
+				Value: 3,
+			},
+			webhookFlag,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			el := newRPCClient(cmd.String("el-rpc"))
+			fetch := func(ctx context.Context, number uint64) (string, error) {
+				block, err := el.blockByNumber(ctx, formatQuantity(number))
+				if err != nil {
+					return "", err
+				}
+				return block.Hash, nil
+			}
+			return monitorReorgs(ctx, newRPCClient(cmd.String("node-rpc")), fetch, newAlerter(cmd.String("webhook")),
+				cmd.Duration("interval"), cmd.Uint64("max-depth"), int(cmd.Int("max-per-hour")))
+		},
+	}
+}
+
+func monitorReorgs(ctx context.Context, node *rpcClient, fetch hashFetcher, alerts *alerter, interval time.Duration, maxDepth uint64, maxPerHour int) error {
+	detector := newReorgDetector()
+	for {
+		status, err := node.syncStatus(ctx)
+		if err != nil {
+			log.Printf("Error fetching sync status: %s", err)
+		} else if event, err := detector.update(ctx, status.UnsafeL2, fetch, time.Now()); err != nil {
+			log.Printf("Error tracking unsafe head: %s", err)
+		} else if event != nil {
+			msg := fmt.Sprintf("reorg of depth %d at %d: head %d %s replaced by %d %s",
+				event.Depth, event.Ancestor, event.OldHead.Number, event.OldHead.Hash, event.NewHead.Number, event.NewHead.Hash)
+			if event.Depth > maxDepth {
+				alerts.alert(ctx, msg)
+			} else {
+				log.Printf("%s", msg)
+			}
+			if n := detector.reorgsSince(time.Now().Add(-time.Hour)); n > maxPerHour {
+				alerts.alert(ctx, fmt.Sprintf("%d reorgs in the last hour, check the sequencer status and the node's peers", n))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func newReorgDetector() *reorgDetector {
+	return &reorgDetector{hashes: map[uint64]string{}}
+}
+
+// update records a new unsafe head, returning a reorgEvent if blocks the detector had
+// seen were replaced.
+func (d *reorgDetector) update(ctx context.Context, head BlockRef, fetch hashFetcher, now time.Time) (*reorgEvent, error) {
+	if len(d.hashes) == 0 {
+		d.record(head)
+		return nil, nil
+	}
+	if head.Hash == d.head.Hash {
+		return nil, nil
+	}
+
+	// Walk back from the lower of the two heads to the newest block whose hash is unchanged,
+	// its ancestors are unchanged too. Running out of stored hashes caps the depth at the window.
+	canonical := func(n uint64) (string, error) {
+		if n == head.Number {
+			return head.Hash, nil
+		}
+		return fetch(ctx, n)
+	}
+	ancestor := min(head.Number, d.head.Number)
+	for ancestor > 0 {
+		stored, ok := d.hashes[ancestor]
+		if !ok {
+			break
+		}
+		hash, err := canonical(ancestor)
+		if err != nil {
+			return nil, err
+		}
+		if hash == stored {
+			break
+		}
+		ancestor--
+	}
+
+	var event *reorgEvent
+	if ancestor < d.head.Number {
+		event = &reorgEvent{Time: now, Depth: d.head.Number - ancestor, Ancestor: ancestor, OldHead: d.head, NewHead: head}
+		d.recent = append(d.recent, now)
+	}
+
+	// Fill in the hashes between the ancestor and the new head.
+	for i := ancestor + 1; i < head.Number; i++ {
+		if head.Number-i > reorgWindow {
+			continue
+		}
+		hash, err := fetch(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		d.hashes[i] = hash
+	}
+	for i := head.Number + 1; i <= d.head.Number; i++ {
+		delete(d.hashes, i)
+	}
+	d.record(head)
+	return event, nil
+}
+
+func (d *reorgDetector) record(head BlockRef) {
+	d.hashes[head.Number] = head.Hash
+	d.head = head
+	for n := range d.hashes {
+		if n+reorgWindow < head.Number {
+			delete(d.hashes, n)
+		}
+	}
+}
+
+// reorgsSince counts the reorgs detected after t and drops older ones.
+func (d *reorgDetector) reorgsSince(t time.Time) int {
+	kept := d.recent[:0]
+	for _, r := range d.recent {
+		if r.After(t) {
+			kept = append(kept, r)
+		}
+	}
+	d.recent = kept
+	return len(kept)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeChain serves canonical hashes from a map that tests rewrite to simulate reorgs.
+type fakeChain map[uint64]string
+
+func (c fakeChain) fetch(ctx context.Context, number uint64) (string, error) {
+	hash, ok := c[number]
+	if !ok {
+		return "", fmt.Errorf("block %d not found", number)
+	}
+	return hash, nil
+}
+
+func (c fakeChain) extend(from uint64, to uint64, fork string) {
+	for n := from; n <= to; n++ {
+		c[n] = fmt.Sprintf("%s-%d", fork, n)
+	}
+}
+
+func (c fakeChain) head(n uint64) BlockRef {
+	return BlockRef{Number: n, Hash: c[n]}
+}
+
+func TestReorgDetector(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	chain := fakeChain{}
+	chain.extend(90, 100, "a")
+	d := newReorgDetector()
+
+	steps := []struct {
+		name      string
+		mutate    func()
+		head      uint64
+		wantDepth uint64
+	}{
+		{"initial head", func() {}, 100, 0},
+		{"advance", func() { chain.extend(101, 105, "a") }, 105, 0},
+		{"same head", func() {}, 105, 0},
+		{"reorg of the tip two blocks at a higher head", func() { chain.extend(104, 107, "b") }, 107, 2},
+		{"advance on the new fork", func() { chain.extend(108, 110, "b") }, 110, 0},
+		{"reorg at the same height", func() { chain.extend(108, 110, "c") }, 110, 3},
+		{"rewind", func() {}, 106, 4},
+	}
+	for _, step := range steps {
+		step.mutate()
+		event, err := d.update(ctx, chain.head(step.head), chain.fetch, now)
+		if err != nil {
+			t.Fatalf("%s: update() unexpected error: %v", step.name, err)
+		}
+		var depth uint64
+		if event != nil {
+			depth = event.Depth
+		}
+		if depth != step.wantDepth {
+			t.Errorf("%s: reorg depth = %d, want %d", step.name, depth, step.wantDepth)
+		}
+	}
+
+	if got := d.reorgsSince(now.Add(-time.Minute)); got != 3 {
+		t.Errorf("reorgsSince() = %d, want 3", got)
+	}
+	if got := d.reorgsSince(now); got != 0 {
+		t.Errorf("reorgsSince(now) = %d, want 0", got)
+	}
+}
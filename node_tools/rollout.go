@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// rolloutState records which copy of the node is live. An empty Active means the
+// repo's own docker-compose.yml project, which is what a node starts out as.
+type rolloutState struct {
+	Active  string    `json:"active"`
+	DataDir string    `json:"dataDir"`
+	Time    time.Time `json:"time"`
+}
+
+type rolloutOptions struct {
+	RepoPath   string
+	Network    Network
+	Client     string
+	DataDir    string
+	PortOffset int
+	MaxLag     uint64
+	Timeout    time.Duration
+	EL         *rpcClient
+}
+
+func rolloutCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "rollout",
+		Usage: "Blue/green upgrade: starts versions.json alongside the running node on alternate ports, waits for it to reach head, then moves it onto the standard ports and retires the old containers",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:  "network",
+				Usage: "Network the node runs on (" + strings.Join(networkNames(), ", ") + ")",
+				Value: "mainnet",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client (geth, reth, nethermind)",
+				Sources: cli.EnvVars("CLIENT"),
+				Value:   "geth",
+			},
+			&cli.StringFlag{
+				Name:     "data-dir",
+				Usage:    "Data directory for the new copy, seed it from a snapshot or a copy of the current data directory",
+				Required: true,
+			},
+			&cli.IntFlag{
+				Name:  "port-offset",
+				Usage: "Added to every host port while the new copy syncs alongside the old one",
+				Value: 10000,
+			},
+			&cli.Uint64Flag{
+				Name:  "max-lag",
+				Usage: "Switch once the new copy is within this many blocks of the running node",
+				Value: 5,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "How long the new copy has to catch up",
+				Value: 6 * time.Hour,
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint of the running node",
+				Value: defaultELRPC,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			network, err := lookupNetwork(cmd.String("network"))
+			if err != nil {
+				return err
+			}
+			dataDir, err := filepath.Abs(cmd.String("data-dir"))
+			if err != nil {
+				return err
+			}
+			return runRollout(ctx, rolloutOptions{
+				RepoPath:   cmd.String("repo"),
+				Network:    network,
+				Client:     cmd.String("client"),
+				DataDir:    dataDir,
+				PortOffset: int(cmd.Int("port-offset")),
+				MaxLag:     cmd.Uint64("max-lag"),
+				Timeout:    cmd.Duration("timeout"),
+				EL:         newRPCClient(cmd.String("el-rpc")),
+			})
+		},
+	}
+}
+
+func runRollout(ctx context.Context, opts rolloutOptions) error {
+	statePath := filepath.Join(opts.RepoPath, stateDir, "rollout.json")
+	state, err := readRolloutState(statePath)
+	if err != nil {
+		return err
+	}
+	live := state.DataDir
+	if state.Active == "" {
+		if live, err = composeDataDir(opts.RepoPath, opts.Client); err != nil {
+			return err
+		}
+	}
+	if live != "" && sharesDir(live, opts.DataDir) {
+		return fmt.Errorf("%s overlaps %s, the data directory of the running node, the new copy needs its own", opts.DataDir, live)
+	}
+	if err := checkSupported(opts.RepoPath, "", opts.Network.Name, opts.Client); err != nil {
+		return err
+	}
+
+	next := nextRolloutColor(state.Active)
+	oldArgs, newArgs := rolloutProjectArgs(state.Active), rolloutProjectArgs(next)
+	composePath := filepath.Join(opts.RepoPath, "docker-compose."+next+".yml")
+	envFile := opts.Network.EnvFile
+
+	setup, err := newComposeSetup(opts.RepoPath, opts.Network, opts.Client)
+	if err != nil {
+		return err
+	}
+	setup.Header = "# Generated by node-tools rollout from versions.json for the " + next + " copy, do not edit."
+	setup.DataDir = opts.DataDir
+	setup.PortOffset = opts.PortOffset
+	if err := writeComposeFile(composePath, setup); err != nil {
+		return err
+	}
+
+	log.Printf("Starting %s (version set %s) on ports offset by %d", next, setup.Digest, opts.PortOffset)
+	if err := runCompose(ctx, opts.RepoPath, envFile, opts.Client, append(newArgs, "up", "-d", "--build")...); err != nil {
+		return err
+	}
+	candidate := newRPCClient(fmt.Sprintf("http://localhost:%d", setup.HostPort(8545)))
+	if err := waitForCatchUp(ctx, opts.EL, candidate, opts.MaxLag, opts.Timeout); err != nil {
+		return fmt.Errorf("%s did not catch up, the running node was left untouched (stop it with docker compose %v down): %s", next, newArgs, err)
+	}
+
+	// Ports can't be shared, so the old copy stops before the new one is recreated on the standard ports.
+	log.Printf("%s caught up, switching ports", next)
+	if err := runCompose(ctx, opts.RepoPath, envFile, opts.Client, append(oldArgs, "stop")...); err != nil {
+		return err
+	}
+	setup.PortOffset = 0
+	if err := writeComposeFile(composePath, setup); err != nil {
+		return err
+	}
+	check := upgradeCheck{
+		RepoPath: opts.RepoPath,
+		Client:   opts.Client,
+		Node:     newRPCClient(defaultNodeRPC),
+		EL:       newRPCClient(defaultELRPC),
+		Timeout:  10 * time.Minute,
+	}
+	err = runCompose(ctx, opts.RepoPath, envFile, opts.Client, append(newArgs, "up", "-d", "--force-recreate")...)
+	if err == nil {
+		err = waitForService(ctx, check, "execution")
+	}
+	if err == nil {
+		err = waitForService(ctx, check, "node")
+	}
+	if err != nil {
+		log.Printf("%s failed on the standard ports, restarting the old containers: %s", next, err)
+		_ = runCompose(ctx, opts.RepoPath, envFile, opts.Client, append(newArgs, "stop")...)
+		if startErr := runCompose(ctx, opts.RepoPath, envFile, opts.Client, append(oldArgs, "start")...); startErr != nil {
+			return fmt.Errorf("switch failed (%s) and the old containers did not restart: %s", err, startErr)
+		}
+		return fmt.Errorf("switch failed, the old containers are running again: %s", err)
+	}
+
+	if err := runCompose(ctx, opts.RepoPath, envFile, opts.Client, append(oldArgs, "down")...); err != nil {
+		log.Printf("Could not remove the old containers: %s", err)
+	}
+	if err := writeRolloutState(statePath, rolloutState{Active: next, DataDir: opts.DataDir, Time: time.Now().UTC()}); err != nil {
+		return err
+	}
+	retired := state.DataDir
+	if retired == "" {
+		retired = "the HOST_DATA_DIR of docker-compose.yml"
+	}
+	log.Printf("Rolled out %s, it now serves the standard ports. The old data directory (%s) can be removed once you're happy.", next, retired)
+	return nil
+}
+
+// composeDataDir is the absolute HOST_DATA_DIR the repo's docker-compose.yml project runs
+// with, set in the environment or .env, with the same ${VAR} and ${VAR:-default}
+// substitutions docker compose applies and relative to the repo.
+func composeDataDir(repoPath string, client string) (string, error) {
+	dotEnv, err := readEnvFile(filepath.Join(repoPath, ".env"))
+	if err != nil {
+		return "", err
+	}
+	lookup := func(name string) string {
+		if name == "CLIENT" && client != "" {
+			return client
+		}
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		}
+		return dotEnv[name]
+	}
+	dataDir := lookup("HOST_DATA_DIR")
+	if dataDir == "" {
+		dataDir = "./" + client + "-data"
+	}
+	dataDir = os.Expand(dataDir, func(name string) string {
+		name, def, _ := strings.Cut(name, ":-")
+		if v := lookup(name); v != "" {
+			return v
+		}
+		return def
+	})
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(repoPath, dataDir)
+	}
+	return filepath.Abs(dataDir)
+}
+
+// sharesDir reports whether a and b are the same directory or one is nested in the other.
+func sharesDir(a string, b string) bool {
+	for _, pair := range [][2]string{{a, b}, {b, a}} {
+		rel, err := filepath.Rel(pair[0], pair[1])
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForCatchUp waits until the candidate's head is within maxLag blocks of the live node.
+func waitForCatchUp(ctx context.Context, live *rpcClient, candidate *rpcClient, maxLag uint64, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		target, err := live.blockNumber(ctx)
+		if err != nil {
+			log.Printf("Could not read the running node's head: %s", err)
+		}
+		head, candidateErr := candidate.blockNumber(ctx)
+		switch {
+		case err == nil && candidateErr == nil && head+maxLag >= target:
+			return nil
+		case err == nil && candidateErr == nil:
+			log.Printf("New copy at %d, running node at %d (%d behind)", head, target, target-head)
+		case candidateErr != nil:
+			log.Printf("New copy not ready: %s", candidateErr)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+func nextRolloutColor(active string) string {
+	if active == "green" {
+		return "blue"
+	}
+	return "green"
+}
+
+// rolloutProjectArgs selects the compose project for a color, the default project has none.
+func rolloutProjectArgs(color string) []string {
+	if color == "" {
+		return nil
+	}
+	return []string{"-p", "base-" + color, "-f", "docker-compose." + color + ".yml"}
+}
+
+func readRolloutState(path string) (rolloutState, error) {
+	var state rolloutState
+	f, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("error reading rollout state: %s", err)
+	}
+	if err := json.Unmarshal(f, &state); err != nil {
+		return state, fmt.Errorf("error unmarshalling rollout state: %s", err)
+	}
+	return state, nil
+}
+
+func writeRolloutState(path string, state rolloutState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating %s: %s", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling rollout state: %s", err)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRolloutColors(t *testing.T) {
+	tests := []struct {
+		active string
+		next   string
+	}{
+		{"", "green"},
+		{"green", "blue"},
+		{"blue", "green"},
+	}
+	for _, tt := range tests {
+		if got := nextRolloutColor(tt.active); got != tt.next {
+			t.Errorf("nextRolloutColor(%q) = %q, want %q", tt.active, got, tt.next)
+		}
+	}
+
+	if args := rolloutProjectArgs(""); args != nil {
+		t.Errorf("rolloutProjectArgs(\"\") = %v, want the default project", args)
+	}
+	want := []string{"-p", "base-green", "-f", "docker-compose.green.yml"}
+	if args := rolloutProjectArgs("green"); !slices.Equal(args, want) {
+		t.Errorf("rolloutProjectArgs(green) = %v, want %v", args, want)
+	}
+}
+
+func TestWaitForCatchUp(t *testing.T) {
+	blockNumber := func(n uint64) map[string]func([]json.RawMessage) any {
+		return map[string]func([]json.RawMessage) any{
+			"eth_blockNumber": func([]json.RawMessage) any { return formatQuantity(n) },
+		}
+	}
+	live := newFakeRPC(t, blockNumber(1000))
+
+	if err := waitForCatchUp(context.Background(), live, newFakeRPC(t, blockNumber(996)), 5, time.Second); err != nil {
+		t.Errorf("waitForCatchUp() within max lag unexpected error: %v", err)
+	}
+	if err := waitForCatchUp(context.Background(), live, newFakeRPC(t, blockNumber(900)), 5, 100*time.Millisecond); err == nil {
+		t.Errorf("waitForCatchUp() far behind expected a timeout")
+	}
+}
+
+func TestComposeDataDir(t *testing.T) {
+	repo := t.TempDir()
+	tests := []struct {
+		dotEnv string
+		want   string
+	}{
+		{"HOST_DATA_DIR=./${CLIENT}-data\n", filepath.Join(repo, "reth-data")},
+		{"HOST_DATA_DIR=${DATA_ROOT:-/srv}/base\n", "/srv/base"},
+		{"CLIENT=geth\n", filepath.Join(repo, "reth-data")},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(filepath.Join(repo, ".env"), []byte(tt.dotEnv), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := composeDataDir(repo, "reth")
+		if err != nil || got != tt.want {
+			t.Errorf("composeDataDir(%q) = %s, %v, want %s", tt.dotEnv, got, err, tt.want)
+		}
+	}
+}
+
+// The first rollout has no state, the running node's data directory is the one of .env.
+func TestRolloutRefusesLiveDataDir(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, ".env"), []byte("HOST_DATA_DIR=./geth-data\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dataDir := range []string{filepath.Join(repo, "geth-data"), filepath.Join(repo, "geth-data", "green"), repo} {
+		err := runRollout(context.Background(), rolloutOptions{RepoPath: repo, Client: "geth", DataDir: dataDir})
+		if err == nil || !strings.Contains(err.Error(), "data directory of the running node") {
+			t.Errorf("runRollout(%s) = %v, want it refused", dataDir, err)
+		}
+	}
+
+	if sharesDir(filepath.Join(repo, "geth-data"), filepath.Join(repo, "geth-data-green")) {
+		t.Error("sharesDir() of sibling directories = true")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultNodeRPC = "http://localhost:7545"
+	defaultELRPC   = "http://localhost:8545"
+)
+
+type rpcClient struct {
+	url    string
+	client *http.Client
+	nextID atomic.Uint64
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+func newRPCClient(url string) *rpcClient {
+	return &rpcClient{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// call invokes method with params and decodes the result into result, which may be nil.
+func (c *rpcClient) call(ctx context.Context, result any, method string, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: c.nextID.Add(1), Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("error encoding %s request: %s", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating %s request: %s", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s on %s: %s", method, c.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error calling %s on %s: unexpected status %s", method, c.url, resp.Status)
+	}
+
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("error decoding %s response: %s", method, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("error calling %s on %s: %w", method, c.url, rpcResp.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("error decoding %s result: %s", method, err)
+	}
+	return nil
+}
+
+// blockNumber returns the result of eth_blockNumber.
+func (c *rpcClient) blockNumber(ctx context.Context) (uint64, error) {
+	var hex string
+	if err := c.call(ctx, &hex, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return parseQuantity(hex)
+}
+
+// Block holds the subset of eth_getBlockByNumber fields the tools use.
+type Block struct {
+	Number     string `json:"number"`
+	Hash       string `json:"hash"`
+	ParentHash string `json:"parentHash"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// blockByNumber fetches a block header by number or tag ("latest", "safe", "finalized").
+func (c *rpcClient) blockByNumber(ctx context.Context, number string) (*Block, error) {
+	var block *Block
+	if err := c.call(ctx, &block, "eth_getBlockByNumber", number, false); err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %s not found on %s", number, c.url)
+	}
+	return block, nil
+}
+
+// BlockRef mirrors op-node's L1BlockRef/L2BlockRef JSON encoding.
+type BlockRef struct {
+	Hash       string `json:"hash"`
+	Number     uint64 `json:"number"`
+	ParentHash string `json:"parentHash"`
+	Timestamp  uint64 `json:"timestamp"`
+}
+
+// SyncStatus is the subset of op-node's optimism_syncStatus result the tools use.
+type SyncStatus struct {
+	CurrentL1   BlockRef `json:"current_l1"`
+	HeadL1      BlockRef `json:"head_l1"`
+	SafeL1      BlockRef `json:"safe_l1"`
+	FinalizedL1 BlockRef `json:"finalized_l1"`
+	UnsafeL2    BlockRef `json:"unsafe_l2"`
+	SafeL2      BlockRef `json:"safe_l2"`
+	FinalizedL2 BlockRef `json:"finalized_l2"`
+}
+
+func (c *rpcClient) syncStatus(ctx context.Context) (*SyncStatus, error) {
+	var status SyncStatus
+	if err := c.call(ctx, &status, "optimism_syncStatus"); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func parseQuantity(hex string) (uint64, error) {
+	if !strings.HasPrefix(hex, "0x") {
+		return 0, fmt.Errorf("invalid hex quantity %q", hex)
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(hex, "0x"), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hex quantity %q: %s", hex, err)
+	}
+	return n, nil
+}
+
+func formatQuantity(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newFakeRPC serves JSON-RPC requests from handlers keyed by method name.
+func newFakeRPC(t *testing.T, handlers map[string]func(params []json.RawMessage) any) *rpcClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		if handler, ok := handlers[req.Method]; ok {
+			resp["result"] = handler(req.Params)
+		} else {
+			resp["error"] = map[string]any{"code": -32601, "message": "method not found"}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return newRPCClient(server.URL)
+}
+
+func TestRPCClientCall(t *testing.T) {
+	client := newFakeRPC(t, map[string]func([]json.RawMessage) any{
+		"eth_blockNumber": func([]json.RawMessage) any { return "0x1a" },
+	})
+
+	n, err := client.blockNumber(t.Context())
+	if err != nil || n != 26 {
+		t.Errorf("blockNumber() = %d, %v, want 26", n, err)
+	}
+
+	var result string
+	if err := client.call(t.Context(), &result, "eth_unknown"); err == nil {
+		t.Errorf("call(eth_unknown) expected error")
+	}
+}
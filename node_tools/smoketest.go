@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// l1BlockPredeploy is the L1Block predeploy present on every OP stack chain.
+const l1BlockPredeploy = "0x4200000000000000000000000000000000000015"
+
+// l1BlockNumberSelector is the selector of L1Block.number().
+const l1BlockNumberSelector = "0x8381f58a"
+
+type smokeCheck struct {
+	Name string
+	// Skip is set when the check does not apply to the configured node.
+	Skip bool
+	Run  func(ctx context.Context) (string, error)
+}
+
+type smokeResult struct {
+	Name     string
+	Status   string
+	Detail   string
+	Duration time.Duration
+}
+
+func smoketestCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "smoketest",
+		Usage: "Runs a checklist of RPC calls against the local node and reports pass/fail",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint",
+				Value: defaultNodeRPC,
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint",
+				Value: defaultELRPC,
+			},
+			&cli.StringFlag{
+				Name:  "network",
+				Usage: "Network the node should be on (mainnet, sepolia), checks eth_chainId if set",
+			},
+			&cli.BoolFlag{
+				Name:  "debug",
+				Usage: "Expect the debug/trace API to be available (enabled by default in the geth entrypoint)",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var chainID uint64
+			if cmd.String("network") != "" {
+				network, err := lookupNetwork(cmd.String("network"))
+				if err != nil {
+					return err
+				}
+				chainID = network.ChainID
+			}
+			checks := smokeChecks(newRPCClient(cmd.String("el-rpc")), newRPCClient(cmd.String("node-rpc")), chainID, cmd.Bool("debug"))
+			results := runSmokeChecks(ctx, checks)
+			for _, r := range results {
+				fmt.Printf("%-4s %-28s %8s  %s\n", r.Status, r.Name, r.Duration.Round(time.Millisecond), r.Detail)
+			}
+			if failed := countSmokeFailures(results); failed > 0 {
+				return fmt.Errorf("%d of %d smoke checks failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+}
+
+func smokeChecks(el *rpcClient, node *rpcClient, chainID uint64, debug bool) []smokeCheck {
+	return []smokeCheck{
+		{
+			Name: "web3_clientVersion",
+			Run: func(ctx context.Context) (string, error) {
+				var version string
+				err := el.call(ctx, &version, "web3_clientVersion")
+				return version, err
+			},
+		},
+		{
+			Name: "eth_chainId",
+			Skip: chainID == 0,
+			Run: func(ctx context.Context) (string, error) {
+				var hex string
+				if err := el.call(ctx, &hex, "eth_chainId"); err != nil {
+					return "", err
+				}
+				got, err := parseQuantity(hex)
+				if err != nil {
+					return "", err
+				}
+				if got != chainID {
+					return "", fmt.Errorf("chain id %d, expected %d", got, chainID)
+				}
+				return fmt.Sprintf("%d", got), nil
+			},
+		},
+		{
+			Name: "eth_blockNumber",
+			Run: func(ctx context.Context) (string, error) {
+				n, err := el.blockNumber(ctx)
+				if err == nil && n == 0 {
+					err = fmt.Errorf("node is at genesis")
+				}
+				return fmt.Sprintf("%d", n), err
+			},
+		},
+		{
+			Name: "eth_getBlockByNumber",
+			Run: func(ctx context.Context) (string, error) {
+				block, err := el.blockByNumber(ctx, "latest")
+				if err != nil {
+					return "", err
+				}
+				if block.Hash == "" {
+					return "", fmt.Errorf("latest block has no hash")
+				}
+				return block.Hash, nil
+			},
+		},
+		{
+			Name: "eth_call L1Block.number()",
+			Run: func(ctx context.Context) (string, error) {
+				var result string
+				call := map[string]string{"to": l1BlockPredeploy, "data": l1BlockNumberSelector}
+				if err := el.call(ctx, &result, "eth_call", call, "latest"); err != nil {
+					return "", err
+				}
+				n, err := parseQuantity("0x" + strings.TrimLeft(strings.TrimPrefix(result, "0x"), "0"))
+				if err != nil || n == 0 {
+					return "", fmt.Errorf("unexpected L1 block number %q", result)
+				}
+				return fmt.Sprintf("l1 block %d", n), nil
+			},
+		},
+		{
+			Name: "optimism_syncStatus",
+			Run: func(ctx context.Context) (string, error) {
+				status, err := node.syncStatus(ctx)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("unsafe %d safe %d finalized %d", status.UnsafeL2.Number, status.SafeL2.Number, status.FinalizedL2.Number), nil
+			},
+		},
+		{
+			Name: "debug_traceBlockByNumber",
+			Skip: !debug,
+			Run: func(ctx context.Context) (string, error) {
+				n, err := el.blockNumber(ctx)
+				if err != nil {
+					return "", err
+				}
+				var traces []any
+				target := formatQuantity(n - min(n, 5))
+				if err := el.call(ctx, &traces, "debug_traceBlockByNumber", target, map[string]string{"tracer": "callTracer"}); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d traces in block %s", len(traces), target), nil
+			},
+		},
+	}
+}
+
+func runSmokeChecks(ctx context.Context, checks []smokeCheck) []smokeResult {
+	var results []smokeResult
+	for _, check := range checks {
+		if check.Skip {
+			results = append(results, smokeResult{Name: check.Name, Status: "SKIP"})
+			continue
+		}
+		start := time.Now()
+		detail, err := check.Run(ctx)
+		result := smokeResult{Name: check.Name, Status: "PASS", Detail: detail, Duration: time.Since(start)}
+		if err != nil {
+			result.Status, result.Detail = "FAIL", err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func countSmokeFailures(results []smokeResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Status == "FAIL" {
+			failed++
+		}
+	}
+	return failed
+}
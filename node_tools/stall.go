@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// stallEvent is a safe head that stopped advancing while the L1 head kept moving.
+type stallEvent struct {
+	Since    time.Time
+	Duration time.Duration
+	SafeL2   BlockRef
+	L1Start  BlockRef
+	L1Now    BlockRef
+	Start    *SyncStatus
+	Current  *SyncStatus
+}
+
+// stallDetector remembers when the safe head last advanced and the sync status at that time.
+type stallDetector struct {
+	maxStall    time.Duration
+	minL1Blocks uint64
+	since       time.Time
+	start       *SyncStatus
+	reported    bool
+}
+
+func stallCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "derivation-stall",
+		Usage: "Detects op-node derivation stalls and captures the logs and sync status for an incident report",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint",
+				Value: defaultNodeRPC,
+			},
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository, incidents are written to <repo>/.node_tools/incidents",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:    "env-file",
+				Usage:   "Network env file passed to docker compose",
+				Sources: cli.EnvVars("NETWORK_ENV"),
+				Value:   ".env.mainnet",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client passed to docker compose",
+				Sources: cli.EnvVars("CLIENT"),
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "Time between samples",
+				Value: 30 * time.Second,
+			},
+			&cli.DurationFlag{
+				Name:  "max-stall",
+				Usage: "Report a stall once the safe head has not advanced for this long",
+				Value: 10 * time.Minute,
+			},
+			&cli.Uint64Flag{
+				Name:  "min-l1-blocks",
+				Usage: "Only report a stall if the L1 head advanced by at least this many blocks meanwhile",
+				Value: 10,
+			},
+			webhookFlag,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			detector := &stallDetector{maxStall: cmd.Duration("max-stall"), minL1Blocks: cmd.Uint64("min-l1-blocks")}
+			return monitorStalls(ctx, newRPCClient(cmd.String("node-rpc")), detector, newAlerter(cmd.String("webhook")), cmd.Duration("interval"),
+				func(ctx context.Context, event *stallEvent) (string, error) {
+					return captureStallIncident(ctx, cmd.String("repo"), cmd.String("env-file"), cmd.String("client"), event)
+				})
+		},
+	}
+}
+
+func monitorStalls(ctx context.Context, node *rpcClient, detector *stallDetector, alerts *alerter, interval time.Duration, capture func(context.Context, *stallEvent) (string, error)) error {
+	for {
+		status, err := node.syncStatus(ctx)
+		if err != nil {
+			log.Printf("Error fetching sync status: %s", err)
+		} else if event, recovered := detector.update(status, time.Now()); event != nil {
+			msg := fmt.Sprintf("derivation stalled: safe head %d unchanged for %s while L1 advanced from %d to %d (derivation at L1 %d)",
+				event.SafeL2.Number, event.Duration.Round(time.Second), event.L1Start.Number, event.L1Now.Number, event.Current.CurrentL1.Number)
+			if dir, err := capture(ctx, event); err != nil {
+				log.Printf("Error capturing incident: %s", err)
+			} else {
+				msg += ", incident captured in " + dir
+			}
+			alerts.alert(ctx, msg)
+		} else if recovered {
+			log.Printf("Derivation recovered, safe head at %d", status.SafeL2.Number)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// update records a sync status. It returns a stallEvent the first time a stall crosses
+// the thresholds, and recovered when the safe head moves again after a reported stall.
+func (d *stallDetector) update(status *SyncStatus, now time.Time) (*stallEvent, bool) {
+	if d.start == nil || status.SafeL2.Number != d.start.SafeL2.Number || status.SafeL2.Hash != d.start.SafeL2.Hash {
+		recovered := d.reported
+		d.since, d.start, d.reported = now, status, false
+		return nil, recovered
+	}
+	if d.reported || now.Sub(d.since) < d.maxStall {
+		return nil, false
+	}
+	if status.HeadL1.Number < d.start.HeadL1.Number+d.minL1Blocks {
+		// L1 itself is not moving (or the L1 RPC is stale), so this is not a derivation problem.
+		return nil, false
+	}
+	d.reported = true
+	return &stallEvent{
+		Since:    d.since,
+		Duration: now.Sub(d.since),
+		SafeL2:   status.SafeL2,
+		L1Start:  d.start.HeadL1,
+		L1Now:    status.HeadL1,
+		Start:    d.start,
+		Current:  status,
+	}, false
+}
+
+// captureStallIncident writes the sync status at the start and at detection, and the op-node
+// logs since shortly before the stall, to a new incident directory.
+func captureStallIncident(ctx context.Context, repoPath string, envFile string, client string, event *stallEvent) (string, error) {
+	dir := filepath.Join(repoPath, stateDir, "incidents", event.Since.UTC().Format("20060102T150405Z")+"-derivation-stall")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating %s: %s", dir, err)
+	}
+	snapshot, err := json.MarshalIndent(map[string]any{
+		"since":    event.Since,
+		"duration": event.Duration.String(),
+		"start":    event.Start,
+		"current":  event.Current,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshaling sync status: %s", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "sync-status.json"), snapshot, 0644); err != nil {
+		return "", err
+	}
+
+	since := event.Since.Add(-5 * time.Minute).UTC().Format(time.RFC3339)
+	logs, err := composeOutput(ctx, repoPath, envFile, client, "logs", "--no-color", "--timestamps", "--since", since, "node")
+	if err != nil {
+		return dir, err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "node.log"), []byte(logs), 0644); err != nil {
+		return dir, err
+	}
+	return dir, nil
+}
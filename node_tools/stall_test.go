@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStallDetector(t *testing.T) {
+	status := func(safe uint64, l1 uint64) *SyncStatus {
+		return &SyncStatus{SafeL2: BlockRef{Number: safe, Hash: formatQuantity(safe)}, HeadL1: BlockRef{Number: l1}}
+	}
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		samples   []*SyncStatus
+		step      time.Duration
+		want      []bool
+		recovered bool
+	}{
+		{
+			name:    "safe head advancing",
+			samples: []*SyncStatus{status(100, 10), status(110, 11), status(120, 12)},
+			step:    10 * time.Minute,
+			want:    []bool{false, false, false},
+		},
+		{
+			name:    "stall reported once",
+			samples: []*SyncStatus{status(100, 10), status(100, 20), status(100, 30), status(100, 40)},
+			step:    6 * time.Minute,
+			want:    []bool{false, false, true, false},
+		},
+		{
+			name:    "l1 not moving",
+			samples: []*SyncStatus{status(100, 10), status(100, 12), status(100, 14)},
+			step:    10 * time.Minute,
+			want:    []bool{false, false, false},
+		},
+		{
+			name:      "recovery",
+			samples:   []*SyncStatus{status(100, 10), status(100, 30), status(101, 31)},
+			step:      15 * time.Minute,
+			want:      []bool{false, true, false},
+			recovered: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &stallDetector{maxStall: 10 * time.Minute, minL1Blocks: 10}
+			var recovered bool
+			for i, s := range tt.samples {
+				event, r := d.update(s, start.Add(time.Duration(i)*tt.step))
+				if (event != nil) != tt.want[i] {
+					t.Fatalf("sample %d: event = %+v, want event %v", i, event, tt.want[i])
+				}
+				recovered = recovered || r
+			}
+			if recovered != tt.recovered {
+				t.Errorf("recovered = %v, want %v", recovered, tt.recovered)
+			}
+		})
+	}
+}
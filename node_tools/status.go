@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// nodeStatus is the JSON document served by the status command. Each section is
+// collected independently, a section that failed is omitted and its error reported in Errors.
+type nodeStatus struct {
+	Time     time.Time         `json:"time"`
+	Healthy  bool              `json:"healthy"`
+	Sync     *statusSync       `json:"sync,omitempty"`
+	Peers    *statusPeers      `json:"peers,omitempty"`
+	Disk     *statusDisk       `json:"disk,omitempty"`
+	Versions []statusVersion   `json:"versions,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+type statusSync struct {
+	Unsafe    uint64 `json:"unsafe"`
+	Safe      uint64 `json:"safe"`
+	Finalized uint64 `json:"finalized"`
+	L1Head    uint64 `json:"l1Head"`
+	L1Current uint64 `json:"l1Current"`
+	HeadLag   uint64 `json:"headLag"`
+}
+
+type statusPeers struct {
+	Node     uint    `json:"node"`
+	EL       uint64  `json:"el"`
+	Banned   int     `json:"banned"`
+	AvgScore float64 `json:"avgScore"`
+}
+
+type statusDisk struct {
+	Path        string  `json:"path"`
+	TotalBytes  uint64  `json:"totalBytes"`
+	FreeBytes   uint64  `json:"freeBytes"`
+	UsedPercent float64 `json:"usedPercent"`
+}
+
+type statusVersion struct {
+	Component string `json:"component"`
+	Pinned    string `json:"pinned"`
+	Running   string `json:"running"`
+	Drift     string `json:"drift,omitempty"`
+}
+
+type statusSources struct {
+	RepoPath string
+	Client   string
+	DataDir  string
+	Node     *rpcClient
+	EL       *rpcClient
+	MaxLag   uint64
+}
+
+func statusCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "Aggregates sync state, peers, head lag, disk usage and versions into one JSON document",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client the node runs (geth, reth, nethermind)",
+				Sources: cli.EnvVars("CLIENT"),
+				Value:   "geth",
+			},
+			&cli.StringFlag{
+				Name:  "data-dir",
+				Usage: "Chain data directory, defaults to <repo>/<client>-data like docker-compose.yml",
+			},
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint",
+				Value: defaultNodeRPC,
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint",
+				Value: defaultELRPC,
+			},
+			&cli.Uint64Flag{
+				Name:  "max-lag",
+				Usage: "Blocks behind the estimated chain head at which the node is reported unhealthy",
+				Value: 30,
+			},
+			&cli.StringFlag{
+				Name:  "listen",
+				Usage: "Serve the status on this address (e.g. :7400) at /status instead of printing it once",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dataDir := cmd.String("data-dir")
+			if dataDir == "" {
+				dataDir = filepath.Join(cmd.String("repo"), cmd.String("client")+"-data")
+			}
+			sources := statusSources{
+				RepoPath: cmd.String("repo"),
+				Client:   cmd.String("client"),
+				DataDir:  dataDir,
+				Node:     newRPCClient(cmd.String("node-rpc")),
+				EL:       newRPCClient(cmd.String("el-rpc")),
+				MaxLag:   cmd.Uint64("max-lag"),
+			}
+
+			if cmd.String("listen") == "" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(collectStatus(ctx, sources))
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+				ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+				defer cancel()
+				status := collectStatus(ctx, sources)
+				w.Header().Set("Content-Type", "application/json")
+				if !status.Healthy {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+				json.NewEncoder(w).Encode(status)
+			})
+			server := &http.Server{Addr: cmd.String("listen"), Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			go func() {
+				<-ctx.Done()
+				server.Close()
+			}()
+			log.Printf("Serving node status on %s/status", cmd.String("listen"))
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func collectStatus(ctx context.Context, sources statusSources) *nodeStatus {
+	status := &nodeStatus{Time: time.Now().UTC(), Errors: map[string]string{}}
+
+	if sync, err := sources.Node.syncStatus(ctx); err != nil {
+		status.Errors["sync"] = err.Error()
+	} else {
+		status.Sync = &statusSync{
+			Unsafe:    sync.UnsafeL2.Number,
+			Safe:      sync.SafeL2.Number,
+			Finalized: sync.FinalizedL2.Number,
+			L1Head:    sync.HeadL1.Number,
+			L1Current: sync.CurrentL1.Number,
+			HeadLag:   estimateHead(sync.UnsafeL2, time.Now()) - sync.UnsafeL2.Number,
+		}
+	}
+
+	if sample, err := takePeerSample(ctx, sources.Node, sources.EL, 0); err != nil {
+		status.Errors["peers"] = err.Error()
+	} else {
+		status.Peers = &statusPeers{Node: sample.NodePeers, EL: sample.ELPeers, Banned: sample.Banned, AvgScore: sample.AvgScore}
+	}
+
+	if volume, err := statVolume(sources.DataDir); err != nil {
+		status.Errors["disk"] = err.Error()
+	} else {
+		disk := &statusDisk{Path: sources.DataDir, TotalBytes: volume.Total, FreeBytes: volume.Free}
+		if volume.Total > 0 {
+			disk.UsedPercent = float64(volume.Total-volume.Free) / float64(volume.Total) * 100
+		}
+		status.Disk = disk
+	}
+
+	if deployed, err := checkDeployedVersions(ctx, sources.RepoPath, sources.Client, sources.EL, sources.Node); err != nil {
+		status.Errors["versions"] = err.Error()
+	} else {
+		for _, d := range deployed {
+			status.Versions = append(status.Versions, statusVersion{Component: d.Component, Pinned: d.Pinned, Running: d.Reported, Drift: d.Drift})
+		}
+	}
+
+	status.Healthy = statusHealthy(status, sources.MaxLag)
+	if len(status.Errors) == 0 {
+		status.Errors = nil
+	}
+	return status
+}
+
+// statusHealthy requires every section to be collected, the head within maxLag and no version drift.
+func statusHealthy(status *nodeStatus, maxLag uint64) bool {
+	if len(status.Errors) > 0 || status.Sync == nil || status.Sync.HeadLag > maxLag {
+		return false
+	}
+	for _, v := range status.Versions {
+		if v.Drift != "" {
+			return false
+		}
+	}
+	return true
+}
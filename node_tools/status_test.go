@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCollectStatus(t *testing.T) {
+	repo := t.TempDir()
+	versions := `{
+		"op_geth": {"tag": "v1.101702.0", "commit": "d0734fd5f44234cde3b0a7c4beb1256fc6feedef", "tracking": "release"},
+		"op_node": {"tag": "op-node/v1.16.11", "tagPrefix": "op-node", "commit": "cba7aba0c98aae22720b21c3a023990a486cb6e0", "tracking": "release"}
+	}`
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte(versions), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := uint64(time.Now().Unix())
+	node := newFakeRPC(t, map[string]func([]json.RawMessage) any{
+		"optimism_syncStatus": func([]json.RawMessage) any {
+			return SyncStatus{
+				UnsafeL2:    BlockRef{Number: 1000, Timestamp: now},
+				SafeL2:      BlockRef{Number: 950},
+				FinalizedL2: BlockRef{Number: 900},
+				HeadL1:      BlockRef{Number: 500},
+			}
+		},
+		"opp2p_peers":      func([]json.RawMessage) any { return PeerDump{TotalConnected: 30} },
+		"optimism_version": func([]json.RawMessage) any { return "v1.16.11" },
+	})
+	el := newFakeRPC(t, map[string]func([]json.RawMessage) any{
+		"net_peerCount":      func([]json.RawMessage) any { return "0x19" },
+		"web3_clientVersion": func([]json.RawMessage) any { return "Geth/v1.101702.0-stable-d0734fd5/linux-amd64/go1.24.1" },
+	})
+
+	sources := statusSources{RepoPath: repo, Client: "geth", DataDir: t.TempDir(), Node: node, EL: el, MaxLag: 30}
+	status := collectStatus(context.Background(), sources)
+	if len(status.Errors) > 0 {
+		t.Fatalf("collectStatus() errors = %v", status.Errors)
+	}
+	if !status.Healthy {
+		t.Errorf("collectStatus() = unhealthy, want healthy: %+v", status)
+	}
+	if status.Sync.Safe != 950 || status.Peers.Node != 30 || status.Peers.EL != 25 || len(status.Versions) != 2 {
+		t.Errorf("collectStatus() = %+v, unexpected sections", status)
+	}
+
+	// A failing source is reported without dropping the other sections.
+	sources.EL = newFakeRPC(t, nil)
+	status = collectStatus(context.Background(), sources)
+	if status.Healthy || status.Errors["peers"] == "" || status.Errors["versions"] == "" || status.Sync == nil || status.Disk == nil {
+		t.Errorf("collectStatus() with a failing EL = %+v", status)
+	}
+}
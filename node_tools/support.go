@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// SupportRange is one supported execution client and op-node combination.
+// Minimums are inclusive and the optional Below bounds exclusive.
+type SupportRange struct {
+	Client      string `json:"client"`
+	ClientMin   string `json:"clientMin"`
+	ClientBelow string `json:"clientBelow,omitempty"`
+	OPNodeMin   string `json:"opNodeMin"`
+	OPNodeBelow string `json:"opNodeBelow,omitempty"`
+}
+
+// SupportMatrix maps network names to their supported combinations.
+type SupportMatrix map[string][]SupportRange
+
+func supportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "support",
+		Usage: "Validate the selected client and versions.json against the client support matrix",
+		Commands: []*cli.Command{
+			{
+				Name:  "check",
+				Usage: "Fails if the pinned client and op-node versions are not a supported combination",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "repo",
+						Usage: "Path to the node repository",
+						Value: ".",
+					},
+					&cli.StringFlag{
+						Name:  "network",
+						Usage: "Network to check (" + strings.Join(networkNames(), ", ") + "), defaults to all",
+					},
+					&cli.StringFlag{
+						Name:    "client",
+						Usage:   "Execution client to check, \"all\" checks every client",
+						Sources: cli.EnvVars("CLIENT"),
+						Value:   "geth",
+					},
+					&cli.StringFlag{
+						Name:  "matrix",
+						Usage: "Support matrix file, defaults to <repo>/node_tools/support-matrix.json",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					networks := networkNames()
+					if cmd.String("network") != "" {
+						network, err := lookupNetwork(cmd.String("network"))
+						if err != nil {
+							return err
+						}
+						networks = []string{network.Name}
+					}
+					clients := []string{cmd.String("client")}
+					if clients[0] == "all" {
+						clients = sortedKeys(clientDependencies)
+					}
+
+					var failed []string
+					for _, network := range networks {
+						for _, client := range clients {
+							if err := checkSupported(cmd.String("repo"), cmd.String("matrix"), network, client); err != nil {
+								log.Printf("FAIL %s", err)
+								failed = append(failed, network+"/"+client)
+								continue
+							}
+							log.Printf("OK   %s/%s", network, client)
+						}
+					}
+					if len(failed) > 0 {
+						return fmt.Errorf("unsupported combinations: %s", strings.Join(failed, ", "))
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// checkSupported validates the versions.json pins for client and op-node against the matrix.
+func checkSupported(repoPath string, matrixPath string, network string, client string) error {
+	if matrixPath == "" {
+		matrixPath = filepath.Join(repoPath, "node_tools", "support-matrix.json")
+	}
+	matrix, err := readSupportMatrix(matrixPath)
+	if err != nil {
+		return err
+	}
+	versions, err := readPinnedVersions(repoPath)
+	if err != nil {
+		return err
+	}
+	dep, ok := clientDependencies[client]
+	if !ok {
+		return fmt.Errorf("unknown client %q", client)
+	}
+	clientPin, ok := versions[dep]
+	if !ok {
+		return fmt.Errorf("%s is missing from versions.json", dep)
+	}
+	nodePin, ok := versions[nodeDependency]
+	if !ok {
+		return fmt.Errorf("%s is missing from versions.json", nodeDependency)
+	}
+	return matrix.supports(network, client, pinnedVersionString(clientPin), pinnedVersionString(nodePin))
+}
+
+func (m SupportMatrix) supports(network string, client string, clientVersion string, nodeVersion string) error {
+	ranges, ok := m[network]
+	if !ok {
+		return fmt.Errorf("support matrix has no entries for %s", network)
+	}
+
+	found := false
+	for _, r := range ranges {
+		if r.Client != client {
+			continue
+		}
+		found = true
+		if versionInRange(clientVersion, r.ClientMin, r.ClientBelow) && versionInRange(nodeVersion, r.OPNodeMin, r.OPNodeBelow) {
+			return nil
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s is not supported on %s, supported clients are %s", client, network, strings.Join(m.supportedClients(network), ", "))
+	}
+	return fmt.Errorf("%s %s with op-node %s is not a supported combination on %s", client, clientVersion, nodeVersion, network)
+}
+
+func readSupportMatrix(path string) (SupportMatrix, error) {
+	var matrix SupportMatrix
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading support matrix: %s", err)
+	}
+	if err := json.Unmarshal(f, &matrix); err != nil {
+		return nil, fmt.Errorf("error unmarshalling support matrix: %s", err)
+	}
+	return matrix, nil
+}
+
+// versionInRange reports whether min <= v < below, an empty bound is unbounded.
+func versionInRange(v string, min string, below string) bool {
+	if min != "" && compareVersions(v, min) < 0 {
+		return false
+	}
+	if below != "" && compareVersions(v, below) >= 0 {
+		return false
+	}
+	return true
+}
+
+// compareVersions compares dotted numeric versions, a pre-release sorts before its release.
+func compareVersions(a string, b string) int {
+	aCore, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bCore, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// supportedClients lists the clients the matrix has any entry for on a network.
+func (m SupportMatrix) supportedClients(network string) []string {
+	var clients []string
+	for _, r := range m[network] {
+		if !slices.Contains(clients, r.Client) {
+			clients = append(clients, r.Client)
+		}
+	}
+	slices.Sort(clients)
+	return clients
+}
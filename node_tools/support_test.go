@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.16.11", "1.16.11", 0},
+		{"v1.16.11", "1.16.11", 0},
+		{"1.16.11", "1.16.2", 1},
+		{"1.101702.0", "1.101603.5", 1},
+		{"0.7", "0.7.0", 0},
+		{"1.17.0-rc.1", "1.17.0", -1},
+		{"1.17.0-rc.2", "1.17.0-rc.1", 1},
+	}
+	for _, tt := range tests {
+		got := compareVersions(tt.a, tt.b)
+		if (got > 0) != (tt.want > 0) || (got < 0) != (tt.want < 0) {
+			t.Errorf("compareVersions(%q, %q) = %d, want sign of %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSupportMatrixSupports(t *testing.T) {
+	matrix := SupportMatrix{
+		"mainnet": {
+			{Client: "geth", ClientMin: "1.101600.0", ClientBelow: "1.101700.0", OPNodeMin: "1.13.0", OPNodeBelow: "1.16.0"},
+			{Client: "geth", ClientMin: "1.101700.0", OPNodeMin: "1.16.0"},
+			{Client: "reth", ClientMin: "0.7.0", OPNodeMin: "1.16.0"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		network       string
+		client        string
+		clientVersion string
+		nodeVersion   string
+		wantErr       string
+	}{
+		{"current pair", "mainnet", "geth", "1.101702.0", "1.16.11", ""},
+		{"older pair", "mainnet", "geth", "1.101603.5", "1.14.0", ""},
+		{"old geth with new op-node", "mainnet", "geth", "1.101603.5", "1.16.11", "not a supported combination"},
+		{"reth too old", "mainnet", "reth", "0.6.9", "1.16.11", "not a supported combination"},
+		{"unlisted client", "mainnet", "nethermind", "1.36.2", "1.16.11", "supported clients are geth, reth"},
+		{"unlisted network", "sepolia", "geth", "1.101702.0", "1.16.11", "no entries for sepolia"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := matrix.supports(tt.network, tt.client, tt.clientVersion, tt.nodeVersion)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("supports() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("supports() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
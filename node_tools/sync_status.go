@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// l2BlockTime is the Base block time, used to estimate the chain head when no reference RPC is given.
+const l2BlockTime = 2 * time.Second
+
+type syncSample struct {
+	Time  time.Time
+	Local uint64
+	Head  uint64
+}
+
+type syncProgress struct {
+	Percent float64
+	Behind  uint64
+	Rate    float64
+	ETA     time.Duration
+	// Converging is false when the node is not catching up faster than the chain grows.
+	Converging bool
+}
+
+func syncStatusCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "sync-status",
+		Usage: "Polls op-node and the execution client and prints sync progress with an ETA",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint",
+				Value: defaultNodeRPC,
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint",
+				Value: defaultELRPC,
+			},
+			&cli.StringFlag{
+				Name:  "reference-rpc",
+				Usage: "Trusted RPC used for the chain head, estimated from the unsafe head timestamp if unset",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "Time between samples",
+				Value: 10 * time.Second,
+			},
+			&cli.Uint64Flag{
+				Name:  "synced-threshold",
+				Usage: "Number of blocks behind the head at which the node is considered synced",
+				Value: 10,
+			},
+			&cli.BoolFlag{
+				Name:  "once",
+				Usage: "Take two samples, print the progress and exit instead of polling until synced",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			node := newRPCClient(cmd.String("node-rpc"))
+			el := newRPCClient(cmd.String("el-rpc"))
+			var reference *rpcClient
+			if cmd.String("reference-rpc") != "" {
+				reference = newRPCClient(cmd.String("reference-rpc"))
+			}
+			return watchSyncStatus(ctx, node, el, reference, cmd.Duration("interval"), cmd.Uint64("synced-threshold"), cmd.Bool("once"))
+		},
+	}
+}
+
+func watchSyncStatus(ctx context.Context, node *rpcClient, el *rpcClient, reference *rpcClient, interval time.Duration, threshold uint64, once bool) error {
+	var prev *syncSample
+	for {
+		sample, elHead, err := takeSyncSample(ctx, node, el, reference)
+		if err != nil {
+			return err
+		}
+
+		if prev != nil {
+			progress := computeSyncProgress(*prev, sample)
+			fmt.Println(formatSyncProgress(sample, elHead, progress))
+			if once {
+				return nil
+			}
+			if progress.Behind <= threshold {
+				log.Printf("Node is synced (%d blocks behind head)", progress.Behind)
+				return nil
+			}
+		} else {
+			fmt.Printf("block %d/%d (el %d), measuring sync rate...\n", sample.Local, sample.Head, elHead)
+		}
+		prev = &sample
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func takeSyncSample(ctx context.Context, node *rpcClient, el *rpcClient, reference *rpcClient) (syncSample, uint64, error) {
+	status, err := node.syncStatus(ctx)
+	if err != nil {
+		return syncSample{}, 0, err
+	}
+	elHead, err := el.blockNumber(ctx)
+	if err != nil {
+		return syncSample{}, 0, err
+	}
+
+	now := time.Now()
+	sample := syncSample{Time: now, Local: status.UnsafeL2.Number}
+	if reference != nil {
+		sample.Head, err = reference.blockNumber(ctx)
+		if err != nil {
+			return syncSample{}, 0, err
+		}
+	} else {
+		sample.Head = estimateHead(status.UnsafeL2, now)
+	}
+	if sample.Head < sample.Local {
+		sample.Head = sample.Local
+	}
+	return sample, elHead, nil
+}
+
+// estimateHead extrapolates the chain head from the local unsafe head's timestamp.
+func estimateHead(unsafe BlockRef, now time.Time) uint64 {
+	lag := now.Unix() - int64(unsafe.Timestamp)
+	if lag <= 0 {
+		return unsafe.Number
+	}
+	return unsafe.Number + uint64(lag)/uint64(l2BlockTime.Seconds())
+}
+
+func computeSyncProgress(prev syncSample, cur syncSample) syncProgress {
+	progress := syncProgress{}
+	if cur.Head > cur.Local {
+		progress.Behind = cur.Head - cur.Local
+	}
+	if cur.Head > 0 {
+		progress.Percent = float64(cur.Local) / float64(cur.Head) * 100
+	}
+
+	elapsed := cur.Time.Sub(prev.Time).Seconds()
+	if elapsed <= 0 {
+		return progress
+	}
+	progress.Rate = (float64(cur.Local) - float64(prev.Local)) / elapsed
+	headRate := (float64(cur.Head) - float64(prev.Head)) / elapsed
+
+	if gain := progress.Rate - headRate; gain > 0 {
+		progress.Converging = true
+		progress.ETA = time.Duration(float64(progress.Behind)/gain) * time.Second
+	}
+	return progress
+}
+
+func formatSyncProgress(sample syncSample, elHead uint64, progress syncProgress) string {
+	eta := "unknown (not catching up)"
+	if progress.Converging {
+		eta = progress.ETA.Round(time.Second).String()
+	}
+	return fmt.Sprintf("%s %6.2f%% block %d/%d (el %d) behind %d rate %.1f blk/s eta %s",
+		progressBar(progress.Percent, 30), progress.Percent, sample.Local, sample.Head, elHead, progress.Behind, progress.Rate, eta)
+}
+
+func progressBar(percent float64, width int) string {
+	filled := int(percent / 100 * float64(width))
+	filled = max(0, min(width, filled))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", width-filled) + "]"
+}
+
+// headLag returns how many blocks the local unsafe head is behind the estimated chain head.
+func headLag(ctx context.Context, node *rpcClient) (uint64, error) {
+	status, err := node.syncStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return estimateHead(status.UnsafeL2, time.Now()) - status.UnsafeL2.Number, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeSyncProgress(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name           string
+		prev           syncSample
+		cur            syncSample
+		wantBehind     uint64
+		wantConverging bool
+		wantETA        time.Duration
+	}{
+		{
+			name:           "catching up",
+			prev:           syncSample{Time: start, Local: 1000, Head: 2000},
+			cur:            syncSample{Time: start.Add(10 * time.Second), Local: 1105, Head: 2005},
+			wantBehind:     900,
+			wantConverging: true,
+			wantETA:        90 * time.Second,
+		},
+		{
+			name:           "falling behind",
+			prev:           syncSample{Time: start, Local: 1000, Head: 2000},
+			cur:            syncSample{Time: start.Add(10 * time.Second), Local: 1002, Head: 2005},
+			wantBehind:     1003,
+			wantConverging: false,
+		},
+		{
+			name:           "synced",
+			prev:           syncSample{Time: start, Local: 2000, Head: 2000},
+			cur:            syncSample{Time: start.Add(10 * time.Second), Local: 2005, Head: 2005},
+			wantBehind:     0,
+			wantConverging: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeSyncProgress(tt.prev, tt.cur)
+			if got.Behind != tt.wantBehind || got.Converging != tt.wantConverging || got.ETA != tt.wantETA {
+				t.Errorf("computeSyncProgress() = behind %d converging %v eta %s, want behind %d converging %v eta %s",
+					got.Behind, got.Converging, got.ETA, tt.wantBehind, tt.wantConverging, tt.wantETA)
+			}
+		})
+	}
+}
+
+func TestEstimateHead(t *testing.T) {
+	unsafe := BlockRef{Number: 100, Timestamp: 1700000000}
+	tests := []struct {
+		now  time.Time
+		want uint64
+	}{
+		{time.Unix(1700000000, 0), 100},
+		{time.Unix(1700000020, 0), 110},
+		{time.Unix(1699999990, 0), 100},
+	}
+
+	for _, tt := range tests {
+		if got := estimateHead(unsafe, tt.now); got != tt.want {
+			t.Errorf("estimateHead(%v, %v) = %d, want %d", unsafe, tt.now, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// dbCheck is an integrity check run with the execution client's own binary against /data
+// while the execution service is stopped.
+type dbCheck struct {
+	Name   string
+	Script string
+}
+
+var dbChecks = map[string][]dbCheck{
+	// db inspect reads every key in the key-value and ancient stores and fails on corruption.
+	"geth": {
+		{Name: "db inspect", Script: `./geth db inspect --datadir="${GETH_DATA_DIR:-/data}"`},
+		{Name: "snapshot verify-state", Script: `./geth snapshot verify-state --datadir="${GETH_DATA_DIR:-/data}"`},
+	},
+	"reth": {
+		{Name: "db stats", Script: `./base-reth-node db --datadir=/data --chain="$RETH_CHAIN" stats`},
+		{Name: "stage checkpoints", Script: `./base-reth-node db --datadir=/data --chain="$RETH_CHAIN" list StageCheckpoints`},
+	},
+}
+
+type blockCheck struct {
+	Number  uint64
+	Local   string
+	Trusted string
+	Err     error
+}
+
+func verifyDataCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "verify-data",
+		Usage: "Checks the chain database integrity and spot-checks historical blocks against a trusted RPC",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:    "env-file",
+				Usage:   "Network env file passed to docker compose",
+				Sources: cli.EnvVars("NETWORK_ENV"),
+				Value:   ".env.mainnet",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client passed to docker compose",
+				Sources: cli.EnvVars("CLIENT"),
+				Value:   "geth",
+			},
+			&cli.StringFlag{
+				Name:  "network",
+				Usage: "Network whose public RPC is trusted when --trusted-rpc is unset (" + strings.Join(networkNames(), ", ") + ")",
+				Value: "mainnet",
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint",
+				Value: defaultELRPC,
+			},
+			&cli.StringFlag{
+				Name:    "trusted-rpc",
+				Usage:   "Trusted RPC endpoint blocks are compared against",
+				Sources: cli.EnvVars("NODE_TOOLS_TRUSTED_RPC"),
+			},
+			&cli.IntFlag{
+				Name:  "samples",
+				Usage: "Number of historical blocks to compare",
+				Value: 32,
+			},
+			&cli.BoolFlag{
+				Name:  "stop",
+				Usage: "Stop a running execution service for the database checks and start it again afterwards",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-db",
+				Usage: "Skip the database checks, which can take hours on a large datadir",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-blocks",
+				Usage: "Skip the historical block comparison",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "How long to wait for the execution RPC after restarting the service",
+				Value: 5 * time.Minute,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			repoPath, envFile, client := cmd.String("repo"), cmd.String("env-file"), cmd.String("client")
+			el := newRPCClient(cmd.String("el-rpc"))
+
+			var failed []string
+			if !cmd.Bool("skip-db") {
+				running, err := composeOutput(ctx, repoPath, envFile, client, "ps", "-q", "--status", "running", "execution")
+				if err != nil {
+					return err
+				}
+				running = strings.TrimSpace(running)
+				if running != "" && !cmd.Bool("stop") {
+					return fmt.Errorf("the execution service is running, stop it first or pass --stop (or --skip-db)")
+				}
+				if running != "" {
+					if err := runCompose(ctx, repoPath, envFile, client, "stop", "execution"); err != nil {
+						return err
+					}
+				}
+				failed = runDBChecks(ctx, repoPath, envFile, client)
+				if running != "" {
+					if err := runCompose(ctx, repoPath, envFile, client, "start", "execution"); err != nil {
+						return err
+					}
+					waitCtx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+					_, err := waitForBlock(waitCtx, el, "latest")
+					cancel()
+					if err != nil {
+						return fmt.Errorf("execution RPC not available after restart: %s", err)
+					}
+				}
+			}
+
+			if !cmd.Bool("skip-blocks") {
+				trustedURL := cmd.String("trusted-rpc")
+				if trustedURL == "" {
+					network, err := lookupNetwork(cmd.String("network"))
+					if err != nil {
+						return err
+					}
+					trustedURL = network.PublicRPC
+				}
+				finalized, err := el.blockByNumber(ctx, "finalized")
+				if err != nil {
+					return fmt.Errorf("error fetching local finalized block: %s", err)
+				}
+				head, err := parseQuantity(finalized.Number)
+				if err != nil {
+					return err
+				}
+				numbers := sampleBlockNumbers(head, int(cmd.Int("samples")), rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0)))
+				mismatches := 0
+				for _, c := range compareBlocks(ctx, el, newRPCClient(trustedURL), numbers) {
+					switch {
+					case c.Err != nil:
+						log.Printf("block %d: %s", c.Number, c.Err)
+					case c.Local != c.Trusted:
+						mismatches++
+						fmt.Printf("block %d MISMATCH local %s trusted %s\n", c.Number, c.Local, c.Trusted)
+					default:
+						fmt.Printf("block %d ok %s\n", c.Number, c.Local)
+					}
+				}
+				if mismatches > 0 {
+					failed = append(failed, fmt.Sprintf("%d of %d sampled blocks differ from %s", mismatches, len(numbers), trustedURL))
+				}
+			}
+
+			if len(failed) > 0 {
+				return fmt.Errorf("data verification failed: %s", strings.Join(failed, "; "))
+			}
+			fmt.Println("Data verification passed")
+			return nil
+		},
+	}
+}
+
+// runDBChecks runs the client's checks one by one and returns the names of the failed ones.
+func runDBChecks(ctx context.Context, repoPath string, envFile string, client string) []string {
+	var failed []string
+	for _, check := range dbChecks[client] {
+		fmt.Printf("Running %s %s\n", client, check.Name)
+		err := runCompose(ctx, repoPath, envFile, client, "run", "--rm", "--no-deps", "-T", "--entrypoint", "bash", "execution", "-c", check.Script)
+		if err != nil {
+			log.Printf("%s %s failed: %s", client, check.Name, err)
+			failed = append(failed, check.Name)
+		}
+	}
+	return failed
+}
+
+// sampleBlockNumbers picks n distinct blocks in [0, head], one at a random offset within each
+// of n equal ranges, so the whole history is covered. Genesis and head are always included.
+func sampleBlockNumbers(head uint64, n int, rng *rand.Rand) []uint64 {
+	if n <= 0 {
+		return nil
+	}
+	if uint64(n) > head+1 {
+		n = int(head + 1)
+	}
+	picked := map[uint64]bool{0: true, head: true}
+	span := (head + 1) / uint64(n)
+	for i := 1; i < n-1; i++ {
+		picked[uint64(i)*span+rng.Uint64N(span)] = true
+	}
+	numbers := make([]uint64, 0, len(picked))
+	for number := range picked {
+		numbers = append(numbers, number)
+	}
+	slices.Sort(numbers)
+	return numbers
+}
+
+func compareBlocks(ctx context.Context, local *rpcClient, trusted *rpcClient, numbers []uint64) []blockCheck {
+	checks := make([]blockCheck, 0, len(numbers))
+	for _, number := range numbers {
+		c := blockCheck{Number: number}
+		if block, err := local.blockByNumber(ctx, formatQuantity(number)); err != nil {
+			c.Err = err
+		} else if other, err := trusted.blockByNumber(ctx, formatQuantity(number)); err != nil {
+			c.Err = err
+		} else {
+			c.Local, c.Trusted = block.Hash, other.Hash
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
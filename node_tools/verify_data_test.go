@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestSampleBlockNumbers(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	tests := []struct {
+		head uint64
+		n    int
+		want int
+	}{
+		{head: 1_000_000, n: 10, want: 10},
+		{head: 4, n: 10, want: 5},
+		{head: 100, n: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		got := sampleBlockNumbers(tt.head, tt.n, rng)
+		if len(got) != tt.want {
+			t.Errorf("sampleBlockNumbers(%d, %d) = %v, want %d blocks", tt.head, tt.n, got, tt.want)
+			continue
+		}
+		if tt.want == 0 {
+			continue
+		}
+		if got[0] != 0 || got[len(got)-1] != tt.head {
+			t.Errorf("sampleBlockNumbers(%d, %d) = %v, want genesis and head included", tt.head, tt.n, got)
+		}
+		if !slices.IsSorted(got) || len(slices.Compact(slices.Clone(got))) != len(got) {
+			t.Errorf("sampleBlockNumbers(%d, %d) = %v, want sorted distinct blocks", tt.head, tt.n, got)
+		}
+	}
+}
+
+func TestCompareBlocks(t *testing.T) {
+	block := func(prefix string) func([]json.RawMessage) any {
+		return func(params []json.RawMessage) any {
+			var number string
+			json.Unmarshal(params[0], &number)
+			if number == "0x3" {
+				return nil
+			}
+			hash := "0xaa" + number
+			if number == "0x2" {
+				hash = prefix + number
+			}
+			return map[string]any{"number": number, "hash": hash}
+		}
+	}
+	local := newFakeRPC(t, map[string]func([]json.RawMessage) any{"eth_getBlockByNumber": block("0xbb")})
+	trusted := newFakeRPC(t, map[string]func([]json.RawMessage) any{"eth_getBlockByNumber": block("0xcc")})
+
+	got := compareBlocks(t.Context(), local, trusted, []uint64{1, 2, 3})
+	if got[0].Err != nil || got[0].Local != got[0].Trusted {
+		t.Errorf("block 1 = %+v, want match", got[0])
+	}
+	if got[1].Err != nil || got[1].Local == got[1].Trusted {
+		t.Errorf("block 2 = %+v, want mismatch", got[1])
+	}
+	if got[2].Err == nil {
+		t.Errorf("block 3 = %+v, want missing block error", got[2])
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+var headTags = []string{"latest", "safe", "finalized"}
+
+type headComparison struct {
+	Tag     string
+	Number  uint64
+	Local   string
+	Trusted string
+	URL     string
+	// Status is "match", "diverged" or "unavailable" when the trusted RPC has no block at Number yet.
+	Status string
+	Err    error
+}
+
+func verifyHeadCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "verify-head",
+		Usage: "Compares the local latest/safe/finalized block hashes against trusted RPCs",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "network",
+				Usage: "Network whose public RPC is trusted when --trusted-rpc is unset (" + strings.Join(networkNames(), ", ") + ")",
+				Value: "mainnet",
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint",
+				Value: defaultELRPC,
+			},
+			&cli.StringSliceFlag{
+				Name:    "trusted-rpc",
+				Usage:   "Trusted RPC endpoints to compare against, can be repeated",
+				Sources: cli.EnvVars("NODE_TOOLS_TRUSTED_RPCS"),
+			},
+			&cli.DurationFlag{
+				Name:  "watch",
+				Usage: "Keep comparing at this interval instead of checking once",
+			},
+			webhookFlag,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			trusted := cmd.StringSlice("trusted-rpc")
+			if len(trusted) == 0 {
+				network, err := lookupNetwork(cmd.String("network"))
+				if err != nil {
+					return err
+				}
+				trusted = []string{network.PublicRPC}
+			}
+			local := newRPCClient(cmd.String("el-rpc"))
+			alerts := newAlerter(cmd.String("webhook"))
+
+			for {
+				diverged := 0
+				for _, c := range compareHeads(ctx, local, trusted) {
+					fmt.Println(formatHeadComparison(c))
+					if c.Status == "diverged" {
+						diverged++
+						alerts.alert(ctx, fmt.Sprintf("local %s block %d is %s but %s has %s, the node may have forked off",
+							c.Tag, c.Number, c.Local, c.URL, c.Trusted))
+					}
+				}
+				if cmd.Duration("watch") == 0 {
+					if diverged > 0 {
+						return fmt.Errorf("%d head(s) diverged from the trusted RPCs", diverged)
+					}
+					return nil
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(cmd.Duration("watch")):
+				}
+			}
+		},
+	}
+}
+
+// compareHeads looks up each local head by number on every trusted RPC. Comparing by
+// number rather than tag keeps a trusted RPC that is a few blocks ahead from looking divergent.
+func compareHeads(ctx context.Context, local *rpcClient, trusted []string) []headComparison {
+	var results []headComparison
+	for _, tag := range headTags {
+		block, err := local.blockByNumber(ctx, tag)
+		if err != nil {
+			results = append(results, headComparison{Tag: tag, Status: "unavailable", Err: fmt.Errorf("local: %s", err)})
+			continue
+		}
+		number, err := parseQuantity(block.Number)
+		if err != nil {
+			results = append(results, headComparison{Tag: tag, Status: "unavailable", Err: err})
+			continue
+		}
+
+		for _, url := range trusted {
+			c := headComparison{Tag: tag, Number: number, Local: block.Hash, URL: url}
+			remote, err := newRPCClient(url).blockByNumber(ctx, block.Number)
+			switch {
+			case err != nil:
+				c.Status, c.Err = "unavailable", err
+			case remote.Hash == block.Hash:
+				c.Status, c.Trusted = "match", remote.Hash
+			default:
+				c.Status, c.Trusted = "diverged", remote.Hash
+			}
+			results = append(results, c)
+		}
+	}
+	return results
+}
+
+func formatHeadComparison(c headComparison) string {
+	switch c.Status {
+	case "match":
+		return fmt.Sprintf("ok       %-9s %d %s matches %s", c.Tag, c.Number, c.Local, c.URL)
+	case "diverged":
+		return fmt.Sprintf("DIVERGED %-9s %d local %s, %s has %s", c.Tag, c.Number, c.Local, c.URL, c.Trusted)
+	default:
+		if c.URL == "" {
+			return fmt.Sprintf("skipped  %-9s %s", c.Tag, c.Err)
+		}
+		log.Printf("Could not compare %s block %d with %s: %s", c.Tag, c.Number, c.URL, c.Err)
+		return fmt.Sprintf("skipped  %-9s %d not available on %s", c.Tag, c.Number, c.URL)
+	}
+}
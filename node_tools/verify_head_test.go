@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestCompareHeads(t *testing.T) {
+	// Each fake chain serves block N with hash prefix+N, for N up to its head.
+	chain := func(prefix string, head uint64) map[string]func([]json.RawMessage) any {
+		return map[string]func([]json.RawMessage) any{
+			"eth_getBlockByNumber": func(params []json.RawMessage) any {
+				var number string
+				json.Unmarshal(params[0], &number)
+				switch number {
+				case `latest`:
+					number = formatQuantity(head)
+				case `safe`:
+					number = formatQuantity(head - 10)
+				case `finalized`:
+					number = formatQuantity(head - 100)
+				}
+				n, err := parseQuantity(number)
+				if err != nil || n > head {
+					return nil
+				}
+				hash := prefix + number
+				if prefix == "fork" && n < head-50 {
+					hash = "same" + number
+				}
+				return Block{Number: number, Hash: hash}
+			},
+		}
+	}
+
+	local := newFakeRPC(t, chain("same", 1000))
+	ahead := newFakeRPC(t, chain("same", 1005))
+	behind := newFakeRPC(t, chain("same", 995))
+	forked := newFakeRPC(t, chain("fork", 1000))
+
+	results := compareHeads(context.Background(), local, []string{ahead.url, behind.url, forked.url})
+	want := map[string]map[string]string{
+		"latest":    {ahead.url: "match", behind.url: "unavailable", forked.url: "diverged"},
+		"safe":      {ahead.url: "match", behind.url: "match", forked.url: "diverged"},
+		"finalized": {ahead.url: "match", behind.url: "match", forked.url: "match"},
+	}
+	if len(results) != 9 {
+		t.Fatalf("compareHeads() returned %d results, want 9", len(results))
+	}
+	for _, r := range results {
+		if got := r.Status; got != want[r.Tag][r.URL] {
+			t.Errorf("%s on %s = %s, want %s", r.Tag, r.URL, got, want[r.Tag][r.URL])
+		}
+	}
+}
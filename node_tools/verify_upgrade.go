@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// headSnapshot is the node state recorded before a restart.
+type headSnapshot struct {
+	Time          time.Time `json:"time"`
+	Number        uint64    `json:"number"`
+	Hash          string    `json:"hash"`
+	ClientVersion string    `json:"clientVersion"`
+}
+
+type upgradeCheck struct {
+	RepoPath string
+	Client   string
+	Node     *rpcClient
+	EL       *rpcClient
+	Timeout  time.Duration
+}
+
+func verifyUpgradeCommand() *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "repo",
+			Usage: "Path to the node repository",
+			Value: ".",
+		},
+		&cli.StringFlag{
+			Name:  "node-rpc",
+			Usage: "op-node RPC endpoint",
+			Value: defaultNodeRPC,
+		},
+		&cli.StringFlag{
+			Name:  "el-rpc",
+			Usage: "Execution client RPC endpoint",
+			Value: defaultELRPC,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "verify-upgrade",
+		Usage: "Verifies that the node resumes and advances on the expected version after a restart",
+		Commands: []*cli.Command{
+			{
+				Name:  "record",
+				Usage: "Records the current head before restarting onto a new version",
+				Flags: flags,
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					snapshot, err := takeHeadSnapshot(ctx, newRPCClient(cmd.String("el-rpc")))
+					if err != nil {
+						return err
+					}
+					path := upgradeSnapshotPath(cmd.String("repo"))
+					if err := writeHeadSnapshot(path, snapshot); err != nil {
+						return err
+					}
+					log.Printf("Recorded head %d (%s) running %s to %s", snapshot.Number, snapshot.Hash, snapshot.ClientVersion, path)
+					return nil
+				},
+			},
+			{
+				Name:  "check",
+				Usage: "Checks the restarted node against the recorded head and versions.json",
+				Flags: append(flags,
+					&cli.StringFlag{
+						Name:    "client",
+						Usage:   "Execution client the node runs (geth, reth, nethermind)",
+						Sources: cli.EnvVars("CLIENT"),
+						Value:   "geth",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "How long the node has to come back and advance past the recorded head",
+						Value: 10 * time.Minute,
+					},
+				),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					before, err := readHeadSnapshot(upgradeSnapshotPath(cmd.String("repo")))
+					if err != nil {
+						return err
+					}
+					check := upgradeCheck{
+						RepoPath: cmd.String("repo"),
+						Client:   cmd.String("client"),
+						Node:     newRPCClient(cmd.String("node-rpc")),
+						EL:       newRPCClient(cmd.String("el-rpc")),
+						Timeout:  cmd.Duration("timeout"),
+					}
+					failures := verifyUpgrade(ctx, check, before)
+					if len(failures) == 0 {
+						log.Printf("Upgrade verified: node resumed from %d and is advancing on the pinned versions", before.Number)
+						return nil
+					}
+					for _, f := range failures {
+						log.Printf("FAIL %s", f)
+					}
+					fmt.Println(rollbackRecommendation(before))
+					return fmt.Errorf("upgrade verification failed")
+				},
+			},
+		},
+	}
+}
+
+func upgradeSnapshotPath(repoPath string) string {
+	return filepath.Join(repoPath, stateDir, "upgrade-snapshot.json")
+}
+
+func takeHeadSnapshot(ctx context.Context, el *rpcClient) (headSnapshot, error) {
+	block, err := el.blockByNumber(ctx, "latest")
+	if err != nil {
+		return headSnapshot{}, err
+	}
+	number, err := parseQuantity(block.Number)
+	if err != nil {
+		return headSnapshot{}, err
+	}
+	var version string
+	if err := el.call(ctx, &version, "web3_clientVersion"); err != nil {
+		return headSnapshot{}, err
+	}
+	return headSnapshot{Time: time.Now().UTC(), Number: number, Hash: block.Hash, ClientVersion: version}, nil
+}
+
+// verifyUpgrade waits for the node to come back and returns a list of failed checks.
+func verifyUpgrade(ctx context.Context, check upgradeCheck, before headSnapshot) []string {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	var failures []string
+
+	// The recorded block must still be canonical, otherwise the node came back on a different chain or a reset database.
+	block, err := waitForBlock(ctx, check.EL, formatQuantity(before.Number))
+	if err != nil {
+		return append(failures, fmt.Sprintf("node did not come back within %s: %s", check.Timeout, err))
+	}
+	if block.Hash != before.Hash {
+		failures = append(failures, fmt.Sprintf("block %d hash changed from %s to %s, node did not resume from the same head", before.Number, before.Hash, block.Hash))
+	}
+
+	if err := waitForAdvance(ctx, check.EL, before.Number); err != nil {
+		failures = append(failures, fmt.Sprintf("head did not advance past %d within %s: %s", before.Number, check.Timeout, err))
+	}
+
+	deployed, err := checkDeployedVersions(ctx, check.RepoPath, check.Client, check.EL, check.Node)
+	if err != nil {
+		return append(failures, fmt.Sprintf("could not check running versions: %s", err))
+	}
+	for _, d := range deployed {
+		if d.Drift != "" {
+			failures = append(failures, fmt.Sprintf("%s is not on the expected version: %s", d.Component, d.Drift))
+		}
+	}
+	return failures
+}
+
+func waitForBlock(ctx context.Context, el *rpcClient, number string) (*Block, error) {
+	for {
+		block, err := el.blockByNumber(ctx, number)
+		if err == nil {
+			return block, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func waitForAdvance(ctx context.Context, el *rpcClient, past uint64) error {
+	for {
+		n, err := el.blockNumber(ctx)
+		if err == nil && n > past {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("still at %d", n)
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func rollbackRecommendation(before headSnapshot) string {
+	lines := []string{
+		"Rollback recommended:",
+		"  the node was running " + orUnknown(before.ClientVersion) + " before the restart.",
+		"  restore the previous versions.json and versions.env (e.g. git checkout HEAD~1 -- versions.json versions.env),",
+		"  then rebuild and restart with docker compose up -d --build.",
+	}
+	return strings.Join(lines, "\n")
+}
+
+func readHeadSnapshot(path string) (headSnapshot, error) {
+	var snapshot headSnapshot
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, fmt.Errorf("error reading head snapshot, run verify-upgrade record before restarting: %s", err)
+	}
+	if err := json.Unmarshal(f, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("error unmarshalling head snapshot: %s", err)
+	}
+	return snapshot, nil
+}
+
+func writeHeadSnapshot(path string, snapshot headSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating %s: %s", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling head snapshot: %s", err)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVerifyUpgrade(t *testing.T) {
+	repo := t.TempDir()
+	versions := `{
+		"op_geth": {"tag": "v1.101702.0", "commit": "d0734fd5f44234cde3b0a7c4beb1256fc6feedef", "tracking": "release"},
+		"op_node": {"tag": "op-node/v1.16.11", "tagPrefix": "op-node", "commit": "cba7aba0c98aae22720b21c3a023990a486cb6e0", "tracking": "release"}
+	}`
+	if err := os.WriteFile(filepath.Join(repo, "versions.json"), []byte(versions), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before := headSnapshot{Number: 100, Hash: "0xaaa"}
+
+	tests := []struct {
+		name         string
+		hash         string
+		head         string
+		elVersion    string
+		wantFailures int
+	}{
+		{"healthy upgrade", "0xaaa", "0x70", "Geth/v1.101702.0-stable-d0734fd5/linux-amd64/go1.24.1", 0},
+		{"different chain", "0xbbb", "0x70", "Geth/v1.101702.0-stable-d0734fd5/linux-amd64/go1.24.1", 1},
+		{"old version still running", "0xaaa", "0x70", "Geth/v1.101701.0-stable-11111111/linux-amd64/go1.24.1", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			el := newFakeRPC(t, map[string]func([]json.RawMessage) any{
+				"eth_getBlockByNumber": func([]json.RawMessage) any { return Block{Number: "0x64", Hash: tt.hash} },
+				"eth_blockNumber":      func([]json.RawMessage) any { return tt.head },
+				"web3_clientVersion":   func([]json.RawMessage) any { return tt.elVersion },
+			})
+			node := newFakeRPC(t, map[string]func([]json.RawMessage) any{
+				"optimism_version": func([]json.RawMessage) any { return "v1.16.11" },
+			})
+
+			check := upgradeCheck{RepoPath: repo, Client: "geth", Node: node, EL: el, Timeout: time.Second}
+			failures := verifyUpgrade(t.Context(), check, before)
+			if len(failures) != tt.wantFailures {
+				t.Errorf("verifyUpgrade() = %v, want %d failures", failures, tt.wantFailures)
+			}
+		})
+	}
+}
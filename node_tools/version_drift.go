@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+)
+
+type deployedVersion struct {
+	Component  string
+	Dependency string
+	Reported   string
+	Pinned     string
+	Drift      string
+}
+
+func versionDriftCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "version-drift",
+		Usage: "Compares the versions reported by the running node against versions.json",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "repo",
+				Usage: "Path to the node repository",
+				Value: ".",
+			},
+			&cli.StringFlag{
+				Name:    "client",
+				Usage:   "Execution client the node runs (geth, reth, nethermind)",
+				Sources: cli.EnvVars("CLIENT"),
+				Value:   "geth",
+			},
+			&cli.StringFlag{
+				Name:  "node-rpc",
+				Usage: "op-node RPC endpoint",
+				Value: defaultNodeRPC,
+			},
+			&cli.StringFlag{
+				Name:  "el-rpc",
+				Usage: "Execution client RPC endpoint",
+				Value: defaultELRPC,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			deployed, err := checkDeployedVersions(ctx, cmd.String("repo"), cmd.String("client"),
+				newRPCClient(cmd.String("el-rpc")), newRPCClient(cmd.String("node-rpc")))
+			if err != nil {
+				return err
+			}
+
+			drifted := 0
+			for _, d := range deployed {
+				status := "ok"
+				if d.Drift != "" {
+					status = "DRIFT"
+					drifted++
+				}
+				fmt.Printf("%-5s %-10s pinned %-20s running %s\n", status, d.Component, d.Pinned, d.Reported)
+				if d.Drift != "" {
+					fmt.Printf("      %s\n", d.Drift)
+				}
+			}
+			if drifted > 0 {
+				return fmt.Errorf("%d component(s) do not match versions.json", drifted)
+			}
+			return nil
+		},
+	}
+}
+
+func checkDeployedVersions(ctx context.Context, repoPath string, client string, el *rpcClient, node *rpcClient) ([]deployedVersion, error) {
+	pinned, err := readPinnedVersions(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	dependency, ok := clientDependencies[client]
+	if !ok {
+		return nil, fmt.Errorf("unknown client %q", client)
+	}
+
+	var elVersion string
+	if err := el.call(ctx, &elVersion, "web3_clientVersion"); err != nil {
+		return nil, err
+	}
+	// optimism_version is served by op-node only, base-consensus does not expose it.
+	var nodeVersion string
+	if err := node.call(ctx, &nodeVersion, "optimism_version"); err != nil {
+		return nil, err
+	}
+
+	var deployed []deployedVersion
+	for _, c := range []struct {
+		component, dependency, reported string
+	}{
+		{client, dependency, elVersion},
+		{"op-node", nodeDependency, nodeVersion},
+	} {
+		p, ok := pinned[c.dependency]
+		if !ok {
+			return nil, fmt.Errorf("%s is not pinned in versions.json", c.dependency)
+		}
+		deployed = append(deployed, deployedVersion{
+			Component:  c.component,
+			Dependency: c.dependency,
+			Reported:   c.reported,
+			Pinned:     p.Tag,
+			Drift:      compareReportedVersion(c.reported, p),
+		})
+	}
+	return deployed, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PinnedVersion is the subset of a versions.json entry the node tools use.
+type PinnedVersion struct {
+	Tag       string `json:"tag,omitempty"`
+	Commit    string `json:"commit"`
+	TagPrefix string `json:"tagPrefix,omitempty"`
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	Branch    string `json:"branch,omitempty"`
+	Tracking  string `json:"tracking"`
+}
+
+// clientDependencies maps execution clients to their versions.json entry.
+var clientDependencies = map[string]string{
+	"geth":       "op_geth",
+	"reth":       "base_reth_node",
+	"nethermind": "nethermind",
+}
+
+// nodeDependency is the versions.json entry for op-node.
+const nodeDependency = "op_node"
+
+var (
+	reportedVersionPattern = regexp.MustCompile(`v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.]+)?)`)
+	reportedCommitPattern  = regexp.MustCompile(`(?:^|[-+/])([0-9a-f]{7,40})(?:$|[-+/])`)
+)
+
+func readPinnedVersions(repoPath string) (map[string]*PinnedVersion, error) {
+	var versions map[string]*PinnedVersion
+	f, err := os.ReadFile(filepath.Join(repoPath, "versions.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading versions JSON: %s", err)
+	}
+	if err := json.Unmarshal(f, &versions); err != nil {
+		return nil, fmt.Errorf("error unmarshalling versions JSON: %s", err)
+	}
+	return versions, nil
+}
+
+// pinnedVersionString strips the tag prefix and v from a pinned tag, "op-node/v1.16.11" -> "1.16.11".
+func pinnedVersionString(p *PinnedVersion) string {
+	v := p.Tag
+	if p.TagPrefix != "" {
+		v = strings.TrimPrefix(strings.TrimPrefix(v, p.TagPrefix), "/")
+	}
+	return strings.TrimPrefix(v, "v")
+}
+
+// parseReportedVersion extracts the version and, if present, the short commit from a
+// client version string such as "Geth/v1.101702.0-stable-d0734fd5/linux-amd64/go1.24.1".
+func parseReportedVersion(reported string) (string, string) {
+	var version, commit string
+	if m := reportedVersionPattern.FindStringSubmatch(reported); m != nil {
+		version = strings.TrimSuffix(m[1], "-stable")
+	}
+	if m := reportedCommitPattern.FindStringSubmatch(reported); m != nil {
+		commit = m[1]
+	}
+	return version, commit
+}
+
+// compareReportedVersion returns an empty string if reported matches the pinned version,
+// otherwise a description of the mismatch.
+func compareReportedVersion(reported string, pinned *PinnedVersion) string {
+	version, commit := parseReportedVersion(reported)
+	want := pinnedVersionString(pinned)
+	if pinned.Tracking != "branch" && version != want {
+		return fmt.Sprintf("running %s, pinned %s", orUnknown(version), want)
+	}
+	if commit != "" && pinned.Commit != "" && !strings.HasPrefix(pinned.Commit, commit) {
+		return fmt.Sprintf("running commit %s, pinned %s", commit, pinned.Commit)
+	}
+	return ""
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
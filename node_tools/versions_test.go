@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCompareReportedVersion(t *testing.T) {
+	geth := &PinnedVersion{Tag: "v1.101702.0", Commit: "d0734fd5f44234cde3b0a7c4beb1256fc6feedef", Tracking: "release"}
+	nethermind := &PinnedVersion{Tag: "1.36.2", Commit: "f5507dec1c9c7f5e31dadae445c08622be166054", Tracking: "release"}
+	opNode := &PinnedVersion{Tag: "op-node/v1.16.11", TagPrefix: "op-node", Commit: "cba7aba0c98aae22720b21c3a023990a486cb6e0", Tracking: "release"}
+
+	tests := []struct {
+		name      string
+		reported  string
+		pinned    *PinnedVersion
+		wantDrift bool
+	}{
+		{"geth matches", "Geth/v1.101702.0-stable-d0734fd5/linux-amd64/go1.24.1", geth, false},
+		{"geth older", "Geth/v1.101701.1-stable-aaaaaaaa/linux-amd64/go1.24.1", geth, true},
+		{"geth wrong commit", "Geth/v1.101702.0-stable-deadbeef/linux-amd64/go1.24.1", geth, true},
+		{"nethermind matches", "Nethermind/v1.36.2+f5507dec/linux-x64/dotnet10.0.0", nethermind, false},
+		{"nethermind differs", "Nethermind/v1.35.0+11111111/linux-x64/dotnet10.0.0", nethermind, true},
+		{"op-node matches", "v1.16.11", opNode, false},
+		{"op-node differs", "v1.16.10", opNode, true},
+		{"unparseable", "something else", opNode, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift := compareReportedVersion(tt.reported, tt.pinned)
+			if (drift != "") != tt.wantDrift {
+				t.Errorf("compareReportedVersion(%q) = %q, wantDrift %v", tt.reported, drift, tt.wantDrift)
+			}
+		})
+	}
+}